@@ -20,7 +20,7 @@ DESCRIPTOR = _descriptor.FileDescriptor(
   name='ab.proto',
   package='atomicbroadcast',
   syntax='proto3',
-  serialized_pb=_b('\n\x08\x61\x62.proto\x12\x0f\x61tomicbroadcast\"<\n\x11\x42roadcastResponse\x12\'\n\x06Status\x18\x01 \x01(\x0e\x32\x17.atomicbroadcast.Status\" \n\x10\x42roadcastMessage\x12\x0c\n\x04\x44\x61ta\x18\x01 \x01(\x0c\"\x9f\x01\n\x08SeekInfo\x12\x32\n\x05Start\x18\x01 \x01(\x0e\x32#.atomicbroadcast.SeekInfo.StartType\x12\x17\n\x0fSpecifiedNumber\x18\x02 \x01(\x04\x12\x12\n\nWindowSize\x18\x03 \x01(\x04\"2\n\tStartType\x12\n\n\x06NEWEST\x10\x00\x12\n\n\x06OLDEST\x10\x01\x12\r\n\tSPECIFIED\x10\x02\"!\n\x0f\x41\x63knowledgement\x12\x0e\n\x06Number\x18\x01 \x01(\x04\"\x7f\n\rDeliverUpdate\x12;\n\x0f\x41\x63knowledgement\x18\x01 \x01(\x0b\x32 .atomicbroadcast.AcknowledgementH\x00\x12)\n\x04Seek\x18\x02 \x01(\x0b\x32\x19.atomicbroadcast.SeekInfoH\x00\x42\x06\n\x04Type\"m\n\x05\x42lock\x12\x0e\n\x06Number\x18\x02 \x01(\x04\x12\x10\n\x08PrevHash\x18\x03 \x01(\x0c\x12\r\n\x05Proof\x18\x04 \x01(\x0c\x12\x33\n\x08Messages\x18\x05 \x03(\x0b\x32!.atomicbroadcast.BroadcastMessage\"l\n\x0f\x44\x65liverResponse\x12(\n\x05\x45rror\x18\x01 \x01(\x0e\x32\x17.atomicbroadcast.StatusH\x00\x12\'\n\x05\x42lock\x18\x02 \x01(\x0b\x32\x16.atomicbroadcast.BlockH\x00\x42\x06\n\x04Type*a\n\x06Status\x12\x0b\n\x07SUCCESS\x10\x00\x12\x10\n\x0b\x42\x41\x44_REQUEST\x10\x90\x03\x12\x0e\n\tFORBIDDEN\x10\x93\x03\x12\x0e\n\tNOT_FOUND\x10\x94\x03\x12\x18\n\x13SERVICE_UNAVAILABLE\x10\xf7\x03\x32\xbe\x01\n\x0f\x41tomicBroadcast\x12X\n\tBroadcast\x12!.atomicbroadcast.BroadcastMessage\x1a\".atomicbroadcast.BroadcastResponse\"\x00(\x01\x30\x01\x12Q\n\x07\x44\x65liver\x12\x1e.atomicbroadcast.DeliverUpdate\x1a .atomicbroadcast.DeliverResponse\"\x00(\x01\x30\x01\x62\x06proto3')
+  serialized_pb=_b('\n\x08\x61\x62.proto\x12\x0f\x61tomicbroadcast\"3\n\x05Hello\x12*\n\x08\x46\x65\x61tures\x18\x01 \x03(\x0e\x32\x18.atomicbroadcast.Feature\"\xc0\x01\n\x11\x42roadcastResponse\x12\'\n\x06Status\x18\x01 \x01(\x0e\x32\x17.atomicbroadcast.Status\x12\x13\n\x0b\x42lockNumber\x18\x02 \x01(\x04\x12\r\n\x05Index\x18\x03 \x01(\x04\x12\x12\n\nRetryAfter\x18\x04 \x01(\x04\x12\x0c\n\x04Info\x18\x05 \x01(\t\x12\x15\n\rCorrelationID\x18\x06 \x01(\x0c\x12%\n\x05Hello\x18\x07 \x01(\x0b\x32\x16.atomicbroadcast.Hello\"o\n\x10\x42roadcastMessage\x12\x0c\n\x04\x44\x61ta\x18\x01 \x01(\x0c\x12\x0f\n\x07\x43hainID\x18\x02 \x01(\x0c\x12\x15\n\rCorrelationID\x18\x03 \x01(\x0c\x12%\n\x05Hello\x18\x04 \x01(\x0b\x32\x16.atomicbroadcast.Hello\"E\n\x0e\x42roadcastBatch\x12\x33\n\x08Messages\x18\x01 \x03(\x0b\x32!.atomicbroadcast.BroadcastMessage\"O\n\x16\x42roadcastBatchResponse\x12\x35\n\tResponses\x18\x01 \x03(\x0b\x32\".atomicbroadcast.BroadcastResponse\"\x84\x01\n\x0cKafkaMessage\x12\x30\n\x04Type\x18\x01 \x01(\x0e\x32\".atomicbroadcast.KafkaMessage.Type\x12\x0f\n\x07Payload\x18\x02 \x01(\x0c\"1\n\x04Type\x12\x0b\n\x07REGULAR\x10\x00\x12\x0f\n\x0bTIME_TO_CUT\x10\x01\x12\x0b\n\x07\x43ONNECT\x10\x02\"8\n\nSignedData\x12\x17\n\x0fPayloadEnvelope\x18\x01 \x01(\x0c\x12\x11\n\tSignature\x18\x02 \x01(\x0c\"2\n\x0fPayloadEnvelope\x12\x0f\n\x07Payload\x18\x01 \x01(\x0c\x12\x0e\n\x06Signer\x18\x02 \x01(\x0c\"\x90\x01\n\x0bTransaction\x12\x10\n\x06Opaque\x18\x01 \x01(\x0cH\x00\x12\x1f\n\x15\x43onfigurationEnvelope\x18\x02 \x01(\x0cH\x00\x12\x15\n\x0b\x43reateChain\x18\x04 \x01(\x0cH\x00\x12/\n\nSignatures\x18\x03 \x03(\x0b\x32\x1b.atomicbroadcast.SignedDataB\x06\n\x04Type\"\x95\x01\n\x15\x43onfigurationEnvelope\x12\x10\n\x08Sequence\x18\x01 \x01(\x04\x12\x0f\n\x07\x43hainID\x18\x02 \x01(\x0c\x12\x34\n\x07\x45ntries\x18\x03 \x03(\x0b\x32#.atomicbroadcast.ConfigurationEntry\x12\x11\n\tNotBefore\x18\x04 \x01(\x03\x12\x10\n\x08NotAfter\x18\x05 \x01(\x03\"\\\n\x12\x43onfigurationEntry\x12\x15\n\rConfiguration\x18\x01 \x01(\x0c\x12/\n\nSignatures\x18\x02 \x03(\x0b\x32\x1b.atomicbroadcast.SignedData\"\x90\x02\n\rConfiguration\x12\x0f\n\x07\x43hainID\x18\x01 \x01(\x0c\x12\n\n\x02ID\x18\x02 \x01(\t\x12\x14\n\x0cLastModified\x18\x03 \x01(\x04\x12>\n\x04Type\x18\x04 \x01(\x0e\x32\x30.atomicbroadcast.Configuration.ConfigurationType\x12\x0c\n\x04\x44\x61ta\x18\x05 \x01(\x0c\x12\x1a\n\x12ModificationPolicy\x18\x06 \x01(\t\"b\n\x11\x43onfigurationType\x12\n\n\x06Policy\x10\x00\x12\n\n\x06\x46\x61\x62ric\x10\x01\x12\t\n\x05\x43hain\x10\x02\x12\x08\n\x04Solo\x10\x03\x12\t\n\x05Kafka\x10\x04\x12\x08\n\x04PBFT\x10\x05\x12\x0b\n\x07Orderer\x10\x06\"H\n\tBatchSize\x12\x10\n\x08Messages\x18\x01 \x01(\r\x12\x10\n\x08MaxBytes\x18\x02 \x01(\r\x12\x17\n\x0fMaxMessageBytes\x18\x03 \x01(\r\"!\n\rBatchMaxBytes\x12\x10\n\x08MaxBytes\x18\x01 \x01(\r\"\x1f\n\x0c\x42\x61tchTimeout\x12\x0f\n\x07Timeout\x18\x01 \x01(\x03\"\xcb\x01\n\x06Policy\x12\x43\n\x0fSignaturePolicy\x18\x02 \x01(\x0b\x32(.atomicbroadcast.SignaturePolicyEnvelopeH\x00\x12G\n\x12SignatureThreshold\x18\x03 \x01(\x0b\x32).atomicbroadcast.SignatureThresholdPolicyH\x00\x12+\n\x04Meta\x18\x04 \x01(\x0b\x32\x1b.atomicbroadcast.MetaPolicyH\x00\x42\x06\n\x04Type\"y\n\nMetaPolicy\x12.\n\x04Rule\x18\x01 \x01(\x0e\x32 .atomicbroadcast.MetaPolicy.Rule\x12\x13\n\x0bSubPolicies\x18\x02 \x03(\t\"&\n\x04Rule\x12\x07\n\x03\x41NY\x10\x00\x12\x07\n\x03\x41LL\x10\x01\x12\x0c\n\x08MAJORITY\x10\x02\"9\n\x18SignatureThresholdPolicy\x12\t\n\x01N\x18\x01 \x01(\x05\x12\x12\n\nIdentities\x18\x02 \x03(\x0c\"p\n\x17SignaturePolicyEnvelope\x12\x0f\n\x07Version\x18\x01 \x01(\x05\x12\x30\n\x06Policy\x18\x02 \x01(\x0b\x32 .atomicbroadcast.SignaturePolicy\x12\x12\n\nIdentities\x18\x03 \x03(\x0c\"\xaf\x01\n\x0fSignaturePolicy\x12\x12\n\x08SignedBy\x18\x01 \x01(\x05H\x00\x12\x37\n\x04\x46rom\x18\x02 \x01(\x0b\x32\'.atomicbroadcast.SignaturePolicy.NOutOfH\x00\x1aG\n\x06NOutOf\x12\t\n\x01N\x18\x01 \x01(\x05\x12\x32\n\x08Policies\x18\x02 \x03(\x0b\x32 .atomicbroadcast.SignaturePolicyB\x06\n\x04Type\"\xa9\x04\n\x08SeekInfo\x12\x32\n\x05Start\x18\x01 \x01(\x0e\x32#.atomicbroadcast.SeekInfo.StartType\x12\x17\n\x0fSpecifiedNumber\x18\x02 \x01(\x04\x12\x12\n\nWindowSize\x18\x03 \x01(\x04\x12\x36\n\x07\x43ontent\x18\x04 \x01(\x0e\x32%.atomicbroadcast.SeekInfo.ContentType\x12\x0f\n\x07\x43hainID\x18\x05 \x01(\x0c\x12\x0e\n\x06\x43ursor\x18\x06 \x01(\x0c\x12\x30\n\x04Stop\x18\x07 \x01(\x0e\x32\".atomicbroadcast.SeekInfo.StopType\x12\x12\n\nStopNumber\x18\x08 \x01(\x04\x12\x13\n\x0bWaitForStop\x18\t \x01(\x08\x12\x16\n\x0eStartTimestamp\x18\n \x01(\x03\x12/\n\nSignatures\x18\x0b \x03(\x0b\x32\x1b.atomicbroadcast.SignedData\x12\x11\n\tTimestamp\x18\x0c \x01(\x03\"A\n\tStartType\x12\n\n\x06NEWEST\x10\x00\x12\n\n\x06OLDEST\x10\x01\x12\r\n\tSPECIFIED\x10\x02\x12\r\n\tTIMESTAMP\x10\x03\")\n\x0b\x43ontentType\x12\x08\n\x04\x46ULL\x10\x00\x12\x10\n\x0cHEADERS_ONLY\x10\x01\">\n\x08StopType\x12\r\n\tUNBOUNDED\x10\x00\x12\x12\n\x0eSTOP_SPECIFIED\x10\x01\x12\x0f\n\x0bSTOP_NEWEST\x10\x02\"!\n\x0f\x41\x63knowledgement\x12\x0e\n\x06Number\x18\x01 \x01(\x04\"\"\n\x0cWindowUpdate\x12\x12\n\nWindowSize\x18\x01 \x01(\x04\"\xdf\x01\n\rDeliverUpdate\x12;\n\x0f\x41\x63knowledgement\x18\x01 \x01(\x0b\x32 .atomicbroadcast.AcknowledgementH\x00\x12)\n\x04Seek\x18\x02 \x01(\x0b\x32\x19.atomicbroadcast.SeekInfoH\x00\x12\x35\n\x0cWindowUpdate\x18\x03 \x01(\x0b\x32\x1d.atomicbroadcast.WindowUpdateH\x00\x12\'\n\x05Hello\x18\x04 \x01(\x0b\x32\x16.atomicbroadcast.HelloH\x00\x42\x06\n\x04Type\"\x91\x01\n\x05\x42lock\x12,\n\x06Header\x18\x01 \x01(\x0b\x32\x1c.atomicbroadcast.BlockHeader\x12(\n\x04\x44\x61ta\x18\x02 \x01(\x0b\x32\x1a.atomicbroadcast.BlockData\x12\x30\n\x08Metadata\x18\x03 \x01(\x0b\x32\x1e.atomicbroadcast.BlockMetadata\"E\n\x0b\x42lockHeader\x12\x0e\n\x06Number\x18\x01 \x01(\x04\x12\x14\n\x0cPreviousHash\x18\x02 \x01(\x0c\x12\x10\n\x08\x44\x61taHash\x18\x03 \x01(\x0c\"@\n\tBlockData\x12\x33\n\x08Messages\x18\x01 \x03(\x0b\x32!.atomicbroadcast.BroadcastMessage\"!\n\rBlockMetadata\x12\x10\n\x08Metadata\x18\x01 \x03(\x0c\"5\n\x0e\x42lockSignature\x12\x10\n\x08Identity\x18\x01 \x01(\x0c\x12\x11\n\tSignature\x18\x02 \x01(\x0c\"\x85\x01\n\x0bLegacyBlock\x12\x0e\n\x06Number\x18\x02 \x01(\x04\x12\x10\n\x08PrevHash\x18\x03 \x01(\x0c\x12\r\n\x05Proof\x18\x04 \x01(\x0c\x12\x33\n\x08Messages\x18\x05 \x03(\x0b\x32!.atomicbroadcast.BroadcastMessage\x12\x10\n\x08\x44\x61taHash\x18\x06 \x01(\x0c\"\x1b\n\tHeartbeat\x12\x0e\n\x06Height\x18\x01 \x01(\x04\"\xf8\x01\n\x0f\x44\x65liverResponse\x12(\n\x05\x45rror\x18\x01 \x01(\x0e\x32\x17.atomicbroadcast.StatusH\x00\x12\'\n\x05\x42lock\x18\x02 \x01(\x0b\x32\x16.atomicbroadcast.BlockH\x00\x12/\n\tHeartbeat\x18\x03 \x01(\x0b\x32\x1a.atomicbroadcast.HeartbeatH\x00\x12\'\n\x05Hello\x18\x07 \x01(\x0b\x32\x16.atomicbroadcast.HelloH\x00\x12\x0e\n\x06\x43ursor\x18\x04 \x01(\x0c\x12\x12\n\nRetryAfter\x18\x05 \x01(\x04\x12\x0c\n\x04Info\x18\x06 \x01(\tB\x06\n\x04Type\"\x83\x01\n\x06\x43ursor\x12\x0f\n\x07\x43hainID\x18\x01 \x01(\x0c\x12\x0e\n\x06Number\x18\x02 \x01(\x04\x12\x0c\n\x04Hash\x18\x03 \x01(\x0c\x12\x12\n\nWindowSize\x18\x04 \x01(\x04\x12\x36\n\x07\x43ontent\x18\x05 \x01(\x0e\x32%.atomicbroadcast.SeekInfo.ContentType\"\"\n\x0cPauseRequest\x12\x12\n\nRetryAfter\x18\x01 \x01(\x04\"\x0f\n\rResumeRequest\"H\n\rAdminResponse\x12\'\n\x06Status\x18\x01 \x01(\x0e\x32\x17.atomicbroadcast.Status\x12\x0e\n\x06Paused\x18\x02 \x01(\x08\"O\n\x16ValidateConfigResponse\x12\'\n\x06Status\x18\x01 \x01(\x0e\x32\x17.atomicbroadcast.Status\x12\x0c\n\x04Info\x18\x02 \x01(\t*\xa7\x01\n\x06Status\x12\x0b\n\x07SUCCESS\x10\x00\x12\x10\n\x0b\x42\x41\x44_REQUEST\x10\x90\x03\x12\x0e\n\tFORBIDDEN\x10\x93\x03\x12\x0e\n\tNOT_FOUND\x10\x94\x03\x12\x14\n\x0fREQUEST_TIMEOUT\x10\x98\x03\x12\x16\n\x11PAYLOAD_TOO_LARGE\x10\x9d\x03\x12\x16\n\x11TOO_MANY_REQUESTS\x10\xad\x03\x12\x18\n\x13SERVICE_UNAVAILABLE\x10\xf7\x03*U\n\x07\x46\x65\x61ture\x12\x0e\n\nNO_FEATURE\x10\x00\x12\x11\n\rCHAIN_ROUTING\x10\x01\x12\x10\n\x0c\x44\x45LIVER_ACKS\x10\x02\x12\x15\n\x11\x46ILTERED_DELIVERY\x10\x03*V\n\x12\x42lockMetadataIndex\x12\t\n\x05PROOF\x10\x00\x12\x0e\n\nSIGNATURES\x10\x01\x12\x16\n\x12LAST_CONFIGURATION\x10\x02\x12\r\n\tTIMESTAMP\x10\x03\x32\xfc\x02\n\x0f\x41tomicBroadcast\x12X\n\tBroadcast\x12!.atomicbroadcast.BroadcastMessage\x1a\".atomicbroadcast.BroadcastResponse\"\x00(\x01\x30\x01\x12Z\n\x0fSubmitBroadcast\x12!.atomicbroadcast.BroadcastMessage\x1a\".atomicbroadcast.BroadcastResponse\"\x00\x12`\n\x0e\x42roadcastBatch\x12\x1f.atomicbroadcast.BroadcastBatch\x1a\'.atomicbroadcast.BroadcastBatchResponse\"\x00(\x01\x30\x01\x12Q\n\x07\x44\x65liver\x12\x1e.atomicbroadcast.DeliverUpdate\x1a .atomicbroadcast.DeliverResponse\"\x00(\x01\x30\x01\x32\x82\x02\n\x05\x41\x64min\x12H\n\x05Pause\x12\x1d.atomicbroadcast.PauseRequest\x1a\x1e.atomicbroadcast.AdminResponse\"\x00\x12J\n\x06Resume\x12\x1e.atomicbroadcast.ResumeRequest\x1a\x1e.atomicbroadcast.AdminResponse\"\x00\x12\x63\n\x0eValidateConfig\x12&.atomicbroadcast.ConfigurationEnvelope\x1a\'.atomicbroadcast.ValidateConfigResponse\"\x00\x62\x06proto3')
 )
 _sym_db.RegisterFileDescriptor(DESCRIPTOR)
 
@@ -47,24 +47,203 @@ _STATUS = _descriptor.EnumDescriptor(
       options=None,
       type=None),
     _descriptor.EnumValueDescriptor(
-      name='SERVICE_UNAVAILABLE', index=4, number=503,
+      name='REQUEST_TIMEOUT', index=4, number=408,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='PAYLOAD_TOO_LARGE', index=5, number=413,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='TOO_MANY_REQUESTS', index=6, number=429,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='SERVICE_UNAVAILABLE', index=7, number=503,
       options=None,
       type=None),
   ],
   containing_type=None,
   options=None,
-  serialized_start=672,
-  serialized_end=769,
+  serialized_start=4264,
+  serialized_end=4431,
 )
 _sym_db.RegisterEnumDescriptor(_STATUS)
 
 Status = enum_type_wrapper.EnumTypeWrapper(_STATUS)
+_FEATURE = _descriptor.EnumDescriptor(
+  name='Feature',
+  full_name='atomicbroadcast.Feature',
+  filename=None,
+  file=DESCRIPTOR,
+  values=[
+    _descriptor.EnumValueDescriptor(
+      name='NO_FEATURE', index=0, number=0,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='CHAIN_ROUTING', index=1, number=1,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='DELIVER_ACKS', index=2, number=2,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='FILTERED_DELIVERY', index=3, number=3,
+      options=None,
+      type=None),
+  ],
+  containing_type=None,
+  options=None,
+  serialized_start=4433,
+  serialized_end=4518,
+)
+_sym_db.RegisterEnumDescriptor(_FEATURE)
+
+Feature = enum_type_wrapper.EnumTypeWrapper(_FEATURE)
+_BLOCKMETADATAINDEX = _descriptor.EnumDescriptor(
+  name='BlockMetadataIndex',
+  full_name='atomicbroadcast.BlockMetadataIndex',
+  filename=None,
+  file=DESCRIPTOR,
+  values=[
+    _descriptor.EnumValueDescriptor(
+      name='PROOF', index=0, number=0,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='SIGNATURES', index=1, number=1,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='LAST_CONFIGURATION', index=2, number=2,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='TIMESTAMP', index=3, number=3,
+      options=None,
+      type=None),
+  ],
+  containing_type=None,
+  options=None,
+  serialized_start=4520,
+  serialized_end=4606,
+)
+_sym_db.RegisterEnumDescriptor(_BLOCKMETADATAINDEX)
+
+BlockMetadataIndex = enum_type_wrapper.EnumTypeWrapper(_BLOCKMETADATAINDEX)
 SUCCESS = 0
 BAD_REQUEST = 400
 FORBIDDEN = 403
 NOT_FOUND = 404
+REQUEST_TIMEOUT = 408
+PAYLOAD_TOO_LARGE = 413
+TOO_MANY_REQUESTS = 429
 SERVICE_UNAVAILABLE = 503
+NO_FEATURE = 0
+CHAIN_ROUTING = 1
+DELIVER_ACKS = 2
+FILTERED_DELIVERY = 3
+PROOF = 0
+SIGNATURES = 1
+LAST_CONFIGURATION = 2
+TIMESTAMP = 3
+
+
+_KAFKAMESSAGE_TYPE = _descriptor.EnumDescriptor(
+  name='Type',
+  full_name='atomicbroadcast.KafkaMessage.Type',
+  filename=None,
+  file=DESCRIPTOR,
+  values=[
+    _descriptor.EnumValueDescriptor(
+      name='REGULAR', index=0, number=0,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='TIME_TO_CUT', index=1, number=1,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='CONNECT', index=2, number=2,
+      options=None,
+      type=None),
+  ],
+  containing_type=None,
+  options=None,
+  serialized_start=626,
+  serialized_end=675,
+)
+_sym_db.RegisterEnumDescriptor(_KAFKAMESSAGE_TYPE)
+
+_CONFIGURATION_CONFIGURATIONTYPE = _descriptor.EnumDescriptor(
+  name='ConfigurationType',
+  full_name='atomicbroadcast.Configuration.ConfigurationType',
+  filename=None,
+  file=DESCRIPTOR,
+  values=[
+    _descriptor.EnumValueDescriptor(
+      name='Policy', index=0, number=0,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='Fabric', index=1, number=1,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='Chain', index=2, number=2,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='Solo', index=3, number=3,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='Kafka', index=4, number=4,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='PBFT', index=5, number=5,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='Orderer', index=6, number=6,
+      options=None,
+      type=None),
+  ],
+  containing_type=None,
+  options=None,
+  serialized_start=1355,
+  serialized_end=1453,
+)
+_sym_db.RegisterEnumDescriptor(_CONFIGURATION_CONFIGURATIONTYPE)
 
+_METAPOLICY_RULE = _descriptor.EnumDescriptor(
+  name='Rule',
+  full_name='atomicbroadcast.MetaPolicy.Rule',
+  filename=None,
+  file=DESCRIPTOR,
+  values=[
+    _descriptor.EnumValueDescriptor(
+      name='ANY', index=0, number=0,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='ALL', index=1, number=1,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='MAJORITY', index=2, number=2,
+      options=None,
+      type=None),
+  ],
+  containing_type=None,
+  options=None,
+  serialized_start=1886,
+  serialized_end=1924,
+)
+_sym_db.RegisterEnumDescriptor(_METAPOLICY_RULE)
 
 _SEEKINFO_STARTTYPE = _descriptor.EnumDescriptor(
   name='StartType',
@@ -84,14 +263,97 @@ _SEEKINFO_STARTTYPE = _descriptor.EnumDescriptor(
       name='SPECIFIED', index=2, number=2,
       options=None,
       type=None),
+    _descriptor.EnumValueDescriptor(
+      name='TIMESTAMP', index=3, number=3,
+      options=None,
+      type=None),
   ],
   containing_type=None,
   options=None,
-  serialized_start=235,
-  serialized_end=285,
+  serialized_start=2659,
+  serialized_end=2724,
 )
 _sym_db.RegisterEnumDescriptor(_SEEKINFO_STARTTYPE)
 
+_SEEKINFO_CONTENTTYPE = _descriptor.EnumDescriptor(
+  name='ContentType',
+  full_name='atomicbroadcast.SeekInfo.ContentType',
+  filename=None,
+  file=DESCRIPTOR,
+  values=[
+    _descriptor.EnumValueDescriptor(
+      name='FULL', index=0, number=0,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='HEADERS_ONLY', index=1, number=1,
+      options=None,
+      type=None),
+  ],
+  containing_type=None,
+  options=None,
+  serialized_start=2726,
+  serialized_end=2767,
+)
+_sym_db.RegisterEnumDescriptor(_SEEKINFO_CONTENTTYPE)
+
+_SEEKINFO_STOPTYPE = _descriptor.EnumDescriptor(
+  name='StopType',
+  full_name='atomicbroadcast.SeekInfo.StopType',
+  filename=None,
+  file=DESCRIPTOR,
+  values=[
+    _descriptor.EnumValueDescriptor(
+      name='UNBOUNDED', index=0, number=0,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='STOP_SPECIFIED', index=1, number=1,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='STOP_NEWEST', index=2, number=2,
+      options=None,
+      type=None),
+  ],
+  containing_type=None,
+  options=None,
+  serialized_start=2769,
+  serialized_end=2831,
+)
+_sym_db.RegisterEnumDescriptor(_SEEKINFO_STOPTYPE)
+
+
+_HELLO = _descriptor.Descriptor(
+  name='Hello',
+  full_name='atomicbroadcast.Hello',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Features', full_name='atomicbroadcast.Hello.Features', index=0,
+      number=1, type=14, cpp_type=8, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=29,
+  serialized_end=80,
+)
+
 
 _BROADCASTRESPONSE = _descriptor.Descriptor(
   name='BroadcastResponse',
@@ -107,6 +369,48 @@ _BROADCASTRESPONSE = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
+    _descriptor.FieldDescriptor(
+      name='BlockNumber', full_name='atomicbroadcast.BroadcastResponse.BlockNumber', index=1,
+      number=2, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Index', full_name='atomicbroadcast.BroadcastResponse.Index', index=2,
+      number=3, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='RetryAfter', full_name='atomicbroadcast.BroadcastResponse.RetryAfter', index=3,
+      number=4, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Info', full_name='atomicbroadcast.BroadcastResponse.Info', index=4,
+      number=5, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='CorrelationID', full_name='atomicbroadcast.BroadcastResponse.CorrelationID', index=5,
+      number=6, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Hello', full_name='atomicbroadcast.BroadcastResponse.Hello', index=6,
+      number=7, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
   ],
   extensions=[
   ],
@@ -119,8 +423,8 @@ _BROADCASTRESPONSE = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=29,
-  serialized_end=89,
+  serialized_start=83,
+  serialized_end=275,
 )
 
 
@@ -138,6 +442,27 @@ _BROADCASTMESSAGE = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
+    _descriptor.FieldDescriptor(
+      name='ChainID', full_name='atomicbroadcast.BroadcastMessage.ChainID', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='CorrelationID', full_name='atomicbroadcast.BroadcastMessage.CorrelationID', index=2,
+      number=3, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Hello', full_name='atomicbroadcast.BroadcastMessage.Hello', index=3,
+      number=4, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
   ],
   extensions=[
   ],
@@ -150,35 +475,1384 @@ _BROADCASTMESSAGE = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=91,
-  serialized_end=123,
+  serialized_start=277,
+  serialized_end=388,
 )
 
 
-_SEEKINFO = _descriptor.Descriptor(
-  name='SeekInfo',
-  full_name='atomicbroadcast.SeekInfo',
+_BROADCASTBATCH = _descriptor.Descriptor(
+  name='BroadcastBatch',
+  full_name='atomicbroadcast.BroadcastBatch',
   filename=None,
   file=DESCRIPTOR,
   containing_type=None,
   fields=[
     _descriptor.FieldDescriptor(
-      name='Start', full_name='atomicbroadcast.SeekInfo.Start', index=0,
-      number=1, type=14, cpp_type=8, label=1,
-      has_default_value=False, default_value=0,
+      name='Messages', full_name='atomicbroadcast.BroadcastBatch.Messages', index=0,
+      number=1, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=390,
+  serialized_end=459,
+)
+
+
+_BROADCASTBATCHRESPONSE = _descriptor.Descriptor(
+  name='BroadcastBatchResponse',
+  full_name='atomicbroadcast.BroadcastBatchResponse',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Responses', full_name='atomicbroadcast.BroadcastBatchResponse.Responses', index=0,
+      number=1, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=461,
+  serialized_end=540,
+)
+
+
+_KAFKAMESSAGE = _descriptor.Descriptor(
+  name='KafkaMessage',
+  full_name='atomicbroadcast.KafkaMessage',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Type', full_name='atomicbroadcast.KafkaMessage.Type', index=0,
+      number=1, type=14, cpp_type=8, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Payload', full_name='atomicbroadcast.KafkaMessage.Payload', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+    _KAFKAMESSAGE_TYPE,
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=543,
+  serialized_end=675,
+)
+
+
+_SIGNEDDATA = _descriptor.Descriptor(
+  name='SignedData',
+  full_name='atomicbroadcast.SignedData',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='PayloadEnvelope', full_name='atomicbroadcast.SignedData.PayloadEnvelope', index=0,
+      number=1, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Signature', full_name='atomicbroadcast.SignedData.Signature', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=677,
+  serialized_end=733,
+)
+
+
+_PAYLOADENVELOPE = _descriptor.Descriptor(
+  name='PayloadEnvelope',
+  full_name='atomicbroadcast.PayloadEnvelope',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Payload', full_name='atomicbroadcast.PayloadEnvelope.Payload', index=0,
+      number=1, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Signer', full_name='atomicbroadcast.PayloadEnvelope.Signer', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=735,
+  serialized_end=785,
+)
+
+
+_TRANSACTION = _descriptor.Descriptor(
+  name='Transaction',
+  full_name='atomicbroadcast.Transaction',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Opaque', full_name='atomicbroadcast.Transaction.Opaque', index=0,
+      number=1, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='ConfigurationEnvelope', full_name='atomicbroadcast.Transaction.ConfigurationEnvelope', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='CreateChain', full_name='atomicbroadcast.Transaction.CreateChain', index=2,
+      number=4, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Signatures', full_name='atomicbroadcast.Transaction.Signatures', index=3,
+      number=3, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+    _descriptor.OneofDescriptor(
+      name='Type', full_name='atomicbroadcast.Transaction.Type',
+      index=0, containing_type=None, fields=[]),
+  ],
+  serialized_start=788,
+  serialized_end=932,
+)
+
+
+_CONFIGURATIONENVELOPE = _descriptor.Descriptor(
+  name='ConfigurationEnvelope',
+  full_name='atomicbroadcast.ConfigurationEnvelope',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Sequence', full_name='atomicbroadcast.ConfigurationEnvelope.Sequence', index=0,
+      number=1, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='ChainID', full_name='atomicbroadcast.ConfigurationEnvelope.ChainID', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Entries', full_name='atomicbroadcast.ConfigurationEnvelope.Entries', index=2,
+      number=3, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='NotBefore', full_name='atomicbroadcast.ConfigurationEnvelope.NotBefore', index=3,
+      number=4, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='NotAfter', full_name='atomicbroadcast.ConfigurationEnvelope.NotAfter', index=4,
+      number=5, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=935,
+  serialized_end=1084,
+)
+
+
+_CONFIGURATIONENTRY = _descriptor.Descriptor(
+  name='ConfigurationEntry',
+  full_name='atomicbroadcast.ConfigurationEntry',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Configuration', full_name='atomicbroadcast.ConfigurationEntry.Configuration', index=0,
+      number=1, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Signatures', full_name='atomicbroadcast.ConfigurationEntry.Signatures', index=1,
+      number=2, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1086,
+  serialized_end=1178,
+)
+
+
+_CONFIGURATION = _descriptor.Descriptor(
+  name='Configuration',
+  full_name='atomicbroadcast.Configuration',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='ChainID', full_name='atomicbroadcast.Configuration.ChainID', index=0,
+      number=1, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='ID', full_name='atomicbroadcast.Configuration.ID', index=1,
+      number=2, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='LastModified', full_name='atomicbroadcast.Configuration.LastModified', index=2,
+      number=3, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Type', full_name='atomicbroadcast.Configuration.Type', index=3,
+      number=4, type=14, cpp_type=8, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Data', full_name='atomicbroadcast.Configuration.Data', index=4,
+      number=5, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='ModificationPolicy', full_name='atomicbroadcast.Configuration.ModificationPolicy', index=5,
+      number=6, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+    _CONFIGURATION_CONFIGURATIONTYPE,
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1181,
+  serialized_end=1453,
+)
+
+
+_BATCHSIZE = _descriptor.Descriptor(
+  name='BatchSize',
+  full_name='atomicbroadcast.BatchSize',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Messages', full_name='atomicbroadcast.BatchSize.Messages', index=0,
+      number=1, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='MaxBytes', full_name='atomicbroadcast.BatchSize.MaxBytes', index=1,
+      number=2, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='MaxMessageBytes', full_name='atomicbroadcast.BatchSize.MaxMessageBytes', index=2,
+      number=3, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1455,
+  serialized_end=1527,
+)
+
+
+_BATCHMAXBYTES = _descriptor.Descriptor(
+  name='BatchMaxBytes',
+  full_name='atomicbroadcast.BatchMaxBytes',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='MaxBytes', full_name='atomicbroadcast.BatchMaxBytes.MaxBytes', index=0,
+      number=1, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1529,
+  serialized_end=1562,
+)
+
+
+_BATCHTIMEOUT = _descriptor.Descriptor(
+  name='BatchTimeout',
+  full_name='atomicbroadcast.BatchTimeout',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Timeout', full_name='atomicbroadcast.BatchTimeout.Timeout', index=0,
+      number=1, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1564,
+  serialized_end=1595,
+)
+
+
+_POLICY = _descriptor.Descriptor(
+  name='Policy',
+  full_name='atomicbroadcast.Policy',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='SignaturePolicy', full_name='atomicbroadcast.Policy.SignaturePolicy', index=0,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='SignatureThreshold', full_name='atomicbroadcast.Policy.SignatureThreshold', index=1,
+      number=3, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Meta', full_name='atomicbroadcast.Policy.Meta', index=2,
+      number=4, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+    _descriptor.OneofDescriptor(
+      name='Type', full_name='atomicbroadcast.Policy.Type',
+      index=0, containing_type=None, fields=[]),
+  ],
+  serialized_start=1598,
+  serialized_end=1801,
+)
+
+
+_METAPOLICY = _descriptor.Descriptor(
+  name='MetaPolicy',
+  full_name='atomicbroadcast.MetaPolicy',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Rule', full_name='atomicbroadcast.MetaPolicy.Rule', index=0,
+      number=1, type=14, cpp_type=8, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='SubPolicies', full_name='atomicbroadcast.MetaPolicy.SubPolicies', index=1,
+      number=2, type=9, cpp_type=9, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+    _METAPOLICY_RULE,
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1803,
+  serialized_end=1924,
+)
+
+
+_SIGNATURETHRESHOLDPOLICY = _descriptor.Descriptor(
+  name='SignatureThresholdPolicy',
+  full_name='atomicbroadcast.SignatureThresholdPolicy',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='N', full_name='atomicbroadcast.SignatureThresholdPolicy.N', index=0,
+      number=1, type=5, cpp_type=1, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Identities', full_name='atomicbroadcast.SignatureThresholdPolicy.Identities', index=1,
+      number=2, type=12, cpp_type=9, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1926,
+  serialized_end=1983,
+)
+
+
+_SIGNATUREPOLICYENVELOPE = _descriptor.Descriptor(
+  name='SignaturePolicyEnvelope',
+  full_name='atomicbroadcast.SignaturePolicyEnvelope',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Version', full_name='atomicbroadcast.SignaturePolicyEnvelope.Version', index=0,
+      number=1, type=5, cpp_type=1, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Policy', full_name='atomicbroadcast.SignaturePolicyEnvelope.Policy', index=1,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Identities', full_name='atomicbroadcast.SignaturePolicyEnvelope.Identities', index=2,
+      number=3, type=12, cpp_type=9, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1985,
+  serialized_end=2097,
+)
+
+
+_SIGNATUREPOLICY_NOUTOF = _descriptor.Descriptor(
+  name='NOutOf',
+  full_name='atomicbroadcast.SignaturePolicy.NOutOf',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='N', full_name='atomicbroadcast.SignaturePolicy.NOutOf.N', index=0,
+      number=1, type=5, cpp_type=1, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Policies', full_name='atomicbroadcast.SignaturePolicy.NOutOf.Policies', index=1,
+      number=2, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2196,
+  serialized_end=2267,
+)
+
+_SIGNATUREPOLICY = _descriptor.Descriptor(
+  name='SignaturePolicy',
+  full_name='atomicbroadcast.SignaturePolicy',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='SignedBy', full_name='atomicbroadcast.SignaturePolicy.SignedBy', index=0,
+      number=1, type=5, cpp_type=1, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='From', full_name='atomicbroadcast.SignaturePolicy.From', index=1,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[_SIGNATUREPOLICY_NOUTOF, ],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+    _descriptor.OneofDescriptor(
+      name='Type', full_name='atomicbroadcast.SignaturePolicy.Type',
+      index=0, containing_type=None, fields=[]),
+  ],
+  serialized_start=2100,
+  serialized_end=2275,
+)
+
+
+_SEEKINFO = _descriptor.Descriptor(
+  name='SeekInfo',
+  full_name='atomicbroadcast.SeekInfo',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Start', full_name='atomicbroadcast.SeekInfo.Start', index=0,
+      number=1, type=14, cpp_type=8, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='SpecifiedNumber', full_name='atomicbroadcast.SeekInfo.SpecifiedNumber', index=1,
+      number=2, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='WindowSize', full_name='atomicbroadcast.SeekInfo.WindowSize', index=2,
+      number=3, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Content', full_name='atomicbroadcast.SeekInfo.Content', index=3,
+      number=4, type=14, cpp_type=8, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='ChainID', full_name='atomicbroadcast.SeekInfo.ChainID', index=4,
+      number=5, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Cursor', full_name='atomicbroadcast.SeekInfo.Cursor', index=5,
+      number=6, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Stop', full_name='atomicbroadcast.SeekInfo.Stop', index=6,
+      number=7, type=14, cpp_type=8, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='StopNumber', full_name='atomicbroadcast.SeekInfo.StopNumber', index=7,
+      number=8, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='WaitForStop', full_name='atomicbroadcast.SeekInfo.WaitForStop', index=8,
+      number=9, type=8, cpp_type=7, label=1,
+      has_default_value=False, default_value=False,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='StartTimestamp', full_name='atomicbroadcast.SeekInfo.StartTimestamp', index=9,
+      number=10, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Signatures', full_name='atomicbroadcast.SeekInfo.Signatures', index=10,
+      number=11, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Timestamp', full_name='atomicbroadcast.SeekInfo.Timestamp', index=11,
+      number=12, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+    _SEEKINFO_STARTTYPE,
+    _SEEKINFO_CONTENTTYPE,
+    _SEEKINFO_STOPTYPE,
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2278,
+  serialized_end=2831,
+)
+
+
+_ACKNOWLEDGEMENT = _descriptor.Descriptor(
+  name='Acknowledgement',
+  full_name='atomicbroadcast.Acknowledgement',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Number', full_name='atomicbroadcast.Acknowledgement.Number', index=0,
+      number=1, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2833,
+  serialized_end=2866,
+)
+
+
+_WINDOWUPDATE = _descriptor.Descriptor(
+  name='WindowUpdate',
+  full_name='atomicbroadcast.WindowUpdate',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='WindowSize', full_name='atomicbroadcast.WindowUpdate.WindowSize', index=0,
+      number=1, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2868,
+  serialized_end=2902,
+)
+
+
+_DELIVERUPDATE = _descriptor.Descriptor(
+  name='DeliverUpdate',
+  full_name='atomicbroadcast.DeliverUpdate',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Acknowledgement', full_name='atomicbroadcast.DeliverUpdate.Acknowledgement', index=0,
+      number=1, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Seek', full_name='atomicbroadcast.DeliverUpdate.Seek', index=1,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='WindowUpdate', full_name='atomicbroadcast.DeliverUpdate.WindowUpdate', index=2,
+      number=3, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Hello', full_name='atomicbroadcast.DeliverUpdate.Hello', index=3,
+      number=4, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+    _descriptor.OneofDescriptor(
+      name='Type', full_name='atomicbroadcast.DeliverUpdate.Type',
+      index=0, containing_type=None, fields=[]),
+  ],
+  serialized_start=2905,
+  serialized_end=3128,
+)
+
+
+_BLOCK = _descriptor.Descriptor(
+  name='Block',
+  full_name='atomicbroadcast.Block',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Header', full_name='atomicbroadcast.Block.Header', index=0,
+      number=1, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Data', full_name='atomicbroadcast.Block.Data', index=1,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Metadata', full_name='atomicbroadcast.Block.Metadata', index=2,
+      number=3, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=3131,
+  serialized_end=3276,
+)
+
+
+_BLOCKHEADER = _descriptor.Descriptor(
+  name='BlockHeader',
+  full_name='atomicbroadcast.BlockHeader',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Number', full_name='atomicbroadcast.BlockHeader.Number', index=0,
+      number=1, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='PreviousHash', full_name='atomicbroadcast.BlockHeader.PreviousHash', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='DataHash', full_name='atomicbroadcast.BlockHeader.DataHash', index=2,
+      number=3, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=3278,
+  serialized_end=3347,
+)
+
+
+_BLOCKDATA = _descriptor.Descriptor(
+  name='BlockData',
+  full_name='atomicbroadcast.BlockData',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Messages', full_name='atomicbroadcast.BlockData.Messages', index=0,
+      number=1, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=3349,
+  serialized_end=3413,
+)
+
+
+_BLOCKMETADATA = _descriptor.Descriptor(
+  name='BlockMetadata',
+  full_name='atomicbroadcast.BlockMetadata',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Metadata', full_name='atomicbroadcast.BlockMetadata.Metadata', index=0,
+      number=1, type=12, cpp_type=9, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=3415,
+  serialized_end=3448,
+)
+
+
+_BLOCKSIGNATURE = _descriptor.Descriptor(
+  name='BlockSignature',
+  full_name='atomicbroadcast.BlockSignature',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Identity', full_name='atomicbroadcast.BlockSignature.Identity', index=0,
+      number=1, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Signature', full_name='atomicbroadcast.BlockSignature.Signature', index=1,
+      number=2, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=3450,
+  serialized_end=3503,
+)
+
+
+_LEGACYBLOCK = _descriptor.Descriptor(
+  name='LegacyBlock',
+  full_name='atomicbroadcast.LegacyBlock',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Number', full_name='atomicbroadcast.LegacyBlock.Number', index=0,
+      number=2, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='PrevHash', full_name='atomicbroadcast.LegacyBlock.PrevHash', index=1,
+      number=3, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Proof', full_name='atomicbroadcast.LegacyBlock.Proof', index=2,
+      number=4, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Messages', full_name='atomicbroadcast.LegacyBlock.Messages', index=3,
+      number=5, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='DataHash', full_name='atomicbroadcast.LegacyBlock.DataHash', index=4,
+      number=6, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=3506,
+  serialized_end=3639,
+)
+
+
+_HEARTBEAT = _descriptor.Descriptor(
+  name='Heartbeat',
+  full_name='atomicbroadcast.Heartbeat',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Height', full_name='atomicbroadcast.Heartbeat.Height', index=0,
+      number=1, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=3641,
+  serialized_end=3668,
+)
+
+
+_DELIVERRESPONSE = _descriptor.Descriptor(
+  name='DeliverResponse',
+  full_name='atomicbroadcast.DeliverResponse',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='Error', full_name='atomicbroadcast.DeliverResponse.Error', index=0,
+      number=1, type=14, cpp_type=8, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Block', full_name='atomicbroadcast.DeliverResponse.Block', index=1,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Heartbeat', full_name='atomicbroadcast.DeliverResponse.Heartbeat', index=2,
+      number=3, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Hello', full_name='atomicbroadcast.DeliverResponse.Hello', index=3,
+      number=7, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Cursor', full_name='atomicbroadcast.DeliverResponse.Cursor', index=4,
+      number=4, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='RetryAfter', full_name='atomicbroadcast.DeliverResponse.RetryAfter', index=5,
+      number=5, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Info', full_name='atomicbroadcast.DeliverResponse.Info', index=6,
+      number=6, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+    _descriptor.OneofDescriptor(
+      name='Type', full_name='atomicbroadcast.DeliverResponse.Type',
+      index=0, containing_type=None, fields=[]),
+  ],
+  serialized_start=3671,
+  serialized_end=3919,
+)
+
+
+_CURSOR = _descriptor.Descriptor(
+  name='Cursor',
+  full_name='atomicbroadcast.Cursor',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='ChainID', full_name='atomicbroadcast.Cursor.ChainID', index=0,
+      number=1, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Number', full_name='atomicbroadcast.Cursor.Number', index=1,
+      number=2, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None),
+    _descriptor.FieldDescriptor(
+      name='Hash', full_name='atomicbroadcast.Cursor.Hash', index=2,
+      number=3, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b(""),
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     _descriptor.FieldDescriptor(
-      name='SpecifiedNumber', full_name='atomicbroadcast.SeekInfo.SpecifiedNumber', index=1,
-      number=2, type=4, cpp_type=4, label=1,
+      name='WindowSize', full_name='atomicbroadcast.Cursor.WindowSize', index=3,
+      number=4, type=4, cpp_type=4, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     _descriptor.FieldDescriptor(
-      name='WindowSize', full_name='atomicbroadcast.SeekInfo.WindowSize', index=2,
-      number=3, type=4, cpp_type=4, label=1,
+      name='Content', full_name='atomicbroadcast.Cursor.Content', index=4,
+      number=5, type=14, cpp_type=8, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
@@ -188,7 +1862,6 @@ _SEEKINFO = _descriptor.Descriptor(
   ],
   nested_types=[],
   enum_types=[
-    _SEEKINFO_STARTTYPE,
   ],
   options=None,
   is_extendable=False,
@@ -196,20 +1869,20 @@ _SEEKINFO = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=126,
-  serialized_end=285,
+  serialized_start=3922,
+  serialized_end=4053,
 )
 
 
-_ACKNOWLEDGEMENT = _descriptor.Descriptor(
-  name='Acknowledgement',
-  full_name='atomicbroadcast.Acknowledgement',
+_PAUSEREQUEST = _descriptor.Descriptor(
+  name='PauseRequest',
+  full_name='atomicbroadcast.PauseRequest',
   filename=None,
   file=DESCRIPTOR,
   containing_type=None,
   fields=[
     _descriptor.FieldDescriptor(
-      name='Number', full_name='atomicbroadcast.Acknowledgement.Number', index=0,
+      name='RetryAfter', full_name='atomicbroadcast.PauseRequest.RetryAfter', index=0,
       number=1, type=4, cpp_type=4, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
@@ -227,32 +1900,18 @@ _ACKNOWLEDGEMENT = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=287,
-  serialized_end=320,
+  serialized_start=4055,
+  serialized_end=4089,
 )
 
 
-_DELIVERUPDATE = _descriptor.Descriptor(
-  name='DeliverUpdate',
-  full_name='atomicbroadcast.DeliverUpdate',
+_RESUMEREQUEST = _descriptor.Descriptor(
+  name='ResumeRequest',
+  full_name='atomicbroadcast.ResumeRequest',
   filename=None,
   file=DESCRIPTOR,
   containing_type=None,
   fields=[
-    _descriptor.FieldDescriptor(
-      name='Acknowledgement', full_name='atomicbroadcast.DeliverUpdate.Acknowledgement', index=0,
-      number=1, type=11, cpp_type=10, label=1,
-      has_default_value=False, default_value=None,
-      message_type=None, enum_type=None, containing_type=None,
-      is_extension=False, extension_scope=None,
-      options=None),
-    _descriptor.FieldDescriptor(
-      name='Seek', full_name='atomicbroadcast.DeliverUpdate.Seek', index=1,
-      number=2, type=11, cpp_type=10, label=1,
-      has_default_value=False, default_value=None,
-      message_type=None, enum_type=None, containing_type=None,
-      is_extension=False, extension_scope=None,
-      options=None),
   ],
   extensions=[
   ],
@@ -264,47 +1923,30 @@ _DELIVERUPDATE = _descriptor.Descriptor(
   syntax='proto3',
   extension_ranges=[],
   oneofs=[
-    _descriptor.OneofDescriptor(
-      name='Type', full_name='atomicbroadcast.DeliverUpdate.Type',
-      index=0, containing_type=None, fields=[]),
   ],
-  serialized_start=322,
-  serialized_end=449,
+  serialized_start=4091,
+  serialized_end=4106,
 )
 
 
-_BLOCK = _descriptor.Descriptor(
-  name='Block',
-  full_name='atomicbroadcast.Block',
+_ADMINRESPONSE = _descriptor.Descriptor(
+  name='AdminResponse',
+  full_name='atomicbroadcast.AdminResponse',
   filename=None,
   file=DESCRIPTOR,
   containing_type=None,
   fields=[
     _descriptor.FieldDescriptor(
-      name='Number', full_name='atomicbroadcast.Block.Number', index=0,
-      number=2, type=4, cpp_type=4, label=1,
+      name='Status', full_name='atomicbroadcast.AdminResponse.Status', index=0,
+      number=1, type=14, cpp_type=8, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     _descriptor.FieldDescriptor(
-      name='PrevHash', full_name='atomicbroadcast.Block.PrevHash', index=1,
-      number=3, type=12, cpp_type=9, label=1,
-      has_default_value=False, default_value=_b(""),
-      message_type=None, enum_type=None, containing_type=None,
-      is_extension=False, extension_scope=None,
-      options=None),
-    _descriptor.FieldDescriptor(
-      name='Proof', full_name='atomicbroadcast.Block.Proof', index=2,
-      number=4, type=12, cpp_type=9, label=1,
-      has_default_value=False, default_value=_b(""),
-      message_type=None, enum_type=None, containing_type=None,
-      is_extension=False, extension_scope=None,
-      options=None),
-    _descriptor.FieldDescriptor(
-      name='Messages', full_name='atomicbroadcast.Block.Messages', index=3,
-      number=5, type=11, cpp_type=10, label=3,
-      has_default_value=False, default_value=[],
+      name='Paused', full_name='atomicbroadcast.AdminResponse.Paused', index=1,
+      number=2, type=8, cpp_type=7, label=1,
+      has_default_value=False, default_value=False,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
@@ -320,29 +1962,29 @@ _BLOCK = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=451,
-  serialized_end=560,
+  serialized_start=4108,
+  serialized_end=4180,
 )
 
 
-_DELIVERRESPONSE = _descriptor.Descriptor(
-  name='DeliverResponse',
-  full_name='atomicbroadcast.DeliverResponse',
+_VALIDATECONFIGRESPONSE = _descriptor.Descriptor(
+  name='ValidateConfigResponse',
+  full_name='atomicbroadcast.ValidateConfigResponse',
   filename=None,
   file=DESCRIPTOR,
   containing_type=None,
   fields=[
     _descriptor.FieldDescriptor(
-      name='Error', full_name='atomicbroadcast.DeliverResponse.Error', index=0,
+      name='Status', full_name='atomicbroadcast.ValidateConfigResponse.Status', index=0,
       number=1, type=14, cpp_type=8, label=1,
       has_default_value=False, default_value=0,
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
     _descriptor.FieldDescriptor(
-      name='Block', full_name='atomicbroadcast.DeliverResponse.Block', index=1,
-      number=2, type=11, cpp_type=10, label=1,
-      has_default_value=False, default_value=None,
+      name='Info', full_name='atomicbroadcast.ValidateConfigResponse.Info', index=1,
+      number=2, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None),
@@ -357,42 +1999,151 @@ _DELIVERRESPONSE = _descriptor.Descriptor(
   syntax='proto3',
   extension_ranges=[],
   oneofs=[
-    _descriptor.OneofDescriptor(
-      name='Type', full_name='atomicbroadcast.DeliverResponse.Type',
-      index=0, containing_type=None, fields=[]),
   ],
-  serialized_start=562,
-  serialized_end=670,
+  serialized_start=4182,
+  serialized_end=4261,
 )
 
+_HELLO.fields_by_name['Features'].enum_type = _FEATURE
 _BROADCASTRESPONSE.fields_by_name['Status'].enum_type = _STATUS
+_BROADCASTRESPONSE.fields_by_name['Hello'].message_type = _HELLO
+_BROADCASTMESSAGE.fields_by_name['Hello'].message_type = _HELLO
+_BROADCASTBATCH.fields_by_name['Messages'].message_type = _BROADCASTMESSAGE
+_BROADCASTBATCHRESPONSE.fields_by_name['Responses'].message_type = _BROADCASTRESPONSE
+_KAFKAMESSAGE.fields_by_name['Type'].enum_type = _KAFKAMESSAGE_TYPE
+_KAFKAMESSAGE_TYPE.containing_type = _KAFKAMESSAGE
+_TRANSACTION.fields_by_name['Signatures'].message_type = _SIGNEDDATA
+_TRANSACTION.oneofs_by_name['Type'].fields.append(
+  _TRANSACTION.fields_by_name['Opaque'])
+_TRANSACTION.fields_by_name['Opaque'].containing_oneof = _TRANSACTION.oneofs_by_name['Type']
+_TRANSACTION.oneofs_by_name['Type'].fields.append(
+  _TRANSACTION.fields_by_name['ConfigurationEnvelope'])
+_TRANSACTION.fields_by_name['ConfigurationEnvelope'].containing_oneof = _TRANSACTION.oneofs_by_name['Type']
+_TRANSACTION.oneofs_by_name['Type'].fields.append(
+  _TRANSACTION.fields_by_name['CreateChain'])
+_TRANSACTION.fields_by_name['CreateChain'].containing_oneof = _TRANSACTION.oneofs_by_name['Type']
+_CONFIGURATIONENVELOPE.fields_by_name['Entries'].message_type = _CONFIGURATIONENTRY
+_CONFIGURATIONENTRY.fields_by_name['Signatures'].message_type = _SIGNEDDATA
+_CONFIGURATION.fields_by_name['Type'].enum_type = _CONFIGURATION_CONFIGURATIONTYPE
+_CONFIGURATION_CONFIGURATIONTYPE.containing_type = _CONFIGURATION
+_POLICY.fields_by_name['SignaturePolicy'].message_type = _SIGNATUREPOLICYENVELOPE
+_POLICY.fields_by_name['SignatureThreshold'].message_type = _SIGNATURETHRESHOLDPOLICY
+_POLICY.fields_by_name['Meta'].message_type = _METAPOLICY
+_POLICY.oneofs_by_name['Type'].fields.append(
+  _POLICY.fields_by_name['SignaturePolicy'])
+_POLICY.fields_by_name['SignaturePolicy'].containing_oneof = _POLICY.oneofs_by_name['Type']
+_POLICY.oneofs_by_name['Type'].fields.append(
+  _POLICY.fields_by_name['SignatureThreshold'])
+_POLICY.fields_by_name['SignatureThreshold'].containing_oneof = _POLICY.oneofs_by_name['Type']
+_POLICY.oneofs_by_name['Type'].fields.append(
+  _POLICY.fields_by_name['Meta'])
+_POLICY.fields_by_name['Meta'].containing_oneof = _POLICY.oneofs_by_name['Type']
+_METAPOLICY.fields_by_name['Rule'].enum_type = _METAPOLICY_RULE
+_METAPOLICY_RULE.containing_type = _METAPOLICY
+_SIGNATUREPOLICYENVELOPE.fields_by_name['Policy'].message_type = _SIGNATUREPOLICY
+_SIGNATUREPOLICY_NOUTOF.fields_by_name['Policies'].message_type = _SIGNATUREPOLICY
+_SIGNATUREPOLICY_NOUTOF.containing_type = _SIGNATUREPOLICY
+_SIGNATUREPOLICY.fields_by_name['From'].message_type = _SIGNATUREPOLICY_NOUTOF
+_SIGNATUREPOLICY.oneofs_by_name['Type'].fields.append(
+  _SIGNATUREPOLICY.fields_by_name['SignedBy'])
+_SIGNATUREPOLICY.fields_by_name['SignedBy'].containing_oneof = _SIGNATUREPOLICY.oneofs_by_name['Type']
+_SIGNATUREPOLICY.oneofs_by_name['Type'].fields.append(
+  _SIGNATUREPOLICY.fields_by_name['From'])
+_SIGNATUREPOLICY.fields_by_name['From'].containing_oneof = _SIGNATUREPOLICY.oneofs_by_name['Type']
 _SEEKINFO.fields_by_name['Start'].enum_type = _SEEKINFO_STARTTYPE
+_SEEKINFO.fields_by_name['Content'].enum_type = _SEEKINFO_CONTENTTYPE
+_SEEKINFO.fields_by_name['Stop'].enum_type = _SEEKINFO_STOPTYPE
+_SEEKINFO.fields_by_name['Signatures'].message_type = _SIGNEDDATA
 _SEEKINFO_STARTTYPE.containing_type = _SEEKINFO
+_SEEKINFO_CONTENTTYPE.containing_type = _SEEKINFO
+_SEEKINFO_STOPTYPE.containing_type = _SEEKINFO
 _DELIVERUPDATE.fields_by_name['Acknowledgement'].message_type = _ACKNOWLEDGEMENT
 _DELIVERUPDATE.fields_by_name['Seek'].message_type = _SEEKINFO
+_DELIVERUPDATE.fields_by_name['WindowUpdate'].message_type = _WINDOWUPDATE
+_DELIVERUPDATE.fields_by_name['Hello'].message_type = _HELLO
 _DELIVERUPDATE.oneofs_by_name['Type'].fields.append(
   _DELIVERUPDATE.fields_by_name['Acknowledgement'])
 _DELIVERUPDATE.fields_by_name['Acknowledgement'].containing_oneof = _DELIVERUPDATE.oneofs_by_name['Type']
 _DELIVERUPDATE.oneofs_by_name['Type'].fields.append(
   _DELIVERUPDATE.fields_by_name['Seek'])
 _DELIVERUPDATE.fields_by_name['Seek'].containing_oneof = _DELIVERUPDATE.oneofs_by_name['Type']
-_BLOCK.fields_by_name['Messages'].message_type = _BROADCASTMESSAGE
+_DELIVERUPDATE.oneofs_by_name['Type'].fields.append(
+  _DELIVERUPDATE.fields_by_name['WindowUpdate'])
+_DELIVERUPDATE.fields_by_name['WindowUpdate'].containing_oneof = _DELIVERUPDATE.oneofs_by_name['Type']
+_DELIVERUPDATE.oneofs_by_name['Type'].fields.append(
+  _DELIVERUPDATE.fields_by_name['Hello'])
+_DELIVERUPDATE.fields_by_name['Hello'].containing_oneof = _DELIVERUPDATE.oneofs_by_name['Type']
+_BLOCK.fields_by_name['Header'].message_type = _BLOCKHEADER
+_BLOCK.fields_by_name['Data'].message_type = _BLOCKDATA
+_BLOCK.fields_by_name['Metadata'].message_type = _BLOCKMETADATA
+_BLOCKDATA.fields_by_name['Messages'].message_type = _BROADCASTMESSAGE
+_LEGACYBLOCK.fields_by_name['Messages'].message_type = _BROADCASTMESSAGE
 _DELIVERRESPONSE.fields_by_name['Error'].enum_type = _STATUS
 _DELIVERRESPONSE.fields_by_name['Block'].message_type = _BLOCK
+_DELIVERRESPONSE.fields_by_name['Heartbeat'].message_type = _HEARTBEAT
+_DELIVERRESPONSE.fields_by_name['Hello'].message_type = _HELLO
 _DELIVERRESPONSE.oneofs_by_name['Type'].fields.append(
   _DELIVERRESPONSE.fields_by_name['Error'])
 _DELIVERRESPONSE.fields_by_name['Error'].containing_oneof = _DELIVERRESPONSE.oneofs_by_name['Type']
 _DELIVERRESPONSE.oneofs_by_name['Type'].fields.append(
   _DELIVERRESPONSE.fields_by_name['Block'])
 _DELIVERRESPONSE.fields_by_name['Block'].containing_oneof = _DELIVERRESPONSE.oneofs_by_name['Type']
+_DELIVERRESPONSE.oneofs_by_name['Type'].fields.append(
+  _DELIVERRESPONSE.fields_by_name['Heartbeat'])
+_DELIVERRESPONSE.fields_by_name['Heartbeat'].containing_oneof = _DELIVERRESPONSE.oneofs_by_name['Type']
+_DELIVERRESPONSE.oneofs_by_name['Type'].fields.append(
+  _DELIVERRESPONSE.fields_by_name['Hello'])
+_DELIVERRESPONSE.fields_by_name['Hello'].containing_oneof = _DELIVERRESPONSE.oneofs_by_name['Type']
+_CURSOR.fields_by_name['Content'].enum_type = _SEEKINFO_CONTENTTYPE
+_ADMINRESPONSE.fields_by_name['Status'].enum_type = _STATUS
+_VALIDATECONFIGRESPONSE.fields_by_name['Status'].enum_type = _STATUS
+DESCRIPTOR.message_types_by_name['Hello'] = _HELLO
 DESCRIPTOR.message_types_by_name['BroadcastResponse'] = _BROADCASTRESPONSE
 DESCRIPTOR.message_types_by_name['BroadcastMessage'] = _BROADCASTMESSAGE
+DESCRIPTOR.message_types_by_name['BroadcastBatch'] = _BROADCASTBATCH
+DESCRIPTOR.message_types_by_name['BroadcastBatchResponse'] = _BROADCASTBATCHRESPONSE
+DESCRIPTOR.message_types_by_name['KafkaMessage'] = _KAFKAMESSAGE
+DESCRIPTOR.message_types_by_name['SignedData'] = _SIGNEDDATA
+DESCRIPTOR.message_types_by_name['PayloadEnvelope'] = _PAYLOADENVELOPE
+DESCRIPTOR.message_types_by_name['Transaction'] = _TRANSACTION
+DESCRIPTOR.message_types_by_name['ConfigurationEnvelope'] = _CONFIGURATIONENVELOPE
+DESCRIPTOR.message_types_by_name['ConfigurationEntry'] = _CONFIGURATIONENTRY
+DESCRIPTOR.message_types_by_name['Configuration'] = _CONFIGURATION
+DESCRIPTOR.message_types_by_name['BatchSize'] = _BATCHSIZE
+DESCRIPTOR.message_types_by_name['BatchMaxBytes'] = _BATCHMAXBYTES
+DESCRIPTOR.message_types_by_name['BatchTimeout'] = _BATCHTIMEOUT
+DESCRIPTOR.message_types_by_name['Policy'] = _POLICY
+DESCRIPTOR.message_types_by_name['MetaPolicy'] = _METAPOLICY
+DESCRIPTOR.message_types_by_name['SignatureThresholdPolicy'] = _SIGNATURETHRESHOLDPOLICY
+DESCRIPTOR.message_types_by_name['SignaturePolicyEnvelope'] = _SIGNATUREPOLICYENVELOPE
+DESCRIPTOR.message_types_by_name['SignaturePolicy'] = _SIGNATUREPOLICY
 DESCRIPTOR.message_types_by_name['SeekInfo'] = _SEEKINFO
 DESCRIPTOR.message_types_by_name['Acknowledgement'] = _ACKNOWLEDGEMENT
+DESCRIPTOR.message_types_by_name['WindowUpdate'] = _WINDOWUPDATE
 DESCRIPTOR.message_types_by_name['DeliverUpdate'] = _DELIVERUPDATE
 DESCRIPTOR.message_types_by_name['Block'] = _BLOCK
+DESCRIPTOR.message_types_by_name['BlockHeader'] = _BLOCKHEADER
+DESCRIPTOR.message_types_by_name['BlockData'] = _BLOCKDATA
+DESCRIPTOR.message_types_by_name['BlockMetadata'] = _BLOCKMETADATA
+DESCRIPTOR.message_types_by_name['BlockSignature'] = _BLOCKSIGNATURE
+DESCRIPTOR.message_types_by_name['LegacyBlock'] = _LEGACYBLOCK
+DESCRIPTOR.message_types_by_name['Heartbeat'] = _HEARTBEAT
 DESCRIPTOR.message_types_by_name['DeliverResponse'] = _DELIVERRESPONSE
+DESCRIPTOR.message_types_by_name['Cursor'] = _CURSOR
+DESCRIPTOR.message_types_by_name['PauseRequest'] = _PAUSEREQUEST
+DESCRIPTOR.message_types_by_name['ResumeRequest'] = _RESUMEREQUEST
+DESCRIPTOR.message_types_by_name['AdminResponse'] = _ADMINRESPONSE
+DESCRIPTOR.message_types_by_name['ValidateConfigResponse'] = _VALIDATECONFIGRESPONSE
 DESCRIPTOR.enum_types_by_name['Status'] = _STATUS
+DESCRIPTOR.enum_types_by_name['Feature'] = _FEATURE
+DESCRIPTOR.enum_types_by_name['BlockMetadataIndex'] = _BLOCKMETADATAINDEX
+
+Hello = _reflection.GeneratedProtocolMessageType('Hello', (_message.Message,), dict(
+  DESCRIPTOR = _HELLO,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.Hello)
+  ))
+_sym_db.RegisterMessage(Hello)
 
 BroadcastResponse = _reflection.GeneratedProtocolMessageType('BroadcastResponse', (_message.Message,), dict(
   DESCRIPTOR = _BROADCASTRESPONSE,
@@ -408,6 +2159,133 @@ BroadcastMessage = _reflection.GeneratedProtocolMessageType('BroadcastMessage',
   ))
 _sym_db.RegisterMessage(BroadcastMessage)
 
+BroadcastBatch = _reflection.GeneratedProtocolMessageType('BroadcastBatch', (_message.Message,), dict(
+  DESCRIPTOR = _BROADCASTBATCH,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BroadcastBatch)
+  ))
+_sym_db.RegisterMessage(BroadcastBatch)
+
+BroadcastBatchResponse = _reflection.GeneratedProtocolMessageType('BroadcastBatchResponse', (_message.Message,), dict(
+  DESCRIPTOR = _BROADCASTBATCHRESPONSE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BroadcastBatchResponse)
+  ))
+_sym_db.RegisterMessage(BroadcastBatchResponse)
+
+KafkaMessage = _reflection.GeneratedProtocolMessageType('KafkaMessage', (_message.Message,), dict(
+  DESCRIPTOR = _KAFKAMESSAGE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.KafkaMessage)
+  ))
+_sym_db.RegisterMessage(KafkaMessage)
+
+SignedData = _reflection.GeneratedProtocolMessageType('SignedData', (_message.Message,), dict(
+  DESCRIPTOR = _SIGNEDDATA,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.SignedData)
+  ))
+_sym_db.RegisterMessage(SignedData)
+
+PayloadEnvelope = _reflection.GeneratedProtocolMessageType('PayloadEnvelope', (_message.Message,), dict(
+  DESCRIPTOR = _PAYLOADENVELOPE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.PayloadEnvelope)
+  ))
+_sym_db.RegisterMessage(PayloadEnvelope)
+
+Transaction = _reflection.GeneratedProtocolMessageType('Transaction', (_message.Message,), dict(
+  DESCRIPTOR = _TRANSACTION,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.Transaction)
+  ))
+_sym_db.RegisterMessage(Transaction)
+
+ConfigurationEnvelope = _reflection.GeneratedProtocolMessageType('ConfigurationEnvelope', (_message.Message,), dict(
+  DESCRIPTOR = _CONFIGURATIONENVELOPE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.ConfigurationEnvelope)
+  ))
+_sym_db.RegisterMessage(ConfigurationEnvelope)
+
+ConfigurationEntry = _reflection.GeneratedProtocolMessageType('ConfigurationEntry', (_message.Message,), dict(
+  DESCRIPTOR = _CONFIGURATIONENTRY,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.ConfigurationEntry)
+  ))
+_sym_db.RegisterMessage(ConfigurationEntry)
+
+Configuration = _reflection.GeneratedProtocolMessageType('Configuration', (_message.Message,), dict(
+  DESCRIPTOR = _CONFIGURATION,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.Configuration)
+  ))
+_sym_db.RegisterMessage(Configuration)
+
+BatchSize = _reflection.GeneratedProtocolMessageType('BatchSize', (_message.Message,), dict(
+  DESCRIPTOR = _BATCHSIZE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BatchSize)
+  ))
+_sym_db.RegisterMessage(BatchSize)
+
+BatchMaxBytes = _reflection.GeneratedProtocolMessageType('BatchMaxBytes', (_message.Message,), dict(
+  DESCRIPTOR = _BATCHMAXBYTES,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BatchMaxBytes)
+  ))
+_sym_db.RegisterMessage(BatchMaxBytes)
+
+BatchTimeout = _reflection.GeneratedProtocolMessageType('BatchTimeout', (_message.Message,), dict(
+  DESCRIPTOR = _BATCHTIMEOUT,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BatchTimeout)
+  ))
+_sym_db.RegisterMessage(BatchTimeout)
+
+Policy = _reflection.GeneratedProtocolMessageType('Policy', (_message.Message,), dict(
+  DESCRIPTOR = _POLICY,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.Policy)
+  ))
+_sym_db.RegisterMessage(Policy)
+
+MetaPolicy = _reflection.GeneratedProtocolMessageType('MetaPolicy', (_message.Message,), dict(
+  DESCRIPTOR = _METAPOLICY,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.MetaPolicy)
+  ))
+_sym_db.RegisterMessage(MetaPolicy)
+
+SignatureThresholdPolicy = _reflection.GeneratedProtocolMessageType('SignatureThresholdPolicy', (_message.Message,), dict(
+  DESCRIPTOR = _SIGNATURETHRESHOLDPOLICY,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.SignatureThresholdPolicy)
+  ))
+_sym_db.RegisterMessage(SignatureThresholdPolicy)
+
+SignaturePolicyEnvelope = _reflection.GeneratedProtocolMessageType('SignaturePolicyEnvelope', (_message.Message,), dict(
+  DESCRIPTOR = _SIGNATUREPOLICYENVELOPE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.SignaturePolicyEnvelope)
+  ))
+_sym_db.RegisterMessage(SignaturePolicyEnvelope)
+
+SignaturePolicy = _reflection.GeneratedProtocolMessageType('SignaturePolicy', (_message.Message,), dict(
+
+  NOutOf = _reflection.GeneratedProtocolMessageType('NOutOf', (_message.Message,), dict(
+    DESCRIPTOR = _SIGNATUREPOLICY_NOUTOF,
+    __module__ = 'ab_pb2'
+    # @@protoc_insertion_point(class_scope:atomicbroadcast.SignaturePolicy.NOutOf)
+    ))
+  ,
+  DESCRIPTOR = _SIGNATUREPOLICY,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.SignaturePolicy)
+  ))
+_sym_db.RegisterMessage(SignaturePolicy)
+_sym_db.RegisterMessage(SignaturePolicy.NOutOf)
+
 SeekInfo = _reflection.GeneratedProtocolMessageType('SeekInfo', (_message.Message,), dict(
   DESCRIPTOR = _SEEKINFO,
   __module__ = 'ab_pb2'
@@ -422,6 +2300,13 @@ Acknowledgement = _reflection.GeneratedProtocolMessageType('Acknowledgement', (_
   ))
 _sym_db.RegisterMessage(Acknowledgement)
 
+WindowUpdate = _reflection.GeneratedProtocolMessageType('WindowUpdate', (_message.Message,), dict(
+  DESCRIPTOR = _WINDOWUPDATE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.WindowUpdate)
+  ))
+_sym_db.RegisterMessage(WindowUpdate)
+
 DeliverUpdate = _reflection.GeneratedProtocolMessageType('DeliverUpdate', (_message.Message,), dict(
   DESCRIPTOR = _DELIVERUPDATE,
   __module__ = 'ab_pb2'
@@ -436,6 +2321,48 @@ Block = _reflection.GeneratedProtocolMessageType('Block', (_message.Message,), d
   ))
 _sym_db.RegisterMessage(Block)
 
+BlockHeader = _reflection.GeneratedProtocolMessageType('BlockHeader', (_message.Message,), dict(
+  DESCRIPTOR = _BLOCKHEADER,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BlockHeader)
+  ))
+_sym_db.RegisterMessage(BlockHeader)
+
+BlockData = _reflection.GeneratedProtocolMessageType('BlockData', (_message.Message,), dict(
+  DESCRIPTOR = _BLOCKDATA,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BlockData)
+  ))
+_sym_db.RegisterMessage(BlockData)
+
+BlockMetadata = _reflection.GeneratedProtocolMessageType('BlockMetadata', (_message.Message,), dict(
+  DESCRIPTOR = _BLOCKMETADATA,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BlockMetadata)
+  ))
+_sym_db.RegisterMessage(BlockMetadata)
+
+BlockSignature = _reflection.GeneratedProtocolMessageType('BlockSignature', (_message.Message,), dict(
+  DESCRIPTOR = _BLOCKSIGNATURE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.BlockSignature)
+  ))
+_sym_db.RegisterMessage(BlockSignature)
+
+LegacyBlock = _reflection.GeneratedProtocolMessageType('LegacyBlock', (_message.Message,), dict(
+  DESCRIPTOR = _LEGACYBLOCK,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.LegacyBlock)
+  ))
+_sym_db.RegisterMessage(LegacyBlock)
+
+Heartbeat = _reflection.GeneratedProtocolMessageType('Heartbeat', (_message.Message,), dict(
+  DESCRIPTOR = _HEARTBEAT,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.Heartbeat)
+  ))
+_sym_db.RegisterMessage(Heartbeat)
+
 DeliverResponse = _reflection.GeneratedProtocolMessageType('DeliverResponse', (_message.Message,), dict(
   DESCRIPTOR = _DELIVERRESPONSE,
   __module__ = 'ab_pb2'
@@ -443,6 +2370,41 @@ DeliverResponse = _reflection.GeneratedProtocolMessageType('DeliverResponse', (_
   ))
 _sym_db.RegisterMessage(DeliverResponse)
 
+Cursor = _reflection.GeneratedProtocolMessageType('Cursor', (_message.Message,), dict(
+  DESCRIPTOR = _CURSOR,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.Cursor)
+  ))
+_sym_db.RegisterMessage(Cursor)
+
+PauseRequest = _reflection.GeneratedProtocolMessageType('PauseRequest', (_message.Message,), dict(
+  DESCRIPTOR = _PAUSEREQUEST,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.PauseRequest)
+  ))
+_sym_db.RegisterMessage(PauseRequest)
+
+ResumeRequest = _reflection.GeneratedProtocolMessageType('ResumeRequest', (_message.Message,), dict(
+  DESCRIPTOR = _RESUMEREQUEST,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.ResumeRequest)
+  ))
+_sym_db.RegisterMessage(ResumeRequest)
+
+AdminResponse = _reflection.GeneratedProtocolMessageType('AdminResponse', (_message.Message,), dict(
+  DESCRIPTOR = _ADMINRESPONSE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.AdminResponse)
+  ))
+_sym_db.RegisterMessage(AdminResponse)
+
+ValidateConfigResponse = _reflection.GeneratedProtocolMessageType('ValidateConfigResponse', (_message.Message,), dict(
+  DESCRIPTOR = _VALIDATECONFIGRESPONSE,
+  __module__ = 'ab_pb2'
+  # @@protoc_insertion_point(class_scope:atomicbroadcast.ValidateConfigResponse)
+  ))
+_sym_db.RegisterMessage(ValidateConfigResponse)
+
 
 import grpc
 from grpc.beta import implementations as beta_implementations
@@ -464,6 +2426,16 @@ class AtomicBroadcastStub(object):
         request_serializer=BroadcastMessage.SerializeToString,
         response_deserializer=BroadcastResponse.FromString,
         )
+    self.SubmitBroadcast = channel.unary_unary(
+        '/atomicbroadcast.AtomicBroadcast/SubmitBroadcast',
+        request_serializer=BroadcastMessage.SerializeToString,
+        response_deserializer=BroadcastResponse.FromString,
+        )
+    self.BroadcastBatch = channel.stream_stream(
+        '/atomicbroadcast.AtomicBroadcast/BroadcastBatch',
+        request_serializer=BroadcastBatch.SerializeToString,
+        response_deserializer=BroadcastBatchResponse.FromString,
+        )
     self.Deliver = channel.stream_stream(
         '/atomicbroadcast.AtomicBroadcast/Deliver',
         request_serializer=DeliverUpdate.SerializeToString,
@@ -480,6 +2452,21 @@ class AtomicBroadcastServicer(object):
     context.set_details('Method not implemented!')
     raise NotImplementedError('Method not implemented!')
 
+  def SubmitBroadcast(self, request, context):
+    """submitBroadcast orders a single message as if it were sent on its own Broadcast stream, replying as that stream would
+    When replies are sent once messages are committed, the call returns after the commit unless its deadline expires first
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+  def BroadcastBatch(self, request_iterator, context):
+    """broadcastBatch is Broadcast for clients which send many small messages, the messages of each batch are received as a stream of them would be
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
   def Deliver(self, request_iterator, context):
     """deliver first requires an update containing a seek message, then a stream of block replies is received.
     The receiver may choose to send an Acknowledgement for any block number it receives, however Acknowledgements must never be more than WindowSize apart
@@ -497,6 +2484,16 @@ def add_AtomicBroadcastServicer_to_server(servicer, server):
           request_deserializer=BroadcastMessage.FromString,
           response_serializer=BroadcastResponse.SerializeToString,
       ),
+      'SubmitBroadcast': grpc.unary_unary_rpc_method_handler(
+          servicer.SubmitBroadcast,
+          request_deserializer=BroadcastMessage.FromString,
+          response_serializer=BroadcastResponse.SerializeToString,
+      ),
+      'BroadcastBatch': grpc.stream_stream_rpc_method_handler(
+          servicer.BroadcastBatch,
+          request_deserializer=BroadcastBatch.FromString,
+          response_serializer=BroadcastBatchResponse.SerializeToString,
+      ),
       'Deliver': grpc.stream_stream_rpc_method_handler(
           servicer.Deliver,
           request_deserializer=DeliverUpdate.FromString,
@@ -513,6 +2510,15 @@ class BetaAtomicBroadcastServicer(object):
     """broadcast receives a reply of Acknowledgement for each BroadcastMessage in order, indicating success or type of failure
     """
     context.code(beta_interfaces.StatusCode.UNIMPLEMENTED)
+  def SubmitBroadcast(self, request, context):
+    """submitBroadcast orders a single message as if it were sent on its own Broadcast stream, replying as that stream would
+    When replies are sent once messages are committed, the call returns after the commit unless its deadline expires first
+    """
+    context.code(beta_interfaces.StatusCode.UNIMPLEMENTED)
+  def BroadcastBatch(self, request_iterator, context):
+    """broadcastBatch is Broadcast for clients which send many small messages, the messages of each batch are received as a stream of them would be
+    """
+    context.code(beta_interfaces.StatusCode.UNIMPLEMENTED)
   def Deliver(self, request_iterator, context):
     """deliver first requires an update containing a seek message, then a stream of block replies is received.
     The receiver may choose to send an Acknowledgement for any block number it receives, however Acknowledgements must never be more than WindowSize apart
@@ -526,6 +2532,16 @@ class BetaAtomicBroadcastStub(object):
     """broadcast receives a reply of Acknowledgement for each BroadcastMessage in order, indicating success or type of failure
     """
     raise NotImplementedError()
+  def SubmitBroadcast(self, request, timeout, metadata=None, with_call=False, protocol_options=None):
+    """submitBroadcast orders a single message as if it were sent on its own Broadcast stream, replying as that stream would
+    When replies are sent once messages are committed, the call returns after the commit unless its deadline expires first
+    """
+    raise NotImplementedError()
+  SubmitBroadcast.future = None
+  def BroadcastBatch(self, request_iterator, timeout, metadata=None, with_call=False, protocol_options=None):
+    """broadcastBatch is Broadcast for clients which send many small messages, the messages of each batch are received as a stream of them would be
+    """
+    raise NotImplementedError()
   def Deliver(self, request_iterator, timeout, metadata=None, with_call=False, protocol_options=None):
     """deliver first requires an update containing a seek message, then a stream of block replies is received.
     The receiver may choose to send an Acknowledgement for any block number it receives, however Acknowledgements must never be more than WindowSize apart
@@ -537,15 +2553,21 @@ class BetaAtomicBroadcastStub(object):
 def beta_create_AtomicBroadcast_server(servicer, pool=None, pool_size=None, default_timeout=None, maximum_timeout=None):
   request_deserializers = {
     ('atomicbroadcast.AtomicBroadcast', 'Broadcast'): BroadcastMessage.FromString,
+    ('atomicbroadcast.AtomicBroadcast', 'BroadcastBatch'): BroadcastBatch.FromString,
     ('atomicbroadcast.AtomicBroadcast', 'Deliver'): DeliverUpdate.FromString,
+    ('atomicbroadcast.AtomicBroadcast', 'SubmitBroadcast'): BroadcastMessage.FromString,
   }
   response_serializers = {
     ('atomicbroadcast.AtomicBroadcast', 'Broadcast'): BroadcastResponse.SerializeToString,
+    ('atomicbroadcast.AtomicBroadcast', 'BroadcastBatch'): BroadcastBatchResponse.SerializeToString,
     ('atomicbroadcast.AtomicBroadcast', 'Deliver'): DeliverResponse.SerializeToString,
+    ('atomicbroadcast.AtomicBroadcast', 'SubmitBroadcast'): BroadcastResponse.SerializeToString,
   }
   method_implementations = {
     ('atomicbroadcast.AtomicBroadcast', 'Broadcast'): face_utilities.stream_stream_inline(servicer.Broadcast),
+    ('atomicbroadcast.AtomicBroadcast', 'BroadcastBatch'): face_utilities.stream_stream_inline(servicer.BroadcastBatch),
     ('atomicbroadcast.AtomicBroadcast', 'Deliver'): face_utilities.stream_stream_inline(servicer.Deliver),
+    ('atomicbroadcast.AtomicBroadcast', 'SubmitBroadcast'): face_utilities.unary_unary_inline(servicer.SubmitBroadcast),
   }
   server_options = beta_implementations.server_options(request_deserializers=request_deserializers, response_serializers=response_serializers, thread_pool=pool, thread_pool_size=pool_size, default_timeout=default_timeout, maximum_timeout=maximum_timeout)
   return beta_implementations.server(method_implementations, options=server_options)
@@ -554,16 +2576,167 @@ def beta_create_AtomicBroadcast_server(servicer, pool=None, pool_size=None, defa
 def beta_create_AtomicBroadcast_stub(channel, host=None, metadata_transformer=None, pool=None, pool_size=None):
   request_serializers = {
     ('atomicbroadcast.AtomicBroadcast', 'Broadcast'): BroadcastMessage.SerializeToString,
+    ('atomicbroadcast.AtomicBroadcast', 'BroadcastBatch'): BroadcastBatch.SerializeToString,
     ('atomicbroadcast.AtomicBroadcast', 'Deliver'): DeliverUpdate.SerializeToString,
+    ('atomicbroadcast.AtomicBroadcast', 'SubmitBroadcast'): BroadcastMessage.SerializeToString,
   }
   response_deserializers = {
     ('atomicbroadcast.AtomicBroadcast', 'Broadcast'): BroadcastResponse.FromString,
+    ('atomicbroadcast.AtomicBroadcast', 'BroadcastBatch'): BroadcastBatchResponse.FromString,
     ('atomicbroadcast.AtomicBroadcast', 'Deliver'): DeliverResponse.FromString,
+    ('atomicbroadcast.AtomicBroadcast', 'SubmitBroadcast'): BroadcastResponse.FromString,
   }
   cardinalities = {
     'Broadcast': cardinality.Cardinality.STREAM_STREAM,
+    'BroadcastBatch': cardinality.Cardinality.STREAM_STREAM,
     'Deliver': cardinality.Cardinality.STREAM_STREAM,
+    'SubmitBroadcast': cardinality.Cardinality.UNARY_UNARY,
   }
   stub_options = beta_implementations.stub_options(host=host, metadata_transformer=metadata_transformer, request_serializers=request_serializers, response_deserializers=response_deserializers, thread_pool=pool, thread_pool_size=pool_size)
   return beta_implementations.dynamic_stub(channel, 'atomicbroadcast.AtomicBroadcast', cardinalities, options=stub_options)
+
+
+class AdminStub(object):
+
+  def __init__(self, channel):
+    """Constructor.
+
+    Args:
+      channel: A grpc.Channel.
+    """
+    self.Pause = channel.unary_unary(
+        '/atomicbroadcast.Admin/Pause',
+        request_serializer=PauseRequest.SerializeToString,
+        response_deserializer=AdminResponse.FromString,
+        )
+    self.Resume = channel.unary_unary(
+        '/atomicbroadcast.Admin/Resume',
+        request_serializer=ResumeRequest.SerializeToString,
+        response_deserializer=AdminResponse.FromString,
+        )
+    self.ValidateConfig = channel.unary_unary(
+        '/atomicbroadcast.Admin/ValidateConfig',
+        request_serializer=ConfigurationEnvelope.SerializeToString,
+        response_deserializer=ValidateConfigResponse.FromString,
+        )
+
+
+class AdminServicer(object):
+
+  def Pause(self, request, context):
+    """pause flushes any pending batch and then stops cutting blocks, broadcast messages are rejected with SERVICE_UNAVAILABLE while deliver continues normally
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+  def Resume(self, request, context):
+    """resume restarts the cutting of blocks
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+  def ValidateConfig(self, request, context):
+    """validateConfig checks a configuration envelope against the current configuration of the default chain, without applying it
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+
+def add_AdminServicer_to_server(servicer, server):
+  rpc_method_handlers = {
+      'Pause': grpc.unary_unary_rpc_method_handler(
+          servicer.Pause,
+          request_deserializer=PauseRequest.FromString,
+          response_serializer=AdminResponse.SerializeToString,
+      ),
+      'Resume': grpc.unary_unary_rpc_method_handler(
+          servicer.Resume,
+          request_deserializer=ResumeRequest.FromString,
+          response_serializer=AdminResponse.SerializeToString,
+      ),
+      'ValidateConfig': grpc.unary_unary_rpc_method_handler(
+          servicer.ValidateConfig,
+          request_deserializer=ConfigurationEnvelope.FromString,
+          response_serializer=ValidateConfigResponse.SerializeToString,
+      ),
+  }
+  generic_handler = grpc.method_handlers_generic_handler(
+      'atomicbroadcast.Admin', rpc_method_handlers)
+  server.add_generic_rpc_handlers((generic_handler,))
+
+
+class BetaAdminServicer(object):
+  def Pause(self, request, context):
+    """pause flushes any pending batch and then stops cutting blocks, broadcast messages are rejected with SERVICE_UNAVAILABLE while deliver continues normally
+    """
+    context.code(beta_interfaces.StatusCode.UNIMPLEMENTED)
+  def Resume(self, request, context):
+    """resume restarts the cutting of blocks
+    """
+    context.code(beta_interfaces.StatusCode.UNIMPLEMENTED)
+  def ValidateConfig(self, request, context):
+    """validateConfig checks a configuration envelope against the current configuration of the default chain, without applying it
+    """
+    context.code(beta_interfaces.StatusCode.UNIMPLEMENTED)
+
+
+class BetaAdminStub(object):
+  def Pause(self, request, timeout, metadata=None, with_call=False, protocol_options=None):
+    """pause flushes any pending batch and then stops cutting blocks, broadcast messages are rejected with SERVICE_UNAVAILABLE while deliver continues normally
+    """
+    raise NotImplementedError()
+  Pause.future = None
+  def Resume(self, request, timeout, metadata=None, with_call=False, protocol_options=None):
+    """resume restarts the cutting of blocks
+    """
+    raise NotImplementedError()
+  Resume.future = None
+  def ValidateConfig(self, request, timeout, metadata=None, with_call=False, protocol_options=None):
+    """validateConfig checks a configuration envelope against the current configuration of the default chain, without applying it
+    """
+    raise NotImplementedError()
+  ValidateConfig.future = None
+
+
+def beta_create_Admin_server(servicer, pool=None, pool_size=None, default_timeout=None, maximum_timeout=None):
+  request_deserializers = {
+    ('atomicbroadcast.Admin', 'Pause'): PauseRequest.FromString,
+    ('atomicbroadcast.Admin', 'Resume'): ResumeRequest.FromString,
+    ('atomicbroadcast.Admin', 'ValidateConfig'): ConfigurationEnvelope.FromString,
+  }
+  response_serializers = {
+    ('atomicbroadcast.Admin', 'Pause'): AdminResponse.SerializeToString,
+    ('atomicbroadcast.Admin', 'Resume'): AdminResponse.SerializeToString,
+    ('atomicbroadcast.Admin', 'ValidateConfig'): ValidateConfigResponse.SerializeToString,
+  }
+  method_implementations = {
+    ('atomicbroadcast.Admin', 'Pause'): face_utilities.unary_unary_inline(servicer.Pause),
+    ('atomicbroadcast.Admin', 'Resume'): face_utilities.unary_unary_inline(servicer.Resume),
+    ('atomicbroadcast.Admin', 'ValidateConfig'): face_utilities.unary_unary_inline(servicer.ValidateConfig),
+  }
+  server_options = beta_implementations.server_options(request_deserializers=request_deserializers, response_serializers=response_serializers, thread_pool=pool, thread_pool_size=pool_size, default_timeout=default_timeout, maximum_timeout=maximum_timeout)
+  return beta_implementations.server(method_implementations, options=server_options)
+
+
+def beta_create_Admin_stub(channel, host=None, metadata_transformer=None, pool=None, pool_size=None):
+  request_serializers = {
+    ('atomicbroadcast.Admin', 'Pause'): PauseRequest.SerializeToString,
+    ('atomicbroadcast.Admin', 'Resume'): ResumeRequest.SerializeToString,
+    ('atomicbroadcast.Admin', 'ValidateConfig'): ConfigurationEnvelope.SerializeToString,
+  }
+  response_deserializers = {
+    ('atomicbroadcast.Admin', 'Pause'): AdminResponse.FromString,
+    ('atomicbroadcast.Admin', 'Resume'): AdminResponse.FromString,
+    ('atomicbroadcast.Admin', 'ValidateConfig'): ValidateConfigResponse.FromString,
+  }
+  cardinalities = {
+    'Pause': cardinality.Cardinality.UNARY_UNARY,
+    'Resume': cardinality.Cardinality.UNARY_UNARY,
+    'ValidateConfig': cardinality.Cardinality.UNARY_UNARY,
+  }
+  stub_options = beta_implementations.stub_options(host=host, metadata_transformer=metadata_transformer, request_serializers=request_serializers, response_deserializers=response_deserializers, thread_pool=pool, thread_pool_size=pool_size)
+  return beta_implementations.dynamic_stub(channel, 'atomicbroadcast.Admin', cardinalities, options=stub_options)
 # @@protoc_insertion_point(module_scope)
@@ -9,22 +9,43 @@ It is generated from these files:
 	ab.proto
 
 It has these top-level messages:
+	Hello
 	BroadcastResponse
 	BroadcastMessage
+	BroadcastBatch
+	BroadcastBatchResponse
+	KafkaMessage
 	SignedData
 	PayloadEnvelope
 	Transaction
 	ConfigurationEnvelope
 	ConfigurationEntry
 	Configuration
+	BatchSize
+	BatchMaxBytes
+	BatchTimeout
 	Policy
+	MetaPolicy
+	SignatureThresholdPolicy
 	SignaturePolicyEnvelope
 	SignaturePolicy
 	SeekInfo
 	Acknowledgement
+	WindowUpdate
 	DeliverUpdate
 	Block
+	BlockHeader
+	BlockData
+	BlockMetadata
+	BlockSignature
+	LegacyBlock
+	Heartbeat
 	DeliverResponse
+	Cursor
+	PauseRequest
+	ResumeRequest
+	AdminResponse
+	ValidateConfigResponse
 */
 package atomicbroadcast
 
@@ -49,6 +70,8 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 // These status codes are intended to resemble selected HTTP status codes
+// Clients should not retry BAD_REQUEST, FORBIDDEN, or NOT_FOUND unchanged, and should retry TOO_MANY_REQUESTS and
+// SERVICE_UNAVAILABLE after the RetryAfter of the response if one is given
 type Status int32
 
 const (
@@ -56,6 +79,9 @@ const (
 	Status_BAD_REQUEST         Status = 400
 	Status_FORBIDDEN           Status = 403
 	Status_NOT_FOUND           Status = 404
+	Status_REQUEST_TIMEOUT     Status = 408
+	Status_PAYLOAD_TOO_LARGE   Status = 413
+	Status_TOO_MANY_REQUESTS   Status = 429
 	Status_SERVICE_UNAVAILABLE Status = 503
 )
 
@@ -64,6 +90,9 @@ var Status_name = map[int32]string{
 	400: "BAD_REQUEST",
 	403: "FORBIDDEN",
 	404: "NOT_FOUND",
+	408: "REQUEST_TIMEOUT",
+	413: "PAYLOAD_TOO_LARGE",
+	429: "TOO_MANY_REQUESTS",
 	503: "SERVICE_UNAVAILABLE",
 }
 var Status_value = map[string]int32{
@@ -71,6 +100,9 @@ var Status_value = map[string]int32{
 	"BAD_REQUEST":         400,
 	"FORBIDDEN":           403,
 	"NOT_FOUND":           404,
+	"REQUEST_TIMEOUT":     408,
+	"PAYLOAD_TOO_LARGE":   413,
+	"TOO_MANY_REQUESTS":   429,
 	"SERVICE_UNAVAILABLE": 503,
 }
 
@@ -79,15 +111,96 @@ func (x Status) String() string {
 }
 func (Status) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+// Feature is a protocol feature which a client may negotiate by a Hello as the first message of a Broadcast or Deliver stream
+// A stream whose first message is not a Hello is served the legacy protocol, with every feature disabled
+type Feature int32
+
+const (
+	Feature_NO_FEATURE        Feature = 0
+	Feature_CHAIN_ROUTING     Feature = 1
+	Feature_DELIVER_ACKS      Feature = 2
+	Feature_FILTERED_DELIVERY Feature = 3
+)
+
+var Feature_name = map[int32]string{
+	0: "NO_FEATURE",
+	1: "CHAIN_ROUTING",
+	2: "DELIVER_ACKS",
+	3: "FILTERED_DELIVERY",
+}
+var Feature_value = map[string]int32{
+	"NO_FEATURE":        0,
+	"CHAIN_ROUTING":     1,
+	"DELIVER_ACKS":      2,
+	"FILTERED_DELIVERY": 3,
+}
+
+func (x Feature) String() string {
+	return proto.EnumName(Feature_name, int32(x))
+}
+func (Feature) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type BlockMetadataIndex int32
+
+const (
+	BlockMetadataIndex_PROOF              BlockMetadataIndex = 0
+	BlockMetadataIndex_SIGNATURES         BlockMetadataIndex = 1
+	BlockMetadataIndex_LAST_CONFIGURATION BlockMetadataIndex = 2
+	BlockMetadataIndex_TIMESTAMP          BlockMetadataIndex = 3
+)
+
+var BlockMetadataIndex_name = map[int32]string{
+	0: "PROOF",
+	1: "SIGNATURES",
+	2: "LAST_CONFIGURATION",
+	3: "TIMESTAMP",
+}
+var BlockMetadataIndex_value = map[string]int32{
+	"PROOF":              0,
+	"SIGNATURES":         1,
+	"LAST_CONFIGURATION": 2,
+	"TIMESTAMP":          3,
+}
+
+func (x BlockMetadataIndex) String() string {
+	return proto.EnumName(BlockMetadataIndex_name, int32(x))
+}
+func (BlockMetadataIndex) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type KafkaMessage_Type int32
+
+const (
+	KafkaMessage_REGULAR     KafkaMessage_Type = 0
+	KafkaMessage_TIME_TO_CUT KafkaMessage_Type = 1
+	KafkaMessage_CONNECT     KafkaMessage_Type = 2
+)
+
+var KafkaMessage_Type_name = map[int32]string{
+	0: "REGULAR",
+	1: "TIME_TO_CUT",
+	2: "CONNECT",
+}
+var KafkaMessage_Type_value = map[string]int32{
+	"REGULAR":     0,
+	"TIME_TO_CUT": 1,
+	"CONNECT":     2,
+}
+
+func (x KafkaMessage_Type) String() string {
+	return proto.EnumName(KafkaMessage_Type_name, int32(x))
+}
+func (KafkaMessage_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{5, 0} }
+
 type Configuration_ConfigurationType int32
 
 const (
-	Configuration_Policy Configuration_ConfigurationType = 0
-	Configuration_Fabric Configuration_ConfigurationType = 1
-	Configuration_Chain  Configuration_ConfigurationType = 2
-	Configuration_Solo   Configuration_ConfigurationType = 3
-	Configuration_Kafka  Configuration_ConfigurationType = 4
-	Configuration_PBFT   Configuration_ConfigurationType = 5
+	Configuration_Policy  Configuration_ConfigurationType = 0
+	Configuration_Fabric  Configuration_ConfigurationType = 1
+	Configuration_Chain   Configuration_ConfigurationType = 2
+	Configuration_Solo    Configuration_ConfigurationType = 3
+	Configuration_Kafka   Configuration_ConfigurationType = 4
+	Configuration_PBFT    Configuration_ConfigurationType = 5
+	Configuration_Orderer Configuration_ConfigurationType = 6
 )
 
 var Configuration_ConfigurationType_name = map[int32]string{
@@ -97,22 +210,48 @@ var Configuration_ConfigurationType_name = map[int32]string{
 	3: "Solo",
 	4: "Kafka",
 	5: "PBFT",
+	6: "Orderer",
 }
 var Configuration_ConfigurationType_value = map[string]int32{
-	"Policy": 0,
-	"Fabric": 1,
-	"Chain":  2,
-	"Solo":   3,
-	"Kafka":  4,
-	"PBFT":   5,
+	"Policy":  0,
+	"Fabric":  1,
+	"Chain":   2,
+	"Solo":    3,
+	"Kafka":   4,
+	"PBFT":    5,
+	"Orderer": 6,
 }
 
 func (x Configuration_ConfigurationType) String() string {
 	return proto.EnumName(Configuration_ConfigurationType_name, int32(x))
 }
 func (Configuration_ConfigurationType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor0, []int{7, 0}
+	return fileDescriptor0, []int{11, 0}
+}
+
+type MetaPolicy_Rule int32
+
+const (
+	MetaPolicy_ANY      MetaPolicy_Rule = 0
+	MetaPolicy_ALL      MetaPolicy_Rule = 1
+	MetaPolicy_MAJORITY MetaPolicy_Rule = 2
+)
+
+var MetaPolicy_Rule_name = map[int32]string{
+	0: "ANY",
+	1: "ALL",
+	2: "MAJORITY",
+}
+var MetaPolicy_Rule_value = map[string]int32{
+	"ANY":      0,
+	"ALL":      1,
+	"MAJORITY": 2,
+}
+
+func (x MetaPolicy_Rule) String() string {
+	return proto.EnumName(MetaPolicy_Rule_name, int32(x))
 }
+func (MetaPolicy_Rule) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{16, 0} }
 
 // Start may be specified to a specific block number, or may be request from the newest or oldest available
 // The start location is always inclusive, so the first reply from NEWEST will contain the newest block at the time
@@ -124,45 +263,180 @@ const (
 	SeekInfo_NEWEST    SeekInfo_StartType = 0
 	SeekInfo_OLDEST    SeekInfo_StartType = 1
 	SeekInfo_SPECIFIED SeekInfo_StartType = 2
+	SeekInfo_TIMESTAMP SeekInfo_StartType = 3
 )
 
 var SeekInfo_StartType_name = map[int32]string{
 	0: "NEWEST",
 	1: "OLDEST",
 	2: "SPECIFIED",
+	3: "TIMESTAMP",
 }
 var SeekInfo_StartType_value = map[string]int32{
 	"NEWEST":    0,
 	"OLDEST":    1,
 	"SPECIFIED": 2,
+	"TIMESTAMP": 3,
 }
 
 func (x SeekInfo_StartType) String() string {
 	return proto.EnumName(SeekInfo_StartType_name, int32(x))
 }
-func (SeekInfo_StartType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{11, 0} }
+func (SeekInfo_StartType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{20, 0} }
+
+// Content selects whether full blocks are sent, or only their Header and Metadata with the Data omitted
+// A block's header carries the DataHash of its Data, so the hash chain may be verified from headers alone
+type SeekInfo_ContentType int32
+
+const (
+	SeekInfo_FULL         SeekInfo_ContentType = 0
+	SeekInfo_HEADERS_ONLY SeekInfo_ContentType = 1
+)
+
+var SeekInfo_ContentType_name = map[int32]string{
+	0: "FULL",
+	1: "HEADERS_ONLY",
+}
+var SeekInfo_ContentType_value = map[string]int32{
+	"FULL":         0,
+	"HEADERS_ONLY": 1,
+}
+
+func (x SeekInfo_ContentType) String() string {
+	return proto.EnumName(SeekInfo_ContentType_name, int32(x))
+}
+func (SeekInfo_ContentType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{20, 1} }
+
+// Stop bounds the range of blocks delivered, after the last block of the range a SUCCESS status is sent and the stream is closed
+// The stop location is inclusive, a stop before the start is a BAD_REQUEST
+type SeekInfo_StopType int32
+
+const (
+	SeekInfo_UNBOUNDED      SeekInfo_StopType = 0
+	SeekInfo_STOP_SPECIFIED SeekInfo_StopType = 1
+	SeekInfo_STOP_NEWEST    SeekInfo_StopType = 2
+)
+
+var SeekInfo_StopType_name = map[int32]string{
+	0: "UNBOUNDED",
+	1: "STOP_SPECIFIED",
+	2: "STOP_NEWEST",
+}
+var SeekInfo_StopType_value = map[string]int32{
+	"UNBOUNDED":      0,
+	"STOP_SPECIFIED": 1,
+	"STOP_NEWEST":    2,
+}
+
+func (x SeekInfo_StopType) String() string {
+	return proto.EnumName(SeekInfo_StopType_name, int32(x))
+}
+func (SeekInfo_StopType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{20, 2} }
 
+// Hello announces the features a client supports, and in the reply the features selected for the stream
+type Hello struct {
+	Features []Feature `protobuf:"varint,1,rep,packed,name=Features,json=features,enum=atomicbroadcast.Feature" json:"Features,omitempty"`
+}
+
+func (m *Hello) Reset()                    { *m = Hello{} }
+func (m *Hello) String() string            { return proto.CompactTextString(m) }
+func (*Hello) ProtoMessage()               {}
+func (*Hello) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+// BroadcastResponse is sent for each BroadcastMessage received, in the order the messages were received
+// When acknowledging after commit, BlockNumber and Index identify where the message was ordered
 type BroadcastResponse struct {
-	Status Status `protobuf:"varint,1,opt,name=Status,json=status,enum=atomicbroadcast.Status" json:"Status,omitempty"`
+	Status        Status `protobuf:"varint,1,opt,name=Status,json=status,enum=atomicbroadcast.Status" json:"Status,omitempty"`
+	BlockNumber   uint64 `protobuf:"varint,2,opt,name=BlockNumber,json=blockNumber" json:"BlockNumber,omitempty"`
+	Index         uint64 `protobuf:"varint,3,opt,name=Index,json=index" json:"Index,omitempty"`
+	RetryAfter    uint64 `protobuf:"varint,4,opt,name=RetryAfter,json=retryAfter" json:"RetryAfter,omitempty"`
+	Info          string `protobuf:"bytes,5,opt,name=Info,json=info" json:"Info,omitempty"`
+	CorrelationID []byte `protobuf:"bytes,6,opt,name=CorrelationID,json=correlationID,proto3" json:"CorrelationID,omitempty"`
+	Hello         *Hello `protobuf:"bytes,7,opt,name=Hello,json=hello" json:"Hello,omitempty"`
 }
 
 func (m *BroadcastResponse) Reset()                    { *m = BroadcastResponse{} }
 func (m *BroadcastResponse) String() string            { return proto.CompactTextString(m) }
 func (*BroadcastResponse) ProtoMessage()               {}
-func (*BroadcastResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (*BroadcastResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *BroadcastResponse) GetHello() *Hello {
+	if m != nil {
+		return m.Hello
+	}
+	return nil
+}
 
 // For backwards compatibility, this is message is being left as bytes for the moment.
 // Internally, for testing authentication, the Data payload will be a marshalled SignedData message
 // However in the future, this whole message is likely to go away.
 // XXX Temporary
 type BroadcastMessage struct {
-	Data []byte `protobuf:"bytes,1,opt,name=Data,json=data,proto3" json:"Data,omitempty"`
+	Data          []byte `protobuf:"bytes,1,opt,name=Data,json=data,proto3" json:"Data,omitempty"`
+	ChainID       []byte `protobuf:"bytes,2,opt,name=ChainID,json=chainID,proto3" json:"ChainID,omitempty"`
+	CorrelationID []byte `protobuf:"bytes,3,opt,name=CorrelationID,json=correlationID,proto3" json:"CorrelationID,omitempty"`
+	// Hello, when set on the first message of a stream, negotiates features and is not ordered, the reply carries the selected features
+	// A legacy orderer replies BAD_REQUEST to it as an empty message
+	Hello *Hello `protobuf:"bytes,4,opt,name=Hello,json=hello" json:"Hello,omitempty"`
 }
 
 func (m *BroadcastMessage) Reset()                    { *m = BroadcastMessage{} }
 func (m *BroadcastMessage) String() string            { return proto.CompactTextString(m) }
 func (*BroadcastMessage) ProtoMessage()               {}
-func (*BroadcastMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*BroadcastMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *BroadcastMessage) GetHello() *Hello {
+	if m != nil {
+		return m.Hello
+	}
+	return nil
+}
+
+// BroadcastBatch carries several messages in a single send, each is handled as if it were sent alone on a Broadcast stream
+type BroadcastBatch struct {
+	Messages []*BroadcastMessage `protobuf:"bytes,1,rep,name=Messages,json=messages" json:"Messages,omitempty"`
+}
+
+func (m *BroadcastBatch) Reset()                    { *m = BroadcastBatch{} }
+func (m *BroadcastBatch) String() string            { return proto.CompactTextString(m) }
+func (*BroadcastBatch) ProtoMessage()               {}
+func (*BroadcastBatch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *BroadcastBatch) GetMessages() []*BroadcastMessage {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+// BroadcastBatchResponse holds a reply for each message of a BroadcastBatch, in the order of the messages
+// A reply which answers no message, such as the closing of an idle stream, is sent in a response of its own
+type BroadcastBatchResponse struct {
+	Responses []*BroadcastResponse `protobuf:"bytes,1,rep,name=Responses,json=responses" json:"Responses,omitempty"`
+}
+
+func (m *BroadcastBatchResponse) Reset()                    { *m = BroadcastBatchResponse{} }
+func (m *BroadcastBatchResponse) String() string            { return proto.CompactTextString(m) }
+func (*BroadcastBatchResponse) ProtoMessage()               {}
+func (*BroadcastBatchResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *BroadcastBatchResponse) GetResponses() []*BroadcastResponse {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
+// KafkaMessage is what the Kafka orderer posts to the partition of a chain, following a version byte
+type KafkaMessage struct {
+	Type    KafkaMessage_Type `protobuf:"varint,1,opt,name=Type,json=type,enum=atomicbroadcast.KafkaMessage_Type" json:"Type,omitempty"`
+	Payload []byte            `protobuf:"bytes,2,opt,name=Payload,json=payload,proto3" json:"Payload,omitempty"`
+}
+
+func (m *KafkaMessage) Reset()                    { *m = KafkaMessage{} }
+func (m *KafkaMessage) String() string            { return proto.CompactTextString(m) }
+func (*KafkaMessage) ProtoMessage()               {}
+func (*KafkaMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 // SignedData is a temporary message type to be removed once the real transaction type is finalized
 // Note that the identity of the signer is explicitely not included, but embedded in the envelope because
@@ -176,7 +450,7 @@ type SignedData struct {
 func (m *SignedData) Reset()                    { *m = SignedData{} }
 func (m *SignedData) String() string            { return proto.CompactTextString(m) }
 func (*SignedData) ProtoMessage()               {}
-func (*SignedData) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*SignedData) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 // PayloadEnvelope is the thin wrapper which allows the embedding of a signer's identity to sign over
 // XXX Temporary
@@ -188,15 +462,17 @@ type PayloadEnvelope struct {
 func (m *PayloadEnvelope) Reset()                    { *m = PayloadEnvelope{} }
 func (m *PayloadEnvelope) String() string            { return proto.CompactTextString(m) }
 func (*PayloadEnvelope) ProtoMessage()               {}
-func (*PayloadEnvelope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*PayloadEnvelope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 // Transaction embeds a configuration change and associated signoffs
 // This will be superseded once the real transaction format is finalized
 // XXX Temporary
+// The Type of a Transaction is what identifies a configuration transaction, which is committed in a block by itself
 type Transaction struct {
 	// Types that are valid to be assigned to Type:
 	//	*Transaction_Opaque
 	//	*Transaction_ConfigurationEnvelope
+	//	*Transaction_CreateChain
 	Type       isTransaction_Type `protobuf_oneof:"Type"`
 	Signatures []*SignedData      `protobuf:"bytes,3,rep,name=Signatures,json=signatures" json:"Signatures,omitempty"`
 }
@@ -204,7 +480,7 @@ type Transaction struct {
 func (m *Transaction) Reset()                    { *m = Transaction{} }
 func (m *Transaction) String() string            { return proto.CompactTextString(m) }
 func (*Transaction) ProtoMessage()               {}
-func (*Transaction) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*Transaction) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
 
 type isTransaction_Type interface {
 	isTransaction_Type()
@@ -216,9 +492,13 @@ type Transaction_Opaque struct {
 type Transaction_ConfigurationEnvelope struct {
 	ConfigurationEnvelope []byte `protobuf:"bytes,2,opt,name=ConfigurationEnvelope,json=configurationEnvelope,proto3,oneof"`
 }
+type Transaction_CreateChain struct {
+	CreateChain []byte `protobuf:"bytes,4,opt,name=CreateChain,json=createChain,proto3,oneof"`
+}
 
 func (*Transaction_Opaque) isTransaction_Type()                {}
 func (*Transaction_ConfigurationEnvelope) isTransaction_Type() {}
+func (*Transaction_CreateChain) isTransaction_Type()           {}
 
 func (m *Transaction) GetType() isTransaction_Type {
 	if m != nil {
@@ -241,6 +521,13 @@ func (m *Transaction) GetConfigurationEnvelope() []byte {
 	return nil
 }
 
+func (m *Transaction) GetCreateChain() []byte {
+	if x, ok := m.GetType().(*Transaction_CreateChain); ok {
+		return x.CreateChain
+	}
+	return nil
+}
+
 func (m *Transaction) GetSignatures() []*SignedData {
 	if m != nil {
 		return m.Signatures
@@ -253,6 +540,7 @@ func (*Transaction) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) e
 	return _Transaction_OneofMarshaler, _Transaction_OneofUnmarshaler, _Transaction_OneofSizer, []interface{}{
 		(*Transaction_Opaque)(nil),
 		(*Transaction_ConfigurationEnvelope)(nil),
+		(*Transaction_CreateChain)(nil),
 	}
 }
 
@@ -266,6 +554,9 @@ func _Transaction_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 	case *Transaction_ConfigurationEnvelope:
 		b.EncodeVarint(2<<3 | proto.WireBytes)
 		b.EncodeRawBytes(x.ConfigurationEnvelope)
+	case *Transaction_CreateChain:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		b.EncodeRawBytes(x.CreateChain)
 	case nil:
 	default:
 		return fmt.Errorf("Transaction.Type has unexpected type %T", x)
@@ -290,6 +581,13 @@ func _Transaction_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Bu
 		x, err := b.DecodeRawBytes(true)
 		m.Type = &Transaction_ConfigurationEnvelope{x}
 		return true, err
+	case 4: // Type.CreateChain
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeRawBytes(true)
+		m.Type = &Transaction_CreateChain{x}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -307,6 +605,10 @@ func _Transaction_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(2<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(len(x.ConfigurationEnvelope)))
 		n += len(x.ConfigurationEnvelope)
+	case *Transaction_CreateChain:
+		n += proto.SizeVarint(4<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(len(x.CreateChain)))
+		n += len(x.CreateChain)
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -325,15 +627,17 @@ func _Transaction_OneofSizer(msg proto.Message) (n int) {
 // that the configuration is well formed, and that some configuration item corresponds to the new
 // ConfigurationEnvelope sequence number.
 type ConfigurationEnvelope struct {
-	Sequence uint64                `protobuf:"varint,1,opt,name=Sequence,json=sequence" json:"Sequence,omitempty"`
-	ChainID  []byte                `protobuf:"bytes,2,opt,name=ChainID,json=chainID,proto3" json:"ChainID,omitempty"`
-	Entries  []*ConfigurationEntry `protobuf:"bytes,3,rep,name=Entries,json=entries" json:"Entries,omitempty"`
+	Sequence  uint64                `protobuf:"varint,1,opt,name=Sequence,json=sequence" json:"Sequence,omitempty"`
+	ChainID   []byte                `protobuf:"bytes,2,opt,name=ChainID,json=chainID,proto3" json:"ChainID,omitempty"`
+	Entries   []*ConfigurationEntry `protobuf:"bytes,3,rep,name=Entries,json=entries" json:"Entries,omitempty"`
+	NotBefore int64                 `protobuf:"varint,4,opt,name=NotBefore,json=notBefore" json:"NotBefore,omitempty"`
+	NotAfter  int64                 `protobuf:"varint,5,opt,name=NotAfter,json=notAfter" json:"NotAfter,omitempty"`
 }
 
 func (m *ConfigurationEnvelope) Reset()                    { *m = ConfigurationEnvelope{} }
 func (m *ConfigurationEnvelope) String() string            { return proto.CompactTextString(m) }
 func (*ConfigurationEnvelope) ProtoMessage()               {}
-func (*ConfigurationEnvelope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*ConfigurationEnvelope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
 
 func (m *ConfigurationEnvelope) GetEntries() []*ConfigurationEntry {
 	if m != nil {
@@ -351,7 +655,7 @@ type ConfigurationEntry struct {
 func (m *ConfigurationEntry) Reset()                    { *m = ConfigurationEntry{} }
 func (m *ConfigurationEntry) String() string            { return proto.CompactTextString(m) }
 func (*ConfigurationEntry) ProtoMessage()               {}
-func (*ConfigurationEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*ConfigurationEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
 
 func (m *ConfigurationEntry) GetSignatures() []*SignedData {
 	if m != nil {
@@ -372,20 +676,55 @@ type Configuration struct {
 func (m *Configuration) Reset()                    { *m = Configuration{} }
 func (m *Configuration) String() string            { return proto.CompactTextString(m) }
 func (*Configuration) ProtoMessage()               {}
-func (*Configuration) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*Configuration) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+// BatchSize is the Data of the Solo configuration item BatchSize, which sets how the blocks of the chain are cut
+// It is also the Data of the Orderer configuration item BatchSize, which sets only Messages
+type BatchSize struct {
+	Messages        uint32 `protobuf:"varint,1,opt,name=Messages,json=messages" json:"Messages,omitempty"`
+	MaxBytes        uint32 `protobuf:"varint,2,opt,name=MaxBytes,json=maxBytes" json:"MaxBytes,omitempty"`
+	MaxMessageBytes uint32 `protobuf:"varint,3,opt,name=MaxMessageBytes,json=maxMessageBytes" json:"MaxMessageBytes,omitempty"`
+}
+
+func (m *BatchSize) Reset()                    { *m = BatchSize{} }
+func (m *BatchSize) String() string            { return proto.CompactTextString(m) }
+func (*BatchSize) ProtoMessage()               {}
+func (*BatchSize) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+// BatchMaxBytes is the Data of the Orderer configuration item BatchMaxBytes
+type BatchMaxBytes struct {
+	MaxBytes uint32 `protobuf:"varint,1,opt,name=MaxBytes,json=maxBytes" json:"MaxBytes,omitempty"`
+}
+
+func (m *BatchMaxBytes) Reset()                    { *m = BatchMaxBytes{} }
+func (m *BatchMaxBytes) String() string            { return proto.CompactTextString(m) }
+func (*BatchMaxBytes) ProtoMessage()               {}
+func (*BatchMaxBytes) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+// BatchTimeout is the Data of the Orderer configuration item BatchTimeout
+type BatchTimeout struct {
+	Timeout int64 `protobuf:"varint,1,opt,name=Timeout,json=timeout" json:"Timeout,omitempty"`
+}
+
+func (m *BatchTimeout) Reset()                    { *m = BatchTimeout{} }
+func (m *BatchTimeout) String() string            { return proto.CompactTextString(m) }
+func (*BatchTimeout) ProtoMessage()               {}
+func (*BatchTimeout) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 // Policy expresses a policy which the orderer can evaluate, because there has been some desire expressed to support
 // multiple policy engines, this is typed as a oneof for now
 type Policy struct {
 	// Types that are valid to be assigned to Type:
 	//	*Policy_SignaturePolicy
+	//	*Policy_SignatureThreshold
+	//	*Policy_Meta
 	Type isPolicy_Type `protobuf_oneof:"Type"`
 }
 
 func (m *Policy) Reset()                    { *m = Policy{} }
 func (m *Policy) String() string            { return proto.CompactTextString(m) }
 func (*Policy) ProtoMessage()               {}
-func (*Policy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*Policy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
 
 type isPolicy_Type interface {
 	isPolicy_Type()
@@ -394,8 +733,16 @@ type isPolicy_Type interface {
 type Policy_SignaturePolicy struct {
 	SignaturePolicy *SignaturePolicyEnvelope `protobuf:"bytes,2,opt,name=SignaturePolicy,json=signaturePolicy,oneof"`
 }
+type Policy_SignatureThreshold struct {
+	SignatureThreshold *SignatureThresholdPolicy `protobuf:"bytes,3,opt,name=SignatureThreshold,json=signatureThreshold,oneof"`
+}
+type Policy_Meta struct {
+	Meta *MetaPolicy `protobuf:"bytes,4,opt,name=Meta,json=meta,oneof"`
+}
 
-func (*Policy_SignaturePolicy) isPolicy_Type() {}
+func (*Policy_SignaturePolicy) isPolicy_Type()    {}
+func (*Policy_SignatureThreshold) isPolicy_Type() {}
+func (*Policy_Meta) isPolicy_Type()               {}
 
 func (m *Policy) GetType() isPolicy_Type {
 	if m != nil {
@@ -411,10 +758,26 @@ func (m *Policy) GetSignaturePolicy() *SignaturePolicyEnvelope {
 	return nil
 }
 
+func (m *Policy) GetSignatureThreshold() *SignatureThresholdPolicy {
+	if x, ok := m.GetType().(*Policy_SignatureThreshold); ok {
+		return x.SignatureThreshold
+	}
+	return nil
+}
+
+func (m *Policy) GetMeta() *MetaPolicy {
+	if x, ok := m.GetType().(*Policy_Meta); ok {
+		return x.Meta
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Policy) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Policy_OneofMarshaler, _Policy_OneofUnmarshaler, _Policy_OneofSizer, []interface{}{
 		(*Policy_SignaturePolicy)(nil),
+		(*Policy_SignatureThreshold)(nil),
+		(*Policy_Meta)(nil),
 	}
 }
 
@@ -427,6 +790,16 @@ func _Policy_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.SignaturePolicy); err != nil {
 			return err
 		}
+	case *Policy_SignatureThreshold:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.SignatureThreshold); err != nil {
+			return err
+		}
+	case *Policy_Meta:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Meta); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Policy.Type has unexpected type %T", x)
@@ -445,6 +818,22 @@ func _Policy_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer)
 		err := b.DecodeMessage(msg)
 		m.Type = &Policy_SignaturePolicy{msg}
 		return true, err
+	case 3: // Type.SignatureThreshold
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(SignatureThresholdPolicy)
+		err := b.DecodeMessage(msg)
+		m.Type = &Policy_SignatureThreshold{msg}
+		return true, err
+	case 4: // Type.Meta
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(MetaPolicy)
+		err := b.DecodeMessage(msg)
+		m.Type = &Policy_Meta{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -459,6 +848,16 @@ func _Policy_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(2<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Policy_SignatureThreshold:
+		s := proto.Size(x.SignatureThreshold)
+		n += proto.SizeVarint(3<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Policy_Meta:
+		s := proto.Size(x.Meta)
+		n += proto.SizeVarint(4<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -466,6 +865,30 @@ func _Policy_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+// MetaPolicy is satisfied according to its Rule by the policies it names, which are resolved by ID as it is evaluated,
+// a meta policy naming no policies is never satisfied, and the meta policies of a chain may not name one another in a cycle
+type MetaPolicy struct {
+	Rule        MetaPolicy_Rule `protobuf:"varint,1,opt,name=Rule,json=rule,enum=atomicbroadcast.MetaPolicy_Rule" json:"Rule,omitempty"`
+	SubPolicies []string        `protobuf:"bytes,2,rep,name=SubPolicies,json=subPolicies" json:"SubPolicies,omitempty"`
+}
+
+func (m *MetaPolicy) Reset()                    { *m = MetaPolicy{} }
+func (m *MetaPolicy) String() string            { return proto.CompactTextString(m) }
+func (*MetaPolicy) ProtoMessage()               {}
+func (*MetaPolicy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+// SignatureThresholdPolicy is satisfied by valid signatures from at least N distinct identities of the Identities listed,
+// an identity which signs more than once is counted once, and signatures by identities not listed are ignored
+type SignatureThresholdPolicy struct {
+	N          int32    `protobuf:"varint,1,opt,name=N,json=n" json:"N,omitempty"`
+	Identities [][]byte `protobuf:"bytes,2,rep,name=Identities,json=identities,proto3" json:"Identities,omitempty"`
+}
+
+func (m *SignatureThresholdPolicy) Reset()                    { *m = SignatureThresholdPolicy{} }
+func (m *SignatureThresholdPolicy) String() string            { return proto.CompactTextString(m) }
+func (*SignatureThresholdPolicy) ProtoMessage()               {}
+func (*SignatureThresholdPolicy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
 // SignaturePolicyEnvelope wraps a SignaturePolicy and includes a version for future enhancements
 type SignaturePolicyEnvelope struct {
 	Version    int32            `protobuf:"varint,1,opt,name=Version,json=version" json:"Version,omitempty"`
@@ -476,7 +899,7 @@ type SignaturePolicyEnvelope struct {
 func (m *SignaturePolicyEnvelope) Reset()                    { *m = SignaturePolicyEnvelope{} }
 func (m *SignaturePolicyEnvelope) String() string            { return proto.CompactTextString(m) }
 func (*SignaturePolicyEnvelope) ProtoMessage()               {}
-func (*SignaturePolicyEnvelope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+func (*SignaturePolicyEnvelope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
 
 func (m *SignaturePolicyEnvelope) GetPolicy() *SignaturePolicy {
 	if m != nil {
@@ -501,7 +924,7 @@ type SignaturePolicy struct {
 func (m *SignaturePolicy) Reset()                    { *m = SignaturePolicy{} }
 func (m *SignaturePolicy) String() string            { return proto.CompactTextString(m) }
 func (*SignaturePolicy) ProtoMessage()               {}
-func (*SignaturePolicy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+func (*SignaturePolicy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
 
 type isSignaturePolicy_Type interface {
 	isSignaturePolicy_Type()
@@ -615,7 +1038,7 @@ type SignaturePolicy_NOutOf struct {
 func (m *SignaturePolicy_NOutOf) Reset()                    { *m = SignaturePolicy_NOutOf{} }
 func (m *SignaturePolicy_NOutOf) String() string            { return proto.CompactTextString(m) }
 func (*SignaturePolicy_NOutOf) ProtoMessage()               {}
-func (*SignaturePolicy_NOutOf) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10, 0} }
+func (*SignaturePolicy_NOutOf) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19, 0} }
 
 func (m *SignaturePolicy_NOutOf) GetPolicies() []*SignaturePolicy {
 	if m != nil {
@@ -625,15 +1048,39 @@ func (m *SignaturePolicy_NOutOf) GetPolicies() []*SignaturePolicy {
 }
 
 type SeekInfo struct {
-	Start           SeekInfo_StartType `protobuf:"varint,1,opt,name=Start,json=start,enum=atomicbroadcast.SeekInfo_StartType" json:"Start,omitempty"`
-	SpecifiedNumber uint64             `protobuf:"varint,2,opt,name=SpecifiedNumber,json=specifiedNumber" json:"SpecifiedNumber,omitempty"`
-	WindowSize      uint64             `protobuf:"varint,3,opt,name=WindowSize,json=windowSize" json:"WindowSize,omitempty"`
+	Start           SeekInfo_StartType   `protobuf:"varint,1,opt,name=Start,json=start,enum=atomicbroadcast.SeekInfo_StartType" json:"Start,omitempty"`
+	SpecifiedNumber uint64               `protobuf:"varint,2,opt,name=SpecifiedNumber,json=specifiedNumber" json:"SpecifiedNumber,omitempty"`
+	WindowSize      uint64               `protobuf:"varint,3,opt,name=WindowSize,json=windowSize" json:"WindowSize,omitempty"`
+	Content         SeekInfo_ContentType `protobuf:"varint,4,opt,name=Content,json=content,enum=atomicbroadcast.SeekInfo_ContentType" json:"Content,omitempty"`
+	ChainID         []byte               `protobuf:"bytes,5,opt,name=ChainID,json=chainID,proto3" json:"ChainID,omitempty"`
+	// Cursor, when set, is a token from a previous stream, delivery resumes after the block it was sent with and Start is ignored
+	// If WindowSize is zero, the window size and content of the previous stream are restored
+	Cursor      []byte            `protobuf:"bytes,6,opt,name=Cursor,json=cursor,proto3" json:"Cursor,omitempty"`
+	Stop        SeekInfo_StopType `protobuf:"varint,7,opt,name=Stop,json=stop,enum=atomicbroadcast.SeekInfo_StopType" json:"Stop,omitempty"`
+	StopNumber  uint64            `protobuf:"varint,8,opt,name=StopNumber,json=stopNumber" json:"StopNumber,omitempty"`
+	WaitForStop bool              `protobuf:"varint,9,opt,name=WaitForStop,json=waitForStop" json:"WaitForStop,omitempty"`
+	// StartTimestamp is in Unix nanoseconds, only used when Start = TIMESTAMP, a timestamp after the newest block is NOT_FOUND
+	// Block timestamps are taken from the clock of the orderer which committed them, not from the clients, and do not decrease along the chain
+	StartTimestamp int64 `protobuf:"varint,10,opt,name=StartTimestamp,json=startTimestamp" json:"StartTimestamp,omitempty"`
+	// Signatures over the seek marshaled without its Signatures, which must satisfy the Readers policy of the chain sought
+	// A seek which resumes from a Cursor is signed afresh, as every seek is
+	Signatures []*SignedData `protobuf:"bytes,11,rep,name=Signatures,json=signatures" json:"Signatures,omitempty"`
+	// Timestamp is when the seek was signed, in Unix nanoseconds, a signed seek further from the clock of the orderer than it allows is FORBIDDEN
+	// It bounds how long signatures captured from one seek may be replayed
+	Timestamp int64 `protobuf:"varint,12,opt,name=Timestamp,json=timestamp" json:"Timestamp,omitempty"`
 }
 
 func (m *SeekInfo) Reset()                    { *m = SeekInfo{} }
 func (m *SeekInfo) String() string            { return proto.CompactTextString(m) }
 func (*SeekInfo) ProtoMessage()               {}
-func (*SeekInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (*SeekInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *SeekInfo) GetSignatures() []*SignedData {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
 
 type Acknowledgement struct {
 	Number uint64 `protobuf:"varint,1,opt,name=Number,json=number" json:"Number,omitempty"`
@@ -642,20 +1089,35 @@ type Acknowledgement struct {
 func (m *Acknowledgement) Reset()                    { *m = Acknowledgement{} }
 func (m *Acknowledgement) String() string            { return proto.CompactTextString(m) }
 func (*Acknowledgement) ProtoMessage()               {}
-func (*Acknowledgement) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+func (*Acknowledgement) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+// WindowUpdate resizes the window of the current seek without moving its position
+// A window smaller than the blocks already sent and unacknowledged sends no further blocks until enough are acknowledged
+type WindowUpdate struct {
+	WindowSize uint64 `protobuf:"varint,1,opt,name=WindowSize,json=windowSize" json:"WindowSize,omitempty"`
+}
+
+func (m *WindowUpdate) Reset()                    { *m = WindowUpdate{} }
+func (m *WindowUpdate) String() string            { return proto.CompactTextString(m) }
+func (*WindowUpdate) ProtoMessage()               {}
+func (*WindowUpdate) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
 
-// The update message either causes a seek to a new stream start with a new window, or acknowledges a received block and advances the base of the window
+// The update message either causes a seek to a new stream start with a new window, acknowledges a received block and advances the base of the window, or resizes the window
+// A seek with no WindowSize nor Cursor, as sent by clients predating acknowledgements, is served with the orderer's default window, without waiting for acknowledgements,
+// until the client sends an Acknowledgement or WindowUpdate, if the orderer has no default window it is a BAD_REQUEST
 type DeliverUpdate struct {
 	// Types that are valid to be assigned to Type:
 	//	*DeliverUpdate_Acknowledgement
 	//	*DeliverUpdate_Seek
+	//	*DeliverUpdate_WindowUpdate
+	//	*DeliverUpdate_Hello
 	Type isDeliverUpdate_Type `protobuf_oneof:"Type"`
 }
 
 func (m *DeliverUpdate) Reset()                    { *m = DeliverUpdate{} }
 func (m *DeliverUpdate) String() string            { return proto.CompactTextString(m) }
 func (*DeliverUpdate) ProtoMessage()               {}
-func (*DeliverUpdate) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+func (*DeliverUpdate) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
 
 type isDeliverUpdate_Type interface {
 	isDeliverUpdate_Type()
@@ -667,9 +1129,17 @@ type DeliverUpdate_Acknowledgement struct {
 type DeliverUpdate_Seek struct {
 	Seek *SeekInfo `protobuf:"bytes,2,opt,name=Seek,json=seek,oneof"`
 }
+type DeliverUpdate_WindowUpdate struct {
+	WindowUpdate *WindowUpdate `protobuf:"bytes,3,opt,name=WindowUpdate,json=windowUpdate,oneof"`
+}
+type DeliverUpdate_Hello struct {
+	Hello *Hello `protobuf:"bytes,4,opt,name=Hello,json=hello,oneof"`
+}
 
 func (*DeliverUpdate_Acknowledgement) isDeliverUpdate_Type() {}
 func (*DeliverUpdate_Seek) isDeliverUpdate_Type()            {}
+func (*DeliverUpdate_WindowUpdate) isDeliverUpdate_Type()    {}
+func (*DeliverUpdate_Hello) isDeliverUpdate_Type()           {}
 
 func (m *DeliverUpdate) GetType() isDeliverUpdate_Type {
 	if m != nil {
@@ -692,11 +1162,27 @@ func (m *DeliverUpdate) GetSeek() *SeekInfo {
 	return nil
 }
 
+func (m *DeliverUpdate) GetWindowUpdate() *WindowUpdate {
+	if x, ok := m.GetType().(*DeliverUpdate_WindowUpdate); ok {
+		return x.WindowUpdate
+	}
+	return nil
+}
+
+func (m *DeliverUpdate) GetHello() *Hello {
+	if x, ok := m.GetType().(*DeliverUpdate_Hello); ok {
+		return x.Hello
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*DeliverUpdate) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _DeliverUpdate_OneofMarshaler, _DeliverUpdate_OneofUnmarshaler, _DeliverUpdate_OneofSizer, []interface{}{
 		(*DeliverUpdate_Acknowledgement)(nil),
 		(*DeliverUpdate_Seek)(nil),
+		(*DeliverUpdate_WindowUpdate)(nil),
+		(*DeliverUpdate_Hello)(nil),
 	}
 }
 
@@ -714,6 +1200,16 @@ func _DeliverUpdate_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Seek); err != nil {
 			return err
 		}
+	case *DeliverUpdate_WindowUpdate:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.WindowUpdate); err != nil {
+			return err
+		}
+	case *DeliverUpdate_Hello:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Hello); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("DeliverUpdate.Type has unexpected type %T", x)
@@ -740,6 +1236,22 @@ func _DeliverUpdate_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.
 		err := b.DecodeMessage(msg)
 		m.Type = &DeliverUpdate_Seek{msg}
 		return true, err
+	case 3: // Type.WindowUpdate
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(WindowUpdate)
+		err := b.DecodeMessage(msg)
+		m.Type = &DeliverUpdate_WindowUpdate{msg}
+		return true, err
+	case 4: // Type.Hello
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Hello)
+		err := b.DecodeMessage(msg)
+		m.Type = &DeliverUpdate_Hello{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -759,6 +1271,16 @@ func _DeliverUpdate_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(2<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *DeliverUpdate_WindowUpdate:
+		s := proto.Size(x.WindowUpdate)
+		n += proto.SizeVarint(3<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *DeliverUpdate_Hello:
+		s := proto.Size(x.Hello)
+		n += proto.SizeVarint(4<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -770,36 +1292,136 @@ func _DeliverUpdate_OneofSizer(msg proto.Message) (n int) {
 // This must be a 'block' structure and not a 'batch' structure, although the terminology is slightly confusing
 // The requirement is to allow for a consumer of the orderer to declare the unvalidated blockchain as the definitive
 // blockchain, without breaking the hash chain or existing proof
+// The hash of a block is the hash of its Header alone, which commits to the Data through DataHash, the Metadata is not hashed
 type Block struct {
+	Header   *BlockHeader   `protobuf:"bytes,1,opt,name=Header,json=header" json:"Header,omitempty"`
+	Data     *BlockData     `protobuf:"bytes,2,opt,name=Data,json=data" json:"Data,omitempty"`
+	Metadata *BlockMetadata `protobuf:"bytes,3,opt,name=Metadata,json=metadata" json:"Metadata,omitempty"`
+}
+
+func (m *Block) Reset()                    { *m = Block{} }
+func (m *Block) String() string            { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()               {}
+func (*Block) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *Block) GetHeader() *BlockHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *Block) GetData() *BlockData {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *Block) GetMetadata() *BlockMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type BlockHeader struct {
+	Number       uint64 `protobuf:"varint,1,opt,name=Number,json=number" json:"Number,omitempty"`
+	PreviousHash []byte `protobuf:"bytes,2,opt,name=PreviousHash,json=previousHash,proto3" json:"PreviousHash,omitempty"`
+	DataHash     []byte `protobuf:"bytes,3,opt,name=DataHash,json=dataHash,proto3" json:"DataHash,omitempty"`
+}
+
+func (m *BlockHeader) Reset()                    { *m = BlockHeader{} }
+func (m *BlockHeader) String() string            { return proto.CompactTextString(m) }
+func (*BlockHeader) ProtoMessage()               {}
+func (*BlockHeader) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+type BlockData struct {
+	Messages []*BroadcastMessage `protobuf:"bytes,1,rep,name=Messages,json=messages" json:"Messages,omitempty"`
+}
+
+func (m *BlockData) Reset()                    { *m = BlockData{} }
+func (m *BlockData) String() string            { return proto.CompactTextString(m) }
+func (*BlockData) ProtoMessage()               {}
+func (*BlockData) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *BlockData) GetMessages() []*BroadcastMessage {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+// Metadata holds entries which are computed after the header, such as the proof of the consenter, indexed by BlockMetadataIndex
+type BlockMetadata struct {
+	Metadata [][]byte `protobuf:"bytes,1,rep,name=Metadata,json=metadata,proto3" json:"Metadata,omitempty"`
+}
+
+func (m *BlockMetadata) Reset()                    { *m = BlockMetadata{} }
+func (m *BlockMetadata) String() string            { return proto.CompactTextString(m) }
+func (*BlockMetadata) ProtoMessage()               {}
+func (*BlockMetadata) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+// BlockSignature is the signature of an orderer over the hash of a block's header
+type BlockSignature struct {
+	Identity  []byte `protobuf:"bytes,1,opt,name=Identity,json=identity,proto3" json:"Identity,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=Signature,json=signature,proto3" json:"Signature,omitempty"`
+}
+
+func (m *BlockSignature) Reset()                    { *m = BlockSignature{} }
+func (m *BlockSignature) String() string            { return proto.CompactTextString(m) }
+func (*BlockSignature) ProtoMessage()               {}
+func (*BlockSignature) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+// LegacyBlock is the shape of blocks written before the header was split out, it is retained only so that such ledgers may be read
+type LegacyBlock struct {
 	Number   uint64              `protobuf:"varint,2,opt,name=Number,json=number" json:"Number,omitempty"`
 	PrevHash []byte              `protobuf:"bytes,3,opt,name=PrevHash,json=prevHash,proto3" json:"PrevHash,omitempty"`
 	Proof    []byte              `protobuf:"bytes,4,opt,name=Proof,json=proof,proto3" json:"Proof,omitempty"`
 	Messages []*BroadcastMessage `protobuf:"bytes,5,rep,name=Messages,json=messages" json:"Messages,omitempty"`
+	DataHash []byte              `protobuf:"bytes,6,opt,name=DataHash,json=dataHash,proto3" json:"DataHash,omitempty"`
 }
 
-func (m *Block) Reset()                    { *m = Block{} }
-func (m *Block) String() string            { return proto.CompactTextString(m) }
-func (*Block) ProtoMessage()               {}
-func (*Block) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+func (m *LegacyBlock) Reset()                    { *m = LegacyBlock{} }
+func (m *LegacyBlock) String() string            { return proto.CompactTextString(m) }
+func (*LegacyBlock) ProtoMessage()               {}
+func (*LegacyBlock) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
 
-func (m *Block) GetMessages() []*BroadcastMessage {
+func (m *LegacyBlock) GetMessages() []*BroadcastMessage {
 	if m != nil {
 		return m.Messages
 	}
 	return nil
 }
 
+// Heartbeat is sent on a Deliver stream which has otherwise been idle, and may be ignored by clients
+type Heartbeat struct {
+	Height uint64 `protobuf:"varint,1,opt,name=Height,json=height" json:"Height,omitempty"`
+}
+
+func (m *Heartbeat) Reset()                    { *m = Heartbeat{} }
+func (m *Heartbeat) String() string            { return proto.CompactTextString(m) }
+func (*Heartbeat) ProtoMessage()               {}
+func (*Heartbeat) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+// Every Deliver stream the orderer ends is ended by an Error, SUCCESS once the range requested has been delivered or the client closed its side,
+// and another status otherwise, so a stream which ends without one failed in transport and may be resumed from the last block received
 type DeliverResponse struct {
 	// Types that are valid to be assigned to Type:
 	//	*DeliverResponse_Error
 	//	*DeliverResponse_Block
-	Type isDeliverResponse_Type `protobuf_oneof:"Type"`
+	//	*DeliverResponse_Heartbeat
+	//	*DeliverResponse_Hello
+	Type       isDeliverResponse_Type `protobuf_oneof:"Type"`
+	Cursor     []byte                 `protobuf:"bytes,4,opt,name=Cursor,json=cursor,proto3" json:"Cursor,omitempty"`
+	RetryAfter uint64                 `protobuf:"varint,5,opt,name=RetryAfter,json=retryAfter" json:"RetryAfter,omitempty"`
+	Info       string                 `protobuf:"bytes,6,opt,name=Info,json=info" json:"Info,omitempty"`
 }
 
 func (m *DeliverResponse) Reset()                    { *m = DeliverResponse{} }
 func (m *DeliverResponse) String() string            { return proto.CompactTextString(m) }
 func (*DeliverResponse) ProtoMessage()               {}
-func (*DeliverResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+func (*DeliverResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
 
 type isDeliverResponse_Type interface {
 	isDeliverResponse_Type()
@@ -811,9 +1433,17 @@ type DeliverResponse_Error struct {
 type DeliverResponse_Block struct {
 	Block *Block `protobuf:"bytes,2,opt,name=Block,json=block,oneof"`
 }
+type DeliverResponse_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,3,opt,name=Heartbeat,json=heartbeat,oneof"`
+}
+type DeliverResponse_Hello struct {
+	Hello *Hello `protobuf:"bytes,7,opt,name=Hello,json=hello,oneof"`
+}
 
-func (*DeliverResponse_Error) isDeliverResponse_Type() {}
-func (*DeliverResponse_Block) isDeliverResponse_Type() {}
+func (*DeliverResponse_Error) isDeliverResponse_Type()     {}
+func (*DeliverResponse_Block) isDeliverResponse_Type()     {}
+func (*DeliverResponse_Heartbeat) isDeliverResponse_Type() {}
+func (*DeliverResponse_Hello) isDeliverResponse_Type()     {}
 
 func (m *DeliverResponse) GetType() isDeliverResponse_Type {
 	if m != nil {
@@ -836,11 +1466,27 @@ func (m *DeliverResponse) GetBlock() *Block {
 	return nil
 }
 
+func (m *DeliverResponse) GetHeartbeat() *Heartbeat {
+	if x, ok := m.GetType().(*DeliverResponse_Heartbeat); ok {
+		return x.Heartbeat
+	}
+	return nil
+}
+
+func (m *DeliverResponse) GetHello() *Hello {
+	if x, ok := m.GetType().(*DeliverResponse_Hello); ok {
+		return x.Hello
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*DeliverResponse) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _DeliverResponse_OneofMarshaler, _DeliverResponse_OneofUnmarshaler, _DeliverResponse_OneofSizer, []interface{}{
 		(*DeliverResponse_Error)(nil),
 		(*DeliverResponse_Block)(nil),
+		(*DeliverResponse_Heartbeat)(nil),
+		(*DeliverResponse_Hello)(nil),
 	}
 }
 
@@ -856,6 +1502,16 @@ func _DeliverResponse_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Block); err != nil {
 			return err
 		}
+	case *DeliverResponse_Heartbeat:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Heartbeat); err != nil {
+			return err
+		}
+	case *DeliverResponse_Hello:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Hello); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("DeliverResponse.Type has unexpected type %T", x)
@@ -881,6 +1537,22 @@ func _DeliverResponse_OneofUnmarshaler(msg proto.Message, tag, wire int, b *prot
 		err := b.DecodeMessage(msg)
 		m.Type = &DeliverResponse_Block{msg}
 		return true, err
+	case 3: // Type.Heartbeat
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Heartbeat)
+		err := b.DecodeMessage(msg)
+		m.Type = &DeliverResponse_Heartbeat{msg}
+		return true, err
+	case 7: // Type.Hello
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Hello)
+		err := b.DecodeMessage(msg)
+		m.Type = &DeliverResponse_Hello{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -898,6 +1570,16 @@ func _DeliverResponse_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(2<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *DeliverResponse_Heartbeat:
+		s := proto.Size(x.Heartbeat)
+		n += proto.SizeVarint(3<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *DeliverResponse_Hello:
+		s := proto.Size(x.Hello)
+		n += proto.SizeVarint(7<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -905,27 +1587,106 @@ func _DeliverResponse_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+// Cursor is the content of the opaque token sent with each delivered block, clients should not rely on its encoding
+type Cursor struct {
+	ChainID    []byte               `protobuf:"bytes,1,opt,name=ChainID,json=chainID,proto3" json:"ChainID,omitempty"`
+	Number     uint64               `protobuf:"varint,2,opt,name=Number,json=number" json:"Number,omitempty"`
+	Hash       []byte               `protobuf:"bytes,3,opt,name=Hash,json=hash,proto3" json:"Hash,omitempty"`
+	WindowSize uint64               `protobuf:"varint,4,opt,name=WindowSize,json=windowSize" json:"WindowSize,omitempty"`
+	Content    SeekInfo_ContentType `protobuf:"varint,5,opt,name=Content,json=content,enum=atomicbroadcast.SeekInfo_ContentType" json:"Content,omitempty"`
+}
+
+func (m *Cursor) Reset()                    { *m = Cursor{} }
+func (m *Cursor) String() string            { return proto.CompactTextString(m) }
+func (*Cursor) ProtoMessage()               {}
+func (*Cursor) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+// PauseRequest asks the orderer to stop cutting blocks until it is resumed
+type PauseRequest struct {
+	RetryAfter uint64 `protobuf:"varint,1,opt,name=RetryAfter,json=retryAfter" json:"RetryAfter,omitempty"`
+}
+
+func (m *PauseRequest) Reset()                    { *m = PauseRequest{} }
+func (m *PauseRequest) String() string            { return proto.CompactTextString(m) }
+func (*PauseRequest) ProtoMessage()               {}
+func (*PauseRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+type ResumeRequest struct {
+}
+
+func (m *ResumeRequest) Reset()                    { *m = ResumeRequest{} }
+func (m *ResumeRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResumeRequest) ProtoMessage()               {}
+func (*ResumeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+type AdminResponse struct {
+	Status Status `protobuf:"varint,1,opt,name=Status,json=status,enum=atomicbroadcast.Status" json:"Status,omitempty"`
+	Paused bool   `protobuf:"varint,2,opt,name=Paused,json=paused" json:"Paused,omitempty"`
+}
+
+func (m *AdminResponse) Reset()                    { *m = AdminResponse{} }
+func (m *AdminResponse) String() string            { return proto.CompactTextString(m) }
+func (*AdminResponse) ProtoMessage()               {}
+func (*AdminResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+type ValidateConfigResponse struct {
+	Status Status `protobuf:"varint,1,opt,name=Status,json=status,enum=atomicbroadcast.Status" json:"Status,omitempty"`
+	Info   string `protobuf:"bytes,2,opt,name=Info,json=info" json:"Info,omitempty"`
+}
+
+func (m *ValidateConfigResponse) Reset()                    { *m = ValidateConfigResponse{} }
+func (m *ValidateConfigResponse) String() string            { return proto.CompactTextString(m) }
+func (*ValidateConfigResponse) ProtoMessage()               {}
+func (*ValidateConfigResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
 func init() {
+	proto.RegisterType((*Hello)(nil), "atomicbroadcast.Hello")
 	proto.RegisterType((*BroadcastResponse)(nil), "atomicbroadcast.BroadcastResponse")
 	proto.RegisterType((*BroadcastMessage)(nil), "atomicbroadcast.BroadcastMessage")
+	proto.RegisterType((*BroadcastBatch)(nil), "atomicbroadcast.BroadcastBatch")
+	proto.RegisterType((*BroadcastBatchResponse)(nil), "atomicbroadcast.BroadcastBatchResponse")
+	proto.RegisterType((*KafkaMessage)(nil), "atomicbroadcast.KafkaMessage")
 	proto.RegisterType((*SignedData)(nil), "atomicbroadcast.SignedData")
 	proto.RegisterType((*PayloadEnvelope)(nil), "atomicbroadcast.PayloadEnvelope")
 	proto.RegisterType((*Transaction)(nil), "atomicbroadcast.Transaction")
 	proto.RegisterType((*ConfigurationEnvelope)(nil), "atomicbroadcast.ConfigurationEnvelope")
 	proto.RegisterType((*ConfigurationEntry)(nil), "atomicbroadcast.ConfigurationEntry")
 	proto.RegisterType((*Configuration)(nil), "atomicbroadcast.Configuration")
+	proto.RegisterType((*BatchSize)(nil), "atomicbroadcast.BatchSize")
+	proto.RegisterType((*BatchMaxBytes)(nil), "atomicbroadcast.BatchMaxBytes")
+	proto.RegisterType((*BatchTimeout)(nil), "atomicbroadcast.BatchTimeout")
 	proto.RegisterType((*Policy)(nil), "atomicbroadcast.Policy")
+	proto.RegisterType((*MetaPolicy)(nil), "atomicbroadcast.MetaPolicy")
+	proto.RegisterType((*SignatureThresholdPolicy)(nil), "atomicbroadcast.SignatureThresholdPolicy")
 	proto.RegisterType((*SignaturePolicyEnvelope)(nil), "atomicbroadcast.SignaturePolicyEnvelope")
 	proto.RegisterType((*SignaturePolicy)(nil), "atomicbroadcast.SignaturePolicy")
 	proto.RegisterType((*SignaturePolicy_NOutOf)(nil), "atomicbroadcast.SignaturePolicy.NOutOf")
 	proto.RegisterType((*SeekInfo)(nil), "atomicbroadcast.SeekInfo")
 	proto.RegisterType((*Acknowledgement)(nil), "atomicbroadcast.Acknowledgement")
+	proto.RegisterType((*WindowUpdate)(nil), "atomicbroadcast.WindowUpdate")
 	proto.RegisterType((*DeliverUpdate)(nil), "atomicbroadcast.DeliverUpdate")
 	proto.RegisterType((*Block)(nil), "atomicbroadcast.Block")
+	proto.RegisterType((*BlockHeader)(nil), "atomicbroadcast.BlockHeader")
+	proto.RegisterType((*BlockData)(nil), "atomicbroadcast.BlockData")
+	proto.RegisterType((*BlockMetadata)(nil), "atomicbroadcast.BlockMetadata")
+	proto.RegisterType((*BlockSignature)(nil), "atomicbroadcast.BlockSignature")
+	proto.RegisterType((*LegacyBlock)(nil), "atomicbroadcast.LegacyBlock")
+	proto.RegisterType((*Heartbeat)(nil), "atomicbroadcast.Heartbeat")
 	proto.RegisterType((*DeliverResponse)(nil), "atomicbroadcast.DeliverResponse")
+	proto.RegisterType((*Cursor)(nil), "atomicbroadcast.Cursor")
+	proto.RegisterType((*PauseRequest)(nil), "atomicbroadcast.PauseRequest")
+	proto.RegisterType((*ResumeRequest)(nil), "atomicbroadcast.ResumeRequest")
+	proto.RegisterType((*AdminResponse)(nil), "atomicbroadcast.AdminResponse")
+	proto.RegisterType((*ValidateConfigResponse)(nil), "atomicbroadcast.ValidateConfigResponse")
 	proto.RegisterEnum("atomicbroadcast.Status", Status_name, Status_value)
+	proto.RegisterEnum("atomicbroadcast.Feature", Feature_name, Feature_value)
+	proto.RegisterEnum("atomicbroadcast.BlockMetadataIndex", BlockMetadataIndex_name, BlockMetadataIndex_value)
+	proto.RegisterEnum("atomicbroadcast.KafkaMessage_Type", KafkaMessage_Type_name, KafkaMessage_Type_value)
 	proto.RegisterEnum("atomicbroadcast.Configuration_ConfigurationType", Configuration_ConfigurationType_name, Configuration_ConfigurationType_value)
+	proto.RegisterEnum("atomicbroadcast.MetaPolicy_Rule", MetaPolicy_Rule_name, MetaPolicy_Rule_value)
 	proto.RegisterEnum("atomicbroadcast.SeekInfo_StartType", SeekInfo_StartType_name, SeekInfo_StartType_value)
+	proto.RegisterEnum("atomicbroadcast.SeekInfo_ContentType", SeekInfo_ContentType_name, SeekInfo_ContentType_value)
+	proto.RegisterEnum("atomicbroadcast.SeekInfo_StopType", SeekInfo_StopType_name, SeekInfo_StopType_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -941,6 +1702,11 @@ const _ = grpc.SupportPackageIsVersion3
 type AtomicBroadcastClient interface {
 	// broadcast receives a reply of Acknowledgement for each BroadcastMessage in order, indicating success or type of failure
 	Broadcast(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastClient, error)
+	// submitBroadcast orders a single message as if it were sent on its own Broadcast stream, replying as that stream would
+	// When replies are sent once messages are committed, the call returns after the commit unless its deadline expires first
+	SubmitBroadcast(ctx context.Context, in *BroadcastMessage, opts ...grpc.CallOption) (*BroadcastResponse, error)
+	// broadcastBatch is Broadcast for clients which send many small messages, the messages of each batch are received as a stream of them would be
+	BroadcastBatch(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastBatchClient, error)
 	// deliver first requires an update containing a seek message, then a stream of block replies is received.
 	// The receiver may choose to send an Acknowledgement for any block number it receives, however Acknowledgements must never be more than WindowSize apart
 	// To avoid latency, clients will likely acknowledge before the WindowSize has been exhausted, preventing the server from stopping and waiting for an Acknowledgement
@@ -986,8 +1752,48 @@ func (x *atomicBroadcastBroadcastClient) Recv() (*BroadcastResponse, error) {
 	return m, nil
 }
 
+func (c *atomicBroadcastClient) SubmitBroadcast(ctx context.Context, in *BroadcastMessage, opts ...grpc.CallOption) (*BroadcastResponse, error) {
+	out := new(BroadcastResponse)
+	err := grpc.Invoke(ctx, "/atomicbroadcast.AtomicBroadcast/SubmitBroadcast", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicBroadcastClient) BroadcastBatch(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastBatchClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[1], c.cc, "/atomicbroadcast.AtomicBroadcast/BroadcastBatch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &atomicBroadcastBroadcastBatchClient{stream}
+	return x, nil
+}
+
+type AtomicBroadcast_BroadcastBatchClient interface {
+	Send(*BroadcastBatch) error
+	Recv() (*BroadcastBatchResponse, error)
+	grpc.ClientStream
+}
+
+type atomicBroadcastBroadcastBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *atomicBroadcastBroadcastBatchClient) Send(m *BroadcastBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *atomicBroadcastBroadcastBatchClient) Recv() (*BroadcastBatchResponse, error) {
+	m := new(BroadcastBatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *atomicBroadcastClient) Deliver(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_DeliverClient, error) {
-	stream, err := grpc.NewClientStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[1], c.cc, "/atomicbroadcast.AtomicBroadcast/Deliver", opts...)
+	stream, err := grpc.NewClientStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[2], c.cc, "/atomicbroadcast.AtomicBroadcast/Deliver", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -1022,6 +1828,11 @@ func (x *atomicBroadcastDeliverClient) Recv() (*DeliverResponse, error) {
 type AtomicBroadcastServer interface {
 	// broadcast receives a reply of Acknowledgement for each BroadcastMessage in order, indicating success or type of failure
 	Broadcast(AtomicBroadcast_BroadcastServer) error
+	// submitBroadcast orders a single message as if it were sent on its own Broadcast stream, replying as that stream would
+	// When replies are sent once messages are committed, the call returns after the commit unless its deadline expires first
+	SubmitBroadcast(context.Context, *BroadcastMessage) (*BroadcastResponse, error)
+	// broadcastBatch is Broadcast for clients which send many small messages, the messages of each batch are received as a stream of them would be
+	BroadcastBatch(AtomicBroadcast_BroadcastBatchServer) error
 	// deliver first requires an update containing a seek message, then a stream of block replies is received.
 	// The receiver may choose to send an Acknowledgement for any block number it receives, however Acknowledgements must never be more than WindowSize apart
 	// To avoid latency, clients will likely acknowledge before the WindowSize has been exhausted, preventing the server from stopping and waiting for an Acknowledgement
@@ -1058,6 +1869,50 @@ func (x *atomicBroadcastBroadcastServer) Recv() (*BroadcastMessage, error) {
 	return m, nil
 }
 
+func _AtomicBroadcast_SubmitBroadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicBroadcastServer).SubmitBroadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/atomicbroadcast.AtomicBroadcast/SubmitBroadcast",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicBroadcastServer).SubmitBroadcast(ctx, req.(*BroadcastMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicBroadcast_BroadcastBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AtomicBroadcastServer).BroadcastBatch(&atomicBroadcastBroadcastBatchServer{stream})
+}
+
+type AtomicBroadcast_BroadcastBatchServer interface {
+	Send(*BroadcastBatchResponse) error
+	Recv() (*BroadcastBatch, error)
+	grpc.ServerStream
+}
+
+type atomicBroadcastBroadcastBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *atomicBroadcastBroadcastBatchServer) Send(m *BroadcastBatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *atomicBroadcastBroadcastBatchServer) Recv() (*BroadcastBatch, error) {
+	m := new(BroadcastBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _AtomicBroadcast_Deliver_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(AtomicBroadcastServer).Deliver(&atomicBroadcastDeliverServer{stream})
 }
@@ -1087,7 +1942,12 @@ func (x *atomicBroadcastDeliverServer) Recv() (*DeliverUpdate, error) {
 var _AtomicBroadcast_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "atomicbroadcast.AtomicBroadcast",
 	HandlerType: (*AtomicBroadcastServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitBroadcast",
+			Handler:    _AtomicBroadcast_SubmitBroadcast_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Broadcast",
@@ -1095,6 +1955,12 @@ var _AtomicBroadcast_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "BroadcastBatch",
+			Handler:       _AtomicBroadcast_BroadcastBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 		{
 			StreamName:    "Deliver",
 			Handler:       _AtomicBroadcast_Deliver_Handler,
@@ -1105,75 +1971,291 @@ var _AtomicBroadcast_serviceDesc = grpc.ServiceDesc{
 	Metadata: fileDescriptor0,
 }
 
+// Client API for Admin service
+
+type AdminClient interface {
+	// pause flushes any pending batch and then stops cutting blocks, broadcast messages are rejected with SERVICE_UNAVAILABLE while deliver continues normally
+	Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*AdminResponse, error)
+	// resume restarts the cutting of blocks
+	Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*AdminResponse, error)
+	// validateConfig checks a configuration envelope against the current configuration of the default chain, without applying it
+	ValidateConfig(ctx context.Context, in *ConfigurationEnvelope, opts ...grpc.CallOption) (*ValidateConfigResponse, error)
+}
+
+type adminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminClient(cc *grpc.ClientConn) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*AdminResponse, error) {
+	out := new(AdminResponse)
+	err := grpc.Invoke(ctx, "/atomicbroadcast.Admin/Pause", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*AdminResponse, error) {
+	out := new(AdminResponse)
+	err := grpc.Invoke(ctx, "/atomicbroadcast.Admin/Resume", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ValidateConfig(ctx context.Context, in *ConfigurationEnvelope, opts ...grpc.CallOption) (*ValidateConfigResponse, error) {
+	out := new(ValidateConfigResponse)
+	err := grpc.Invoke(ctx, "/atomicbroadcast.Admin/ValidateConfig", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Admin service
+
+type AdminServer interface {
+	// pause flushes any pending batch and then stops cutting blocks, broadcast messages are rejected with SERVICE_UNAVAILABLE while deliver continues normally
+	Pause(context.Context, *PauseRequest) (*AdminResponse, error)
+	// resume restarts the cutting of blocks
+	Resume(context.Context, *ResumeRequest) (*AdminResponse, error)
+	// validateConfig checks a configuration envelope against the current configuration of the default chain, without applying it
+	ValidateConfig(context.Context, *ConfigurationEnvelope) (*ValidateConfigResponse, error)
+}
+
+func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
+	s.RegisterService(&_Admin_serviceDesc, srv)
+}
+
+func _Admin_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/atomicbroadcast.Admin/Pause",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Pause(ctx, req.(*PauseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/atomicbroadcast.Admin/Resume",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Resume(ctx, req.(*ResumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ValidateConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigurationEnvelope)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ValidateConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/atomicbroadcast.Admin/ValidateConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ValidateConfig(ctx, req.(*ConfigurationEnvelope))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Admin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "atomicbroadcast.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Pause",
+			Handler:    _Admin_Pause_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _Admin_Resume_Handler,
+		},
+		{
+			MethodName: "ValidateConfig",
+			Handler:    _Admin_ValidateConfig_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: fileDescriptor0,
+}
+
 func init() { proto.RegisterFile("ab.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1063 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x94, 0x56, 0x51, 0x6f, 0xe3, 0x44,
-	0x10, 0xae, 0x13, 0xdb, 0x71, 0xa6, 0xe9, 0xc5, 0xb7, 0x70, 0x77, 0xa1, 0xa0, 0x53, 0x31, 0x08,
-	0x0a, 0x0f, 0xb9, 0x53, 0x91, 0x10, 0x08, 0x78, 0x48, 0x62, 0x47, 0x17, 0x51, 0x92, 0x9c, 0x9d,
-	0xf4, 0x78, 0xab, 0x1c, 0x67, 0xd3, 0xb3, 0x9a, 0x78, 0x8d, 0xd7, 0x6d, 0xd5, 0xfb, 0x0d, 0x20,
-	0x9d, 0x04, 0xe2, 0x17, 0xf0, 0x3b, 0xee, 0x85, 0x57, 0xfe, 0x0f, 0xaf, 0xec, 0xae, 0xd7, 0xbe,
-	0x24, 0x6e, 0xa9, 0xee, 0xa5, 0xdd, 0x99, 0x9d, 0x99, 0xfd, 0xe6, 0xdb, 0x6f, 0xc7, 0x01, 0xc3,
-	0x9f, 0xb5, 0xe3, 0x84, 0xa4, 0x04, 0x35, 0xfd, 0x94, 0xac, 0xc2, 0x60, 0x96, 0x10, 0x7f, 0x1e,
-	0xf8, 0x34, 0xb5, 0x6c, 0xb8, 0xdf, 0xcd, 0x0d, 0x17, 0xd3, 0x98, 0x44, 0x14, 0xa3, 0x27, 0xa0,
-	0x7b, 0xa9, 0x9f, 0x5e, 0xd0, 0x96, 0x72, 0xa0, 0x1c, 0xde, 0x3b, 0x7a, 0xd4, 0xde, 0x4a, 0x6b,
-	0x67, 0xdb, 0xae, 0x4e, 0xc5, 0x7f, 0xeb, 0x33, 0x30, 0x8b, 0x2a, 0x3f, 0x61, 0x4a, 0xfd, 0x33,
-	0x8c, 0x10, 0xa8, 0xb6, 0x9f, 0xfa, 0xa2, 0x44, 0xc3, 0x55, 0xe7, 0x6c, 0x6d, 0x4d, 0x00, 0xbc,
-	0xf0, 0x2c, 0xc2, 0x73, 0xbe, 0x83, 0x0e, 0xa1, 0x39, 0xf6, 0xaf, 0x97, 0x2c, 0xcf, 0x89, 0x2e,
-	0xf1, 0x92, 0xc4, 0x58, 0x06, 0x37, 0xe3, 0x4d, 0x37, 0xfa, 0x08, 0xea, 0x3c, 0x8f, 0x9d, 0x95,
-	0xe0, 0x56, 0x45, 0xc4, 0xd4, 0x69, 0xee, 0xb0, 0x7a, 0xa5, 0x3a, 0xa8, 0x05, 0x35, 0xe9, 0x92,
-	0x25, 0x6b, 0xb2, 0x24, 0x7a, 0xc8, 0x7a, 0xe3, 0x10, 0x12, 0x59, 0x47, 0xa7, 0xc2, 0xb2, 0xfe,
-	0x52, 0x60, 0x77, 0x92, 0xf8, 0x11, 0xf5, 0x83, 0x34, 0x24, 0x11, 0xab, 0xa0, 0x8f, 0x62, 0xff,
-	0x97, 0x0b, 0x89, 0xe9, 0xd9, 0x8e, 0xab, 0x13, 0x61, 0xa3, 0xaf, 0xe1, 0x41, 0x8f, 0x44, 0x8b,
-	0xf0, 0xec, 0x22, 0xf1, 0x79, 0x68, 0x01, 0xbe, 0x22, 0x03, 0x1f, 0x04, 0x37, 0x6d, 0xa3, 0xef,
-	0xb2, 0xe6, 0x05, 0x66, 0xda, 0xaa, 0x1e, 0x54, 0x0f, 0x77, 0x8f, 0x3e, 0x2c, 0x33, 0x5b, 0xf0,
-	0xe3, 0x42, 0xd1, 0x22, 0xed, 0xea, 0xa0, 0x4e, 0xae, 0x63, 0x6c, 0xfd, 0xaa, 0xdc, 0x72, 0x3a,
-	0xda, 0x07, 0xc3, 0xc3, 0x0c, 0x5f, 0x14, 0x64, 0x90, 0x55, 0xd7, 0xa0, 0xd2, 0xe6, 0x74, 0xf4,
-	0x5e, 0xfa, 0x61, 0x34, 0xb0, 0x65, 0xd7, 0xb5, 0x20, 0x33, 0xd1, 0x0f, 0x50, 0x73, 0xa2, 0x34,
-	0x09, 0x0b, 0x44, 0x9f, 0x94, 0x10, 0x6d, 0x1d, 0x97, 0x26, 0xd7, 0x6e, 0x0d, 0x67, 0x39, 0xd6,
-	0x15, 0xa0, 0xf2, 0x36, 0xfa, 0x14, 0xf6, 0x36, 0xbc, 0xf2, 0x0e, 0xf6, 0x36, 0x78, 0xd9, 0xe2,
-	0xa3, 0xf2, 0x4e, 0x7c, 0x58, 0x6f, 0x2a, 0x5b, 0x67, 0xac, 0xf7, 0xa8, 0x6c, 0xf6, 0x78, 0x0f,
-	0x2a, 0xb2, 0xf1, 0xba, 0x5b, 0x09, 0x6d, 0x64, 0x41, 0xe3, 0x98, 0x0b, 0x95, 0xcc, 0xc3, 0x45,
-	0x88, 0xe7, 0xac, 0x71, 0xce, 0x56, 0x63, 0xb9, 0xe6, 0x43, 0x76, 0xc6, 0x77, 0x4b, 0x15, 0x0f,
-	0xe0, 0xe9, 0xff, 0x93, 0xb2, 0x69, 0xf1, 0x3c, 0x57, 0x4d, 0xd9, 0xdf, 0xe2, 0x0d, 0x68, 0x6f,
-	0xdf, 0x00, 0x6a, 0x03, 0xca, 0x4e, 0x09, 0x44, 0xf4, 0x98, 0x2c, 0xc3, 0xe0, 0xba, 0xa5, 0x0b,
-	0x74, 0x68, 0x55, 0xda, 0xb1, 0xa6, 0x70, 0xbf, 0x54, 0x1e, 0x01, 0xe8, 0xd9, 0xb6, 0xb9, 0xc3,
-	0xd7, 0x7d, 0x7f, 0x96, 0x84, 0x81, 0xa9, 0xa0, 0x3a, 0x68, 0x82, 0x04, 0xb3, 0x82, 0x0c, 0x50,
-	0x3d, 0xb2, 0x24, 0x66, 0x95, 0x3b, 0x7f, 0xf4, 0x17, 0xe7, 0xbe, 0xa9, 0x72, 0xe7, 0xb8, 0xdb,
-	0x9f, 0x98, 0x9a, 0xb5, 0xc8, 0x2b, 0xa0, 0x09, 0x34, 0x8b, 0x7b, 0x90, 0x68, 0x38, 0x57, 0xbb,
-	0x47, 0x87, 0x37, 0x5e, 0xc6, 0x5a, 0x5c, 0xae, 0x3d, 0xa6, 0xf9, 0x26, 0xdd, 0xdc, 0x2a, 0x04,
-	0xfb, 0x9b, 0x02, 0x8f, 0x6e, 0x49, 0xe3, 0x57, 0x76, 0x82, 0x13, 0x9a, 0x2b, 0x44, 0x73, 0x6b,
-	0x97, 0x99, 0x89, 0xbe, 0xc9, 0xd1, 0x49, 0x28, 0x07, 0x77, 0x41, 0x71, 0xf5, 0x38, 0xeb, 0xe6,
-	0x31, 0xc0, 0x60, 0xce, 0xe4, 0x19, 0xa6, 0xb9, 0xa6, 0x1b, 0x2e, 0x84, 0x85, 0xc7, 0xfa, 0x47,
-	0x29, 0xb5, 0xcb, 0xc6, 0x8b, 0x91, 0xc9, 0xac, 0x7b, 0x9d, 0x01, 0x61, 0x0d, 0x19, 0x54, 0x7a,
-	0xd8, 0x13, 0x51, 0xfb, 0x09, 0x59, 0x49, 0x24, 0x9f, 0xdf, 0x85, 0xa4, 0x3d, 0x1c, 0x5d, 0xa4,
-	0xa3, 0x05, 0x2b, 0xa1, 0x2e, 0x58, 0xda, 0xfe, 0x04, 0xf4, 0xcc, 0x83, 0x1a, 0xa0, 0x0c, 0x65,
-	0xa3, 0x4a, 0x84, 0xbe, 0x07, 0x43, 0x24, 0x84, 0x85, 0xf8, 0xef, 0x6e, 0xd2, 0x88, 0x65, 0x46,
-	0x41, 0xef, 0xdf, 0x0a, 0x7f, 0xf6, 0xf8, 0x7c, 0x10, 0x2d, 0x08, 0xfa, 0x16, 0x34, 0x36, 0x98,
-	0x93, 0x54, 0x8e, 0xed, 0xf2, 0x53, 0xce, 0x23, 0xdb, 0x22, 0x4c, 0x08, 0x55, 0xa3, 0x7c, 0xc9,
-	0x67, 0xb1, 0x17, 0xe3, 0x40, 0x88, 0x7f, 0x78, 0xb1, 0x9a, 0xc9, 0xf9, 0xa8, 0xb2, 0x8b, 0xdd,
-	0x74, 0x73, 0x82, 0x5f, 0x84, 0xd1, 0x9c, 0x5c, 0x79, 0xe1, 0x2b, 0x2c, 0xdf, 0x0e, 0x5c, 0x15,
-	0x1e, 0xeb, 0x88, 0xcd, 0xea, 0xbc, 0x3a, 0xd7, 0xe6, 0xd0, 0x79, 0xe1, 0x78, 0x93, 0x4c, 0xa7,
-	0xa3, 0x63, 0x9b, 0xaf, 0x15, 0xb4, 0xc7, 0x82, 0xc6, 0x4e, 0x6f, 0xd0, 0x1f, 0x38, 0xb6, 0x59,
-	0xb1, 0xbe, 0x80, 0x66, 0x27, 0x38, 0x8f, 0xc8, 0xd5, 0x12, 0xcf, 0xcf, 0xf0, 0x8a, 0x5d, 0x16,
-	0x9f, 0xd3, 0x12, 0x47, 0x36, 0xcc, 0xf4, 0x48, 0x58, 0xd6, 0x9f, 0x0a, 0xec, 0xd9, 0x78, 0x19,
-	0x32, 0xa5, 0x4c, 0x63, 0xf6, 0xa0, 0x30, 0x3a, 0x2e, 0x25, 0x8b, 0x94, 0x9b, 0xf8, 0xdc, 0x8a,
-	0xe3, 0xba, 0xf5, 0xb7, 0xce, 0x7d, 0xc2, 0x9e, 0x0d, 0x63, 0x49, 0xde, 0xf6, 0x07, 0xb7, 0x52,
-	0xc8, 0xef, 0x97, 0xb2, 0x75, 0x71, 0x13, 0xaf, 0x15, 0xd0, 0xba, 0x4b, 0x12, 0x9c, 0xaf, 0x41,
-	0xaf, 0xac, 0x43, 0xe7, 0x13, 0x7a, 0x9c, 0xe0, 0xcb, 0x67, 0x3e, 0x7d, 0x29, 0x78, 0x6b, 0xb0,
-	0xfb, 0x94, 0x36, 0x7a, 0x1f, 0xb4, 0x71, 0x42, 0xc8, 0x42, 0x0c, 0x9c, 0x86, 0xab, 0xc5, 0xdc,
-	0x60, 0xd2, 0x33, 0xe4, 0xe7, 0x94, 0xb2, 0x19, 0xc2, 0x35, 0xf2, 0x71, 0x09, 0xd0, 0xf6, 0x87,
-	0xd7, 0x35, 0x56, 0x32, 0xc5, 0x7a, 0x05, 0x4d, 0x49, 0xd5, 0xda, 0xa7, 0x5d, 0x73, 0x92, 0x84,
-	0x24, 0x77, 0x7c, 0xd9, 0x59, 0x77, 0x1a, 0xe6, 0x71, 0x6c, 0x5c, 0x65, 0x5d, 0x49, 0x42, 0x1e,
-	0x96, 0xcf, 0xe7, 0xbb, 0x3c, 0x7e, 0xc6, 0x17, 0x39, 0x1d, 0x5f, 0xfa, 0xf9, 0x6f, 0x08, 0xb4,
-	0x0b, 0x35, 0x6f, 0xda, 0xeb, 0x39, 0x9e, 0xc7, 0x44, 0x60, 0xc2, 0x6e, 0xb7, 0x63, 0x9f, 0xba,
-	0xce, 0xf3, 0x29, 0x57, 0xc2, 0xeb, 0x2a, 0x9b, 0xce, 0xf5, 0xfe, 0xc8, 0xed, 0x0e, 0x6c, 0xdb,
-	0x19, 0x9a, 0xbf, 0x0b, 0x7b, 0x38, 0x9a, 0x9c, 0xf6, 0x47, 0xd3, 0xa1, 0x6d, 0xfe, 0x51, 0x65,
-	0x43, 0xe2, 0x3d, 0xcf, 0x71, 0x4f, 0x06, 0x3d, 0xe7, 0x74, 0x3a, 0xec, 0x9c, 0x74, 0x06, 0xc7,
-	0x9d, 0xee, 0xb1, 0x63, 0xfe, 0x5b, 0x3d, 0x7a, 0xc3, 0x9e, 0x72, 0x47, 0xa0, 0x29, 0x38, 0x40,
-	0x3f, 0x43, 0xfd, 0xad, 0x71, 0x37, 0x59, 0xfb, 0xd6, 0xed, 0x21, 0x39, 0x67, 0xd6, 0xce, 0xa1,
-	0xf2, 0x54, 0x41, 0xcf, 0xa1, 0x26, 0xc9, 0x44, 0x8f, 0x4b, 0x49, 0x1b, 0x8a, 0xdc, 0x3f, 0xb8,
-	0x6d, 0x7f, 0xb3, 0xe4, 0x4c, 0x17, 0x3f, 0xca, 0xbe, 0xfa, 0x2f, 0x00, 0x00, 0xff, 0xff, 0x52,
-	0xe5, 0xdc, 0xcc, 0xa0, 0x09, 0x00, 0x00,
+	// 2347 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x39, 0x4f, 0x73, 0xe3, 0x48,
+	0xf5, 0x96, 0x6d, 0xc9, 0xf6, 0xb3, 0x13, 0x6b, 0xfa, 0xb7, 0x93, 0xf5, 0x2f, 0x0c, 0x4b, 0xd0,
+	0xc2, 0xac, 0x67, 0xa0, 0xbc, 0xbb, 0x61, 0x6a, 0x0b, 0x16, 0x06, 0x90, 0x6d, 0x79, 0xec, 0x59,
+	0xc7, 0xca, 0xb6, 0xe4, 0xcc, 0x66, 0x29, 0xca, 0x28, 0x76, 0x27, 0x51, 0x8d, 0x6d, 0x79, 0x25,
+	0x79, 0x32, 0xe1, 0x48, 0x71, 0x03, 0xaa, 0xa8, 0xda, 0x3d, 0x70, 0xe1, 0x4c, 0x71, 0xd8, 0xa2,
+	0x6a, 0x3f, 0x04, 0x45, 0x15, 0x7c, 0x1e, 0xb8, 0x70, 0xa0, 0xba, 0xd5, 0x92, 0x25, 0x2b, 0x4e,
+	0x32, 0x03, 0x27, 0xeb, 0xbd, 0x7e, 0xfd, 0xfa, 0xfd, 0x7f, 0xaf, 0xdb, 0x50, 0xb4, 0x4e, 0x1a,
+	0x0b, 0xd7, 0xf1, 0x1d, 0x54, 0xb5, 0x7c, 0x67, 0x66, 0x8f, 0x4f, 0x5c, 0xc7, 0x9a, 0x8c, 0x2d,
+	0xcf, 0x57, 0x1e, 0x83, 0xd8, 0x25, 0xd3, 0xa9, 0x83, 0x1e, 0x41, 0xb1, 0x43, 0x2c, 0x7f, 0xe9,
+	0x12, 0xaf, 0x26, 0xec, 0xe5, 0xea, 0xdb, 0xfb, 0xb5, 0xc6, 0x1a, 0x71, 0x83, 0x13, 0xe0, 0xe2,
+	0x29, 0xa7, 0x54, 0x7e, 0x9d, 0x85, 0x3b, 0xcd, 0x70, 0x1d, 0x13, 0x6f, 0xe1, 0xcc, 0x3d, 0x82,
+	0xde, 0x05, 0xc9, 0xf0, 0x2d, 0x7f, 0x49, 0x39, 0x09, 0xf5, 0xed, 0xfd, 0x37, 0x53, 0x9c, 0x82,
+	0x65, 0x2c, 0x79, 0xec, 0x17, 0xed, 0x41, 0xb9, 0x39, 0x75, 0xc6, 0xcf, 0x07, 0xcb, 0xd9, 0x09,
+	0x71, 0x6b, 0xd9, 0x3d, 0xa1, 0x9e, 0xc7, 0xe5, 0x93, 0x15, 0x0a, 0xbd, 0x01, 0x62, 0x6f, 0x3e,
+	0x21, 0x2f, 0x6b, 0x39, 0xb6, 0x26, 0xda, 0x14, 0x40, 0x6f, 0x01, 0x60, 0xe2, 0xbb, 0x97, 0xea,
+	0xa9, 0x4f, 0xdc, 0x5a, 0x9e, 0x2d, 0x81, 0x1b, 0x61, 0x10, 0x82, 0x7c, 0x6f, 0x7e, 0xea, 0xd4,
+	0xc4, 0x3d, 0xa1, 0x5e, 0xc2, 0x79, 0x7b, 0x7e, 0xea, 0xa0, 0x6f, 0xc1, 0x56, 0xcb, 0x71, 0x5d,
+	0x32, 0xb5, 0x7c, 0xdb, 0x99, 0xf7, 0xda, 0x35, 0x69, 0x4f, 0xa8, 0x57, 0xf0, 0xd6, 0x38, 0x8e,
+	0x44, 0xdf, 0xe5, 0x76, 0xa9, 0x15, 0xf6, 0x84, 0x7a, 0x79, 0x7f, 0x27, 0xa5, 0x01, 0x5b, 0xc5,
+	0xe2, 0x39, 0xfd, 0x51, 0xbe, 0x10, 0x40, 0x8e, 0xcc, 0x70, 0x40, 0x3c, 0xcf, 0x3a, 0x23, 0xf4,
+	0xf0, 0xb6, 0xe5, 0x5b, 0xcc, 0x06, 0x15, 0x9c, 0x9f, 0x58, 0xbe, 0x85, 0x6a, 0x50, 0x68, 0x9d,
+	0x5b, 0x36, 0x3d, 0x36, 0xcb, 0xd0, 0x85, 0x71, 0x00, 0xa6, 0xc5, 0xca, 0x5d, 0x2b, 0x56, 0xfe,
+	0x36, 0x62, 0xe9, 0xb0, 0x1d, 0x49, 0xd5, 0xb4, 0xfc, 0xf1, 0x39, 0x7a, 0x0c, 0x45, 0x2e, 0x5e,
+	0xe0, 0xe5, 0xf2, 0xfe, 0x37, 0x53, 0x2c, 0xd6, 0x15, 0xc1, 0xc5, 0x19, 0xdf, 0xa2, 0x7c, 0x0a,
+	0x3b, 0x49, 0x86, 0x91, 0xcb, 0x7f, 0x0a, 0xa5, 0xf0, 0x3b, 0xe4, 0xac, 0x6c, 0xe6, 0x1c, 0x92,
+	0xe2, 0x92, 0x1b, 0x6e, 0x52, 0x3e, 0x17, 0xa0, 0xf2, 0x91, 0x75, 0xfa, 0xdc, 0x0a, 0xed, 0xf7,
+	0x01, 0xe4, 0xcd, 0xcb, 0x05, 0xe1, 0x31, 0x94, 0xe6, 0x16, 0x27, 0x6e, 0x50, 0x4a, 0x9c, 0xf7,
+	0x2f, 0x17, 0x84, 0xda, 0xf8, 0xd0, 0xba, 0x9c, 0x3a, 0xd6, 0x24, 0xb4, 0xf1, 0x22, 0x00, 0x95,
+	0xf7, 0x03, 0x8e, 0xa8, 0x0c, 0x05, 0xac, 0x3d, 0x19, 0xf6, 0x55, 0x2c, 0x67, 0x50, 0x15, 0xca,
+	0x66, 0xef, 0x40, 0x1b, 0x99, 0xfa, 0xa8, 0x35, 0x34, 0x65, 0x81, 0xae, 0xb6, 0xf4, 0xc1, 0x40,
+	0x6b, 0x99, 0x72, 0x56, 0x31, 0x01, 0x0c, 0xfb, 0x6c, 0x4e, 0x26, 0xd4, 0x95, 0xa8, 0x0e, 0x55,
+	0xce, 0x5a, 0x9b, 0xbf, 0x20, 0x53, 0x87, 0x4b, 0x57, 0xc1, 0xd5, 0x45, 0x12, 0x8d, 0xee, 0x41,
+	0x89, 0xee, 0x63, 0x69, 0xc2, 0xc5, 0x28, 0x79, 0x21, 0x42, 0x69, 0xa5, 0xf8, 0xc4, 0xa5, 0x16,
+	0x12, 0x52, 0xa3, 0x1d, 0x90, 0x98, 0x08, 0x2e, 0xe7, 0x23, 0x79, 0x0c, 0x52, 0xfe, 0x26, 0x40,
+	0xd9, 0x74, 0xad, 0xb9, 0x67, 0x8d, 0x69, 0x74, 0xa0, 0x1a, 0x48, 0xfa, 0xc2, 0xfa, 0x6c, 0xc9,
+	0x65, 0xea, 0x66, 0xb0, 0xe4, 0x30, 0x18, 0x7d, 0x00, 0x77, 0x5b, 0xce, 0xfc, 0xd4, 0x3e, 0x5b,
+	0xba, 0x2c, 0x90, 0x22, 0xe1, 0xb3, 0x9c, 0xf0, 0xee, 0xf8, 0xaa, 0x65, 0xa4, 0x40, 0xb9, 0xe5,
+	0x12, 0xcb, 0x27, 0x2c, 0x66, 0x6b, 0x79, 0x4e, 0x5d, 0x1e, 0xaf, 0x90, 0xe8, 0x87, 0x81, 0x81,
+	0x78, 0xe5, 0xc8, 0x31, 0xcf, 0x7f, 0x2d, 0x9d, 0xef, 0x91, 0x0d, 0x31, 0x44, 0x66, 0xf0, 0x9a,
+	0x52, 0xe0, 0x10, 0xe5, 0xaf, 0xc2, 0x06, 0x09, 0xd1, 0x2e, 0x14, 0x0d, 0xf2, 0xd9, 0x92, 0xcc,
+	0xc7, 0x81, 0x5a, 0x79, 0x5c, 0xf4, 0x38, 0x7c, 0x4d, 0x32, 0x3d, 0x86, 0x82, 0x36, 0xf7, 0x5d,
+	0x3b, 0x92, 0xe8, 0xed, 0x94, 0x44, 0x6b, 0xc7, 0xf9, 0xee, 0x25, 0x2e, 0x90, 0x60, 0x0f, 0x75,
+	0xde, 0xc0, 0xf1, 0x9b, 0xe4, 0xd4, 0x71, 0x09, 0xd3, 0x3a, 0x87, 0x4b, 0xf3, 0x10, 0x41, 0x45,
+	0x1a, 0x38, 0x7e, 0x50, 0x72, 0x44, 0xb6, 0x58, 0x9c, 0x73, 0x58, 0xb9, 0x00, 0x94, 0x66, 0x1c,
+	0xe4, 0x76, 0x0c, 0xcb, 0x3d, 0xbc, 0x95, 0xb0, 0xfa, 0x9a, 0x25, 0xb3, 0xaf, 0x64, 0x49, 0xe5,
+	0xef, 0xd9, 0xb5, 0x33, 0xe2, 0xd6, 0x11, 0x92, 0xd6, 0xd9, 0x86, 0x2c, 0x37, 0x59, 0x09, 0x67,
+	0xed, 0x36, 0x52, 0xa0, 0xd2, 0xa7, 0xe9, 0xee, 0x4c, 0xec, 0x53, 0x9b, 0x4c, 0x78, 0x89, 0xad,
+	0x4c, 0x63, 0x38, 0xd4, 0xe6, 0xc9, 0x98, 0x67, 0xc9, 0xf8, 0xde, 0xf5, 0xe6, 0x4c, 0x42, 0xb1,
+	0xd4, 0x0c, 0x4b, 0xa2, 0x18, 0x2b, 0x89, 0x0d, 0x40, 0xc1, 0x29, 0x63, 0x46, 0x7d, 0xe8, 0x4c,
+	0xed, 0xf1, 0x25, 0x2b, 0xca, 0x25, 0x8c, 0x66, 0xa9, 0x15, 0xe5, 0x04, 0xee, 0xa4, 0xd8, 0x23,
+	0x00, 0x29, 0x58, 0x96, 0x33, 0xf4, 0xbb, 0x63, 0x9d, 0xb8, 0xf6, 0x58, 0x16, 0x50, 0x09, 0x44,
+	0x66, 0x04, 0x39, 0x8b, 0x8a, 0x90, 0x37, 0x9c, 0xa9, 0x23, 0xe7, 0x28, 0x92, 0xd5, 0x0e, 0x39,
+	0x4f, 0x91, 0x87, 0xcd, 0x8e, 0x29, 0x8b, 0x34, 0xeb, 0x75, 0x77, 0x42, 0x5c, 0xe2, 0xca, 0x92,
+	0x32, 0x83, 0x12, 0x2b, 0x6f, 0x86, 0xfd, 0x4b, 0xe6, 0xef, 0x58, 0xcd, 0x14, 0xea, 0x5b, 0xab,
+	0x82, 0xc8, 0xd6, 0xac, 0x97, 0xcd, 0x4b, 0x9f, 0x79, 0x2c, 0x58, 0xe3, 0x30, 0x2d, 0x16, 0x07,
+	0xd6, 0x4b, 0xbe, 0x35, 0x20, 0xc9, 0x31, 0x92, 0xea, 0x2c, 0x89, 0x56, 0xbe, 0x03, 0x5b, 0xec,
+	0xb8, 0x90, 0x55, 0x82, 0xad, 0x90, 0x64, 0xab, 0xd4, 0xa1, 0xc2, 0x88, 0x4d, 0x7b, 0x46, 0x9c,
+	0xa5, 0x4f, 0xfd, 0xcc, 0x3f, 0x19, 0x69, 0x0e, 0x17, 0xfc, 0x00, 0x54, 0xfe, 0x29, 0x84, 0x56,
+	0x41, 0x26, 0x54, 0xa3, 0xd8, 0xe2, 0x16, 0xce, 0xb2, 0x0e, 0x52, 0xbf, 0x32, 0xc0, 0x62, 0x74,
+	0x61, 0x26, 0x76, 0x33, 0xb8, 0xea, 0x25, 0x97, 0xd0, 0xcf, 0x00, 0x45, 0xd4, 0xe6, 0xb9, 0x4b,
+	0xbc, 0x73, 0x67, 0x1a, 0x84, 0x4f, 0x79, 0xff, 0xc1, 0x66, 0xc6, 0x11, 0x69, 0xc0, 0xa6, 0x9b,
+	0xc1, 0xc8, 0x4b, 0xad, 0xa1, 0xf7, 0x21, 0x7f, 0x40, 0x7c, 0x8b, 0x77, 0xba, 0x74, 0x22, 0xd0,
+	0xc5, 0x88, 0x41, 0x7e, 0x46, 0x7c, 0x2b, 0x2a, 0x27, 0xbf, 0x15, 0x00, 0x56, 0xcb, 0xe8, 0x11,
+	0xe4, 0xf1, 0x72, 0x1a, 0x36, 0x92, 0xbd, 0x6b, 0x38, 0x35, 0x28, 0x1d, 0xce, 0xbb, 0xcb, 0x29,
+	0xa1, 0x33, 0x89, 0xb1, 0x3c, 0x61, 0x78, 0x9b, 0xe7, 0x63, 0x09, 0x97, 0xbd, 0x15, 0x4a, 0xb9,
+	0x1f, 0xf0, 0x45, 0x05, 0xc8, 0xa9, 0x83, 0x63, 0x39, 0xc3, 0x3e, 0xfa, 0x7d, 0x59, 0x40, 0x15,
+	0x28, 0x1e, 0xa8, 0x4f, 0x75, 0xdc, 0x33, 0x8f, 0xe5, 0xac, 0xd2, 0x85, 0xda, 0x26, 0xdd, 0x51,
+	0x05, 0x84, 0x01, 0x13, 0x4c, 0xc4, 0xc2, 0x9c, 0xce, 0x33, 0xbd, 0x09, 0x99, 0xfb, 0xb6, 0x1f,
+	0x1e, 0x59, 0xc1, 0x60, 0x47, 0x18, 0xe5, 0x77, 0x02, 0xbc, 0xb9, 0xc1, 0x3f, 0x34, 0x0e, 0x8e,
+	0x88, 0xeb, 0x85, 0xe5, 0x45, 0xc4, 0x85, 0x17, 0x01, 0x88, 0xbe, 0x0f, 0x52, 0xc2, 0xe7, 0x7b,
+	0x37, 0xf9, 0x1c, 0x4b, 0x8b, 0x40, 0xba, 0xa4, 0x3c, 0xb9, 0x94, 0x3c, 0xff, 0x10, 0x52, 0x71,
+	0x85, 0xee, 0x41, 0x31, 0xa8, 0x51, 0xcd, 0xcb, 0x40, 0x90, 0x6e, 0x06, 0x17, 0x3d, 0x8e, 0x41,
+	0x8f, 0x21, 0xdf, 0x71, 0x9d, 0x19, 0x97, 0xe4, 0x9d, 0x9b, 0x24, 0x69, 0x0c, 0xf4, 0xa5, 0xaf,
+	0x9f, 0x52, 0x0f, 0x9f, 0xba, 0xce, 0x6c, 0xd7, 0x04, 0x29, 0xc0, 0xac, 0x19, 0xee, 0x47, 0x50,
+	0x4c, 0x78, 0xea, 0x36, 0x4a, 0x16, 0x17, 0x7c, 0x47, 0x14, 0x37, 0x5f, 0x8a, 0xb4, 0xdb, 0x90,
+	0xe7, 0x74, 0x66, 0x44, 0x3f, 0x00, 0xd1, 0xf0, 0x2d, 0xd7, 0xe7, 0x61, 0x93, 0xee, 0x20, 0x21,
+	0x65, 0x83, 0x91, 0xb1, 0x2a, 0x27, 0x7a, 0xf4, 0x93, 0x66, 0xbe, 0xb1, 0x20, 0x63, 0x56, 0x39,
+	0x13, 0x23, 0x6d, 0xd5, 0x4b, 0xa2, 0xa9, 0x81, 0x9f, 0xd9, 0xf3, 0x89, 0x73, 0x41, 0x2b, 0x0d,
+	0x2f, 0xbc, 0x70, 0x11, 0x61, 0xd0, 0x4f, 0xa0, 0xd0, 0x72, 0xe6, 0x3e, 0x99, 0xfb, 0xbc, 0xf2,
+	0x7e, 0x7b, 0xb3, 0x18, 0x9c, 0x90, 0x09, 0x52, 0x18, 0x07, 0x40, 0xbc, 0x0b, 0x88, 0xc9, 0x2e,
+	0xb0, 0x03, 0x52, 0x6b, 0xe9, 0x7a, 0x8e, 0xcb, 0x07, 0x60, 0x69, 0xcc, 0x20, 0x3a, 0x76, 0x19,
+	0xbe, 0xb3, 0xa8, 0x15, 0x36, 0x8c, 0x5d, 0x31, 0xb5, 0x9d, 0x45, 0x50, 0xdb, 0x3d, 0xdf, 0x59,
+	0x50, 0x55, 0x28, 0x86, 0xeb, 0x5b, 0x0c, 0x54, 0xf1, 0x22, 0x0c, 0xcd, 0xa7, 0x67, 0x96, 0xed,
+	0x77, 0x1c, 0x97, 0xb1, 0x2f, 0xed, 0x09, 0xf5, 0x22, 0x2e, 0x5f, 0xac, 0x50, 0xe8, 0x3e, 0x6c,
+	0x07, 0xa6, 0xb4, 0x67, 0xc4, 0xf3, 0xad, 0xd9, 0xa2, 0x06, 0xac, 0xa0, 0x6d, 0x7b, 0x09, 0xec,
+	0x5a, 0xa3, 0x2c, 0xbf, 0x52, 0xa3, 0xa4, 0xbd, 0x7d, 0xc5, 0xbf, 0x12, 0xf4, 0x76, 0x3f, 0x44,
+	0x28, 0x2a, 0x94, 0x22, 0x6f, 0xd2, 0x46, 0x32, 0xd0, 0x9e, 0x69, 0x86, 0x19, 0x34, 0x15, 0xbd,
+	0xdf, 0xa6, 0xdf, 0x02, 0xda, 0x82, 0x92, 0x71, 0xa8, 0xb5, 0x7a, 0x9d, 0x9e, 0xd6, 0x96, 0xb3,
+	0x14, 0xa4, 0x03, 0xa4, 0x61, 0xaa, 0x07, 0x87, 0x72, 0x4e, 0x79, 0x00, 0xe5, 0x98, 0x27, 0x68,
+	0x87, 0xe9, 0x0c, 0xfb, 0x7d, 0x39, 0x83, 0x64, 0xa8, 0x74, 0x35, 0xb5, 0xad, 0x61, 0x63, 0xa4,
+	0x0f, 0xfa, 0xc7, 0xb2, 0xa0, 0xfc, 0x18, 0x8a, 0xa1, 0x11, 0x29, 0x97, 0xe1, 0xa0, 0xa9, 0x0f,
+	0x07, 0x6d, 0xad, 0x2d, 0x67, 0x10, 0x82, 0x6d, 0xc3, 0xd4, 0x0f, 0x47, 0xab, 0x83, 0x04, 0x3a,
+	0xa9, 0x32, 0x1c, 0x17, 0x2a, 0xab, 0x3c, 0x80, 0xaa, 0x3a, 0x7e, 0x3e, 0x77, 0x2e, 0xa6, 0x64,
+	0x72, 0x46, 0x66, 0xd4, 0xdf, 0x3b, 0x20, 0x71, 0x0f, 0x04, 0xd3, 0x92, 0x34, 0x67, 0x90, 0xd2,
+	0x80, 0x4a, 0x10, 0x68, 0xc3, 0xc5, 0xc4, 0xf2, 0xc9, 0x5a, 0xe0, 0x09, 0xeb, 0x81, 0xa7, 0xfc,
+	0x26, 0x0b, 0x5b, 0x6d, 0x32, 0xb5, 0x5f, 0x10, 0x97, 0xef, 0xe8, 0xa7, 0x0e, 0x63, 0xdb, 0xae,
+	0xca, 0xb4, 0x35, 0x3a, 0xda, 0x3a, 0xac, 0x35, 0x39, 0xdf, 0x85, 0x3c, 0x0d, 0x24, 0x5e, 0x07,
+	0xfe, 0x7f, 0x63, 0x94, 0xd1, 0xcc, 0xf7, 0x08, 0x79, 0x8e, 0x5a, 0x49, 0x05, 0x78, 0x97, 0xf9,
+	0x7a, 0x6a, 0x63, 0x9c, 0xa8, 0x9b, 0xc1, 0x95, 0x8b, 0xb8, 0xd6, 0x8d, 0x5b, 0x5d, 0x9f, 0xba,
+	0x19, 0x7e, 0x81, 0x8a, 0x0a, 0xc3, 0x9f, 0x05, 0x10, 0xd9, 0x05, 0x15, 0x3d, 0x02, 0xa9, 0x4b,
+	0xac, 0x09, 0xb7, 0x6f, 0x79, 0xff, 0x5e, 0xfa, 0x92, 0x43, 0xe9, 0x02, 0x1a, 0x2c, 0x9d, 0xb3,
+	0x5f, 0xd4, 0xe0, 0x73, 0x4f, 0xa0, 0xed, 0xee, 0xd5, 0x7b, 0x58, 0xa8, 0x06, 0x33, 0xd1, 0x87,
+	0x74, 0xe4, 0xf0, 0x2d, 0xfa, 0xcd, 0x15, 0x7d, 0xeb, 0xea, 0x3d, 0x21, 0x15, 0x1d, 0x49, 0x82,
+	0x2f, 0x85, 0x40, 0x39, 0x26, 0xc2, 0xa6, 0x80, 0xa0, 0x43, 0xdf, 0xa1, 0x4b, 0x5e, 0xd8, 0xce,
+	0xd2, 0xeb, 0x5a, 0xde, 0x39, 0x9f, 0xa0, 0x2b, 0x8b, 0x18, 0x8e, 0x8e, 0x21, 0x54, 0x28, 0xb6,
+	0x1e, 0x5c, 0x47, 0x8b, 0x13, 0x0e, 0x2b, 0x4f, 0xa1, 0x14, 0x49, 0xfd, 0xdf, 0x5e, 0x2b, 0xe9,
+	0xfc, 0x13, 0xd7, 0x26, 0x18, 0xb9, 0xb8, 0xfe, 0x02, 0xeb, 0x3a, 0x2b, 0xfd, 0x9e, 0xc2, 0x36,
+	0x23, 0x8e, 0x4a, 0x00, 0xa5, 0xe6, 0x5d, 0xea, 0x92, 0x8f, 0xba, 0x45, 0xde, 0xa3, 0x2e, 0x6f,
+	0xb8, 0x87, 0x7d, 0x25, 0x40, 0xb9, 0x4f, 0xce, 0xac, 0xf1, 0x65, 0xe0, 0xdd, 0x95, 0xb1, 0xb2,
+	0x09, 0x63, 0xed, 0x42, 0x91, 0x1a, 0x2b, 0x6e, 0x88, 0x05, 0x87, 0xe9, 0xcb, 0xc4, 0xa1, 0xeb,
+	0x38, 0xa7, 0xc1, 0xf5, 0x08, 0x8b, 0x0b, 0x0a, 0x24, 0x2c, 0x22, 0xbe, 0xb2, 0x45, 0x12, 0x96,
+	0x97, 0xd6, 0x2c, 0xff, 0x36, 0x94, 0xba, 0xc4, 0x72, 0xfd, 0x13, 0x62, 0xb1, 0x7c, 0xef, 0x12,
+	0xfb, 0xec, 0xdc, 0x0f, 0xdd, 0x7b, 0xce, 0x20, 0xe5, 0xab, 0x2c, 0x54, 0x79, 0xfe, 0xc6, 0x9e,
+	0x65, 0x44, 0xcd, 0x75, 0x1d, 0xf7, 0x86, 0x57, 0x19, 0x1a, 0xfe, 0x84, 0xd2, 0xd1, 0x74, 0x61,
+	0x76, 0xa9, 0x65, 0x37, 0xa4, 0x4b, 0x10, 0x68, 0x19, 0x2c, 0xb2, 0xa7, 0x1a, 0xf4, 0x61, 0x4c,
+	0xb2, 0x5a, 0x6e, 0x43, 0xac, 0x47, 0x14, 0xdd, 0x0c, 0x2e, 0x9d, 0x47, 0x8a, 0x34, 0x6e, 0xf5,
+	0xe0, 0x12, 0xa5, 0x66, 0xac, 0x7d, 0xe5, 0x13, 0xed, 0x2b, 0xf9, 0x24, 0x24, 0x6e, 0x7c, 0x12,
+	0x92, 0x56, 0x4f, 0x42, 0x51, 0x9a, 0xff, 0x45, 0x08, 0x99, 0x5e, 0x73, 0x7b, 0xda, 0x14, 0x23,
+	0x08, 0xf2, 0xb1, 0xf8, 0xc8, 0x9f, 0xd3, 0xd8, 0x48, 0x56, 0xd9, 0xfc, 0x75, 0xed, 0x5d, 0x7c,
+	0x9d, 0xf6, 0x4e, 0xcb, 0xfa, 0xa1, 0xb5, 0xf4, 0x08, 0xa6, 0x77, 0x62, 0xcf, 0x5f, 0xd3, 0x5e,
+	0x58, 0xd7, 0x5e, 0xa9, 0xc2, 0x16, 0x26, 0xde, 0x72, 0x16, 0x6e, 0x50, 0x3e, 0x81, 0x2d, 0x75,
+	0x32, 0xb3, 0xe7, 0xaf, 0xff, 0x76, 0xb7, 0x03, 0x12, 0x13, 0x21, 0x78, 0x6d, 0x29, 0x62, 0x69,
+	0xc1, 0x20, 0xe5, 0xe7, 0xb0, 0x73, 0x64, 0x4d, 0x6d, 0x5a, 0x77, 0x83, 0xfb, 0xda, 0xeb, 0x1f,
+	0x11, 0xfa, 0x2c, 0xbb, 0xf2, 0xd9, 0xc3, 0x3f, 0x09, 0x21, 0x17, 0x7a, 0x75, 0x33, 0x86, 0xad,
+	0x96, 0x66, 0x18, 0xac, 0xcb, 0x96, 0x9b, 0x6a, 0x7b, 0x84, 0xb5, 0x8f, 0x87, 0xb4, 0x49, 0xfe,
+	0x3e, 0x87, 0xb6, 0xa1, 0xd4, 0xd1, 0x71, 0xb3, 0xd7, 0x6e, 0x6b, 0x03, 0xf9, 0x73, 0x06, 0x0f,
+	0x74, 0x73, 0xd4, 0xa1, 0xbd, 0x56, 0xfe, 0x22, 0x87, 0xde, 0x80, 0x2a, 0xa7, 0x1e, 0xd1, 0x3e,
+	0xae, 0x0f, 0x4d, 0xf9, 0x0f, 0x39, 0xb4, 0x03, 0x77, 0x0e, 0xd5, 0xe3, 0xbe, 0xae, 0xb6, 0x47,
+	0xa6, 0xae, 0x8f, 0xfa, 0x2a, 0x7e, 0xa2, 0xc9, 0x7f, 0x64, 0x78, 0x0a, 0x1f, 0xa8, 0x83, 0xe3,
+	0xf0, 0x10, 0x43, 0xfe, 0x32, 0x87, 0x6a, 0xf0, 0x7f, 0x86, 0x86, 0x8f, 0x7a, 0x2d, 0x6d, 0x34,
+	0x1c, 0xa8, 0x47, 0x6a, 0xaf, 0xaf, 0x36, 0xfb, 0x9a, 0xfc, 0xaf, 0xdc, 0xc3, 0x21, 0x14, 0xf8,
+	0xc3, 0x29, 0xda, 0x06, 0x18, 0xe8, 0xa3, 0x8e, 0xa6, 0x9a, 0x43, 0xac, 0xc9, 0x19, 0x74, 0x07,
+	0xb6, 0x5a, 0x5d, 0xb5, 0x37, 0x18, 0x61, 0x7d, 0x68, 0xf6, 0x06, 0x4f, 0x64, 0x81, 0x4e, 0x09,
+	0x6d, 0xad, 0xdf, 0x3b, 0xd2, 0xf0, 0x48, 0x6d, 0x7d, 0x64, 0xc8, 0x59, 0x74, 0x17, 0xee, 0x74,
+	0x7a, 0x7d, 0x53, 0xc3, 0x5a, 0x7b, 0xc4, 0x97, 0x8e, 0xe5, 0xdc, 0xc3, 0x23, 0x40, 0x89, 0xa2,
+	0xc9, 0x9e, 0x47, 0xe9, 0xdd, 0xf6, 0x10, 0xeb, 0x7a, 0x47, 0xce, 0xd0, 0xc3, 0x8c, 0xde, 0x93,
+	0x01, 0x3b, 0xcb, 0x90, 0x05, 0xb4, 0x03, 0xa8, 0xaf, 0x1a, 0xe6, 0xa8, 0xa5, 0x0f, 0x3a, 0xbd,
+	0x27, 0x43, 0xac, 0x9a, 0x3d, 0x7d, 0x90, 0x9a, 0x5f, 0xf6, 0xff, 0x9d, 0x85, 0xaa, 0xca, 0xfc,
+	0x11, 0xd5, 0x27, 0xf4, 0x09, 0x94, 0x56, 0xc0, 0xcd, 0x85, 0x6c, 0xf7, 0x16, 0x4f, 0x7f, 0x4a,
+	0xa6, 0x2e, 0xbc, 0x27, 0xa0, 0x4f, 0xa1, 0x6a, 0x2c, 0x4f, 0x66, 0xb6, 0xff, 0xbf, 0xe7, 0x8f,
+	0x7e, 0x91, 0x7a, 0xfe, 0xfc, 0xc6, 0xe6, 0x7d, 0x8c, 0x60, 0xf7, 0x9d, 0x1b, 0x08, 0xd6, 0xa4,
+	0xff, 0x18, 0x0a, 0xbc, 0xc8, 0xa2, 0x74, 0x83, 0x4e, 0x8c, 0x4f, 0xbb, 0x7b, 0x9b, 0xd6, 0x93,
+	0x2c, 0xf7, 0x7f, 0x95, 0x05, 0x91, 0x65, 0x24, 0xea, 0x82, 0xc8, 0x12, 0x0b, 0xa5, 0x87, 0x9c,
+	0x78, 0xce, 0xef, 0xa6, 0x4f, 0x4e, 0x64, 0xb4, 0x92, 0x41, 0x4f, 0x41, 0x0a, 0xb2, 0xfe, 0x0a,
+	0x29, 0x13, 0xe5, 0xe0, 0x16, 0xbc, 0xc6, 0xb0, 0x9d, 0x4c, 0x6b, 0x74, 0xff, 0xa6, 0xb7, 0xb5,
+	0xe0, 0x82, 0x7a, 0x85, 0x6d, 0xaf, 0xae, 0x0f, 0x4a, 0xe6, 0x44, 0x62, 0xff, 0x56, 0x7c, 0xef,
+	0x3f, 0x03, 0x00, 0xa1, 0xd0, 0x05, 0x90, 0xb9, 0x18, 0x00, 0x00,
 }
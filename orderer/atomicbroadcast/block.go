@@ -17,15 +17,165 @@ limitations under the License.
 package atomicbroadcast
 
 import (
+	"encoding/binary"
+	"time"
+
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/util"
 )
 
+// NewBlock returns a block of the given messages following the block whose header hashes to previousHash, with its data hash populated
+// If the marshaled form of each message is supplied, the data hash is computed from it rather than marshaling the messages again
+func NewBlock(number uint64, previousHash []byte, messages []*BroadcastMessage, encoded [][]byte) *Block {
+	data := &BlockData{Messages: messages}
+	var dataHash []byte
+	if encoded != nil {
+		dataHash = ComputeDataHashEncoded(encoded)
+	} else {
+		dataHash = data.Hash()
+	}
+
+	return &Block{
+		Header: &BlockHeader{
+			Number:       number,
+			PreviousHash: previousHash,
+			DataHash:     dataHash,
+		},
+		Data:     data,
+		Metadata: &BlockMetadata{Metadata: make([][]byte, len(BlockMetadataIndex_name))},
+	}
+}
+
+// Hash returns the hash of the block's header, which commits to the messages through the data hash
 func (b *Block) Hash() []byte {
-	data, err := proto.Marshal(b) // XXX this is wrong, protobuf is not the right mechanism to serialize for a hash
+	return b.Header.Hash()
+}
+
+// Proof returns the consenter's proof from the block's metadata, or nil if there is none
+func (b *Block) Proof() []byte {
+	return b.GetMetadata().entry(BlockMetadataIndex_PROOF)
+}
+
+// SetProof records the consenter's proof in the block's metadata, which does not change the block's hash
+func (b *Block) SetProof(proof []byte) {
+	b.setMetadata(BlockMetadataIndex_PROOF, proof)
+}
+
+// Signature returns the orderer's signature from the block's metadata, or nil if the block is unsigned
+func (b *Block) Signature() (*BlockSignature, error) {
+	data := b.GetMetadata().entry(BlockMetadataIndex_SIGNATURES)
+	if data == nil {
+		return nil, nil
+	}
+
+	signature := &BlockSignature{}
+	if err := proto.Unmarshal(data, signature); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// SetSignature records the orderer's signature in the block's metadata, which does not change the block's hash
+func (b *Block) SetSignature(signature *BlockSignature) {
+	data, err := proto.Marshal(signature)
+	if err != nil {
+		panic("This should never fail and is generally irrecoverable")
+	}
+	b.setMetadata(BlockMetadataIndex_SIGNATURES, data)
+}
+
+// LastConfiguration returns the number of the most recent configuration block recorded in the block's metadata, or false if none is recorded
+func (b *Block) LastConfiguration() (uint64, bool) {
+	data := b.GetMetadata().entry(BlockMetadataIndex_LAST_CONFIGURATION)
+	if len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+// SetLastConfiguration records the number of the most recent configuration block in the block's metadata
+func (b *Block) SetLastConfiguration(number uint64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, number)
+	b.setMetadata(BlockMetadataIndex_LAST_CONFIGURATION, data)
+}
+
+// Timestamp returns the time at which the block was committed, or false if the block does not record one
+func (b *Block) Timestamp() (time.Time, bool) {
+	data := b.GetMetadata().entry(BlockMetadataIndex_TIMESTAMP)
+	if len(data) != 8 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data))), true
+}
+
+// SetTimestamp records the time at which the block was committed in the block's metadata
+func (b *Block) SetTimestamp(timestamp time.Time) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(timestamp.UnixNano()))
+	b.setMetadata(BlockMetadataIndex_TIMESTAMP, data)
+}
+
+func (b *Block) setMetadata(index BlockMetadataIndex, value []byte) {
+	if b.Metadata == nil {
+		b.Metadata = &BlockMetadata{}
+	}
+	for len(b.Metadata.Metadata) <= int(index) {
+		b.Metadata.Metadata = append(b.Metadata.Metadata, nil)
+	}
+	b.Metadata.Metadata[index] = value
+}
+
+func (m *BlockMetadata) entry(index BlockMetadataIndex) []byte {
+	if m == nil || len(m.Metadata) <= int(index) {
+		return nil
+	}
+	return m.Metadata[index]
+}
+
+// Hash returns the hash of the marshaled header, the bytes hashed are pinned by a golden test as changing them changes every block hash
+func (h *BlockHeader) Hash() []byte {
+	data, err := proto.Marshal(h)
 	if err != nil {
 		panic("This should never fail and is generally irrecoverable")
 	}
 
 	return util.ComputeCryptoHash(data)
 }
+
+// Hash returns the hash which a block's header carries as its data hash
+func (d *BlockData) Hash() []byte {
+	data, err := proto.Marshal(d)
+	if err != nil {
+		panic("This should never fail and is generally irrecoverable")
+	}
+
+	return util.ComputeCryptoHash(data)
+}
+
+// messagesTag is the key of each element of the Messages field of a marshaled BlockData, field 1 with the length-delimited wire type
+const messagesTag = 1<<3 | 2
+
+// ComputeDataHashEncoded returns the same hash as BlockData.Hash, given the marshaled form of each message
+// The bytes which BlockData.Hash would marshal are assembled directly from those of the messages
+func ComputeDataHashEncoded(encoded [][]byte) []byte {
+	size := 0
+	for _, msg := range encoded {
+		size += 1 + proto.SizeVarint(uint64(len(msg))) + len(msg)
+	}
+
+	data := make([]byte, 0, size)
+	for _, msg := range encoded {
+		data = append(data, messagesTag)
+		for x := uint64(len(msg)); ; x >>= 7 {
+			if x < 0x80 {
+				data = append(data, byte(x))
+				break
+			}
+			data = append(data, byte(x&0x7f|0x80))
+		}
+		data = append(data, msg...)
+	}
+
+	return util.ComputeCryptoHash(data)
+}
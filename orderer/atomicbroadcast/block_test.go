@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atomicbroadcast
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestDataHashEncoded(t *testing.T) {
+	// The last message is long enough that its length takes more than one byte to encode
+	messages := []*BroadcastMessage{
+		&BroadcastMessage{},
+		&BroadcastMessage{Data: []byte("Some bytes"), ChainID: []byte("chain")},
+		&BroadcastMessage{Data: make([]byte, 300)},
+	}
+	encoded := make([][]byte, len(messages))
+	for i, msg := range messages {
+		var err error
+		if encoded[i], err = proto.Marshal(msg); err != nil {
+			t.Fatalf("Error marshaling message: %s", err)
+		}
+	}
+
+	if !bytes.Equal(ComputeDataHashEncoded(encoded), (&BlockData{Messages: messages}).Hash()) {
+		t.Fatalf("Expected the data hash computed from the marshaled messages to match")
+	}
+	if !bytes.Equal(NewBlock(1, []byte("prev"), messages, encoded).Hash(), NewBlock(1, []byte("prev"), messages, nil).Hash()) {
+		t.Fatalf("Expected the block hash computed from the marshaled messages to match")
+	}
+}
+
+// The golden values below pin the bytes which are hashed, any change to them changes the hash of every block ever written
+func TestHeaderHashGolden(t *testing.T) {
+	header := &BlockHeader{Number: 7, PreviousHash: []byte("previous"), DataHash: []byte("data")}
+
+	encoded, err := proto.Marshal(header)
+	if err != nil {
+		t.Fatalf("Error marshaling header: %s", err)
+	}
+	if hex.EncodeToString(encoded) != "0807120870726576696f75731a0464617461" {
+		t.Fatalf("Header encoding has changed: %x", encoded)
+	}
+	if hex.EncodeToString(header.Hash()) != "b6d319e1b8b01b5d13f1e7a39677ee822d6ed627cb60335f2ed7e00e1c0388c69ca8aa41f7479bf009fdcb84fff92ad96f5da974c341911373fb45ea3b02a0b2" {
+		t.Fatalf("Header hash has changed: %x", header.Hash())
+	}
+}
+
+func TestDataHashGolden(t *testing.T) {
+	data := &BlockData{Messages: []*BroadcastMessage{
+		&BroadcastMessage{Data: []byte("first")},
+		&BroadcastMessage{Data: []byte("second"), ChainID: []byte("chain")},
+	}}
+
+	encoded, err := proto.Marshal(data)
+	if err != nil {
+		t.Fatalf("Error marshaling data: %s", err)
+	}
+	if hex.EncodeToString(encoded) != "0a070a0566697273740a0f0a067365636f6e641205636861696e" {
+		t.Fatalf("Data encoding has changed: %x", encoded)
+	}
+	if hex.EncodeToString(data.Hash()) != "5c78bf976c6e8c5381ba6ccbb8a275595206d7ee4ac4668bd691dbc679fbf8f8b07e97ac3047eff137c98e0109cd1c29f6a7b5fea20e7b61848ddd59a65eced1" {
+		t.Fatalf("Data hash has changed: %x", data.Hash())
+	}
+}
+
+func TestBlockHashIsHeaderHash(t *testing.T) {
+	block := NewBlock(3, []byte("previous"), []*BroadcastMessage{&BroadcastMessage{Data: []byte("message")}}, nil)
+	hash := block.Hash()
+
+	if !bytes.Equal(hash, block.Header.Hash()) {
+		t.Fatalf("Expected the block hash to be the hash of its header")
+	}
+	if !bytes.Equal(block.Header.DataHash, block.Data.Hash()) {
+		t.Fatalf("Expected the header to carry the hash of the block data")
+	}
+
+	block.SetProof([]byte("proof"))
+	if !bytes.Equal(block.Hash(), hash) || !bytes.Equal(block.Proof(), []byte("proof")) {
+		t.Fatalf("Expected the proof to be kept in the metadata without changing the hash")
+	}
+
+	block.Data.Messages[0].Data = []byte("tampered")
+	if bytes.Equal((&BlockData{Messages: block.Data.Messages}).Hash(), block.Header.DataHash) {
+		t.Fatalf("Expected tampered data to no longer match the data hash")
+	}
+}
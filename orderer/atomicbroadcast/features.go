@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atomicbroadcast
+
+import "fmt"
+
+// Features is a set of protocol features, the zero value is the legacy protocol with every feature disabled
+type Features uint32
+
+// AllFeatures holds every feature known to this version of the protocol
+var AllFeatures = NewFeatures(Feature_CHAIN_ROUTING, Feature_DELIVER_ACKS, Feature_FILTERED_DELIVERY)
+
+// NewFeatures returns the set of the given features
+func NewFeatures(features ...Feature) Features {
+	var fs Features
+	for _, f := range features {
+		if f > Feature_NO_FEATURE && f < 32 {
+			fs |= 1 << uint(f)
+		}
+	}
+	return fs
+}
+
+// ParseFeatures returns the set of the named features, or an error naming the first which is not known
+func ParseFeatures(names []string) (Features, error) {
+	var features []Feature
+	for _, name := range names {
+		value, ok := Feature_value[name]
+		if !ok || Feature(value) == Feature_NO_FEATURE {
+			return 0, fmt.Errorf("unknown protocol feature '%s'", name)
+		}
+		features = append(features, Feature(value))
+	}
+	return NewFeatures(features...), nil
+}
+
+// Has returns whether f is in the set
+func (fs Features) Has(f Feature) bool {
+	return NewFeatures(f)&fs != 0
+}
+
+// Negotiate returns the features of the set which hello also announces, those unknown to this version are ignored
+func (fs Features) Negotiate(hello *Hello) Features {
+	return fs & NewFeatures(hello.Features...)
+}
+
+// Hello returns a Hello announcing the features of the set, in ascending order
+func (fs Features) Hello() *Hello {
+	hello := &Hello{}
+	for f := Feature_NO_FEATURE + 1; f < 32; f++ {
+		if fs.Has(f) {
+			hello.Features = append(hello.Features, f)
+		}
+	}
+	return hello
+}
@@ -0,0 +1,50 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atomicbroadcast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	server := NewFeatures(Feature_CHAIN_ROUTING, Feature_DELIVER_ACKS)
+	// A client may announce features the server does not enable, or which are unknown to it
+	selected := server.Negotiate(&Hello{Features: []Feature{Feature_DELIVER_ACKS, Feature_FILTERED_DELIVERY, Feature(17)}})
+	if !reflect.DeepEqual(selected.Hello().Features, []Feature{Feature_DELIVER_ACKS}) {
+		t.Fatalf("Expected only DELIVER_ACKS to be selected, got %v", selected.Hello().Features)
+	}
+	if selected.Has(Feature_CHAIN_ROUTING) {
+		t.Fatalf("A feature the client did not announce should not be selected")
+	}
+	if Features(0).Has(Feature_CHAIN_ROUTING) || len(Features(0).Hello().Features) != 0 {
+		t.Fatalf("The legacy protocol should have no features")
+	}
+}
+
+func TestParseFeatures(t *testing.T) {
+	fs, err := ParseFeatures([]string{"CHAIN_ROUTING", "DELIVER_ACKS", "FILTERED_DELIVERY"})
+	if err != nil || fs != AllFeatures {
+		t.Fatalf("Expected every feature to be parsed, got %v (%v)", fs.Hello().Features, err)
+	}
+	if _, err := ParseFeatures([]string{"NO_FEATURE"}); err == nil {
+		t.Fatalf("NO_FEATURE should not be accepted")
+	}
+	if _, err := ParseFeatures([]string{"HEADER_SPLIT"}); err == nil {
+		t.Fatalf("An unknown feature should not be accepted")
+	}
+}
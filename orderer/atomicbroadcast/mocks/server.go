@@ -0,0 +1,207 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mocks
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// Server is a scripted AtomicBroadcast service which records the messages and updates it receives
+// By default every message is acknowledged with SUCCESS and no update is answered, hellos are negotiated against every feature
+// It may be called directly with the fake streams of this package, or served over a loopback listener by StartServer
+type Server struct {
+	lock           sync.Mutex
+	broadcastReply func(*ab.BroadcastMessage) *ab.BroadcastResponse
+	deliverReply   func(*ab.DeliverUpdate) []*ab.DeliverResponse
+	broadcasts     []*ab.BroadcastMessage
+	updates        []*ab.DeliverUpdate
+
+	grpcServer *grpc.Server
+	listener   net.Listener
+	conn       *grpc.ClientConn
+}
+
+// NewServer returns a Server which is not listening
+func NewServer() *Server {
+	return &Server{
+		broadcastReply: func(*ab.BroadcastMessage) *ab.BroadcastResponse {
+			return &ab.BroadcastResponse{Status: ab.Status_SUCCESS}
+		},
+		deliverReply: func(*ab.DeliverUpdate) []*ab.DeliverResponse { return nil },
+	}
+}
+
+// StartServer returns a Server serving on a loopback port, which must be stopped by Stop
+func StartServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("Could not listen for the mock server: %s", err))
+	}
+
+	s := NewServer()
+	s.listener = listener
+	s.grpcServer = grpc.NewServer()
+	ab.RegisterAtomicBroadcastServer(s.grpcServer, s)
+	go s.grpcServer.Serve(listener)
+	return s
+}
+
+// Address returns the address the server is listening on
+func (s *Server) Address() string {
+	return s.listener.Addr().String()
+}
+
+// Client returns a client connected to the server, which is closed by Stop
+func (s *Server) Client() ab.AtomicBroadcastClient {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		conn, err := grpc.Dial(s.Address(), grpc.WithInsecure())
+		if err != nil {
+			panic(fmt.Sprintf("Could not dial the mock server: %s", err))
+		}
+		s.conn = conn
+	}
+	return ab.NewAtomicBroadcastClient(s.conn)
+}
+
+// Stop closes the client connection and stops serving
+func (s *Server) Stop() {
+	s.lock.Lock()
+	conn := s.conn
+	s.conn = nil
+	s.lock.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+}
+
+// OnBroadcast sets the function which determines the reply to each message broadcast after it returns
+func (s *Server) OnBroadcast(reply func(*ab.BroadcastMessage) *ab.BroadcastResponse) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.broadcastReply = reply
+}
+
+// OnDeliver sets the function which determines the replies to each update received after it returns, other than a hello
+func (s *Server) OnDeliver(reply func(*ab.DeliverUpdate) []*ab.DeliverResponse) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.deliverReply = reply
+}
+
+// Broadcasts returns the messages received so far by Broadcast, SubmitBroadcast, and BroadcastBatch, in the order they were replied to
+func (s *Server) Broadcasts() []*ab.BroadcastMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*ab.BroadcastMessage(nil), s.broadcasts...)
+}
+
+// DeliverUpdates returns the updates received so far by Deliver, other than hellos
+func (s *Server) DeliverUpdates() []*ab.DeliverUpdate {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*ab.DeliverUpdate(nil), s.updates...)
+}
+
+// broadcast records a message and returns the scripted reply to it, or the negotiated hello if it is one
+func (s *Server) broadcast(msg *ab.BroadcastMessage) *ab.BroadcastResponse {
+	if msg.Hello != nil {
+		return &ab.BroadcastResponse{Status: ab.Status_SUCCESS, Hello: ab.AllFeatures.Negotiate(msg.Hello).Hello()}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.broadcasts = append(s.broadcasts, msg)
+	// The reply is copied, as a script may return the same one for every message
+	reply := *s.broadcastReply(msg)
+	reply.CorrelationID = msg.CorrelationID
+	return &reply
+}
+
+// Broadcast replies to each message received on srv in turn
+func (s *Server) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
+	for {
+		msg, err := srv.Recv()
+		if err != nil {
+			return err
+		}
+		if err = srv.Send(s.broadcast(msg)); err != nil {
+			return err
+		}
+	}
+}
+
+// SubmitBroadcast replies to the message as Broadcast would
+func (s *Server) SubmitBroadcast(ctx context.Context, msg *ab.BroadcastMessage) (*ab.BroadcastResponse, error) {
+	return s.broadcast(msg), nil
+}
+
+// BroadcastBatch replies to the messages of each batch received on srv in one response
+func (s *Server) BroadcastBatch(srv ab.AtomicBroadcast_BroadcastBatchServer) error {
+	for {
+		batch, err := srv.Recv()
+		if err != nil {
+			return err
+		}
+		resp := &ab.BroadcastBatchResponse{}
+		for _, msg := range batch.Messages {
+			resp.Responses = append(resp.Responses, s.broadcast(msg))
+		}
+		if err = srv.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// Deliver records each update received on srv and sends the scripted replies to it, or the negotiated hello if it is one
+func (s *Server) Deliver(srv ab.AtomicBroadcast_DeliverServer) error {
+	for {
+		update, err := srv.Recv()
+		if err != nil {
+			return err
+		}
+
+		var replies []*ab.DeliverResponse
+		if hello := update.GetHello(); hello != nil {
+			replies = []*ab.DeliverResponse{&ab.DeliverResponse{Type: &ab.DeliverResponse_Hello{Hello: ab.AllFeatures.Negotiate(hello).Hello()}}}
+		} else {
+			s.lock.Lock()
+			s.updates = append(s.updates, update)
+			replies = s.deliverReply(update)
+			s.lock.Unlock()
+		}
+
+		for _, reply := range replies {
+			if err = srv.Send(reply); err != nil {
+				return err
+			}
+		}
+	}
+}
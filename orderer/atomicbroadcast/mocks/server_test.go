@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mocks
+
+import (
+	"bytes"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"golang.org/x/net/context"
+)
+
+func TestServerRecordsBroadcasts(t *testing.T) {
+	srv := StartServer()
+	defer srv.Stop()
+	stream, err := srv.Client().Broadcast(context.Background())
+	if err != nil {
+		t.Fatalf("Could not open a Broadcast stream: %s", err)
+	}
+
+	for _, data := range []string{"a", "b"} {
+		if err = stream.Send(&ab.BroadcastMessage{Data: []byte(data)}); err != nil {
+			t.Fatalf("Could not send: %s", err)
+		}
+		if reply, err := stream.Recv(); err != nil || reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected SUCCESS, got %v (%v)", reply, err)
+		}
+	}
+
+	broadcasts := srv.Broadcasts()
+	if len(broadcasts) != 2 || string(broadcasts[0].Data) != "a" || string(broadcasts[1].Data) != "b" {
+		t.Fatalf("Expected the two messages to be recorded in order, got %v", broadcasts)
+	}
+}
+
+func TestServerScriptedReplies(t *testing.T) {
+	srv := NewServer()
+	srv.OnBroadcast(func(msg *ab.BroadcastMessage) *ab.BroadcastResponse {
+		if len(msg.Data) == 0 {
+			return ab.ReasonMalformed.BroadcastResponse("empty message")
+		}
+		return &ab.BroadcastResponse{Status: ab.Status_SUCCESS}
+	})
+	srv.OnDeliver(func(update *ab.DeliverUpdate) []*ab.DeliverResponse {
+		return []*ab.DeliverResponse{&ab.DeliverResponse{Type: &ab.DeliverResponse_Error{Error: ab.Status_NOT_FOUND}}}
+	})
+
+	m := NewBroadcastStream()
+	go srv.Broadcast(m)
+	m.RecvChan <- &ab.BroadcastMessage{CorrelationID: []byte("id")}
+	if reply := <-m.SendChan; reply.Status != ab.Status_BAD_REQUEST || !bytes.Equal(reply.CorrelationID, []byte("id")) {
+		t.Fatalf("Expected the scripted rejection carrying the correlation ID, got %v", reply)
+	}
+	close(m.RecvChan)
+
+	d := NewLegacyDeliverStream()
+	go srv.Deliver(d)
+	d.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_OLDEST, WindowSize: 1}}}
+	if reply := <-d.SendChan; reply.GetError() != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected the scripted NOT_FOUND, got %v", reply)
+	}
+	close(d.RecvChan)
+
+	if updates := srv.DeliverUpdates(); len(updates) != 1 || updates[0].GetSeek() == nil {
+		t.Fatalf("Expected the seek to be recorded, got %v", updates)
+	}
+}
+
+func TestServerNegotiatesHello(t *testing.T) {
+	srv := NewServer()
+	d := NewLegacyDeliverStream()
+	go srv.Deliver(d)
+	defer close(d.RecvChan)
+
+	d.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.NewFeatures(ab.Feature_DELIVER_ACKS).Hello()}}
+	hello := (<-d.SendChan).GetHello()
+	if hello == nil || ab.AllFeatures.Negotiate(hello) != ab.NewFeatures(ab.Feature_DELIVER_ACKS) {
+		t.Fatalf("Expected the hello to be answered with the requested feature, got %v", hello)
+	}
+	if updates := srv.DeliverUpdates(); len(updates) != 0 {
+		t.Fatalf("A hello should not be recorded as an update, got %v", updates)
+	}
+}
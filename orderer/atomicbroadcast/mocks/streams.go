@@ -0,0 +1,158 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mocks provides fakes of the AtomicBroadcast service for testing code on either side of it
+package mocks
+
+import (
+	"io"
+	"net"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// BroadcastStream is the server side of a Broadcast stream, driven by a test acting as the client through its channels
+// The messages of RecvChan are preceded by Hello, and the reply to it is dropped, unless Hello is nil
+// Closing RecvChan ends the client's side of the stream, so that Recv returns CloseErr, or io.EOF if it is nil
+type BroadcastStream struct {
+	grpc.ServerStream
+	RecvChan chan *ab.BroadcastMessage
+	CloseErr error // Set before closing RecvChan to end the stream as a canceled or broken one would
+	SendChan chan *ab.BroadcastResponse
+	Hello    *ab.Hello
+	ctx      context.Context
+	greeted  bool
+}
+
+// NewBroadcastStream returns a BroadcastStream which announces every feature
+func NewBroadcastStream() *BroadcastStream {
+	return &BroadcastStream{
+		RecvChan: make(chan *ab.BroadcastMessage),
+		SendChan: make(chan *ab.BroadcastResponse),
+		Hello:    ab.AllFeatures.Hello(),
+		ctx:      context.Background(),
+	}
+}
+
+// NewLegacyBroadcastStream returns a BroadcastStream which sends no hello, as a legacy client would
+func NewLegacyBroadcastStream() *BroadcastStream {
+	m := NewBroadcastStream()
+	m.Hello = nil
+	return m
+}
+
+// Context returns a background context, as the client never cancels the stream
+func (m *BroadcastStream) Context() context.Context {
+	return m.ctx
+}
+
+// Send passes the reply to SendChan, blocking until the test receives it
+func (m *BroadcastStream) Send(br *ab.BroadcastResponse) error {
+	if m.Hello != nil && br.Hello != nil {
+		return nil
+	}
+	m.SendChan <- br
+	return nil
+}
+
+// Recv returns the hello, then the messages of RecvChan
+func (m *BroadcastStream) Recv() (*ab.BroadcastMessage, error) {
+	if hello := m.Hello; hello != nil && !m.greeted {
+		m.greeted = true
+		return &ab.BroadcastMessage{Hello: hello}, nil
+	}
+	msg, ok := <-m.RecvChan
+	if !ok {
+		return nil, closeErr(m.CloseErr)
+	}
+	return msg, nil
+}
+
+// DeliverStream is the server side of a Deliver stream, driven by a test acting as the client through its channels
+// The updates of RecvChan are preceded by Hello, and the reply to it is dropped, unless Hello is nil
+// Closing RecvChan ends the client's side of the stream, so that Recv returns CloseErr, or io.EOF if it is nil
+type DeliverStream struct {
+	grpc.ServerStream
+	RecvChan chan *ab.DeliverUpdate
+	CloseErr error // Set before closing RecvChan to end the stream as a canceled or broken one would
+	SendChan chan *ab.DeliverResponse
+	Hello    *ab.Hello
+	ctx      context.Context
+	greeted  bool
+}
+
+// NewDeliverStream returns a DeliverStream which announces every feature
+func NewDeliverStream() *DeliverStream {
+	return &DeliverStream{
+		RecvChan: make(chan *ab.DeliverUpdate),
+		SendChan: make(chan *ab.DeliverResponse),
+		Hello:    ab.AllFeatures.Hello(),
+		ctx:      context.Background(),
+	}
+}
+
+// NewLegacyDeliverStream returns a DeliverStream which sends no hello, as a legacy client would
+func NewLegacyDeliverStream() *DeliverStream {
+	m := NewDeliverStream()
+	m.Hello = nil
+	return m
+}
+
+// NewDeliverStreamFrom returns a DeliverStream whose context identifies the client by the given address
+func NewDeliverStreamFrom(address string) *DeliverStream {
+	m := NewDeliverStream()
+	addr, _ := net.ResolveTCPAddr("tcp", address)
+	m.ctx = peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	return m
+}
+
+// Context returns the context of the stream, which identifies the client if it was created by NewDeliverStreamFrom
+func (m *DeliverStream) Context() context.Context {
+	return m.ctx
+}
+
+// Send passes the reply to SendChan, blocking until the test receives it
+func (m *DeliverStream) Send(br *ab.DeliverResponse) error {
+	if m.Hello != nil && br.GetHello() != nil {
+		return nil
+	}
+	m.SendChan <- br
+	return nil
+}
+
+// Recv returns the hello, then the updates of RecvChan
+func (m *DeliverStream) Recv() (*ab.DeliverUpdate, error) {
+	if hello := m.Hello; hello != nil && !m.greeted {
+		m.greeted = true
+		return &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: hello}}, nil
+	}
+	update, ok := <-m.RecvChan
+	if !ok {
+		return nil, closeErr(m.CloseErr)
+	}
+	return update, nil
+}
+
+func closeErr(err error) error {
+	if err == nil {
+		return io.EOF
+	}
+	return err
+}
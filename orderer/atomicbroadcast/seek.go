@@ -0,0 +1,33 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atomicbroadcast
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// SignedBytes returns the bytes over which the Signatures of a seek are made, the seek marshaled without its Signatures
+func (si *SeekInfo) SignedBytes() []byte {
+	unsigned := *si
+	unsigned.Signatures = nil
+	data, err := proto.Marshal(&unsigned)
+	if err != nil {
+		// A SeekInfo holds no field which fails to marshal
+		panic(err)
+	}
+	return data
+}
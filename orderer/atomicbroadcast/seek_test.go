@@ -0,0 +1,44 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atomicbroadcast
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSeekSignedBytes(t *testing.T) {
+	seek := &SeekInfo{Start: SeekInfo_OLDEST, ChainID: []byte("chain"), Timestamp: 1}
+	unsigned := seek.SignedBytes()
+	seek.Signatures = []*SignedData{&SignedData{Signature: []byte("signature")}}
+	if !bytes.Equal(seek.SignedBytes(), unsigned) {
+		t.Fatalf("Expected the signed bytes not to cover the Signatures")
+	}
+	if len(seek.Signatures) != 1 {
+		t.Fatalf("Expected the Signatures of the seek to be left in place")
+	}
+
+	for _, changed := range []*SeekInfo{
+		&SeekInfo{Start: SeekInfo_NEWEST, ChainID: []byte("chain"), Timestamp: 1},
+		&SeekInfo{Start: SeekInfo_OLDEST, ChainID: []byte("other"), Timestamp: 1},
+		&SeekInfo{Start: SeekInfo_OLDEST, ChainID: []byte("chain"), Timestamp: 2},
+	} {
+		if bytes.Equal(changed.SignedBytes(), unsigned) {
+			t.Fatalf("Expected the signed bytes to cover every other field, but %v signs the same bytes", changed)
+		}
+	}
+}
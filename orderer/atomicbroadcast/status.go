@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atomicbroadcast
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Reason is why the orderer refused or ended a request, every failure replied to a client is classified by one, so
+// that the status clients see for a kind of failure is decided in one place
+type Reason int
+
+const (
+	ReasonMalformed     Reason = iota // The request or message was invalid
+	ReasonOversized                   // A message was larger than the orderer or its backing service accepts
+	ReasonForbidden                   // A message was well formed but not authorized
+	ReasonUnknownChain                // The chain named by the request does not exist
+	ReasonNotRetained                 // The requested block is beyond the chain, or no longer retained by it
+	ReasonIdle                        // A stream was closed after being idle for too long
+	ReasonBackpressure                // The orderer cannot take on more work from the client until it has caught up
+	ReasonUnavailable                 // The orderer is paused, shutting down, or has lost its backing service
+	ReasonBlockTooLarge               // A requested block is larger than the orderer may send
+)
+
+var reasonStatus = map[Reason]Status{
+	ReasonMalformed:     Status_BAD_REQUEST,
+	ReasonOversized:     Status_BAD_REQUEST,
+	ReasonForbidden:     Status_FORBIDDEN,
+	ReasonUnknownChain:  Status_NOT_FOUND,
+	ReasonNotRetained:   Status_NOT_FOUND,
+	ReasonIdle:          Status_REQUEST_TIMEOUT,
+	ReasonBackpressure:  Status_TOO_MANY_REQUESTS,
+	ReasonUnavailable:   Status_SERVICE_UNAVAILABLE,
+	ReasonBlockTooLarge: Status_PAYLOAD_TOO_LARGE,
+}
+
+// Status returns the status replied to clients for the reason, SERVICE_UNAVAILABLE for a reason which is not known
+func (r Reason) Status() Status {
+	if status, ok := reasonStatus[r]; ok {
+		return status
+	}
+	return Status_SERVICE_UNAVAILABLE
+}
+
+// Retryable returns whether a client may succeed by sending the same request again later
+func (s Status) Retryable() bool {
+	return s == Status_TOO_MANY_REQUESTS || s == Status_SERVICE_UNAVAILABLE || s == Status_REQUEST_TIMEOUT
+}
+
+// BroadcastResponse returns the reply to a message which failed for the reason, with a detail formatted from format and args
+func (r Reason) BroadcastResponse(format string, args ...interface{}) *BroadcastResponse {
+	return &BroadcastResponse{Status: r.Status(), Info: Detail(format, args...)}
+}
+
+// DeliverResponse returns the reply which ends a Deliver stream failed for the reason, with a detail formatted from format and args
+func (r Reason) DeliverResponse(format string, args ...interface{}) *DeliverResponse {
+	return &DeliverResponse{Type: &DeliverResponse_Error{Error: r.Status()}, Info: Detail(format, args...)}
+}
+
+// LedgerReason returns the reason to reply with when reading from a ledger fails with status
+func LedgerReason(status Status) Reason {
+	if status == Status_NOT_FOUND {
+		return ReasonNotRetained
+	}
+	return ReasonUnavailable
+}
+
+// maxDetail is the length beyond which a detail is truncated, it is a hint to the client rather than a log
+const maxDetail = 256
+
+var (
+	// Network addresses, which may be internal to the deployment, such as those of the brokers in a Kafka error
+	addressPattern = regexp.MustCompile(`\[[0-9A-Fa-f:.]+\](:\d+)?|\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b|\b[A-Za-z][\w-]*(\.[\w-]+)*:\d{2,5}\b`)
+	// Absolute file system paths, such as those of the ledger or the pending log
+	pathPattern = regexp.MustCompile(`(^|[\s'"(=])(/[\w.-]+)+/?`)
+)
+
+// Detail formats a detail for a client, replacing network addresses and file system paths, which are of no use to a
+// client and may reveal the deployment, and truncating it to a bounded length
+func Detail(format string, args ...interface{}) string {
+	detail := fmt.Sprintf(format, args...)
+	detail = addressPattern.ReplaceAllString(detail, "<address>")
+	detail = pathPattern.ReplaceAllString(detail, "$1<path>")
+	if len(detail) > maxDetail {
+		detail = detail[:maxDetail-3] + "..."
+	}
+	return detail
+}
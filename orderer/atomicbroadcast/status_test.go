@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atomicbroadcast
+
+import "testing"
+
+func TestReasonStatus(t *testing.T) {
+	for reason, status := range map[Reason]Status{
+		ReasonMalformed:     Status_BAD_REQUEST,
+		ReasonOversized:     Status_BAD_REQUEST,
+		ReasonForbidden:     Status_FORBIDDEN,
+		ReasonUnknownChain:  Status_NOT_FOUND,
+		ReasonNotRetained:   Status_NOT_FOUND,
+		ReasonIdle:          Status_REQUEST_TIMEOUT,
+		ReasonBackpressure:  Status_TOO_MANY_REQUESTS,
+		ReasonUnavailable:   Status_SERVICE_UNAVAILABLE,
+		ReasonBlockTooLarge: Status_PAYLOAD_TOO_LARGE,
+		Reason(-1):          Status_SERVICE_UNAVAILABLE,
+	} {
+		if reason.Status() != status {
+			t.Fatalf("Expected reason %d to be replied as %v, got %v", reason, status, reason.Status())
+		}
+	}
+}
+
+func TestStatusRetryable(t *testing.T) {
+	for status, retryable := range map[Status]bool{
+		Status_BAD_REQUEST:         false,
+		Status_FORBIDDEN:           false,
+		Status_NOT_FOUND:           false,
+		Status_REQUEST_TIMEOUT:     true,
+		Status_TOO_MANY_REQUESTS:   true,
+		Status_SERVICE_UNAVAILABLE: true,
+	} {
+		if status.Retryable() != retryable {
+			t.Fatalf("Expected %v to be retryable=%v", status, retryable)
+		}
+	}
+}
+
+func TestReasonResponses(t *testing.T) {
+	broadcast := ReasonOversized.BroadcastResponse("message %d bytes exceeds limit %d", 5242880, 1048576)
+	if broadcast.Status != Status_BAD_REQUEST || broadcast.Info != "message 5242880 bytes exceeds limit 1048576" {
+		t.Fatalf("Unexpected broadcast reply %v", broadcast)
+	}
+	deliver := ReasonNotRetained.DeliverResponse("seek target %d exceeds height %d", 1042, 977)
+	if deliver.GetError() != Status_NOT_FOUND || deliver.Info != "seek target 1042 exceeds height 977" {
+		t.Fatalf("Unexpected deliver reply %v", deliver)
+	}
+}
+
+func TestDetailSanitized(t *testing.T) {
+	for detail, expected := range map[string]string{
+		"posting to partition 0 failed: dial tcp 10.0.3.7:9092: connection refused": "posting to partition 0 failed: dial tcp <address>: connection refused",
+		"broker kafka0.internal:9092 is unreachable":                                "broker <address> is unreachable",
+		"broker [fe80::1]:9092 is unreachable":                                      "broker <address> is unreachable",
+		"reading /var/hyperledger/ledger/chain_0a/block_12 failed":                  "reading <path> failed",
+		"open '/tmp/pending.log': no space left":                                    "open '<path>': no space left",
+		"seek target 1042 exceeds height 977":                                       "seek target 1042 exceeds height 977",
+		"window size 0 is outside of 1 to 10":                                       "window size 0 is outside of 1 to 10",
+	} {
+		if sanitized := Detail("%s", detail); sanitized != expected {
+			t.Fatalf("Expected %q to be sanitized as %q, got %q", detail, expected, sanitized)
+		}
+	}
+
+	long := Detail("%0300d", 0)
+	if len(long) != maxDetail || long[maxDetail-3:] != "..." {
+		t.Fatalf("Expected a detail of %d bytes to be truncated to %d, got %d", 300, maxDetail, len(long))
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client provides clients of the AtomicBroadcast service which reconnect and retry on transient failures
+package client
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/tlsutil"
+
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+)
+
+var logger = logging.MustGetLogger("orderer/client")
+
+// requestedFeatures are announced in the hello opening each stream
+var requestedFeatures = ab.NewFeatures(ab.Feature_CHAIN_ROUTING)
+
+var (
+	errClosed         = errors.New("the client is closed")
+	errNoChainRouting = errors.New("the orderer does not support chain routing, the message names a chain")
+)
+
+// BroadcastClient orders messages on a Broadcast stream, one at a time, reopening the stream and resending a message
+// when the stream fails or the orderer replies with a status which is retryable
+type BroadcastClient struct {
+	conf   Config
+	conn   *grpc.ClientConn
+	prefix []byte // Random, so that the correlation IDs assigned by different clients do not collide
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+
+	lock     sync.Mutex // Serializes Send, and guards the fields below
+	stream   ab.AtomicBroadcast_BroadcastClient
+	cancel   context.CancelFunc // Ends stream
+	features ab.Features        // Negotiated by the hello which opened stream
+	nextID   uint64
+}
+
+// NewBroadcastClient returns a client of the orderer at conf.Address, the connection is made by the first Send
+func NewBroadcastClient(conf Config) (*BroadcastClient, error) {
+	conf = conf.withDefaults()
+
+	tlsConfig, err := newTLSConfig(conf.TLS)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dial(conf.Address, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, 8)
+	if _, err = rand.Read(prefix); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Failed to generate the correlation ID prefix: %s", err)
+	}
+
+	return &BroadcastClient{conf: conf, conn: conn, prefix: prefix, closeChan: make(chan struct{})}, nil
+}
+
+// Send orders msg, returning the reply of the orderer once it accepts or refuses the message for a reason which is not retryable
+// A message without a CorrelationID is assigned one unique to the client, which the reply carries; retries resend the
+// same message, so an orderer suppressing duplicates replies to a retry with the block the message was first ordered in
+// If the orderer replies once the message is committed, the reply holds the number of the block which contains it
+// The error is set only if ctx ends, the client is closed, or the message cannot be sent however often it is retried
+func (c *BroadcastClient) Send(ctx context.Context, msg *ab.BroadcastMessage) (*ab.BroadcastResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(msg.CorrelationID) == 0 {
+		assigned := *msg
+		assigned.CorrelationID = c.newCorrelationID()
+		msg = &assigned
+	}
+
+	backoff := c.conf.Backoff
+	for attempt := 1; ; attempt++ {
+		delay := backoff
+		reply, err := c.attempt(ctx, msg)
+		switch {
+		case err == nil && !reply.Status.Retryable():
+			return reply, nil
+		case err == nil:
+			logger.Debugf("Attempt %d to broadcast was replied %s (%s), retrying", attempt, reply.Status, reply.Info)
+			if reply.RetryAfter > 0 {
+				delay = time.Duration(reply.RetryAfter) * time.Millisecond
+			}
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case err == errClosed || permanent(err):
+			return nil, err
+		default:
+			logger.Warningf("Attempt %d to broadcast to %s failed, reopening the stream: %s", attempt, c.conf.Address, err)
+		}
+
+		select {
+		case <-time.After(tlsutil.Jitter(delay)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.closeChan:
+			return nil, errClosed
+		}
+		if backoff *= 2; backoff > c.conf.MaxBackoff {
+			backoff = c.conf.MaxBackoff
+		}
+	}
+}
+
+// Close ends the stream and the connection, a Send in progress returns without waiting for its reply
+func (c *BroadcastClient) Close() error {
+	c.closeOnce.Do(func() { close(c.closeChan) })
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.reset()
+	return c.conn.Close()
+}
+
+// newCorrelationID returns the random prefix of the client followed by the big endian count of the IDs assigned before
+func (c *BroadcastClient) newCorrelationID() []byte {
+	id := make([]byte, len(c.prefix)+8)
+	copy(id, c.prefix)
+	binary.BigEndian.PutUint64(id[len(c.prefix):], c.nextID)
+	c.nextID++
+	return id
+}
+
+// attempt sends msg on the stream, opening one if there is none, and returns the reply to it
+// The stream is ended after a failure or a retryable reply, as the orderer may be closing it, so the next attempt opens another
+func (c *BroadcastClient) attempt(ctx context.Context, msg *ab.BroadcastMessage) (*ab.BroadcastResponse, error) {
+	if c.stream == nil {
+		if err := c.open(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(msg.ChainID) > 0 && !c.features.Has(ab.Feature_CHAIN_ROUTING) {
+		return nil, errNoChainRouting
+	}
+
+	reply, err := c.exchange(ctx, msg)
+	if err != nil || reply.Status.Retryable() {
+		c.reset()
+	}
+	return reply, err
+}
+
+// open starts a stream and negotiates its features, a legacy orderer refuses the hello as it would an empty message
+func (c *BroadcastClient) open(ctx context.Context) error {
+	type opened struct {
+		stream ab.AtomicBroadcast_BroadcastClient
+		err    error
+	}
+
+	// The stream outlives ctx, which bounds only the attempt
+	streamCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan opened, 1)
+	go func() {
+		stream, err := ab.NewAtomicBroadcastClient(c.conn).Broadcast(streamCtx)
+		done <- opened{stream, err}
+	}()
+
+	var result opened
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	case <-c.closeChan:
+		cancel()
+		return errClosed
+	}
+	if result.err != nil {
+		cancel()
+		return result.err
+	}
+	c.stream, c.cancel = result.stream, cancel
+
+	reply, err := c.exchange(ctx, &ab.BroadcastMessage{Hello: requestedFeatures.Hello()})
+	switch {
+	case err != nil:
+		c.reset()
+		return err
+	case reply.Hello != nil:
+		c.features = requestedFeatures.Negotiate(reply.Hello)
+	case reply.Status.Retryable():
+		c.reset()
+		return fmt.Errorf("the orderer replied %s to the hello (%s)", reply.Status, reply.Info)
+	default:
+		logger.Debugf("The orderer at %s did not negotiate features, it is treated as a legacy orderer", c.conf.Address)
+		c.features = 0
+	}
+	return nil
+}
+
+// exchange sends msg on the stream and waits for the reply, ending the stream if ctx ends first
+func (c *BroadcastClient) exchange(ctx context.Context, msg *ab.BroadcastMessage) (*ab.BroadcastResponse, error) {
+	type replied struct {
+		reply *ab.BroadcastResponse
+		err   error
+	}
+
+	stream := c.stream
+	done := make(chan replied, 1)
+	go func() {
+		if err := stream.Send(msg); err != nil {
+			done <- replied{nil, err}
+			return
+		}
+		reply, err := stream.Recv()
+		done <- replied{reply, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.reply, result.err
+	case <-ctx.Done():
+		// The reply may still arrive, the stream is ended so that it is not taken for the reply to a later message
+		c.reset()
+		return nil, ctx.Err()
+	case <-c.closeChan:
+		c.reset()
+		return nil, errClosed
+	}
+}
+
+// reset ends the stream if there is one
+func (c *BroadcastClient) reset() {
+	if c.stream == nil {
+		return
+	}
+	c.cancel()
+	c.stream, c.cancel = nil, nil
+}
+
+// dial returns a connection to the orderer at address, over TLS unless tlsConfig is nil
+func dial(address string, tlsConfig *tls.Config) (*grpc.ClientConn, error) {
+	dialOpt := grpc.WithInsecure()
+	if tlsConfig != nil {
+		dialOpt = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	conn, err := grpc.Dial(address, dialOpt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to dial the orderer at %s: %s", address, err)
+	}
+	return conn, nil
+}
+
+// permanent returns whether sending the message again would fail in the same way
+func permanent(err error) bool {
+	if err == errNoChainRouting {
+		return true
+	}
+	switch grpc.Code(err) {
+	case codes.InvalidArgument, codes.ResourceExhausted, codes.PermissionDenied, codes.Unauthenticated, codes.Unimplemented:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap/static"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+	"github.com/hyperledger/fabric/orderer/solo"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func newTestClient(t *testing.T, address string) *BroadcastClient {
+	c, err := NewBroadcastClient(Config{Address: address, Backoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Could not create the client: %s", err)
+	}
+	return c
+}
+
+// scriptReplies has srv reply with the given statuses in turn, then SUCCESS
+func scriptReplies(srv *mocks.Server, statuses ...ab.Status) {
+	var lock sync.Mutex
+	srv.OnBroadcast(func(*ab.BroadcastMessage) *ab.BroadcastResponse {
+		lock.Lock()
+		defer lock.Unlock()
+		if len(statuses) == 0 {
+			return &ab.BroadcastResponse{Status: ab.Status_SUCCESS}
+		}
+		reply := &ab.BroadcastResponse{Status: statuses[0]}
+		statuses = statuses[1:]
+		return reply
+	})
+}
+
+func TestSendRetriesRetryableStatuses(t *testing.T) {
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	scriptReplies(srv, ab.Status_TOO_MANY_REQUESTS, ab.Status_SERVICE_UNAVAILABLE, ab.Status_REQUEST_TIMEOUT)
+	c := newTestClient(t, srv.Address())
+	defer c.Close()
+
+	reply, err := c.Send(context.Background(), &ab.BroadcastMessage{Data: []byte("data")})
+	if err != nil || reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected SUCCESS after the retries, got %v (%v)", reply, err)
+	}
+
+	broadcasts := srv.Broadcasts()
+	if len(broadcasts) != 4 {
+		t.Fatalf("Expected the message to be sent 4 times, got %d", len(broadcasts))
+	}
+	for _, msg := range broadcasts {
+		if !bytes.Equal(msg.CorrelationID, reply.CorrelationID) || len(msg.CorrelationID) == 0 {
+			t.Fatalf("Expected every attempt to carry the assigned correlation ID %x, got %x", reply.CorrelationID, msg.CorrelationID)
+		}
+	}
+}
+
+func TestSendReturnsRefusal(t *testing.T) {
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	scriptReplies(srv, ab.Status_BAD_REQUEST)
+	c := newTestClient(t, srv.Address())
+	defer c.Close()
+
+	reply, err := c.Send(context.Background(), &ab.BroadcastMessage{Data: []byte("data"), CorrelationID: []byte("mine")})
+	if err != nil || reply.Status != ab.Status_BAD_REQUEST || string(reply.CorrelationID) != "mine" {
+		t.Fatalf("Expected the refusal to be returned with the client's correlation ID, got %v (%v)", reply, err)
+	}
+	if len(srv.Broadcasts()) != 1 {
+		t.Fatalf("A refused message should not be retried")
+	}
+}
+
+func TestSendRespectsRetryAfter(t *testing.T) {
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	var once sync.Once
+	srv.OnBroadcast(func(*ab.BroadcastMessage) *ab.BroadcastResponse {
+		reply := &ab.BroadcastResponse{Status: ab.Status_SUCCESS}
+		once.Do(func() { reply = &ab.BroadcastResponse{Status: ab.Status_SERVICE_UNAVAILABLE, RetryAfter: 200} })
+		return reply
+	})
+	c := newTestClient(t, srv.Address())
+	defer c.Close()
+
+	start := time.Now()
+	if reply, err := c.Send(context.Background(), &ab.BroadcastMessage{Data: []byte("data")}); err != nil || reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected SUCCESS after the retry, got %v (%v)", reply, err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("Expected the retry to wait for RetryAfter, but it was sent after %v", elapsed)
+	}
+}
+
+func TestSendDeadline(t *testing.T) {
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	srv.OnBroadcast(func(*ab.BroadcastMessage) *ab.BroadcastResponse {
+		return &ab.BroadcastResponse{Status: ab.Status_SERVICE_UNAVAILABLE}
+	})
+	c := newTestClient(t, srv.Address())
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.Send(ctx, &ab.BroadcastMessage{Data: []byte("data")}); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the deadline to end the retries, got %v", err)
+	}
+}
+
+func TestSendAfterClose(t *testing.T) {
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	c := newTestClient(t, srv.Address())
+	c.Close()
+
+	if _, err := c.Send(context.Background(), &ab.BroadcastMessage{Data: []byte("data")}); err != errClosed {
+		t.Fatalf("Expected a closed client to refuse to send, got %v", err)
+	}
+}
+
+func TestNewBroadcastClientTLSErrors(t *testing.T) {
+	if _, err := NewBroadcastClient(Config{Address: "127.0.0.1:0", TLS: TLS{Enabled: true, RootCAs: []string{"/nonexistent/ca.pem"}}}); err == nil {
+		t.Fatalf("Expected a missing root CA to be reported")
+	}
+	if _, err := NewBroadcastClient(Config{Address: "127.0.0.1:0", TLS: TLS{Enabled: true, Certificate: "/nonexistent/cert.pem", PrivateKey: "/nonexistent/key.pem"}}); err == nil {
+		t.Fatalf("Expected a missing client certificate to be reported")
+	}
+}
+
+// soloServer serves a solo orderer on address with opts, so that it may be restarted on the same address
+type soloServer struct {
+	orderer    solo.Orderer
+	grpcServer *grpc.Server
+	opts       []grpc.ServerOption
+}
+
+func (s *soloServer) start(t *testing.T, address string) string {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	s.grpcServer = grpc.NewServer(s.opts...)
+	ab.RegisterAtomicBroadcastServer(s.grpcServer, s.orderer)
+	go s.grpcServer.Serve(lis)
+	return lis.Addr().String()
+}
+
+func TestSendAcrossServerRestart(t *testing.T) {
+	genesisBlock, err := static.New().GenesisBlock()
+	if err != nil {
+		t.Fatalf("Could not create the genesis block: %s", err)
+	}
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	orderer, err := solo.New(solo.Options{QueueSize: 10, BatchSize: 1, BatchTimeout: time.Hour, MaxWindowSize: 10, AckAfterCommit: true, DedupWindow: 10, Features: ab.AllFeatures}, lf, static.TestChainID)
+	if err != nil {
+		t.Fatalf("Could not create the orderer: %s", err)
+	}
+	defer orderer.Teardown()
+
+	s := &soloServer{orderer: orderer}
+	address := s.start(t, "127.0.0.1:0")
+	c := newTestClient(t, address)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if reply, err := c.Send(ctx, &ab.BroadcastMessage{Data: []byte("first")}); err != nil || reply.BlockNumber != 1 {
+		t.Fatalf("Expected the message to be committed in block 1, got %v (%v)", reply, err)
+	}
+
+	// The stream breaks while the next message is being sent, and the client reconnects once the server is back
+	s.grpcServer.Stop()
+	replied := make(chan *ab.BroadcastResponse)
+	go func() {
+		reply, err := c.Send(ctx, &ab.BroadcastMessage{Data: []byte("second")})
+		if err != nil {
+			t.Errorf("Expected the message to be sent after the restart, got %s", err)
+		}
+		replied <- reply
+	}()
+	time.Sleep(100 * time.Millisecond)
+	s.start(t, address)
+	defer s.grpcServer.Stop()
+
+	if reply := <-replied; reply == nil || reply.BlockNumber != 2 {
+		t.Fatalf("Expected the message to be committed in block 2, got %v", reply)
+	}
+
+	// A resent message is suppressed as a duplicate, the reply names the block it was ordered in
+	if reply, err := c.Send(ctx, &ab.BroadcastMessage{Data: []byte("first")}); err != nil || reply.BlockNumber != 1 {
+		t.Fatalf("Expected the duplicate to be replied with block 1, got %v (%v)", reply, err)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/tlsutil"
+)
+
+// Config contains what a client needs to reach an orderer and retry failed requests
+type Config struct {
+	Address    string        // The host:port of the orderer
+	TLS        TLS           // Unless enabled, the connection is not encrypted
+	Backoff    time.Duration // The delay before the first retry, defaults to 100ms
+	MaxBackoff time.Duration // The delay doubles after each retry up to this, defaults to 10s
+
+	// Window is the number of blocks a Deliver stream may send ahead of those received, defaults to 10
+	Window uint64
+	// Signers are the paths to the PEM encoded certificates of the orderers whose signatures are accepted on delivered
+	// blocks, if any are set every block must be signed by one of them
+	Signers []string
+}
+
+// TLS contains config for the connection to the orderer, the client certificate is presented for mutual TLS
+type TLS struct {
+	Enabled     bool
+	Certificate string   // Path to the PEM encoded client certificate, presented if the orderer asks for one
+	PrivateKey  string   // Path to the PEM encoded private key of the client certificate
+	RootCAs     []string // Paths to the PEM encoded certificates of the CAs trusted to sign the orderer's certificate, the system's if none
+	ServerName  string   // The name the orderer's certificate is verified against, the host of Address if empty
+}
+
+const (
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+	defaultWindow     = 10
+)
+
+// withDefaults returns conf with the defaults filled in for unset durations
+func (conf Config) withDefaults() Config {
+	if conf.Backoff <= 0 {
+		conf.Backoff = defaultBackoff
+	}
+	if conf.MaxBackoff <= 0 {
+		conf.MaxBackoff = defaultMaxBackoff
+	}
+	if conf.MaxBackoff < conf.Backoff {
+		conf.MaxBackoff = conf.Backoff
+	}
+	if conf.Window == 0 {
+		conf.Window = defaultWindow
+	}
+	return conf
+}
+
+// newTLSConfig builds the config of the connection to the orderer from conf, returning nil if TLS is not enabled
+func newTLSConfig(conf TLS) (*tls.Config, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(conf.Certificate, conf.PrivateKey, conf.RootCAs, "orderer")
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ServerName = conf.ServerName
+	return tlsConfig, nil
+}
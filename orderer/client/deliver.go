@@ -0,0 +1,305 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/blocksigner"
+	"github.com/hyperledger/fabric/orderer/common/tlsutil"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // Registers the gzip compressor of Deliver requests
+)
+
+// deliverFeatures are announced in the hello opening each Deliver stream
+var deliverFeatures = ab.NewFeatures(ab.Feature_CHAIN_ROUTING, ab.Feature_DELIVER_ACKS)
+
+// StatusError is the status with which the orderer ended a delivery
+type StatusError struct {
+	Status     ab.Status
+	Info       string
+	RetryAfter time.Duration // How long the orderer asked the client to wait before retrying, if at all
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("the orderer ended the delivery with %s (%s)", e.Status, e.Info)
+}
+
+// VerificationError reports a delivered block which does not extend the one delivered before it, or is not signed as required
+type VerificationError struct {
+	Number uint64
+	Reason string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("block %d failed verification: %s", e.Number, e.Reason)
+}
+
+// DeliverClient receives blocks from an orderer on Deliver streams, acknowledging them as they are consumed and
+// resuming after the last block received when a stream fails
+type DeliverClient struct {
+	conf     Config
+	conn     *grpc.ClientConn
+	verifier *blocksigner.Verifier
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDeliverClient returns a client of the orderer at conf.Address, the connection is made by the first delivery
+func NewDeliverClient(conf Config) (*DeliverClient, error) {
+	conf = conf.withDefaults()
+
+	tlsConfig, err := newTLSConfig(conf.TLS)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := blocksigner.NewVerifier(conf.Signers)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dial(conf.Address, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeliverClient{conf: conf, conn: conn, verifier: verifier, closeChan: make(chan struct{})}, nil
+}
+
+// Close ends the deliveries in progress and the connection
+func (c *DeliverClient) Close() error {
+	c.closeOnce.Do(func() { close(c.closeChan) })
+	return c.conn.Close()
+}
+
+// Blocks delivers the blocks sought in order, each exactly once, checking that each extends the hash chain of the one
+// before it and, if the client has Signers, that it is signed by one of them
+// The WindowSize of seek defaults to the Window of the client; seek may carry a Cursor from an earlier delivery
+// A failed stream is resumed after the last block delivered, by its cursor if the orderer sent one, so a STOP_NEWEST
+// seek which is resumed stops at the newest block at the time of the resume
+// The error channel receives one value once the block channel is closed: nil if the stop of the seek was reached,
+// a *StatusError or *VerificationError, the error of ctx, or an error which retrying would not overcome
+func (c *DeliverClient) Blocks(ctx context.Context, seek *ab.SeekInfo) (<-chan *ab.Block, <-chan error) {
+	d := &delivery{
+		client: c,
+		seek:   proto.Clone(seek).(*ab.SeekInfo),
+		blocks: make(chan *ab.Block),
+		cursor: seek.Cursor,
+	}
+	if d.seek.WindowSize == 0 {
+		d.seek.WindowSize = c.conf.Window
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		err := d.run(ctx)
+		close(d.blocks)
+		errChan <- err
+	}()
+	return d.blocks, errChan
+}
+
+// delivery is the state of a call to Blocks, which is carried from each stream to the one resuming it
+type delivery struct {
+	client   *DeliverClient
+	seek     *ab.SeekInfo
+	blocks   chan *ab.Block
+	features ab.Features     // Negotiated by the hello which opened the current stream
+	cursor   []byte          // Sent with the last block delivered
+	last     *ab.BlockHeader // The header of the last block delivered, nil before the first
+}
+
+// run opens streams until one reaches the stop of the seek or fails for a reason which is not retryable
+func (d *delivery) run(ctx context.Context) error {
+	conf := d.client.conf
+	backoff := conf.Backoff
+	for attempt := 1; ; attempt++ {
+		progressed, err := d.stream(ctx)
+		if progressed {
+			// The failures are counted, and backed off from, anew once a stream makes progress
+			backoff, attempt = conf.Backoff, 1
+		}
+
+		delay := backoff
+		switch e := err.(type) {
+		case nil:
+			return nil
+		case *VerificationError:
+			return err
+		case *StatusError:
+			if !e.Status.Retryable() {
+				if d.cursor == nil {
+					return err
+				}
+				// The orderer may no longer accept the cursor, such as after it was restarted with another key
+				logger.Warningf("The orderer refused to resume from the cursor, resuming by block number: %s", err)
+				d.cursor = nil
+				continue
+			}
+			if e.RetryAfter > 0 {
+				delay = e.RetryAfter
+			}
+			logger.Debugf("Attempt %d to deliver was ended with %s (%s), retrying", attempt, e.Status, e.Info)
+		default:
+			select {
+			case <-d.client.closeChan:
+				return errClosed
+			default:
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if permanent(err) {
+				return err
+			}
+			logger.Warningf("Attempt %d to deliver from %s failed, resuming on a new stream: %s", attempt, conf.Address, err)
+		}
+
+		select {
+		case <-time.After(tlsutil.Jitter(delay)):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.client.closeChan:
+			return errClosed
+		}
+		if backoff *= 2; backoff > conf.MaxBackoff {
+			backoff = conf.MaxBackoff
+		}
+	}
+}
+
+// stream delivers blocks from a new stream, returning whether any were delivered and nil once the stop is reached
+func (d *delivery) stream(ctx context.Context) (bool, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-d.client.closeChan:
+			cancel()
+		case <-streamCtx.Done():
+		}
+	}()
+
+	// An orderer compressing Deliver responses compresses those of the streams whose requests are compressed
+	stream, err := ab.NewAtomicBroadcastClient(d.client.conn).Deliver(streamCtx, grpc.UseCompressor("gzip"))
+	if err != nil {
+		return false, err
+	}
+
+	// The reply to the hello is not awaited, as a legacy orderer sends none
+	d.features = 0
+	if err = stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: deliverFeatures.Hello()}}); err != nil {
+		return false, err
+	}
+	if err = stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: d.resumeSeek()}}); err != nil {
+		return false, err
+	}
+
+	progressed := false
+	unacknowledged := uint64(0)
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return progressed, err
+		}
+
+		switch t := resp.Type.(type) {
+		case *ab.DeliverResponse_Hello:
+			d.features = deliverFeatures.Negotiate(t.Hello)
+		case *ab.DeliverResponse_Heartbeat:
+		case *ab.DeliverResponse_Error:
+			if t.Error == ab.Status_SUCCESS {
+				return progressed, nil
+			}
+			return progressed, &StatusError{Status: t.Error, Info: resp.Info, RetryAfter: time.Duration(resp.RetryAfter) * time.Millisecond}
+		case *ab.DeliverResponse_Block:
+			block := t.Block
+			if block.Header == nil {
+				return progressed, &VerificationError{Reason: "the block has no header"}
+			}
+			if d.last != nil && block.Header.Number <= d.last.Number {
+				// Delivered before the stream was resumed
+				continue
+			}
+			if len(d.seek.ChainID) > 0 && !d.features.Has(ab.Feature_CHAIN_ROUTING) {
+				return progressed, errNoChainRouting
+			}
+			if err := d.verify(block); err != nil {
+				return progressed, err
+			}
+
+			select {
+			case d.blocks <- block:
+			case <-streamCtx.Done():
+				return progressed, streamCtx.Err()
+			}
+			d.last, d.cursor, progressed = block.Header, resp.Cursor, true
+			if d.seek.Stop == ab.SeekInfo_STOP_SPECIFIED && block.Header.Number >= d.seek.StopNumber {
+				// The status which follows is not awaited, so that a stream failing after the last block is not resumed
+				return progressed, nil
+			}
+
+			// Half the window is acknowledged at a time, so that the orderer need not wait for each acknowledgement
+			if unacknowledged++; d.features.Has(ab.Feature_DELIVER_ACKS) && unacknowledged >= (d.seek.WindowSize+1)/2 {
+				unacknowledged = 0
+				if err := stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: block.Header.Number}}}); err != nil {
+					return progressed, err
+				}
+			}
+		}
+	}
+}
+
+// resumeSeek returns the seek which continues after the last block delivered, or the original seek if none was
+func (d *delivery) resumeSeek() *ab.SeekInfo {
+	seek := proto.Clone(d.seek).(*ab.SeekInfo)
+	seek.Cursor = d.cursor
+	if d.cursor == nil && d.last != nil {
+		seek.Start = ab.SeekInfo_SPECIFIED
+		seek.SpecifiedNumber = d.last.Number + 1
+	}
+	return seek
+}
+
+// verify returns a *VerificationError unless the block follows the last one delivered and is signed as required
+func (d *delivery) verify(block *ab.Block) error {
+	number := block.Header.Number
+	if d.last != nil {
+		if number != d.last.Number+1 {
+			return &VerificationError{Number: number, Reason: fmt.Sprintf("expected block %d", d.last.Number+1)}
+		}
+		if !bytes.Equal(block.Header.PreviousHash, d.last.Hash()) {
+			return &VerificationError{Number: number, Reason: fmt.Sprintf("the previous hash does not match the hash of block %d", d.last.Number)}
+		}
+	}
+	if d.seek.Content == ab.SeekInfo_FULL {
+		if block.Data == nil || !bytes.Equal(block.Data.Hash(), block.Header.DataHash) {
+			return &VerificationError{Number: number, Reason: "the data does not match the data hash of the header"}
+		}
+	}
+	if err := d.client.verifier.Verify(block); err != nil {
+		return &VerificationError{Number: number, Reason: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
+	"github.com/hyperledger/fabric/orderer/common/blocksigner"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap/static"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+	"github.com/hyperledger/fabric/orderer/solo"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// writeIdentity writes a self-signed certificate and its private key to dir, returning the paths of both files
+func writeIdentity(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "orderer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "orderer.crt"), filepath.Join(dir, "orderer.key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func newTestDeliverClient(t *testing.T, conf Config) *DeliverClient {
+	conf.Backoff, conf.MaxBackoff = time.Millisecond, 10*time.Millisecond
+	c, err := NewDeliverClient(conf)
+	if err != nil {
+		t.Fatalf("Could not create the client: %s", err)
+	}
+	return c
+}
+
+func TestBlocksAcrossServerRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeIdentity(t, dir)
+	signer, err := blocksigner.New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Error loading the signing identity: %s", err)
+	}
+
+	genesisBlock, err := static.New().GenesisBlock()
+	if err != nil {
+		t.Fatalf("Could not create the genesis block: %s", err)
+	}
+	lf := ramledger.NewFactory(100)
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	last := uint64(20)
+	for i := uint64(1); i <= last; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, signer.Seal)
+	}
+	orderer, err := solo.New(solo.Options{QueueSize: 10, BatchSize: 1, BatchTimeout: time.Hour, MaxWindowSize: 10, Features: ab.AllFeatures}, lf, static.TestChainID)
+	if err != nil {
+		t.Fatalf("Could not create the orderer: %s", err)
+	}
+	defer orderer.Teardown()
+
+	s := &soloServer{orderer: orderer}
+	address := s.start(t, "127.0.0.1:0")
+	c := newTestDeliverClient(t, Config{Address: address, Window: 2, Signers: []string{certFile}})
+	defer c.Close()
+
+	// The genesis block is not signed, so the replay starts after it
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	blocks, errs := c.Blocks(ctx, &ab.SeekInfo{Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: 1, Stop: ab.SeekInfo_STOP_SPECIFIED, StopNumber: last})
+
+	expected := uint64(1)
+	for block := range blocks {
+		if block.Header.Number != expected {
+			t.Fatalf("Expected block %d, got block %d", expected, block.Header.Number)
+		}
+		expected++
+
+		// The orderer is killed twice during the replay, the stream is resumed once it is back
+		if block.Header.Number == 5 || block.Header.Number == 12 {
+			s.grpcServer.Stop()
+			time.Sleep(100 * time.Millisecond)
+			s.start(t, address)
+		}
+	}
+	defer s.grpcServer.Stop()
+
+	if err := <-errs; err != nil {
+		t.Fatalf("Expected the delivery to end at its stop, got %s", err)
+	}
+	if expected != last+1 {
+		t.Fatalf("Expected every block up to %d, the last was %d", last, expected-1)
+	}
+}
+
+func deliverBlocks(srv *mocks.Server, blocks ...*ab.Block) {
+	srv.OnDeliver(func(update *ab.DeliverUpdate) []*ab.DeliverResponse {
+		if update.GetSeek() == nil {
+			return nil
+		}
+		var replies []*ab.DeliverResponse
+		for _, block := range blocks {
+			replies = append(replies, &ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: block}})
+		}
+		return append(replies, &ab.DeliverResponse{Type: &ab.DeliverResponse_Error{Error: ab.Status_SUCCESS}})
+	})
+}
+
+func TestBlocksBrokenHashChain(t *testing.T) {
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	first := ab.NewBlock(1, nil, nil, nil)
+	deliverBlocks(srv, first, ab.NewBlock(2, []byte("forged"), nil, nil))
+	c := newTestDeliverClient(t, Config{Address: srv.Address()})
+	defer c.Close()
+
+	blocks, errs := c.Blocks(context.Background(), &ab.SeekInfo{Start: ab.SeekInfo_OLDEST})
+	if block := <-blocks; block == nil || block.Header.Number != 1 {
+		t.Fatalf("Expected block 1 to be delivered, got %v", block)
+	}
+	if block, ok := <-blocks; ok {
+		t.Fatalf("Expected the forged block not to be delivered, got %v", block)
+	}
+	if err, ok := (<-errs).(*VerificationError); !ok || err.Number != 2 {
+		t.Fatalf("Expected a verification error for block 2, got %v", err)
+	}
+}
+
+func TestBlocksUnsignedRefused(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, _ := writeIdentity(t, dir)
+
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	deliverBlocks(srv, ab.NewBlock(1, nil, nil, nil))
+	c := newTestDeliverClient(t, Config{Address: srv.Address(), Signers: []string{certFile}})
+	defer c.Close()
+
+	blocks, errs := c.Blocks(context.Background(), &ab.SeekInfo{Start: ab.SeekInfo_OLDEST})
+	if block, ok := <-blocks; ok {
+		t.Fatalf("Expected the unsigned block not to be delivered, got %v", block)
+	}
+	if _, ok := (<-errs).(*VerificationError); !ok {
+		t.Fatalf("Expected a verification error")
+	}
+}
+
+func TestBlocksTerminalStatus(t *testing.T) {
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	srv.OnDeliver(func(*ab.DeliverUpdate) []*ab.DeliverResponse {
+		return []*ab.DeliverResponse{ab.ReasonNotRetained.DeliverResponse("seek target 7 exceeds height 3")}
+	})
+	c := newTestDeliverClient(t, Config{Address: srv.Address()})
+	defer c.Close()
+
+	blocks, errs := c.Blocks(context.Background(), &ab.SeekInfo{Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: 7})
+	if block, ok := <-blocks; ok {
+		t.Fatalf("Expected no block, got %v", block)
+	}
+	if err, ok := (<-errs).(*StatusError); !ok || err.Status != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected NOT_FOUND as a status error, got %v", err)
+	}
+	if len(srv.DeliverUpdates()) != 1 {
+		t.Fatalf("A terminal status should not be retried")
+	}
+}
+
+func TestBlocksResumeByNumber(t *testing.T) {
+	srv := mocks.StartServer()
+	defer srv.Stop()
+	first := ab.NewBlock(1, nil, nil, nil)
+	second := ab.NewBlock(2, first.Hash(), nil, nil)
+	seeks := 0
+	srv.OnDeliver(func(update *ab.DeliverUpdate) []*ab.DeliverResponse {
+		if update.GetSeek() == nil {
+			return nil
+		}
+		if seeks++; seeks == 1 {
+			return []*ab.DeliverResponse{
+				&ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: first}},
+				ab.ReasonUnavailable.DeliverResponse("shutting down"),
+			}
+		}
+		// The resumed stream repeats the block already delivered, which the client skips
+		return []*ab.DeliverResponse{
+			&ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: first}},
+			&ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: second}},
+			&ab.DeliverResponse{Type: &ab.DeliverResponse_Error{Error: ab.Status_SUCCESS}},
+		}
+	})
+	c := newTestDeliverClient(t, Config{Address: srv.Address()})
+	defer c.Close()
+
+	blocks, errs := c.Blocks(context.Background(), &ab.SeekInfo{Start: ab.SeekInfo_OLDEST})
+	var numbers []uint64
+	for block := range blocks {
+		numbers = append(numbers, block.Header.Number)
+	}
+	if err := <-errs; err != nil || len(numbers) != 2 || numbers[0] != 1 || numbers[1] != 2 {
+		t.Fatalf("Expected blocks 1 and 2 once each, got %v (%v)", numbers, err)
+	}
+
+	var resumed *ab.SeekInfo
+	for _, update := range srv.DeliverUpdates() {
+		if seek := update.GetSeek(); seek != nil {
+			resumed = seek
+		}
+	}
+	if resumed.Start != ab.SeekInfo_SPECIFIED || resumed.SpecifiedNumber != 2 {
+		t.Fatalf("Expected the stream to be resumed at block 2, got %v", resumed)
+	}
+}
+
+// compressionRecorder records the compression of the requests of each method called
+type compressionRecorder struct {
+	lock    sync.Mutex
+	methods map[string]string
+}
+
+func (cr *compressionRecorder) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (cr *compressionRecorder) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if header, ok := s.(*stats.InHeader); ok {
+		cr.lock.Lock()
+		defer cr.lock.Unlock()
+		cr.methods[header.FullMethod] = header.Compression
+	}
+}
+
+func (cr *compressionRecorder) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (cr *compressionRecorder) HandleConn(ctx context.Context, s stats.ConnStats) {}
+
+func (cr *compressionRecorder) compression(method string) (string, bool) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	compression, ok := cr.methods[method]
+	return compression, ok
+}
+
+func TestOnlyDeliverRequestsCompressed(t *testing.T) {
+	genesisBlock, err := static.New().GenesisBlock()
+	if err != nil {
+		t.Fatalf("Could not create the genesis block: %s", err)
+	}
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	orderer, err := solo.New(solo.Options{QueueSize: 10, BatchSize: 1, BatchTimeout: time.Hour, MaxWindowSize: 10, AckAfterCommit: true, Features: ab.AllFeatures}, lf, static.TestChainID)
+	if err != nil {
+		t.Fatalf("Could not create the orderer: %s", err)
+	}
+	defer orderer.Teardown()
+
+	recorder := &compressionRecorder{methods: make(map[string]string)}
+	s := &soloServer{orderer: orderer, opts: []grpc.ServerOption{grpc.StatsHandler(recorder)}}
+	address := s.start(t, "127.0.0.1:0")
+	defer s.grpcServer.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	bc := newTestClient(t, address)
+	defer bc.Close()
+	if _, err := bc.Send(ctx, &ab.BroadcastMessage{Data: []byte("first")}); err != nil {
+		t.Fatalf("Expected the message to be committed, got %s", err)
+	}
+	dc := newTestDeliverClient(t, Config{Address: address})
+	defer dc.Close()
+	blocks, errs := dc.Blocks(ctx, &ab.SeekInfo{Start: ab.SeekInfo_OLDEST, Stop: ab.SeekInfo_STOP_SPECIFIED, StopNumber: 1})
+	for range blocks {
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Expected the delivery to end at its stop, got %s", err)
+	}
+
+	if compression, ok := recorder.compression("/atomicbroadcast.AtomicBroadcast/Deliver"); !ok || compression != "gzip" {
+		t.Fatalf("Expected the Deliver requests to be compressed with gzip, got %q", compression)
+	}
+	if compression, ok := recorder.compression("/atomicbroadcast.AtomicBroadcast/Broadcast"); !ok || compression != "" {
+		t.Fatalf("Expected the Broadcast requests not to be compressed, got %q", compression)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+)
+
+var logger = logging.MustGetLogger("orderer/audit")
+
+// Item is a configuration item which a configuration transaction set
+type Item struct {
+	Type    string   `json:"type"`
+	ID      string   `json:"id"`
+	Signers []string `json:"signers"` // The fingerprints of the identities which signed the item, see Fingerprints
+}
+
+// Record is the audit record of a configuration transaction applied to a chain
+type Record struct {
+	ChainID     string    `json:"chainID"` // Hex encoded
+	Sequence    uint64    `json:"sequence"`
+	BlockNumber uint64    `json:"blockNumber"` // The block carrying the configuration transaction
+	Changed     []Item    `json:"changed"`
+	Time        time.Time `json:"time"` // When the configuration was applied
+}
+
+// NewRecord describes the items which configTx, committed in the given block, changed, those last modified at its sequence
+// The items it restates unchanged are left out, as every configuration transaction restates the whole configuration
+func NewRecord(blockNumber uint64, configTx *ab.ConfigurationEnvelope, now time.Time) *Record {
+	record := &Record{
+		ChainID:     hex.EncodeToString(configTx.ChainID),
+		Sequence:    configTx.Sequence,
+		BlockNumber: blockNumber,
+		Changed:     []Item{},
+		Time:        now.UTC(),
+	}
+
+	for _, entry := range configTx.Entries {
+		config := &ab.Configuration{}
+		if err := proto.Unmarshal(entry.Configuration, config); err != nil {
+			// The transaction was applied, so this is unreachable
+			continue
+		}
+		if config.LastModified != configTx.Sequence {
+			continue
+		}
+		record.Changed = append(record.Changed, Item{Type: config.Type.String(), ID: config.ID, Signers: Fingerprints(cauthdsl.Signers(entry.Signatures))})
+	}
+
+	return record
+}
+
+// Fingerprints names each identity by the hex encoded SHA-256 fingerprint of its certificate, or as none if it is nil
+func Fingerprints(identities [][]byte) []string {
+	names := make([]string, len(identities))
+	for i, identity := range identities {
+		if identity == nil {
+			names[i] = "none"
+			continue
+		}
+		sum := sha256.Sum256(identity)
+		names[i] = hex.EncodeToString(sum[:])
+	}
+	return names
+}
+
+// Trail writes each audit record to the orderer/audit logger, and if it has a file, appends it there as a line of JSON
+type Trail struct {
+	lock sync.Mutex // Guards file
+	file *os.File
+}
+
+// Open returns a Trail appending to the file at path, which is created if need be, or which only logs if path is empty
+func Open(path string) (*Trail, error) {
+	if path == "" {
+		return &Trail{}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &Trail{file: file}, nil
+}
+
+// Write records a configuration change, a nil Trail only logs it
+// The record is synced to the file before Write returns, a record which cannot be written is logged as an error
+func (t *Trail) Write(record *Record) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		logger.Errorf("Could not marshal the audit record of configuration sequence %d: %s", record.Sequence, err)
+		return
+	}
+	logger.Infof("Configuration applied: %s", line)
+
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.file == nil {
+		return
+	}
+	if _, err := t.file.Write(append(line, '\n')); err != nil {
+		logger.Errorf("Could not append the audit record of configuration sequence %d to %s: %s", record.Sequence, t.file.Name(), err)
+		return
+	}
+	if err := t.file.Sync(); err != nil {
+		logger.Errorf("Could not sync the audit record of configuration sequence %d to %s: %s", record.Sequence, t.file.Name(), err)
+	}
+}
+
+// Close releases the file, records written afterwards are only logged
+func (t *Trail) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func entry(id string, lastModified uint64, signers ...[]byte) *ab.ConfigurationEntry {
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: []byte("chain"), ID: id, Type: ab.Configuration_Orderer, LastModified: lastModified})
+	entry := &ab.ConfigurationEntry{Configuration: item}
+	for _, signer := range signers {
+		envelope := []byte("malformed")
+		if signer != nil {
+			envelope, _ = proto.Marshal(&ab.PayloadEnvelope{Signer: signer})
+		}
+		entry.Signatures = append(entry.Signatures, &ab.SignedData{PayloadEnvelope: envelope})
+	}
+	return entry
+}
+
+func TestNewRecord(t *testing.T) {
+	configTx := &ab.ConfigurationEnvelope{
+		Sequence: 4,
+		ChainID:  []byte("chain"),
+		Entries:  []*ab.ConfigurationEntry{entry("BatchSize", 4, []byte("admin"), nil), entry("BatchTimeout", 2, []byte("admin"))},
+	}
+	now := time.Unix(1000, 0)
+	record := NewRecord(7, configTx, now)
+
+	if record.ChainID != "636861696e" || record.Sequence != 4 || record.BlockNumber != 7 || !record.Time.Equal(now) {
+		t.Fatalf("Expected the record to name the chain, sequence, block and time, got %+v", record)
+	}
+	if len(record.Changed) != 1 || record.Changed[0].Type != "Orderer" || record.Changed[0].ID != "BatchSize" {
+		t.Fatalf("Expected only the item modified at sequence 4 to be listed, got %v", record.Changed)
+	}
+	if signers := record.Changed[0].Signers; len(signers) != 2 || signers[0] != Fingerprints([][]byte{[]byte("admin")})[0] || signers[1] != "none" {
+		t.Fatalf("Expected the signer and a malformed signature to be listed, got %v", signers)
+	}
+}
+
+func TestTrailAppends(t *testing.T) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+	path := location + "/audit.log"
+
+	for sequence := uint64(1); sequence <= 2; sequence++ {
+		trail, err := Open(path)
+		if err != nil {
+			t.Fatalf("Error opening the audit trail: %s", err)
+		}
+		trail.Write(NewRecord(sequence, &ab.ConfigurationEnvelope{Sequence: sequence}, time.Now()))
+		if err := trail.Close(); err != nil {
+			t.Fatalf("Error closing the audit trail: %s", err)
+		}
+		// Records written once the trail is closed are only logged
+		trail.Write(NewRecord(sequence, &ab.ConfigurationEnvelope{Sequence: 10}, time.Now()))
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading the audit trail: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected the records of both trails to be kept, got %d", len(lines))
+	}
+	for i, line := range lines {
+		record := &Record{}
+		if err := json.Unmarshal([]byte(line), record); err != nil || record.Sequence != uint64(i+1) {
+			t.Fatalf("Expected record %d to be configuration sequence %d, got %s (%v)", i, i+1, line, err)
+		}
+	}
+}
+
+func TestNilTrail(t *testing.T) {
+	var trail *Trail
+	trail.Write(NewRecord(1, &ab.ConfigurationEnvelope{Sequence: 1}, time.Now()))
+	if err := trail.Close(); err != nil {
+		t.Fatalf("Expected closing a nil trail to do nothing, got %s", err)
+	}
+}
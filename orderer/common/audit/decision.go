@@ -0,0 +1,244 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/clock"
+
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// The enforcement points at which policies are evaluated
+const (
+	PointBroadcast     = "broadcast"      // The policy which the messages broadcast to a chain must satisfy
+	PointDeliver       = "deliver"        // The Readers policy which the seeks delivered from a chain must satisfy
+	PointConfiguration = "configuration"  // The modification policies of the items a configuration transaction sets
+	PointChainCreation = "chain_creation" // The policy of the system chain which chain creation transactions must satisfy
+)
+
+// Decision is the outcome of a policy evaluated at an enforcement point
+type Decision struct {
+	Point      string    `json:"point"`
+	PolicyID   string    `json:"policy"`
+	ChainID    string    `json:"chainID"`          // Hex encoded
+	Client     string    `json:"client,omitempty"` // The identity of the client, see ClientIdentity, if known
+	Signers    []string  `json:"signers"`          // The fingerprints of the identities which signed, see Fingerprints
+	Allowed    bool      `json:"allowed"`
+	Detail     string    `json:"detail,omitempty"`     // Why the policy was not satisfied
+	Suppressed int       `json:"suppressed,omitempty"` // The denials of the same identity left unwritten since the last written
+	Time       time.Time `json:"time"`
+}
+
+// NewDecision describes the outcome err of evaluating a policy of a chain over the signatures of signers, see cauthdsl.Signers, it is allowed if err is nil
+func NewDecision(point, policyID string, chainID []byte, client string, signers [][]byte, err error) *Decision {
+	decision := &Decision{
+		Point:    point,
+		PolicyID: policyID,
+		ChainID:  hex.EncodeToString(chainID),
+		Client:   client,
+		Signers:  Fingerprints(signers),
+		Allowed:  err == nil,
+	}
+	if err != nil {
+		decision.Detail = err.Error()
+	}
+	return decision
+}
+
+// ClientIdentity returns the fingerprint of the certificate a client authenticated with over TLS, or its host if it did not,
+// which unlike the signatures of its requests the client cannot choose freely, or the empty string if the peer is unknown
+func ClientIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+		fingerprint := sha256.Sum256(tlsInfo.State.PeerCertificates[0].Raw)
+		return hex.EncodeToString(fingerprint[:])
+	}
+
+	if p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// The bounds of a DecisionLog across identities, so that denials of ever changing identities can neither flood the log nor exhaust memory
+const (
+	maxWindows        = 10000 // The identities whose denials are limited separately, those of further identities share the overflow window
+	globalBurstFactor = 100   // At most this many times the burst of denials are written per interval across every identity
+)
+
+// overflowIdentity is the identity of the window shared by the identities beyond maxWindows, which no client identity can be
+const overflowIdentity = "*"
+
+type denialWindow struct {
+	start      time.Time
+	written    int
+	suppressed int
+}
+
+// DecisionLog writes each policy decision to the orderer/audit logger as JSON, denials at WARNING and the rest at DEBUG
+// At most burst denials of each client identity are written per interval, and at most globalBurstFactor times burst across
+// every identity, so that a flood of denials cannot flood the log, the count of those left unwritten is carried by the
+// next denial written for the identity, or logged once the interval ends if the global limit left them out
+// Denials are limited by the client the request came from and never by its signers, which a client may vary at will
+type DecisionLog struct {
+	burst    int
+	interval time.Duration
+	clock    clock.Clock
+	out      *logging.Logger
+
+	lock      sync.Mutex // Guards windows, all and lastSweep
+	windows   map[string]*denialWindow
+	all       denialWindow
+	lastSweep time.Time
+}
+
+// NewDecisionLog returns a DecisionLog writing burst denials of each identity per interval, as measured by clk,
+// if burst or interval is zero the denials are not limited
+func NewDecisionLog(burst int, interval time.Duration, clk clock.Clock) *DecisionLog {
+	return &DecisionLog{
+		burst:    burst,
+		interval: interval,
+		clock:    clk,
+		out:      logging.MustGetLogger("orderer/audit"),
+		windows:  make(map[string]*denialWindow),
+	}
+}
+
+// SetBackend writes the decisions to backend rather than to the logging backend of the process, before dl is first used
+func (dl *DecisionLog) SetBackend(backend logging.LeveledBackend) {
+	dl.out.SetBackend(backend)
+}
+
+// output returns the logger decisions are written to, the package logger for a nil DecisionLog
+func (dl *DecisionLog) output() *logging.Logger {
+	if dl == nil {
+		return logger
+	}
+	return dl.out
+}
+
+// admit returns whether a denial of identity may be written at now, and how many of its denials were left unwritten before it
+func (dl *DecisionLog) admit(identity string, now time.Time) (bool, int) {
+	if dl.burst == 0 || dl.interval == 0 {
+		return true, 0
+	}
+
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	// The windows of identities which have fallen quiet are dropped, so that denials of ever changing identities do not accumulate
+	if now.Sub(dl.lastSweep) >= dl.interval {
+		for id, window := range dl.windows {
+			if id == identity || now.Sub(window.start) < dl.interval {
+				continue
+			}
+			if window.suppressed > 0 {
+				dl.out.Warningf("Policy denied %d more requests of %s, which were not written", window.suppressed, id)
+			}
+			delete(dl.windows, id)
+		}
+		dl.lastSweep = now
+	}
+
+	if now.Sub(dl.all.start) >= dl.interval {
+		if dl.all.suppressed > 0 {
+			dl.out.Warningf("Policy denied %d more requests across all clients, which were not written", dl.all.suppressed)
+		}
+		dl.all = denialWindow{start: now}
+	}
+	if dl.all.written >= dl.burst*globalBurstFactor {
+		dl.all.suppressed++
+		return false, 0
+	}
+
+	window, ok := dl.windows[identity]
+	if !ok && len(dl.windows) >= maxWindows {
+		identity = overflowIdentity
+		window, ok = dl.windows[identity]
+	}
+	if !ok || now.Sub(window.start) >= dl.interval {
+		suppressed := 0
+		if ok {
+			suppressed = window.suppressed
+		}
+		dl.windows[identity] = &denialWindow{start: now, written: 1}
+		dl.all.written++
+		return true, suppressed
+	}
+	if window.written < dl.burst {
+		window.written++
+		dl.all.written++
+		suppressed := window.suppressed
+		window.suppressed = 0
+		return true, suppressed
+	}
+	window.suppressed++
+	return false, 0
+}
+
+// Record writes a decision, a nil DecisionLog writes every denial
+func (dl *DecisionLog) Record(decision *Decision) {
+	out := dl.output()
+	if decision.Allowed {
+		if !out.IsEnabledFor(logging.DEBUG) {
+			return
+		}
+		decision.Time = time.Now().UTC()
+		if dl != nil {
+			decision.Time = dl.clock.Now().UTC()
+		}
+		if line, err := json.Marshal(decision); err == nil {
+			out.Debugf("Policy decision: %s", line)
+		}
+		return
+	}
+
+	now := time.Now()
+	if dl != nil {
+		now = dl.clock.Now()
+		ok, suppressed := dl.admit(decision.Client, now)
+		if !ok {
+			return
+		}
+		decision.Suppressed = suppressed
+	}
+	decision.Time = now.UTC()
+	line, err := json.Marshal(decision)
+	if err != nil {
+		out.Errorf("Could not marshal the denial by policy %s at %s: %s", decision.PolicyID, decision.Point, err)
+		return
+	}
+	out.Warningf("Policy denied: %s", line)
+}
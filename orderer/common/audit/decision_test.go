@@ -0,0 +1,210 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/clock"
+
+	"github.com/op/go-logging"
+)
+
+// logCapture collects the lines written by the DecisionLogs it is installed in
+type logCapture struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (lc *logCapture) Write(p []byte) (int, error) {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.buf.Write(p)
+}
+
+// decisions returns the decisions logged after prefix, such as "Policy denied: "
+func (lc *logCapture) decisions(t *testing.T, prefix string) []*Decision {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	var decisions []*Decision
+	for _, line := range strings.Split(lc.buf.String(), "\n") {
+		i := strings.Index(line, prefix)
+		if i < 0 {
+			continue
+		}
+		decision := &Decision{}
+		if err := json.Unmarshal([]byte(line[i+len(prefix):]), decision); err != nil {
+			t.Fatalf("Expected a JSON decision after %q, got %q: %s", prefix, line, err)
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
+
+// captureLogs installs a logCapture as the backend of each DecisionLog
+func captureLogs(logs ...*DecisionLog) *logCapture {
+	lc := &logCapture{}
+	backend := logging.AddModuleLevel(logging.NewLogBackend(lc, "", 0))
+	for _, dl := range logs {
+		dl.SetBackend(backend)
+	}
+	return lc
+}
+
+func (lc *logCapture) String() string {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.buf.String()
+}
+
+func TestDecisionFields(t *testing.T) {
+	clk := clock.NewFake()
+	dl := NewDecisionLog(0, 0, clk)
+	lc := captureLogs(dl)
+	dl.Record(NewDecision(PointDeliver, "Readers", []byte("chain"), "client", [][]byte{[]byte("alice"), nil}, fmt.Errorf("Failed to authenticate policy")))
+
+	decisions := lc.decisions(t, "Policy denied: ")
+	if len(decisions) != 1 {
+		t.Fatalf("Expected one denial to be logged, got %d", len(decisions))
+	}
+	d := decisions[0]
+	if d.Point != PointDeliver || d.PolicyID != "Readers" || d.ChainID != "636861696e" || d.Client != "client" || d.Allowed || d.Detail != "Failed to authenticate policy" || !d.Time.Equal(clk.Now()) {
+		t.Fatalf("Expected the denial to name the point, policy, chain, client, outcome, and time, got %+v", d)
+	}
+	if len(d.Signers) != 2 || d.Signers[0] != Fingerprints([][]byte{[]byte("alice")})[0] || d.Signers[1] != "none" {
+		t.Fatalf("Expected the fingerprints of the signers, got %v", d.Signers)
+	}
+}
+
+func TestDecisionAllowedAtDebug(t *testing.T) {
+	defer logging.SetLevel(logging.DEBUG, "orderer/audit")
+
+	allowed := func() *Decision {
+		return NewDecision(PointBroadcast, "Writers", []byte("chain"), "", [][]byte{[]byte("alice")}, nil)
+	}
+	atInfo, atDebug := NewDecisionLog(0, 0, clock.NewFake()), NewDecisionLog(0, 0, clock.NewFake())
+	lc := captureLogs(atInfo, atDebug)
+	logging.SetLevel(logging.INFO, "orderer/audit")
+	atInfo.Record(allowed())
+	if decisions := lc.decisions(t, "Policy decision: "); len(decisions) != 0 {
+		t.Fatalf("Expected allowed decisions not to be logged above DEBUG, got %v", decisions)
+	}
+
+	logging.SetLevel(logging.DEBUG, "orderer/audit")
+	atDebug.Record(allowed())
+	decisions := lc.decisions(t, "Policy decision: ")
+	if len(decisions) != 1 || !decisions[0].Allowed || decisions[0].PolicyID != "Writers" {
+		t.Fatalf("Expected the allowed decision to be logged at DEBUG, got %v", decisions)
+	}
+	if denials := lc.decisions(t, "Policy denied: "); len(denials) != 0 {
+		t.Fatalf("Expected an allowed decision not to be logged as a denial")
+	}
+}
+
+func TestDecisionRateLimit(t *testing.T) {
+	clk := clock.NewFake()
+	dl := NewDecisionLog(2, time.Minute, clk)
+	lc := captureLogs(dl)
+	deny := func(client string, signer string) {
+		dl.Record(NewDecision(PointBroadcast, "Writers", []byte("chain"), client, [][]byte{[]byte(signer)}, fmt.Errorf("denied")))
+	}
+
+	// The signers do not matter, a client varying them is limited all the same
+	for i := 0; i < 5; i++ {
+		deny("flood", fmt.Sprintf("signer %d", i))
+	}
+	// Another client is limited separately
+	deny("other", "signer 0")
+	if decisions := lc.decisions(t, "Policy denied: "); len(decisions) != 3 {
+		t.Fatalf("Expected two denials of the flooding client and one of the other, got %d", len(decisions))
+	}
+
+	clk.Advance(time.Minute)
+	deny("flood", "alice")
+	decisions := lc.decisions(t, "Policy denied: ")
+	if len(decisions) != 4 || decisions[3].Client != "flood" || decisions[3].Suppressed != 3 {
+		t.Fatalf("Expected the next denial of the interval to count the 3 left unwritten, got %+v", decisions[len(decisions)-1])
+	}
+}
+
+func TestDecisionRateLimitSweep(t *testing.T) {
+	clk := clock.NewFake()
+	dl := NewDecisionLog(1, time.Minute, clk)
+	lc := captureLogs(dl)
+	for i := 0; i < 3; i++ {
+		dl.Record(NewDecision(PointBroadcast, "Writers", nil, "quiet", nil, fmt.Errorf("denied")))
+	}
+
+	clk.Advance(time.Minute)
+	dl.Record(NewDecision(PointBroadcast, "Writers", nil, "other", nil, fmt.Errorf("denied")))
+	logged := lc.String()
+	if !strings.Contains(logged, "Policy denied 2 more requests of quiet") {
+		t.Fatalf("Expected the denials left unwritten of a client which fell quiet to be counted once its window is dropped, got %q", logged)
+	}
+	if len(dl.windows) != 1 {
+		t.Fatalf("Expected only the window of the client denied in the current interval to be kept, got %d", len(dl.windows))
+	}
+}
+
+func TestDecisionRateLimitWindowCap(t *testing.T) {
+	clk := clock.NewFake()
+	// A burst large enough that the global limit admits a denial of every client
+	dl := NewDecisionLog(maxWindows/globalBurstFactor+1, time.Minute, clk)
+	lc := captureLogs(dl)
+
+	// Each client beyond the cap shares the overflow window, rather than adding its own
+	for i := 0; i < maxWindows+10; i++ {
+		dl.Record(NewDecision(PointDeliver, "Readers", nil, fmt.Sprintf("client %d", i), nil, fmt.Errorf("denied")))
+	}
+	if len(dl.windows) > maxWindows+1 {
+		t.Fatalf("Expected at most %d windows, got %d", maxWindows+1, len(dl.windows))
+	}
+	if _, ok := dl.windows[overflowIdentity]; !ok {
+		t.Fatalf("Expected the clients beyond the cap to share the overflow window")
+	}
+	if denials := lc.decisions(t, "Policy denied: "); len(denials) != maxWindows+10 {
+		t.Fatalf("Expected a denial of each client to be written, got %d", len(denials))
+	}
+}
+
+func TestDecisionRateLimitGlobalCap(t *testing.T) {
+	clk := clock.NewFake()
+	dl := NewDecisionLog(1, time.Minute, clk)
+	lc := captureLogs(dl)
+
+	for i := 0; i < globalBurstFactor+50; i++ {
+		dl.Record(NewDecision(PointBroadcast, "Writers", nil, fmt.Sprintf("client %d", i), nil, fmt.Errorf("denied")))
+	}
+	if denials := lc.decisions(t, "Policy denied: "); len(denials) != globalBurstFactor {
+		t.Fatalf("Expected %d denials to be written across the clients, got %d", globalBurstFactor, len(denials))
+	}
+
+	clk.Advance(time.Minute)
+	dl.Record(NewDecision(PointBroadcast, "Writers", nil, "late", nil, fmt.Errorf("denied")))
+	if logged := lc.String(); !strings.Contains(logged, "Policy denied 50 more requests across all clients") {
+		t.Fatalf("Expected the denials left out by the global limit to be counted once the interval ends, got %q", logged)
+	}
+	if denials := lc.decisions(t, "Policy denied: "); len(denials) != globalBurstFactor+1 {
+		t.Fatalf("Expected the denial of the next interval to be written")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blockcutter
+
+import (
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+// Receiver accumulates ordered messages into batches, cutting a batch once it holds the batch size in messages,
+// or once the next message would take its data beyond the byte limit
+// The batch timeout is left to the caller, which cuts the pending batch once it expires
+type Receiver interface {
+	// Ordered adds a message to the pending batch, returning the batches which are ready to be committed, in order,
+	// and whether a batch remains pending
+	Ordered(msg *ab.BroadcastMessage) (batches [][]*ab.BroadcastMessage, pending bool)
+
+	// Cut returns the pending batch, which may be empty, and begins a new one
+	Cut() []*ab.BroadcastMessage
+}
+
+type receiver struct {
+	batchSize    int
+	maxBytes     int
+	pendingBatch []*ab.BroadcastMessage
+	pendingBytes int
+}
+
+// NewReceiver creates a Receiver which cuts batches of batchSize messages, a maxBytes of zero disables the byte limit
+func NewReceiver(batchSize, maxBytes int) Receiver {
+	return &receiver{
+		batchSize: batchSize,
+		maxBytes:  maxBytes,
+	}
+}
+
+func (r *receiver) Ordered(msg *ab.BroadcastMessage) ([][]*ab.BroadcastMessage, bool) {
+	var batches [][]*ab.BroadcastMessage
+	size := len(msg.Data)
+
+	if r.maxBytes > 0 && r.pendingBytes+size > r.maxBytes {
+		if len(r.pendingBatch) > 0 {
+			batches = append(batches, r.Cut())
+		}
+		// A message beyond the limit by itself is ordered in a batch of its own
+		if size > r.maxBytes {
+			return append(batches, []*ab.BroadcastMessage{msg}), false
+		}
+	}
+
+	r.pendingBatch = append(r.pendingBatch, msg)
+	r.pendingBytes += size
+	if len(r.pendingBatch) >= r.batchSize {
+		batches = append(batches, r.Cut())
+	}
+
+	return batches, len(r.pendingBatch) > 0
+}
+
+func (r *receiver) Cut() []*ab.BroadcastMessage {
+	batch := r.pendingBatch
+	r.pendingBatch = nil
+	r.pendingBytes = 0
+	return batch
+}
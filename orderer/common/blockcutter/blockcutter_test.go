@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blockcutter
+
+import (
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+func message(size int) *ab.BroadcastMessage {
+	return &ab.BroadcastMessage{Data: make([]byte, size)}
+}
+
+func TestCutOnBatchSize(t *testing.T) {
+	r := NewReceiver(2, 0)
+
+	if batches, pending := r.Ordered(message(1)); len(batches) != 0 || !pending {
+		t.Fatalf("Expected the first message to remain pending")
+	}
+	batches, pending := r.Ordered(message(1))
+	if len(batches) != 1 || len(batches[0]) != 2 || pending {
+		t.Fatalf("Expected a batch of 2 messages to be cut with nothing pending, but got %d batches, pending=%v", len(batches), pending)
+	}
+}
+
+func TestCutOnMaxBytes(t *testing.T) {
+	r := NewReceiver(10, 10)
+
+	r.Ordered(message(6))
+	batches, pending := r.Ordered(message(6))
+	if len(batches) != 1 || len(batches[0]) != 1 || !pending {
+		t.Fatalf("Expected the pending message to be cut ahead of the one which would exceed the limit, but got %d batches, pending=%v", len(batches), pending)
+	}
+	if batch := r.Cut(); len(batch) != 1 {
+		t.Fatalf("Expected the second message to be pending in a new batch")
+	}
+}
+
+func TestOversizedMessageIsolated(t *testing.T) {
+	r := NewReceiver(10, 10)
+
+	r.Ordered(message(1))
+	batches, pending := r.Ordered(message(11))
+	if len(batches) != 2 || len(batches[0]) != 1 || len(batches[1]) != 1 || pending {
+		t.Fatalf("Expected the pending batch and the oversized message to be cut separately, but got %d batches, pending=%v", len(batches), pending)
+	}
+}
+
+func TestCut(t *testing.T) {
+	r := NewReceiver(10, 0)
+
+	if batch := r.Cut(); len(batch) != 0 {
+		t.Fatalf("Expected an empty batch when nothing is pending")
+	}
+	r.Ordered(message(1))
+	r.Ordered(message(1))
+	if batch := r.Cut(); len(batch) != 2 {
+		t.Fatalf("Expected the 2 pending messages to be cut but got %d", len(batch))
+	}
+	if batches, pending := r.Ordered(message(1)); len(batches) != 0 || !pending {
+		t.Fatalf("Expected a new batch to begin after the cut")
+	}
+}
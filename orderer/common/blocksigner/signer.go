@@ -0,0 +1,148 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blocksigner
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+// Signer signs the hash of the header of each block it seals, recording the signature and its certificate in the block's metadata
+type Signer struct {
+	identity []byte // The DER encoded certificate
+	key      *ecdsa.PrivateKey
+}
+
+// ecdsaSignature is the ASN.1 structure in which the signatures are encoded, as in X.509
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// New loads the signing identity from PEM encoded certificate and private key files
+// If both paths are empty it returns nil, whose Seal leaves blocks unsigned
+func New(certificate, privateKey string) (*Signer, error) {
+	if certificate == "" && privateKey == "" {
+		return nil, nil
+	}
+
+	pair, err := tls.LoadX509KeyPair(certificate, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading the signing identity: %s", err)
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Signing identity must have an ECDSA key, got %T", pair.PrivateKey)
+	}
+
+	return &Signer{identity: pair.Certificate[0], key: key}, nil
+}
+
+// Seal signs the block, it is a rawledger.Sealer and must only be called once the ledger has assigned the block its number and previous hash
+func (s *Signer) Seal(block *ab.Block) {
+	if s == nil {
+		return
+	}
+
+	r, sigS, err := ecdsa.Sign(rand.Reader, s.key, block.Hash())
+	if err != nil {
+		panic(fmt.Errorf("Error signing block %d: %s", block.Header.Number, err))
+	}
+	signature, err := asn1.Marshal(ecdsaSignature{R: r, S: sigS})
+	if err != nil {
+		panic("This should never fail and is generally irrecoverable")
+	}
+	block.SetSignature(&ab.BlockSignature{Identity: s.identity, Signature: signature})
+}
+
+// Verifier checks that blocks were signed by one of a set of orderer identities
+type Verifier struct {
+	identities []*x509.Certificate
+}
+
+// NewVerifier loads the orderer identities from PEM encoded certificate files
+// If no paths are given it returns nil, whose Verify accepts every block
+func NewVerifier(certificates []string) (*Verifier, error) {
+	if len(certificates) == 0 {
+		return nil, nil
+	}
+
+	v := &Verifier{}
+	for _, path := range certificates {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the orderer certificate: %s", err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("No certificate found in the orderer certificate file %s", path)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing the orderer certificate %s: %s", path, err)
+		}
+		if _, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("Orderer certificate %s must have an ECDSA key, got %T", path, cert.PublicKey)
+		}
+		v.identities = append(v.identities, cert)
+	}
+	return v, nil
+}
+
+// Verify returns an error unless the block carries a signature over the hash of its header by one of the identities
+func (v *Verifier) Verify(block *ab.Block) error {
+	if v == nil {
+		return nil
+	}
+
+	signature, err := block.Signature()
+	if err != nil {
+		return fmt.Errorf("block %d has a malformed signature: %s", block.Header.Number, err)
+	}
+	if signature == nil {
+		return fmt.Errorf("block %d is not signed", block.Header.Number)
+	}
+
+	var cert *x509.Certificate
+	for _, identity := range v.identities {
+		if bytes.Equal(signature.Identity, identity.Raw) {
+			cert = identity
+			break
+		}
+	}
+	if cert == nil {
+		return fmt.Errorf("block %d was signed by an unknown identity", block.Header.Number)
+	}
+
+	rs := ecdsaSignature{}
+	if _, err := asn1.Unmarshal(signature.Signature, &rs); err != nil {
+		return fmt.Errorf("block %d has a malformed signature: %s", block.Header.Number, err)
+	}
+	if !ecdsa.Verify(cert.PublicKey.(*ecdsa.PublicKey), block.Hash(), rs.R, rs.S) {
+		return fmt.Errorf("signature of block %d does not match its header", block.Header.Number)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blocksigner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/rawledger/fileledger"
+)
+
+var genesisBlock = ab.NewBlock(0, nil, []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("genesis")}}, nil)
+
+// writeIdentity writes a self-signed certificate and its private key to dir, returning the certificate and the paths of both files
+func writeIdentity(t *testing.T, dir, name string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+// verify checks that the block was signed by the holder of cert over the hash of its header
+func verify(block *ab.Block, cert *x509.Certificate) error {
+	return (&Verifier{identities: []*x509.Certificate{cert}}).Verify(block)
+}
+
+func TestSealedBlocksVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	cert, certFile, keyFile := writeIdentity(t, dir, "orderer")
+	signer, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Error loading the signing identity: %s", err)
+	}
+
+	ledgerDir := filepath.Join(dir, "ledger")
+	rl := fileledger.New(ledgerDir, genesisBlock)
+	for i := 0; i < 3; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, signer.Seal)
+	}
+
+	// The signatures are read back from the stored blocks, after the ledger assigned their numbers and previous hashes
+	rl = fileledger.New(ledgerDir, genesisBlock)
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	for number := uint64(1); number < rl.Height(); number++ {
+		block, _ := it.Next()
+		if err := verify(block, cert); err != nil {
+			t.Fatalf("Expected the signature to validate against the configured certificate: %s", err)
+		}
+	}
+}
+
+func TestTamperedHeaderFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	cert, certFile, keyFile := writeIdentity(t, dir, "orderer")
+	signer, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Error loading the signing identity: %s", err)
+	}
+
+	for _, tamper := range []func(header *ab.BlockHeader){
+		func(header *ab.BlockHeader) { header.Number++ },
+		func(header *ab.BlockHeader) { header.PreviousHash = []byte("forged") },
+		func(header *ab.BlockHeader) { header.DataHash = (&ab.BlockData{}).Hash() },
+	} {
+		block := ab.NewBlock(1, genesisBlock.Hash(), []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("data")}}, nil)
+		signer.Seal(block)
+		if err := verify(block, cert); err != nil {
+			t.Fatalf("Expected the untampered block to verify: %s", err)
+		}
+		tamper(block.Header)
+		if verify(block, cert) == nil {
+			t.Fatalf("Expected a tampered header to fail verification: %v", block.Header)
+		}
+	}
+}
+
+func TestOtherIdentityFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	_, certFile, keyFile := writeIdentity(t, dir, "orderer")
+	other, otherCertFile, _ := writeIdentity(t, dir, "other")
+	signer, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Error loading the signing identity: %s", err)
+	}
+
+	block := ab.NewBlock(1, nil, nil, nil)
+	signer.Seal(block)
+	if verify(block, other) == nil {
+		t.Fatalf("Expected the signature not to validate against another certificate")
+	}
+
+	if _, err := New(otherCertFile, keyFile); err == nil {
+		t.Fatalf("Expected a certificate which does not match the private key to be refused")
+	}
+}
+
+func TestUnconfiguredLeavesBlocksUnsigned(t *testing.T) {
+	signer, err := New("", "")
+	if signer != nil || err != nil {
+		t.Fatalf("Expected no signer without a configured identity, got %v, %v", signer, err)
+	}
+
+	block := ab.NewBlock(1, nil, nil, nil)
+	signer.Seal(block)
+	if signature, _ := block.Signature(); signature != nil {
+		t.Fatalf("Expected the block to be left unsigned")
+	}
+}
+
+func TestVerifierLoadsIdentities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	_, certFile, keyFile := writeIdentity(t, dir, "orderer")
+	_, otherCertFile, _ := writeIdentity(t, dir, "other")
+	signer, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Error loading the signing identity: %s", err)
+	}
+
+	verifier, err := NewVerifier([]string{otherCertFile, certFile})
+	if err != nil {
+		t.Fatalf("Error loading the orderer certificates: %s", err)
+	}
+	block := ab.NewBlock(1, nil, nil, nil)
+	if verifier.Verify(block) == nil {
+		t.Fatalf("Expected an unsigned block to be refused")
+	}
+	signer.Seal(block)
+	if err := verifier.Verify(block); err != nil {
+		t.Fatalf("Expected the block to verify against the second identity: %s", err)
+	}
+
+	if _, err := NewVerifier([]string{keyFile}); err == nil {
+		t.Fatalf("Expected a file holding no certificate to be refused")
+	}
+	if verifier, err = NewVerifier(nil); verifier != nil || err != nil || verifier.Verify(block) != nil {
+		t.Fatalf("Expected no verifier without configured identities, got %v, %v", verifier, err)
+	}
+}
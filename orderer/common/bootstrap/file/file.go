@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type bootstrapper struct {
+	path string
+}
+
+// New returns a bootstrap helper reading the genesis block from the file at path, as written by -export-genesis
+func New(path string) bootstrap.Helper {
+	return &bootstrapper{path: path}
+}
+
+// GenesisBlock returns the genesis block read from the file, which must be block 0 carrying a configuration transaction
+func (b *bootstrapper) GenesisBlock() (*ab.Block, error) {
+	data, err := ioutil.ReadFile(b.path)
+	if err != nil {
+		return nil, err
+	}
+	block := &ab.Block{}
+	if err := proto.Unmarshal(data, block); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling the genesis block in %s: %s", b.path, err)
+	}
+	if block.Header == nil || block.Data == nil || block.Header.Number != 0 {
+		return nil, fmt.Errorf("The block in %s is not a genesis block", b.path)
+	}
+	if !bytes.Equal(block.Header.DataHash, block.Data.Hash()) {
+		return nil, fmt.Errorf("The data hash of the genesis block in %s does not match its messages", b.path)
+	}
+	if configtx.BlockConfiguration(block) == nil {
+		return nil, fmt.Errorf("The genesis block in %s carries no configuration transaction", b.path)
+	}
+	return block, nil
+}
@@ -17,8 +17,6 @@ limitations under the License.
 package static
 
 import (
-	"math/rand"
-
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/common/bootstrap"
 	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
@@ -27,17 +25,17 @@ import (
 	"github.com/golang/protobuf/proto"
 )
 
+// TestChainID is the chain ID of the genesis block, it is fixed so that a persistent ledger resumes the same chain on restart
+var TestChainID = []byte("**TEST_CHAINID**")
+
 type bootstrapper struct {
 	chainID []byte
 }
 
 // New returns a new static bootstrap helper
 func New() bootstrap.Helper {
-	b := make([]byte, 16)
-	rand.Read(b)
-
 	return &bootstrapper{
-		chainID: b,
+		chainID: TestChainID,
 	}
 }
 
@@ -79,20 +77,21 @@ func (b *bootstrapper) GenesisBlock() (*ab.Block, error) {
 	// Lock down the default modification policy to prevent any further policy modifications
 	lockdownDefaultModificationPolicy := b.makeConfigurationEntry(configtx.DefaultModificationPolicyID, ab.Configuration_Policy, sigPolicyToPolicy(cauthdsl.RejectAllPolicy), configtx.DefaultModificationPolicyID)
 
-	initialConfigTX := errorlessMarshal(&ab.ConfigurationEnvelope{
+	initialConfigTX, err := configtx.MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{
 		Sequence: 0,
 		ChainID:  b.chainID,
 		Entries: []*ab.ConfigurationEntry{
 			lockdownDefaultModificationPolicy,
 		},
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return &ab.Block{
-		Number:   0,
-		PrevHash: []byte("GENESIS"),
-		Messages: []*ab.BroadcastMessage{
-			&ab.BroadcastMessage{Data: initialConfigTX},
-		},
-	}, nil
+	block := ab.NewBlock(0, []byte("GENESIS"), []*ab.BroadcastMessage{
+		&ab.BroadcastMessage{Data: initialConfigTX},
+	}, nil)
+	block.SetLastConfiguration(0)
+	return block, nil
 
 }
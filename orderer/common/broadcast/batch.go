@@ -0,0 +1,95 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcast
+
+import (
+	"sync"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+// batchStream presents a BroadcastBatch stream as a Broadcast stream of the messages of its batches
+// The messages are preceded by a hello announcing every feature, as no legacy client makes the call
+type batchStream struct {
+	ab.AtomicBroadcast_BroadcastBatchServer
+	greeted  bool
+	received []*ab.BroadcastMessage // The messages of the last batch not yet returned by Recv, only accessed by Recv
+
+	lock    sync.Mutex // Guards sizes and replies, and serializes sends on the stream
+	sizes   []int      // The number of messages of each batch whose replies have not all been sent
+	replies []*ab.BroadcastResponse
+}
+
+func (bs *batchStream) Recv() (*ab.BroadcastMessage, error) {
+	if !bs.greeted {
+		bs.greeted = true
+		return &ab.BroadcastMessage{Hello: ab.AllFeatures.Hello()}, nil
+	}
+	for len(bs.received) == 0 {
+		batch, err := bs.AtomicBroadcast_BroadcastBatchServer.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		// The size is recorded before any message is returned, so that every reply finds its batch
+		bs.lock.Lock()
+		bs.sizes = append(bs.sizes, len(batch.Messages))
+		err = bs.flush()
+		bs.lock.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		bs.received = batch.Messages
+	}
+
+	msg := bs.received[0]
+	bs.received = bs.received[1:]
+	return msg, nil
+}
+
+func (bs *batchStream) Send(resp *ab.BroadcastResponse) error {
+	if resp.Hello != nil {
+		return nil
+	}
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+
+	if len(bs.sizes) == 0 {
+		return bs.AtomicBroadcast_BroadcastBatchServer.Send(&ab.BroadcastBatchResponse{Responses: []*ab.BroadcastResponse{resp}})
+	}
+	bs.replies = append(bs.replies, resp)
+	return bs.flush()
+}
+
+// flush sends the responses of the batches whose messages have all been replied to, the caller must hold the lock
+func (bs *batchStream) flush() error {
+	for len(bs.sizes) > 0 && len(bs.replies) >= bs.sizes[0] {
+		resp := &ab.BroadcastBatchResponse{Responses: bs.replies[:bs.sizes[0]]}
+		bs.replies = bs.replies[bs.sizes[0]:]
+		bs.sizes = bs.sizes[1:]
+		if err := bs.AtomicBroadcast_BroadcastBatchServer.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleBatches passes the messages of each batch received on srv to handle as a Broadcast stream, and sends the replies
+// to the messages of each batch in one response, each message is filtered, limited, and ordered as if it were sent alone
+func HandleBatches(srv ab.AtomicBroadcast_BroadcastBatchServer, handle func(ab.AtomicBroadcast_BroadcastServer) error) error {
+	return handle(&batchStream{AtomicBroadcast_BroadcastBatchServer: srv})
+}
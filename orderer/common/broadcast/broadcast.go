@@ -0,0 +1,370 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/consenter"
+
+	"github.com/op/go-logging"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+var logger = logging.MustGetLogger("orderer/common/broadcast")
+
+// errChainFailed ends a stream whose chain failed with a message not yet replied to
+var errChainFailed = errors.New("the chain failed before the message was ordered")
+
+// Options configures the handling of a Broadcast stream
+type Options struct {
+	Filter         *broadcastfilter.RuleSet // Checks each message before it is enqueued, if nil only empty messages and overlong correlation IDs are rejected
+	Enabled        ab.Features              // The features the stream may negotiate, a stream without a hello uses none
+	MaxOutstanding int                      // How many replies may await sending before no further message is handled, zero to reply to each before the next
+	IdleTimeout    time.Duration            // How long the stream may go without messages or outstanding replies before it is closed, zero to disable
+	Clock          clock.Clock              // The clock of the idle timeout, if nil the real clock
+	IdleClosed     gometrics.Counter        // Counts the streams closed as idle, may be nil
+}
+
+// Handle receives messages from the stream until it ends, enqueueing those accepted by filter on the chain named by their chain ID
+// Each message is replied to before the next is handled, with SUCCESS once it has been enqueued, and its correlation ID
+// The features of enabled which a hello as the first message announces are used for the stream, without one it uses none
+func Handle(srv ab.AtomicBroadcast_BroadcastServer, filter *broadcastfilter.RuleSet, c consenter.Consenter, enabled ab.Features) error {
+	return HandleStream(srv, c, Options{Filter: filter, Enabled: enabled})
+}
+
+// HandleStream receives messages from the stream until it ends, enqueueing those accepted by the filter of opts on the chain named by their chain ID
+// The replies are sent in the order the messages were received, a StreamChain may fill them once the messages are committed
+func HandleStream(srv ab.AtomicBroadcast_BroadcastServer, c consenter.Consenter, opts Options) error {
+	h := newHandler(c, opts)
+	h.client = audit.ClientIdentity(srv.Context())
+	defer h.closeStreams()
+	return h.run(srv)
+}
+
+type handler struct {
+	consenter   consenter.Consenter
+	filter      *broadcastfilter.RuleSet
+	client      string                               // The identity of the client, named in the decisions of the filter
+	streams     map[consenter.Chain]consenter.Stream // Only accessed by the goroutine receiving from the stream
+	replies     chan *replySlot
+	outstanding int32         // The number of replies not yet sent, accessed atomically
+	drained     chan struct{} // Signaled when outstanding falls to zero
+	sentLock    sync.Mutex    // Guards lastSent
+	lastSent    time.Time
+	sendDone    chan struct{}
+	sendErr     error
+	idleTimeout time.Duration
+	clock       clock.Clock
+	idleClosed  gometrics.Counter
+	enabled     ab.Features
+	features    ab.Features // The features negotiated, only accessed by the goroutine receiving from the stream
+	greeted     bool        // Whether a message has been received, after which a hello is refused
+}
+
+// replySlot is filled with the response to a message, exitChan is closed if the reply may never be filled
+type replySlot struct {
+	reply         chan *ab.BroadcastResponse
+	exitChan      <-chan struct{}
+	correlationID []byte // Echoed in the response, set before the slot is queued
+}
+
+func newHandler(c consenter.Consenter, opts Options) *handler {
+	filter := opts.Filter
+	if filter == nil {
+		filter = broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.CorrelationIDRule, broadcastfilter.AcceptRule})
+	}
+	clk := opts.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	idleClosed := opts.IdleClosed
+	if idleClosed == nil {
+		idleClosed = gometrics.NilCounter{}
+	}
+	return &handler{
+		consenter:   c,
+		filter:      filter,
+		streams:     make(map[consenter.Chain]consenter.Stream),
+		replies:     make(chan *replySlot, opts.MaxOutstanding),
+		drained:     make(chan struct{}, 1),
+		sendDone:    make(chan struct{}),
+		idleTimeout: opts.IdleTimeout,
+		clock:       clk,
+		idleClosed:  idleClosed,
+		enabled:     opts.Enabled,
+	}
+}
+
+// streamFor returns the stream of this Broadcast stream on chain, opening it if this is the first message for the chain
+func (h *handler) streamFor(chain consenter.StreamChain) consenter.Stream {
+	st, ok := h.streams[chain]
+	if !ok {
+		st = chain.NewStream()
+		h.streams[chain] = st
+	}
+	return st
+}
+
+// closeStreams releases the streams of the chains, leaving any queued messages to be ordered
+func (h *handler) closeStreams() {
+	for _, st := range h.streams {
+		st.Close()
+	}
+}
+
+// sendReplies writes the replies to the client in the order the messages were received
+func (h *handler) sendReplies(srv ab.AtomicBroadcast_BroadcastServer) {
+	defer close(h.sendDone)
+	for slot := range h.replies {
+		var resp *ab.BroadcastResponse
+		failed := false
+		select {
+		case resp = <-slot.reply:
+		default:
+			select {
+			case resp = <-slot.reply:
+			case <-slot.exitChan:
+				// The message will never be replied to, the client is told so rather than seeing the stream end cleanly
+				resp = ab.ReasonUnavailable.BroadcastResponse("chain can no longer order messages")
+				failed = true
+			}
+		}
+
+		// The reply is accounted for before it is sent, so that the client never observes a stream whose replies are still outstanding
+		h.sentLock.Lock()
+		h.lastSent = h.clock.Now()
+		h.sentLock.Unlock()
+		if atomic.AddInt32(&h.outstanding, -1) == 0 {
+			select {
+			case h.drained <- struct{}{}:
+			default:
+			}
+		}
+
+		resp.CorrelationID = slot.correlationID
+		if err := srv.Send(resp); err != nil {
+			h.sendErr = err
+			return
+		}
+		if failed {
+			h.sendErr = errChainFailed
+			return
+		}
+	}
+}
+
+func (h *handler) lastSendTime() time.Time {
+	h.sentLock.Lock()
+	defer h.sentLock.Unlock()
+	return h.lastSent
+}
+
+// receive passes the messages of the stream to msgs until the stream ends or quit is closed
+func (h *handler) receive(srv ab.AtomicBroadcast_BroadcastServer, msgs chan<- *ab.BroadcastMessage, recvErr chan<- error, quit <-chan struct{}) {
+	for {
+		msg, err := srv.Recv()
+		if err != nil {
+			recvErr <- err
+			return
+		}
+		select {
+		case msgs <- msg:
+		case <-quit:
+			return
+		}
+	}
+}
+
+func (h *handler) run(srv ab.AtomicBroadcast_BroadcastServer) error {
+	go h.sendReplies(srv)
+
+	msgs := make(chan *ab.BroadcastMessage)
+	recvErr := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+	go h.receive(srv, msgs, recvErr, quit)
+
+	var timer clock.Timer
+	armIdle := func() {
+		if h.idleTimeout == 0 {
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = h.clock.NewTimer(h.idleTimeout)
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	armIdle()
+
+	// Set when the idle timer expired with replies outstanding, the stream is idle only once they have been sent
+	awaitingReplies := false
+
+	for {
+		var idle <-chan time.Time
+		if timer != nil {
+			idle = timer.C()
+		}
+
+		select {
+		case msg := <-msgs:
+			armIdle()
+			if !h.handleMessage(msg) {
+				return h.sendErr
+			}
+		case err := <-recvErr:
+			// Wait for any outstanding replies to be sent before the stream is torn down
+			close(h.replies)
+			<-h.sendDone
+			if h.sendErr != nil {
+				return h.sendErr
+			}
+			return err
+		case <-idle:
+			timer = nil
+			if atomic.LoadInt32(&h.outstanding) > 0 {
+				awaitingReplies = true
+				continue
+			}
+			// A reply may have been sent since the timer was armed, the stream is idle only a full timeout after it
+			if remaining := h.idleTimeout - h.clock.Now().Sub(h.lastSendTime()); remaining > 0 {
+				timer = h.clock.NewTimer(remaining)
+				continue
+			}
+			logger.Debugf("Closing broadcast stream which has been idle for %v", h.idleTimeout)
+			h.idleClosed.Inc(1)
+			slot := &replySlot{reply: make(chan *ab.BroadcastResponse, 1)}
+			slot.reply <- ab.ReasonIdle.BroadcastResponse("stream idle for %v", h.idleTimeout)
+			h.replies <- slot
+			close(h.replies)
+			<-h.sendDone
+			return h.sendErr
+		case <-h.drained:
+			if awaitingReplies {
+				awaitingReplies = false
+				armIdle()
+			}
+		case <-h.sendDone:
+			return h.sendErr
+		}
+	}
+}
+
+// reserve queues the reply slot of a message, returning false once replies can no longer be sent
+func (h *handler) reserve(slot *replySlot) bool {
+	atomic.AddInt32(&h.outstanding, 1)
+	select {
+	case h.replies <- slot:
+		return true
+	case <-h.sendDone:
+		return false
+	}
+}
+
+// handleHello negotiates the features of the stream from the hello of its first message, a later hello is refused
+func (h *handler) handleHello(msg *ab.BroadcastMessage, first bool) bool {
+	resp := ab.ReasonMalformed.BroadcastResponse("a hello may only be the first message of a stream")
+	if first {
+		h.features = h.enabled.Negotiate(msg.Hello)
+		logger.Debugf("Negotiated features %v", h.features.Hello().Features)
+		resp = &ab.BroadcastResponse{Status: ab.Status_SUCCESS, Hello: h.features.Hello()}
+	}
+
+	slot := &replySlot{reply: make(chan *ab.BroadcastResponse, 1), correlationID: msg.CorrelationID}
+	slot.reply <- resp
+	return h.reserve(slot)
+}
+
+// handleMessage routes a message to its chain and reserves its reply, returning false once replies can no longer be sent
+func (h *handler) handleMessage(msg *ab.BroadcastMessage) bool {
+	first := !h.greeted
+	h.greeted = true
+	if msg.Hello != nil {
+		return h.handleHello(msg, first)
+	}
+
+	slot := &replySlot{reply: make(chan *ab.BroadcastResponse, 1), correlationID: msg.CorrelationID}
+	resp, chain, st := h.route(msg)
+	if resp != nil {
+		slot.reply <- resp
+	} else {
+		slot.exitChan = chain.Errored()
+	}
+	// The slot is queued before the message is enqueued on a stream, so that the reply keeps its place behind those sent before it
+	if !h.reserve(slot) {
+		return false
+	}
+	if st != nil {
+		st.Enqueue(msg, slot.reply)
+	}
+	return true
+}
+
+// route filters a message and enqueues it on its chain, returning the reply, or the stream of the StreamChain it is to be enqueued on
+func (h *handler) route(msg *ab.BroadcastMessage) (*ab.BroadcastResponse, consenter.Chain, consenter.Stream) {
+	if !h.features.Has(ab.Feature_CHAIN_ROUTING) && len(msg.ChainID) > 0 {
+		// A legacy client knows of a single chain, and may not name another without negotiating routing
+		logger.Debugf("Rejecting message for chain %x from a client which did not negotiate chain routing", msg.ChainID)
+		return ab.ReasonMalformed.BroadcastResponse("chain %x may only be named once CHAIN_ROUTING is negotiated", msg.ChainID), nil, nil
+	}
+
+	chain, ok := h.consenter.Chain(msg.ChainID)
+	if !ok {
+		logger.Debugf("Rejecting message for unknown chain %x", msg.ChainID)
+		return ab.ReasonUnknownChain.BroadcastResponse("chain %x does not exist", msg.ChainID), nil, nil
+	}
+
+	select {
+	case <-chain.Errored():
+		return ab.ReasonUnavailable.BroadcastResponse("chain can no longer order messages"), chain, nil
+	default:
+	}
+
+	action, rule := h.filter.ApplyFrom(msg, h.client)
+	switch action {
+	case broadcastfilter.Accept, broadcastfilter.Reconfigure:
+		switch c := chain.(type) {
+		case consenter.StreamChain:
+			return nil, chain, h.streamFor(c)
+		case consenter.StatusChain:
+			return c.EnqueueReply(msg), chain, nil
+		}
+		if !chain.Enqueue(msg) {
+			return ab.ReasonUnavailable.BroadcastResponse("chain did not accept the message"), chain, nil
+		}
+		return &ab.BroadcastResponse{Status: ab.Status_SUCCESS}, chain, nil
+	case broadcastfilter.Forward, broadcastfilter.Reject:
+		logger.Debugf("Rejecting message because it was not accepted by a filter")
+		return broadcastfilter.RejectReply(rule, msg), chain, nil
+	default:
+		// TODO add support for other cases, unreachable for now
+		logger.Fatalf("NOT IMPLEMENTED YET")
+		return ab.ReasonUnavailable.BroadcastResponse("message cannot be handled"), chain, nil
+	}
+}
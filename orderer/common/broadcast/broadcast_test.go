@@ -0,0 +1,401 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcast
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/consenter"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// mockB announces every feature by a hello before the messages of recvChan, and drops the reply, unless hello is cleared
+type mockB struct {
+	grpc.ServerStream
+	recvChan chan *ab.BroadcastMessage
+	sendChan chan *ab.BroadcastResponse
+	hello    *ab.Hello
+	greeted  bool
+	ctx      context.Context
+}
+
+func newMockB() *mockB {
+	return &mockB{
+		recvChan: make(chan *ab.BroadcastMessage),
+		sendChan: make(chan *ab.BroadcastResponse),
+		hello:    ab.AllFeatures.Hello(),
+	}
+}
+
+func (m *mockB) Send(br *ab.BroadcastResponse) error {
+	if m.hello != nil && br.Hello != nil {
+		return nil
+	}
+	m.sendChan <- br
+	return nil
+}
+
+// Context returns the context of the stream, which identifies no client unless ctx is set
+func (m *mockB) Context() context.Context {
+	if m.ctx != nil {
+		return m.ctx
+	}
+	return context.Background()
+}
+
+func (m *mockB) Recv() (*ab.BroadcastMessage, error) {
+	if hello := m.hello; hello != nil && !m.greeted {
+		m.greeted = true
+		return &ab.BroadcastMessage{Hello: hello}, nil
+	}
+	msg, ok := <-m.recvChan
+	if !ok {
+		return msg, fmt.Errorf("Channel closed")
+	}
+	return msg, nil
+}
+
+type mockChain struct {
+	accept  bool
+	queue   []*ab.BroadcastMessage
+	errored chan struct{}
+}
+
+func newMockChain() *mockChain {
+	return &mockChain{accept: true, errored: make(chan struct{})}
+}
+
+func (mc *mockChain) Enqueue(msg *ab.BroadcastMessage) bool {
+	if !mc.accept {
+		return false
+	}
+	mc.queue = append(mc.queue, msg)
+	return true
+}
+
+func (mc *mockChain) Start() {}
+
+func (mc *mockChain) Halt() {}
+
+func (mc *mockChain) Errored() <-chan struct{} {
+	return mc.errored
+}
+
+// mockStatusChain replies to every message with status
+type mockStatusChain struct {
+	*mockChain
+	status ab.Status
+}
+
+func (mc *mockStatusChain) EnqueueReply(msg *ab.BroadcastMessage) *ab.BroadcastResponse {
+	return &ab.BroadcastResponse{Status: mc.status, Info: "refused by the chain"}
+}
+
+// mockStreamChain passes the messages of its streams to enqueued without ever replying to them
+type mockStreamChain struct {
+	*mockChain
+	enqueued chan *ab.BroadcastMessage
+}
+
+func (mc *mockStreamChain) NewStream() consenter.Stream {
+	return mockStream(mc.enqueued)
+}
+
+type mockStream chan *ab.BroadcastMessage
+
+func (ms mockStream) Enqueue(msg *ab.BroadcastMessage, reply chan *ab.BroadcastResponse) {
+	ms <- msg
+}
+
+func (ms mockStream) Close() {}
+
+type mockConsenter map[string]consenter.Chain
+
+func (mc mockConsenter) Chain(chainID []byte) (consenter.Chain, bool) {
+	chain, ok := mc[string(chainID)]
+	return chain, ok
+}
+
+var defaultFilter = broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.AcceptRule})
+
+func startHandler(c consenter.Consenter) *mockB {
+	m := newMockB()
+	go Handle(m, defaultFilter, c, ab.AllFeatures)
+	return m
+}
+
+func expectStatus(t *testing.T, m *mockB, msg *ab.BroadcastMessage, status ab.Status, info string) {
+	m.recvChan <- msg
+	if reply := <-m.sendChan; reply.Status != status || reply.Info != info {
+		t.Fatalf("Expected %v (%q) but got %v (%q)", status, info, reply.Status, reply.Info)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	chain := newMockChain()
+	m := startHandler(mockConsenter{"": chain})
+	defer close(m.recvChan)
+
+	expectStatus(t, m, &ab.BroadcastMessage{Data: []byte("Some bytes")}, ab.Status_SUCCESS, "")
+	if len(chain.queue) != 1 {
+		t.Fatalf("Expected the message to be enqueued on the chain")
+	}
+}
+
+func TestCorrelationIDEchoed(t *testing.T) {
+	m := startHandler(mockConsenter{"": newMockChain()})
+	defer close(m.recvChan)
+
+	for _, msg := range []*ab.BroadcastMessage{{Data: []byte("Some bytes"), CorrelationID: []byte("accepted")}, {CorrelationID: []byte("rejected")}} {
+		m.recvChan <- msg
+		if reply := <-m.sendChan; string(reply.CorrelationID) != string(msg.CorrelationID) {
+			t.Fatalf("Expected the reply to echo %q but got %q", msg.CorrelationID, reply.CorrelationID)
+		}
+	}
+}
+
+func startNegotiatingHandler(c consenter.Consenter, enabled ab.Features) *mockB {
+	m := newMockB()
+	m.hello = nil
+	go Handle(m, defaultFilter, c, enabled)
+	return m
+}
+
+func expectHello(t *testing.T, m *mockB, hello *ab.Hello, features ...ab.Feature) {
+	m.recvChan <- &ab.BroadcastMessage{Hello: hello}
+	reply := <-m.sendChan
+	if reply.Status != ab.Status_SUCCESS || reply.Hello == nil || !reflect.DeepEqual(reply.Hello.Features, features) {
+		t.Fatalf("Expected the features %v to be selected but got %v", features, reply)
+	}
+}
+
+func TestLegacyClient(t *testing.T) {
+	chain := newMockChain()
+	m := startNegotiatingHandler(mockConsenter{"": chain}, ab.AllFeatures)
+	defer close(m.recvChan)
+
+	// Without a hello a message naming a chain is refused, one naming none goes to the default chain
+	expectStatus(t, m, &ab.BroadcastMessage{ChainID: []byte("other"), Data: []byte("Some bytes")}, ab.Status_BAD_REQUEST, "chain 6f74686572 may only be named once CHAIN_ROUTING is negotiated")
+	if len(chain.queue) != 0 {
+		t.Fatalf("Expected the message naming a chain not to be enqueued")
+	}
+	expectStatus(t, m, &ab.BroadcastMessage{Data: []byte("Some bytes")}, ab.Status_SUCCESS, "")
+	if len(chain.queue) != 1 {
+		t.Fatalf("Expected the message to be enqueued on the default chain")
+	}
+	expectStatus(t, m, &ab.BroadcastMessage{Hello: ab.AllFeatures.Hello()}, ab.Status_BAD_REQUEST, "a hello may only be the first message of a stream")
+}
+
+func TestHelloNegotiation(t *testing.T) {
+	m := startNegotiatingHandler(mockConsenter{"": newMockChain()}, ab.AllFeatures)
+	defer close(m.recvChan)
+
+	expectHello(t, m, &ab.Hello{Features: []ab.Feature{ab.Feature_CHAIN_ROUTING, ab.Feature(17)}}, ab.Feature_CHAIN_ROUTING)
+	expectStatus(t, m, &ab.BroadcastMessage{ChainID: []byte("other"), Data: []byte("Some bytes")}, ab.Status_NOT_FOUND, "chain 6f74686572 does not exist")
+}
+
+func TestHelloFeatureDisabled(t *testing.T) {
+	chain := newMockChain()
+	m := startNegotiatingHandler(mockConsenter{"": chain}, ab.NewFeatures(ab.Feature_DELIVER_ACKS))
+	defer close(m.recvChan)
+
+	expectHello(t, m, ab.AllFeatures.Hello(), ab.Feature_DELIVER_ACKS)
+	expectStatus(t, m, &ab.BroadcastMessage{ChainID: []byte("other"), Data: []byte("Some bytes")}, ab.Status_BAD_REQUEST, "chain 6f74686572 may only be named once CHAIN_ROUTING is negotiated")
+	expectStatus(t, m, &ab.BroadcastMessage{Data: []byte("Some bytes")}, ab.Status_SUCCESS, "")
+	if len(chain.queue) != 1 {
+		t.Fatalf("Expected the message to be enqueued on the default chain")
+	}
+}
+
+func TestFilterReject(t *testing.T) {
+	chain := newMockChain()
+	m := startHandler(mockConsenter{"": chain})
+	defer close(m.recvChan)
+
+	expectStatus(t, m, &ab.BroadcastMessage{}, ab.Status_BAD_REQUEST, "message is empty")
+	if len(chain.queue) != 0 {
+		t.Fatalf("Expected the rejected message not to be enqueued")
+	}
+}
+
+// clientRule passes the clients of the messages it is applied to on to clients
+type clientRule chan string
+
+func (cr clientRule) Apply(message *ab.BroadcastMessage) broadcastfilter.Action {
+	return cr.ApplyFrom(message, "")
+}
+
+func (cr clientRule) ApplyFrom(message *ab.BroadcastMessage, client string) broadcastfilter.Action {
+	cr <- client
+	return broadcastfilter.Accept
+}
+
+func TestFilterClient(t *testing.T) {
+	clients := make(clientRule, 1)
+	m := newMockB()
+	m.ctx = peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 5000}})
+	go Handle(m, broadcastfilter.NewRuleSet([]broadcastfilter.Rule{clients}), mockConsenter{"": newMockChain()}, ab.AllFeatures)
+	defer close(m.recvChan)
+
+	expectStatus(t, m, &ab.BroadcastMessage{Data: []byte("Some bytes")}, ab.Status_SUCCESS, "")
+	if client := <-clients; client != "10.0.0.7" {
+		t.Fatalf("Expected the filter to be applied for the peer of the stream, got %q", client)
+	}
+}
+
+func TestUnknownChain(t *testing.T) {
+	m := startHandler(mockConsenter{"": newMockChain()})
+	defer close(m.recvChan)
+
+	expectStatus(t, m, &ab.BroadcastMessage{ChainID: []byte("other"), Data: []byte("Some bytes")}, ab.Status_NOT_FOUND, "chain 6f74686572 does not exist")
+}
+
+func TestEnqueueFailure(t *testing.T) {
+	chain := newMockChain()
+	chain.accept = false
+	m := startHandler(mockConsenter{"": chain})
+	defer close(m.recvChan)
+
+	expectStatus(t, m, &ab.BroadcastMessage{Data: []byte("Some bytes")}, ab.Status_SERVICE_UNAVAILABLE, "chain did not accept the message")
+}
+
+func TestErroredChain(t *testing.T) {
+	chain := newMockChain()
+	close(chain.errored)
+	m := startHandler(mockConsenter{"": chain})
+	defer close(m.recvChan)
+
+	expectStatus(t, m, &ab.BroadcastMessage{Data: []byte("Some bytes")}, ab.Status_SERVICE_UNAVAILABLE, "chain can no longer order messages")
+	if len(chain.queue) != 0 {
+		t.Fatalf("Expected no message to be enqueued on an errored chain")
+	}
+}
+
+func TestChainFailsWithPendingReply(t *testing.T) {
+	chain := &mockStreamChain{mockChain: newMockChain(), enqueued: make(chan *ab.BroadcastMessage, 1)}
+	m := newMockB()
+	errChan := make(chan error)
+	go func() {
+		errChan <- Handle(m, defaultFilter, mockConsenter{"": chain}, ab.AllFeatures)
+	}()
+
+	m.recvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+	<-chain.enqueued
+	close(chain.errored)
+	if reply := <-m.sendChan; reply.Status != ab.Status_SERVICE_UNAVAILABLE {
+		t.Fatalf("Expected the pending message to be answered with SERVICE_UNAVAILABLE but got %v", reply)
+	}
+	if err := <-errChan; err == nil {
+		t.Fatalf("Expected the stream of a failed chain to end with an error")
+	}
+}
+
+func TestEnqueueReply(t *testing.T) {
+	chain := &mockStatusChain{mockChain: newMockChain(), status: ab.Status_BAD_REQUEST}
+	m := startHandler(mockConsenter{"": chain})
+	defer close(m.recvChan)
+
+	expectStatus(t, m, &ab.BroadcastMessage{Data: []byte("Some bytes")}, ab.Status_BAD_REQUEST, "refused by the chain")
+}
+
+func TestSubmit(t *testing.T) {
+	chain := newMockChain()
+	handle := func(srv ab.AtomicBroadcast_BroadcastServer) error {
+		return Handle(srv, defaultFilter, mockConsenter{"": chain}, ab.AllFeatures)
+	}
+
+	reply, err := Submit(context.Background(), &ab.BroadcastMessage{Data: []byte("Some bytes")}, handle)
+	if err != nil || reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the submitted message to be enqueued, got %v (%v)", reply, err)
+	}
+	if len(chain.queue) != 1 {
+		t.Fatalf("Expected the message to be enqueued on the chain")
+	}
+
+	reply, err = Submit(context.Background(), &ab.BroadcastMessage{}, handle)
+	if err != nil || reply.Status != ab.Status_BAD_REQUEST {
+		t.Fatalf("Expected the submitted message to be rejected by the filter, got %v (%v)", reply, err)
+	}
+}
+
+type mockBatch struct {
+	grpc.ServerStream
+	recvChan chan *ab.BroadcastBatch
+	sendChan chan *ab.BroadcastBatchResponse
+}
+
+// Context returns a context which identifies no client
+func (m *mockBatch) Context() context.Context {
+	return context.Background()
+}
+
+func (m *mockBatch) Send(resp *ab.BroadcastBatchResponse) error {
+	m.sendChan <- resp
+	return nil
+}
+
+func (m *mockBatch) Recv() (*ab.BroadcastBatch, error) {
+	batch, ok := <-m.recvChan
+	if !ok {
+		return batch, fmt.Errorf("Channel closed")
+	}
+	return batch, nil
+}
+
+func TestHandleBatches(t *testing.T) {
+	chain := newMockChain()
+	m := &mockBatch{recvChan: make(chan *ab.BroadcastBatch), sendChan: make(chan *ab.BroadcastBatchResponse)}
+	go HandleBatches(m, func(srv ab.AtomicBroadcast_BroadcastServer) error {
+		return Handle(srv, defaultFilter, mockConsenter{"": chain}, ab.AllFeatures)
+	})
+	defer close(m.recvChan)
+
+	// A rejected message in the middle of a batch does not affect the others
+	m.recvChan <- &ab.BroadcastBatch{Messages: []*ab.BroadcastMessage{
+		&ab.BroadcastMessage{Data: []byte("first")},
+		&ab.BroadcastMessage{},
+		&ab.BroadcastMessage{ChainID: []byte("other"), Data: []byte("unknown")},
+		&ab.BroadcastMessage{Data: []byte("last")},
+	}}
+	resp := <-m.sendChan
+	expected := []ab.Status{ab.Status_SUCCESS, ab.Status_BAD_REQUEST, ab.Status_NOT_FOUND, ab.Status_SUCCESS}
+	if len(resp.Responses) != len(expected) {
+		t.Fatalf("Expected a reply for each of the %d messages but got %d", len(expected), len(resp.Responses))
+	}
+	for i, status := range expected {
+		if resp.Responses[i].Status != status {
+			t.Fatalf("Expected %v for message %d but got %v", status, i, resp.Responses[i])
+		}
+	}
+	if len(chain.queue) != 2 || string(chain.queue[0].Data) != "first" || string(chain.queue[1].Data) != "last" {
+		t.Fatalf("Expected the accepted messages to be enqueued in order, got %v", chain.queue)
+	}
+
+	// An empty batch is answered with an empty response
+	m.recvChan <- &ab.BroadcastBatch{}
+	if resp = <-m.sendChan; len(resp.Responses) != 0 {
+		t.Fatalf("Expected an empty response to an empty batch, got %v", resp)
+	}
+}
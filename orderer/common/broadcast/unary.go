@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcast
+
+import (
+	"errors"
+	"io"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// unaryStream is a Broadcast stream carrying the single message of a unary call, after which it ends
+// The message is preceded by a hello announcing every feature, as no legacy client makes the call
+type unaryStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	msg      *ab.BroadcastMessage
+	greeted  bool
+	received bool
+	reply    chan *ab.BroadcastResponse
+}
+
+func (us *unaryStream) Context() context.Context {
+	return us.ctx
+}
+
+func (us *unaryStream) Recv() (*ab.BroadcastMessage, error) {
+	if !us.greeted {
+		us.greeted = true
+		return &ab.BroadcastMessage{Hello: ab.AllFeatures.Hello()}, nil
+	}
+	if us.received {
+		return nil, io.EOF
+	}
+	us.received = true
+	return us.msg, nil
+}
+
+func (us *unaryStream) Send(resp *ab.BroadcastResponse) error {
+	if resp.Hello != nil {
+		return nil
+	}
+	select {
+	case us.reply <- resp:
+		return nil
+	default:
+		return errors.New("a unary call has a single reply")
+	}
+}
+
+// Submit passes msg to handle as the only message of a Broadcast stream and returns the reply sent to it
+// If ctx is done before the reply, the error reflects the context, but a message already accepted is still ordered
+func Submit(ctx context.Context, msg *ab.BroadcastMessage, handle func(ab.AtomicBroadcast_BroadcastServer) error) (*ab.BroadcastResponse, error) {
+	us := &unaryStream{ctx: ctx, msg: msg, reply: make(chan *ab.BroadcastResponse, 1)}
+	done := make(chan error, 1)
+	go func() {
+		done <- handle(us)
+	}()
+
+	select {
+	case resp := <-us.reply:
+		return resp, nil
+	case err := <-done:
+		// The reply is sent before the handler returns, but may not have been selected
+		select {
+		case resp := <-us.reply:
+			return resp, nil
+		default:
+		}
+		if err == nil || err == io.EOF {
+			err = errors.New("the message was not replied to")
+		}
+		return nil, grpc.Errorf(codes.Unavailable, "%s", err)
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, grpc.Errorf(codes.DeadlineExceeded, "deadline expired before the message was replied to")
+		}
+		return nil, grpc.Errorf(codes.Canceled, "call canceled before the message was replied to")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcastfilter
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+
+	"github.com/op/go-logging"
+)
+
+// logCapture collects the lines logged while it is installed as the logging backend
+type logCapture struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (lc *logCapture) Write(p []byte) (int, error) {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.buf.Write(p)
+}
+
+func (lc *logCapture) String() string {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.buf.String()
+}
+
+// newCapturedLog returns a DecisionLog writing every decision into the returned logCapture
+func newCapturedLog() (*audit.DecisionLog, *logCapture) {
+	lc := &logCapture{}
+	dl := audit.NewDecisionLog(0, 0, clock.Real{})
+	dl.SetBackend(logging.AddModuleLevel(logging.NewLogBackend(lc, "", 0)))
+	return dl, lc
+}
+
+// assertDenial checks that a single denial was logged with each of the given fields
+func assertDenial(t *testing.T, lc *logCapture, point, policyID string, chainID []byte, client string, signer []byte) {
+	logged := lc.String()
+	if count := strings.Count(logged, "Policy denied: "); count != 1 {
+		t.Fatalf("Expected one denial at %s to be logged, got %d in %q", point, count, logged)
+	}
+	fields := []string{
+		fmt.Sprintf(`"point":"%s"`, point),
+		fmt.Sprintf(`"policy":"%s"`, policyID),
+		fmt.Sprintf(`"chainID":"%s"`, hex.EncodeToString(chainID)),
+		fmt.Sprintf(`"client":"%s"`, client),
+		`"allowed":false`,
+		`"detail":"`,
+	}
+	if signer != nil {
+		fields = append(fields, fmt.Sprintf(`"signers":["%s"]`, audit.Fingerprints([][]byte{signer})[0]))
+	}
+	for _, field := range fields {
+		if !strings.Contains(logged, field) {
+			t.Fatalf("Expected the denial at %s to contain %s, got %q", point, field, logged)
+		}
+	}
+}
+
+func TestPolicyRuleAudit(t *testing.T) {
+	dl, lc := newCapturedLog()
+	managers := map[string]policies.Manager{"writers": newWritersManager()}
+	resolve := func(chainID []byte) (policies.Manager, bool) {
+		manager, ok := managers[string(chainID)]
+		return manager, ok
+	}
+	rs := NewRuleSet([]Rule{NewChainPolicyRule(resolve, writersPolicyID, dl), AcceptRule})
+
+	msg := signedMessage([]byte("other"), []byte("otherpayload"))
+	msg.ChainID = []byte("writers")
+	if result, _ := rs.ApplyFrom(msg, "10.0.0.7"); result != Reject {
+		t.Fatalf("Should have rejected the message signed by another")
+	}
+	assertDenial(t, lc, audit.PointBroadcast, writersPolicyID, []byte("writers"), "10.0.0.7", []byte("other"))
+}
+
+func TestConfigRuleAudit(t *testing.T) {
+	admin := newECDSAIdentity(t)
+	other := newECDSAIdentity(t)
+
+	pm := policies.NewManagerImpl(cauthdsl.ECDSAHelper{})
+	handlers := make(map[ab.Configuration_ConfigurationType]configtx.Handler)
+	for ctype := range ab.Configuration_ConfigurationType_name {
+		handlers[ab.Configuration_ConfigurationType(ctype)] = configtx.NewBytesHandler()
+	}
+	handlers[ab.Configuration_Policy] = pm
+	cm, err := configtx.NewConfigurationManager(adminEnvelope(0, admin, nil), pm, handlers)
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	dl, lc := newCapturedLog()
+	rs := NewRuleSet([]Rule{NewConfigRule(cm, dl), AcceptRule})
+
+	msg := configMessage(adminEnvelope(1, admin, func(data []byte) []*ab.SignedData {
+		return []*ab.SignedData{other.sign(t, data)}
+	}))
+	if result, _ := rs.ApplyFrom(msg, "10.0.0.7"); result != Reject {
+		t.Fatalf("Should have rejected the configuration signed by another")
+	}
+	assertDenial(t, lc, audit.PointConfiguration, "Admins", configChain, "10.0.0.7", nil)
+}
+
+func TestChainCreationRuleAudit(t *testing.T) {
+	dl, lc := newCapturedLog()
+	creationRule := NewChainCreationRule(newWritersManager(), writersPolicyID, systemChain, newChains(t), 0, dl)
+	rs := NewRuleSet([]Rule{creationRule, AcceptRule})
+
+	msg := creationMessage(genesisConfig([]byte("newchain")), []byte("other"), false)
+	if result, _ := rs.ApplyFrom(msg, "10.0.0.7"); result != Reject {
+		t.Fatalf("Should have rejected the creation signed by another")
+	}
+	// Building the reply must not record the decision a second time
+	RejectReply(creationRule, msg)
+	assertDenial(t, lc, audit.PointChainCreation, writersPolicyID, []byte("newchain"), "10.0.0.7", []byte("other"))
+}
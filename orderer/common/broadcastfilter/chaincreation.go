@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcastfilter
+
+import (
+	"bytes"
+	"fmt"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type chainCreationRule struct {
+	manager       policies.Manager
+	policyID      string
+	systemChainID []byte
+	chains        rawledger.Factory
+	maxChains     int
+	decisions     *audit.DecisionLog
+}
+
+// NewChainCreationRule creates a Rule which replies Reconfigure to the chain creation transactions sent to the system chain whose
+// signatures satisfy the named policy and whose genesis configuration is of a chain which is not among chains, so long as fewer than
+// maxChains chains exist or maxChains is zero, rejects every other chain creation transaction, and forwards the rest
+// As with NewPolicyRule the signatures are evaluated over the bytes of the Transaction Type, and the policy is looked up for each message,
+// each decision of the policy is recorded to decisions
+func NewChainCreationRule(manager policies.Manager, policyID string, systemChainID []byte, chains rawledger.Factory, maxChains int, decisions *audit.DecisionLog) Rule {
+	return &chainCreationRule{
+		manager:       manager,
+		policyID:      policyID,
+		systemChainID: systemChainID,
+		chains:        chains,
+		maxChains:     maxChains,
+		decisions:     decisions,
+	}
+}
+
+// check returns whether a message is a chain creation transaction, and if so the reason and error for which it may not be ordered,
+// recording the decision of the policy, naming client, if record is set
+func (cr *chainCreationRule) check(message *ab.BroadcastMessage, client string, record bool) (bool, ab.Reason, error) {
+	tx := &ab.Transaction{}
+	if err := proto.Unmarshal(message.Data, tx); err != nil {
+		return false, 0, nil
+	}
+	t, ok := tx.Type.(*ab.Transaction_CreateChain)
+	if !ok {
+		return false, 0, nil
+	}
+
+	if len(message.ChainID) != 0 && !bytes.Equal(message.ChainID, cr.systemChainID) {
+		return true, ab.ReasonMalformed, fmt.Errorf("Chains may only be created through the system chain %x", cr.systemChainID)
+	}
+	configTx := &ab.ConfigurationEnvelope{}
+	if err := proto.Unmarshal(t.CreateChain, configTx); err != nil {
+		return true, ab.ReasonMalformed, fmt.Errorf("Genesis configuration envelope is malformed: %s", err)
+	}
+	if err := configtx.CheckGenesisConfiguration(configTx); err != nil {
+		return true, ab.ReasonMalformed, err
+	}
+	if _, ok := cr.chains.Get(configTx.ChainID); ok {
+		return true, ab.ReasonMalformed, fmt.Errorf("Chain %x already exists", configTx.ChainID)
+	}
+
+	// An unknown policy is the default policy, which rejects or accepts everything as the manager is configured
+	policy, _ := cr.manager.GetPolicy(cr.policyID)
+	err := policy.Evaluate(t.CreateChain, tx.Signatures)
+	if record {
+		cr.decisions.Record(audit.NewDecision(audit.PointChainCreation, cr.policyID, configTx.ChainID, client, cauthdsl.Signers(tx.Signatures), err))
+	}
+	if err != nil {
+		return true, ab.ReasonForbidden, fmt.Errorf("Chain creation is not authorized by policy %s: %s", cr.policyID, err)
+	}
+	// The limit is only revealed to those who may create chains
+	if cr.maxChains > 0 && len(cr.chains.ChainIDs()) >= cr.maxChains {
+		return true, ab.ReasonForbidden, fmt.Errorf("The maximum of %d chains already exist", cr.maxChains)
+	}
+	return true, 0, nil
+}
+
+func (cr *chainCreationRule) Apply(message *ab.BroadcastMessage) Action {
+	return cr.ApplyFrom(message, "")
+}
+
+func (cr *chainCreationRule) ApplyFrom(message *ab.BroadcastMessage, client string) Action {
+	isCreation, _, err := cr.check(message, client, true)
+	switch {
+	case err != nil:
+		logger.Debugf("Rejecting chain creation transaction: %s", err)
+		return Reject
+	case isCreation:
+		return Reconfigure
+	default:
+		return Forward
+	}
+}
+
+// RejectReply is FORBIDDEN if the signatures do not satisfy the policy or too many chains exist, and BAD_REQUEST for any other reason the chain may not be created
+func (cr *chainCreationRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	_, reason, err := cr.check(message, "", false)
+	return reason.BroadcastResponse("invalid chain creation transaction: %v", err)
+}
+
+// ChainCreationRejectRule rejects every chain creation transaction, for orderers which do not create chains
+var ChainCreationRejectRule = Rule(chainCreationRejectRule{})
+
+type chainCreationRejectRule struct{}
+
+func (a chainCreationRejectRule) Apply(message *ab.BroadcastMessage) Action {
+	tx := &ab.Transaction{}
+	if err := proto.Unmarshal(message.Data, tx); err != nil {
+		return Forward
+	}
+	if _, ok := tx.Type.(*ab.Transaction_CreateChain); ok {
+		return Reject
+	}
+	return Forward
+}
+
+func (a chainCreationRejectRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	return ab.ReasonMalformed.BroadcastResponse("chains may not be created through this orderer")
+}
@@ -0,0 +1,197 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcastfilter
+
+import (
+	"strings"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var systemChain = []byte("SystemChainID")
+
+func genesisConfig(chainID []byte) *ab.ConfigurationEnvelope {
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: chainID, ID: "foo", Data: []byte("bar")})
+	return &ab.ConfigurationEnvelope{
+		ChainID: chainID,
+		Entries: []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}},
+	}
+}
+
+// creationMessage signs the genesis configuration of a chain as signer, with a valid signature unless forged
+func creationMessage(configTx *ab.ConfigurationEnvelope, signer []byte, forged bool) *ab.BroadcastMessage {
+	envelope, _ := proto.Marshal(configTx)
+	signature := append(append([]byte{}, signer...), envelope...)
+	if forged {
+		signature = []byte("forged")
+	}
+	payloadEnvelope, _ := proto.Marshal(&ab.PayloadEnvelope{Payload: envelope, Signer: signer})
+	data, _ := configtx.MarshalChainCreationTransaction(configTx, []*ab.SignedData{&ab.SignedData{PayloadEnvelope: payloadEnvelope, Signature: signature}})
+	return &ab.BroadcastMessage{Data: data, ChainID: systemChain}
+}
+
+// newChains returns a ledger factory holding the system chain and the other chains named
+func newChains(t *testing.T, chainIDs ...string) rawledger.Factory {
+	lf := ramledger.NewFactory(10)
+	for _, chainID := range append([]string{string(systemChain)}, chainIDs...) {
+		genesis, err := configtx.GenesisBlock(genesisConfig([]byte(chainID)))
+		if err != nil {
+			t.Fatalf("Error creating the genesis block of %s: %s", chainID, err)
+		}
+		lf.GetOrCreate([]byte(chainID), genesis)
+	}
+	return lf
+}
+
+func newChainCreationRule(t *testing.T) Rule {
+	return NewChainCreationRule(newWritersManager(), writersPolicyID, systemChain, newChains(t), 0, nil)
+}
+
+func TestChainCreationRuleAccept(t *testing.T) {
+	creationRule := newChainCreationRule(t)
+	rs := NewRuleSet([]Rule{creationRule, AcceptRule})
+	if result, rule := rs.Apply(creationMessage(genesisConfig([]byte("newchain")), writer, false)); result != Reconfigure || rule != creationRule {
+		t.Fatalf("Should have isolated an authorized chain creation transaction")
+	}
+
+	// Through the default chain
+	msg := creationMessage(genesisConfig([]byte("newchain")), writer, false)
+	msg.ChainID = nil
+	if result, _ := rs.Apply(msg); result != Reconfigure {
+		t.Fatalf("Should have isolated a chain creation transaction sent to the default chain")
+	}
+}
+
+func TestChainCreationRuleForwards(t *testing.T) {
+	rs := NewRuleSet([]Rule{newChainCreationRule(t), AcceptRule})
+	for _, msg := range []*ab.BroadcastMessage{
+		&ab.BroadcastMessage{Data: []byte("Not a transaction")},
+		configMessage(configEnvelope(1)),
+	} {
+		if result, rule := rs.Apply(msg); result != Accept || rule != AcceptRule {
+			t.Fatalf("Should have forwarded a message which is not a chain creation transaction")
+		}
+	}
+}
+
+func TestChainCreationRuleReject(t *testing.T) {
+	creationRule := newChainCreationRule(t)
+	rs := NewRuleSet([]Rule{creationRule, AcceptRule})
+
+	otherChain := creationMessage(genesisConfig([]byte("newchain")), writer, false)
+	otherChain.ChainID = []byte("other")
+	sequence := genesisConfig([]byte("newchain"))
+	sequence.Sequence = 1
+
+	for _, tc := range []struct {
+		name   string
+		msg    *ab.BroadcastMessage
+		status ab.Status
+		info   string
+	}{
+		{"forged", creationMessage(genesisConfig([]byte("newchain")), writer, true), ab.Status_FORBIDDEN, "not authorized by policy " + writersPolicyID},
+		{"unauthorized", creationMessage(genesisConfig([]byte("newchain")), []byte("other"), false), ab.Status_FORBIDDEN, "not authorized by policy " + writersPolicyID},
+		{"duplicate", creationMessage(genesisConfig(systemChain), writer, false), ab.Status_BAD_REQUEST, "already exists"},
+		{"empty ID", creationMessage(genesisConfig(nil), writer, false), ab.Status_BAD_REQUEST, "Chain ID is empty"},
+		{"illegal ID", creationMessage(genesisConfig([]byte("new chain")), writer, false), ab.Status_BAD_REQUEST, "may only contain"},
+		{"sequence", creationMessage(sequence, writer, false), ab.Status_BAD_REQUEST, "Sequence 0"},
+		{"other chain", otherChain, ab.Status_BAD_REQUEST, "only be created through the system chain"},
+	} {
+		result, rule := rs.Apply(tc.msg)
+		if result != Reject || rule != creationRule {
+			t.Fatalf("%s: should have been rejected by the chain creation rule", tc.name)
+		}
+		if reply := RejectReply(rule, tc.msg); reply.Status != tc.status || !strings.Contains(reply.Info, tc.info) {
+			t.Fatalf("%s: expected %v mentioning %q, but got %v", tc.name, tc.status, tc.info, reply)
+		}
+	}
+}
+
+func TestChainCreationRuleMissingPolicy(t *testing.T) {
+	for _, defaultDeny := range []bool{false, true} {
+		manager := policies.NewManagerImpl(mockCryptoHelper{})
+		manager.SetDefaultDeny(defaultDeny)
+		creationRule := NewChainCreationRule(manager, policies.ChainCreatorsPolicyID, systemChain, newChains(t), 0, nil)
+
+		result, rule := NewRuleSet([]Rule{creationRule, AcceptRule}).Apply(creationMessage(genesisConfig([]byte("newchain")), []byte("other"), false))
+		if !defaultDeny {
+			if result != Reconfigure || rule != creationRule {
+				t.Fatalf("Should have isolated the chain creation transaction, when missing policies accept")
+			}
+			continue
+		}
+		if result != Reject || rule != creationRule {
+			t.Fatalf("Should have rejected the chain creation transaction, when missing policies deny")
+		}
+		if reply := RejectReply(rule, creationMessage(genesisConfig([]byte("newchain")), []byte("other"), false)); reply.Status != ab.Status_FORBIDDEN {
+			t.Fatalf("Expected FORBIDDEN, but got %v", reply)
+		}
+	}
+}
+
+func TestChainCreationRuleMaxChains(t *testing.T) {
+	creationRule := NewChainCreationRule(newWritersManager(), writersPolicyID, systemChain, newChains(t, "otherchain"), 3, nil)
+	rs := NewRuleSet([]Rule{creationRule, AcceptRule})
+	if result, _ := rs.Apply(creationMessage(genesisConfig([]byte("newchain")), writer, false)); result != Reconfigure {
+		t.Fatalf("Should have isolated an authorized chain creation transaction below the maximum number of chains")
+	}
+
+	creationRule = NewChainCreationRule(newWritersManager(), writersPolicyID, systemChain, newChains(t, "otherchain", "thirdchain"), 3, nil)
+	rs = NewRuleSet([]Rule{creationRule, AcceptRule})
+	for _, tc := range []struct {
+		name   string
+		signer []byte
+		info   string
+	}{
+		{"authorized", writer, "maximum of 3 chains"},
+		{"unauthorized", []byte("other"), "not authorized by policy " + writersPolicyID},
+	} {
+		msg := creationMessage(genesisConfig([]byte("newchain")), tc.signer, false)
+		if result, rule := rs.Apply(msg); result != Reject || rule != creationRule {
+			t.Fatalf("%s: should have rejected the chain creation transaction at the maximum number of chains", tc.name)
+		}
+		if reply := RejectReply(creationRule, msg); reply.Status != ab.Status_FORBIDDEN || !strings.Contains(reply.Info, tc.info) {
+			t.Fatalf("%s: expected FORBIDDEN mentioning %q, but got %v", tc.name, tc.info, reply)
+		}
+	}
+}
+
+func TestChainCreationRejectRule(t *testing.T) {
+	rs := NewRuleSet([]Rule{ChainCreationRejectRule, AcceptRule})
+	msg := creationMessage(genesisConfig([]byte("newchain")), writer, false)
+	if result, rule := rs.Apply(msg); result != Reject || rule != ChainCreationRejectRule {
+		t.Fatalf("Should have rejected a chain creation transaction")
+	}
+	if reply := RejectReply(ChainCreationRejectRule, msg); reply.Status != ab.Status_BAD_REQUEST {
+		t.Fatalf("Expected a chain creation transaction to be a BAD_REQUEST, got %v", reply)
+	}
+	for _, msg := range []*ab.BroadcastMessage{
+		&ab.BroadcastMessage{Data: []byte("Not a transaction")},
+		configMessage(configEnvelope(1)),
+	} {
+		if result, rule := rs.Apply(msg); result != Accept || rule != AcceptRule {
+			t.Fatalf("Should have forwarded a message which is not a chain creation transaction")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcastfilter
+
+import (
+	"fmt"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+)
+
+type configRule struct {
+	resolve   configtx.ManagerResolver
+	decisions *audit.DecisionLog
+}
+
+// NewConfigRule creates a Rule which rejects configuration transactions which the manager would not apply, as a replayed
+// or out of sequence configuration would be, replies Reconfigure to those it would apply, and forwards every other message
+// A transaction whose signatures do not satisfy the policy governing an item it restates is rejected as unauthorized, and recorded to decisions
+func NewConfigRule(manager configtx.Manager, decisions *audit.DecisionLog) Rule {
+	return NewChainConfigRule(func(chainID []byte) (configtx.Manager, bool) { return manager, true }, decisions)
+}
+
+// NewChainConfigRule creates a Rule which checks configuration transactions as NewConfigRule does, against the manager of the chain
+// they are sent to, those sent to a chain which does not exist or whose configuration is not managed are rejected, as nothing would authorize them
+func NewChainConfigRule(resolve configtx.ManagerResolver, decisions *audit.DecisionLog) Rule {
+	return &configRule{
+		resolve:   resolve,
+		decisions: decisions,
+	}
+}
+
+// check returns whether a message is a configuration transaction, and if so the manager of its chain and why it may not be applied
+func (cr *configRule) check(message *ab.BroadcastMessage) (bool, configtx.Manager, error) {
+	configTx, ok, err := configtx.UnmarshalConfigurationTransaction(message.Data)
+	if !ok || err != nil {
+		return ok, nil, err
+	}
+	manager, ok := cr.resolve(message.ChainID)
+	if !ok {
+		return true, nil, fmt.Errorf("The configuration of chain %x is not managed by the orderer", message.ChainID)
+	}
+	return true, manager, manager.Validate(configTx)
+}
+
+func (cr *configRule) Apply(message *ab.BroadcastMessage) Action {
+	return cr.ApplyFrom(message, "")
+}
+
+func (cr *configRule) ApplyFrom(message *ab.BroadcastMessage, client string) Action {
+	isConfig, manager, err := cr.check(message)
+	switch {
+	case err != nil:
+		if pe, ok := err.(*configtx.PolicyError); ok {
+			cr.decisions.Record(audit.NewDecision(audit.PointConfiguration, pe.PolicyID, manager.ChainID(), client, pe.Signers, pe))
+		}
+		logger.Debugf("Rejecting configuration transaction: %s", err)
+		return Reject
+	case isConfig:
+		return Reconfigure
+	default:
+		return Forward
+	}
+}
+
+// RejectReply is FORBIDDEN if the signatures do not satisfy a modification policy, and otherwise BAD_REQUEST, explaining why the configuration may not be applied
+func (cr *configRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	_, _, err := cr.check(message)
+	if _, ok := err.(*configtx.PolicyError); ok {
+		return ab.ReasonForbidden.BroadcastResponse("unauthorized configuration transaction: %v", err)
+	}
+	return ab.ReasonMalformed.BroadcastResponse("invalid configuration transaction: %v", err)
+}
@@ -0,0 +1,249 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcastfilter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var configChain = []byte("ConfigChainID")
+
+// acceptAllManager authorizes every configuration change, so that only the sequence decides the outcome
+type acceptAllManager struct{}
+
+func (am acceptAllManager) GetPolicy(id string) (policies.Policy, bool) {
+	return am, true
+}
+
+func (am acceptAllManager) Evaluate(msg []byte, sigs []*ab.SignedData) error {
+	return nil
+}
+
+func newConfigManager(t *testing.T) configtx.Manager {
+	handlers := make(map[ab.Configuration_ConfigurationType]configtx.Handler)
+	for ctype := range ab.Configuration_ConfigurationType_name {
+		handlers[ab.Configuration_ConfigurationType(ctype)] = configtx.NewBytesHandler()
+	}
+	cm, err := configtx.NewConfigurationManager(configEnvelope(0), acceptAllManager{}, handlers)
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	return cm
+}
+
+// configEnvelope modifies a single item, as each configuration must
+func configEnvelope(sequence uint64) *ab.ConfigurationEnvelope {
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: configChain, ID: "foo", Data: []byte{byte(sequence)}, LastModified: sequence})
+	return &ab.ConfigurationEnvelope{
+		Sequence: sequence,
+		ChainID:  configChain,
+		Entries:  []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}},
+	}
+}
+
+func configMessage(configTx *ab.ConfigurationEnvelope) *ab.BroadcastMessage {
+	data, _ := configtx.MarshalConfigurationTransaction(configTx)
+	return &ab.BroadcastMessage{Data: data}
+}
+
+func TestConfigRuleNextSequence(t *testing.T) {
+	cm := newConfigManager(t)
+	configRule := NewConfigRule(cm, nil)
+	rs := NewRuleSet([]Rule{configRule, AcceptRule})
+	if result, rule := rs.Apply(configMessage(configEnvelope(1))); result != Reconfigure || rule != configRule {
+		t.Fatalf("Should have isolated the configuration with the next sequence number")
+	}
+}
+
+func TestConfigRuleReplay(t *testing.T) {
+	cm := newConfigManager(t)
+	if err := cm.Apply(configEnvelope(1)); err != nil {
+		t.Fatalf("Error applying configuration: %s", err)
+	}
+	configRule := NewConfigRule(cm, nil)
+	rs := NewRuleSet([]Rule{configRule, AcceptRule})
+
+	for _, msg := range []*ab.BroadcastMessage{
+		configMessage(configEnvelope(0)),
+		configMessage(configEnvelope(1)),
+		configMessage(configEnvelope(3)),
+	} {
+		result, rule := rs.Apply(msg)
+		if result != Reject || rule != configRule {
+			t.Fatalf("Should have rejected a configuration which is not the next in sequence")
+		}
+		if reply := RejectReply(rule, msg); reply.Status != ab.Status_BAD_REQUEST || !strings.Contains(reply.Info, "the next must be 2") {
+			t.Fatalf("Out of sequence configurations should be BAD_REQUEST naming the next sequence, but got %v", reply)
+		}
+	}
+}
+
+func TestConfigRuleForwardsOtherMessages(t *testing.T) {
+	rs := NewRuleSet([]Rule{NewConfigRule(newConfigManager(t), nil), AcceptRule})
+	for _, msg := range []*ab.BroadcastMessage{
+		signedMessage(writer, []byte("writerpayload")),
+		&ab.BroadcastMessage{Data: []byte("Not a transaction")},
+	} {
+		if result, rule := rs.Apply(msg); result != Accept || rule != AcceptRule {
+			t.Fatalf("Should have forwarded a message which is not a configuration transaction")
+		}
+	}
+}
+
+func TestChainConfigRuleRoutesByChain(t *testing.T) {
+	cm := newConfigManager(t)
+	configRule := NewChainConfigRule(func(chainID []byte) (configtx.Manager, bool) {
+		return cm, string(chainID) == string(configChain)
+	}, nil)
+	rs := NewRuleSet([]Rule{configRule, AcceptRule})
+
+	msg := configMessage(configEnvelope(1))
+	msg.ChainID = configChain
+	if result, rule := rs.Apply(msg); result != Reconfigure || rule != configRule {
+		t.Fatalf("Should have isolated the configuration of the chain it is sent to")
+	}
+
+	// Nothing would authorize a configuration of a chain whose configuration is not managed
+	msg.ChainID = []byte("missing")
+	if result, rule := rs.Apply(msg); result != Reject || rule != configRule {
+		t.Fatalf("Should have rejected a configuration sent to a chain whose configuration is not managed")
+	}
+	if reply := RejectReply(configRule, msg); reply.Status != ab.Status_BAD_REQUEST {
+		t.Fatalf("Expected a configuration of a chain which is not managed to be a BAD_REQUEST, got %v", reply)
+	}
+}
+
+// ecdsaIdentity is a generated key and a self signed certificate for it
+type ecdsaIdentity struct {
+	cert []byte
+	key  *ecdsa.PrivateKey
+}
+
+func newECDSAIdentity(t *testing.T) *ecdsaIdentity {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	return &ecdsaIdentity{cert: cert, key: key}
+}
+
+// sign returns the signature of data by the identity, as the policies verify it
+func (id *ecdsaIdentity) sign(t *testing.T, data []byte) *ab.SignedData {
+	signature, err := cauthdsl.SignECDSA(id.key, data)
+	if err != nil {
+		t.Fatalf("Error signing: %s", err)
+	}
+	envelope, _ := proto.Marshal(&ab.PayloadEnvelope{Payload: data, Signer: id.cert})
+	return &ab.SignedData{PayloadEnvelope: envelope, Signature: signature}
+}
+
+// adminEnvelope sets the policy Admins to require a signature by admin, and the item foo, both modifiable only by Admins
+// The entries are signed with sign, unless it is nil
+func adminEnvelope(sequence uint64, admin *ecdsaIdentity, sign func(data []byte) []*ab.SignedData) *ab.ConfigurationEnvelope {
+	policy, _ := proto.Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{admin.cert})}})
+	policyItem, _ := proto.Marshal(&ab.Configuration{ChainID: configChain, ID: "Admins", Type: ab.Configuration_Policy, Data: policy, ModificationPolicy: "Admins"})
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: configChain, ID: "foo", Type: ab.Configuration_Fabric, Data: []byte{byte(sequence)}, ModificationPolicy: "Admins", LastModified: sequence})
+
+	configTx := &ab.ConfigurationEnvelope{Sequence: sequence, ChainID: configChain}
+	for _, data := range [][]byte{policyItem, item} {
+		entry := &ab.ConfigurationEntry{Configuration: data}
+		if sign != nil {
+			entry.Signatures = sign(data)
+		}
+		configTx.Entries = append(configTx.Entries, entry)
+	}
+	return configTx
+}
+
+func TestConfigRuleSignatures(t *testing.T) {
+	admin := newECDSAIdentity(t)
+	other := newECDSAIdentity(t)
+
+	pm := policies.NewManagerImpl(cauthdsl.ECDSAHelper{})
+	handlers := make(map[ab.Configuration_ConfigurationType]configtx.Handler)
+	for ctype := range ab.Configuration_ConfigurationType_name {
+		handlers[ab.Configuration_ConfigurationType(ctype)] = configtx.NewBytesHandler()
+	}
+	handlers[ab.Configuration_Policy] = pm
+	cm, err := configtx.NewConfigurationManager(adminEnvelope(0, admin, nil), pm, handlers)
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	configRule := NewConfigRule(cm, nil)
+	rs := NewRuleSet([]Rule{configRule, AcceptRule})
+
+	authorized := configMessage(adminEnvelope(1, admin, func(data []byte) []*ab.SignedData {
+		return []*ab.SignedData{admin.sign(t, data)}
+	}))
+	if result, rule := rs.Apply(authorized); result != Reconfigure || rule != configRule {
+		t.Fatalf("Should have isolated the configuration signed by the admin")
+	}
+
+	for _, tc := range []struct {
+		name string
+		sign func(data []byte) []*ab.SignedData
+	}{
+		{"unauthorized", func(data []byte) []*ab.SignedData { return []*ab.SignedData{other.sign(t, data)} }},
+		{"malformed signature", func(data []byte) []*ab.SignedData {
+			sig := admin.sign(t, data)
+			sig.Signature = []byte("malformed")
+			return []*ab.SignedData{sig}
+		}},
+		{"malformed envelope", func(data []byte) []*ab.SignedData {
+			return []*ab.SignedData{&ab.SignedData{PayloadEnvelope: []byte("malformed"), Signature: []byte("malformed")}}
+		}},
+		{"unsigned", nil},
+	} {
+		msg := configMessage(adminEnvelope(1, admin, tc.sign))
+		result, rule := rs.Apply(msg)
+		if result != Reject || rule != configRule {
+			t.Fatalf("%s: should have rejected the configuration", tc.name)
+		}
+		if reply := RejectReply(rule, msg); reply.Status != ab.Status_FORBIDDEN || !strings.Contains(reply.Info, "policy Admins") {
+			t.Fatalf("%s: expected FORBIDDEN naming the policy, but got %v", tc.name, reply)
+		}
+	}
+
+	if cm.Sequence() != 0 {
+		t.Fatalf("Filtering should not have applied any configuration, but the sequence is %d", cm.Sequence())
+	}
+}
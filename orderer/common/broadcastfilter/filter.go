@@ -17,7 +17,11 @@ limitations under the License.
 package broadcastfilter
 
 import (
+	"sync/atomic"
+
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
 )
 
 // Action is used to express the output of a rule
@@ -40,6 +44,31 @@ type Rule interface {
 	Apply(message *ab.BroadcastMessage) Action
 }
 
+// ClientRule is implemented by Rules which record their decisions, naming the client the message came from
+type ClientRule interface {
+	Rule
+	// ApplyFrom applies the rule to a BroadcastMessage sent by client, as identified by audit.ClientIdentity
+	ApplyFrom(message *ab.BroadcastMessage, client string) Action
+}
+
+// StatusRule is implemented by Rules which explain their rejections, or reply to them with a status other than BAD_REQUEST
+type StatusRule interface {
+	Rule
+	// RejectReply returns the reply to a message the rule rejected
+	RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse
+}
+
+// RejectReply returns the reply to a message rejected by rule, or which no rule accepted if rule is nil
+func RejectReply(rule Rule, message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	if sr, ok := rule.(StatusRule); ok {
+		return sr.RejectReply(message)
+	}
+	if rule == nil {
+		return ab.ReasonMalformed.BroadcastResponse("message was not accepted by any filter")
+	}
+	return ab.ReasonMalformed.BroadcastResponse("message was rejected by a filter")
+}
+
 // EmptyRejectRule rejects empty messages
 var EmptyRejectRule = Rule(emptyRejectRule{})
 
@@ -52,6 +81,57 @@ func (a emptyRejectRule) Apply(message *ab.BroadcastMessage) Action {
 	return Forward
 }
 
+func (a emptyRejectRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	return ab.ReasonMalformed.BroadcastResponse("message is empty")
+}
+
+// MaxCorrelationIDBytes is the longest correlation ID a client may attach to a message
+const MaxCorrelationIDBytes = 64
+
+// CorrelationIDRule rejects messages whose correlation ID exceeds MaxCorrelationIDBytes
+var CorrelationIDRule = Rule(correlationIDRule{})
+
+type correlationIDRule struct{}
+
+func (a correlationIDRule) Apply(message *ab.BroadcastMessage) Action {
+	if len(message.CorrelationID) > MaxCorrelationIDBytes {
+		return Reject
+	}
+	return Forward
+}
+
+func (a correlationIDRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	return ab.ReasonMalformed.BroadcastResponse("correlation ID of %d bytes exceeds limit %d", len(message.CorrelationID), MaxCorrelationIDBytes)
+}
+
+// MaxBytesRule rejects messages whose marshaled size exceeds a limit, which may be changed while the rule is in use
+type MaxBytesRule struct {
+	maxBytes int64 // Accessed atomically
+}
+
+// NewMaxBytesRule returns a rule rejecting messages whose marshaled size exceeds maxBytes
+func NewMaxBytesRule(maxBytes int) *MaxBytesRule {
+	return &MaxBytesRule{maxBytes: int64(maxBytes)}
+}
+
+// SetMaxBytes changes the limit, for the messages filtered from then on
+func (r *MaxBytesRule) SetMaxBytes(maxBytes int) {
+	atomic.StoreInt64(&r.maxBytes, int64(maxBytes))
+}
+
+// Apply rejects a message beyond the limit, forwarding any other
+func (r *MaxBytesRule) Apply(message *ab.BroadcastMessage) Action {
+	if int64(proto.Size(message)) > atomic.LoadInt64(&r.maxBytes) {
+		return Reject
+	}
+	return Forward
+}
+
+// RejectReply is BAD_REQUEST, naming the limit
+func (r *MaxBytesRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	return ab.ReasonOversized.BroadcastResponse("message %d bytes exceeds limit %d", proto.Size(message), atomic.LoadInt64(&r.maxBytes))
+}
+
 // AcceptRule always returns Accept as a result for Apply
 var AcceptRule = Rule(acceptRule{})
 
@@ -76,8 +156,18 @@ func NewRuleSet(rules []Rule) *RuleSet {
 // Apply applies the rules given for this set in order, returning the first non-Forward result and the Rule which generated it
 // or returning Forward, nil if no rules accept or reject it
 func (rs *RuleSet) Apply(message *ab.BroadcastMessage) (Action, Rule) {
+	return rs.ApplyFrom(message, "")
+}
+
+// ApplyFrom applies the rules as Apply does to a message sent by client, which is named in the decisions of each ClientRule
+func (rs *RuleSet) ApplyFrom(message *ab.BroadcastMessage, client string) (Action, Rule) {
 	for _, rule := range rs.rules {
-		action := rule.Apply(message)
+		var action Action
+		if cr, ok := rule.(ClientRule); ok {
+			action = cr.ApplyFrom(message, client)
+		} else {
+			action = rule.Apply(message)
+		}
 		switch action {
 		case Forward:
 			continue
@@ -17,9 +17,12 @@ limitations under the License.
 package broadcastfilter
 
 import (
+	"fmt"
 	"testing"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
 )
 
 var RejectRule = Rule(rejectRule{})
@@ -53,6 +56,52 @@ func TestEmptyRejectRule(t *testing.T) {
 	}
 }
 
+func TestCorrelationIDRule(t *testing.T) {
+	rs := NewRuleSet([]Rule{CorrelationIDRule})
+	if result, _ := rs.Apply(&ab.BroadcastMessage{CorrelationID: make([]byte, MaxCorrelationIDBytes)}); result != Forward {
+		t.Fatalf("Should have forwarded a correlation ID of the maximum length")
+	}
+	msg := &ab.BroadcastMessage{CorrelationID: make([]byte, MaxCorrelationIDBytes+1)}
+	result, rule := rs.Apply(msg)
+	if result != Reject {
+		t.Fatalf("Should have rejected a correlation ID beyond the maximum length")
+	}
+	if reply := RejectReply(rule, msg); reply.Status != ab.Status_BAD_REQUEST {
+		t.Fatalf("Overlong correlation IDs should be a BAD_REQUEST, but got %v", reply)
+	}
+}
+
+func TestMaxBytesRule(t *testing.T) {
+	msg := &ab.BroadcastMessage{Data: []byte("fakedata")}
+	size := proto.Size(msg)
+	if result, _ := NewRuleSet([]Rule{NewMaxBytesRule(size)}).Apply(msg); result != Forward {
+		t.Fatalf("Should have forwarded a message of the maximum size")
+	}
+	result, rule := NewRuleSet([]Rule{NewMaxBytesRule(size - 1)}).Apply(msg)
+	if result != Reject {
+		t.Fatalf("Should have rejected a message beyond the maximum size")
+	}
+	if reply := RejectReply(rule, msg); reply.Status != ab.Status_BAD_REQUEST || reply.Info != fmt.Sprintf("message %d bytes exceeds limit %d", size, size-1) {
+		t.Fatalf("Oversized messages should be a BAD_REQUEST, but got %v", reply)
+	}
+}
+
+func TestMaxBytesRuleChanged(t *testing.T) {
+	msg := &ab.BroadcastMessage{Data: []byte("fakedata")}
+	size := proto.Size(msg)
+	maxBytesRule := NewMaxBytesRule(size)
+	rs := NewRuleSet([]Rule{maxBytesRule})
+
+	maxBytesRule.SetMaxBytes(size - 1)
+	if result, _ := rs.Apply(msg); result != Reject {
+		t.Fatalf("Should have rejected a message beyond the lowered maximum size")
+	}
+	maxBytesRule.SetMaxBytes(size)
+	if result, _ := rs.Apply(msg); result != Forward {
+		t.Fatalf("Should have forwarded a message within the raised maximum size")
+	}
+}
+
 func TestAcceptReject(t *testing.T) {
 	rs := NewRuleSet([]Rule{AcceptRule, RejectRule})
 	result, rule := rs.Apply(&ab.BroadcastMessage{})
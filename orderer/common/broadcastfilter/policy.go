@@ -0,0 +1,142 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcastfilter
+
+import (
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+)
+
+var logger = logging.MustGetLogger("orderer/common/broadcastfilter")
+
+// signedBytes returns the bytes of the Type of a transaction, over which its Signatures are made
+func signedBytes(tx *ab.Transaction) []byte {
+	switch t := tx.Type.(type) {
+	case *ab.Transaction_Opaque:
+		return t.Opaque
+	case *ab.Transaction_ConfigurationEnvelope:
+		return t.ConfigurationEnvelope
+	case *ab.Transaction_CreateChain:
+		return t.CreateChain
+	}
+	return nil
+}
+
+type policyRule struct {
+	manager   policies.Manager
+	policyID  string
+	decisions *audit.DecisionLog
+}
+
+// NewPolicyRule creates a Rule which rejects messages whose signatures do not satisfy the named policy, and forwards the rest
+// The message Data must be a marshaled Transaction, whose Signatures are evaluated over the bytes of its Type
+// The policy is looked up for each message, so that it reflects the current configuration, and each decision is recorded to decisions
+func NewPolicyRule(manager policies.Manager, policyID string, decisions *audit.DecisionLog) Rule {
+	return &policyRule{
+		manager:   manager,
+		policyID:  policyID,
+		decisions: decisions,
+	}
+}
+
+func (pr *policyRule) Apply(message *ab.BroadcastMessage) Action {
+	return pr.ApplyFrom(message, "")
+}
+
+func (pr *policyRule) ApplyFrom(message *ab.BroadcastMessage, client string) Action {
+	tx := &ab.Transaction{}
+	if err := proto.Unmarshal(message.Data, tx); err != nil {
+		logger.Debugf("Rejecting message which is not a transaction: %s", err)
+		return Reject
+	}
+
+	// An unknown policy is the default policy, which rejects or accepts everything as the manager is configured
+	policy, _ := pr.manager.GetPolicy(pr.policyID)
+	err := policy.Evaluate(signedBytes(tx), tx.Signatures)
+	pr.decisions.Record(audit.NewDecision(audit.PointBroadcast, pr.policyID, message.ChainID, client, cauthdsl.Signers(tx.Signatures), err))
+	if err != nil {
+		logger.Debugf("Rejecting message which does not satisfy policy %s: %s", pr.policyID, err)
+		return Reject
+	}
+	return Forward
+}
+
+// RejectReply is FORBIDDEN, as the message was well formed but not authorized
+func (pr *policyRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	return ab.ReasonForbidden.BroadcastResponse("message is not authorized by policy %s", pr.policyID)
+}
+
+type chainPolicyRule struct {
+	resolve   policies.ManagerResolver
+	policyID  string
+	decisions *audit.DecisionLog
+}
+
+// NewChainPolicyRule creates a Rule which rejects the messages whose signatures do not satisfy the named policy of the chain
+// they are sent to, and forwards the rest, it must follow the rules which accept configuration and chain creation transactions,
+// as every message which reaches it is checked, whatever its type
+// A message whose Data is not a Transaction carries no signatures, and so satisfies only a policy which requires none
+// If the chain has no such policy, its messages are rejected or forwarded as the default policy of its manager decides
+// Each decision of the policy is recorded to decisions
+func NewChainPolicyRule(resolve policies.ManagerResolver, policyID string, decisions *audit.DecisionLog) Rule {
+	return &chainPolicyRule{
+		resolve:   resolve,
+		policyID:  policyID,
+		decisions: decisions,
+	}
+}
+
+// check returns why a message from client is not authorized by the policy of its chain, or nil if it is, recording the decision of the policy
+func (cr *chainPolicyRule) check(message *ab.BroadcastMessage, client string) error {
+	msg, sigs := message.Data, []*ab.SignedData(nil)
+	tx := &ab.Transaction{}
+	if err := proto.Unmarshal(message.Data, tx); err == nil && tx.Type != nil {
+		msg, sigs = signedBytes(tx), tx.Signatures
+	}
+
+	// A chain which does not exist is left to be refused when the message is routed
+	manager, ok := cr.resolve(message.ChainID)
+	if !ok {
+		return nil
+	}
+	policy, _ := manager.GetPolicy(cr.policyID)
+	err := policy.Evaluate(msg, sigs)
+	cr.decisions.Record(audit.NewDecision(audit.PointBroadcast, cr.policyID, message.ChainID, client, cauthdsl.Signers(sigs), err))
+	return err
+}
+
+func (cr *chainPolicyRule) Apply(message *ab.BroadcastMessage) Action {
+	return cr.ApplyFrom(message, "")
+}
+
+func (cr *chainPolicyRule) ApplyFrom(message *ab.BroadcastMessage, client string) Action {
+	if err := cr.check(message, client); err != nil {
+		logger.Debugf("Rejecting message to chain %x which does not satisfy policy %s: %s", message.ChainID, cr.policyID, err)
+		return Reject
+	}
+	return Forward
+}
+
+// RejectReply is FORBIDDEN, as the message was well formed but not authorized
+func (cr *chainPolicyRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	return ab.ReasonForbidden.BroadcastResponse("message is not authorized by policy %s of the chain", cr.policyID)
+}
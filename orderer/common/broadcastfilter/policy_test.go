@@ -0,0 +1,175 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broadcastfilter
+
+import (
+	"bytes"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const writersPolicyID = "Writers"
+
+var writer = []byte("writer")
+
+// mockCryptoHelper considers a signature valid only if it is the message prefixed by the signer's identity
+type mockCryptoHelper struct{}
+
+func (mch mockCryptoHelper) VerifySignature(msg []byte, id []byte, signature []byte) bool {
+	return bytes.Equal(signature, append(append([]byte{}, id...), msg...))
+}
+
+func newWritersManager() policies.Manager {
+	policy, err := proto.Marshal(&ab.Policy{
+		Type: &ab.Policy_SignaturePolicy{
+			SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{writer}),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	manager := policies.NewManagerImpl(mockCryptoHelper{})
+	manager.BeginConfig()
+	if err := manager.ProposeConfig(&ab.Configuration{ID: writersPolicyID, Type: ab.Configuration_Policy, Data: policy}); err != nil {
+		panic(err)
+	}
+	manager.CommitConfig()
+	return manager
+}
+
+func signedMessage(signer []byte, signature []byte) *ab.BroadcastMessage {
+	payload := []byte("payload")
+	envelope, _ := proto.Marshal(&ab.PayloadEnvelope{Payload: payload, Signer: signer})
+	data, _ := proto.Marshal(&ab.Transaction{
+		Type:       &ab.Transaction_Opaque{Opaque: payload},
+		Signatures: []*ab.SignedData{&ab.SignedData{PayloadEnvelope: envelope, Signature: signature}},
+	})
+	return &ab.BroadcastMessage{Data: data}
+}
+
+func TestPolicyAccept(t *testing.T) {
+	rs := NewRuleSet([]Rule{EmptyRejectRule, NewPolicyRule(newWritersManager(), writersPolicyID, nil), AcceptRule})
+	result, rule := rs.Apply(signedMessage(writer, []byte("writerpayload")))
+	if result != Accept || rule != AcceptRule {
+		t.Fatalf("Should have forwarded a properly signed message to be accepted")
+	}
+}
+
+func TestPolicyReject(t *testing.T) {
+	policyRule := NewPolicyRule(newWritersManager(), writersPolicyID, nil)
+	rs := NewRuleSet([]Rule{EmptyRejectRule, policyRule, AcceptRule})
+
+	for _, msg := range []*ab.BroadcastMessage{
+		signedMessage(writer, []byte("forged")),
+		signedMessage([]byte("other"), []byte("otherpayload")),
+		&ab.BroadcastMessage{Data: []byte("Not a transaction")},
+	} {
+		result, rule := rs.Apply(msg)
+		if result != Reject || rule != policyRule {
+			t.Fatalf("Should have been rejected by the policy rule")
+		}
+		if reply := RejectReply(rule, msg); reply.Status != ab.Status_FORBIDDEN || reply.Info != "message is not authorized by policy "+writersPolicyID {
+			t.Fatalf("Policy rejections should be FORBIDDEN, but got %v", reply)
+		}
+	}
+}
+
+func TestPolicyUnknown(t *testing.T) {
+	rs := NewRuleSet([]Rule{NewPolicyRule(newWritersManager(), "Unknown", nil), AcceptRule})
+	if result, _ := rs.Apply(signedMessage(writer, []byte("writerpayload"))); result != Reject {
+		t.Fatalf("Should have rejected a message against an unknown policy")
+	}
+
+	// Unless the manager is configured for unknown policies to accept
+	manager := policies.NewManagerImpl(mockCryptoHelper{})
+	manager.SetDefaultDeny(false)
+	rs = NewRuleSet([]Rule{NewPolicyRule(manager, "Unknown", nil), AcceptRule})
+	if result, _ := rs.Apply(signedMessage([]byte("other"), []byte("otherpayload"))); result != Accept {
+		t.Fatalf("Should have accepted a message against an unknown policy, when unknown policies accept")
+	}
+}
+
+func TestRuleOrdering(t *testing.T) {
+	rs := NewRuleSet([]Rule{EmptyRejectRule, NewPolicyRule(newWritersManager(), writersPolicyID, nil), AcceptRule})
+	result, rule := rs.Apply(&ab.BroadcastMessage{})
+	if result != Reject || rule != EmptyRejectRule {
+		t.Fatalf("Empty messages should be rejected before the policy is evaluated")
+	}
+	if reply := RejectReply(rule, &ab.BroadcastMessage{}); reply.Status != ab.Status_BAD_REQUEST || reply.Info != "message is empty" {
+		t.Fatalf("Empty messages should be a BAD_REQUEST, but got %v", reply)
+	}
+}
+
+func TestChainPolicyRule(t *testing.T) {
+	onChain := func(msg *ab.BroadcastMessage, chainID string) *ab.BroadcastMessage {
+		msg.ChainID = []byte(chainID)
+		return msg
+	}
+	configData, _ := configtx.MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{ChainID: []byte("writers")})
+
+	for _, defaultDeny := range []bool{false, true} {
+		open := policies.NewManagerImpl(mockCryptoHelper{})
+		open.SetDefaultDeny(defaultDeny)
+		managers := map[string]policies.Manager{
+			"":        newWritersManager(),
+			"open":    open,
+			"writers": newWritersManager(),
+		}
+		resolve := func(chainID []byte) (policies.Manager, bool) {
+			manager, ok := managers[string(chainID)]
+			return manager, ok
+		}
+		policyRule := NewChainPolicyRule(resolve, writersPolicyID, nil)
+		rs := NewRuleSet([]Rule{policyRule, AcceptRule})
+
+		for _, tc := range []struct {
+			name       string
+			msg        *ab.BroadcastMessage
+			authorized bool
+		}{
+			{"signed on the default chain", signedMessage(writer, []byte("writerpayload")), true},
+			{"signed", onChain(signedMessage(writer, []byte("writerpayload")), "writers"), true},
+			{"forged", onChain(signedMessage(writer, []byte("forged")), "writers"), false},
+			{"signed by another", onChain(signedMessage([]byte("other"), []byte("otherpayload")), "writers"), false},
+			{"not a transaction", &ab.BroadcastMessage{Data: []byte("Not a transaction"), ChainID: []byte("writers")}, false},
+			{"unsigned configuration", &ab.BroadcastMessage{Data: configData, ChainID: []byte("writers")}, false},
+			{"without a Writers policy", onChain(signedMessage([]byte("other"), []byte("otherpayload")), "open"), !defaultDeny},
+			{"to an unknown chain", &ab.BroadcastMessage{Data: []byte("Not a transaction"), ChainID: []byte("unknown")}, true},
+		} {
+			result, rule := rs.Apply(tc.msg)
+			if tc.authorized {
+				if result != Accept || rule != AcceptRule {
+					t.Fatalf("%s with defaultDeny %v: should have been forwarded to be accepted", tc.name, defaultDeny)
+				}
+				continue
+			}
+			if result != Reject || rule != policyRule {
+				t.Fatalf("%s with defaultDeny %v: should have been rejected by the policy rule", tc.name, defaultDeny)
+			}
+			if reply := RejectReply(rule, tc.msg); reply.Status != ab.Status_FORBIDDEN {
+				t.Fatalf("%s with defaultDeny %v: policy rejections should be FORBIDDEN, but got %v", tc.name, defaultDeny, reply)
+			}
+		}
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
 )
 
 // CryptoHelper is used to provide a plugin point for different signature validation types
@@ -38,6 +40,9 @@ func NewSignaturePolicyEvaluator(policy *ab.SignaturePolicyEnvelope, ch CryptoHe
 	if policy.Version != 0 {
 		return nil, fmt.Errorf("This evaluator only understands messages of version 0, but version was %d", policy.Version)
 	}
+	if err := checkIdentities(policy.Identities); err != nil {
+		return nil, err
+	}
 
 	compiled, err := compile(policy.Policy, policy.Identities, ch)
 	if err != nil {
@@ -51,8 +56,14 @@ func NewSignaturePolicyEvaluator(policy *ab.SignaturePolicyEnvelope, ch CryptoHe
 
 // compile recursively builds a go evaluatable function corresponding to the policy specified
 func compile(policy *ab.SignaturePolicy, identities [][]byte, ch CryptoHelper) (func([]byte, [][]byte, [][]byte) bool, error) {
+	if policy == nil {
+		return nil, fmt.Errorf("Nil signature policy")
+	}
 	switch t := policy.Type.(type) {
 	case *ab.SignaturePolicy_From:
+		if t.From == nil || t.From.N < 0 {
+			return nil, fmt.Errorf("NOutOf must require a number of policies which is not negative")
+		}
 		policies := make([]func([]byte, [][]byte, [][]byte) bool, len(t.From.Policies))
 		for i, policy := range t.From.Policies {
 			compiledPolicy, err := compile(policy, identities, ch)
@@ -94,3 +105,31 @@ func compile(policy *ab.SignaturePolicy, identities [][]byte, ch CryptoHelper) (
 func (ape *SignaturePolicyEvaluator) Authenticate(msg []byte, ids [][]byte, signatures [][]byte) bool {
 	return ape.compiledAuthenticator(msg, ids, signatures)
 }
+
+// Signers returns the identity each of sigs claims to be signed by, nil for a signature whose envelope is malformed
+func Signers(sigs []*ab.SignedData) [][]byte {
+	identities := make([][]byte, len(sigs))
+	for i, sig := range sigs {
+		envelope := &ab.PayloadEnvelope{}
+		if err := proto.Unmarshal(sig.PayloadEnvelope, envelope); err == nil {
+			identities[i] = envelope.Signer
+		}
+	}
+	return identities
+}
+
+// checkIdentities returns an error if an identity is empty, as a signature whose signer could not be read would match it,
+// or if an identity is listed more than once
+func checkIdentities(identities [][]byte) error {
+	listed := make(map[string]struct{}, len(identities))
+	for i, identity := range identities {
+		if len(identity) == 0 {
+			return fmt.Errorf("Identity %d is empty", i)
+		}
+		if _, ok := listed[string(identity)]; ok {
+			return fmt.Errorf("Identity %d is listed more than once", i)
+		}
+		listed[string(identity)] = struct{}{}
+	}
+	return nil
+}
@@ -89,3 +89,11 @@ func NOutOf(n int32, policies []*ab.SignaturePolicy) *ab.SignaturePolicy {
 		},
 	}
 }
+
+// SignatureThreshold creates a policy which requires valid signatures from n distinct identities of those given
+func SignatureThreshold(n int32, identities [][]byte) *ab.SignatureThresholdPolicy {
+	return &ab.SignatureThresholdPolicy{
+		N:          n,
+		Identities: identities,
+	}
+}
@@ -107,3 +107,14 @@ func TestNegatively(t *testing.T) {
 		t.Fatalf("Should have errored compiling because the Type field was nil")
 	}
 }
+
+func TestSigners(t *testing.T) {
+	envelope, err := proto.Marshal(&ab.PayloadEnvelope{Signer: signers[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	identities := Signers([]*ab.SignedData{{PayloadEnvelope: envelope}, {PayloadEnvelope: []byte("malformed")}})
+	if len(identities) != 2 || !bytes.Equal(identities[0], signers[0]) || identities[1] != nil {
+		t.Fatalf("Expected the signer of the well formed envelope and nil for the malformed one, got %v", identities)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cauthdsl
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"math/big"
+)
+
+// ECDSAHelper is a CryptoHelper for identities which are DER encoded X.509 certificates with ECDSA keys, whose signatures
+// are ASN.1 encoded ECDSA signatures over the SHA-256 hash of the message
+// A malformed identity or signature never verifies, so every orderer reaches the same decision for the same message
+type ECDSAHelper struct{}
+
+// ecdsaSignature is the ASN.1 structure in which the signatures are encoded, as in X.509
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// VerifySignature returns whether signature is the signature of msg by the key of the certificate id
+func (eh ECDSAHelper) VerifySignature(msg []byte, id []byte, signature []byte) bool {
+	cert, err := x509.ParseCertificate(id)
+	if err != nil {
+		return false
+	}
+	key, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+
+	rs := ecdsaSignature{}
+	if rest, err := asn1.Unmarshal(signature, &rs); err != nil || len(rest) != 0 {
+		return false
+	}
+	digest := sha256.Sum256(msg)
+	return ecdsa.Verify(key, digest[:], rs.R, rs.S)
+}
+
+// SignECDSA returns the signature of msg by key, as ECDSAHelper verifies it
+func SignECDSA(key *ecdsa.PrivateKey, msg []byte) ([]byte, error) {
+	digest := sha256.Sum256(msg)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ecdsaSignature{R: r, S: s})
+}
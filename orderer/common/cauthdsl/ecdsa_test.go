@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cauthdsl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newIdentity generates a key and a self signed certificate for it
+func newIdentity(t *testing.T, key interface{}, public interface{}) []byte {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, public, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	return cert
+}
+
+func newECDSAIdentity(t *testing.T) ([]byte, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	return newIdentity(t, key, &key.PublicKey), key
+}
+
+func TestECDSAHelper(t *testing.T) {
+	msg := []byte("message")
+	id, key := newECDSAIdentity(t)
+	otherID, otherKey := newECDSAIdentity(t)
+	signature, err := SignECDSA(key, msg)
+	if err != nil {
+		t.Fatalf("Error signing: %s", err)
+	}
+	otherSignature, _ := SignECDSA(otherKey, msg)
+
+	eh := ECDSAHelper{}
+	if !eh.VerifySignature(msg, id, signature) {
+		t.Fatalf("Should have verified the signature of the identity")
+	}
+	if eh.VerifySignature([]byte("other message"), id, signature) {
+		t.Fatalf("Should not have verified the signature of another message")
+	}
+	if eh.VerifySignature(msg, id, otherSignature) || eh.VerifySignature(msg, otherID, signature) {
+		t.Fatalf("Should not have verified the signature of another identity")
+	}
+	if eh.VerifySignature(msg, id, append(signature, 0)) || eh.VerifySignature(msg, id, []byte("malformed")) || eh.VerifySignature(msg, id, nil) {
+		t.Fatalf("Should not have verified a malformed signature")
+	}
+	if eh.VerifySignature(msg, []byte("malformed"), signature) {
+		t.Fatalf("Should not have verified a signature by a malformed identity")
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	if eh.VerifySignature(msg, newIdentity(t, rsaKey, &rsaKey.PublicKey), signature) {
+		t.Fatalf("Should not have verified a signature by an identity without an ECDSA key")
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cauthdsl
+
+import (
+	"fmt"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+// NewSignatureThresholdEvaluator compiles a SignatureThresholdPolicy, which must list some identities, and require at least
+// one signature and no more than it lists identities, so that it may be satisfied
+func NewSignatureThresholdEvaluator(policy *ab.SignatureThresholdPolicy, ch CryptoHelper) (*SignaturePolicyEvaluator, error) {
+	if len(policy.Identities) == 0 {
+		return nil, fmt.Errorf("The threshold policy lists no identities")
+	}
+	if err := checkIdentities(policy.Identities); err != nil {
+		return nil, err
+	}
+	members := make(map[string]struct{}, len(policy.Identities))
+	for _, identity := range policy.Identities {
+		members[string(identity)] = struct{}{}
+	}
+	if policy.N <= 0 || int(policy.N) > len(members) {
+		return nil, fmt.Errorf("The threshold must be between 1 and the %d identities listed, but was %d", len(members), policy.N)
+	}
+
+	threshold := int(policy.N)
+	return &SignaturePolicyEvaluator{
+		compiledAuthenticator: func(msg []byte, ids [][]byte, signatures [][]byte) bool {
+			// An identity is counted once its first valid signature is found, a further signature by it is not verified
+			counted := make(map[string]struct{}, threshold)
+			for i, id := range ids {
+				if _, ok := members[string(id)]; !ok {
+					continue
+				}
+				if _, ok := counted[string(id)]; ok {
+					continue
+				}
+				if ch.VerifySignature(msg, id, signatures[i]) {
+					counted[string(id)] = struct{}{}
+					if len(counted) >= threshold {
+						return true
+					}
+				}
+			}
+			return false
+		},
+	}, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cauthdsl
+
+import (
+	"testing"
+)
+
+// countingHelper counts the signatures it verifies
+type countingHelper struct {
+	ECDSAHelper
+	verified int
+}
+
+func (ch *countingHelper) VerifySignature(msg []byte, id []byte, signature []byte) bool {
+	ch.verified++
+	return ch.ECDSAHelper.VerifySignature(msg, id, signature)
+}
+
+func TestSignatureThreshold(t *testing.T) {
+	msg := []byte("message")
+	ids := make([][]byte, 4)
+	sigs := make([][]byte, 4)
+	for i := range ids {
+		id, key := newECDSAIdentity(t)
+		signature, err := SignECDSA(key, msg)
+		if err != nil {
+			t.Fatalf("Error signing: %s", err)
+		}
+		ids[i], sigs[i] = id, signature
+	}
+	// The last identity is not a member
+	members := ids[:3]
+	signedBy := func(indexes ...int) ([][]byte, [][]byte) {
+		var signers, signatures [][]byte
+		for _, i := range indexes {
+			signers = append(signers, ids[i])
+			signatures = append(signatures, sigs[i])
+		}
+		return signers, signatures
+	}
+
+	testCases := []struct {
+		name    string
+		n       int32
+		signers []int
+		result  bool
+	}{
+		{"OneOfOne", 1, []int{1}, true},
+		{"OneOfNone", 1, nil, false},
+		{"AllOfAll", 3, []int{2, 0, 1}, true},
+		{"AllOfTwo", 3, []int{0, 1}, false},
+		{"DuplicateSigner", 2, []int{0, 0}, false},
+		{"DuplicateSignerAndAnother", 2, []int{0, 0, 2}, true},
+		{"UnknownSigner", 2, []int{0, 3}, false},
+		{"UnknownSignerAndMembers", 2, []int{3, 1, 2}, true},
+	}
+	for _, tc := range testCases {
+		evaluator, err := NewSignatureThresholdEvaluator(SignatureThreshold(tc.n, members), ECDSAHelper{})
+		if err != nil {
+			t.Fatalf("%s: error compiling the policy: %s", tc.name, err)
+		}
+		signers, signatures := signedBy(tc.signers...)
+		if result := evaluator.Authenticate(msg, signers, signatures); result != tc.result {
+			t.Errorf("%s: expected %t, got %t", tc.name, tc.result, result)
+		}
+	}
+}
+
+func TestSignatureThresholdMalformed(t *testing.T) {
+	msg := []byte("message")
+	id, key := newECDSAIdentity(t)
+	otherID, otherKey := newECDSAIdentity(t)
+	signature, _ := SignECDSA(key, msg)
+	otherSignature, _ := SignECDSA(otherKey, msg)
+	evaluator, err := NewSignatureThresholdEvaluator(SignatureThreshold(2, [][]byte{id, otherID, []byte("malformed")}), ECDSAHelper{})
+	if err != nil {
+		t.Fatalf("Error compiling the policy: %s", err)
+	}
+
+	// A malformed identity, a missing identity, and a malformed signature satisfy nothing, but do not prevent the others counting
+	signers := [][]byte{[]byte("malformed"), nil, otherID, id, otherID}
+	signatures := [][]byte{signature, signature, []byte("malformed"), signature, otherSignature}
+	if !evaluator.Authenticate(msg, signers, signatures) {
+		t.Errorf("Expected the two valid signatures to satisfy the policy")
+	}
+	if evaluator.Authenticate(msg, signers[:4], signatures[:4]) {
+		t.Errorf("Expected one valid signature not to satisfy the policy")
+	}
+}
+
+func TestSignatureThresholdShortCircuits(t *testing.T) {
+	msg := []byte("message")
+	var ids, sigs [][]byte
+	for i := 0; i < 3; i++ {
+		id, key := newECDSAIdentity(t)
+		signature, _ := SignECDSA(key, msg)
+		ids, sigs = append(ids, id), append(sigs, signature)
+	}
+	ch := &countingHelper{}
+	evaluator, err := NewSignatureThresholdEvaluator(SignatureThreshold(2, ids), ch)
+	if err != nil {
+		t.Fatalf("Error compiling the policy: %s", err)
+	}
+
+	// The first signature is repeated, the repeat is not verified, and the third is not verified once two are found
+	signers := [][]byte{ids[0], ids[0], ids[1], ids[2]}
+	signatures := [][]byte{sigs[0], sigs[0], sigs[1], sigs[2]}
+	if !evaluator.Authenticate(msg, signers, signatures) {
+		t.Fatalf("Expected two valid signatures to satisfy the policy")
+	}
+	if ch.verified != 2 {
+		t.Errorf("Expected evaluation to stop after two signatures were verified, but %d were", ch.verified)
+	}
+}
+
+func TestSignatureThresholdInvalid(t *testing.T) {
+	ids := [][]byte{[]byte("a"), []byte("b")}
+	for _, policy := range []struct {
+		name       string
+		n          int32
+		identities [][]byte
+	}{
+		{"Zero", 0, ids},
+		{"Negative", -1, ids},
+		{"Unsatisfiable", 3, ids},
+		{"DuplicateIdentity", 2, [][]byte{[]byte("a"), []byte("a")}},
+	} {
+		if _, err := NewSignatureThresholdEvaluator(SignatureThreshold(policy.n, policy.identities), ECDSAHelper{}); err == nil {
+			t.Errorf("%s: expected the policy to be refused", policy.name)
+		}
+	}
+}
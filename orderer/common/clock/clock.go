@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clock is the source of time for the batching loops of the orderers, it may be replaced so that batch
+// timeouts can be tested deterministically
+package clock
+
+import (
+	"time"
+)
+
+// Clock returns the current time and creates timers
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// NewTimer returns a Timer which fires once after d has elapsed
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event created by a Clock
+type Timer interface {
+	// C returns the channel on which the time is delivered when the timer fires
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, returning false if it had already fired or been stopped
+	Stop() bool
+}
+
+// Real is the clock of the system
+type Real struct{}
+
+// Now returns the current time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer returns a Timer which fires once after d has elapsed
+func (Real) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (rt realTimer) C() <-chan time.Time {
+	return rt.timer.C
+}
+
+func (rt realTimer) Stop() bool {
+	return rt.timer.Stop()
+}
@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake only moves when advanced, its timers fire synchronously within Advance
+type Fake struct {
+	lock    sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	created chan time.Time
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	deadline time.Time
+	c        chan time.Time
+}
+
+// NewFake returns a clock standing at the Unix epoch
+func NewFake() *Fake {
+	return &Fake{
+		now:     time.Unix(0, 0),
+		created: make(chan time.Time, 100),
+	}
+}
+
+// Now returns the time the clock was advanced to
+func (fc *Fake) Now() time.Time {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	return fc.now
+}
+
+// NewTimer returns a Timer which fires once the clock is advanced by d, its deadline is sent on the channel of Created
+func (fc *Fake) NewTimer(d time.Duration) Timer {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	ft := &fakeTimer{clock: fc, deadline: fc.now.Add(d), c: make(chan time.Time, 1)}
+	fc.timers = append(fc.timers, ft)
+	fc.created <- ft.deadline
+	return ft
+}
+
+// Advance moves the clock forward by d, firing any timers which fall due, a negative d steps it backwards as a wall clock may
+func (fc *Fake) Advance(d time.Duration) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	fc.now = fc.now.Add(d)
+	var pending []*fakeTimer
+	for _, ft := range fc.timers {
+		if ft.deadline.After(fc.now) {
+			pending = append(pending, ft)
+			continue
+		}
+		ft.c <- fc.now
+	}
+	fc.timers = pending
+}
+
+// ActiveTimers returns the number of timers which have neither fired nor been stopped
+func (fc *Fake) ActiveTimers() int {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	return len(fc.timers)
+}
+
+// Created returns a channel with the deadline of each timer, in the order they were created
+func (fc *Fake) Created() <-chan time.Time {
+	return fc.created
+}
+
+func (ft *fakeTimer) C() <-chan time.Time {
+	return ft.c
+}
+
+func (ft *fakeTimer) Stop() bool {
+	ft.clock.lock.Lock()
+	defer ft.clock.lock.Unlock()
+	for i, active := range ft.clock.timers {
+		if active == ft {
+			ft.clock.timers = append(ft.clock.timers[:i], ft.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	fc := NewFake()
+	start := fc.Now()
+	first := fc.NewTimer(time.Second)
+	second := fc.NewTimer(2 * time.Second)
+	if deadline := <-fc.Created(); deadline != start.Add(time.Second) {
+		t.Fatalf("Expected the first timer to be due at %v, got %v", start.Add(time.Second), deadline)
+	}
+
+	fc.Advance(time.Second)
+	select {
+	case <-first.C():
+	default:
+		t.Fatal("Expected the first timer to fire once it fell due")
+	}
+	if !second.Stop() || fc.ActiveTimers() != 0 {
+		t.Fatal("Expected the second timer to be stopped before it fell due")
+	}
+	fc.Advance(time.Second)
+	select {
+	case <-second.C():
+		t.Fatal("Expected a stopped timer not to fire")
+	default:
+	}
+}
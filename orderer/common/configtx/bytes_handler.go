@@ -17,20 +17,22 @@ limitations under the License.
 package configtx
 
 import (
+	"sync/atomic"
+
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 )
 
 // BytesHandler is a trivial ConfigHandler which simpy tracks the bytes stores in a config
 type BytesHandler struct {
-	config   map[string][]byte
+	config   atomic.Value // The map[string][]byte committed, replaced whole so that GetBytes never waits on a proposal
 	proposed map[string][]byte
 }
 
 // NewBytesHandler creates a new BytesHandler
 func NewBytesHandler() *BytesHandler {
-	return &BytesHandler{
-		config: make(map[string][]byte),
-	}
+	bh := &BytesHandler{}
+	bh.config.Store(make(map[string][]byte))
+	return bh
 }
 
 // BeginConfig called when a config proposal is begun
@@ -51,7 +53,7 @@ func (bh *BytesHandler) CommitConfig() {
 	if bh.proposed == nil {
 		panic("Programming error, called CommitConfig with no proposal in process")
 	}
-	bh.config = bh.proposed
+	bh.config.Store(bh.proposed)
 	bh.proposed = nil
 }
 
@@ -63,5 +65,5 @@ func (bh *BytesHandler) ProposeConfig(configItem *ab.Configuration) error {
 
 // GetBytes allows the caller to retrieve the bytes for a config
 func (bh *BytesHandler) GetBytes(id string) []byte {
-	return bh.config[id]
+	return bh.config.Load().(map[string][]byte)[id]
 }
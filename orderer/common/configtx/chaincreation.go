@@ -0,0 +1,138 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// MaxChainIDLength is the longest ID a chain may be created with
+const MaxChainIDLength = 249
+
+var legalChainID = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// ValidateChainID returns an error unless a chain may be created with the given ID
+func ValidateChainID(chainID []byte) error {
+	switch {
+	case len(chainID) == 0:
+		return fmt.Errorf("Chain ID is empty")
+	case len(chainID) > MaxChainIDLength:
+		return fmt.Errorf("Chain ID %x is longer than %d bytes", chainID, MaxChainIDLength)
+	case !legalChainID.Match(chainID):
+		return fmt.Errorf("Chain ID %x may only contain letters, digits, '.', '_' and '-'", chainID)
+	}
+	return nil
+}
+
+// MarshalChainCreationTransaction wraps the genesis configuration of a new chain in a Transaction of the chain creation type, signed by sigs
+func MarshalChainCreationTransaction(configTx *ab.ConfigurationEnvelope, sigs []*ab.SignedData) ([]byte, error) {
+	envelope, err := proto.Marshal(configTx)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&ab.Transaction{Type: &ab.Transaction_CreateChain{CreateChain: envelope}, Signatures: sigs})
+}
+
+// UnmarshalChainCreationTransaction returns the genesis configuration carried by data if it is a Transaction of the chain creation type,
+// false for any other message, and an error if it is of the chain creation type but its envelope is malformed
+func UnmarshalChainCreationTransaction(data []byte) (*ab.ConfigurationEnvelope, bool, error) {
+	tx := &ab.Transaction{}
+	if err := proto.Unmarshal(data, tx); err != nil {
+		return nil, false, nil
+	}
+	t, ok := tx.Type.(*ab.Transaction_CreateChain)
+	if !ok {
+		return nil, false, nil
+	}
+
+	configTx := &ab.ConfigurationEnvelope{}
+	if err := proto.Unmarshal(t.CreateChain, configTx); err != nil {
+		return nil, true, fmt.Errorf("Genesis configuration envelope is malformed: %s", err)
+	}
+	return configTx, true, nil
+}
+
+// BlockChainCreation returns the genesis configuration of the chain a block creates, or nil if it creates none
+// Chain creation transactions are always alone in their block
+func BlockChainCreation(block *ab.Block) *ab.ConfigurationEnvelope {
+	if len(block.GetData().GetMessages()) != 1 {
+		return nil
+	}
+
+	configTx, ok, err := UnmarshalChainCreationTransaction(block.Data.Messages[0].Data)
+	if err != nil {
+		logger.Warningf("Skipping block %d, whose chain creation transaction cannot be used: %s", block.Header.Number, err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return configTx
+}
+
+// CheckGenesisConfiguration returns an error unless configTx may be the first configuration of a new chain, that is unless
+// its chain ID is valid, its Sequence is zero, and each of its entries is a distinct item of that chain created at that sequence
+func CheckGenesisConfiguration(configTx *ab.ConfigurationEnvelope) error {
+	if err := ValidateChainID(configTx.ChainID); err != nil {
+		return err
+	}
+	if configTx.Sequence != 0 {
+		return fmt.Errorf("Genesis configuration must have Sequence 0, got %d", configTx.Sequence)
+	}
+
+	seen := make(map[ab.Configuration_ConfigurationType]map[string]bool)
+	for _, entry := range configTx.Entries {
+		config := &ab.Configuration{}
+		if err := proto.Unmarshal(entry.Configuration, config); err != nil {
+			return fmt.Errorf("Genesis configuration item is malformed: %s", err)
+		}
+		if _, ok := ab.Configuration_ConfigurationType_name[int32(config.Type)]; !ok {
+			return fmt.Errorf("Config item %v has unknown type %v", config.ID, config.Type)
+		}
+		if !bytes.Equal(config.ChainID, configTx.ChainID) {
+			return fmt.Errorf("Config item %v for type %v was meant for a different chain %x", config.ID, config.Type, config.ChainID)
+		}
+		if config.LastModified != 0 {
+			return fmt.Errorf("Config item %v for type %v has LastModified %d, but is created by the genesis configuration", config.ID, config.Type, config.LastModified)
+		}
+		if seen[config.Type][config.ID] {
+			return fmt.Errorf("Config item %v for type %v appears more than once", config.ID, config.Type)
+		}
+		if seen[config.Type] == nil {
+			seen[config.Type] = make(map[string]bool)
+		}
+		seen[config.Type][config.ID] = true
+	}
+	return nil
+}
+
+// GenesisBlock returns the first block of the chain configTx creates, carrying configTx as its configuration transaction
+func GenesisBlock(configTx *ab.ConfigurationEnvelope) (*ab.Block, error) {
+	data, err := MarshalConfigurationTransaction(configTx)
+	if err != nil {
+		return nil, err
+	}
+	block := ab.NewBlock(0, nil, []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: data}}, nil)
+	block.SetLastConfiguration(0)
+	return block, nil
+}
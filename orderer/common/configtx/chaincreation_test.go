@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtx
+
+import (
+	"strings"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var newChain = []byte("newchain")
+
+func genesisEntry(chainID []byte, id string, ctype ab.Configuration_ConfigurationType, lastModified uint64) *ab.ConfigurationEntry {
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: chainID, ID: id, Type: ctype, LastModified: lastModified})
+	return &ab.ConfigurationEntry{Configuration: item}
+}
+
+func TestChainCreationRoundTrip(t *testing.T) {
+	configTx := &ab.ConfigurationEnvelope{ChainID: newChain, Entries: []*ab.ConfigurationEntry{genesisEntry(newChain, "foo", ab.Configuration_Fabric, 0)}}
+	data, err := MarshalChainCreationTransaction(configTx, nil)
+	if err != nil {
+		t.Fatalf("Error marshaling chain creation transaction: %s", err)
+	}
+
+	block := ab.NewBlock(1, nil, []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: data}}, nil)
+	if created := BlockChainCreation(block); created == nil || !proto.Equal(created, configTx) {
+		t.Fatalf("Expected the block to create the chain, got %v", created)
+	}
+	if BlockConfiguration(block) != nil {
+		t.Fatalf("A chain creation transaction should not be the configuration of the chain it is sent on")
+	}
+	if BlockChainCreation(ab.NewBlock(1, nil, []*ab.BroadcastMessage{configMessage(1)}, nil)) != nil {
+		t.Fatalf("A configuration transaction should not create a chain")
+	}
+
+	genesis, err := GenesisBlock(configTx)
+	if err != nil {
+		t.Fatalf("Error creating genesis block: %s", err)
+	}
+	if number, ok := genesis.LastConfiguration(); genesis.Header.Number != 0 || !ok || number != 0 {
+		t.Fatalf("Expected the genesis block to be its own last configuration block")
+	}
+	if genesisConfig := BlockConfiguration(genesis); genesisConfig == nil || !proto.Equal(genesisConfig, configTx) {
+		t.Fatalf("Expected the genesis block to carry the genesis configuration, got %v", genesisConfig)
+	}
+}
+
+func TestCheckGenesisConfiguration(t *testing.T) {
+	if err := CheckGenesisConfiguration(&ab.ConfigurationEnvelope{ChainID: newChain, Entries: []*ab.ConfigurationEntry{
+		genesisEntry(newChain, "foo", ab.Configuration_Fabric, 0),
+		genesisEntry(newChain, "foo", ab.Configuration_Policy, 0),
+	}}); err != nil {
+		t.Fatalf("Should have accepted the genesis configuration: %s", err)
+	}
+
+	for _, tc := range []struct {
+		configTx *ab.ConfigurationEnvelope
+		err      string
+	}{
+		{&ab.ConfigurationEnvelope{ChainID: []byte(strings.Repeat("a", MaxChainIDLength+1))}, "longer than"},
+		{&ab.ConfigurationEnvelope{ChainID: []byte("new/chain")}, "may only contain"},
+		{&ab.ConfigurationEnvelope{ChainID: newChain, Sequence: 1}, "Sequence 0"},
+		{&ab.ConfigurationEnvelope{ChainID: newChain, Entries: []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: []byte("garbage")}}}, "malformed"},
+		{&ab.ConfigurationEnvelope{ChainID: newChain, Entries: []*ab.ConfigurationEntry{genesisEntry(newChain, "foo", ab.Configuration_ConfigurationType(100), 0)}}, "unknown type"},
+		{&ab.ConfigurationEnvelope{ChainID: newChain, Entries: []*ab.ConfigurationEntry{genesisEntry(defaultChain, "foo", ab.Configuration_Fabric, 0)}}, "different chain"},
+		{&ab.ConfigurationEnvelope{ChainID: newChain, Entries: []*ab.ConfigurationEntry{genesisEntry(newChain, "foo", ab.Configuration_Fabric, 1)}}, "LastModified"},
+		{&ab.ConfigurationEnvelope{ChainID: newChain, Entries: []*ab.ConfigurationEntry{
+			genesisEntry(newChain, "foo", ab.Configuration_Fabric, 0),
+			genesisEntry(newChain, "foo", ab.Configuration_Fabric, 0),
+		}}, "more than once"},
+	} {
+		if err := CheckGenesisConfiguration(tc.configTx); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("Expected an error mentioning %q, got %v", tc.err, err)
+		}
+	}
+}
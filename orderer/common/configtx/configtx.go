@@ -19,14 +19,22 @@ package configtx
 import (
 	"bytes"
 	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/clock"
 	"github.com/hyperledger/fabric/orderer/common/policies"
 
 	"github.com/golang/protobuf/proto"
 )
 
 // Handler provides a hook which allows other pieces of code to participate in config proposals
+// A handler stages the items proposed to it, and its state observed by others must not change until CommitConfig,
+// as the proposal is rolled back in every handler if any item of the configtx is rejected
 type Handler interface {
 	// BeginConfig called when a config proposal is begun
 	BeginConfig()
@@ -46,29 +54,81 @@ type Manager interface {
 	// Apply attempts to apply a configtx to become the new configuration
 	Apply(configtx *ab.ConfigurationEnvelope) error
 
-	// Validate attempts to validate a new configtx against the current config state
+	// Validate attempts to validate a new configtx against the current config state, which it never changes
 	Validate(configtx *ab.ConfigurationEnvelope) error
+
+	// Sequence returns the sequence number of the configtx most recently applied
+	Sequence() uint64
+
+	// ChainID returns the ID of the chain the configuration is for
+	ChainID() []byte
+
+	// Get returns the data of the config item of the given type and ID, or false if there is no such item
+	Get(ctype ab.Configuration_ConfigurationType, id string) ([]byte, bool)
+
+	// RegisterObserver arranges for observer to be called after each configtx is applied
+	RegisterObserver(observer Observer)
+
+	// Snapshot returns a configtx restating the current configuration, from which a Manager may be created in the same state
+	Snapshot() *ab.ConfigurationEnvelope
+
+	// ExportGenesis returns a genesis block carrying the Snapshot, with which another orderer may be bootstrapped in the same state
+	ExportGenesis() (*ab.Block, error)
 }
 
+// ManagerResolver returns the configuration manager of a chain, the default chain if chainID is empty, or false if the chain has none
+type ManagerResolver func(chainID []byte) (Manager, bool)
+
 // DefaultModificationPolicyID is the ID of the policy used when no other policy can be resolved, for instance when attempting to create a new config item
 const DefaultModificationPolicyID = "DefaultModificationPolicy"
 
+// PolicyError is returned for a configtx with an entry whose signatures do not satisfy the policy governing the modification of its item
+type PolicyError struct {
+	PolicyID string
+	Type     ab.Configuration_ConfigurationType
+	ID       string
+	Signers  [][]byte // The identities which signed the entry, as they claim to be, in the order they signed
+	Err      error
+}
+
+func (pe *PolicyError) Error() string {
+	return fmt.Sprintf("Modification policy %s of key %v for type %v was not satisfied: %s", pe.PolicyID, pe.ID, pe.Type, pe.Err)
+}
+
 type acceptAllPolicy struct{}
 
 func (ap *acceptAllPolicy) Evaluate(msg []byte, sigs []*ab.SignedData) error {
 	return nil
 }
 
-type configurationManager struct {
+// configState is the configuration as of one configtx, it is never modified once stored, so that it may be queried without locking
+type configState struct {
 	sequence      uint64
+	configuration map[ab.Configuration_ConfigurationType]map[string]*ab.Configuration
+	entries       map[ab.Configuration_ConfigurationType]map[string]*ab.ConfigurationEntry // The entry which set each item, as signed
+}
+
+type configurationManager struct {
+	lock          sync.Mutex   // Guards observers, and serializes proposals to the handlers
+	notifyLock    sync.Mutex   // Held while the observers are notified, so that they see each configtx in order
+	observers     []Observer   // Copied on write, so that the observers may be notified without holding lock
+	state         atomic.Value // The *configState of the configtx most recently applied, replaced whole so that queries never wait on a proposal
 	chainID       []byte
 	pm            policies.Manager
-	configuration map[ab.Configuration_ConfigurationType]map[string]*ab.Configuration
 	handlers      map[ab.Configuration_ConfigurationType]Handler
+	defaultPolicy string        // The ID of the policy authorizing the creation of config items
+	clock         clock.Clock   // Checks the validity window of each configtx, nil while the initial configtx is applied
+	restoring     bool          // Set while the initial configtx is applied, whose items may have been set by the configtxs before it
+	skew          time.Duration // How far the clock may disagree with the proposer of a configtx about its validity window
 }
 
 // NewConfigurationManager creates a new Manager unless an error is encountered
+// The creation of config items is authorized by the policy DefaultModificationPolicyID
 func NewConfigurationManager(configtx *ab.ConfigurationEnvelope, pm policies.Manager, handlers map[ab.Configuration_ConfigurationType]Handler) (Manager, error) {
+	return newConfigurationManager(configtx, pm, handlers, DefaultModificationPolicyID, clock.Real{}, 0)
+}
+
+func newConfigurationManager(configtx *ab.ConfigurationEnvelope, pm policies.Manager, handlers map[ab.Configuration_ConfigurationType]Handler, defaultPolicy string, clk clock.Clock, skew time.Duration) (Manager, error) {
 	for ctype := range ab.Configuration_ConfigurationType_name {
 		if _, ok := handlers[ab.Configuration_ConfigurationType(ctype)]; !ok {
 			return nil, fmt.Errorf("Must supply a handler for all known types")
@@ -76,12 +136,13 @@ func NewConfigurationManager(configtx *ab.ConfigurationEnvelope, pm policies.Man
 	}
 
 	cm := &configurationManager{
-		sequence:      configtx.Sequence - 1,
+		restoring:     true,
 		chainID:       configtx.ChainID,
 		pm:            pm,
 		handlers:      handlers,
-		configuration: makeConfigMap(),
+		defaultPolicy: defaultPolicy,
 	}
+	cm.state.Store(&configState{sequence: configtx.Sequence - 1, configuration: makeConfigMap(handlers)})
 
 	err := cm.Apply(configtx)
 
@@ -89,147 +150,265 @@ func NewConfigurationManager(configtx *ab.ConfigurationEnvelope, pm policies.Man
 		return nil, err
 	}
 
+	// The initial configtx was accepted when it was proposed, so it must not expire when the orderer restarts from it
+	cm.clock = clk
+	cm.skew = skew
+	cm.restoring = false
+
 	return cm, nil
 }
 
-func makeConfigMap() map[ab.Configuration_ConfigurationType]map[string]*ab.Configuration {
+// makeConfigMap has an empty map for each type with a handler, including any registered beyond the known types
+func makeConfigMap(handlers map[ab.Configuration_ConfigurationType]Handler) map[ab.Configuration_ConfigurationType]map[string]*ab.Configuration {
 	configMap := make(map[ab.Configuration_ConfigurationType]map[string]*ab.Configuration)
-	for ctype := range ab.Configuration_ConfigurationType_name {
-		configMap[ab.Configuration_ConfigurationType(ctype)] = make(map[string]*ab.Configuration)
+	for ctype := range handlers {
+		configMap[ctype] = make(map[string]*ab.Configuration)
 	}
 	return configMap
 }
 
+// current returns the configuration most recently applied
+func (cm *configurationManager) current() *configState {
+	return cm.state.Load().(*configState)
+}
+
 func (cm *configurationManager) beginHandlers() {
-	for ctype := range ab.Configuration_ConfigurationType_name {
-		cm.handlers[ab.Configuration_ConfigurationType(ctype)].BeginConfig()
+	for _, handler := range cm.handlers {
+		handler.BeginConfig()
 	}
 }
 
 func (cm *configurationManager) rollbackHandlers() {
-	for ctype := range ab.Configuration_ConfigurationType_name {
-		cm.handlers[ab.Configuration_ConfigurationType(ctype)].RollbackConfig()
+	for _, handler := range cm.handlers {
+		handler.RollbackConfig()
 	}
 }
 
 func (cm *configurationManager) commitHandlers() {
-	for ctype := range ab.Configuration_ConfigurationType_name {
-		cm.handlers[ab.Configuration_ConfigurationType(ctype)].CommitConfig()
+	for _, handler := range cm.handlers {
+		handler.CommitConfig()
 	}
 }
 
-func (cm *configurationManager) processConfig(configtx *ab.ConfigurationEnvelope) (configMap map[ab.Configuration_ConfigurationType]map[string]*ab.Configuration, err error) {
-	// Verify config is a sequential update to prevent exhausting sequence numbers
-	if configtx.Sequence != cm.sequence+1 {
-		return nil, fmt.Errorf("Config sequence number jumped from %d to %d", cm.sequence, configtx.Sequence)
+func (cm *configurationManager) processConfig(configtx *ab.ConfigurationEnvelope) (configMap map[ab.Configuration_ConfigurationType]map[string]*ab.Configuration, entryMap map[ab.Configuration_ConfigurationType]map[string]*ab.ConfigurationEntry, err error) {
+	current := cm.current()
+
+	// Verify config is a sequential update to prevent replaying old configs and exhausting sequence numbers
+	if configtx.Sequence != current.sequence+1 {
+		if configtx.Sequence <= current.sequence {
+			return nil, nil, fmt.Errorf("Config sequence number %d was already applied, the next must be %d", configtx.Sequence, current.sequence+1)
+		}
+		return nil, nil, fmt.Errorf("Config sequence number jumped from %d to %d, the next must be %d", current.sequence, configtx.Sequence, current.sequence+1)
 	}
 
 	// Verify config is intended for this globally unique chain ID
 	if !bytes.Equal(configtx.ChainID, cm.chainID) {
-		return nil, fmt.Errorf("Config is for the wrong chain, expected %x, got %x", cm.chainID, configtx.ChainID)
+		return nil, nil, fmt.Errorf("Config is for the wrong chain, expected %x, got %x", cm.chainID, configtx.ChainID)
 	}
 
-	defaultModificationPolicy, defaultPolicySet := cm.pm.GetPolicy(DefaultModificationPolicyID)
+	if err = cm.checkValidity(configtx); err != nil {
+		return nil, nil, err
+	}
 
-	// If the default modification policy is not set, it indicates this is an uninitialized chain, so be permissive of modification
-	if !defaultPolicySet {
+	// The initial configtx is applied before the policies it defines exist, so until the default modification policy is set be permissive of it
+	defaultModificationPolicy, defaultPolicySet := cm.pm.GetPolicy(cm.defaultPolicy)
+	if !defaultPolicySet && cm.restoring {
 		defaultModificationPolicy = &acceptAllPolicy{}
 	}
 
-	configMap = makeConfigMap()
+	configMap = makeConfigMap(cm.handlers)
+	entryMap = make(map[ab.Configuration_ConfigurationType]map[string]*ab.ConfigurationEntry)
 
 	for _, entry := range configtx.Entries {
 		// Verify every entry is well formed
 		config := &ab.Configuration{}
 		err = proto.Unmarshal(entry.Configuration, config)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+
+		// Ensure some handler is responsible for this type, rather than silently tracking data nothing understands
+		handler, ok := cm.handlers[config.Type]
+		if !ok {
+			return nil, nil, fmt.Errorf("Config item %v has unknown type %v", config.ID, config.Type)
 		}
 
 		// Ensure this configuration was intended for this chain
 		if !bytes.Equal(config.ChainID, cm.chainID) {
-			return nil, fmt.Errorf("Config item %v for type %v was not meant for a different chain %x", config.ID, config.Type, config.ChainID)
+			return nil, nil, fmt.Errorf("Config item %v for type %v was not meant for a different chain %x", config.ID, config.Type, config.ChainID)
 		}
 
 		// Get the modification policy for this config item if one was previously specified
 		// or the default if this is a new config item
 		var policy policies.Policy
-		oldItem, ok := cm.configuration[config.Type][config.ID]
+		policyID := cm.defaultPolicy
+		oldItem, ok := current.configuration[config.Type][config.ID]
 		if ok {
-			policy, _ = cm.pm.GetPolicy(oldItem.ModificationPolicy)
+			policyID = oldItem.ModificationPolicy
+			policy, _ = cm.pm.GetPolicy(policyID)
 		} else {
 			policy = defaultModificationPolicy
 		}
 
 		// Ensure the policy is satisfied
 		if err = policy.Evaluate(entry.Configuration, entry.Signatures); err != nil {
-			return nil, err
+			return nil, nil, &PolicyError{PolicyID: policyID, Type: config.Type, ID: config.ID, Signers: cauthdsl.Signers(entry.Signatures), Err: err}
 		}
 
 		// Ensure the config sequence numbers are correct to prevent replay attacks
 		isModified := false
 
-		if val, ok := cm.configuration[config.Type][config.ID]; ok {
+		if val, ok := current.configuration[config.Type][config.ID]; ok {
 			// Config was modified if the LastModified or the Data contents changed
 			isModified = (val.LastModified != config.LastModified) || !bytes.Equal(config.Data, val.Data)
 		} else {
-			if config.LastModified != configtx.Sequence {
-				return nil, fmt.Errorf("Key %v for type %v was new, but had an older Sequence %d set", config.ID, config.Type, config.LastModified)
+			// On restart the items which were not modified by the last configtx carry the sequence which last modified them
+			if config.LastModified != configtx.Sequence && !(cm.restoring && config.LastModified < configtx.Sequence) {
+				return nil, nil, fmt.Errorf("Key %v for type %v was new, but had an older Sequence %d set", config.ID, config.Type, config.LastModified)
 			}
-			isModified = true
+			isModified = !cm.restoring
 		}
 
 		// If a config item was modified, its LastModified must be set correctly
 		if isModified {
 			if config.LastModified != configtx.Sequence {
-				return nil, fmt.Errorf("Key %v for type %v was modified, but its LastModified %d does not equal current configtx Sequence %d", config.ID, config.Type, config.LastModified, configtx.Sequence)
+				return nil, nil, fmt.Errorf("Key %v for type %v was modified, but its LastModified %d does not equal current configtx Sequence %d", config.ID, config.Type, config.LastModified, configtx.Sequence)
 			}
 		}
 
 		// Ensure the type handler agrees the config is well formed
-		err = cm.handlers[config.Type].ProposeConfig(config)
+		err = handler.ProposeConfig(config)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		configMap[config.Type][config.ID] = config
+		if entryMap[config.Type] == nil {
+			entryMap[config.Type] = make(map[string]*ab.ConfigurationEntry)
+		}
+		entryMap[config.Type][config.ID] = entry
 	}
 
 	// Ensure that any config items which used to exist still exist, to prevent implicit deletion
-	for ctype := range ab.Configuration_ConfigurationType_name {
-		curMap := cm.configuration[ab.Configuration_ConfigurationType(ctype)]
-		newMap := configMap[ab.Configuration_ConfigurationType(ctype)]
+	for ctype, curMap := range current.configuration {
+		newMap := configMap[ctype]
 		for id := range curMap {
 			_, ok := newMap[id]
 			if !ok {
-				return nil, fmt.Errorf("Missing key %v for type %v in new configuration", id, ctype)
+				return nil, nil, fmt.Errorf("Missing key %v for type %v in new configuration", id, ctype)
 			}
 
 		}
 	}
 
-	return configMap, nil
+	return configMap, entryMap, nil
+
+}
+
+// checkValidity ensures the clock is within the validity window of configtx, allowing for the skew at either end
+func (cm *configurationManager) checkValidity(configtx *ab.ConfigurationEnvelope) error {
+	if cm.clock == nil {
+		return nil
+	}
 
+	now := cm.clock.Now()
+	if configtx.NotBefore != 0 {
+		if notBefore := time.Unix(0, configtx.NotBefore); now.Add(cm.skew).Before(notBefore) {
+			return fmt.Errorf("Config is not valid until %v, it is now %v", notBefore.UTC(), now.UTC())
+		}
+	}
+	if configtx.NotAfter != 0 {
+		if notAfter := time.Unix(0, configtx.NotAfter); now.Add(-cm.skew).After(notAfter) {
+			return fmt.Errorf("Config expired at %v, it is now %v", notAfter.UTC(), now.UTC())
+		}
+	}
+	return nil
 }
 
 // Validate attempts to validate a new configtx against the current config state
 func (cm *configurationManager) Validate(configtx *ab.ConfigurationEnvelope) error {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+
 	cm.beginHandlers()
-	_, err := cm.processConfig(configtx)
+	_, _, err := cm.processConfig(configtx)
 	cm.rollbackHandlers()
 	return err
 }
 
 // Apply attempts to apply a configtx to become the new configuration
 func (cm *configurationManager) Apply(configtx *ab.ConfigurationEnvelope) error {
+	cm.lock.Lock()
+
 	cm.beginHandlers()
-	configMap, err := cm.processConfig(configtx)
+	configMap, entryMap, err := cm.processConfig(configtx)
 	if err != nil {
 		cm.rollbackHandlers()
+		cm.lock.Unlock()
 		return err
 	}
-	cm.configuration = configMap
-	cm.sequence = configtx.Sequence
+	cm.state.Store(&configState{sequence: configtx.Sequence, configuration: configMap, entries: entryMap})
 	cm.commitHandlers()
+
+	// The observers are notified once the new configuration may be queried, the notify lock is taken first so that
+	// an Apply which follows cannot notify ahead of this one
+	observers := cm.observers
+	changed := changedTypes(configMap, configtx.Sequence)
+	cm.notifyLock.Lock()
+	defer cm.notifyLock.Unlock()
+	cm.lock.Unlock()
+
+	for _, observer := range observers {
+		notify(observer, configtx.Sequence, changed)
+	}
 	return nil
 }
+
+// Sequence returns the sequence number of the configtx most recently applied
+func (cm *configurationManager) Sequence() uint64 {
+	return cm.current().sequence
+}
+
+// ChainID returns the ID of the chain the configuration is for
+func (cm *configurationManager) ChainID() []byte {
+	return cm.chainID
+}
+
+// Get returns the data of the config item of the given type and ID, or false if there is no such item
+func (cm *configurationManager) Get(ctype ab.Configuration_ConfigurationType, id string) ([]byte, bool) {
+	item, ok := cm.current().configuration[ctype][id]
+	if !ok {
+		return nil, false
+	}
+	return item.Data, true
+}
+
+// Snapshot returns a configtx restating the current configuration, at the sequence most recently applied, with the entry
+// which set each item as it was signed, ordered by type and then ID, so that managers in the same state return the same configtx
+func (cm *configurationManager) Snapshot() *ab.ConfigurationEnvelope {
+	current := cm.current()
+	snapshot := &ab.ConfigurationEnvelope{Sequence: current.sequence, ChainID: cm.chainID}
+	ctypes := make([]int, 0, len(current.entries))
+	for ctype := range current.entries {
+		ctypes = append(ctypes, int(ctype))
+	}
+	sort.Ints(ctypes)
+	for _, ctype := range ctypes {
+		items := current.entries[ab.Configuration_ConfigurationType(ctype)]
+		ids := make([]string, 0, len(items))
+		for id := range items {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			snapshot.Entries = append(snapshot.Entries, items[id])
+		}
+	}
+	return snapshot
+}
+
+// ExportGenesis returns a genesis block carrying the Snapshot of the current configuration, from which an orderer may join
+// the chain in the same configuration state, the sequence is kept so that configtxs in flight remain valid on it
+func (cm *configurationManager) ExportGenesis() (*ab.Block, error) {
+	return GenesisBlock(cm.Snapshot())
+}
@@ -17,10 +17,16 @@ limitations under the License.
 package configtx
 
 import (
+	"bytes"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/clock"
 	"github.com/hyperledger/fabric/orderer/common/policies"
 
 	"github.com/golang/protobuf/proto"
@@ -54,7 +60,10 @@ type mockPolicyManager struct {
 }
 
 func (mpm *mockPolicyManager) GetPolicy(id string) (policies.Policy, bool) {
-	return mpm.policy, (mpm.policy != nil)
+	if mpm.policy == nil {
+		return &mockPolicy{fmt.Errorf("Policy %s does not exist", id)}, false
+	}
+	return mpm.policy, true
 }
 
 func makeConfiguration(id, modificationPolicy string, lastModified uint64, data []byte) *ab.Configuration {
@@ -159,12 +168,14 @@ func TestInvalidInitialConfigByStructure(t *testing.T) {
 	}
 }
 
-// TestValidConfigChange tests the happy path of updating a configuration value with no defaultModificationPolicy
+// TestValidConfigChange tests the happy path of updating a configuration value with no defaultModificationPolicy while missing policies accept
 func TestValidConfigChange(t *testing.T) {
+	pm := policies.NewManagerImpl(nil)
+	pm.SetDefaultDeny(false)
 	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
 		Sequence: 0,
 		ChainID:  defaultChain,
-	}, &mockPolicyManager{}, defaultHandlers())
+	}, pm, defaultHandlers())
 
 	if err != nil {
 		t.Fatalf("Error constructing configuration manager: %s", err)
@@ -497,3 +508,683 @@ func TestConfigItemOnWrongChain(t *testing.T) {
 		t.Errorf("Should have errored applying config because new config item is for a different chain")
 	}
 }
+
+// TestQueries tests that the sequence and config items reflect the configuration most recently applied
+func TestQueries(t *testing.T) {
+	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", 0, []byte("foo"))},
+	}, &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	if !bytes.Equal(cm.ChainID(), defaultChain) {
+		t.Errorf("Expected chain ID %s, got %s", defaultChain, cm.ChainID())
+	}
+	if cm.Sequence() != 0 {
+		t.Errorf("Expected sequence 0, got %d", cm.Sequence())
+	}
+	if data, ok := cm.Get(ab.Configuration_Policy, "foo"); !ok || string(data) != "foo" {
+		t.Errorf("Expected foo to be foo, got %s", data)
+	}
+	if _, ok := cm.Get(ab.Configuration_Policy, "bar"); ok {
+		t.Errorf("Should not have found bar before it was configured")
+	}
+
+	newConfig := &ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			makeConfigurationEntry("foo", "foo", 1, []byte("baz")),
+			makeConfigurationEntry("bar", "bar", 1, []byte("bar")),
+		},
+	}
+
+	if err = cm.Validate(newConfig); err != nil {
+		t.Fatalf("Should not have errored validating config: %s", err)
+	}
+	if data, _ := cm.Get(ab.Configuration_Policy, "foo"); cm.Sequence() != 0 || string(data) != "foo" {
+		t.Errorf("Validating a config should not have changed it")
+	}
+
+	if err = cm.Apply(newConfig); err != nil {
+		t.Fatalf("Should not have errored applying config: %s", err)
+	}
+	if cm.Sequence() != 1 {
+		t.Errorf("Expected sequence 1, got %d", cm.Sequence())
+	}
+	if data, ok := cm.Get(ab.Configuration_Policy, "foo"); !ok || string(data) != "baz" {
+		t.Errorf("Expected foo to be baz, got %s", data)
+	}
+	if data, ok := cm.Get(ab.Configuration_Policy, "bar"); !ok || string(data) != "bar" {
+		t.Errorf("Expected bar to be bar, got %s", data)
+	}
+}
+
+// TestQueriesConcurrentWithApply tests that queries never observe a configuration which is partially applied
+func TestQueriesConcurrentWithApply(t *testing.T) {
+	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", 0, []byte("0"))},
+	}, &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := uint64(1); i <= 100; i++ {
+			err := cm.Apply(&ab.ConfigurationEnvelope{
+				Sequence: i,
+				ChainID:  defaultChain,
+				Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", i, []byte(fmt.Sprintf("%d", i)))},
+			})
+			if err != nil {
+				t.Errorf("Should not have errored applying config %d: %s", i, err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		cm.Sequence()
+		if _, ok := cm.Get(ab.Configuration_Policy, "foo"); !ok {
+			t.Fatalf("Config item should exist in every applied configuration")
+		}
+	}
+}
+
+// TestQueriesStress runs many readers of every query against a stream of configtxs, for the race detector
+func TestQueriesStress(t *testing.T) {
+	handlers := defaultHandlers()
+	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", 0, []byte("0"))},
+	}, &mockPolicyManager{&mockPolicy{}}, handlers)
+
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	readers := 8
+	reads := 2000
+	var wg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < reads; i++ {
+				sequence := cm.Sequence()
+				data, ok := cm.Get(ab.Configuration_Policy, "foo")
+				if !ok {
+					t.Errorf("Config item should exist in every applied configuration")
+					return
+				}
+				// The item is read after the sequence, so it is from that configuration or a later one
+				if value, _ := strconv.ParseUint(string(data), 10, 64); value < sequence {
+					t.Errorf("Read item from sequence %d after reading sequence %d", value, sequence)
+					return
+				}
+				if snapshot := cm.Snapshot(); len(snapshot.Entries) != 1 {
+					t.Errorf("Expected a snapshot of a single item, got %d", len(snapshot.Entries))
+					return
+				}
+				handlers[ab.Configuration_Policy].(*BytesHandler).GetBytes("foo")
+			}
+		}()
+	}
+
+	for i := uint64(1); i <= 200; i++ {
+		err := cm.Apply(&ab.ConfigurationEnvelope{
+			Sequence: i,
+			ChainID:  defaultChain,
+			Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", i, []byte(fmt.Sprintf("%d", i)))},
+		})
+		if err != nil {
+			t.Fatalf("Should not have errored applying config %d: %s", i, err)
+		}
+	}
+	wg.Wait()
+}
+
+// blockingHandler holds each proposal until released, reporting when one has begun
+type blockingHandler struct {
+	*BytesHandler
+	proposing chan struct{}
+	release   chan struct{}
+}
+
+func (bh *blockingHandler) ProposeConfig(item *ab.Configuration) error {
+	bh.proposing <- struct{}{}
+	<-bh.release
+	return bh.BytesHandler.ProposeConfig(item)
+}
+
+// TestQueriesDuringApply tests that queries do not wait on a configtx being applied, and see the configuration before it
+func TestQueriesDuringApply(t *testing.T) {
+	handlers := defaultHandlers()
+	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", 0, []byte("0"))},
+	}, &mockPolicyManager{&mockPolicy{}}, handlers)
+
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	bh := &blockingHandler{BytesHandler: handlers[ab.Configuration_Policy].(*BytesHandler), proposing: make(chan struct{}), release: make(chan struct{})}
+	cm.(*configurationManager).handlers[ab.Configuration_Policy] = bh
+
+	applied := make(chan error)
+	go func() {
+		applied <- cm.Apply(&ab.ConfigurationEnvelope{
+			Sequence: 1,
+			ChainID:  defaultChain,
+			Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", 1, []byte("1"))},
+		})
+	}()
+	<-bh.proposing
+
+	queried := make(chan struct{})
+	go func() {
+		defer close(queried)
+		if sequence := cm.Sequence(); sequence != 0 {
+			t.Errorf("Expected the sequence before the configtx being applied, got %d", sequence)
+		}
+		if data, _ := cm.Get(ab.Configuration_Policy, "foo"); string(data) != "0" {
+			t.Errorf("Expected the item before the configtx being applied, got %s", data)
+		}
+		if snapshot := cm.Snapshot(); snapshot.Sequence != 0 {
+			t.Errorf("Expected a snapshot of the configuration before the configtx being applied, got sequence %d", snapshot.Sequence)
+		}
+		if data := bh.GetBytes("foo"); string(data) != "0" {
+			t.Errorf("Expected the handler to hold the item before the configtx being applied, got %s", data)
+		}
+	}()
+	select {
+	case <-queried:
+	case <-time.After(time.Second):
+		t.Fatalf("Queries should not have waited on the configtx being applied")
+	}
+
+	close(bh.release)
+	if err := <-applied; err != nil {
+		t.Fatalf("Should not have errored applying config: %s", err)
+	}
+	if data, _ := cm.Get(ab.Configuration_Policy, "foo"); string(data) != "1" {
+		t.Fatalf("Expected the item of the configtx applied, got %s", data)
+	}
+}
+
+// TestConfigSkippedSequence tests that a config which skips ahead of the next sequence number is rejected
+func TestConfigSkippedSequence(t *testing.T) {
+	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+	}, &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	newConfig := &ab.ConfigurationEnvelope{
+		Sequence: 2,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", 2, []byte("foo"))},
+	}
+
+	if err = cm.Validate(newConfig); err == nil {
+		t.Errorf("Should have errored when validating a configuration which skipped a sequence number")
+	}
+
+	if err = cm.Apply(newConfig); err == nil {
+		t.Errorf("Should have errored when applying a configuration which skipped a sequence number")
+	}
+}
+
+// TestSequenceRecovered tests that a manager bootstrapped from the config of an existing chain requires the sequence after it
+func TestSequenceRecovered(t *testing.T) {
+	recovered := &ab.ConfigurationEnvelope{
+		Sequence: 3,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", 3, []byte("foo"))},
+	}
+	cm, err := NewConfigurationManager(recovered, &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	if cm.Sequence() != 3 {
+		t.Fatalf("Expected the sequence of the recovered config 3, got %d", cm.Sequence())
+	}
+
+	if err = cm.Validate(recovered); err == nil {
+		t.Errorf("Should have errored when validating a replay of the recovered configuration")
+	}
+
+	err = cm.Validate(&ab.ConfigurationEnvelope{
+		Sequence: 4,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "foo", 4, []byte("bar"))},
+	})
+	if err != nil {
+		t.Errorf("Should not have errored validating the configuration following the recovered one: %s", err)
+	}
+}
+
+// failOnIDHandler stages items as a BytesHandler does, but rejects the item with the ID failID
+type failOnIDHandler struct {
+	*BytesHandler
+	failID string
+}
+
+func (fh *failOnIDHandler) ProposeConfig(item *ab.Configuration) error {
+	if item.ID == fh.failID {
+		return fmt.Errorf("Fail")
+	}
+	return fh.BytesHandler.ProposeConfig(item)
+}
+
+func makeTypedConfigurationEntry(ctype ab.Configuration_ConfigurationType, id string, lastModified uint64, data []byte) *ab.ConfigurationEntry {
+	config := makeConfiguration(id, id, lastModified, data)
+	config.Type = ctype
+	marshaledConfig, err := proto.Marshal(config)
+	if err != nil {
+		panic(err)
+	}
+	return &ab.ConfigurationEntry{
+		Configuration: marshaledConfig,
+	}
+}
+
+// TestRollbackOnFailedItem tests that when an item of a config is rejected, the items proposed before it to other handlers
+// are rolled back, leaving no observable change, and that the handlers accept the next proposal
+func TestRollbackOnFailedItem(t *testing.T) {
+	types := []ab.Configuration_ConfigurationType{ab.Configuration_Policy, ab.Configuration_Fabric, ab.Configuration_Chain, ab.Configuration_Solo, ab.Configuration_Kafka}
+	handlers := defaultHandlers()
+	failer := &failOnIDHandler{BytesHandler: NewBytesHandler()}
+	handlers[ab.Configuration_Chain] = failer
+
+	makeEnvelope := func(sequence uint64) *ab.ConfigurationEnvelope {
+		configtx := &ab.ConfigurationEnvelope{Sequence: sequence, ChainID: defaultChain}
+		for i, ctype := range types {
+			configtx.Entries = append(configtx.Entries, makeTypedConfigurationEntry(ctype, fmt.Sprintf("item%d", i), sequence, []byte(fmt.Sprintf("%d", sequence))))
+		}
+		return configtx
+	}
+
+	cm, err := NewConfigurationManager(makeEnvelope(0), &mockPolicyManager{&mockPolicy{}}, handlers)
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	// The third of the five items fails, after the first two were proposed to their handlers
+	failer.failID = "item2"
+	if err = cm.Apply(makeEnvelope(1)); err == nil {
+		t.Fatalf("Should have errored applying config because the handler rejected an item")
+	}
+
+	if cm.Sequence() != 0 {
+		t.Errorf("Sequence should not have advanced for a rejected config, got %d", cm.Sequence())
+	}
+	for i, ctype := range types {
+		id := fmt.Sprintf("item%d", i)
+		if data, _ := cm.Get(ctype, id); string(data) != "0" {
+			t.Errorf("Expected %s to be unchanged by the rejected config, got %s", id, data)
+		}
+		var bh *BytesHandler
+		if ctype == ab.Configuration_Chain {
+			bh = failer.BytesHandler
+		} else {
+			bh = handlers[ctype].(*BytesHandler)
+		}
+		if data := bh.GetBytes(id); string(data) != "0" {
+			t.Errorf("Expected the handler of %s to be unchanged by the rejected config, got %s", id, data)
+		}
+	}
+
+	failer.failID = ""
+	if err = cm.Apply(makeEnvelope(1)); err != nil {
+		t.Fatalf("Should not have errored applying config once the handler accepts it: %s", err)
+	}
+	if data := handlers[ab.Configuration_Policy].(*BytesHandler).GetBytes("item0"); string(data) != "1" {
+		t.Errorf("Expected the applied config to be committed to the handlers, got %s", data)
+	}
+}
+
+// scriptedPolicyManager evaluates each policy to the error scripted for its ID, a policy with no script does not exist
+type scriptedPolicyManager map[string]error
+
+func (spm scriptedPolicyManager) GetPolicy(id string) (policies.Policy, bool) {
+	result, ok := spm[id]
+	if !ok {
+		return &mockPolicy{policyResult: fmt.Errorf("Policy %s does not exist", id)}, false
+	}
+	return &mockPolicy{policyResult: result}, true
+}
+
+// newScriptedManager creates a manager with item foo modified under policy fooPolicy, and items created under policy creators
+func newScriptedManager(t *testing.T, spm scriptedPolicyManager) Manager {
+	registry := NewRegistry()
+	registry.SetDefaultModificationPolicy("creators")
+	cm, err := registry.NewManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "fooPolicy", 0, []byte("foo"))},
+	}, spm)
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	return cm
+}
+
+// TestItemPolicySatisfied tests that a modification is applied once the policy of every item it modifies is satisfied
+func TestItemPolicySatisfied(t *testing.T) {
+	cm := newScriptedManager(t, scriptedPolicyManager{"fooPolicy": nil, "creators": nil})
+
+	err := cm.Apply(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			makeConfigurationEntry("foo", "fooPolicy", 1, []byte("bar")),
+			makeConfigurationEntry("bar", "barPolicy", 1, []byte("bar")),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Should not have errored applying config: %s", err)
+	}
+}
+
+// TestItemPolicyUnsatisfied tests that the whole configtx is rejected, naming the item and policy, when the policy of one item is not satisfied
+func TestItemPolicyUnsatisfied(t *testing.T) {
+	cm := newScriptedManager(t, scriptedPolicyManager{"fooPolicy": fmt.Errorf("unsatisfied"), "creators": nil})
+
+	err := cm.Apply(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			makeConfigurationEntry("bar", "barPolicy", 1, []byte("bar")),
+			makeConfigurationEntry("foo", "fooPolicy", 1, []byte("bar")),
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "policy fooPolicy of key foo") {
+		t.Fatalf("Should have errored naming the unsatisfied policy of foo, got %v", err)
+	}
+	if _, ok := cm.Get(ab.Configuration_Policy, "bar"); ok || cm.Sequence() != 0 {
+		t.Fatalf("The items of a rejected configuration should not have been applied")
+	}
+}
+
+// TestItemPolicyMissing tests that an item whose modification policy does not exist may not be modified
+func TestItemPolicyMissing(t *testing.T) {
+	cm := newScriptedManager(t, scriptedPolicyManager{"creators": nil})
+
+	err := cm.Apply(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "fooPolicy", 1, []byte("bar"))},
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("Should have errored modifying an item whose policy does not exist, got %v", err)
+	}
+}
+
+// TestItemPolicyDefault tests that new items are authorized by the configured default policy
+func TestItemPolicyDefault(t *testing.T) {
+	newItem := &ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			makeConfigurationEntry("foo", "fooPolicy", 0, []byte("foo")),
+			makeConfigurationEntry("bar", "barPolicy", 1, []byte("bar")),
+		},
+	}
+
+	spm := scriptedPolicyManager{"fooPolicy": nil, "creators": nil}
+	cm := newScriptedManager(t, spm)
+	spm["creators"] = fmt.Errorf("unsatisfied")
+	if err := cm.Validate(newItem); err == nil || !strings.Contains(err.Error(), "policy creators of key bar") {
+		t.Errorf("Should have errored creating an item against the unsatisfied default policy, got %v", err)
+	}
+
+	cm = newScriptedManager(t, scriptedPolicyManager{"fooPolicy": nil, "creators": nil})
+	if err := cm.Validate(newItem); err != nil {
+		t.Errorf("Should not have errored creating an item under the satisfied default policy: %s", err)
+	}
+}
+
+// TestMissingPolicyDefault tests that modifications authorized by missing policies are decided by the default of the policy manager
+func TestMissingPolicyDefault(t *testing.T) {
+	configs := map[string]*ab.ConfigurationEnvelope{
+		"created": {
+			Sequence: 1,
+			ChainID:  defaultChain,
+			Entries: []*ab.ConfigurationEntry{
+				makeConfigurationEntry("foo", "fooPolicy", 0, []byte("foo")),
+				makeConfigurationEntry("bar", "barPolicy", 1, []byte("bar")),
+			},
+		},
+		"modified": {
+			Sequence: 1,
+			ChainID:  defaultChain,
+			Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "fooPolicy", 1, []byte("bar"))},
+		},
+	}
+
+	for _, deny := range []bool{true, false} {
+		for name, config := range configs {
+			pm := policies.NewManagerImpl(nil)
+			pm.SetDefaultDeny(deny)
+			cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+				Sequence: 0,
+				ChainID:  defaultChain,
+				Entries:  []*ab.ConfigurationEntry{makeConfigurationEntry("foo", "fooPolicy", 0, []byte("foo"))},
+			}, pm, defaultHandlers())
+			if err != nil {
+				t.Fatalf("Error constructing configuration manager with default deny %v: %s", deny, err)
+			}
+
+			err = cm.Validate(config)
+			if deny && (err == nil || !strings.Contains(err.Error(), "does not exist")) {
+				t.Errorf("Should have errored on the %s item authorized by a missing policy, got %v", name, err)
+			}
+			if !deny && err != nil {
+				t.Errorf("Should not have errored on the %s item authorized by a missing policy: %s", name, err)
+			}
+		}
+	}
+}
+
+// newWindowedManager returns a manager whose clock stands an hour past the epoch, and the time it stands at
+func newWindowedManager(t *testing.T, skew time.Duration) (Manager, time.Time) {
+	clk := clock.NewFake()
+	clk.Advance(time.Hour)
+	cm, err := newConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+	}, &mockPolicyManager{&mockPolicy{}}, defaultHandlers(), DefaultModificationPolicyID, clk, skew)
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	return cm, clk.Now()
+}
+
+func windowedEnvelope(notBefore, notAfter time.Time) *ab.ConfigurationEnvelope {
+	configtx := &ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+	}
+	if !notBefore.IsZero() {
+		configtx.NotBefore = notBefore.UnixNano()
+	}
+	if !notAfter.IsZero() {
+		configtx.NotAfter = notAfter.UnixNano()
+	}
+	return configtx
+}
+
+// TestValidityWindowJustInside tests that a configtx whose window ends are exactly the skew away from the clock is valid
+func TestValidityWindowJustInside(t *testing.T) {
+	skew := time.Minute
+	cm, now := newWindowedManager(t, skew)
+
+	if err := cm.Validate(windowedEnvelope(now.Add(skew), time.Time{})); err != nil {
+		t.Errorf("A configtx valid from the clock plus the skew should be valid: %s", err)
+	}
+	if err := cm.Validate(windowedEnvelope(time.Time{}, now.Add(-skew))); err != nil {
+		t.Errorf("A configtx valid until the clock less the skew should be valid: %s", err)
+	}
+}
+
+// TestValidityWindowJustOutside tests that a configtx whose window ends just beyond the skew from the clock is rejected
+func TestValidityWindowJustOutside(t *testing.T) {
+	skew := time.Minute
+	cm, now := newWindowedManager(t, skew)
+
+	err := cm.Validate(windowedEnvelope(now.Add(skew+time.Nanosecond), time.Time{}))
+	if err == nil || !strings.Contains(err.Error(), "not valid until") {
+		t.Errorf("A configtx not yet valid should have been rejected as such, got: %v", err)
+	}
+
+	err = cm.Apply(windowedEnvelope(time.Time{}, now.Add(-skew-time.Nanosecond)))
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("An expired configtx should have been rejected as such, got: %v", err)
+	}
+	if cm.Sequence() != 0 {
+		t.Errorf("An expired configtx should not have been applied")
+	}
+}
+
+// TestValidityWindowStraddlesSkew tests that a window the clock is outside of, but only by less than the skew, is valid
+func TestValidityWindowStraddlesSkew(t *testing.T) {
+	configtx := func(now time.Time) *ab.ConfigurationEnvelope {
+		return windowedEnvelope(now.Add(30*time.Second), now.Add(2*time.Minute))
+	}
+
+	cm, now := newWindowedManager(t, 0)
+	if err := cm.Validate(configtx(now)); err == nil {
+		t.Errorf("Without a skew, a configtx not yet valid should have been rejected")
+	}
+
+	cm, now = newWindowedManager(t, time.Minute)
+	if err := cm.Validate(configtx(now)); err != nil {
+		t.Errorf("A configtx whose window begins within the skew should be valid: %s", err)
+	}
+}
+
+// TestValidityWindowUnbounded tests that a configtx without a window is valid whatever the clock reads
+func TestValidityWindowUnbounded(t *testing.T) {
+	cm, _ := newWindowedManager(t, 0)
+	if err := cm.Apply(windowedEnvelope(time.Time{}, time.Time{})); err != nil {
+		t.Errorf("A configtx without a window should always be valid: %s", err)
+	}
+}
+
+// TestValidityWindowNotCheckedOnRestart tests that the configtx a manager is created from may have expired
+func TestValidityWindowNotCheckedOnRestart(t *testing.T) {
+	clk := clock.NewFake()
+	clk.Advance(time.Hour)
+	expired := windowedEnvelope(time.Unix(1, 0), time.Unix(2, 0))
+	if _, err := newConfigurationManager(expired, &mockPolicyManager{&mockPolicy{}}, defaultHandlers(), DefaultModificationPolicyID, clk, 0); err != nil {
+		t.Errorf("The last applied configtx should be accepted on restart after it has expired: %s", err)
+	}
+}
+
+// TestSnapshot tests that a snapshot restates each item at its current value, with the signatures which set it, and
+// that a manager created from it is in the same state
+func TestSnapshot(t *testing.T) {
+	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			makeTypedConfigurationEntry(ab.Configuration_Orderer, "foo", 0, []byte("foo")),
+			makeTypedConfigurationEntry(ab.Configuration_Policy, "bar", 0, []byte("bar")),
+		},
+	}, &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	signed := makeTypedConfigurationEntry(ab.Configuration_Orderer, "foo", 1, []byte("baz"))
+	signed.Signatures = []*ab.SignedData{&ab.SignedData{Signature: []byte("signature")}}
+	err = cm.Apply(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			signed,
+			makeTypedConfigurationEntry(ab.Configuration_Policy, "bar", 0, []byte("bar")),
+			makeTypedConfigurationEntry(ab.Configuration_Policy, "aaa", 1, []byte("aaa")),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Should not have errored applying config: %s", err)
+	}
+
+	snapshot := cm.Snapshot()
+	expected := &ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			makeTypedConfigurationEntry(ab.Configuration_Policy, "aaa", 1, []byte("aaa")),
+			makeTypedConfigurationEntry(ab.Configuration_Policy, "bar", 0, []byte("bar")),
+			signed,
+		},
+	}
+	if !proto.Equal(snapshot, expected) {
+		t.Fatalf("Expected the snapshot to hold the items in order of type and ID at their current values, got %v", snapshot)
+	}
+
+	restored, err := NewConfigurationManager(snapshot, &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager from the snapshot: %s", err)
+	}
+	if restored.Sequence() != cm.Sequence() {
+		t.Errorf("Expected the restored sequence %d, got %d", cm.Sequence(), restored.Sequence())
+	}
+	if data, ok := restored.Get(ab.Configuration_Orderer, "foo"); !ok || string(data) != "baz" {
+		t.Errorf("Expected foo to be restored as baz, got %s", data)
+	}
+	if again := restored.Snapshot(); !proto.Equal(again, snapshot) {
+		t.Errorf("Expected the restored manager to take the same snapshot, got %v", again)
+	}
+}
+
+// TestExportGenesis tests that the exported genesis block is block 0, carrying the snapshot, and pointing to itself as the last configuration
+func TestExportGenesis(t *testing.T) {
+	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 3,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeTypedConfigurationEntry(ab.Configuration_Orderer, "foo", 2, []byte("foo"))},
+	}, &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	block, err := cm.ExportGenesis()
+	if err != nil {
+		t.Fatalf("Error exporting the genesis block: %s", err)
+	}
+	if block.Header.Number != 0 || len(block.Header.PreviousHash) != 0 || !bytes.Equal(block.Header.DataHash, block.Data.Hash()) {
+		t.Fatalf("Expected block 0 with a matching data hash, got %v", block.Header)
+	}
+	if last, ok := block.LastConfiguration(); !ok || last != 0 {
+		t.Fatalf("Expected the genesis block to be its own last configuration block, got %d", last)
+	}
+	if configTx := BlockConfiguration(block); !proto.Equal(configTx, cm.Snapshot()) {
+		t.Fatalf("Expected the genesis block to carry the snapshot at sequence 3, got %v", configTx)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtx
+
+import (
+	"fmt"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// MarshalConfigurationTransaction wraps configTx in a Transaction of the configuration type, as it is broadcast and committed
+func MarshalConfigurationTransaction(configTx *ab.ConfigurationEnvelope) ([]byte, error) {
+	envelope, err := proto.Marshal(configTx)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&ab.Transaction{Type: &ab.Transaction_ConfigurationEnvelope{ConfigurationEnvelope: envelope}})
+}
+
+// UnmarshalConfigurationTransaction returns the envelope of data if it is a Transaction of the configuration type, false
+// for any other message, and an error if it is of the configuration type but its envelope is malformed
+// The type of the Transaction is what distinguishes a configuration transaction, as almost any bytes unmarshal as an envelope
+func UnmarshalConfigurationTransaction(data []byte) (*ab.ConfigurationEnvelope, bool, error) {
+	tx := &ab.Transaction{}
+	if err := proto.Unmarshal(data, tx); err != nil {
+		return nil, false, nil
+	}
+	t, ok := tx.Type.(*ab.Transaction_ConfigurationEnvelope)
+	if !ok {
+		return nil, false, nil
+	}
+
+	configTx := &ab.ConfigurationEnvelope{}
+	if err := proto.Unmarshal(t.ConfigurationEnvelope, configTx); err != nil {
+		return nil, true, fmt.Errorf("Configuration envelope is malformed: %s", err)
+	}
+	return configTx, true, nil
+}
+
+// BlockConfiguration returns the configuration transaction a block carries, or nil if it carries none
+// Configuration transactions are always alone in their block
+func BlockConfiguration(block *ab.Block) *ab.ConfigurationEnvelope {
+	if len(block.GetData().GetMessages()) != 1 {
+		return nil
+	}
+
+	configTx, ok, err := UnmarshalConfigurationTransaction(block.Data.Messages[0].Data)
+	if err != nil {
+		logger.Warningf("Skipping block %d, whose configuration transaction cannot be used: %s", block.Header.Number, err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return configTx
+}
+
+// LastConfigurationNumber returns the number of the most recent block of rl which carries a configuration transaction
+// It is read from the metadata of the tail block, or found by scanning the chain if the tail block was written without it,
+// in which case the genesis block is assumed if no retained block carries one
+func LastConfigurationNumber(rl rawledger.Reader) (uint64, error) {
+	tailNumber := rl.Height() - 1
+	tail, status := rl.GetBlock(tailNumber)
+	if status != ab.Status_SUCCESS {
+		return 0, fmt.Errorf("Error reading the tail block %d: %v", tailNumber, status)
+	}
+	if number, ok := tail.LastConfiguration(); ok {
+		return number, nil
+	}
+
+	// Written before the pointer was recorded
+	logger.Warningf("The tail block %d records no pointer to the last configuration block, scanning the chain for it", tailNumber)
+	var last uint64
+	it, number := rl.Iterator(ab.SeekInfo_OLDEST, 0)
+	for ; number <= tailNumber; number++ {
+		block, status := it.Next()
+		if status != ab.Status_SUCCESS {
+			return 0, fmt.Errorf("Error reading block %d while scanning for the last configuration block: %v", number, status)
+		}
+		if BlockConfiguration(block) != nil {
+			last = block.Header.Number
+		}
+	}
+	return last, nil
+}
+
+// LastConfigTracker records in the metadata of each block it seals the number of the most recent configuration block
+// It is not safe for concurrent use, the blocks of a chain must be sealed in order by a single goroutine
+type LastConfigTracker struct {
+	last uint64
+}
+
+// NewLastConfigTracker returns a tracker for the blocks appended to rl, following from its tail block
+// It panics if the chain cannot be read, as the blocks appended could not point to the right configuration block
+func NewLastConfigTracker(rl rawledger.Reader) *LastConfigTracker {
+	last, err := LastConfigurationNumber(rl)
+	if err != nil {
+		panic(err)
+	}
+	return &LastConfigTracker{last: last}
+}
+
+// Last returns the number of the most recent configuration block sealed, or found in the chain before the tracker was created
+func (lct *LastConfigTracker) Last() uint64 {
+	return lct.last
+}
+
+// Seal is a rawledger.Sealer, a nil tracker leaves the metadata unset
+func (lct *LastConfigTracker) Seal(block *ab.Block) {
+	if lct == nil {
+		return
+	}
+
+	if BlockConfiguration(block) != nil {
+		lct.last = block.Header.Number
+	}
+	block.SetLastConfiguration(lct.last)
+}
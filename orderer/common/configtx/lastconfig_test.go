@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtx
+
+import (
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func configMessage(sequence uint64) *ab.BroadcastMessage {
+	data, err := MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{Sequence: sequence, ChainID: defaultChain})
+	if err != nil {
+		panic(err)
+	}
+	return &ab.BroadcastMessage{Data: data}
+}
+
+// appendChain appends a block after the genesis block for each entry of configs, carrying a configuration transaction if the entry is set
+func appendChain(rl rawledger.Writer, configs []bool, seal rawledger.Sealer) []*ab.Block {
+	var blocks []*ab.Block
+	for i, config := range configs {
+		msg := &ab.BroadcastMessage{Data: []byte("normal")}
+		if config {
+			msg = configMessage(uint64(i + 1))
+		}
+		blocks = append(blocks, rl.Append([]*ab.BroadcastMessage{msg}, seal))
+	}
+	return blocks
+}
+
+func TestLastConfigTracker(t *testing.T) {
+	genesis := ab.NewBlock(0, nil, []*ab.BroadcastMessage{configMessage(0)}, nil)
+	genesis.SetLastConfiguration(0)
+	rl := ramledger.New(10, genesis)
+
+	blocks := appendChain(rl, []bool{false, true, false, false, true, false}, NewLastConfigTracker(rl).Seal)
+	for i, expected := range []uint64{0, 2, 2, 2, 5, 5} {
+		if number, ok := blocks[i].LastConfiguration(); !ok || number != expected {
+			t.Fatalf("Expected block %d to point to configuration block %d, got %d", i+1, expected, number)
+		}
+	}
+
+	// A tracker created after a restart follows from the pointer of the tail block
+	appendChain(rl, []bool{false}, NewLastConfigTracker(rl).Seal)
+	if number, err := LastConfigurationNumber(rl); err != nil || number != 5 {
+		t.Fatalf("Expected the restarted tracker to keep pointing to block 5, got %d", number)
+	}
+}
+
+func TestLastConfigurationWithoutPointer(t *testing.T) {
+	rl := ramledger.New(10, ab.NewBlock(0, nil, []*ab.BroadcastMessage{configMessage(0)}, nil))
+	appendChain(rl, []bool{false, true, false}, nil)
+
+	if number, err := LastConfigurationNumber(rl); err != nil || number != 2 {
+		t.Fatalf("Expected a chain written without pointers to be scanned for configuration block 2, got %d", number)
+	}
+
+	// The first block sealed after such a chain points to the configuration block found by the scan
+	blocks := appendChain(rl, []bool{false}, NewLastConfigTracker(rl).Seal)
+	if number, ok := blocks[0].LastConfiguration(); !ok || number != 2 {
+		t.Fatalf("Expected the first tracked block to point to configuration block 2, got %d", number)
+	}
+}
+
+// TestImpostorConfiguration tests that only a message of the configuration type is taken for a configuration transaction
+func TestImpostorConfiguration(t *testing.T) {
+	// Proto unmarshaling is permissive, so this payload, which is not even a transaction, unmarshals as an envelope
+	untagged := []byte{0x08, 0x05}
+	if err := proto.Unmarshal(untagged, &ab.ConfigurationEnvelope{}); err != nil {
+		t.Fatalf("Expected the impostor to unmarshal as an envelope: %s", err)
+	}
+	opaque, _ := proto.Marshal(&ab.Transaction{Type: &ab.Transaction_Opaque{Opaque: configMessage(5).Data}})
+	malformed, _ := proto.Marshal(&ab.Transaction{Type: &ab.Transaction_ConfigurationEnvelope{ConfigurationEnvelope: []byte{0xff}}})
+
+	rl := ramledger.New(10, ab.NewBlock(0, nil, []*ab.BroadcastMessage{configMessage(0)}, nil))
+	for _, data := range [][]byte{untagged, opaque, malformed} {
+		block := rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: data}}, nil)
+		if BlockConfiguration(block) != nil {
+			t.Fatalf("Block %d should not have been taken to carry a configuration transaction", block.Header.Number)
+		}
+	}
+
+	if number, err := LastConfigurationNumber(rl); err != nil || number != 0 {
+		t.Fatalf("Expected the scan to skip the impostors and find the genesis configuration, got block %d", number)
+	}
+}
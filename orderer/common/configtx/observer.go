@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtx
+
+import (
+	"sort"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/op/go-logging"
+)
+
+var logger = logging.MustGetLogger("orderer/common/configtx")
+
+// Observer is called with the sequence number of each configtx applied, and the types of the config items it modified
+// Observers are called in the order they were registered, one configtx at a time and in sequence, once the configuration
+// may be queried, they must not apply a configtx themselves
+type Observer func(sequence uint64, changed []ab.Configuration_ConfigurationType)
+
+// RegisterObserver arranges for observer to be called after each configtx is applied
+func (cm *configurationManager) RegisterObserver(observer Observer) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	cm.observers = append(cm.observers[:len(cm.observers):len(cm.observers)], observer)
+}
+
+// notify calls observer, recovering from a panic so that one failing observer cannot keep the others from the change
+func notify(observer Observer, sequence uint64, changed []ab.Configuration_ConfigurationType) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Configuration observer panicked for sequence %d: %v", sequence, r)
+		}
+	}()
+	observer(sequence, changed)
+}
+
+// changedTypes returns, in order, the types with an item which was modified by the configtx of the given sequence number
+func changedTypes(configMap map[ab.Configuration_ConfigurationType]map[string]*ab.Configuration, sequence uint64) []ab.Configuration_ConfigurationType {
+	var changed []ab.Configuration_ConfigurationType
+	for ctype, items := range configMap {
+		for _, item := range items {
+			if item.LastModified == sequence {
+				changed = append(changed, ctype)
+				break
+			}
+		}
+	}
+	sort.Sort(byType(changed))
+	return changed
+}
+
+type byType []ab.Configuration_ConfigurationType
+
+func (s byType) Len() int           { return len(s) }
+func (s byType) Less(i, j int) bool { return s[i] < s[j] }
+func (s byType) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtx
+
+import (
+	"fmt"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+type notification struct {
+	sequence uint64
+	changed  []ab.Configuration_ConfigurationType
+	data     string // The data of item foo of type Chain, as queried by the observer
+}
+
+func newObservedManager(t *testing.T) (Manager, chan notification) {
+	cm, err := NewConfigurationManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			makeTypedConfigurationEntry(ab.Configuration_Chain, "foo", 0, []byte("0")),
+			makeTypedConfigurationEntry(ab.Configuration_Solo, "bar", 0, []byte("0")),
+		},
+	}, &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	notifications := make(chan notification, 10)
+	cm.RegisterObserver(func(sequence uint64, changed []ab.Configuration_ConfigurationType) {
+		data, _ := cm.Get(ab.Configuration_Chain, "foo")
+		notifications <- notification{sequence: sequence, changed: changed, data: string(data)}
+	})
+	return cm, notifications
+}
+
+// fooEnvelope modifies item foo of type Chain, leaving bar of type Solo as it was first configured
+func fooEnvelope(sequence uint64) *ab.ConfigurationEnvelope {
+	return &ab.ConfigurationEnvelope{
+		Sequence: sequence,
+		ChainID:  defaultChain,
+		Entries: []*ab.ConfigurationEntry{
+			makeTypedConfigurationEntry(ab.Configuration_Chain, "foo", sequence, []byte(fmt.Sprintf("%d", sequence))),
+			makeTypedConfigurationEntry(ab.Configuration_Solo, "bar", 0, []byte("0")),
+		},
+	}
+}
+
+// TestObserverNotified tests that an observer is told of each configtx in order, once its changes may be queried
+func TestObserverNotified(t *testing.T) {
+	cm, notifications := newObservedManager(t)
+
+	for i := uint64(1); i <= 3; i++ {
+		if err := cm.Apply(fooEnvelope(i)); err != nil {
+			t.Fatalf("Should not have errored applying config: %s", err)
+		}
+	}
+
+	for i := uint64(1); i <= 3; i++ {
+		n := <-notifications
+		if n.sequence != i {
+			t.Fatalf("Expected the notification of sequence %d, got %d", i, n.sequence)
+		}
+		if len(n.changed) != 1 || n.changed[0] != ab.Configuration_Chain {
+			t.Errorf("Expected only the Chain type to have changed, got %v", n.changed)
+		}
+		if n.data != fmt.Sprintf("%d", i) {
+			t.Errorf("Expected the observer to query the applied value %d, got %s", i, n.data)
+		}
+	}
+}
+
+// TestObserverNotNotifiedOfRejectedConfig tests that a configtx which is not applied is not observed
+func TestObserverNotNotifiedOfRejectedConfig(t *testing.T) {
+	cm, notifications := newObservedManager(t)
+
+	if err := cm.Apply(fooEnvelope(2)); err == nil {
+		t.Fatalf("Should have errored applying a config which skipped a sequence number")
+	}
+
+	select {
+	case n := <-notifications:
+		t.Fatalf("Should not have been notified of a rejected config, got %v", n)
+	default:
+	}
+}
+
+// TestObserverPanicRecovered tests that a panicking observer neither keeps later observers from being notified nor breaks the manager
+func TestObserverPanicRecovered(t *testing.T) {
+	cm, err := NewConfigurationManager(fooEnvelope(0), &mockPolicyManager{&mockPolicy{}}, defaultHandlers())
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	cm.RegisterObserver(func(sequence uint64, changed []ab.Configuration_ConfigurationType) {
+		panic("observer failed")
+	})
+	notified := make(chan uint64, 10)
+	cm.RegisterObserver(func(sequence uint64, changed []ab.Configuration_ConfigurationType) {
+		notified <- sequence
+	})
+
+	for i := uint64(1); i <= 2; i++ {
+		if err := cm.Apply(fooEnvelope(i)); err != nil {
+			t.Fatalf("Should not have errored applying config %d: %s", i, err)
+		}
+		if sequence := <-notified; sequence != i {
+			t.Fatalf("Expected the second observer to be notified of sequence %d, got %d", i, sequence)
+		}
+	}
+	if cm.Sequence() != 2 {
+		t.Fatalf("Expected sequence 2 after the observer panicked, got %d", cm.Sequence())
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtx
+
+import (
+	"fmt"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+)
+
+// Registry collects the handlers of the configuration types a Manager is to understand
+// Types beyond those known to the protocol may be registered by embedders, an item of a type with no handler is rejected
+type Registry struct {
+	handlers      map[ab.Configuration_ConfigurationType]Handler
+	defaultPolicy string
+	clock         clock.Clock
+	skew          time.Duration
+}
+
+// NewRegistry creates an empty Registry, whose managers authorize the creation of config items by DefaultModificationPolicyID
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers:      make(map[ab.Configuration_ConfigurationType]Handler),
+		defaultPolicy: DefaultModificationPolicyID,
+		clock:         clock.Real{},
+	}
+}
+
+// SetDefaultModificationPolicy sets the ID of the policy which authorizes the creation of config items, if it does not exist
+// every item may be created, as on a chain which has not been configured
+func (r *Registry) SetDefaultModificationPolicy(policyID string) {
+	r.defaultPolicy = policyID
+}
+
+// SetValiditySkew sets how far the clock of the orderer may disagree with the proposer of a configtx about its validity window
+func (r *Registry) SetValiditySkew(skew time.Duration) {
+	r.skew = skew
+}
+
+// SetClock replaces the clock against which the validity window of each configtx is checked
+func (r *Registry) SetClock(clk clock.Clock) {
+	r.clock = clk
+}
+
+// Register makes handler responsible for the items of type ctype, unless another handler already is
+func (r *Registry) Register(ctype ab.Configuration_ConfigurationType, handler Handler) error {
+	if _, ok := r.handlers[ctype]; ok {
+		return fmt.Errorf("A handler is already registered for type %v", ctype)
+	}
+	r.handlers[ctype] = handler
+	return nil
+}
+
+// NewManager creates a Manager from configtx, with the registered handlers and a BytesHandler tracking each known type which has no other handler
+func (r *Registry) NewManager(configtx *ab.ConfigurationEnvelope, pm policies.Manager) (Manager, error) {
+	return newConfigurationManager(configtx, pm, r.handlerMap(), r.defaultPolicy, r.clock, r.skew)
+}
+
+func (r *Registry) handlerMap() map[ab.Configuration_ConfigurationType]Handler {
+	handlers := make(map[ab.Configuration_ConfigurationType]Handler)
+	for ctype := range ab.Configuration_ConfigurationType_name {
+		handlers[ab.Configuration_ConfigurationType(ctype)] = NewBytesHandler()
+	}
+	for ctype, handler := range r.handlers {
+		handlers[ctype] = handler
+	}
+	return handlers
+}
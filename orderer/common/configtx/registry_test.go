@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configtx
+
+import (
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+const customType = ab.Configuration_ConfigurationType(100)
+
+// TestRegisteredCustomType tests that items of a type beyond the known ones reach the handler registered for it
+func TestRegisteredCustomType(t *testing.T) {
+	custom := NewBytesHandler()
+	registry := NewRegistry()
+	if err := registry.Register(customType, custom); err != nil {
+		t.Fatalf("Error registering handler: %s", err)
+	}
+
+	cm, err := registry.NewManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeTypedConfigurationEntry(customType, "foo", 0, []byte("foo"))},
+	}, &mockPolicyManager{&mockPolicy{}})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	if string(custom.GetBytes("foo")) != "foo" {
+		t.Fatalf("Expected the custom handler to have received the item")
+	}
+
+	err = cm.Apply(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeTypedConfigurationEntry(customType, "foo", 1, []byte("bar"))},
+	})
+	if err != nil {
+		t.Fatalf("Should not have errored applying config: %s", err)
+	}
+	if data, ok := cm.Get(customType, "foo"); !ok || string(data) != "bar" || string(custom.GetBytes("foo")) != "bar" {
+		t.Fatalf("Expected the custom item to have been updated to bar, got %s", data)
+	}
+}
+
+// TestUnknownTypeRejected tests that an item of a type with no registered handler fails validation
+func TestUnknownTypeRejected(t *testing.T) {
+	cm, err := NewRegistry().NewManager(&ab.ConfigurationEnvelope{
+		Sequence: 0,
+		ChainID:  defaultChain,
+	}, &mockPolicyManager{&mockPolicy{}})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	err = cm.Validate(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  defaultChain,
+		Entries:  []*ab.ConfigurationEntry{makeTypedConfigurationEntry(customType, "foo", 1, []byte("foo"))},
+	})
+	if err == nil {
+		t.Fatalf("Should have errored validating an item of a type with no handler")
+	}
+}
+
+// TestDuplicateRegistration tests that a second handler may not be registered for a type
+func TestDuplicateRegistration(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(ab.Configuration_Policy, NewBytesHandler()); err != nil {
+		t.Fatalf("Error registering handler: %s", err)
+	}
+	if err := registry.Register(ab.Configuration_Policy, NewBytesHandler()); err == nil {
+		t.Fatalf("Should have errored registering a second handler for the same type")
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consenter
+
+import (
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+// Chain orders the messages of one chain, the blocks it cuts are written to its ledger or broker
+type Chain interface {
+	// Enqueue submits a message for ordering, returning false if it could not be accepted
+	Enqueue(msg *ab.BroadcastMessage) bool
+
+	// Start begins ordering, messages may be enqueued only once the chain is started
+	Start()
+
+	// Halt stops ordering, messages which have been enqueued but not yet cut may be lost
+	Halt()
+
+	// Errored returns a channel which is closed once the chain can no longer order messages
+	Errored() <-chan struct{}
+}
+
+// StatusChain is implemented by Chains which explain why they cannot accept a message, or reply to some of those
+// messages with a status other than SERVICE_UNAVAILABLE
+type StatusChain interface {
+	Chain
+	// EnqueueReply submits a message for ordering, returning a reply of SUCCESS, or of why it could not be accepted
+	EnqueueReply(msg *ab.BroadcastMessage) *ab.BroadcastResponse
+}
+
+// StreamChain is implemented by Chains which queue the messages of each Broadcast stream apart, so that the streams are
+// ordered fairly, and which may reply to a message only once it is committed
+type StreamChain interface {
+	Chain
+	// NewStream opens the queue of the messages of one Broadcast stream
+	NewStream() Stream
+}
+
+// Stream enqueues the messages of one Broadcast stream on a StreamChain
+type Stream interface {
+	// Enqueue submits a message which has been filtered for ordering, reply has room for its response, which is sent
+	// once the message is queued, or once it is committed if the chain acknowledges after commit
+	Enqueue(msg *ab.BroadcastMessage, reply chan *ab.BroadcastResponse)
+	// Close releases the stream once no further message is enqueued, the messages already queued are still ordered
+	Close()
+}
+
+// Consenter returns the chains it orders
+type Consenter interface {
+	// Chain returns the chain with the given ID, the default chain if the ID is empty, or false if there is no such chain
+	Chain(chainID []byte) (Chain, bool)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// encodeCursor marshals a cursor into the token sent to clients, followed by its HMAC if key is set
+func encodeCursor(cursor *ab.Cursor, key []byte) []byte {
+	token, err := proto.Marshal(cursor)
+	if err != nil {
+		panic(err)
+	}
+	if len(key) == 0 {
+		return token
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(token)
+	return mac.Sum(token)
+}
+
+// decodeCursor returns the cursor encoded in a token, or false if the token is malformed or its HMAC does not verify
+func decodeCursor(token []byte, key []byte) (*ab.Cursor, bool) {
+	if len(key) > 0 {
+		if len(token) < sha256.Size {
+			return nil, false
+		}
+		payload, sum := token[:len(token)-sha256.Size], token[len(token)-sha256.Size:]
+		mac := hmac.New(sha256.New, key)
+		mac.Write(payload)
+		if !hmac.Equal(sum, mac.Sum(nil)) {
+			return nil, false
+		}
+		token = payload
+	}
+
+	cursor := &ab.Cursor{}
+	if err := proto.Unmarshal(token, cursor); err != nil {
+		return nil, false
+	}
+	return cursor, true
+}
@@ -0,0 +1,793 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+)
+
+var logger = logging.MustGetLogger("orderer/common/deliver")
+
+// defaultFinishTimeout bounds the send of the terminal status of a stream, which a client that has stopped reading may never take
+const defaultFinishTimeout = time.Second
+
+// DefaultSeekSkew is how far the Timestamp of a signed seek may be from the clock of the orderer, unless the Options set another skew
+const DefaultSeekSkew = time.Minute
+
+// LedgerResolver returns the ledger of the given chain, the default chain if the ID is empty, or false if the chain does not exist
+type LedgerResolver func(chainID []byte) (rawledger.Reader, bool)
+
+// Options configures a Server, zero values of the optional fields disable the feature they control
+type Options struct {
+	MaxWindowSize       int           // The largest window a client may request
+	DefaultWindowSize   int           // The window of seeks which give none, not enforced, zero to refuse such seeks
+	MaxIdleTime         time.Duration // How long a client may leave its window exhausted before it is evicted
+	MaxLag              int           // How many blocks further behind the tail a client may fall before it is evicted
+	HeartbeatInterval   time.Duration // How long a stream may be idle before a heartbeat is sent
+	CursorKey           []byte        // The key with which the cursors sent to clients are authenticated, if empty they are not
+	MaxSendMsgSize      int           // The largest response sent, zero for no limit
+	Features            ab.Features   // The protocol features streams may negotiate, a stream without a hello uses none
+	MaxStreams          int           // The number of streams which may be open at once, zero for no limit
+	MaxStreamsPerClient int           // The number of streams each client may have open at once, zero for no limit
+	RetryAfter          time.Duration // How long clients whose stream is not admitted are asked to wait before retrying
+
+	// Policies resolves the Readers policy which the signatures of seeks must satisfy, if nil seeks are not checked
+	Policies policies.ManagerResolver
+	// SeekSkew is how far the Timestamp of a signed seek may be from the clock of the orderer, DefaultSeekSkew if zero
+	SeekSkew time.Duration
+	// Decisions records each decision of the Readers policies, if nil every denial is logged
+	Decisions *audit.DecisionLog
+}
+
+// Server serves the Deliver streams of the chains of a consenter from their ledgers
+type Server struct {
+	ledger            LedgerResolver
+	maxWindow         int
+	maxIdleTime       time.Duration
+	maxLag            uint64
+	heartbeatInterval time.Duration
+	cursorKey         []byte // The HMAC key of the cursors sent to clients, they are not authenticated if empty
+	limiter           *streamLimiter
+	maxSendBytes      int                      // The largest response sent, a larger block ends the stream, zero for no limit
+	defaultWindow     int                      // The window of seeks which give none, not enforced, zero to refuse such seeks
+	enabled           ab.Features              // The features streams may negotiate, every feature unless restricted after construction
+	policies          policies.ManagerResolver // Resolves the Readers policy of each chain sought, if nil every seek is authorized
+	decisions         *audit.DecisionLog       // Records each decision of the Readers policies
+	seekSkew          time.Duration            // How far the Timestamp of a signed seek may be from clock
+	clock             clock.Clock
+	evicted           uint64                   // Accessed atomically
+	finishTimeout     time.Duration
+	lock              sync.Mutex // Guards stopped, so that no stream is added to streams once shutdown waits on it
+	stopped           bool
+	streams           sync.WaitGroup
+	stopChan          chan struct{}
+}
+
+// NewServer creates a Server which serves each seek from the ledger of the chain it names
+func NewServer(ledgers LedgerResolver, opts Options) *Server {
+	ds := newMultiChainDeliverServer(ledgers, opts.MaxWindowSize, opts.MaxIdleTime, uint64(opts.MaxLag), opts.HeartbeatInterval, opts.CursorKey, newStreamLimiter(opts.MaxStreams, opts.MaxStreamsPerClient, opts.RetryAfter))
+	ds.maxSendBytes = opts.MaxSendMsgSize
+	ds.defaultWindow = opts.DefaultWindowSize
+	ds.enabled = opts.Features
+	ds.policies = opts.Policies
+	ds.decisions = opts.Decisions
+	if opts.SeekSkew > 0 {
+		ds.seekSkew = opts.SeekSkew
+	}
+	return ds
+}
+
+// newDeliverServer creates a Server which serves every seek from rl, regardless of its chain ID
+func newDeliverServer(rl rawledger.Reader, maxWindow int, maxIdleTime time.Duration, maxLag uint64, heartbeatInterval time.Duration, cursorKey []byte, limiter *streamLimiter) *Server {
+	return newMultiChainDeliverServer(func(chainID []byte) (rawledger.Reader, bool) { return rl, true }, maxWindow, maxIdleTime, maxLag, heartbeatInterval, cursorKey, limiter)
+}
+
+// newMultiChainDeliverServer creates a Server which serves each seek from the ledger of the chain it names
+// The number of concurrent streams is bounded by limiter, which may be nil to admit every stream
+func newMultiChainDeliverServer(ledger LedgerResolver, maxWindow int, maxIdleTime time.Duration, maxLag uint64, heartbeatInterval time.Duration, cursorKey []byte, limiter *streamLimiter) *Server {
+	return &Server{
+		ledger:            ledger,
+		maxWindow:         maxWindow,
+		maxIdleTime:       maxIdleTime,
+		maxLag:            maxLag,
+		heartbeatInterval: heartbeatInterval,
+		cursorKey:         cursorKey,
+		limiter:           limiter,
+		enabled:           ab.AllFeatures,
+		seekSkew:          DefaultSeekSkew,
+		clock:             clock.Real{},
+		finishTimeout:     defaultFinishTimeout,
+		stopChan:          make(chan struct{}),
+	}
+}
+
+// evictedCount returns the number of streams which have been closed for failing to make progress
+func (ds *Server) evictedCount() uint64 {
+	return atomic.LoadUint64(&ds.evicted)
+}
+
+// Shutdown ends every stream with SERVICE_UNAVAILABLE, waiting up to the finish timeout for the statuses to be sent,
+// so that the clients can tell the orderer stopping from the network failing
+func (ds *Server) Shutdown() {
+	ds.lock.Lock()
+	if !ds.stopped {
+		ds.stopped = true
+		close(ds.stopChan)
+	}
+	ds.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ds.streams.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(ds.finishTimeout):
+		logger.Warningf("Deliver streams did not end within %v of the shutdown", ds.finishTimeout)
+	}
+}
+
+// Handle sends the blocks a client seeks until the stream ends, clients beyond the stream limits are refused
+func (ds *Server) Handle(srv ab.AtomicBroadcast_DeliverServer) error {
+	var client string
+	if ds.limiter != nil {
+		client = audit.ClientIdentity(srv.Context())
+	}
+	if !ds.limiter.admit(client) {
+		// Rejected immediately rather than queued, so that a replay storm cannot accumulate waiting streams
+		logger.Warningf("Rejecting Deliver stream from client '%s' which would exceed the stream limits", client)
+		reply := ab.ReasonBackpressure.DeliverResponse("too many deliver streams")
+		reply.RetryAfter = uint64(ds.limiter.retryAfter / time.Millisecond)
+		return srv.Send(reply)
+	}
+	defer ds.limiter.release(client)
+
+	ds.lock.Lock()
+	if ds.stopped {
+		ds.lock.Unlock()
+		return srv.Send(ab.ReasonUnavailable.DeliverResponse("orderer is shutting down"))
+	}
+	ds.streams.Add(1)
+	ds.lock.Unlock()
+	defer ds.streams.Done()
+
+	logger.Debugf("Starting new Deliver loop")
+	d := newDeliverer(ds, srv)
+	return d.recv()
+
+}
+
+type deliverer struct {
+	ds              *Server
+	srv             ab.AtomicBroadcast_DeliverServer
+	rl              rawledger.Reader // The ledger of the most recently sought chain
+	chainID         []byte           // The ID of the most recently sought chain, as requested
+	cursor          rawledger.Iterator
+	nextBlockNumber uint64
+	windowSize      uint64
+	compat          bool        // Whether each block is treated as acknowledged once sent, for a client which sent no window
+	features        ab.Features // The features negotiated by the hello of the stream, none for a legacy client
+	greeted         bool        // Whether an update has been received, after which a hello is refused
+	lastAck         uint64
+	bestLag         uint64
+	content         ab.SeekInfo_ContentType
+	bounded         bool // Whether the stream is closed once stopNumber has been sent
+	stopNumber      uint64
+	finished        bool  // Whether the terminal status was sent, or a send failed so that none can be
+	recvErr         error // Why the client's side of the stream ended, set before exitChan is closed
+	recvChan        chan *ab.DeliverUpdate
+	exitChan        chan struct{}
+	doneChan        chan struct{}
+	haltOnce        sync.Once
+}
+
+func newDeliverer(ds *Server, srv ab.AtomicBroadcast_DeliverServer) *deliverer {
+	d := &deliverer{
+		ds:       ds,
+		srv:      srv,
+		exitChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+		recvChan: make(chan *ab.DeliverUpdate),
+	}
+	// Heartbeats report the height of the default chain until the client seeks
+	d.rl, _ = ds.ledger(nil)
+	go d.main()
+	return d
+}
+
+func (d *deliverer) halt() {
+	d.haltOnce.Do(func() { close(d.exitChan) })
+}
+
+func (d *deliverer) main() {
+	defer close(d.doneChan)
+	defer d.halt()
+	var signal <-chan struct{}
+	var idleTimer <-chan time.Time
+	heartbeat := d.nextHeartbeat()
+	for {
+		select {
+		case update := <-d.recvChan:
+			logger.Debugf("Receiving message %v", update)
+			first := !d.greeted
+			d.greeted = true
+			switch t := update.Type.(type) {
+			case *ab.DeliverUpdate_Hello:
+				if !d.processHello(t.Hello, first) {
+					return
+				}
+			case *ab.DeliverUpdate_Acknowledgement:
+				logger.Debugf("Received acknowledgement from client")
+				lastAck := d.lastAck
+				if !d.processAck(t.Acknowledgement) {
+					return
+				}
+				if d.lastAck != lastAck {
+					idleTimer = nil
+				}
+			case *ab.DeliverUpdate_Seek:
+				if !d.processUpdate(t.Seek) {
+					return
+				}
+			case *ab.DeliverUpdate_WindowUpdate:
+				if !d.processWindowUpdate(t.WindowUpdate) {
+					return
+				}
+			case nil:
+				logger.Errorf("Nil update")
+				d.sendErrorReply(ab.ReasonMalformed, "update is empty")
+				return
+			default:
+				logger.Errorf("Unknown type: %T:%v", t, t)
+				d.sendErrorReply(ab.ReasonMalformed, "update type %T is unknown", t)
+				return
+			}
+		case <-signal:
+			block, status := d.cursor.Next()
+			if status != ab.Status_SUCCESS {
+				logger.Errorf("Error reading from channel, cause was: %v", status)
+				d.sendErrorReply(ab.LedgerReason(status), "reading block %d failed with %v", d.nextBlockNumber, status)
+				return
+			} else {
+				d.nextBlockNumber = block.Header.Number + 1
+				if !d.sendBlockReply(block) {
+					return
+				}
+				if d.compat {
+					d.lastAck = block.Header.Number
+				}
+				if d.bounded && block.Header.Number >= d.stopNumber {
+					logger.Debugf("Delivered the requested range, closing the stream")
+					d.sendDoneReply()
+					return
+				}
+			}
+			heartbeat = d.nextHeartbeat()
+		case <-heartbeat:
+			if !d.sendHeartbeatReply() {
+				return
+			}
+			heartbeat = d.nextHeartbeat()
+		case <-d.ds.stopChan:
+			logger.Debugf("Orderer shutting down, ending the stream")
+			d.sendErrorReply(ab.ReasonUnavailable, "orderer is shutting down")
+			return
+		case <-idleTimer:
+			logger.Warningf("Client failed to acknowledge block %d within %v, evicting", d.lastAck+1, d.ds.maxIdleTime)
+			d.evict()
+			return
+		case <-d.exitChan:
+			if d.recvErr == io.EOF {
+				// The client has no further updates, the stream is ended rather than served without them
+				d.sendDoneReply()
+			} else {
+				// The client most likely canceled the stream, in which case this send fails
+				d.sendErrorReply(ab.ReasonUnavailable, "stream ended: %v", d.recvErr)
+			}
+			return
+		}
+
+		if d.cursor == nil {
+			signal = nil
+			idleTimer = nil
+			continue
+		}
+
+		if !d.checkLag() {
+			return
+		}
+
+		if d.lastAck+d.windowSize < d.nextBlockNumber {
+			logger.Debugf("Window exhausted, waiting for acknowledgement")
+			signal = nil
+			if idleTimer == nil && d.ds.maxIdleTime > 0 {
+				idleTimer = time.After(d.ds.maxIdleTime)
+			}
+			continue
+		}
+		idleTimer = nil
+
+		logger.Debugf("Room for more blocks, activating channel")
+		signal = d.cursor.ReadyChan()
+	}
+}
+
+func (d *deliverer) recv() error {
+	errChan := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := d.srv.Recv()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			logger.Debugf("Received message %v", msg)
+			select {
+			case <-d.exitChan:
+				return
+			case d.recvChan <- msg:
+				logger.Debugf("Sent update")
+			}
+		}
+	}()
+
+	select {
+	case err := <-errChan:
+		d.recvErr = err
+		d.halt()
+		// Returning ends the stream, so the terminal status is given the time to be sent
+		select {
+		case <-d.doneChan:
+		case <-time.After(d.ds.finishTimeout):
+		}
+		return err
+	case <-d.doneChan:
+		return nil // something has gone wrong enough, or the stream has reached a terminal status, so we disconnect
+	}
+}
+
+// send sends a reply which does not end the stream, a failure marks the stream finished as no status can follow it
+func (d *deliverer) send(reply *ab.DeliverResponse) bool {
+	if err := d.srv.Send(reply); err != nil {
+		logger.Debugf("Error sending to the client, the stream is broken: %s", err)
+		d.finished = true
+		return false
+	}
+	return true
+}
+
+// finish sends the terminal status of the stream, every exit of main which is not caused by a failed send passes through it
+// Unless the transport has broken, the client thereby learns why the stream ended; the send is abandoned after the
+// finish timeout, so that a client which has stopped reading cannot hold the stream open
+func (d *deliverer) finish(reply *ab.DeliverResponse) {
+	if d.finished {
+		return
+	}
+	d.finished = true
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.srv.Send(reply)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			logger.Debugf("Error sending the terminal status %v: %s", reply.GetError(), err)
+		}
+	case <-time.After(d.ds.finishTimeout):
+		logger.Warningf("Abandoned sending the terminal status %v after %v", reply.GetError(), d.ds.finishTimeout)
+	}
+}
+
+// sendErrorReply ends the stream with the status of the reason, with a detail formatted from format and args
+func (d *deliverer) sendErrorReply(reason ab.Reason, format string, args ...interface{}) {
+	d.finish(reason.DeliverResponse(format, args...))
+}
+
+// sendDoneReply ends the stream with SUCCESS, once every block requested has been delivered
+func (d *deliverer) sendDoneReply() {
+	d.finish(&ab.DeliverResponse{
+		Type: &ab.DeliverResponse_Error{Error: ab.Status_SUCCESS},
+	})
+}
+
+func (d *deliverer) sendBlockReply(block *ab.Block) bool {
+	cursor := encodeCursor(&ab.Cursor{
+		ChainID:    d.chainID,
+		Number:     block.Header.Number,
+		Hash:       block.Hash(),
+		WindowSize: d.windowSize,
+		Content:    d.content,
+	}, d.ds.cursorKey)
+
+	if d.content == ab.SeekInfo_HEADERS_ONLY {
+		// The block may be shared with the ledger, so a stripped copy is sent rather than modifying it
+		block = &ab.Block{Header: block.Header, Metadata: block.Metadata}
+	}
+
+	reply := &ab.DeliverResponse{
+		Type:   &ab.DeliverResponse_Block{Block: block},
+		Cursor: cursor,
+	}
+
+	// Blocks are cut within the limit, but one cut before the limit was lowered may exceed it
+	if size := proto.Size(reply); d.ds.maxSendBytes > 0 && size > d.ds.maxSendBytes {
+		logger.Warningf("Block %d is %d bytes, beyond the largest response of %d bytes which may be sent", block.Header.Number, size, d.ds.maxSendBytes)
+		d.sendErrorReply(ab.ReasonBlockTooLarge, "block %d of %d bytes exceeds the limit %d", block.Header.Number, size, d.ds.maxSendBytes)
+		return false
+	}
+
+	return d.send(reply)
+}
+
+// lag returns the number of blocks between the next block to be sent and the tail of the chain
+func (d *deliverer) lag() uint64 {
+	height := d.rl.Height()
+	if height <= d.nextBlockNumber {
+		return 0
+	}
+	return height - d.nextBlockNumber
+}
+
+// checkLag evicts the client if it has fallen more than maxLag blocks further behind the tail than it has previously been,
+// so that a client replaying a long chain is not penalized for the length of the range it requested
+func (d *deliverer) checkLag() bool {
+	if d.ds.maxLag == 0 {
+		return true
+	}
+
+	lag := d.lag()
+	if lag < d.bestLag {
+		d.bestLag = lag
+	}
+
+	if lag-d.bestLag > d.ds.maxLag {
+		logger.Warningf("Client has fallen %d blocks further behind the tail, evicting", lag-d.bestLag)
+		d.evict()
+		return false
+	}
+
+	return true
+}
+
+// evict sends a terminal status to a client which is failing to make progress and releases its cursor
+func (d *deliverer) evict() {
+	atomic.AddUint64(&d.ds.evicted, 1)
+	d.cursor = nil
+	d.sendErrorReply(ab.ReasonUnavailable, "client evicted for failing to keep up at block %d", d.nextBlockNumber)
+}
+
+// nextHeartbeat returns a channel which fires once the stream has been idle for the heartbeat interval, or nil if heartbeats are disabled
+func (d *deliverer) nextHeartbeat() <-chan time.Time {
+	if d.ds.heartbeatInterval == 0 {
+		return nil
+	}
+	return time.After(d.ds.heartbeatInterval)
+}
+
+func (d *deliverer) sendHeartbeatReply() bool {
+	heartbeat := &ab.Heartbeat{}
+	if d.rl != nil {
+		heartbeat.Height = d.rl.Height()
+	}
+	return d.send(&ab.DeliverResponse{
+		Type: &ab.DeliverResponse_Heartbeat{Heartbeat: heartbeat},
+	})
+}
+
+// processHello negotiates the features of the stream, returning false if the hello was not the first update
+func (d *deliverer) processHello(hello *ab.Hello, first bool) bool {
+	if !first {
+		d.sendErrorReply(ab.ReasonMalformed, "a hello may only be the first update of a stream")
+		return false
+	}
+
+	d.features = d.ds.enabled.Negotiate(hello)
+	logger.Debugf("Negotiated features %v", d.features.Hello().Features)
+	return d.send(&ab.DeliverResponse{Type: &ab.DeliverResponse_Hello{Hello: d.features.Hello()}})
+}
+
+// downgrade clears the parts of a seek which the features of the stream do not cover, returning false,
+// having sent BAD_REQUEST, if it names a chain without having negotiated chain routing
+func (d *deliverer) downgrade(update *ab.SeekInfo) bool {
+	if !d.features.Has(ab.Feature_CHAIN_ROUTING) && len(update.ChainID) > 0 {
+		d.sendErrorReply(ab.ReasonMalformed, "chain %x may only be sought once CHAIN_ROUTING is negotiated", update.ChainID)
+		return false
+	}
+	if !d.features.Has(ab.Feature_FILTERED_DELIVERY) {
+		update.Content = ab.SeekInfo_FULL
+	}
+	return true
+}
+
+// processAck advances the base of the window, returning false if the client acknowledged a block it was never sent
+func (d *deliverer) processAck(ack *ab.Acknowledgement) bool {
+	if !d.features.Has(ab.Feature_DELIVER_ACKS) {
+		logger.Debugf("Ignoring acknowledgement from a client which did not negotiate them")
+		return true
+	}
+
+	if d.cursor != nil && ack.Number > d.nextBlockNumber {
+		logger.Warningf("Client acknowledged block %d but the next block to be sent is %d", ack.Number, d.nextBlockNumber)
+		d.sendErrorReply(ab.ReasonMalformed, "acknowledged block %d exceeds next block %d", ack.Number, d.nextBlockNumber)
+		return false
+	}
+
+	// Acknowledgements which do not advance the window are ignored, note the initial lastAck may have wrapped to represent -1
+	if ack.Number+1 > d.lastAck+1 {
+		d.lastAck = ack.Number
+	}
+	// A client which acknowledges understands the window
+	d.compat = false
+
+	return true
+}
+
+// processWindowUpdate resizes the window of the current seek, returning false if there is no seek or the size is out of range
+func (d *deliverer) processWindowUpdate(update *ab.WindowUpdate) bool {
+	if !d.features.Has(ab.Feature_DELIVER_ACKS) {
+		logger.Debugf("Ignoring window update from a client which did not negotiate acknowledgements")
+		return true
+	}
+
+	if d.cursor == nil {
+		d.sendErrorReply(ab.ReasonMalformed, "window update without a seek")
+		return false
+	}
+
+	if update.WindowSize == 0 || update.WindowSize > uint64(d.ds.maxWindow) {
+		d.sendErrorReply(ab.ReasonMalformed, "window size %d is outside of 1 to %d", update.WindowSize, d.ds.maxWindow)
+		return false
+	}
+
+	d.windowSize = update.WindowSize
+	d.compat = false
+	return true
+}
+
+// processUpdate returns false if the stream should be closed with whatever status has been sent
+func (d *deliverer) processUpdate(update *ab.SeekInfo) bool {
+	if d.cursor != nil {
+		d.cursor = nil
+	}
+	logger.Debugf("Updating properties for client")
+
+	if update == nil {
+		d.sendErrorReply(ab.ReasonMalformed, "seek is missing")
+		return false
+	}
+	if !d.downgrade(update) {
+		return false
+	}
+
+	if len(update.Cursor) > 0 {
+		return d.resume(update)
+	}
+
+	windowSize, compat := update.WindowSize, false
+	switch {
+	case !d.features.Has(ab.Feature_DELIVER_ACKS):
+		// A legacy client never acknowledges, whatever window it asks for
+		windowSize, compat = 1, true
+	case windowSize == 0 && d.ds.defaultWindow > 0:
+		windowSize, compat = uint64(d.ds.defaultWindow), true
+	}
+	if !compat && (windowSize == 0 || windowSize > uint64(d.ds.maxWindow)) {
+		d.sendErrorReply(ab.ReasonMalformed, "window size %d is outside of 1 to %d", windowSize, d.ds.maxWindow)
+		return false
+	}
+
+	rl, ok := d.ds.ledger(update.ChainID)
+	if !ok {
+		logger.Debugf("Client requested unknown chain %x", update.ChainID)
+		d.sendErrorReply(ab.ReasonUnknownChain, "chain %x does not exist", update.ChainID)
+		return false
+	}
+	if !d.authorize(update.ChainID, update) {
+		return false
+	}
+	d.rl = rl
+	d.chainID = update.ChainID
+
+	start, specified := update.Start, update.SpecifiedNumber
+	if start == ab.SeekInfo_TIMESTAMP {
+		timestamp := time.Unix(0, update.StartTimestamp)
+		number, status := rawledger.SeekTimestamp(d.rl, timestamp)
+		if status == ab.Status_NOT_FOUND {
+			logger.Debugf("Client requested timestamp %v which is after the newest block", timestamp)
+			d.sendErrorReply(ab.ReasonNotRetained, "seek timestamp %v is after the newest block", timestamp)
+			return false
+		}
+		if status != ab.Status_SUCCESS {
+			d.sendErrorReply(ab.ReasonUnavailable, "seek timestamp %v could not be resolved: %s", timestamp, status)
+			return false
+		}
+		start, specified = ab.SeekInfo_SPECIFIED, number
+	}
+
+	if start == ab.SeekInfo_SPECIFIED {
+		height := d.rl.Height()
+		if specified > height {
+			logger.Debugf("Client requested block %d which is beyond the next block %d", specified, height)
+			d.sendErrorReply(ab.ReasonNotRetained, "seek target %d exceeds height %d", specified, height)
+			return false
+		}
+
+		oldest := d.rl.OldestRetained()
+		if specified < oldest {
+			logger.Debugf("Client requested block %d which is no longer retained, the oldest available block is %d", specified, oldest)
+			d.sendErrorReply(ab.ReasonNotRetained, "seek target %d precedes oldest retained block %d", specified, oldest)
+			return false
+		}
+	}
+
+	d.windowSize = windowSize
+	d.compat = compat
+	d.content = update.Content
+
+	d.cursor, d.nextBlockNumber = d.rl.Iterator(start, specified)
+	d.lastAck = d.nextBlockNumber - 1
+	d.bestLag = d.lag()
+
+	return d.setStop(update)
+}
+
+// authorize returns false, having sent FORBIDDEN, if the signatures of a seek of chainID do not satisfy the Readers policy of the chain,
+// or if the seek was signed further from the clock of the orderer than the skew allows, so that signatures captured from a seek soon expire
+func (d *deliverer) authorize(chainID []byte, seek *ab.SeekInfo) bool {
+	if d.ds.policies == nil {
+		return true
+	}
+	if err := d.checkSeekTimestamp(seek); err != nil {
+		d.ds.decisions.Record(audit.NewDecision(audit.PointDeliver, policies.ReadersPolicyID, chainID, audit.ClientIdentity(d.srv.Context()), cauthdsl.Signers(seek.Signatures), err))
+		logger.Debugf("Client seek of chain %x is stale: %s", chainID, err)
+		d.sendErrorReply(ab.ReasonForbidden, "seek timestamp is outside of the allowed clock skew of %v", d.ds.seekSkew)
+		return false
+	}
+	err := policies.Authorize(d.ds.policies, chainID, policies.ReadersPolicyID, seek.SignedBytes(), seek.Signatures)
+	d.ds.decisions.Record(audit.NewDecision(audit.PointDeliver, policies.ReadersPolicyID, chainID, audit.ClientIdentity(d.srv.Context()), cauthdsl.Signers(seek.Signatures), err))
+	if err != nil {
+		logger.Debugf("Client seek of chain %x does not satisfy policy %s: %s", chainID, policies.ReadersPolicyID, err)
+		d.sendErrorReply(ab.ReasonForbidden, "seek is not authorized by policy %s of the chain", policies.ReadersPolicyID)
+		return false
+	}
+	return true
+}
+
+// checkSeekTimestamp returns an error if a signed seek was signed further from the clock of the orderer than the skew allows
+// An unsigned seek carries nothing to replay, and satisfies only a policy which requires no signature
+func (d *deliverer) checkSeekTimestamp(seek *ab.SeekInfo) error {
+	if len(seek.Signatures) == 0 {
+		return nil
+	}
+	signed := time.Unix(0, seek.Timestamp)
+	if skew := d.ds.clock.Now().Sub(signed); skew > d.ds.seekSkew || skew < -d.ds.seekSkew {
+		return fmt.Errorf("Seek was signed at %v, %v from the clock of the orderer, beyond the allowed skew of %v", signed, skew, d.ds.seekSkew)
+	}
+	return nil
+}
+
+// resume positions the stream after the block a cursor was sent with, returning false if the cursor is invalid,
+// or if the chain no longer retains that block or has diverged from it
+func (d *deliverer) resume(update *ab.SeekInfo) bool {
+	cursor, ok := decodeCursor(update.Cursor, d.ds.cursorKey)
+	if !ok || (len(update.ChainID) > 0 && !bytes.Equal(update.ChainID, cursor.ChainID)) {
+		logger.Warningf("Client sent a cursor which is malformed, was not issued by this orderer, or is for another chain")
+		d.sendErrorReply(ab.ReasonMalformed, "cursor is invalid")
+		return false
+	}
+
+	windowSize, content := update.WindowSize, update.Content
+	if windowSize == 0 {
+		windowSize, content = cursor.WindowSize, cursor.Content
+	}
+	if windowSize == 0 || windowSize > uint64(d.ds.maxWindow) {
+		d.sendErrorReply(ab.ReasonMalformed, "window size %d is outside of 1 to %d", windowSize, d.ds.maxWindow)
+		return false
+	}
+
+	rl, ok := d.ds.ledger(cursor.ChainID)
+	if !ok {
+		logger.Debugf("Client resumed on unknown chain %x", cursor.ChainID)
+		d.sendErrorReply(ab.ReasonUnknownChain, "chain %x does not exist", cursor.ChainID)
+		return false
+	}
+	if !d.authorize(cursor.ChainID, update) {
+		return false
+	}
+
+	if cursor.Number < rl.OldestRetained() || cursor.Number >= rl.Height() {
+		logger.Debugf("Client resumed after block %d which is not retained", cursor.Number)
+		d.sendErrorReply(ab.ReasonNotRetained, "cursor block %d is outside of retained blocks %d to %d", cursor.Number, rl.OldestRetained(), rl.Height()-1)
+		return false
+	}
+
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, cursor.Number)
+	block, status := it.Next()
+	if status != ab.Status_SUCCESS || !bytes.Equal(block.Hash(), cursor.Hash) {
+		logger.Warningf("Client resumed after block %d, but the chain no longer contains the block it was sent", cursor.Number)
+		d.sendErrorReply(ab.ReasonNotRetained, "cursor block %d no longer matches the chain", cursor.Number)
+		return false
+	}
+
+	d.rl = rl
+	d.chainID = cursor.ChainID
+	d.windowSize = windowSize
+	d.compat = !d.features.Has(ab.Feature_DELIVER_ACKS)
+	d.content = content
+
+	d.cursor, d.nextBlockNumber = it, cursor.Number+1
+	d.lastAck = cursor.Number
+	d.bestLag = d.lag()
+
+	return d.setStop(update)
+}
+
+// setStop records the last block of the range requested, returning false if the range is invalid or nothing remains to be delivered in it
+func (d *deliverer) setStop(update *ab.SeekInfo) bool {
+	d.bounded = false
+
+	height := d.rl.Height()
+	var stop uint64
+	switch update.Stop {
+	case ab.SeekInfo_UNBOUNDED:
+		return true
+	case ab.SeekInfo_STOP_SPECIFIED:
+		if update.StopNumber < d.nextBlockNumber {
+			logger.Debugf("Client requested a stop at block %d which is before the start at block %d", update.StopNumber, d.nextBlockNumber)
+			d.sendErrorReply(ab.ReasonMalformed, "stop block %d precedes start block %d", update.StopNumber, d.nextBlockNumber)
+			return false
+		}
+		stop = update.StopNumber
+	case ab.SeekInfo_STOP_NEWEST:
+		stop = height - 1
+	default:
+		d.sendErrorReply(ab.ReasonMalformed, "stop type %v is unknown", update.Stop)
+		return false
+	}
+
+	if stop >= height && !update.WaitForStop {
+		stop = height - 1
+	}
+
+	if stop < d.nextBlockNumber {
+		logger.Debugf("No blocks remain to be delivered before the requested stop, closing the stream")
+		d.cursor = nil
+		d.sendDoneReply()
+		return false
+	}
+
+	d.bounded = true
+	d.stopNumber = stop
+	return true
+}
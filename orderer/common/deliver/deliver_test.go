@@ -0,0 +1,1572 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap/static"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/fileledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+)
+
+// MagicLargestWindow is used as the default max window size for initializing the deliver service
+const MagicLargestWindow int = 1000
+
+var genesisBlock *ab.Block
+
+func init() {
+	bootstrapper := static.New()
+	var err error
+	genesisBlock, err = bootstrapper.GenesisBlock()
+	if err != nil {
+		panic("Error intializing static bootstrap genesis block")
+	}
+}
+
+func TestOldestSeek(t *testing.T) {
+	ledgerSize := 5
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST}}}
+
+	count := 0
+	for {
+		select {
+		case deliverReply := <-m.SendChan:
+			if deliverReply.GetError() != ab.Status_SUCCESS {
+				t.Fatalf("Received an error on the reply channel")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting to get all blocks")
+		}
+		count++
+		if count == ledgerSize {
+			break
+		}
+	}
+}
+
+func TestNewestSeek(t *testing.T) {
+	ledgerSize := 5
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_NEWEST}}}
+
+	select {
+	case blockReply := <-m.SendChan:
+		if blockReply.GetError() != ab.Status_SUCCESS {
+			t.Fatalf("Received an error on the reply channel")
+		}
+
+		if blockReply.GetBlock().Header.Number != uint64(ledgerSize-1) {
+			t.Fatalf("Expected only the most recent block")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting to get all blocks")
+	}
+}
+
+func TestSpecificSeek(t *testing.T) {
+	ledgerSize := 5
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: uint64(ledgerSize - 1)}}}
+
+	select {
+	case blockReply := <-m.SendChan:
+		if blockReply.GetError() != ab.Status_SUCCESS {
+			t.Fatalf("Received an error on the reply channel")
+		}
+
+		if blockReply.GetBlock().Header.Number != uint64(ledgerSize-1) {
+			t.Fatalf("Expected only to get block 4")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting to get all blocks")
+	}
+}
+
+func TestBadSeek(t *testing.T) {
+	ledgerSize := 5
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < 2*ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	for _, specified := range []uint64{uint64(ledgerSize - 1), uint64(3 * ledgerSize)} {
+		m := mocks.NewDeliverStream()
+		done := make(chan error)
+		go func() { done <- ds.Handle(m) }()
+
+		m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: specified}}}
+
+		select {
+		case blockReply := <-m.SendChan:
+			if blockReply.GetError() != ab.Status_NOT_FOUND {
+				t.Fatalf("Received wrong error on the reply channel")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting to get all blocks")
+		}
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the stream to be closed after a NOT_FOUND status")
+		}
+		close(m.RecvChan)
+	}
+}
+
+func TestBadWindow(t *testing.T) {
+	ledgerSize := 5
+	rl := ramledger.New(ledgerSize, genesisBlock)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow) * 2, Start: ab.SeekInfo_OLDEST}}}
+
+	select {
+	case blockReply := <-m.SendChan:
+		if blockReply.GetError() != ab.Status_BAD_REQUEST {
+			t.Fatalf("Received wrong error on the reply channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting to get all blocks")
+	}
+}
+
+func TestAck(t *testing.T) {
+	ledgerSize := 10
+	windowSize := uint64(2)
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: windowSize, Start: ab.SeekInfo_OLDEST}}}
+
+	count := uint64(0)
+	for {
+		select {
+		case blockReply := <-m.SendChan:
+			if blockReply.GetError() != ab.Status_SUCCESS {
+				t.Fatalf("Received an error on the reply channel")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting to get all blocks")
+		}
+		count++
+		if count == windowSize {
+			select {
+			case <-m.SendChan:
+				t.Fatalf("Window size exceeded")
+			default:
+			}
+		}
+
+		if count%windowSize == 0 {
+			m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: count}}}
+		}
+
+		if count == uint64(ledgerSize) {
+			break
+		}
+	}
+}
+
+func TestAckEveryBlock(t *testing.T) {
+	ledgerSize := 10
+	windowSize := uint64(1)
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, time.Second, 0, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: windowSize, Start: ab.SeekInfo_OLDEST}}}
+
+	for i := 0; i < ledgerSize; i++ {
+		select {
+		case blockReply := <-m.SendChan:
+			if blockReply.GetBlock() == nil || blockReply.GetBlock().Header.Number != uint64(i) {
+				t.Fatalf("Expected block %d but got %v", i, blockReply)
+			}
+			m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: uint64(i)}}}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for block %d", i)
+		}
+	}
+}
+
+func TestLateAck(t *testing.T) {
+	ledgerSize := 10
+	windowSize := uint64(3)
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: windowSize, Start: ab.SeekInfo_OLDEST}}}
+
+	for i := uint64(0); i < windowSize; i++ {
+		select {
+		case <-m.SendChan:
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for block %d", i)
+		}
+	}
+
+	select {
+	case <-m.SendChan:
+		t.Fatalf("Window size exceeded")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: 0}}}
+
+	select {
+	case blockReply := <-m.SendChan:
+		if blockReply.GetBlock().Header.Number != windowSize {
+			t.Fatalf("Expected to resume at block %d but got %d", windowSize, blockReply.GetBlock().Header.Number)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for delivery to resume after the acknowledgement")
+	}
+}
+
+func TestAckTimeout(t *testing.T) {
+	ledgerSize := 10
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 100*time.Millisecond, 0, 0, nil, nil)
+
+	done := make(chan error)
+	go func() { done <- ds.Handle(m) }()
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: 1, Start: ab.SeekInfo_OLDEST}}}
+
+	<-m.SendChan
+
+	select {
+	case blockReply := <-m.SendChan:
+		if blockReply.GetError() != ab.Status_SERVICE_UNAVAILABLE {
+			t.Fatalf("Expected SERVICE_UNAVAILABLE but got %v", blockReply)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the client to be disconnected for failing to acknowledge")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the stream to be closed")
+	}
+
+	if ds.evictedCount() != 1 {
+		t.Fatalf("Expected the stalled client to be counted as evicted")
+	}
+}
+
+func TestAckUnsentBlock(t *testing.T) {
+	ledgerSize := 10
+	rl := ramledger.New(ledgerSize, genesisBlock)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: 1, Start: ab.SeekInfo_OLDEST}}}
+
+	<-m.SendChan
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: 5}}}
+
+	select {
+	case blockReply := <-m.SendChan:
+		if blockReply.GetError() != ab.Status_BAD_REQUEST {
+			t.Fatalf("Expected BAD_REQUEST but got %v", blockReply)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the acknowledgement to be rejected")
+	}
+}
+
+func deliverAll(t *testing.T, ds *Server, content ab.SeekInfo_ContentType, count int) []*ab.DeliverResponse {
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST, Content: content}}}
+
+	var replies []*ab.DeliverResponse
+	for i := 0; i < count; i++ {
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetBlock() == nil {
+				t.Fatalf("Expected a block but got %v", reply)
+			}
+			replies = append(replies, reply)
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for block %d", i)
+		}
+	}
+	return replies
+}
+
+func TestHeadersOnlySeek(t *testing.T) {
+	ledgerSize := 5
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("payload-%d", i))}}, nil)
+	}
+
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	full := deliverAll(t, ds, ab.SeekInfo_FULL, ledgerSize)
+	headers := deliverAll(t, ds, ab.SeekInfo_HEADERS_ONLY, ledgerSize)
+
+	for i := range headers {
+		header := headers[i].GetBlock()
+		block := full[i].GetBlock()
+
+		if header.Data != nil {
+			t.Fatalf("Expected block %d to be sent without data", header.Header.Number)
+		}
+
+		if !bytes.Equal(header.Hash(), block.Hash()) {
+			t.Fatalf("Header hash for block %d does not match the full block hash", header.Header.Number)
+		}
+
+		if i > 0 && !bytes.Equal(header.Header.PreviousHash, headers[i-1].GetBlock().Hash()) {
+			t.Fatalf("Header hash chain is broken at block %d", header.Header.Number)
+		}
+
+		wire, err := proto.Marshal(headers[i])
+		if err != nil {
+			t.Fatalf("Error marshaling reply: %s", err)
+		}
+		if bytes.Contains(wire, []byte("payload-")) {
+			t.Fatalf("Payload bytes were sent for block %d in headers only mode", header.Header.Number)
+		}
+	}
+
+	// The stored blocks must not have been stripped
+	if len(deliverAll(t, ds, ab.SeekInfo_FULL, ledgerSize)[ledgerSize-1].GetBlock().Data.Messages) != 1 {
+		t.Fatalf("Headers only delivery modified the stored block")
+	}
+}
+
+func TestHeartbeatWhenIdle(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 50*time.Millisecond, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: 1, Start: ab.SeekInfo_NEWEST}}}
+
+	if reply := <-m.SendChan; reply.GetBlock() == nil {
+		t.Fatalf("Expected the newest block but got %v", reply)
+	}
+
+	// The window is exhausted, but heartbeats do not count against it
+	for i := 0; i < 2; i++ {
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetHeartbeat() == nil {
+				t.Fatalf("Expected a heartbeat but got %v", reply)
+			}
+			if reply.GetHeartbeat().Height != rl.Height() {
+				t.Fatalf("Expected heartbeat to report height %d but got %d", rl.Height(), reply.GetHeartbeat().Height)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for a heartbeat")
+		}
+	}
+
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("Some bytes")}}, nil)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: 0}}}
+
+	for {
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetHeartbeat() != nil {
+				continue
+			}
+			if reply.GetBlock() == nil || reply.GetBlock().Header.Number != 1 {
+				t.Fatalf("Expected block 1 after the heartbeats but got %v", reply)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for block 1")
+		}
+	}
+}
+
+func TestNoHeartbeatWhileBlocksFlow(t *testing.T) {
+	rl := ramledger.New(100, genesisBlock)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 200*time.Millisecond, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_NEWEST}}}
+	<-m.SendChan
+
+	for i := 0; i < 20; i++ {
+		time.Sleep(20 * time.Millisecond)
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("Some bytes")}}, nil)
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetBlock() == nil {
+				t.Fatalf("Expected only blocks while blocks are flowing but got %v", reply)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for block")
+		}
+	}
+}
+
+func TestShutdownEndsStreams(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 50*time.Millisecond, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_NEWEST}}}
+	<-m.SendChan
+
+	done := make(chan struct{})
+	go func() {
+		ds.Shutdown()
+		close(done)
+	}()
+
+	reply := <-m.SendChan
+	if reply.GetHeartbeat() != nil {
+		// A heartbeat may have already been in flight when shutdown began
+		reply = <-m.SendChan
+	}
+	if reply.GetError() != ab.Status_SERVICE_UNAVAILABLE {
+		t.Fatalf("Expected the stream to be ended with SERVICE_UNAVAILABLE, got %v", reply)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected shutdown to return once the stream was ended")
+	}
+
+	// A stream opened after the shutdown is ended at once
+	late := mocks.NewDeliverStream()
+	go ds.Handle(late)
+	if reply := <-late.SendChan; reply.GetError() != ab.Status_SERVICE_UNAVAILABLE {
+		t.Fatalf("Expected a stream opened after shutdown to be ended with SERVICE_UNAVAILABLE, got %v", reply)
+	}
+}
+
+// failingLedger is a ledger whose iterators fail to read any block
+type failingLedger struct {
+	rawledger.Reader
+}
+
+type failingIterator struct{}
+
+func (fi failingIterator) Next() (*ab.Block, ab.Status) { return nil, ab.Status_SERVICE_UNAVAILABLE }
+
+func (fi failingIterator) ReadyChan() <-chan struct{} {
+	ready := make(chan struct{})
+	close(ready)
+	return ready
+}
+
+func (fl failingLedger) Iterator(startType ab.SeekInfo_StartType, specified uint64) (rawledger.Iterator, uint64) {
+	_, number := fl.Reader.Iterator(startType, specified)
+	return failingIterator{}, number
+}
+
+// expectTerminalStatus receives replies until a status, failing unless it is the expected one and the stream then ends
+func expectTerminalStatus(t *testing.T, m *mocks.DeliverStream, done chan error, status ab.Status) {
+	for {
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetBlock() != nil || reply.GetHeartbeat() != nil {
+				continue
+			}
+			if reply.GetError() != status {
+				t.Fatalf("Expected the terminal status %v, got %v", status, reply)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the terminal status %v", status)
+		}
+		break
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the stream to end after its terminal status")
+	}
+}
+
+func TestTerminalStatusOnEveryExit(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	seek := &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST}}}
+
+	for _, tc := range []struct {
+		name   string
+		ledger rawledger.Reader
+		exit   func(m *mocks.DeliverStream) // Causes the stream to end, once it has sought
+		status ab.Status
+	}{
+		{"client close", rl, func(m *mocks.DeliverStream) { close(m.RecvChan) }, ab.Status_SUCCESS},
+		{"client cancel", rl, func(m *mocks.DeliverStream) {
+			m.CloseErr = context.Canceled
+			close(m.RecvChan)
+		}, ab.Status_SERVICE_UNAVAILABLE},
+		{"empty update", rl, func(m *mocks.DeliverStream) {
+			m.RecvChan <- &ab.DeliverUpdate{}
+			close(m.RecvChan)
+		}, ab.Status_BAD_REQUEST},
+		{"ledger error", failingLedger{rl}, func(m *mocks.DeliverStream) {}, ab.Status_SERVICE_UNAVAILABLE},
+	} {
+		t.Logf("Ending the stream by %s", tc.name)
+		m := mocks.NewDeliverStream()
+		ds := newDeliverServer(tc.ledger, MagicLargestWindow, 0, 0, 0, nil, nil)
+		done := make(chan error)
+		go func() { done <- ds.Handle(m) }()
+
+		m.RecvChan <- seek
+		tc.exit(m)
+		expectTerminalStatus(t, m, done, tc.status)
+	}
+}
+
+func TestCancelRacingSend(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	for i := 0; i < 3; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	for i := 0; i < 20; i++ {
+		m := mocks.NewDeliverStream()
+		ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+		done := make(chan error)
+		go func() { done <- ds.Handle(m) }()
+
+		// The cancel races the send of the next block, either order ends with the status
+		m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST}}}
+		<-m.SendChan
+		m.CloseErr = context.Canceled
+		close(m.RecvChan)
+		expectTerminalStatus(t, m, done, ab.Status_SERVICE_UNAVAILABLE)
+	}
+}
+
+func TestFinishAbandonedForStalledClient(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	m := mocks.NewDeliverStream()
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+	ds.finishTimeout = 50 * time.Millisecond
+	done := make(chan error)
+	go func() { done <- ds.Handle(m) }()
+
+	// The client sends an empty update and never reads the status it causes
+	m.RecvChan <- &ab.DeliverUpdate{}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the stream to end once the send of its terminal status was abandoned")
+	}
+	close(m.RecvChan)
+}
+
+func TestSlowReplayNotEvicted(t *testing.T) {
+	ledgerSize := 50
+	windowSize := uint64(2)
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	// The requested range is far longer than the maximum lag, but the client is making progress
+	ds := newDeliverServer(rl, MagicLargestWindow, 200*time.Millisecond, 5, 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: windowSize, Start: ab.SeekInfo_OLDEST}}}
+
+	for i := 0; i < ledgerSize; i++ {
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetBlock() == nil {
+				t.Fatalf("Expected block %d but got %v", i, reply)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for block %d", i)
+		}
+		time.Sleep(time.Millisecond)
+		m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: uint64(i)}}}
+	}
+
+	if ds.evictedCount() != 0 {
+		t.Fatalf("Expected the slow but progressing client not to be evicted")
+	}
+}
+
+func TestLaggingClientEvicted(t *testing.T) {
+	rl := ramledger.New(100, genesisBlock)
+	maxLag := 5
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, uint64(maxLag), 0, nil, nil)
+
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: 1, Start: ab.SeekInfo_NEWEST}}}
+	<-m.SendChan
+
+	// The chain grows while the client acknowledges only the block it already has
+	for i := 0; i <= maxLag+1; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("Some bytes")}}, nil)
+	}
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: 0}}}
+
+	for {
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetBlock() != nil {
+				continue
+			}
+			if reply.GetError() != ab.Status_SERVICE_UNAVAILABLE {
+				t.Fatalf("Expected SERVICE_UNAVAILABLE but got %v", reply)
+			}
+			if ds.evictedCount() != 1 {
+				t.Fatalf("Expected the lagging client to be counted as evicted")
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for the lagging client to be evicted")
+		}
+	}
+}
+
+func seekCursor(m *mocks.DeliverStream, cursor []byte) {
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Cursor: cursor}}}
+}
+
+// receiveBlocks returns the cursor sent with the last of the expected blocks
+func receiveBlocks(t *testing.T, m *mocks.DeliverStream, first, last uint64) []byte {
+	var cursor []byte
+	for number := first; number <= last; number++ {
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetBlock() == nil || reply.GetBlock().Header.Number != number {
+				t.Fatalf("Expected block %d but got %v", number, reply)
+			}
+			if len(reply.Cursor) == 0 {
+				t.Fatalf("Expected a cursor to be sent with block %d", number)
+			}
+			cursor = reply.Cursor
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for block %d", number)
+		}
+	}
+	return cursor
+}
+
+func expectDeliverError(t *testing.T, m *mocks.DeliverStream, status ab.Status) {
+	select {
+	case reply := <-m.SendChan:
+		if reply.GetError() != status {
+			t.Fatalf("Expected %v but got %v", status, reply)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for %v", status)
+	}
+}
+
+func TestCursorResumeMidChain(t *testing.T) {
+	ledgerSize := 10
+	rl := ramledger.New(2*ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, []byte("secret"), nil)
+
+	windowSize := uint64(3)
+	m := mocks.NewDeliverStream()
+	go ds.Handle(m)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: windowSize, Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: 2}}}
+	cursor := receiveBlocks(t, m, 2, 4)
+	close(m.RecvChan)
+
+	// The resumed stream continues after the last block received, with the window of the previous stream
+	m = mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go ds.Handle(m)
+	seekCursor(m, cursor)
+	receiveBlocks(t, m, 5, 4+windowSize)
+
+	select {
+	case reply := <-m.SendChan:
+		t.Fatalf("Expected the restored window to be exhausted, but got %v", reply)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCursorResumePruned(t *testing.T) {
+	ledgerSize := 3
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	m := mocks.NewDeliverStream()
+	go ds.Handle(m)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST}}}
+	cursor := receiveBlocks(t, m, 0, 0)
+	close(m.RecvChan)
+
+	for i := 0; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m = mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go ds.Handle(m)
+	seekCursor(m, cursor)
+	expectDeliverError(t, m, ab.Status_NOT_FOUND)
+}
+
+func TestCursorResumeDiverged(t *testing.T) {
+	original := ramledger.New(10, genesisBlock)
+	diverged := ramledger.New(10, genesisBlock)
+	original.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("original")}}, nil)
+	diverged.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("diverged")}}, nil)
+
+	m := mocks.NewDeliverStream()
+	go newDeliverServer(original, MagicLargestWindow, 0, 0, 0, nil, nil).Handle(m)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: 1}}}
+	cursor := receiveBlocks(t, m, 1, 1)
+	close(m.RecvChan)
+
+	m = mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go newDeliverServer(diverged, MagicLargestWindow, 0, 0, 0, nil, nil).Handle(m)
+	seekCursor(m, cursor)
+	expectDeliverError(t, m, ab.Status_NOT_FOUND)
+}
+
+func TestCursorTampered(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	for i := 1; i < 5; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, []byte("secret"), nil)
+
+	m := mocks.NewDeliverStream()
+	go ds.Handle(m)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: 1}}}
+	cursor := receiveBlocks(t, m, 1, 1)
+	close(m.RecvChan)
+
+	// Claim a later block, leaving the authentication untouched
+	decoded := &ab.Cursor{}
+	if err := proto.Unmarshal(cursor[:len(cursor)-32], decoded); err != nil {
+		t.Fatalf("Expected the cursor to be a marshaled Cursor followed by its HMAC: %s", err)
+	}
+	decoded.Number = 3
+	forged, _ := proto.Marshal(decoded)
+	forged = append(forged, cursor[len(cursor)-32:]...)
+
+	m = mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go ds.Handle(m)
+	seekCursor(m, forged)
+	expectDeliverError(t, m, ab.Status_BAD_REQUEST)
+}
+
+func seekRange(m *mocks.DeliverStream, start, stop uint64, waitForStop bool) {
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{
+		WindowSize:      uint64(MagicLargestWindow),
+		Start:           ab.SeekInfo_SPECIFIED,
+		SpecifiedNumber: start,
+		Stop:            ab.SeekInfo_STOP_SPECIFIED,
+		StopNumber:      stop,
+		WaitForStop:     waitForStop,
+	}}}
+}
+
+func expectStreamClosed(t *testing.T, done chan error) {
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected the stream to be closed cleanly but got %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the stream to be closed")
+	}
+}
+
+func TestBoundedRange(t *testing.T) {
+	rl := ramledger.New(20, genesisBlock)
+	for i := 1; i < 10; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	done := make(chan error)
+	go func() { done <- ds.Handle(m) }()
+
+	// The terminal status follows the last block of the range, and nothing more is sent
+	seekRange(m, 3, 6, false)
+	receiveBlocks(t, m, 3, 6)
+	expectDeliverError(t, m, ab.Status_SUCCESS)
+	expectStreamClosed(t, done)
+}
+
+func TestBoundedRangeNewest(t *testing.T) {
+	rl := ramledger.New(20, genesisBlock)
+	for i := 1; i < 5; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	done := make(chan error)
+	go func() { done <- ds.Handle(m) }()
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST, Stop: ab.SeekInfo_STOP_NEWEST}}}
+	receiveBlocks(t, m, 0, 2)
+
+	// Blocks appended after the request are not part of the range
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("later")}}, nil)
+	receiveBlocks(t, m, 3, 4)
+	expectDeliverError(t, m, ab.Status_SUCCESS)
+	expectStreamClosed(t, done)
+}
+
+func TestBoundedRangeBeyondTail(t *testing.T) {
+	rl := ramledger.New(20, genesisBlock)
+	for i := 1; i < 5; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	// Without waiting, the range ends at the tail
+	m := mocks.NewDeliverStream()
+	done := make(chan error)
+	go func() { done <- ds.Handle(m) }()
+	seekRange(m, 2, 8, false)
+	receiveBlocks(t, m, 2, 4)
+	expectDeliverError(t, m, ab.Status_SUCCESS)
+	expectStreamClosed(t, done)
+	close(m.RecvChan)
+
+	// Waiting, the range ends once the stop block is created
+	m = mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go func() { done <- ds.Handle(m) }()
+	seekRange(m, 2, 6, true)
+	receiveBlocks(t, m, 2, 4)
+	select {
+	case reply := <-m.SendChan:
+		t.Fatalf("Expected to wait for block 5, but got %v", reply)
+	case <-time.After(100 * time.Millisecond):
+	}
+	for i := 5; i < 8; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+	receiveBlocks(t, m, 5, 6)
+	expectDeliverError(t, m, ab.Status_SUCCESS)
+	expectStreamClosed(t, done)
+}
+
+func TestBoundedRangeStopBeforeStart(t *testing.T) {
+	rl := ramledger.New(20, genesisBlock)
+	for i := 1; i < 5; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go ds.Handle(m)
+	seekRange(m, 3, 2, false)
+	expectDeliverError(t, m, ab.Status_BAD_REQUEST)
+}
+
+// openLimitedStream starts a Deliver stream, returning the channel on which the handler's result is sent
+func openLimitedStream(ds *Server, m *mocks.DeliverStream) chan error {
+	done := make(chan error, 1)
+	go func() { done <- ds.Handle(m) }()
+	return done
+}
+
+// expectAdmitted checks that a stream was admitted by seeking to the newest block
+func expectAdmitted(t *testing.T, m *mocks.DeliverStream) {
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_NEWEST}}}
+	select {
+	case reply := <-m.SendChan:
+		if reply.GetBlock() == nil {
+			t.Fatalf("Expected the stream to be admitted but got %v", reply)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the stream to be admitted")
+	}
+}
+
+func expectRejected(t *testing.T, m *mocks.DeliverStream, done chan error) {
+	select {
+	case reply := <-m.SendChan:
+		if reply.GetError() != ab.Status_TOO_MANY_REQUESTS || reply.RetryAfter != 2000 {
+			t.Fatalf("Expected TOO_MANY_REQUESTS with a retry hint but got %v", reply)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the stream to be rejected")
+	}
+	expectStreamClosed(t, done)
+}
+
+func TestMaxGlobalStreams(t *testing.T) {
+	limit := 3
+	ds := newDeliverServer(ramledger.New(10, genesisBlock), MagicLargestWindow, 0, 0, 0, nil, newStreamLimiter(limit, 0, 2*time.Second))
+
+	streams := make([]*mocks.DeliverStream, limit)
+	dones := make([]chan error, limit)
+	for i := range streams {
+		streams[i] = mocks.NewDeliverStreamFrom(fmt.Sprintf("10.0.0.%d:5000", i))
+		dones[i] = openLimitedStream(ds, streams[i])
+		expectAdmitted(t, streams[i])
+	}
+	defer func() {
+		for _, m := range streams[1:] {
+			close(m.RecvChan)
+		}
+	}()
+
+	excess := mocks.NewDeliverStreamFrom("10.0.0.100:5000")
+	expectRejected(t, excess, openLimitedStream(ds, excess))
+
+	// A client cancelling its stream frees its place
+	close(streams[0].RecvChan)
+	<-dones[0]
+	m := mocks.NewDeliverStreamFrom("10.0.0.100:5000")
+	defer close(m.RecvChan)
+	openLimitedStream(ds, m)
+	expectAdmitted(t, m)
+}
+
+func TestMaxStreamsPerClient(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, newStreamLimiter(0, 1, 2*time.Second))
+
+	// Streams from the same host are counted together, regardless of their port
+	first := mocks.NewDeliverStreamFrom("10.0.0.1:5000")
+	done := openLimitedStream(ds, first)
+	expectAdmitted(t, first)
+
+	second := mocks.NewDeliverStreamFrom("10.0.0.1:5001")
+	expectRejected(t, second, openLimitedStream(ds, second))
+
+	other := mocks.NewDeliverStreamFrom("10.0.0.2:5000")
+	defer close(other.RecvChan)
+	openLimitedStream(ds, other)
+	expectAdmitted(t, other)
+
+	// A stream which reaches a terminal status frees its place
+	first.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST, Stop: ab.SeekInfo_STOP_NEWEST}}}
+	receiveBlocks(t, first, 0, 0)
+	expectDeliverError(t, first, ab.Status_SUCCESS)
+	expectStreamClosed(t, done)
+	close(first.RecvChan)
+
+	third := mocks.NewDeliverStreamFrom("10.0.0.1:5002")
+	defer close(third.RecvChan)
+	openLimitedStream(ds, third)
+	expectAdmitted(t, third)
+}
+
+func seekTimestamp(m *mocks.DeliverStream, timestamp time.Time) {
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{
+		WindowSize:     uint64(MagicLargestWindow),
+		Start:          ab.SeekInfo_TIMESTAMP,
+		StartTimestamp: timestamp.UnixNano(),
+		Stop:           ab.SeekInfo_STOP_NEWEST,
+	}}}
+}
+
+func TestTimestampSeek(t *testing.T) {
+	base := time.Unix(1000, 0)
+	rl := ramledger.New(20, genesisBlock)
+	for i := 1; i < 5; i++ {
+		committed := base.Add(time.Duration(i) * time.Second)
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, func(block *ab.Block) { block.SetTimestamp(committed) })
+	}
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	done := make(chan error)
+	go func() { done <- ds.Handle(m) }()
+
+	// A timestamp between blocks 2 and 3 starts from block 3
+	seekTimestamp(m, base.Add(2500*time.Millisecond))
+	receiveBlocks(t, m, 3, 4)
+	expectDeliverError(t, m, ab.Status_SUCCESS)
+	expectStreamClosed(t, done)
+
+	m = mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go func() { done <- ds.Handle(m) }()
+
+	seekTimestamp(m, base.Add(5*time.Second))
+	expectDeliverError(t, m, ab.Status_NOT_FOUND)
+}
+
+func TestOversizedHistoricalBlock(t *testing.T) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+
+	// The ledger is written under a larger limit than the orderer reading it back delivers under
+	rl := fileledger.New(location, genesisBlock)
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: make([]byte, 8192)}}, nil)
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("small")}}, nil)
+
+	ds := newDeliverServer(fileledger.New(location, genesisBlock), MagicLargestWindow, 0, 0, 0, nil, nil)
+	ds.maxSendBytes = 4096
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	done := make(chan error)
+	go func() { done <- ds.Handle(m) }()
+
+	seekRange(m, 0, 2, false)
+	receiveBlocks(t, m, 0, 0)
+	expectDeliverError(t, m, ab.Status_PAYLOAD_TOO_LARGE)
+	expectStreamClosed(t, done)
+
+	// Without its data the block fits
+	if replies := deliverAll(t, ds, ab.SeekInfo_HEADERS_ONLY, 3); replies[1].GetBlock().Header.Number != 1 {
+		t.Fatalf("Expected block 1 to be delivered without its data, got %v", replies[1])
+	}
+}
+
+func expectBlock(t *testing.T, m *mocks.DeliverStream, number uint64) {
+	select {
+	case reply := <-m.SendChan:
+		if reply.GetBlock() == nil || reply.GetBlock().Header.Number != number {
+			t.Fatalf("Expected block %d but got %v", number, reply)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for block %d", number)
+	}
+}
+
+func expectNoReply(t *testing.T, m *mocks.DeliverStream) {
+	select {
+	case reply := <-m.SendChan:
+		t.Fatalf("Window size exceeded, received %v", reply)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func windowUpdate(m *mocks.DeliverStream, windowSize uint64) {
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_WindowUpdate{WindowUpdate: &ab.WindowUpdate{WindowSize: windowSize}}}
+}
+
+func TestWindowUpdate(t *testing.T) {
+	ledgerSize := 10
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: 2, Start: ab.SeekInfo_OLDEST}}}
+	expectBlock(t, m, 0)
+	expectBlock(t, m, 1)
+	expectNoReply(t, m)
+
+	// Growing the window releases more blocks without an acknowledgement
+	windowUpdate(m, 5)
+	for i := uint64(2); i < 5; i++ {
+		expectBlock(t, m, i)
+	}
+	expectNoReply(t, m)
+
+	// Shrinking the window below the blocks outstanding holds delivery until enough are acknowledged
+	windowUpdate(m, 1)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: 2}}}
+	expectNoReply(t, m)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: 4}}}
+	expectBlock(t, m, 5)
+	expectNoReply(t, m)
+
+	windowUpdate(m, 0)
+	expectDeliverError(t, m, ab.Status_BAD_REQUEST)
+}
+
+func TestWindowUpdateWithoutSeek(t *testing.T) {
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(ramledger.New(2, genesisBlock), MagicLargestWindow, 0, 0, 0, nil, nil)
+	go ds.Handle(m)
+
+	windowUpdate(m, 2)
+	expectDeliverError(t, m, ab.Status_BAD_REQUEST)
+}
+
+func TestCompatSeek(t *testing.T) {
+	ledgerSize := 20
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+	ds.defaultWindow = 2
+	go ds.Handle(m)
+
+	// A client which sends no window is never waited on for an acknowledgement
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_OLDEST}}}
+	for i := uint64(0); i < 5; i++ {
+		expectBlock(t, m, i)
+	}
+
+	// Once it acknowledges the default window is enforced
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: 4}}}
+	next := uint64(5)
+	for {
+		select {
+		case reply := <-m.SendChan:
+			if reply.GetBlock() == nil || reply.GetBlock().Header.Number != next {
+				t.Fatalf("Expected block %d but got %v", next, reply)
+			}
+			next++
+			continue
+		case <-time.After(100 * time.Millisecond):
+		}
+		break
+	}
+	if next == uint64(ledgerSize) {
+		t.Fatalf("Expected the window to be enforced once the client acknowledged")
+	}
+}
+
+func TestSeekWithoutWindowRefused(t *testing.T) {
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	ds := newDeliverServer(ramledger.New(2, genesisBlock), MagicLargestWindow, 0, 0, 0, nil, nil)
+	go ds.Handle(m)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_OLDEST}}}
+	expectDeliverError(t, m, ab.Status_BAD_REQUEST)
+}
+
+func startLegacyDeliver(enabled ab.Features) (*mocks.DeliverStream, func()) {
+	ledgerSize := 10
+	rl := ramledger.New(ledgerSize, genesisBlock)
+	for i := 1; i < ledgerSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	m := mocks.NewDeliverStream()
+	m.Hello = nil
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+	ds.enabled = enabled
+	go ds.Handle(m)
+	return m, func() { close(m.RecvChan) }
+}
+
+func expectFullBlocks(t *testing.T, m *mocks.DeliverStream, first, last uint64) {
+	for number := first; number <= last; number++ {
+		select {
+		case reply := <-m.SendChan:
+			if block := reply.GetBlock(); block == nil || block.Header.Number != number || block.Data == nil {
+				t.Fatalf("Expected the full block %d but got %v", number, reply)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for block %d", number)
+		}
+	}
+}
+
+func expectDeliverHello(t *testing.T, m *mocks.DeliverStream, hello *ab.Hello, features ...ab.Feature) {
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: hello}}
+	select {
+	case reply := <-m.SendChan:
+		if reply.GetHello() == nil || len(reply.GetHello().Features) != len(features) {
+			t.Fatalf("Expected the features %v to be selected but got %v", features, reply)
+		}
+		for i, f := range features {
+			if reply.GetHello().Features[i] != f {
+				t.Fatalf("Expected the features %v to be selected but got %v", features, reply)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the reply to the hello")
+	}
+}
+
+func TestLegacyDeliver(t *testing.T) {
+	m, stop := startLegacyDeliver(ab.AllFeatures)
+	defer stop()
+
+	// Without a hello the window is not enforced and the content is ignored
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: 2, Start: ab.SeekInfo_OLDEST, Content: ab.SeekInfo_HEADERS_ONLY}}}
+	expectFullBlocks(t, m, 0, 9)
+
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.AllFeatures.Hello()}}
+	expectDeliverError(t, m, ab.Status_BAD_REQUEST)
+}
+
+func TestDeliverHelloNegotiation(t *testing.T) {
+	m, stop := startLegacyDeliver(ab.AllFeatures)
+	defer stop()
+
+	expectDeliverHello(t, m, &ab.Hello{Features: []ab.Feature{ab.Feature_DELIVER_ACKS}}, ab.Feature_DELIVER_ACKS)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: 2, Start: ab.SeekInfo_OLDEST, Content: ab.SeekInfo_HEADERS_ONLY}}}
+	expectFullBlocks(t, m, 0, 1)
+	expectNoReply(t, m)
+}
+
+func TestDeliverHelloFeatureDisabled(t *testing.T) {
+	m, stop := startLegacyDeliver(ab.NewFeatures(ab.Feature_CHAIN_ROUTING))
+	defer stop()
+
+	expectDeliverHello(t, m, ab.AllFeatures.Hello(), ab.Feature_CHAIN_ROUTING)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: 2, Start: ab.SeekInfo_OLDEST}}}
+	expectFullBlocks(t, m, 0, 9)
+}
+
+func TestDeliverChainWithoutRouting(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		hello *ab.Hello
+	}{
+		{"Legacy", nil},
+		{"NotNegotiated", &ab.Hello{Features: []ab.Feature{ab.Feature_DELIVER_ACKS}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, stop := startLegacyDeliver(ab.AllFeatures)
+			defer stop()
+
+			if tc.hello != nil {
+				expectDeliverHello(t, m, tc.hello, ab.Feature_DELIVER_ACKS)
+			}
+			m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{ChainID: []byte("other"), WindowSize: 2, Start: ab.SeekInfo_OLDEST}}}
+			expectDeliverError(t, m, ab.Status_BAD_REQUEST)
+		})
+	}
+}
+
+// prefixCryptoHelper considers a signature valid only if it is the message prefixed by the signer's identity
+type prefixCryptoHelper struct{}
+
+func (ph prefixCryptoHelper) VerifySignature(msg []byte, id []byte, signature []byte) bool {
+	return bytes.Equal(signature, append(append([]byte{}, id...), msg...))
+}
+
+// signedSeek returns a seek signed by signer now, unless it is empty
+func signedSeek(seek *ab.SeekInfo, signer string) *ab.DeliverUpdate {
+	return signedSeekAt(seek, signer, time.Now())
+}
+
+// signedSeekAt returns a seek signed by signer at the given time, unless signer is empty
+func signedSeekAt(seek *ab.SeekInfo, signer string, signed time.Time) *ab.DeliverUpdate {
+	if signer != "" {
+		seek.Timestamp = signed.UnixNano()
+		envelope, _ := proto.Marshal(&ab.PayloadEnvelope{Signer: []byte(signer)})
+		seek.Signatures = []*ab.SignedData{&ab.SignedData{PayloadEnvelope: envelope, Signature: append([]byte(signer), seek.SignedBytes()...)}}
+	}
+	return &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: seek}}
+}
+
+func TestReadersPolicy(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("1")}}, nil)
+
+	policy, _ := policies.Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{[]byte("reader")})}})
+	pm := policies.NewManagerImpl(prefixCryptoHelper{})
+	pm.BeginConfig()
+	if err := pm.ProposeConfig(&ab.Configuration{ID: policies.ReadersPolicyID, Type: ab.Configuration_Policy, Data: policy}); err != nil {
+		t.Fatalf("Error proposing the Readers policy: %s", err)
+	}
+	pm.CommitConfig()
+
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, []byte("secret"), nil)
+	ds.policies = func(chainID []byte) (policies.Manager, bool) { return pm, true }
+	seek := func() *ab.SeekInfo {
+		return &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST}
+	}
+
+	m := mocks.NewDeliverStream()
+	go ds.Handle(m)
+	m.RecvChan <- signedSeek(seek(), "reader")
+	cursor := receiveBlocks(t, m, 0, 0)
+	close(m.RecvChan)
+
+	for _, tc := range []struct {
+		name   string
+		update *ab.DeliverUpdate
+	}{
+		{"unsigned", signedSeek(seek(), "")},
+		{"signed by another", signedSeek(seek(), "other")},
+		{"unsigned resume", signedSeek(&ab.SeekInfo{Cursor: cursor}, "")},
+	} {
+		m = mocks.NewDeliverStream()
+		go ds.Handle(m)
+		m.RecvChan <- tc.update
+		expectDeliverError(t, m, ab.Status_FORBIDDEN)
+		close(m.RecvChan)
+	}
+
+	m = mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go ds.Handle(m)
+	m.RecvChan <- signedSeek(&ab.SeekInfo{Cursor: cursor}, "reader")
+	receiveBlocks(t, m, 1, 1)
+}
+
+func TestReadersPolicySeekReplay(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	policy, _ := policies.Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{[]byte("reader")})}})
+	pm := policies.NewManagerImpl(prefixCryptoHelper{})
+	pm.BeginConfig()
+	if err := pm.ProposeConfig(&ab.Configuration{ID: policies.ReadersPolicyID, Type: ab.Configuration_Policy, Data: policy}); err != nil {
+		t.Fatalf("Error proposing the Readers policy: %s", err)
+	}
+	pm.CommitConfig()
+
+	ds := NewServer(func(chainID []byte) (rawledger.Reader, bool) { return rl, true }, Options{
+		MaxWindowSize: MagicLargestWindow,
+		Policies:      func(chainID []byte) (policies.Manager, bool) { return pm, true },
+		SeekSkew:      time.Minute,
+	})
+	fc := clock.NewFake()
+	fc.Advance(time.Hour)
+	ds.clock = fc
+	seek := func() *ab.SeekInfo {
+		return &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST}
+	}
+	captured := signedSeekAt(seek(), "reader", fc.Now())
+	tampered := signedSeekAt(seek(), "reader", fc.Now())
+	tampered.GetSeek().Start = ab.SeekInfo_NEWEST
+
+	for _, tc := range []struct {
+		name   string
+		update *ab.DeliverUpdate
+		status ab.Status
+	}{
+		{"signed now", captured, ab.Status_SUCCESS},
+		{"signed within the skew", signedSeekAt(seek(), "reader", fc.Now().Add(-50*time.Second)), ab.Status_SUCCESS},
+		{"signed before the skew", signedSeekAt(seek(), "reader", fc.Now().Add(-2*time.Minute)), ab.Status_FORBIDDEN},
+		{"signed after the skew", signedSeekAt(seek(), "reader", fc.Now().Add(2*time.Minute)), ab.Status_FORBIDDEN},
+		{"altered after signing", tampered, ab.Status_FORBIDDEN},
+	} {
+		m := mocks.NewDeliverStream()
+		go ds.Handle(m)
+		m.RecvChan <- tc.update
+		if tc.status == ab.Status_SUCCESS {
+			expectBlock(t, m, 0)
+		} else {
+			expectDeliverError(t, m, tc.status)
+		}
+		close(m.RecvChan)
+	}
+
+	// The signatures of the captured seek expire with the skew
+	fc.Advance(2 * time.Minute)
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go ds.Handle(m)
+	m.RecvChan <- captured
+	expectDeliverError(t, m, ab.Status_FORBIDDEN)
+}
+
+func TestReadersPolicyMissing(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	for _, defaultDeny := range []bool{false, true} {
+		ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+		pm := policies.NewManagerImpl(prefixCryptoHelper{})
+		pm.SetDefaultDeny(defaultDeny)
+		ds.policies = func(chainID []byte) (policies.Manager, bool) { return pm, true }
+
+		m := mocks.NewDeliverStream()
+		go ds.Handle(m)
+		m.RecvChan <- signedSeek(&ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST}, "")
+		if defaultDeny {
+			expectDeliverError(t, m, ab.Status_FORBIDDEN)
+		} else {
+			expectBlock(t, m, 0)
+		}
+		close(m.RecvChan)
+	}
+}
+
+// lockedBuffer collects the lines logged by the handlers while it is installed as the logging backend
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (lb *lockedBuffer) Write(p []byte) (int, error) {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	return lb.buf.Write(p)
+}
+
+func (lb *lockedBuffer) String() string {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	return lb.buf.String()
+}
+
+func TestReadersPolicyAudit(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	ds := newDeliverServer(rl, MagicLargestWindow, 0, 0, 0, nil, nil)
+	pm := policies.NewManagerImpl(prefixCryptoHelper{})
+	pm.SetDefaultDeny(true)
+	ds.policies = func(chainID []byte) (policies.Manager, bool) { return pm, true }
+	ds.decisions = audit.NewDecisionLog(1, time.Hour, clock.Real{})
+	logged := &lockedBuffer{}
+	ds.decisions.SetBackend(logging.AddModuleLevel(logging.NewLogBackend(logged, "", 0)))
+
+	for i := 0; i < 3; i++ {
+		m := mocks.NewDeliverStreamFrom("10.0.0.7:5000")
+		go ds.Handle(m)
+		m.RecvChan <- signedSeek(&ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_OLDEST}, "other")
+		expectDeliverError(t, m, ab.Status_FORBIDDEN)
+		close(m.RecvChan)
+	}
+
+	output := logged.String()
+	if count := strings.Count(output, "Policy denied: "); count != 1 {
+		t.Fatalf("Expected only the first denial of the client to be written, got %d in %q", count, output)
+	}
+	for _, field := range []string{
+		`"point":"deliver"`,
+		`"policy":"` + policies.ReadersPolicyID + `"`,
+		`"chainID":"`,
+		`"client":"10.0.0.7"`,
+		`"signers":["` + audit.Fingerprints([][]byte{[]byte("other")})[0] + `"]`,
+		`"allowed":false`,
+	} {
+		if !strings.Contains(output, field) {
+			t.Fatalf("Expected the denial to contain %s, got %q", field, output)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"sync"
+	"time"
+)
+
+// streamLimiter bounds the number of streams open at once, in total and for each client
+type streamLimiter struct {
+	maxGlobal    int
+	maxPerClient int
+	retryAfter   time.Duration // The retry hint sent to clients whose streams are not admitted
+	lock         sync.Mutex
+	total        int
+	perClient    map[string]int
+}
+
+// newStreamLimiter returns nil if neither limit is set, admitting every stream
+func newStreamLimiter(maxGlobal, maxPerClient int, retryAfter time.Duration) *streamLimiter {
+	if maxGlobal == 0 && maxPerClient == 0 {
+		return nil
+	}
+	return &streamLimiter{
+		maxGlobal:    maxGlobal,
+		maxPerClient: maxPerClient,
+		retryAfter:   retryAfter,
+		perClient:    make(map[string]int),
+	}
+}
+
+// admit counts a new stream from client, returning false without counting it if either limit would be exceeded
+func (sl *streamLimiter) admit(client string) bool {
+	if sl == nil {
+		return true
+	}
+
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+
+	if sl.maxGlobal > 0 && sl.total >= sl.maxGlobal {
+		return false
+	}
+	if sl.maxPerClient > 0 && sl.perClient[client] >= sl.maxPerClient {
+		return false
+	}
+
+	sl.total++
+	sl.perClient[client]++
+	return true
+}
+
+// release uncounts a stream which was admitted for client
+func (sl *streamLimiter) release(client string) {
+	if sl == nil {
+		return
+	}
+
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+
+	sl.total--
+	if sl.perClient[client]--; sl.perClient[client] == 0 {
+		delete(sl.perClient, client)
+	}
+}
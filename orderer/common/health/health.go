@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health implements the gRPC health service of the orderer
+package health
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Server reports the status of named services, and of the orderer as a whole, which is SERVING once startup has
+// completed and only while every named service is SERVING
+type Server struct {
+	lock     sync.Mutex
+	started  bool
+	statuses map[string]healthpb.HealthCheckResponse_ServingStatus
+}
+
+// NewServer returns a server reporting NOT_SERVING until Started is called
+func NewServer() *Server {
+	return &Server{statuses: make(map[string]healthpb.HealthCheckResponse_ServingStatus)}
+}
+
+// Check returns the status of the named service, or of the orderer as a whole if no service is named
+func (s *Server) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if req.Service != "" {
+		status, ok := s.statuses[req.Service]
+		if !ok {
+			return nil, grpc.Errorf(codes.NotFound, "unknown service %s", req.Service)
+		}
+		return &healthpb.HealthCheckResponse{Status: status}, nil
+	}
+
+	if !s.started {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	for _, status := range s.statuses {
+		if status != healthpb.HealthCheckResponse_SERVING {
+			return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+		}
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// SetServingStatus records the status of a named service
+func (s *Server) SetServingStatus(service string, status healthpb.HealthCheckResponse_ServingStatus) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.statuses[service] = status
+}
+
+// Started marks the startup of the orderer as completed
+func (s *Server) Started() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.started = true
+}
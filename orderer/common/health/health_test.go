@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func checkStatus(t *testing.T, s *Server, service string, expected healthpb.HealthCheckResponse_ServingStatus) {
+	res, err := s.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Failed to check the status of %q: %s", service, err)
+	}
+	if res.Status != expected {
+		t.Fatalf("Expected %q to be %v, got %v", service, expected, res.Status)
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	checkStatus(t, s, "", healthpb.HealthCheckResponse_NOT_SERVING)
+	if _, err := s.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "other"}); grpc.Code(err) != codes.NotFound {
+		t.Fatalf("Expected an unknown service to be reported as not found, got %v", err)
+	}
+
+	s.SetServingStatus("consenter", healthpb.HealthCheckResponse_SERVING)
+	checkStatus(t, s, "consenter", healthpb.HealthCheckResponse_SERVING)
+	checkStatus(t, s, "", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	s.Started()
+	checkStatus(t, s, "", healthpb.HealthCheckResponse_SERVING)
+
+	s.SetServingStatus("consenter", healthpb.HealthCheckResponse_NOT_SERVING)
+	checkStatus(t, s, "consenter", healthpb.HealthCheckResponse_NOT_SERVING)
+	checkStatus(t, s, "", healthpb.HealthCheckResponse_NOT_SERVING)
+}
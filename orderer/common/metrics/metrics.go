@@ -0,0 +1,32 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// Namespace prefixes the names of all metrics registered by the orderer
+const Namespace = "orderer"
+
+// Registry is the registry into which the orderer's subsystems register their metrics
+var Registry = gometrics.NewRegistry()
+
+// NewSubsystemRegistry returns a registry which names each metric registered in it <Namespace>.<subsystem>.<name> in parent
+func NewSubsystemRegistry(parent gometrics.Registry, subsystem string) gometrics.Registry {
+	return gometrics.NewPrefixedChildRegistry(parent, Namespace+"."+subsystem+".")
+}
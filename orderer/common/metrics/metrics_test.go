@@ -0,0 +1,31 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+func TestSubsystemRegistry(t *testing.T) {
+	parent := gometrics.NewRegistry()
+	gometrics.GetOrRegisterCounter("foo", NewSubsystemRegistry(parent, "bar")).Inc(1)
+	if _, ok := parent.Get(Namespace + ".bar.foo").(gometrics.Counter); !ok {
+		t.Fatalf("Expected the counter to be registered in the parent under the namespace and subsystem")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policies
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+type cacheEntry struct {
+	key     [sha256.Size]byte
+	err     error
+	expires time.Time
+}
+
+// EvaluationCache remembers the outcomes of recent policy evaluations, so that a signature set presented again over the
+// same message is not verified again, the least recently used outcome is forgotten first
+// The outcomes must be invalidated whenever the policies they were evaluated by may have changed
+type EvaluationCache struct {
+	size   int
+	ttl    time.Duration
+	clock  clock.Clock
+	hits   gometrics.Counter
+	misses gometrics.Counter
+
+	lock       sync.Mutex // Guards generation, entries, and lru
+	generation uint64     // Incremented by Invalidate, so that evaluations which began before it are not remembered
+	entries    map[[sha256.Size]byte]*list.Element
+	lru        *list.List // Of *cacheEntry, the most recently used first
+}
+
+// NewEvaluationCache returns a cache of size outcomes each remembered for ttl, or until evicted if ttl is zero, and counts
+// its hits and misses in registry, which may be nil, it returns nil if size is zero, disabling caching
+func NewEvaluationCache(size int, ttl time.Duration, clk clock.Clock, registry gometrics.Registry) *EvaluationCache {
+	if size == 0 {
+		return nil
+	}
+	if registry == nil {
+		registry = gometrics.NewRegistry()
+	}
+	return &EvaluationCache{
+		size:    size,
+		ttl:     ttl,
+		clock:   clk,
+		hits:    gometrics.GetOrRegisterCounter("evaluation_cache_hits", registry),
+		misses:  gometrics.GetOrRegisterCounter("evaluation_cache_misses", registry),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Invalidate forgets every outcome, including those of evaluations still in progress
+func (ec *EvaluationCache) Invalidate() {
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+
+	ec.generation++
+	ec.entries = make(map[[sha256.Size]byte]*list.Element)
+	ec.lru.Init()
+}
+
+// evaluate returns the remembered outcome of the policy id over msg and sigs, or evaluates p and remembers its outcome
+func (ec *EvaluationCache) evaluate(id string, p *policy, msg []byte, sigs []*ab.SignedData) error {
+	key := evaluationKey(id, p.digest, msg, sigs)
+	now := ec.clock.Now()
+
+	ec.lock.Lock()
+	if elem, ok := ec.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if ec.ttl == 0 || now.Before(entry.expires) {
+			ec.lru.MoveToFront(elem)
+			ec.lock.Unlock()
+			ec.hits.Inc(1)
+			return entry.err
+		}
+		ec.lru.Remove(elem)
+		delete(ec.entries, key)
+	}
+	generation := ec.generation
+	ec.lock.Unlock()
+	ec.misses.Inc(1)
+
+	// The signatures are verified outside the lock, so that evaluations of different signature sets do not wait on each other
+	err := p.Evaluate(msg, sigs)
+
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+	if ec.generation != generation {
+		return err
+	}
+	if elem, ok := ec.entries[key]; ok {
+		// Evaluated concurrently with the same outcome
+		ec.lru.MoveToFront(elem)
+		return err
+	}
+	ec.entries[key] = ec.lru.PushFront(&cacheEntry{key: key, err: err, expires: now.Add(ec.ttl)})
+	for ec.lru.Len() > ec.size {
+		oldest := ec.lru.Back()
+		ec.lru.Remove(oldest)
+		delete(ec.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return err
+}
+
+// evaluationKey digests the ID and canonical encoding of a policy with the message and signature set it is evaluated over,
+// the signatures are sorted, as the outcome of a policy does not depend on their order
+func evaluationKey(id string, digest [sha256.Size]byte, msg []byte, sigs []*ab.SignedData) [sha256.Size]byte {
+	encoded := make([][]byte, len(sigs))
+	for i, sig := range sigs {
+		var buf bytes.Buffer
+		writeField(&buf, sig.PayloadEnvelope)
+		writeField(&buf, sig.Signature)
+		encoded[i] = buf.Bytes()
+	}
+	sort.Sort(byBytes(encoded))
+
+	h := sha256.New()
+	writeField(h, []byte(id))
+	h.Write(digest[:])
+	writeField(h, msg)
+	for _, sig := range encoded {
+		writeField(h, sig)
+	}
+
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// writeField writes data prefixed by its length, so that the boundaries between fields cannot be shifted to collide
+func writeField(w io.Writer, data []byte) {
+	length := make([]byte, 8)
+	binary.BigEndian.PutUint64(length, uint64(len(data)))
+	w.Write(length)
+	w.Write(data)
+}
+
+type byBytes [][]byte
+
+func (s byBytes) Len() int           { return len(s) }
+func (s byBytes) Less(i, j int) bool { return bytes.Compare(s[i], s[j]) < 0 }
+func (s byBytes) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// cachedPolicy evaluates a policy through the cache of its manager
+type cachedPolicy struct {
+	id     string
+	policy *policy
+	cache  *EvaluationCache
+}
+
+func (cp cachedPolicy) Evaluate(msg []byte, sigs []*ab.SignedData) error {
+	return cp.cache.evaluate(cp.id, cp.policy, msg, sigs)
+}
@@ -0,0 +1,244 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policies
+
+import (
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+
+	"github.com/golang/protobuf/proto"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// countingCryptoHelper accepts the signatures which are "valid", counting each verification, and calls during as it verifies
+type countingCryptoHelper struct {
+	verified *int
+	during   func()
+}
+
+func (cch countingCryptoHelper) VerifySignature(msg []byte, identity []byte, signature []byte) bool {
+	*cch.verified++
+	if cch.during != nil {
+		cch.during()
+	}
+	return string(signature) == "valid"
+}
+
+func signedData(signer, signature string) *ab.SignedData {
+	envelope, _ := proto.Marshal(&ab.PayloadEnvelope{Signer: []byte(signer)})
+	return &ab.SignedData{PayloadEnvelope: envelope, Signature: []byte(signature)}
+}
+
+// newCachedManager returns a manager with policy signer requiring one signature by alice or bob, evaluated through a cache of size outcomes
+func newCachedManager(size int, ttl time.Duration) (*ManagerImpl, *EvaluationCache, *countingCryptoHelper, *clock.Fake, gometrics.Registry) {
+	ch := &countingCryptoHelper{verified: new(int)}
+	clk := clock.NewFake()
+	registry := gometrics.NewRegistry()
+	cache := NewEvaluationCache(size, ttl, clk, registry)
+	m := NewManagerImpl(ch)
+	m.SetCache(cache)
+	source, _ := Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.Or(cauthdsl.SignedBy(0), cauthdsl.SignedBy(1)), [][]byte{[]byte("alice"), []byte("bob")})}})
+	addPolicy(m, "signer", source)
+	return m, cache, ch, clk, registry
+}
+
+func evaluate(t *testing.T, m *ManagerImpl, msg string, sigs ...*ab.SignedData) error {
+	policy, ok := m.GetPolicy("signer")
+	if !ok {
+		t.Fatalf("Should have found the policy")
+	}
+	return policy.Evaluate([]byte(msg), sigs)
+}
+
+func TestEvaluationCacheHits(t *testing.T) {
+	m, _, ch, _, registry := newCachedManager(10, 0)
+
+	if err := evaluate(t, m, "msg", signedData("alice", "valid")); err != nil {
+		t.Fatalf("Should have satisfied the policy: %s", err)
+	}
+	verified := *ch.verified
+	if err := evaluate(t, m, "msg", signedData("alice", "valid")); err != nil {
+		t.Fatalf("Should have satisfied the policy from the cache: %s", err)
+	}
+	if *ch.verified != verified {
+		t.Fatalf("Should not have verified the signatures of a repeated evaluation")
+	}
+
+	// The outcome of a rejected signature set is remembered as well
+	for i := 0; i < 2; i++ {
+		if err := evaluate(t, m, "msg", signedData("alice", "forged")); err == nil {
+			t.Fatalf("Should not have satisfied the policy with a forged signature")
+		}
+	}
+
+	hits := registry.Get("evaluation_cache_hits").(gometrics.Counter).Count()
+	misses := registry.Get("evaluation_cache_misses").(gometrics.Counter).Count()
+	if hits != 2 || misses != 2 {
+		t.Fatalf("Expected 2 hits and 2 misses, got %d and %d", hits, misses)
+	}
+}
+
+func TestEvaluationCacheKey(t *testing.T) {
+	m, _, ch, _, _ := newCachedManager(10, 0)
+	alice, bob := signedData("alice", "valid"), signedData("bob", "forged")
+	evaluate(t, m, "msg", alice, bob)
+
+	*ch.verified = 0
+	if err := evaluate(t, m, "msg", bob, alice); err != nil || *ch.verified != 0 {
+		t.Fatalf("Should have remembered the outcome whatever the order of the signatures, got %v after %d verifications", err, *ch.verified)
+	}
+
+	for _, tc := range []struct {
+		name string
+		msg  string
+		sigs []*ab.SignedData
+	}{
+		{"message", "other", []*ab.SignedData{alice, bob}},
+		{"signature", "msg", []*ab.SignedData{signedData("alice", "forged"), bob}},
+		{"signer", "msg", []*ab.SignedData{signedData("carol", "valid"), bob}},
+		{"subset", "msg", []*ab.SignedData{bob}},
+	} {
+		*ch.verified = 0
+		evaluate(t, m, tc.msg, tc.sigs...)
+		if *ch.verified == 0 {
+			t.Errorf("%s: should have verified the signatures when the %s differs", tc.name, tc.name)
+		}
+	}
+}
+
+func TestEvaluationCacheExpiry(t *testing.T) {
+	m, _, ch, clk, _ := newCachedManager(10, time.Minute)
+	evaluate(t, m, "msg", signedData("alice", "valid"))
+
+	clk.Advance(59 * time.Second)
+	*ch.verified = 0
+	if evaluate(t, m, "msg", signedData("alice", "valid")); *ch.verified != 0 {
+		t.Fatalf("Should have remembered the outcome within its TTL")
+	}
+	clk.Advance(time.Second)
+	if evaluate(t, m, "msg", signedData("alice", "valid")); *ch.verified == 0 {
+		t.Fatalf("Should have evaluated again once the outcome expired")
+	}
+}
+
+func TestEvaluationCacheEviction(t *testing.T) {
+	m, _, ch, _, _ := newCachedManager(2, 0)
+	for _, msg := range []string{"a", "b", "a", "c"} {
+		evaluate(t, m, msg, signedData("alice", "valid"))
+	}
+
+	// b was the least recently used when c was remembered
+	for _, tc := range []struct {
+		msg     string
+		evicted bool
+	}{{"a", false}, {"c", false}, {"b", true}} {
+		*ch.verified = 0
+		evaluate(t, m, tc.msg, signedData("alice", "valid"))
+		if evicted := *ch.verified != 0; evicted != tc.evicted {
+			t.Fatalf("Expected the outcome over %s evicted to be %v", tc.msg, tc.evicted)
+		}
+	}
+}
+
+func TestEvaluationCacheInvalidate(t *testing.T) {
+	m, cache, ch, _, _ := newCachedManager(10, 0)
+	evaluate(t, m, "msg", signedData("bob", "valid"))
+
+	// Bob is removed from the policy
+	source, _ := Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{[]byte("alice")})}})
+	addPolicy(m, "signer", source)
+	cache.Invalidate()
+	if err := evaluate(t, m, "msg", signedData("bob", "valid")); err == nil {
+		t.Fatalf("Should not have satisfied the changed policy with a remembered outcome")
+	}
+
+	// An evaluation in progress when the cache is invalidated may have been by the policies replaced, and is not remembered
+	ch.during = cache.Invalidate
+	evaluate(t, m, "other", signedData("alice", "valid"))
+	ch.during = nil
+	*ch.verified = 0
+	if evaluate(t, m, "other", signedData("alice", "valid")); *ch.verified == 0 {
+		t.Fatalf("Should not have remembered the outcome of an evaluation which the cache was invalidated during")
+	}
+}
+
+func TestEvaluationCacheMetaPolicy(t *testing.T) {
+	m, _, _, _, _ := newCachedManager(10, 0)
+	signer, _ := Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.Or(cauthdsl.SignedBy(0), cauthdsl.SignedBy(1)), [][]byte{[]byte("alice"), []byte("bob")})}})
+	policies := map[string][]byte{"signer": signer, "top": metaPolicy(ab.MetaPolicy_ANY, "signer")}
+	if err := proposePolicies(m, []string{"signer", "top"}, policies); err != nil {
+		t.Fatalf("Error proposing the policies: %s", err)
+	}
+	top, _ := m.GetPolicy("top")
+	if err := top.Evaluate([]byte("msg"), []*ab.SignedData{signedData("bob", "valid")}); err != nil {
+		t.Fatalf("Should have satisfied the meta policy: %s", err)
+	}
+
+	// Bob is removed from the policy the meta policy names, which is itself unchanged
+	policies["signer"], _ = Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{[]byte("alice")})}})
+	if err := proposePolicies(m, []string{"signer", "top"}, policies); err != nil {
+		t.Fatalf("Error proposing the policies: %s", err)
+	}
+	top, _ = m.GetPolicy("top")
+	if err := top.Evaluate([]byte("msg"), []*ab.SignedData{signedData("bob", "valid")}); err == nil {
+		t.Fatalf("Should not have satisfied the meta policy with the outcome remembered before its sub-policy changed")
+	}
+}
+
+func TestEvaluationCacheDisabled(t *testing.T) {
+	if cache := NewEvaluationCache(0, time.Minute, clock.NewFake(), nil); cache != nil {
+		t.Fatalf("Expected a cache of size zero to be nil")
+	}
+}
+
+func benchmarkEvaluate(b *testing.B, cache *EvaluationCache) {
+	msg := []byte("message")
+	id, key := newECDSAIdentity(b)
+	signature, err := cauthdsl.SignECDSA(key, msg)
+	if err != nil {
+		b.Fatalf("Error signing: %s", err)
+	}
+	envelope, _ := proto.Marshal(&ab.PayloadEnvelope{Signer: id})
+	sigs := []*ab.SignedData{&ab.SignedData{PayloadEnvelope: envelope, Signature: signature}}
+
+	m := NewManagerImpl(cauthdsl.ECDSAHelper{})
+	m.SetCache(cache)
+	source, _ := Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{id})}})
+	addPolicy(m, WritersPolicyID, source)
+	policy, _ := m.GetPolicy(WritersPolicyID)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := policy.Evaluate(msg, sigs); err != nil {
+			b.Fatalf("Should have satisfied the policy: %s", err)
+		}
+	}
+}
+
+// BenchmarkEvaluateUncached verifies the signature of a repeat submitter each time
+func BenchmarkEvaluateUncached(b *testing.B) {
+	benchmarkEvaluate(b, nil)
+}
+
+// BenchmarkEvaluateCached remembers the outcome for a repeat submitter after the first evaluation
+func BenchmarkEvaluateCached(b *testing.B) {
+	benchmarkEvaluate(b, NewEvaluationCache(1000, time.Minute, clock.Real{}, nil))
+}
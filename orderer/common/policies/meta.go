@@ -0,0 +1,95 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policies
+
+import (
+	"fmt"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+// required returns how many of the sub-policies of a meta policy which names some must be satisfied
+func required(meta *ab.MetaPolicy) int {
+	count := len(meta.SubPolicies)
+	switch meta.Rule {
+	case ab.MetaPolicy_ANY:
+		return 1
+	case ab.MetaPolicy_ALL:
+		return count
+	default:
+		// Strictly more than half
+		return count/2 + 1
+	}
+}
+
+// evaluateMeta evaluates the sub-policies of a meta policy as resolved in policies, stopping once the outcome is decided
+// A sub-policy which does not exist is evaluated as the default policy, which rejects
+func (p *policy) evaluateMeta(policies map[string]*policy, msg []byte, sigs []*ab.SignedData) error {
+	count := len(p.meta.SubPolicies)
+	if count == 0 {
+		return fmt.Errorf("Meta policy %v names no sub-policies, results in reject", p.meta.Rule)
+	}
+	needed := required(p.meta)
+
+	satisfied := 0
+	for i, id := range p.meta.SubPolicies {
+		sub := policies[id]
+		var err error
+		if sub != nil && sub.meta != nil {
+			err = sub.evaluateMeta(policies, msg, sigs)
+		} else {
+			err = sub.Evaluate(msg, sigs)
+		}
+		if err == nil {
+			satisfied++
+		}
+		if satisfied >= needed {
+			return nil
+		}
+		if satisfied+count-i-1 < needed {
+			break
+		}
+	}
+	return fmt.Errorf("Meta policy %v requires %d of its %d sub-policies, but fewer were satisfied", p.meta.Rule, needed, count)
+}
+
+// cycle returns the path by which the pending meta policies lead from the meta policy proposed as id back to it, or nil if
+// they do not
+func (pm *ManagerImpl) cycle(id string, meta *ab.MetaPolicy) []string {
+	visited := make(map[string]bool)
+	var walk func(path []string, meta *ab.MetaPolicy) []string
+	walk = func(path []string, meta *ab.MetaPolicy) []string {
+		for _, sub := range meta.SubPolicies {
+			// The path is copied, so that the paths of sibling sub-policies do not share it
+			next := append(append([]string{}, path...), sub)
+			if sub == id {
+				return next
+			}
+			if visited[sub] {
+				continue
+			}
+			visited[sub] = true
+			if p, ok := pm.pendingPolicies[sub]; ok && p.meta != nil {
+				if found := walk(next, p.meta); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+	return walk([]string{id}, meta)
+}
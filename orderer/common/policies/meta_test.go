@@ -0,0 +1,148 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policies
+
+import (
+	"strings"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func metaPolicy(rule ab.MetaPolicy_Rule, subPolicies ...string) []byte {
+	data, err := proto.Marshal(&ab.Policy{Type: &ab.Policy_Meta{Meta: &ab.MetaPolicy{Rule: rule, SubPolicies: subPolicies}}})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// proposePolicies proposes the policies in the order given as one proposal, committing it unless a policy is refused
+func proposePolicies(manager *ManagerImpl, ids []string, policies map[string][]byte) error {
+	manager.BeginConfig()
+	for _, id := range ids {
+		if err := manager.ProposeConfig(&ab.Configuration{ID: id, Type: ab.Configuration_Policy, Data: policies[id]}); err != nil {
+			manager.RollbackConfig()
+			return err
+		}
+	}
+	manager.CommitConfig()
+	return nil
+}
+
+func TestMetaPolicyNested(t *testing.T) {
+	policies := map[string][]byte{
+		"accept":  acceptAllPolicy,
+		"accept2": acceptAllPolicy,
+		"reject":  rejectAllPolicy,
+		// One level
+		"anyOf":      metaPolicy(ab.MetaPolicy_ANY, "reject", "accept"),
+		"allOf":      metaPolicy(ab.MetaPolicy_ALL, "accept", "reject"),
+		"allAccept":  metaPolicy(ab.MetaPolicy_ALL, "accept", "accept2"),
+		"halfOf":     metaPolicy(ab.MetaPolicy_MAJORITY, "accept", "reject"),
+		"noneOf":     metaPolicy(ab.MetaPolicy_MAJORITY),
+		"anyOfNone":  metaPolicy(ab.MetaPolicy_ANY),
+		"allOfNone":  metaPolicy(ab.MetaPolicy_ALL),
+		"anyMissing": metaPolicy(ab.MetaPolicy_ANY, "missing"),
+		// Two levels
+		"majority": metaPolicy(ab.MetaPolicy_MAJORITY, "anyOf", "allOf", "allAccept"),
+		"minority": metaPolicy(ab.MetaPolicy_MAJORITY, "allOf", "halfOf", "anyOf"),
+		// Three levels
+		"top":      metaPolicy(ab.MetaPolicy_ALL, "majority", "anyOf"),
+		"topFails": metaPolicy(ab.MetaPolicy_ANY, "minority", "noneOf", "anyMissing"),
+	}
+	ids := make([]string, 0, len(policies))
+	for id := range policies {
+		ids = append(ids, id)
+	}
+	m := NewManagerImpl(&mockCryptoHelper{})
+	if err := proposePolicies(m, ids, policies); err != nil {
+		t.Fatalf("Error proposing the policies: %s", err)
+	}
+
+	for id, satisfied := range map[string]bool{
+		"anyOf":      true,
+		"allOf":      false,
+		"allAccept":  true,
+		"halfOf":     false,
+		"noneOf":     false,
+		"anyOfNone":  false,
+		"allOfNone":  false,
+		"anyMissing": false,
+		"majority":   true,
+		"minority":   false,
+		"top":        true,
+		"topFails":   false,
+	} {
+		policy, ok := m.GetPolicy(id)
+		if !ok {
+			t.Fatalf("Expected policy %s to be committed", id)
+		}
+		if err := policy.Evaluate(nil, nil); (err == nil) != satisfied {
+			t.Errorf("Expected %s to be satisfied %t, got %v", id, satisfied, err)
+		}
+	}
+
+	// The sub-policies are resolved as they are evaluated, so a meta policy follows changes to them
+	policies["reject"] = acceptAllPolicy
+	if err := proposePolicies(m, ids, policies); err != nil {
+		t.Fatalf("Error proposing the policies: %s", err)
+	}
+	if policy, _ := m.GetPolicy("minority"); policy.Evaluate(nil, nil) != nil {
+		t.Errorf("Expected minority to be satisfied once its sub-policies accept")
+	}
+}
+
+func TestMetaPolicyCycle(t *testing.T) {
+	policies := map[string][]byte{
+		"accept": acceptAllPolicy,
+		"a":      metaPolicy(ab.MetaPolicy_ANY, "accept", "b"),
+		"b":      metaPolicy(ab.MetaPolicy_ALL, "c"),
+		"c":      metaPolicy(ab.MetaPolicy_MAJORITY, "accept", "a"),
+		"self":   metaPolicy(ab.MetaPolicy_ANY, "self"),
+	}
+
+	// The cycle is refused whichever of its policies is proposed last
+	for _, ids := range [][]string{{"accept", "a", "b", "c"}, {"c", "b", "accept", "a"}, {"b", "a", "c"}} {
+		m := NewManagerImpl(&mockCryptoHelper{})
+		err := proposePolicies(m, ids, policies)
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Fatalf("Expected a cycle error proposing %v, got %v", ids, err)
+		}
+		last := ids[len(ids)-1]
+		if !strings.HasPrefix(err.Error(), "Meta policy "+last+" names itself through the cycle "+last+" -> ") {
+			t.Errorf("Expected the error to describe the cycle from %s, got %s", last, err)
+		}
+		if _, ok := m.GetPolicy("a"); ok {
+			t.Errorf("Expected no policy of the refused proposal to be committed")
+		}
+	}
+
+	m := NewManagerImpl(&mockCryptoHelper{})
+	if err := proposePolicies(m, []string{"self"}, policies); err == nil || err.Error() != "Meta policy self names itself through the cycle self -> self" {
+		t.Errorf("Expected a policy naming itself to be refused, got %v", err)
+	}
+}
+
+func TestMetaPolicyUnknownRule(t *testing.T) {
+	m := NewManagerImpl(&mockCryptoHelper{})
+	if err := proposePolicies(m, []string{"unknown"}, map[string][]byte{"unknown": metaPolicy(ab.MetaPolicy_Rule(7), "a")}); err == nil {
+		t.Errorf("Expected a meta policy with an unknown rule to be refused")
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policies
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Marshal returns the canonical encoding of a policy, the only encoding of it which Parse accepts
+func Marshal(policy *ab.Policy) ([]byte, error) {
+	return proto.Marshal(policy)
+}
+
+// Parse converts the data of a Policy configuration item into a policy which may be evaluated, the sub-policies of a meta
+// policy are resolved among the policies pm has committed as it is evaluated
+// It refuses data which is not the canonical encoding of a well formed policy, so that a bad policy is rejected when it is
+// configured rather than when it is evaluated, and every orderer stores and hashes the same bytes for the same policy
+func (pm *ManagerImpl) Parse(data []byte) (Policy, error) {
+	return pm.parse(data)
+}
+
+func (pm *ManagerImpl) parse(data []byte) (*policy, error) {
+	source := &ab.Policy{}
+	if err := proto.Unmarshal(data, source); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling policy: %s", err)
+	}
+	// Fields this orderer does not know, and so cannot evaluate, are dropped by Unmarshal, and so fail this check as well
+	if canonical, err := Marshal(source); err != nil || !bytes.Equal(canonical, data) {
+		return nil, fmt.Errorf("Policy is not canonically encoded, or holds fields which are not understood")
+	}
+
+	var evaluator *cauthdsl.SignaturePolicyEvaluator
+	var err error
+	switch t := source.Type.(type) {
+	case *ab.Policy_SignaturePolicy:
+		if t.SignaturePolicy == nil {
+			return nil, fmt.Errorf("Nil signature policy received")
+		}
+		evaluator, err = cauthdsl.NewSignaturePolicyEvaluator(t.SignaturePolicy, pm.ch)
+	case *ab.Policy_SignatureThreshold:
+		if t.SignatureThreshold == nil {
+			return nil, fmt.Errorf("Nil signature threshold policy received")
+		}
+		evaluator, err = cauthdsl.NewSignatureThresholdEvaluator(t.SignatureThreshold, pm.ch)
+	case *ab.Policy_Meta:
+		if err := checkMeta(t.Meta); err != nil {
+			return nil, err
+		}
+		return &policy{source: source, digest: sha256.Sum256(data), meta: t.Meta, manager: pm}, nil
+	default:
+		return nil, fmt.Errorf("Unknown policy type: %T", source.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy{
+		evaluator: evaluator,
+		source:    source,
+		digest:    sha256.Sum256(data),
+	}, nil
+}
+
+// checkMeta returns an error if a meta policy has an unknown rule, or names a sub-policy which is empty or already named
+func checkMeta(meta *ab.MetaPolicy) error {
+	if meta == nil {
+		return fmt.Errorf("Nil meta policy received")
+	}
+	if _, ok := ab.MetaPolicy_Rule_name[int32(meta.Rule)]; !ok {
+		return fmt.Errorf("Unknown meta policy rule %d", meta.Rule)
+	}
+	named := make(map[string]struct{}, len(meta.SubPolicies))
+	for i, id := range meta.SubPolicies {
+		if id == "" {
+			return fmt.Errorf("Sub-policy %d of the meta policy is not named", i)
+		}
+		// A sub-policy named twice would be counted twice
+		if _, ok := named[id]; ok {
+			return fmt.Errorf("Sub-policy %s is named more than once", id)
+		}
+		named[id] = struct{}{}
+	}
+	return nil
+}
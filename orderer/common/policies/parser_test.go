@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policies
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func signaturePolicy(envelope *ab.SignaturePolicyEnvelope) *ab.Policy {
+	return &ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: envelope}}
+}
+
+func thresholdPolicy(n int32, identities ...string) *ab.Policy {
+	ids := make([][]byte, len(identities))
+	for i, id := range identities {
+		ids[i] = []byte(id)
+	}
+	return &ab.Policy{Type: &ab.Policy_SignatureThreshold{SignatureThreshold: cauthdsl.SignatureThreshold(n, ids)}}
+}
+
+func marshalPolicy(t *testing.T, policy *ab.Policy) []byte {
+	data, err := Marshal(policy)
+	if err != nil {
+		t.Fatalf("Error marshaling policy: %s", err)
+	}
+	return data
+}
+
+// The golden encodings below pin the wire format of policies, a change to them would change the bytes signed and hashed for
+// every policy already configured
+func TestParseGolden(t *testing.T) {
+	testCases := []struct {
+		name    string
+		policy  *ab.Policy
+		encoded string
+	}{
+		{"Signature", signaturePolicy(cauthdsl.Envelope(cauthdsl.Or(cauthdsl.SignedBy(0), cauthdsl.SignedBy(1)), [][]byte{[]byte("a"), []byte("b")})), "1214120c120a080112020800120208011a01611a0162"},
+		{"RejectAll", signaturePolicy(cauthdsl.RejectAllPolicy), "1206120412020801"},
+		{"Threshold", thresholdPolicy(2, "a", "b", "c"), "1a0b0802120161120162120163"},
+		{"Meta", &ab.Policy{Type: &ab.Policy_Meta{Meta: &ab.MetaPolicy{Rule: ab.MetaPolicy_MAJORITY, SubPolicies: []string{"x", "y", "z"}}}}, "220b080212017812017912017a"},
+	}
+
+	m := NewManagerImpl(&mockCryptoHelper{})
+	for _, tc := range testCases {
+		data := marshalPolicy(t, tc.policy)
+		if hex.EncodeToString(data) != tc.encoded {
+			t.Errorf("%s: policy encoding has changed: %x", tc.name, data)
+			continue
+		}
+		golden, _ := hex.DecodeString(tc.encoded)
+		parsed, err := m.parse(golden)
+		if err != nil {
+			t.Fatalf("%s: error parsing the golden encoding: %s", tc.name, err)
+		}
+		if !proto.Equal(parsed.source, tc.policy) {
+			t.Errorf("%s: expected the golden encoding to parse to %v, got %v", tc.name, tc.policy, parsed.source)
+		}
+	}
+}
+
+func TestParseValidation(t *testing.T) {
+	envelope := func(policy *ab.SignaturePolicy, identities ...string) *ab.Policy {
+		ids := make([][]byte, len(identities))
+		for i, id := range identities {
+			ids[i] = []byte(id)
+		}
+		return signaturePolicy(cauthdsl.Envelope(policy, ids))
+	}
+	meta := func(rule ab.MetaPolicy_Rule, subPolicies ...string) *ab.Policy {
+		return &ab.Policy{Type: &ab.Policy_Meta{Meta: &ab.MetaPolicy{Rule: rule, SubPolicies: subPolicies}}}
+	}
+
+	canonical := marshalPolicy(t, thresholdPolicy(1, "a"))
+
+	testCases := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{"Garbage", []byte("garbage"), "Error unmarshaling policy"},
+		{"UnknownField", append(append([]byte{}, canonical...), 0x78, 0x01), "not canonically encoded"},
+		{"RepeatedField", append(append([]byte{}, canonical...), canonical...), "not canonically encoded"},
+		{"NoType", []byte{}, "Unknown policy type"},
+		{"EmptyEnvelope", []byte{0x12, 0x00}, "Nil signature policy"},
+		{"Version", marshalPolicy(t, signaturePolicy(&ab.SignaturePolicyEnvelope{Version: 1, Policy: cauthdsl.SignedBy(0), Identities: [][]byte{[]byte("a")}})), "version"},
+		{"EmptyInnerPolicy", marshalPolicy(t, envelope(cauthdsl.NOutOf(1, []*ab.SignaturePolicy{&ab.SignaturePolicy{}}), "a")), "Unknown type"},
+		{"NegativeNOutOf", marshalPolicy(t, envelope(cauthdsl.NOutOf(-1, []*ab.SignaturePolicy{cauthdsl.SignedBy(0)}), "a")), "not negative"},
+		{"SignedByNoIdentities", marshalPolicy(t, envelope(cauthdsl.SignedBy(0))), "out of range"},
+		{"SignedByOutOfRange", marshalPolicy(t, envelope(cauthdsl.SignedBy(1), "a")), "out of range"},
+		{"EmptyIdentity", marshalPolicy(t, envelope(cauthdsl.SignedBy(0), "")), "Identity 0 is empty"},
+		{"DuplicateIdentity", marshalPolicy(t, envelope(cauthdsl.SignedBy(0), "a", "a")), "Identity 1 is listed more than once"},
+		{"EmptyThreshold", []byte{0x1a, 0x00}, "lists no identities"},
+		{"ThresholdNoIdentities", marshalPolicy(t, thresholdPolicy(1)), "lists no identities"},
+		{"ThresholdZero", marshalPolicy(t, thresholdPolicy(0, "a")), "threshold must be between 1 and the 1 identities"},
+		{"ThresholdTooLarge", marshalPolicy(t, thresholdPolicy(2, "a")), "threshold must be between 1 and the 1 identities"},
+		{"ThresholdEmptyIdentity", marshalPolicy(t, thresholdPolicy(1, "a", "")), "Identity 1 is empty"},
+		{"ThresholdDuplicateIdentity", marshalPolicy(t, thresholdPolicy(1, "a", "a")), "Identity 1 is listed more than once"},
+		{"MetaUnknownRule", marshalPolicy(t, meta(ab.MetaPolicy_Rule(7), "a")), "Unknown meta policy rule 7"},
+		{"MetaUnnamed", marshalPolicy(t, meta(ab.MetaPolicy_ANY, "a", "")), "Sub-policy 1 of the meta policy is not named"},
+		{"MetaDuplicate", marshalPolicy(t, meta(ab.MetaPolicy_ALL, "a", "a")), "Sub-policy a is named more than once"},
+	}
+
+	m := NewManagerImpl(&mockCryptoHelper{})
+	for _, tc := range testCases {
+		_, err := m.Parse(tc.data)
+		if err == nil {
+			t.Errorf("%s: should have been refused", tc.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.error) {
+			t.Errorf("%s: expected an error containing %q, got: %s", tc.name, tc.error, err)
+		}
+	}
+}
+
+func TestProposeRejectsInvalidPolicy(t *testing.T) {
+	policyID := "policyID"
+	m := NewManagerImpl(&mockCryptoHelper{})
+	addPolicy(m, policyID, acceptAllPolicy)
+
+	err := proposePolicies(m, []string{policyID}, map[string][]byte{policyID: marshalPolicy(t, thresholdPolicy(2, "a"))})
+	if err == nil || !strings.Contains(err.Error(), "Invalid policy "+policyID) {
+		t.Fatalf("Expected the invalid policy to be refused when configured, got: %v", err)
+	}
+
+	policy, _ := m.GetPolicy(policyID)
+	if err = policy.Evaluate(nil, nil); err != nil {
+		t.Fatalf("The previously configured policy should remain in force")
+	}
+}
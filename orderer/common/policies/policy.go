@@ -17,14 +17,19 @@ limitations under the License.
 package policies
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"strings"
+	"sync/atomic"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
 
-	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
 )
 
+var logger = logging.MustGetLogger("orderer/common/policies")
+
 // Policy is used to determine if a signature is valid
 type Policy interface {
 	// Evaluate returns nil if a msg is properly signed by sigs, or an error indicating why it failed
@@ -35,53 +40,69 @@ type Policy interface {
 // It is intended to discourage use of the other exported ManagerImpl methods
 // which are used for updating policy by the ConfigManager
 type Manager interface {
-	// GetPolicy returns a policy and true if it was the policy requested, or false if it is the default policy
+	// GetPolicy returns a policy and true if it was the policy requested, or false and the default policy standing in for it,
+	// which rejects or accepts every message as the manager is configured
 	GetPolicy(id string) (Policy, bool)
 }
 
-type policy struct {
-	source    *ab.Policy
-	evaluator *cauthdsl.SignaturePolicyEvaluator
-}
+const (
+	// WritersPolicyID is the ID of the policy which the signatures of the messages broadcast to a chain must satisfy
+	WritersPolicyID = "Writers"
+	// ReadersPolicyID is the ID of the policy which the signatures of the seeks delivered from a chain must satisfy
+	ReadersPolicyID = "Readers"
+	// ChainCreatorsPolicyID is the ID of the policy of the system chain which the signatures of chain creation transactions must satisfy
+	ChainCreatorsPolicyID = "ChainCreators"
+)
 
-func newPolicy(policySource *ab.Policy, ch cauthdsl.CryptoHelper) (*policy, error) {
-	envelopeWrapper, ok := policySource.Type.(*ab.Policy_SignaturePolicy)
+// ManagerResolver returns the policy manager of a chain, the default chain if chainID is empty, or false if the chain has none
+type ManagerResolver func(chainID []byte) (Manager, bool)
 
+// Authorize evaluates the policy id of a chain over msg and sigs, a chain which resolve does not know is left to the caller to refuse
+func Authorize(resolve ManagerResolver, chainID []byte, id string, msg []byte, sigs []*ab.SignedData) error {
+	manager, ok := resolve(chainID)
 	if !ok {
-		return nil, fmt.Errorf("Unknown policy type: %T", policySource.Type)
-	}
-
-	if envelopeWrapper.SignaturePolicy == nil {
-		return nil, fmt.Errorf("Nil signature policy received")
+		return nil
 	}
+	policy, _ := manager.GetPolicy(id)
+	return policy.Evaluate(msg, sigs)
+}
 
-	sigPolicy := envelopeWrapper.SignaturePolicy
+// defaultPolicy stands in for a policy which does not exist
+type defaultPolicy struct {
+	id   string
+	deny bool
+}
 
-	evaluator, err := cauthdsl.NewSignaturePolicyEvaluator(sigPolicy, ch)
-	if err != nil {
-		return nil, err
+// Evaluate rejects every message if the default is to deny, and accepts every message otherwise
+func (dp defaultPolicy) Evaluate(msg []byte, sigs []*ab.SignedData) error {
+	if dp.deny {
+		return fmt.Errorf("Policy %s does not exist, and missing policies deny", dp.id)
 	}
+	return nil
+}
 
-	return &policy{
-		evaluator: evaluator,
-		source:    policySource,
-	}, nil
+type policy struct {
+	source    *ab.Policy
+	digest    [sha256.Size]byte                  // Of the canonical encoding of source
+	evaluator *cauthdsl.SignaturePolicyEvaluator // Nil for a meta policy
+	meta      *ab.MetaPolicy
+	manager   *ManagerImpl // Resolves the sub-policies of a meta policy
 }
 
 // Evaluate returns nil if a msg is properly signed by sigs, or an error indicating why it failed
+// A signature whose payload envelope is malformed satisfies nothing, but the others are still evaluated
 func (p *policy) Evaluate(msg []byte, sigs []*ab.SignedData) error {
 	if p == nil {
 		return fmt.Errorf("Evaluated default policy, results in reject")
 	}
+	if p.meta != nil {
+		// The sub-policies are all resolved from the same committed policies, among which there are no cycles
+		return p.evaluateMeta(p.manager.policies.Load().(map[string]*policy), msg, sigs)
+	}
 
-	identities := make([][]byte, len(sigs))
+	identities := cauthdsl.Signers(sigs)
 	signatures := make([][]byte, len(sigs))
 	for i, sigpair := range sigs {
-		envelope := &ab.PayloadEnvelope{}
-		if err := proto.Unmarshal(sigpair.PayloadEnvelope, envelope); err != nil {
-			return fmt.Errorf("Failed to unmarshal the payload envelope to extract the signatures")
-		}
-		identities[i] = envelope.Signer
 		signatures[i] = sigpair.Signature
 	}
 	// XXX This is wrong, as the signatures are over the payload envelope, not the message, fix either here, or in cauthdsl once transaction is finalized
@@ -94,24 +115,50 @@ func (p *policy) Evaluate(msg []byte, sigs []*ab.SignedData) error {
 // ManagerImpl is an implementation of Manager and configtx.ConfigHandler
 // In general, it should only be referenced as an Impl for the configtx.ConfigManager
 type ManagerImpl struct {
-	policies        map[string]*policy
+	policies        atomic.Value // The map[string]*policy committed, replaced whole so that GetPolicy never waits on a proposal
 	pendingPolicies map[string]*policy
 	ch              cauthdsl.CryptoHelper
+	defaultDeny     bool             // Whether the default policy rejects every message, rather than accepting every message
+	cache           *EvaluationCache // Remembers the outcomes of the committed policies, nil to evaluate each time
 }
 
 // NewManagerImpl creates a new ManagerImpl with the given CryptoHelper
 func NewManagerImpl(ch cauthdsl.CryptoHelper) *ManagerImpl {
-	return &ManagerImpl{
-		ch:       ch,
-		policies: make(map[string]*policy),
+	pm := &ManagerImpl{
+		ch:          ch,
+		defaultDeny: true,
 	}
+	pm.policies.Store(make(map[string]*policy))
+	return pm
+}
+
+// SetDefaultDeny sets whether the default policy returned for a missing policy rejects every message, as it does unless set otherwise,
+// or accepts every message, it must be set before the manager is used
+func (pm *ManagerImpl) SetDefaultDeny(deny bool) {
+	pm.defaultDeny = deny
 }
 
-// GetPolicy returns a policy and true if it was the policy requested, or false if it is the default policy
+// SetCache sets the cache through which the committed policies are evaluated, it must be set before the manager is used,
+// and is invalidated as each configuration is committed
+func (pm *ManagerImpl) SetCache(cache *EvaluationCache) {
+	pm.cache = cache
+}
+
+// GetPolicy returns a policy and true if it was the policy requested, or false and the default policy, logging that it is missing
 func (pm *ManagerImpl) GetPolicy(id string) (Policy, bool) {
-	policy, ok := pm.policies[id]
-	// Note the nil policy evaluates fine
-	return policy, ok
+	policy, ok := pm.policies.Load().(map[string]*policy)[id]
+	if !ok {
+		decision := "accepting"
+		if pm.defaultDeny {
+			decision = "rejecting"
+		}
+		logger.Warningf("Policy %s does not exist, %s by default", id, decision)
+		return defaultPolicy{id: id, deny: pm.defaultDeny}, false
+	}
+	if pm.cache != nil {
+		return cachedPolicy{id: id, policy: policy, cache: pm.cache}, true
+	}
+	return policy, true
 }
 
 // BeginConfig is used to start a new configuration proposal
@@ -132,8 +179,12 @@ func (pm *ManagerImpl) CommitConfig() {
 	if pm.pendingPolicies == nil {
 		panic("Programming error, cannot call commit without an existing proposal")
 	}
-	pm.policies = pm.pendingPolicies
+	pm.policies.Store(pm.pendingPolicies)
 	pm.pendingPolicies = nil
+	// The outcome of a meta policy is remembered by its own digest, which does not change with the policies it names
+	if pm.cache != nil {
+		pm.cache.Invalidate()
+	}
 }
 
 // ProposeConfig is used to add new configuration to the configuration proposal
@@ -142,15 +193,15 @@ func (pm *ManagerImpl) ProposeConfig(configItem *ab.Configuration) error {
 		return fmt.Errorf("Expected type of Configuration_Policy, got %v", configItem.Type)
 	}
 
-	policy := &ab.Policy{}
-	err := proto.Unmarshal(configItem.Data, policy)
+	cPolicy, err := pm.parse(configItem.Data)
 	if err != nil {
-		return err
+		return fmt.Errorf("Invalid policy %s: %s", configItem.ID, err)
 	}
-
-	cPolicy, err := newPolicy(policy, pm.ch)
-	if err != nil {
-		return err
+	if cPolicy.meta != nil {
+		// A cycle is found once its last policy is proposed, as the policies of a configtx are all proposed before it is committed
+		if cycle := pm.cycle(configItem.ID, cPolicy.meta); cycle != nil {
+			return fmt.Errorf("Meta policy %s names itself through the cycle %s", configItem.ID, strings.Join(cycle, " -> "))
+		}
 	}
 
 	pm.pendingPolicies[configItem.ID] = cPolicy
@@ -17,7 +17,14 @@ limitations under the License.
 package policies
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"testing"
+	"time"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
@@ -109,3 +116,97 @@ func TestRejectOnUnknown(t *testing.T) {
 		t.Fatalf("Should have errored evaluating the default policy")
 	}
 }
+
+func TestAcceptOnUnknown(t *testing.T) {
+	m := NewManagerImpl(&mockCryptoHelper{})
+	m.SetDefaultDeny(false)
+	policy, ok := m.GetPolicy("FakePolicyID")
+	if ok {
+		t.Errorf("Should not have found policy which was never added, but did")
+	}
+	err := policy.Evaluate(nil, nil)
+	if err != nil {
+		t.Fatalf("Should not have errored evaluating the default policy which accepts: %s", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	policyID := "policyID"
+	m := NewManagerImpl(&mockCryptoHelper{})
+	addPolicy(m, policyID, acceptAllPolicy)
+
+	m.BeginConfig()
+	err := m.ProposeConfig(&ab.Configuration{
+		ID:   policyID,
+		Type: ab.Configuration_Policy,
+		Data: rejectAllPolicy,
+	})
+	if err != nil {
+		t.Fatalf("Error proposing policy: %s", err)
+	}
+	policy, _ := m.GetPolicy(policyID)
+	if err = policy.Evaluate(nil, nil); err != nil {
+		t.Fatalf("A proposed policy should not be in force before it is committed")
+	}
+
+	m.RollbackConfig()
+	policy, _ = m.GetPolicy(policyID)
+	if err = policy.Evaluate(nil, nil); err != nil {
+		t.Fatalf("A rolled back policy should not be in force")
+	}
+}
+
+func newECDSAIdentity(t testing.TB) ([]byte, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	return cert, key
+}
+
+func TestSignatureThreshold(t *testing.T) {
+	msg := []byte("message")
+	var ids [][]byte
+	var sigs []*ab.SignedData
+	for i := 0; i < 3; i++ {
+		id, key := newECDSAIdentity(t)
+		signature, err := cauthdsl.SignECDSA(key, msg)
+		if err != nil {
+			t.Fatalf("Error signing: %s", err)
+		}
+		envelope, _ := proto.Marshal(&ab.PayloadEnvelope{Signer: id})
+		ids, sigs = append(ids, id), append(sigs, &ab.SignedData{PayloadEnvelope: envelope, Signature: signature})
+	}
+	source, _ := proto.Marshal(&ab.Policy{Type: &ab.Policy_SignatureThreshold{SignatureThreshold: cauthdsl.SignatureThreshold(2, ids)}})
+
+	policyID := "policyID"
+	m := NewManagerImpl(cauthdsl.ECDSAHelper{})
+	addPolicy(m, policyID, source)
+	policy, _ := m.GetPolicy(policyID)
+
+	// A malformed payload envelope satisfies nothing, but the signatures which follow it are evaluated
+	malformed := &ab.SignedData{PayloadEnvelope: []byte("malformed"), Signature: sigs[0].Signature}
+	if err := policy.Evaluate(msg, []*ab.SignedData{malformed, sigs[1], sigs[2]}); err != nil {
+		t.Errorf("Expected two of the three identities to satisfy the policy: %s", err)
+	}
+	if err := policy.Evaluate(msg, []*ab.SignedData{malformed, sigs[1], sigs[1]}); err == nil {
+		t.Errorf("Expected one identity signing twice not to satisfy the policy")
+	}
+
+	m.BeginConfig()
+	unsatisfiable, _ := proto.Marshal(&ab.Policy{Type: &ab.Policy_SignatureThreshold{SignatureThreshold: cauthdsl.SignatureThreshold(4, ids)}})
+	if err := m.ProposeConfig(&ab.Configuration{ID: policyID, Type: ab.Configuration_Policy, Data: unsatisfiable}); err == nil {
+		t.Errorf("Expected a threshold beyond the identities listed to be refused")
+	}
+	m.RollbackConfig()
+}
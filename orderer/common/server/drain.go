@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Drain counts the calls in flight through its interceptors, so that a server may be stopped once they have completed,
+// the vendored gRPC predates GracefulStop
+type Drain struct {
+	lock     sync.Mutex // Guards stopping, so that no call is added to calls once Stop waits on it
+	stopping bool
+	calls    sync.WaitGroup
+}
+
+// AddDrain appends the interceptors of a new drain and returns it, they are innermost so that a refused call is still logged and counted
+func (b *Builder) AddDrain() *Drain {
+	d := &Drain{}
+	b.Append(d.unary, d.stream)
+	return d
+}
+
+// begin returns false once the server is stopping, otherwise the caller must end the call with calls.Done
+func (d *Drain) begin(method string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.stopping {
+		logger.Debugf("Refusing %s, the server is stopping", method)
+		return false
+	}
+	d.calls.Add(1)
+	return true
+}
+
+func (d *Drain) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !d.begin(info.FullMethod) {
+		return nil, grpc.Errorf(codes.Unavailable, "server is stopping")
+	}
+	defer d.calls.Done()
+	return handler(ctx, req)
+}
+
+func (d *Drain) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !d.begin(info.FullMethod) {
+		return grpc.Errorf(codes.Unavailable, "server is stopping")
+	}
+	defer d.calls.Done()
+	return handler(srv, ss)
+}
+
+// Stop closes lis, so that no connection is accepted, refuses new calls on the connections already accepted, and stops srv
+// once the calls in flight have completed, or once timeout has elapsed, closing the connections of those which have not
+func (d *Drain) Stop(srv *grpc.Server, lis net.Listener, timeout time.Duration) {
+	lis.Close()
+	d.lock.Lock()
+	d.stopping = true
+	d.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.calls.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logger.Warningf("Calls were still in flight %v after the server began stopping, closing their connections", timeout)
+	}
+	srv.Stop()
+}
@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestDrainStop(t *testing.T) {
+	d := (&Builder{}).AddDrain()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s", err)
+	}
+	srv := grpc.NewServer()
+	go srv.Serve(lis)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go d.stream(nil, &mockStream{ctx: context.Background()}, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		d.Stop(srv, lis, time.Minute)
+		close(stopped)
+	}()
+
+	// New calls are refused once the drain begins, while the call in flight continues
+	for {
+		_, err := d.unary(context.Background(), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+		if grpc.Code(err) == codes.Unavailable {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-stopped:
+		t.Fatalf("Should not have stopped while a call was in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("Should have stopped once the call in flight completed")
+	}
+	if _, err := net.Dial("tcp", lis.Addr().String()); err == nil {
+		t.Fatalf("Should have stopped accepting connections")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	d := (&Builder{}).AddDrain()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s", err)
+	}
+	srv := grpc.NewServer()
+	go srv.Serve(lis)
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	go d.unary(context.Background(), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		d.Stop(srv, lis, 10*time.Millisecond)
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("Should have stopped once the timeout elapsed, despite the call in flight")
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// recoverUnary fails a call whose handler panics with Internal, rather than letting the panic crash the orderer
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recoverCall(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func recoverStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverCall(info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+func recoverCall(method string, err *error) {
+	if r := recover(); r != nil {
+		logger.Errorf("Recovered from a panic serving %s: %v\n%s", method, r, debug.Stack())
+		*err = grpc.Errorf(codes.Internal, "internal error serving %s", method)
+	}
+}
+
+// logUnary logs each call once it completes, with the identity of the client, the resulting code, and its duration
+func logUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func logStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logCall(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	logger.Infof("%s from %s completed with %s in %s", method, extractIdentity(ctx), grpc.Code(err), time.Since(start))
+}
+
+// callMetrics counts the calls served, those which failed, and the marshaled bytes of the messages sent before any compression
+type callMetrics struct {
+	calls    gometrics.Counter
+	failures gometrics.Counter
+	sent     gometrics.Counter
+}
+
+func newMetrics(registry gometrics.Registry) *callMetrics {
+	return &callMetrics{
+		calls:    gometrics.GetOrRegisterCounter("calls", registry),
+		failures: gometrics.GetOrRegisterCounter("failures", registry),
+		sent:     gometrics.GetOrRegisterCounter("message_bytes_sent", registry),
+	}
+}
+
+func (m *callMetrics) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	m.count(err)
+	m.countMessage(resp)
+	return resp, err
+}
+
+func (m *callMetrics) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, &countingStream{ServerStream: ss, metrics: m})
+	m.count(err)
+	return err
+}
+
+func (m *callMetrics) count(err error) {
+	m.calls.Inc(1)
+	if err != nil {
+		m.failures.Inc(1)
+	}
+}
+
+func (m *callMetrics) countMessage(msg interface{}) {
+	if pb, ok := msg.(proto.Message); ok {
+		m.sent.Inc(int64(proto.Size(pb)))
+	}
+}
+
+type countingStream struct {
+	grpc.ServerStream
+	metrics *callMetrics
+}
+
+func (cs *countingStream) SendMsg(m interface{}) error {
+	cs.metrics.countMessage(m)
+	return cs.ServerStream.SendMsg(m)
+}
+
+// Identity is who a client connected as
+type Identity struct {
+	Fingerprint string // The hex encoded SHA256 of the certificate the client presented over TLS, empty if it presented none
+	Host        string // The host the client connected from
+}
+
+// String returns the fingerprint of the client, or its host if it presented no certificate
+func (id Identity) String() string {
+	if id.Fingerprint != "" {
+		return id.Fingerprint
+	}
+	return id.Host
+}
+
+func extractIdentity(ctx context.Context) Identity {
+	var id Identity
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return id
+	}
+
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+		fingerprint := sha256.Sum256(tlsInfo.State.PeerCertificates[0].Raw)
+		id.Fingerprint = hex.EncodeToString(fingerprint[:])
+	}
+	if p.Addr != nil {
+		id.Host = p.Addr.String()
+		if host, _, err := net.SplitHostPort(id.Host); err == nil {
+			id.Host = host
+		}
+	}
+	return id
+}
+
+type identityKey struct{}
+
+// IdentityFromContext returns the identity of the client making a call, false if the call was not intercepted
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// identifyUnary attaches the identity of the client to the context of the call, for the interceptors within and the handler
+func identifyUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(context.WithValue(ctx, identityKey{}, extractIdentity(ctx)), req)
+}
+
+func identifyStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ss.Context()
+	return handler(srv, &contextStream{ServerStream: ss, ctx: context.WithValue(ctx, identityKey{}, extractIdentity(ctx))})
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (cs *contextStream) Context() context.Context {
+	return cs.ctx
+}
+
+// adminService prefixes the methods of the Admin service, which pause and resume ordering
+const adminService = "/atomicbroadcast.Admin/"
+
+// authorizer permits only the listed clients, by fingerprint or host, to call the Admin service
+type authorizer struct {
+	admins map[string]struct{}
+}
+
+func newAuthorizer(admins []string) *authorizer {
+	a := &authorizer{admins: make(map[string]struct{})}
+	for _, admin := range admins {
+		a.admins[strings.ToLower(admin)] = struct{}{}
+	}
+	return a
+}
+
+func (a *authorizer) authorize(ctx context.Context, method string) error {
+	if !strings.HasPrefix(method, adminService) {
+		return nil
+	}
+	id, _ := IdentityFromContext(ctx)
+	if _, ok := a.admins[id.Fingerprint]; ok && id.Fingerprint != "" {
+		return nil
+	}
+	if _, ok := a.admins[id.Host]; ok && id.Host != "" {
+		return nil
+	}
+	logger.Warningf("Refused %s to %s, which is not an admin client", method, id)
+	return grpc.Errorf(codes.PermissionDenied, "client %s may not call %s", id, method)
+}
+
+func (a *authorizer) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authorizer) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
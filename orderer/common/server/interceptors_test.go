@@ -0,0 +1,179 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/proto"
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+type mockStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []interface{}
+}
+
+func (ms *mockStream) Context() context.Context {
+	return ms.ctx
+}
+
+func (ms *mockStream) SendMsg(m interface{}) error {
+	ms.sent = append(ms.sent, m)
+	return nil
+}
+
+// peerContext returns the context of a call from host, over TLS with a certificate whose contents are cert if it is not nil
+func peerContext(host string, cert []byte) context.Context {
+	p := &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(host), Port: 7050}}
+	if cert != nil {
+		p.AuthInfo = credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{&x509.Certificate{Raw: cert}}}}
+	}
+	return peer.NewContext(context.Background(), p)
+}
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: "/atomicbroadcast.AtomicBroadcast/SubmitBroadcast"}
+
+var streamInfo = &grpc.StreamServerInfo{FullMethod: "/atomicbroadcast.AtomicBroadcast/Deliver"}
+
+func TestRecovery(t *testing.T) {
+	_, err := recoverUnary(context.Background(), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("handler failed")
+	})
+	if grpc.Code(err) != codes.Internal {
+		t.Fatalf("Expected a panicking unary call to fail with Internal, got %v", err)
+	}
+
+	err = recoverStream(nil, &mockStream{ctx: context.Background()}, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+		panic("handler failed")
+	})
+	if grpc.Code(err) != codes.Internal {
+		t.Fatalf("Expected a panicking stream to fail with Internal, got %v", err)
+	}
+
+	if _, err = recoverUnary(context.Background(), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, grpc.Errorf(codes.NotFound, "missing")
+	}); grpc.Code(err) != codes.NotFound {
+		t.Fatalf("Expected the error of a call which does not panic to be returned, got %v", err)
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	reply := &ab.BroadcastResponse{}
+	resp, err := logUnary(peerContext("10.0.0.1", nil), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return reply, nil
+	})
+	if resp != reply || err != nil {
+		t.Fatalf("Expected the reply of the handler to be returned, got %v, %v", resp, err)
+	}
+
+	if err = logStream(nil, &mockStream{ctx: peerContext("10.0.0.1", nil)}, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+		return grpc.Errorf(codes.Unavailable, "closed")
+	}); grpc.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected the error of the handler to be returned, got %v", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	m := newMetrics(registry)
+	reply := &ab.BroadcastResponse{Status: ab.Status_SUCCESS, BlockNumber: 12}
+
+	m.unary(context.Background(), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return reply, nil
+	})
+	m.stream(nil, &mockStream{ctx: context.Background()}, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+		ss.SendMsg(reply)
+		ss.SendMsg(reply)
+		return grpc.Errorf(codes.Unavailable, "closed")
+	})
+
+	if calls := m.calls.Count(); calls != 2 {
+		t.Fatalf("Expected 2 calls to be counted, got %d", calls)
+	}
+	if failures := m.failures.Count(); failures != 1 {
+		t.Fatalf("Expected 1 failure to be counted, got %d", failures)
+	}
+	if sent := m.sent.Count(); sent != int64(3*proto.Size(reply)) {
+		t.Fatalf("Expected %d bytes to be counted, got %d", 3*proto.Size(reply), sent)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	var id Identity
+	identifyUnary(peerContext("10.0.0.1", []byte("certificate")), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, _ = IdentityFromContext(ctx)
+		return nil, nil
+	})
+	if id.Host != "10.0.0.1" || len(id.Fingerprint) != 64 || id.String() != id.Fingerprint {
+		t.Fatalf("Expected the fingerprint and host of the client, got %+v", id)
+	}
+
+	identifyStream(nil, &mockStream{ctx: peerContext("10.0.0.2", nil)}, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+		id, _ = IdentityFromContext(ss.Context())
+		return nil
+	})
+	if id.Fingerprint != "" || id.String() != "10.0.0.2" {
+		t.Fatalf("Expected a client without a certificate to be identified by its host, got %+v", id)
+	}
+
+	if _, ok := IdentityFromContext(context.Background()); ok {
+		t.Fatalf("Expected no identity outside an intercepted call")
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	var admin Identity
+	identifyUnary(peerContext("10.0.0.1", []byte("admin")), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		admin, _ = IdentityFromContext(ctx)
+		return nil, nil
+	})
+	a := newAuthorizer([]string{admin.Fingerprint, "10.0.0.2"})
+	pause := &grpc.UnaryServerInfo{FullMethod: adminService + "Pause"}
+
+	testCases := []struct {
+		name   string
+		ctx    context.Context
+		info   *grpc.UnaryServerInfo
+		denied bool
+	}{
+		{"admin by fingerprint", peerContext("10.0.0.9", []byte("admin")), pause, false},
+		{"admin by host", peerContext("10.0.0.2", nil), pause, false},
+		{"other client", peerContext("10.0.0.3", []byte("other")), pause, true},
+		{"other client broadcasting", peerContext("10.0.0.3", nil), unaryInfo, false},
+	}
+
+	for _, tc := range testCases {
+		_, err := identifyUnary(tc.ctx, nil, tc.info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return a.unary(ctx, req, tc.info, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+		})
+		if tc.denied != (grpc.Code(err) == codes.PermissionDenied) {
+			t.Errorf("%s: unexpected result %v", tc.name, err)
+		}
+	}
+}
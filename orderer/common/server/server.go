@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server assembles the options of the orderer's gRPC server
+package server
+
+import (
+	"github.com/hyperledger/fabric/orderer/config"
+
+	"github.com/op/go-logging"
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+var logger = logging.MustGetLogger("orderer/common/server")
+
+// Builder assembles the options of a gRPC server, the vendored gRPC accepts only one interceptor of each kind, so those
+// added are chained in the order they were added, the first outermost
+type Builder struct {
+	opts   []grpc.ServerOption
+	unary  []grpc.UnaryServerInterceptor
+	stream []grpc.StreamServerInterceptor
+}
+
+// NewBuilder returns a builder chaining the interceptors enabled by conf in a fixed order: recovery outermost, so that
+// no panic escapes it, then request logging, metrics counted in registry, identity extraction, and authorization
+// innermost, so that a denied call is still logged and counted
+func NewBuilder(conf config.Interceptors, registry gometrics.Registry) *Builder {
+	b := &Builder{}
+	if conf.Recovery {
+		b.Append(recoverUnary, recoverStream)
+	}
+	if conf.RequestLogging {
+		b.Append(logUnary, logStream)
+	}
+	m := newMetrics(registry)
+	b.Append(m.unary, m.stream)
+	b.Append(identifyUnary, identifyStream)
+	if len(conf.AdminClients) > 0 {
+		a := newAuthorizer(conf.AdminClients)
+		b.Append(a.unary, a.stream)
+	}
+	return b
+}
+
+// AddOption adds an option other than an interceptor
+func (b *Builder) AddOption(opt grpc.ServerOption) *Builder {
+	b.opts = append(b.opts, opt)
+	return b
+}
+
+// Append adds interceptors inside those already added, so that embedders may extend the chain, either may be nil
+func (b *Builder) Append(unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) *Builder {
+	if unary != nil {
+		b.unary = append(b.unary, unary)
+	}
+	if stream != nil {
+		b.stream = append(b.stream, stream)
+	}
+	return b
+}
+
+// Options returns the options added and a single interceptor of each kind running the chain
+func (b *Builder) Options() []grpc.ServerOption {
+	opts := append([]grpc.ServerOption{}, b.opts...)
+	if len(b.unary) > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(chainUnary(b.unary)))
+	}
+	if len(b.stream) > 0 {
+		opts = append(opts, grpc.StreamInterceptor(chainStream(b.stream)))
+	}
+	return opts
+}
+
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+func chainStream(interceptors []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, next)
+			}
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/config"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// mockAdmin pauses by panicking, so that the recovery of the chain is exercised
+type mockAdmin struct{}
+
+func (ma mockAdmin) Pause(ctx context.Context, req *ab.PauseRequest) (*ab.AdminResponse, error) {
+	panic("pause failed")
+}
+
+func (ma mockAdmin) Resume(ctx context.Context, req *ab.ResumeRequest) (*ab.AdminResponse, error) {
+	return &ab.AdminResponse{Status: ab.Status_SUCCESS}, nil
+}
+
+func (ma mockAdmin) ValidateConfig(ctx context.Context, configTx *ab.ConfigurationEnvelope) (*ab.ValidateConfigResponse, error) {
+	return &ab.ValidateConfigResponse{Status: ab.Status_SUCCESS}, nil
+}
+
+// recorder appends its name to the calls it intercepts, along with whether the identity of the client was known to it
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) interceptor(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := IdentityFromContext(ctx); !ok {
+			name += " without identity"
+		}
+		r.calls = append(r.calls, name)
+		return handler(ctx, req)
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error listening:", err)
+	}
+
+	registry := gometrics.NewRegistry()
+	rec := &recorder{}
+	builder := NewBuilder(config.Interceptors{Recovery: true, RequestLogging: true}, registry)
+	builder.Append(rec.interceptor("first"), nil).Append(rec.interceptor("second"), nil)
+	grpcServer := grpc.NewServer(builder.Options()...)
+	ab.RegisterAdminServer(grpcServer, mockAdmin{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal("Error dialing:", err)
+	}
+	defer conn.Close()
+	client := ab.NewAdminClient(conn)
+
+	if _, err = client.Resume(context.Background(), &ab.ResumeRequest{}); err != nil {
+		t.Fatal("Error resuming:", err)
+	}
+	// The appended interceptors run within the identity extraction, in the order appended
+	if expected := []string{"first", "second"}; !reflect.DeepEqual(rec.calls, expected) {
+		t.Fatalf("Expected the interceptors to be called as %v, got %v", expected, rec.calls)
+	}
+
+	// A panic in the handler unwinds every other interceptor before it is recovered
+	if _, err = client.Pause(context.Background(), &ab.PauseRequest{}); grpc.Code(err) != codes.Internal {
+		t.Fatalf("Expected the panic to be recovered as Internal, got %v", err)
+	}
+	if calls := gometrics.GetOrRegisterCounter("calls", registry).Count(); calls != 1 {
+		t.Fatalf("Expected only the call which returned to be counted, got %d", calls)
+	}
+
+	grpcServer.Stop()
+	lis, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error listening:", err)
+	}
+
+	// Authorization refuses the call before the appended interceptors see it
+	rec = &recorder{}
+	builder = NewBuilder(config.Interceptors{Recovery: true, AdminClients: []string{"10.255.255.1"}}, registry)
+	builder.Append(rec.interceptor("first"), nil)
+	grpcServer = grpc.NewServer(builder.Options()...)
+	ab.RegisterAdminServer(grpcServer, mockAdmin{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err = grpc.Dial(lis.Addr().String(), grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal("Error dialing:", err)
+	}
+	defer conn.Close()
+
+	if _, err = ab.NewAdminClient(conn).Resume(context.Background(), &ab.ResumeRequest{}); grpc.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected a client which is not an admin to be refused, got %v", err)
+	}
+	if len(rec.calls) != 0 {
+		t.Fatalf("Expected the refused call not to reach the appended interceptors, got %v", rec.calls)
+	}
+}
+
+func TestChainStream(t *testing.T) {
+	var calls []string
+	record := func(name string) grpc.StreamServerInterceptor {
+		return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			calls = append(calls, name)
+			return handler(srv, ss)
+		}
+	}
+
+	chain := chainStream([]grpc.StreamServerInterceptor{record("outer"), record("inner")})
+	chain(nil, &mockStream{ctx: context.Background()}, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+
+	if expected := []string{"outer", "inner", "handler"}; !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("Expected the stream chain to run as %v, got %v", expected, calls)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharedconfig handles the items of the Orderer configuration type, which every consenter honors alike
+package sharedconfig
+
+import (
+	"fmt"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+)
+
+var logger = logging.MustGetLogger("orderer/common/sharedconfig")
+
+// The IDs of the Orderer configuration items which set how blocks are cut, each takes effect from the block after the
+// configuration block which sets it
+const (
+	BatchSizeKey     = "BatchSize"     // Data is a marshaled BatchSize, of which only Messages may be set
+	BatchMaxBytesKey = "BatchMaxBytes" // Data is a marshaled BatchMaxBytes
+	BatchTimeoutKey  = "BatchTimeout"  // Data is a marshaled BatchTimeout
+)
+
+// Limits are the largest batch parameters which the configuration of a chain may set, a zero limit places no bound
+type Limits struct {
+	BatchSize     uint32
+	BatchMaxBytes uint32
+	BatchTimeout  time.Duration
+}
+
+// BatchParameters are how a consenter cuts the blocks of a chain
+type BatchParameters struct {
+	Size     int           // The number of messages which cut a block
+	MaxBytes int           // The total size of message data beyond which a block is cut, zero for no limit
+	Timeout  time.Duration // How long after the first message of a batch the block is cut regardless of size
+}
+
+type handler struct {
+	limits Limits
+}
+
+// NewHandler creates the Handler of the Orderer configuration type, which rejects items it does not know, and batch
+// parameters which are not positive or are beyond limits
+// The items are tracked by the configtx.Manager, which is queried for them by Batch
+func NewHandler(limits Limits) configtx.Handler {
+	return &handler{limits: limits}
+}
+
+// BeginConfig called when a config proposal is begun
+func (h *handler) BeginConfig() {}
+
+// RollbackConfig called when a config proposal is abandoned
+func (h *handler) RollbackConfig() {}
+
+// CommitConfig called when a config proposal is committed
+func (h *handler) CommitConfig() {}
+
+// ProposeConfig called when config is added to a proposal
+func (h *handler) ProposeConfig(item *ab.Configuration) error {
+	switch item.ID {
+	case BatchSizeKey:
+		size, err := batchSize(item.Data)
+		if err != nil {
+			return err
+		}
+		if h.limits.BatchSize > 0 && size > h.limits.BatchSize {
+			return fmt.Errorf("Batch size of %d messages is beyond the limit of %d", size, h.limits.BatchSize)
+		}
+	case BatchMaxBytesKey:
+		maxBytes, err := batchMaxBytes(item.Data)
+		if err != nil {
+			return err
+		}
+		if h.limits.BatchMaxBytes > 0 && maxBytes > h.limits.BatchMaxBytes {
+			return fmt.Errorf("Batch max bytes of %d is beyond the limit of %d", maxBytes, h.limits.BatchMaxBytes)
+		}
+	case BatchTimeoutKey:
+		timeout, err := batchTimeout(item.Data)
+		if err != nil {
+			return err
+		}
+		if h.limits.BatchTimeout > 0 && timeout > h.limits.BatchTimeout {
+			return fmt.Errorf("Batch timeout of %v is beyond the limit of %v", timeout, h.limits.BatchTimeout)
+		}
+	default:
+		return fmt.Errorf("Unknown Orderer configuration item %s", item.ID)
+	}
+	return nil
+}
+
+func batchSize(data []byte) (uint32, error) {
+	size := &ab.BatchSize{}
+	if err := proto.Unmarshal(data, size); err != nil {
+		return 0, fmt.Errorf("Batch size is malformed: %s", err)
+	}
+	if size.MaxBytes != 0 || size.MaxMessageBytes != 0 {
+		return 0, fmt.Errorf("Batch size may only set Messages, the limit in bytes is the item %s", BatchMaxBytesKey)
+	}
+	if size.Messages == 0 {
+		return 0, fmt.Errorf("Batch size must be positive")
+	}
+	return size.Messages, nil
+}
+
+func batchMaxBytes(data []byte) (uint32, error) {
+	maxBytes := &ab.BatchMaxBytes{}
+	if err := proto.Unmarshal(data, maxBytes); err != nil {
+		return 0, fmt.Errorf("Batch max bytes is malformed: %s", err)
+	}
+	if maxBytes.MaxBytes == 0 {
+		return 0, fmt.Errorf("Batch max bytes must be positive")
+	}
+	return maxBytes.MaxBytes, nil
+}
+
+func batchTimeout(data []byte) (time.Duration, error) {
+	timeout := &ab.BatchTimeout{}
+	if err := proto.Unmarshal(data, timeout); err != nil {
+		return 0, fmt.Errorf("Batch timeout is malformed: %s", err)
+	}
+	if timeout.Timeout <= 0 {
+		return 0, fmt.Errorf("Batch timeout must be positive, got %v", time.Duration(timeout.Timeout))
+	}
+	return time.Duration(timeout.Timeout), nil
+}
+
+// Batch returns defaults with each parameter which cm configures in its place
+func Batch(cm configtx.Manager, defaults BatchParameters) BatchParameters {
+	params := defaults
+	if data, ok := cm.Get(ab.Configuration_Orderer, BatchSizeKey); ok {
+		if size, err := batchSize(data); err != nil {
+			logger.Warningf("Ignoring configured batch size: %s", err)
+		} else {
+			params.Size = int(size)
+		}
+	}
+	if data, ok := cm.Get(ab.Configuration_Orderer, BatchMaxBytesKey); ok {
+		if maxBytes, err := batchMaxBytes(data); err != nil {
+			logger.Warningf("Ignoring configured batch max bytes: %s", err)
+		} else {
+			params.MaxBytes = int(maxBytes)
+		}
+	}
+	if data, ok := cm.Get(ab.Configuration_Orderer, BatchTimeoutKey); ok {
+		if timeout, err := batchTimeout(data); err != nil {
+			logger.Warningf("Ignoring configured batch timeout: %s", err)
+		} else {
+			params.Timeout = timeout
+		}
+	}
+	return params
+}
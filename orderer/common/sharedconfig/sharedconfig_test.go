@@ -0,0 +1,119 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharedconfig
+
+import (
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var testChainID = []byte("test")
+
+// acceptAllPolicies authorizes every configuration change
+type acceptAllPolicies struct{}
+
+func (ap acceptAllPolicies) GetPolicy(id string) (policies.Policy, bool) {
+	return ap, true
+}
+
+func (ap acceptAllPolicies) Evaluate(msg []byte, sigs []*ab.SignedData) error {
+	return nil
+}
+
+func item(id string, msg proto.Message) *ab.Configuration {
+	data, _ := proto.Marshal(msg)
+	return &ab.Configuration{ChainID: testChainID, ID: id, Type: ab.Configuration_Orderer, Data: data}
+}
+
+func TestProposeConfig(t *testing.T) {
+	h := NewHandler(Limits{BatchSize: 100, BatchMaxBytes: 1000, BatchTimeout: time.Minute})
+
+	for _, valid := range []*ab.Configuration{
+		item(BatchSizeKey, &ab.BatchSize{Messages: 100}),
+		item(BatchMaxBytesKey, &ab.BatchMaxBytes{MaxBytes: 1}),
+		item(BatchTimeoutKey, &ab.BatchTimeout{Timeout: int64(time.Minute)}),
+	} {
+		if err := h.ProposeConfig(valid); err != nil {
+			t.Errorf("Expected item %s to be accepted, got %s", valid.ID, err)
+		}
+	}
+
+	for _, invalid := range []struct {
+		name string
+		item *ab.Configuration
+	}{
+		{"zero batch size", item(BatchSizeKey, &ab.BatchSize{})},
+		{"batch size beyond the limit", item(BatchSizeKey, &ab.BatchSize{Messages: 101})},
+		{"batch size setting bytes", item(BatchSizeKey, &ab.BatchSize{Messages: 10, MaxBytes: 10})},
+		{"malformed batch size", &ab.Configuration{ID: BatchSizeKey, Type: ab.Configuration_Orderer, Data: []byte("garbage")}},
+		{"zero batch max bytes", item(BatchMaxBytesKey, &ab.BatchMaxBytes{})},
+		{"batch max bytes beyond the limit", item(BatchMaxBytesKey, &ab.BatchMaxBytes{MaxBytes: 1001})},
+		{"negative batch timeout", item(BatchTimeoutKey, &ab.BatchTimeout{Timeout: -1})},
+		{"batch timeout beyond the limit", item(BatchTimeoutKey, &ab.BatchTimeout{Timeout: int64(time.Minute + 1)})},
+		{"unknown item", item("Unknown", &ab.BatchSize{Messages: 1})},
+	} {
+		if err := h.ProposeConfig(invalid.item); err == nil {
+			t.Errorf("Expected the %s to be rejected", invalid.name)
+		}
+	}
+}
+
+func TestProposeConfigUnlimited(t *testing.T) {
+	h := NewHandler(Limits{})
+	if err := h.ProposeConfig(item(BatchSizeKey, &ab.BatchSize{Messages: 1 << 30})); err != nil {
+		t.Fatalf("Expected a zero limit to place no bound, got %s", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	registry := configtx.NewRegistry()
+	if err := registry.Register(ab.Configuration_Orderer, NewHandler(Limits{})); err != nil {
+		t.Fatalf("Error registering handler: %s", err)
+	}
+	envelope := func(sequence uint64, items ...*ab.Configuration) *ab.ConfigurationEnvelope {
+		configTx := &ab.ConfigurationEnvelope{Sequence: sequence, ChainID: testChainID}
+		for _, item := range items {
+			item.LastModified = sequence
+			data, _ := proto.Marshal(item)
+			configTx.Entries = append(configTx.Entries, &ab.ConfigurationEntry{Configuration: data})
+		}
+		return configTx
+	}
+
+	cm, err := registry.NewManager(envelope(0), acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	defaults := BatchParameters{Size: 10, MaxBytes: 100, Timeout: time.Second}
+	if params := Batch(cm, defaults); params != defaults {
+		t.Fatalf("Expected the defaults while none are configured, got %+v", params)
+	}
+
+	if err := cm.Apply(envelope(1, item(BatchSizeKey, &ab.BatchSize{Messages: 5}), item(BatchTimeoutKey, &ab.BatchTimeout{Timeout: int64(time.Minute)}))); err != nil {
+		t.Fatalf("Error applying configuration: %s", err)
+	}
+	expected := BatchParameters{Size: 5, MaxBytes: 100, Timeout: time.Minute}
+	if params := Batch(cm, defaults); params != expected {
+		t.Fatalf("Expected the configured parameters in place of the defaults, %+v, got %+v", expected, params)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tlsutil builds the TLS configs of the orderer, of its Kafka client and gateway, and of its clients from the
+// PEM encoded certificates and keys they name, and spreads out the reconnections of those clients
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// NewClientConfig returns the config of a connection to what, presenting the certificate and private key if either is
+// set and verifying what against rootCAs if any, or against the system roots otherwise
+func NewClientConfig(certificate, privateKey string, rootCAs []string, what string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if certificate != "" || privateKey != "" {
+		cert, err := LoadKeyPair(certificate, privateKey, what+" client certificate")
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(rootCAs) > 0 {
+		pool, err := LoadCertPool(rootCAs, what+" root CA")
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// NewServerConfig returns the config of the what listener, presenting the certificate and private key and requiring
+// client certificates issued by clientRootCAs if any
+func NewServerConfig(certificate, privateKey string, clientRootCAs []string, what string) (*tls.Config, error) {
+	cert, err := LoadKeyPair(certificate, privateKey, what+" certificate")
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if len(clientRootCAs) > 0 {
+		pool, err := LoadCertPool(clientRootCAs, what+" client root CA")
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// LoadKeyPair loads the certificate and private key at the given paths, naming the certificate as what in errors
+func LoadKeyPair(certificate, privateKey, what string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certificate, privateKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Failed to load the %s: %s", what, err)
+	}
+	return cert, nil
+}
+
+// LoadCertPool returns a pool of the certificates of the PEM files at paths, naming them as what in errors, a file
+// without any certificate is an error
+func LoadCertPool(paths []string, what string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, path := range paths {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the %s: %s", what, err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in the %s file %s", what, path)
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self signed certificate and its key to dir, returning their paths
+func writeKeyPair(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "orderer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "orderer.crt"), filepath.Join(dir, "orderer.key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestLoadKeyPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeKeyPair(t, dir)
+
+	if cert, err := LoadKeyPair(certFile, keyFile, "test certificate"); err != nil || len(cert.Certificate) != 1 {
+		t.Fatalf("Expected the key pair to load, got %v", err)
+	}
+	if _, err := LoadKeyPair(certFile, certFile, "test certificate"); err == nil || !strings.Contains(err.Error(), "test certificate") {
+		t.Fatalf("Expected an error naming the test certificate, got %v", err)
+	}
+}
+
+func TestLoadCertPool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeKeyPair(t, dir)
+
+	pool, err := LoadCertPool([]string{certFile}, "test root CA")
+	if err != nil || len(pool.Subjects()) != 1 {
+		t.Fatalf("Expected a pool of the one certificate, got %v", err)
+	}
+
+	for name, paths := range map[string][]string{
+		"file without certificates": []string{certFile, keyFile},
+		"missing file":              []string{filepath.Join(dir, "missing.crt")},
+	} {
+		if _, err := LoadCertPool(paths, "test root CA"); err == nil || !strings.Contains(err.Error(), "test root CA") {
+			t.Errorf("Expected an error naming the test root CA for the %s, got %v", name, err)
+		}
+	}
+}
+
+func TestNewClientConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeKeyPair(t, dir)
+
+	tlsConfig, err := NewClientConfig("", "", nil, "test")
+	if err != nil || len(tlsConfig.Certificates) != 0 || tlsConfig.RootCAs != nil {
+		t.Fatalf("Expected an empty config to use the system roots without a client certificate, got %v", err)
+	}
+	tlsConfig, err = NewClientConfig(certFile, keyFile, []string{certFile}, "test")
+	if err != nil || len(tlsConfig.Certificates) != 1 || tlsConfig.RootCAs == nil {
+		t.Fatalf("Expected the client certificate and root CA to be loaded, got %v", err)
+	}
+	if _, err := NewClientConfig(certFile, "", nil, "test"); err == nil || !strings.Contains(err.Error(), "test client certificate") {
+		t.Fatalf("Expected an error naming the test client certificate, got %v", err)
+	}
+	if _, err := NewClientConfig("", "", []string{keyFile}, "test"); err == nil || !strings.Contains(err.Error(), "test root CA") {
+		t.Fatalf("Expected an error naming the test root CA, got %v", err)
+	}
+}
+
+func TestNewServerConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeKeyPair(t, dir)
+
+	tlsConfig, err := NewServerConfig(certFile, keyFile, nil, "test")
+	if err != nil || len(tlsConfig.Certificates) != 1 || tlsConfig.ClientAuth != tls.NoClientCert {
+		t.Fatalf("Expected the certificate to be loaded without requiring client certificates, got %v", err)
+	}
+	tlsConfig, err = NewServerConfig(certFile, keyFile, []string{certFile}, "test")
+	if err != nil || tlsConfig.ClientCAs == nil || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("Expected client certificates to be required, got %v", err)
+	}
+	if _, err := NewServerConfig("", "", nil, "test"); err == nil || !strings.Contains(err.Error(), "test certificate") {
+		t.Fatalf("Expected an error naming the test certificate, got %v", err)
+	}
+	if _, err := NewServerConfig(certFile, keyFile, []string{keyFile}, "test"); err == nil || !strings.Contains(err.Error(), "test client root CA") {
+		t.Fatalf("Expected an error naming the test client root CA, got %v", err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	backoff := 100 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		if delay := Jitter(backoff); delay < backoff/2 || delay > backoff {
+			t.Fatalf("Expected a delay between %v and %v, got %v", backoff/2, backoff, delay)
+		}
+	}
+}
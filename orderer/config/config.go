@@ -18,12 +18,14 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/Shopify/sarama"
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
 	"github.com/op/go-logging"
 	"github.com/spf13/viper"
 )
@@ -39,15 +41,131 @@ const Prefix string = "ORDERER"
 
 // General contains config which should be common among all orderer types
 type General struct {
-	OrdererType   string
-	LedgerType    string
-	BatchTimeout  time.Duration
+	OrdererType    string
+	LedgerType     string
+	BatchTimeout   time.Duration
+	BatchSize      uint
+	BatchMaxBytes  uint
+	QueueSize      uint
+	MaxWindowSize  uint
+	MaxRecvMsgSize uint // The largest Broadcast message accepted, in bytes as marshaled
+	MaxSendMsgSize uint // The largest Deliver response sent, in bytes as marshaled
+	ListenAddress  string
+	ListenPort     uint16
+	GenesisMethod  string
+	GenesisFile    string // The genesis block read by GenesisMethod file, as written by -export-genesis
+	NetworkID      string // Names the ordering network, so that networks sharing a Kafka cluster do not share topics
+	// ConfigValiditySkew is allowed at either end of the validity window of a configuration transaction
+	ConfigValiditySkew time.Duration
+	ConfigLimits       ConfigLimits
+	// ConfigSnapshotInterval is how many blocks may follow the last configuration block before a snapshot of the configuration is committed, zero for none
+	ConfigSnapshotInterval uint
+	// EnableReflection registers the gRPC reflection service, on in the orderer.yaml shipped with the orderer
+	EnableReflection bool
+	Keepalive        Keepalive
+	Interceptors     Interceptors
+	Protocol         Protocol
+	Broadcast        Broadcast
+	Deliver          Deliver
+	Signer           Signer
+	Gateway          Gateway
+	Audit            Audit
+	Policies         Policies
+}
+
+// Broadcast contains config for the handling of Broadcast requests
+type Broadcast struct {
+	AckAfterCommit bool
+	DedupWindow    uint
+	PendingLogDir  string
+	WritePolicy    string
+	MaxChains      uint // The most chains, the system chain among them, which chain creation transactions may bring to exist, zero for no limit
+	StartPaused    bool
+	RetryAfter     time.Duration
+	IdleTimeout    time.Duration
+}
+
+// Deliver contains config for the handling of Deliver requests
+type Deliver struct {
+	MaxIdleTime         time.Duration
+	MaxLag              uint
+	HeartbeatInterval   time.Duration
+	CursorKey           string
+	MaxGlobalStreams    uint
+	MaxStreamsPerClient uint
+	RetryAfter          time.Duration
+	Compression         string        // The codec compressing the responses to clients which compress their requests, one of none or gzip
+	DefaultWindowSize   uint          // The window of seeks which give none, which is not enforced, zero to refuse such seeks
+	SeekSkew            time.Duration // How far the Timestamp of a signed seek may be from the clock of the orderer
+}
+
+// ConfigLimits contains the largest batch parameters which the configuration of a chain may set, zero for no bound
+type ConfigLimits struct {
 	BatchSize     uint
-	QueueSize     uint
-	MaxWindowSize uint
+	BatchMaxBytes uint
+	BatchTimeout  time.Duration
+}
+
+// Signer contains config for the identity with which the orderer signs each block it commits
+type Signer struct {
+	Certificate string // Path to the PEM encoded certificate of the orderer, blocks are not signed if it and PrivateKey are empty
+	PrivateKey  string // Path to the PEM encoded private key of the certificate
+}
+
+// Keepalive contains config for the HTTP/2 pings of idle client connections, and for the pings clients may send
+type Keepalive struct {
+	Time                time.Duration // How long a connection may be idle before the server pings the client, and before the TCP keepalive probes begin
+	Timeout             time.Duration // How long the server waits for the client to answer a ping before closing the connection
+	MinTime             time.Duration // The shortest interval at which a client may ping, clients pinging more often are sent a GOAWAY
+	PermitWithoutStream bool          // Whether clients may ping while they have no call open
+}
+
+// Protocol contains config for the features negotiated at the start of Broadcast and Deliver streams
+type Protocol struct {
+	Features []string // The features a stream may negotiate by a hello, one without a hello uses none of them
+}
+
+// Enabled returns the set of the features, which Load has validated
+func (p Protocol) Enabled() ab.Features {
+	features, _ := ab.ParseFeatures(p.Features)
+	return features
+}
+
+// Interceptors contains config for the interceptors wrapping each call to the gRPC server
+type Interceptors struct {
+	Recovery       bool     // Whether a call whose handler panics fails with Internal, rather than crashing the orderer
+	RequestLogging bool     // Whether each call is logged once it completes
+	AdminClients   []string // The certificate fingerprints or hosts of the clients which may call the Admin service, any client if empty
+}
+
+// Audit contains config for the audit trail of the configuration changes applied to each chain, and of the requests policies deny
+type Audit struct {
+	File           string        // The file to which each record is appended as a line of JSON, if empty records are only logged to orderer/audit
+	DenialBurst    uint          // The denials of each client logged per DenialInterval, zero to log every denial
+	DenialInterval time.Duration // The interval over which DenialBurst is counted
+}
+
+// Policies contains config for the evaluation of the policies of each chain, among them the Writers and Readers policies authorizing Broadcast and Deliver
+type Policies struct {
+	DefaultDeny bool          // Whether a policy which does not exist rejects everything it would authorize, rather than accepting it
+	CacheSize   uint          // The number of evaluation outcomes remembered for each chain, zero to evaluate every time
+	CacheTTL    time.Duration // How long each outcome is remembered, zero until evicted
+}
+
+// Gateway contains config for the HTTP listener translating JSON requests onto Broadcast and Deliver
+type Gateway struct {
+	Enabled       bool
 	ListenAddress string
 	ListenPort    uint16
-	GenesisMethod string
+	TLS           ServerTLS
+}
+
+// ServerTLS contains config for a listener which serves over TLS
+type ServerTLS struct {
+	Enabled       bool
+	Certificate   string   // Path to the PEM encoded certificate of the listener
+	PrivateKey    string   // Path to the PEM encoded private key of the certificate
+	ClientRootCAs []string // Paths to the PEM encoded certificates of the CAs trusted to sign client certificates, clients must present one if any are set
 }
 
 // RAMLedger contains config for the RAM ledger
@@ -57,17 +175,78 @@ type RAMLedger struct {
 
 // FileLedger contains config for the File ledger
 type FileLedger struct {
-	Location string
-	Prefix   string
+	Location   string
+	Prefix     string
+	SyncWrites bool // Whether each block is synced to disk before it is appended
 }
 
 // Kafka contains config for the Kafka orderer
 type Kafka struct {
-	Brokers     []string
-	Topic       string
-	PartitionID int32
-	Retry       Retry
-	Version     sarama.KafkaVersion // TODO For now set this in code
+	Brokers                 []string
+	Topic                   string
+	PartitionID             int32
+	ChainMapping            string   // Either partition or topic, how each chain is mapped onto the Kafka cluster
+	TopicTemplate           string   // Names the topic of a chain from {network} and {chain}, its chain ID in hex, when ChainMapping is topic
+	ChainPartitions         []string // Entries of the form <chain ID in hex>:<partition of Topic>, when ChainMapping is partition
+	Partitions              int32    // The number of partitions the topic is expected to have
+	ReplicationFactor       int16    // The minimum number of replicas of each partition of the topic
+	CreateTopic             bool     // Whether a missing topic is created with Partitions and ReplicationFactor
+	MinInSyncReplicas       int      // The number of brokers which must be reachable before the orderer starts
+	StartupTimeout          time.Duration
+	ShutdownTimeout         time.Duration // How long a chain waits on teardown for the messages it posted to be cut into blocks
+	MetadataRefreshInterval time.Duration // How often the Kafka clients refresh the cluster metadata in the background
+	Retry                   Retry
+	Producer                Producer
+	Consumer                Consumer
+	Version                 string // The protocol version of the brokers, parsed by the Kafka orderer
+	StrictVersion           bool   // Refuse to start, rather than fall back to an older Version, if the brokers are older than Version
+	Compression             string // The codec compressing the messages posted to the brokers, one of none, gzip, snappy, or lz4
+	TLS                     TLS
+	SASL                    SASL
+}
+
+// Producer contains config for the delivery of blocks to the Kafka brokers
+type Producer struct {
+	RequiredAcks    string // One of WaitForAll, WaitForLocal, or NoResponse
+	MaxMessageBytes int    // Broadcast messages which would exceed it on the partition are rejected, at most the message.max.bytes of the brokers
+	Retry           ProducerRetry
+	Flush           Flush
+}
+
+// ProducerRetry contains config for resending a block which the brokers failed to store
+type ProducerRetry struct {
+	Max     int
+	Backoff time.Duration
+}
+
+// Consumer contains config for the consumption of the partition from the Kafka brokers
+type Consumer struct {
+	Retry           ConsumerRetry
+	AllowOffsetGaps bool // Resume a chain halted because its partition skipped or repeated offsets
+}
+
+// ConsumerRetry contains config for re-establishing the partition consumer once its connection to the brokers is lost
+type ConsumerRetry struct {
+	Backoff    time.Duration // The delay before the first attempt, doubled after each failed attempt
+	MaxBackoff time.Duration
+}
+
+// Flush contains config for batching the messages sent to the Kafka brokers, zero values send each message as soon as possible
+// Batching raises throughput at the cost of latency, both a message and the time-to-cut message of its block may wait up
+// to Frequency, so the time-to-cut message is posted that much earlier twice over to keep blocks within BatchTimeout
+type Flush struct {
+	Frequency time.Duration // The longest a message waits for others to be sent with it, required if Bytes or Messages is set
+	Bytes     int           // Send once this many bytes are buffered
+	Messages  int           // Send once this many messages are buffered
+}
+
+// TLS contains config for the connections to the Kafka brokers
+type TLS struct {
+	Enabled            bool
+	Certificate        string   // Path to the PEM encoded client certificate, presented if the brokers ask for one
+	PrivateKey         string   // Path to the PEM encoded private key of the client certificate
+	RootCAs            []string // Paths to the PEM encoded certificates of the CAs trusted to sign the brokers' certificates
+	InsecureSkipVerify bool     // Skips verification of the brokers' certificates and hostnames, for testing only
 }
 
 // Retry contains config for the reconnection attempts to the Kafka brokers
@@ -76,6 +255,56 @@ type Retry struct {
 	Stop   time.Duration
 }
 
+// SASLPlain is the only SASL mechanism which may be configured for the connections to the Kafka brokers, as it is the only one the Kafka client implements
+const SASLPlain = "PLAIN"
+
+// SASL contains config for authenticating to the Kafka brokers
+type SASL struct {
+	Enabled      bool
+	Mechanism    string
+	User         string
+	Password     string
+	PasswordFile string // Read into Password when the config is loaded
+}
+
+// String redacts the password, so that it is never logged along with the rest of the config
+func (s SASL) String() string {
+	password := ""
+	if s.Password != "" {
+		password = redacted
+	}
+	return fmt.Sprintf("{Enabled:%t Mechanism:%s User:%s Password:%s PasswordFile:%s}", s.Enabled, s.Mechanism, s.User, password, s.PasswordFile)
+}
+
+// resolve validates the mechanism and credentials, and reads the password from PasswordFile if one is named
+func (s *SASL) resolve() error {
+	if !s.Enabled {
+		return nil
+	}
+
+	if s.Mechanism != SASLPlain {
+		return fmt.Errorf("Kafka.SASL.Mechanism must be %s, the only mechanism the Kafka client supports, got '%s'", SASLPlain, s.Mechanism)
+	}
+
+	switch {
+	case s.User == "":
+		return fmt.Errorf("Kafka.SASL.User must be set when SASL is enabled")
+	case s.Password != "" && s.PasswordFile != "":
+		return fmt.Errorf("Only one of Kafka.SASL.Password and Kafka.SASL.PasswordFile may be set")
+	case s.PasswordFile != "":
+		password, err := ioutil.ReadFile(s.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("Failed to read Kafka.SASL.PasswordFile: %s", err)
+		}
+		s.Password = strings.TrimRight(string(password), "\r\n")
+	}
+
+	if s.Password == "" {
+		return fmt.Errorf("Kafka.SASL.Password or Kafka.SASL.PasswordFile must be set when SASL is enabled")
+	}
+	return nil
+}
+
 // TopLevel directly corresponds to the orderer config yaml
 // Note, for non 1-1 mappings, you may append
 // something like `mapstructure:"weirdFoRMat"` to
@@ -90,35 +319,108 @@ type TopLevel struct {
 
 var defaults = TopLevel{
 	General: General{
-		OrdererType:   "solo",
-		LedgerType:    "ram",
-		BatchTimeout:  10 * time.Second,
-		BatchSize:     10,
-		QueueSize:     1000,
-		MaxWindowSize: 1000,
-		ListenAddress: "127.0.0.1",
-		ListenPort:    5151,
-		GenesisMethod: "static",
+		OrdererType:      "solo",
+		LedgerType:       "ram",
+		BatchTimeout:     10 * time.Second,
+		BatchSize:        10,
+		QueueSize:        1000,
+		MaxWindowSize:    1000,
+		MaxRecvMsgSize:   4 * 1024 * 1024,
+		MaxSendMsgSize:   100 * 1024 * 1024,
+		ListenAddress:    "127.0.0.1",
+		ListenPort:       5151,
+		GenesisMethod:    "static",
+		NetworkID:        "default",
+		EnableReflection: true,
+		Keepalive: Keepalive{
+			Time:    time.Minute,
+			Timeout: 20 * time.Second,
+			MinTime: time.Minute,
+		},
+		Interceptors: Interceptors{
+			Recovery: true,
+		},
+		Protocol: Protocol{
+			Features: []string{"CHAIN_ROUTING", "DELIVER_ACKS", "FILTERED_DELIVERY"},
+		},
+		Broadcast: Broadcast{
+			AckAfterCommit: true,
+			RetryAfter:     5 * time.Second,
+		},
+		Deliver: Deliver{
+			RetryAfter:  5 * time.Second,
+			Compression: "none",
+			SeekSkew:    time.Minute,
+		},
+		Gateway: Gateway{
+			ListenAddress: "127.0.0.1",
+			ListenPort:    5152,
+		},
 	},
 	RAMLedger: RAMLedger{
 		HistorySize: 10000,
 	},
 	FileLedger: FileLedger{
-		Location: "",
-		Prefix:   "hyperledger-fabric-rawledger",
+		Location:   "",
+		Prefix:     "hyperledger-fabric-rawledger",
+		SyncWrites: false,
 	},
 	Kafka: Kafka{
-		Brokers:     []string{"127.0.0.1:9092"},
-		Topic:       "test",
-		PartitionID: 0,
-		Version:     sarama.V0_9_0_1,
+		Brokers:                 []string{"127.0.0.1:9092"},
+		Topic:                   "test",
+		PartitionID:             0,
+		ChainMapping:            "partition",
+		TopicTemplate:           "fabric-{network}-{chain}",
+		Partitions:              1,
+		ReplicationFactor:       1,
+		CreateTopic:             true,
+		MinInSyncReplicas:       1,
+		Compression:             "none",
+		StartupTimeout:          60 * time.Second,
+		ShutdownTimeout:         10 * time.Second,
+		MetadataRefreshInterval: 5 * time.Minute,
 		Retry: Retry{
 			Period: 3 * time.Second,
 			Stop:   60 * time.Second,
 		},
+		Producer: Producer{
+			RequiredAcks:    "WaitForAll",
+			MaxMessageBytes: 1000000,
+			Retry: ProducerRetry{
+				Max:     3,
+				Backoff: 100 * time.Millisecond,
+			},
+		},
+		Consumer: Consumer{
+			Retry: ConsumerRetry{
+				Backoff:    100 * time.Millisecond,
+				MaxBackoff: 10 * time.Second,
+			},
+		},
+		SASL: SASL{
+			Mechanism: SASLPlain,
+		},
 	},
 }
 
+// BlockOverhead is the room left in a Deliver response for the header and metadata of a block besides its messages
+const BlockOverhead = 64 * 1024
+
+// checkMessageSizes returns an error if a block cut under the batch limits could be too large for Deliver to send
+func (g *General) checkMessageSizes() error {
+	// A message too large for the batch is cut into a block of its own
+	if g.MaxRecvMsgSize+BlockOverhead > g.MaxSendMsgSize {
+		return fmt.Errorf("General.MaxSendMsgSize %d must exceed General.MaxRecvMsgSize %d by at least %d, so that a block of one message may be delivered", g.MaxSendMsgSize, g.MaxRecvMsgSize, BlockOverhead)
+	}
+	if g.BatchMaxBytes > 0 && g.BatchMaxBytes+BlockOverhead > g.MaxSendMsgSize {
+		return fmt.Errorf("General.MaxSendMsgSize %d must exceed General.BatchMaxBytes %d by at least %d, so that a full block may be delivered", g.MaxSendMsgSize, g.BatchMaxBytes, BlockOverhead)
+	}
+	if g.BatchMaxBytes == 0 && uint64(g.BatchSize)*uint64(g.MaxRecvMsgSize)+BlockOverhead > uint64(g.MaxSendMsgSize) {
+		logger.Warningf("General.BatchMaxBytes is unset, so a block of %d large messages may exceed General.MaxSendMsgSize %d and be refused to Deliver clients", g.BatchSize, g.MaxSendMsgSize)
+	}
+	return nil
+}
+
 func (c *TopLevel) completeInitialization() {
 	defer logger.Infof("Validated configuration to: %+v", c)
 
@@ -142,6 +444,12 @@ func (c *TopLevel) completeInitialization() {
 		case c.General.MaxWindowSize == 0:
 			logger.Infof("General.MaxWindowSize unset, setting to %s", defaults.General.MaxWindowSize)
 			c.General.MaxWindowSize = defaults.General.MaxWindowSize
+		case c.General.MaxRecvMsgSize == 0:
+			logger.Infof("General.MaxRecvMsgSize unset, setting to %d", defaults.General.MaxRecvMsgSize)
+			c.General.MaxRecvMsgSize = defaults.General.MaxRecvMsgSize
+		case c.General.MaxSendMsgSize == 0:
+			logger.Infof("General.MaxSendMsgSize unset, setting to %d", defaults.General.MaxSendMsgSize)
+			c.General.MaxSendMsgSize = defaults.General.MaxSendMsgSize
 		case c.General.ListenAddress == "":
 			logger.Infof("General.ListenAddress unset, setting to %s", defaults.General.ListenAddress)
 			c.General.ListenAddress = defaults.General.ListenAddress
@@ -150,6 +458,36 @@ func (c *TopLevel) completeInitialization() {
 			c.General.ListenPort = defaults.General.ListenPort
 		case c.General.GenesisMethod == "":
 			c.General.GenesisMethod = defaults.General.GenesisMethod
+		case c.General.NetworkID == "":
+			logger.Infof("General.NetworkID unset, setting to %s", defaults.General.NetworkID)
+			c.General.NetworkID = defaults.General.NetworkID
+		case c.General.Deliver.RetryAfter == 0:
+			logger.Infof("General.Deliver.RetryAfter unset, setting to %v", defaults.General.Deliver.RetryAfter)
+			c.General.Deliver.RetryAfter = defaults.General.Deliver.RetryAfter
+		case c.General.Deliver.Compression == "":
+			logger.Infof("General.Deliver.Compression unset, setting to %s", defaults.General.Deliver.Compression)
+			c.General.Deliver.Compression = defaults.General.Deliver.Compression
+		case c.General.Deliver.SeekSkew == 0:
+			logger.Infof("General.Deliver.SeekSkew unset, setting to %v", defaults.General.Deliver.SeekSkew)
+			c.General.Deliver.SeekSkew = defaults.General.Deliver.SeekSkew
+		case c.General.Broadcast.RetryAfter == 0:
+			logger.Infof("General.Broadcast.RetryAfter unset, setting to %v", defaults.General.Broadcast.RetryAfter)
+			c.General.Broadcast.RetryAfter = defaults.General.Broadcast.RetryAfter
+		case c.General.Gateway.ListenAddress == "":
+			logger.Infof("General.Gateway.ListenAddress unset, setting to %s", defaults.General.Gateway.ListenAddress)
+			c.General.Gateway.ListenAddress = defaults.General.Gateway.ListenAddress
+		case c.General.Keepalive.Time == 0:
+			logger.Infof("General.Keepalive.Time unset, setting to %s", defaults.General.Keepalive.Time)
+			c.General.Keepalive.Time = defaults.General.Keepalive.Time
+		case c.General.Keepalive.Timeout == 0:
+			logger.Infof("General.Keepalive.Timeout unset, setting to %s", defaults.General.Keepalive.Timeout)
+			c.General.Keepalive.Timeout = defaults.General.Keepalive.Timeout
+		case c.General.Keepalive.MinTime == 0:
+			logger.Infof("General.Keepalive.MinTime unset, setting to %s", defaults.General.Keepalive.MinTime)
+			c.General.Keepalive.MinTime = defaults.General.Keepalive.MinTime
+		case c.General.Gateway.ListenPort == 0:
+			logger.Infof("General.Gateway.ListenPort unset, setting to %d", defaults.General.Gateway.ListenPort)
+			c.General.Gateway.ListenPort = defaults.General.Gateway.ListenPort
 		case c.FileLedger.Prefix == "":
 			logger.Infof("FileLedger.Prefix unset, setting to %s", defaults.FileLedger.Prefix)
 			c.FileLedger.Prefix = defaults.FileLedger.Prefix
@@ -159,16 +497,61 @@ func (c *TopLevel) completeInitialization() {
 		case c.Kafka.Topic == "":
 			logger.Infof("Kafka.Topic unset, setting to %v", defaults.Kafka.Topic)
 			c.Kafka.Topic = defaults.Kafka.Topic
+		case c.Kafka.ChainMapping == "":
+			logger.Infof("Kafka.ChainMapping unset, setting to %s", defaults.Kafka.ChainMapping)
+			c.Kafka.ChainMapping = defaults.Kafka.ChainMapping
+		case c.Kafka.TopicTemplate == "":
+			logger.Infof("Kafka.TopicTemplate unset, setting to %s", defaults.Kafka.TopicTemplate)
+			c.Kafka.TopicTemplate = defaults.Kafka.TopicTemplate
+		case c.Kafka.Partitions == 0:
+			logger.Infof("Kafka.Partitions unset, setting to %d", defaults.Kafka.Partitions)
+			c.Kafka.Partitions = defaults.Kafka.Partitions
+		case c.Kafka.ReplicationFactor == 0:
+			logger.Infof("Kafka.ReplicationFactor unset, setting to %d", defaults.Kafka.ReplicationFactor)
+			c.Kafka.ReplicationFactor = defaults.Kafka.ReplicationFactor
+		case c.Kafka.MinInSyncReplicas == 0:
+			logger.Infof("Kafka.MinInSyncReplicas unset, setting to %d", defaults.Kafka.MinInSyncReplicas)
+			c.Kafka.MinInSyncReplicas = defaults.Kafka.MinInSyncReplicas
+		case c.Kafka.StartupTimeout == 0:
+			logger.Infof("Kafka.StartupTimeout unset, setting to %v", defaults.Kafka.StartupTimeout)
+			c.Kafka.StartupTimeout = defaults.Kafka.StartupTimeout
+		case c.Kafka.Compression == "":
+			logger.Infof("Kafka.Compression unset, setting to %s", defaults.Kafka.Compression)
+			c.Kafka.Compression = defaults.Kafka.Compression
+		case c.Kafka.ShutdownTimeout == 0:
+			logger.Infof("Kafka.ShutdownTimeout unset, setting to %v", defaults.Kafka.ShutdownTimeout)
+			c.Kafka.ShutdownTimeout = defaults.Kafka.ShutdownTimeout
+		case c.Kafka.MetadataRefreshInterval == 0:
+			logger.Infof("Kafka.MetadataRefreshInterval unset, setting to %v", defaults.Kafka.MetadataRefreshInterval)
+			c.Kafka.MetadataRefreshInterval = defaults.Kafka.MetadataRefreshInterval
 		case c.Kafka.Retry.Period == 0*time.Second:
 			logger.Infof("Kafka.Retry.Period unset, setting to %v", defaults.Kafka.Retry.Period)
 			c.Kafka.Retry.Period = defaults.Kafka.Retry.Period
 		case c.Kafka.Retry.Stop == 0*time.Second:
 			logger.Infof("Kafka.Retry.Stop unset, setting to %v", defaults.Kafka.Retry.Stop)
 			c.Kafka.Retry.Stop = defaults.Kafka.Retry.Stop
+		case c.Kafka.Producer.RequiredAcks == "":
+			logger.Infof("Kafka.Producer.RequiredAcks unset, setting to %s", defaults.Kafka.Producer.RequiredAcks)
+			c.Kafka.Producer.RequiredAcks = defaults.Kafka.Producer.RequiredAcks
+		case c.Kafka.Producer.MaxMessageBytes == 0:
+			logger.Infof("Kafka.Producer.MaxMessageBytes unset, setting to %d", defaults.Kafka.Producer.MaxMessageBytes)
+			c.Kafka.Producer.MaxMessageBytes = defaults.Kafka.Producer.MaxMessageBytes
+		case c.Kafka.Producer.Retry.Max == 0:
+			logger.Infof("Kafka.Producer.Retry.Max unset, setting to %d", defaults.Kafka.Producer.Retry.Max)
+			c.Kafka.Producer.Retry.Max = defaults.Kafka.Producer.Retry.Max
+		case c.Kafka.Producer.Retry.Backoff == 0:
+			logger.Infof("Kafka.Producer.Retry.Backoff unset, setting to %v", defaults.Kafka.Producer.Retry.Backoff)
+			c.Kafka.Producer.Retry.Backoff = defaults.Kafka.Producer.Retry.Backoff
+		case c.Kafka.Consumer.Retry.Backoff == 0:
+			logger.Infof("Kafka.Consumer.Retry.Backoff unset, setting to %v", defaults.Kafka.Consumer.Retry.Backoff)
+			c.Kafka.Consumer.Retry.Backoff = defaults.Kafka.Consumer.Retry.Backoff
+		case c.Kafka.Consumer.Retry.MaxBackoff == 0:
+			logger.Infof("Kafka.Consumer.Retry.MaxBackoff unset, setting to %v", defaults.Kafka.Consumer.Retry.MaxBackoff)
+			c.Kafka.Consumer.Retry.MaxBackoff = defaults.Kafka.Consumer.Retry.MaxBackoff
+		case c.Kafka.SASL.Enabled && c.Kafka.SASL.Mechanism == "":
+			logger.Infof("Kafka.SASL.Mechanism unset, setting to %s", defaults.Kafka.SASL.Mechanism)
+			c.Kafka.SASL.Mechanism = defaults.Kafka.SASL.Mechanism
 		default:
-			// A bit hacky, but its type makes it impossible to test for a nil value.
-			// This may be overwritten by the Kafka orderer upon instantiation.
-			c.Kafka.Version = defaults.Kafka.Version
 			return
 		}
 	}
@@ -210,5 +593,17 @@ func Load() *TopLevel {
 
 	uconf.completeInitialization()
 
+	if err = uconf.Kafka.SASL.resolve(); err != nil {
+		panic(err)
+	}
+
+	if err = uconf.General.checkMessageSizes(); err != nil {
+		panic(err)
+	}
+
+	if _, err = ab.ParseFeatures(uconf.General.Protocol.Features); err != nil {
+		panic(fmt.Errorf("Invalid General.Protocol.Features: %s", err))
+	}
+
 	return &uconf
 }
@@ -19,11 +19,14 @@ package config
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
 
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
 	"github.com/spf13/viper"
 )
 
@@ -35,6 +38,12 @@ func TestGoodConfig(t *testing.T) {
 	t.Logf("%+v", config)
 }
 
+func TestProtocolFeatures(t *testing.T) {
+	if features := Load().General.Protocol.Enabled(); features != ab.AllFeatures {
+		t.Fatalf("Expected every protocol feature to be enabled by the shipped config, got %v", features.Hello().Features)
+	}
+}
+
 func TestBadConfig(t *testing.T) {
 	config := viper.New()
 	config.SetConfigName("orderer")
@@ -111,3 +120,73 @@ func TestEnvInnerVar(t *testing.T) {
 		t.Fatalf("Environmental override of inner config did not work")
 	}
 }
+
+func TestSASLResolve(t *testing.T) {
+	passwordFile, err := ioutil.TempFile("", "hyperledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(passwordFile.Name())
+	passwordFile.WriteString("fromfile\n")
+	passwordFile.Close()
+
+	testCases := []struct {
+		name     string
+		sasl     SASL
+		password string
+		fails    bool
+	}{
+		{"disabled", SASL{Mechanism: "unknown"}, "", false},
+		{"plain", SASL{Enabled: true, Mechanism: SASLPlain, User: "u", Password: "p"}, "p", false},
+		{"password file", SASL{Enabled: true, Mechanism: SASLPlain, User: "u", PasswordFile: passwordFile.Name()}, "fromfile", false},
+		{"unknown mechanism", SASL{Enabled: true, Mechanism: "GSSAPI", User: "u", Password: "p"}, "", true},
+		{"scram", SASL{Enabled: true, Mechanism: "SCRAM-SHA-256", User: "u", Password: "p"}, "", true},
+		{"no user", SASL{Enabled: true, Mechanism: SASLPlain, Password: "p"}, "", true},
+		{"no password", SASL{Enabled: true, Mechanism: SASLPlain, User: "u"}, "", true},
+		{"both passwords", SASL{Enabled: true, Mechanism: SASLPlain, User: "u", Password: "p", PasswordFile: passwordFile.Name()}, "", true},
+		{"missing password file", SASL{Enabled: true, Mechanism: SASLPlain, User: "u", PasswordFile: passwordFile.Name() + ".missing"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		err := tc.sasl.resolve()
+		if tc.fails != (err != nil) {
+			t.Errorf("%s: unexpected result %v", tc.name, err)
+			continue
+		}
+		if !tc.fails && tc.sasl.Password != tc.password {
+			t.Errorf("%s: expected password %q, got %q", tc.name, tc.password, tc.sasl.Password)
+		}
+	}
+}
+
+func TestSASLPasswordRedacted(t *testing.T) {
+	conf := TopLevel{Kafka: Kafka{SASL: SASL{Enabled: true, User: "u", Password: "hunter2"}}}
+	if dump := fmt.Sprintf("%+v", conf); strings.Contains(dump, "hunter2") {
+		t.Fatalf("The password should be redacted from the config dump: %s", dump)
+	}
+
+	keys := map[string]interface{}{"kafka": map[string]interface{}{"sasl": map[string]interface{}{"user": "u", "password": "hunter2"}}}
+	if dump := fmt.Sprintf("%+v", redactSecrets("", keys)); strings.Contains(dump, "hunter2") {
+		t.Fatalf("The password should be redacted from the logged keys: %s", dump)
+	}
+}
+
+func TestCheckMessageSizes(t *testing.T) {
+	testCases := []struct {
+		name    string
+		general General
+		fails   bool
+	}{
+		{"defaults", defaults.General, false},
+		{"recv beyond send", General{BatchSize: 1, MaxRecvMsgSize: 1000, MaxSendMsgSize: 1000 + BlockOverhead - 1}, true},
+		{"batch beyond send", General{BatchSize: 10, BatchMaxBytes: 5000, MaxRecvMsgSize: 1000, MaxSendMsgSize: 5000 + BlockOverhead - 1}, true},
+		{"batch within send", General{BatchSize: 10, BatchMaxBytes: 5000, MaxRecvMsgSize: 1000, MaxSendMsgSize: 5000 + BlockOverhead}, false},
+		{"unbounded batch", General{BatchSize: 10, MaxRecvMsgSize: 1000, MaxSendMsgSize: 1000 + BlockOverhead}, false},
+	}
+
+	for _, tc := range testCases {
+		if err := tc.general.checkMessageSizes(); tc.fails != (err != nil) {
+			t.Errorf("%s: unexpected result %v", tc.name, err)
+		}
+	}
+}
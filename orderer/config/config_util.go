@@ -25,6 +25,28 @@ import (
 	"github.com/spf13/viper"
 )
 
+const redacted = "[REDACTED]"
+
+// isSecret reports whether the value of a config key must not be logged
+func isSecret(fqKey string) bool {
+	return strings.HasSuffix(strings.ToLower(fqKey), "password")
+}
+
+// redactSecrets returns a copy of keys suitable for logging, with the values of secret keys replaced
+func redactSecrets(base string, keys map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(keys))
+	for key, val := range keys {
+		if isSecret(base + key) {
+			result[key] = redacted
+		} else if m, ok := val.(map[string]interface{}); ok {
+			result[key] = redactSecrets(base+key+".", m)
+		} else {
+			result[key] = val
+		}
+	}
+	return result
+}
+
 func getKeysRecursively(base string, v *viper.Viper, nodeKeys map[string]interface{}) map[string]interface{} {
 	result := make(map[string]interface{})
 	for key := range nodeKeys {
@@ -41,6 +63,9 @@ func getKeysRecursively(base string, v *viper.Viper, nodeKeys map[string]interfa
 				tmp[cik] = iv
 			}
 			result[key] = getKeysRecursively(fqKey+".", v, tmp)
+		} else if isSecret(fqKey) {
+			logger.Debugf("Found real value for %s setting to %T %s", fqKey, val, redacted)
+			result[key] = val
 		} else {
 			logger.Debugf("Found real value for %s setting to %T %v", fqKey, val, val)
 			result[key] = val
@@ -87,7 +112,7 @@ func ExactWithDateUnmarshal(v *viper.Viper, output interface{}) error {
 	baseKeys := v.AllSettings() // AllKeys doesn't actually return all keys, it only returns the base ones
 	leafKeys := getKeysRecursively("", v, baseKeys)
 
-	logger.Infof("%+v", leafKeys)
+	logger.Infof("%+v", redactSecrets("", leafKeys))
 	config := &mapstructure.DecoderConfig{
 		ErrorUnused:      true,
 		Metadata:         nil,
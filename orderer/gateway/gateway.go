@@ -0,0 +1,235 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gateway serves the Broadcast and Deliver services of an orderer over HTTP as JSON, for tools which cannot speak gRPC
+package gateway
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+var logger = logging.MustGetLogger("orderer/gateway")
+
+// deliverWindow is the window of the Deliver streams behind /blocks, each block is acknowledged once it is written
+const deliverWindow = 1
+
+type handler struct {
+	srv       ab.AtomicBroadcastServer
+	marshaler jsonpb.Marshaler
+}
+
+// New returns a handler serving POST /broadcast, which orders the JSON encoded BroadcastMessage of the request body and
+// replies with the JSON encoded BroadcastResponse, and GET /blocks?from=N[&to=M][&chain=ID], which streams the JSON
+// encoded DeliverResponse of each block as newline delimited JSON, the HTTP status of a failure following its Status
+func New(srv ab.AtomicBroadcastServer) http.Handler {
+	h := &handler{srv: srv}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/broadcast", h.broadcast)
+	mux.HandleFunc("/blocks", h.blocks)
+	return mux
+}
+
+// httpStatus returns the HTTP status of a reply, the failure statuses share their numbers with HTTP
+func httpStatus(status ab.Status) int {
+	if status == ab.Status_SUCCESS {
+		return http.StatusOK
+	}
+	return int(status)
+}
+
+// requestContext carries the client of the request as a gRPC peer, so that the orderer identifies it as it would over gRPC
+func requestContext(r *http.Request) context.Context {
+	p := &peer.Peer{}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		p.Addr = addr
+	}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	return peer.NewContext(r.Context(), p)
+}
+
+func (h *handler) write(w http.ResponseWriter, status int, msg proto.Message) error {
+	var buf bytes.Buffer
+	if err := h.marshaler.Marshal(&buf, msg); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (h *handler) broadcast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	msg := &ab.BroadcastMessage{}
+	if err := jsonpb.Unmarshal(r.Body, msg); err != nil {
+		h.write(w, http.StatusBadRequest, ab.ReasonMalformed.BroadcastResponse("request body is not a JSON encoded message: %s", err))
+		return
+	}
+
+	resp, err := h.srv.SubmitBroadcast(requestContext(r), msg)
+	if err != nil {
+		logger.Debugf("Broadcast over HTTP from %s failed: %s", r.RemoteAddr, err)
+		h.write(w, http.StatusServiceUnavailable, ab.ReasonUnavailable.BroadcastResponse("%s", grpc.ErrorDesc(err)))
+		return
+	}
+	if resp.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatUint((resp.RetryAfter+999)/1000, 10))
+	}
+	h.write(w, httpStatus(resp.Status), resp)
+}
+
+// parseSeek returns the seek described by the query of a /blocks request
+func parseSeek(r *http.Request) (*ab.SeekInfo, error) {
+	query := r.URL.Query()
+	seek := &ab.SeekInfo{Start: ab.SeekInfo_OLDEST, WindowSize: deliverWindow}
+
+	if from := query.Get("from"); from != "" {
+		number, err := strconv.ParseUint(from, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("from %q is not a block number", from)
+		}
+		seek.Start, seek.SpecifiedNumber = ab.SeekInfo_SPECIFIED, number
+	}
+	if to := query.Get("to"); to != "" {
+		number, err := strconv.ParseUint(to, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("to %q is not a block number", to)
+		}
+		seek.Stop, seek.StopNumber, seek.WaitForStop = ab.SeekInfo_STOP_SPECIFIED, number, true
+	}
+	if chain := query.Get("chain"); chain != "" {
+		chainID, err := hex.DecodeString(chain)
+		if err != nil {
+			return nil, fmt.Errorf("chain %q is not hex encoded", chain)
+		}
+		seek.ChainID = chainID
+	}
+	return seek, nil
+}
+
+func (h *handler) blocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	seek, err := parseSeek(r)
+	if err != nil {
+		h.write(w, http.StatusBadRequest, ab.ReasonMalformed.DeliverResponse("%s", err))
+		return
+	}
+
+	ds := &deliverStream{
+		h:       h,
+		w:       w,
+		ctx:     requestContext(r),
+		updates: make(chan *ab.DeliverUpdate, deliverWindow+2),
+	}
+	ds.flusher, _ = w.(http.Flusher)
+	ds.updates <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.NewFeatures(ab.Feature_CHAIN_ROUTING, ab.Feature_DELIVER_ACKS).Hello()}}
+	ds.updates <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: seek}}
+	if err := h.srv.Deliver(ds); err != nil && err != io.EOF {
+		logger.Debugf("Deliver over HTTP to %s ended: %s", r.RemoteAddr, err)
+	}
+}
+
+// deliverStream is a Deliver stream writing each response to an HTTP response as a line of JSON
+type deliverStream struct {
+	grpc.ServerStream
+	h       *handler
+	w       http.ResponseWriter
+	flusher http.Flusher
+	ctx     context.Context
+	updates chan *ab.DeliverUpdate // The hello and the seek, followed by the acknowledgement of each block written
+	started bool
+}
+
+func (ds *deliverStream) Context() context.Context {
+	return ds.ctx
+}
+
+// Recv returns the hello and the seek and then the acknowledgements, and ends the stream once the client has gone
+func (ds *deliverStream) Recv() (*ab.DeliverUpdate, error) {
+	select {
+	case update := <-ds.updates:
+		return update, nil
+	case <-ds.ctx.Done():
+		return nil, io.EOF
+	}
+}
+
+func (ds *deliverStream) Send(resp *ab.DeliverResponse) error {
+	// The features selected are of no interest to the HTTP client
+	if resp.GetHello() != nil {
+		return nil
+	}
+
+	// Until something is written the status of the first response may be that of the whole request
+	status := 0
+	if !ds.started {
+		ds.started = true
+		status = http.StatusOK
+		if _, ok := resp.Type.(*ab.DeliverResponse_Error); ok {
+			status = httpStatus(resp.GetError())
+			if resp.RetryAfter > 0 {
+				ds.w.Header().Set("Retry-After", strconv.FormatUint((resp.RetryAfter+999)/1000, 10))
+			}
+		}
+	}
+	if err := ds.h.write(ds.w, status, resp); err != nil {
+		return err
+	}
+	if ds.flusher != nil {
+		ds.flusher.Flush()
+	}
+
+	if block := resp.GetBlock(); block != nil {
+		select {
+		case ds.updates <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: block.Header.Number}}}:
+		case <-ds.ctx.Done():
+			return ds.ctx.Err()
+		}
+	}
+	return nil
+}
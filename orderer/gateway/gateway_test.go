@@ -0,0 +1,253 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap/static"
+	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+	"github.com/hyperledger/fabric/orderer/solo"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+// newTestOrderer returns a solo orderer which cuts each message into its own block and replies once it is committed
+func newTestOrderer(t *testing.T) (solo.Orderer, rawledger.ReadWriter) {
+	genesisBlock, err := static.New().GenesisBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lf := ramledger.NewFactory(10)
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	srv, err := solo.New(solo.Options{QueueSize: 10, BatchSize: 1, MaxWindowSize: 10, BatchTimeout: time.Hour, AckAfterCommit: true, Features: ab.AllFeatures}, lf, static.TestChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, rl
+}
+
+func post(t *testing.T, url, body string) (int, *ab.BroadcastResponse) {
+	resp, err := http.Post(url+"/broadcast", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to post: %s", err)
+	}
+	defer resp.Body.Close()
+	reply := &ab.BroadcastResponse{}
+	if err := jsonpb.Unmarshal(resp.Body, reply); err != nil {
+		t.Fatalf("Expected a JSON encoded reply: %s", err)
+	}
+	return resp.StatusCode, reply
+}
+
+// getBlocks returns the HTTP status of a /blocks request and the responses streamed by it
+func getBlocks(t *testing.T, url, query string) (int, []*ab.DeliverResponse) {
+	resp, err := http.Get(url + "/blocks?" + query)
+	if err != nil {
+		t.Fatalf("Failed to get: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var responses []*ab.DeliverResponse
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		reply := &ab.DeliverResponse{}
+		if err := jsonpb.Unmarshal(bytes.NewReader(scanner.Bytes()), reply); err != nil {
+			t.Fatalf("Expected each line to be a JSON encoded response, got %q: %s", scanner.Text(), err)
+		}
+		responses = append(responses, reply)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Failed to read the stream: %s", err)
+	}
+	return resp.StatusCode, responses
+}
+
+func TestBroadcast(t *testing.T) {
+	srv, rl := newTestOrderer(t)
+	defer srv.Teardown()
+	server := httptest.NewServer(New(srv))
+	defer server.Close()
+
+	// The fields are named as in JSON encoded protobuf, bytes are base64 encoded
+	status, reply := post(t, server.URL, `{"data": "aGVsbG8="}`)
+	if status != http.StatusOK || reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 1 {
+		t.Fatalf("Expected the message to be committed in block 1, got %d %v", status, reply)
+	}
+	if block, _ := rl.GetBlock(1); string(block.Data.Messages[0].Data) != "hello" {
+		t.Fatalf("Expected block 1 to hold the message, got %v", block)
+	}
+
+	status, reply = post(t, server.URL, `{}`)
+	if status != http.StatusBadRequest || reply.Status != ab.Status_BAD_REQUEST || reply.Info != "message is empty" {
+		t.Fatalf("Expected the empty message to be rejected, got %d %v", status, reply)
+	}
+
+	status, reply = post(t, server.URL, `{"chainID": "dW5rbm93bg==", "data": "aGVsbG8="}`)
+	if status != http.StatusNotFound || reply.Status != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected NOT_FOUND for an unknown chain, got %d %v", status, reply)
+	}
+
+	if status, _ = post(t, server.URL, `not json`); status != http.StatusBadRequest {
+		t.Fatalf("Expected a malformed body to be a bad request, got %d", status)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	srv, rl := newTestOrderer(t)
+	defer srv.Teardown()
+	server := httptest.NewServer(New(srv))
+	defer server.Close()
+	for _, data := range []string{"aGVsbG8=", "d29ybGQ="} {
+		if status, reply := post(t, server.URL, `{"data": "`+data+`"}`); status != http.StatusOK {
+			t.Fatalf("Failed to broadcast: %v", reply)
+		}
+	}
+
+	status, responses := getBlocks(t, server.URL, "from=0&to=2")
+	if status != http.StatusOK || len(responses) != 4 {
+		t.Fatalf("Expected three blocks and the end of the range, got %d %v", status, responses)
+	}
+	// Every field of each block survives the round trip through JSON, so that its hash and signature may be verified
+	for number, resp := range responses[:3] {
+		expected, _ := rl.GetBlock(uint64(number))
+		if !proto.Equal(resp.GetBlock(), expected) {
+			t.Fatalf("Expected block %d to be %v, got %v", number, expected, resp.GetBlock())
+		}
+	}
+	if responses[3].GetError() != ab.Status_SUCCESS {
+		t.Fatalf("Expected the stream to end with SUCCESS, got %v", responses[3])
+	}
+
+	status, responses = getBlocks(t, server.URL, "from=5")
+	if status != http.StatusNotFound || len(responses) != 1 || responses[0].Info != "seek target 5 exceeds height 3" {
+		t.Fatalf("Expected a seek beyond the tail to be NOT_FOUND, got %d %v", status, responses)
+	}
+
+	// The gateway negotiates chain routing, so the chain named is honored
+	if status, _ = getBlocks(t, server.URL, "chain=756e6b6e6f776e"); status != http.StatusNotFound {
+		t.Fatalf("Expected an unknown chain to be NOT_FOUND, got %d", status)
+	}
+
+	if status, _ = getBlocks(t, server.URL, "from=first"); status != http.StatusBadRequest {
+		t.Fatalf("Expected a malformed block number to be a bad request, got %d", status)
+	}
+}
+
+// writeIdentity writes a self signed certificate and its key, returning their paths
+func writeIdentity(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	serverCert, serverKey := writeIdentity(t, dir, "server")
+	clientCert, clientKey := writeIdentity(t, dir, "client")
+
+	srv, _ := newTestOrderer(t)
+	defer srv.Teardown()
+	lis, err := Start(config.Gateway{
+		Enabled:       true,
+		ListenAddress: "127.0.0.1",
+		TLS:           config.ServerTLS{Enabled: true, Certificate: serverCert, PrivateKey: serverKey, ClientRootCAs: []string{clientCert}},
+	}, srv)
+	if err != nil {
+		t.Fatalf("Failed to start the gateway: %s", err)
+	}
+	defer lis.Close()
+	url := "https://" + lis.Addr().String() + "/broadcast"
+
+	roots := x509.NewCertPool()
+	pemBytes, _ := ioutil.ReadFile(serverCert)
+	roots.AppendCertsFromPEM(pemBytes)
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if _, err := anonymous.Post(url, "application/json", strings.NewReader(`{"data": "aGVsbG8="}`)); err == nil {
+		t.Fatal("Expected a client without a certificate to be refused")
+	}
+
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticated := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}}}}
+	resp, err := authenticated.Post(url, "application/json", strings.NewReader(`{"data": "aGVsbG8="}`))
+	if err != nil {
+		t.Fatalf("Expected a client with a certificate to be served: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the message to be ordered, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/tlsutil"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+// newTLSConfig builds the config of the gateway listener, which requires client certificates if conf names client root CAs,
+// returning nil if TLS is not enabled
+func newTLSConfig(conf config.ServerTLS) (*tls.Config, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	return tlsutil.NewServerConfig(conf.Certificate, conf.PrivateKey, conf.ClientRootCAs, "gateway")
+}
+
+// Start listens as configured and serves srv over HTTP in the background, returning the listener so that it may be
+// closed, or nil if the gateway is not enabled
+func Start(conf config.Gateway, srv ab.AtomicBroadcastServer) (net.Listener, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig, err := newTLSConfig(conf.TLS)
+	if err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", conf.ListenAddress, conf.ListenPort))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to listen for the gateway: %s", err)
+	}
+	if tlsConfig != nil {
+		lis = tls.NewListener(lis, tlsConfig)
+	}
+
+	logger.Infof("Serving the HTTP gateway on %s", lis.Addr())
+	go http.Serve(lis, New(srv))
+	return lis, nil
+}
@@ -0,0 +1,239 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// The requests the Kafka client does not implement, which are encoded here
+const (
+	saslHandshakeKey int16 = 17 // Answered from 0.10.0.0
+	createTopicsKey  int16 = 19 // Answered from 0.10.1.0, by the controller only
+)
+
+// The errors of a CreateTopics response which are not known to the Kafka client
+const (
+	errTopicAlreadyExists sarama.KError = 36
+	errNotController      sarama.KError = 41
+)
+
+// adminTimeout bounds each administrative request, and how long the controller is asked to wait for a topic to be created
+var adminTimeout = 30 * time.Second
+
+// adminClientID identifies the administrative connections of the orderer to the brokers
+const adminClientID = "orderer-admin"
+
+// dialBroker connects to the broker at addr, over TLS if the config enables it, and authenticates if it enables SASL
+func dialBroker(addr string, brokerConfig *sarama.Config, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if brokerConfig.Net.TLS.Enable {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, brokerConfig.Net.TLS.Config)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if brokerConfig.Net.SASL.Enable {
+		if err := authenticatePlain(conn, brokerConfig.Net.SASL.User, brokerConfig.Net.SASL.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// authenticatePlain performs the SASL PLAIN handshake on a connection, as the Kafka client does on its own
+func authenticatePlain(conn net.Conn, user, password string) error {
+	body := &requestEncoder{}
+	body.putString("PLAIN")
+	res, err := roundTrip(conn, saslHandshakeKey, body.Bytes())
+	if err != nil {
+		return fmt.Errorf("SASL handshake failed: %s", err)
+	}
+	if code := res.getInt16(); res.err == nil && code != 0 {
+		return fmt.Errorf("SASL handshake failed: %s", sarama.KError(code))
+	}
+
+	// The token is sent and answered without a request header, a broker rejecting it closes the connection
+	token := []byte("\x00" + user + "\x00" + password)
+	frame := make([]byte, 4+len(token))
+	binary.BigEndian.PutUint32(frame, uint32(len(token)))
+	copy(frame[4:], token)
+	if _, err := conn.Write(frame); err != nil {
+		return fmt.Errorf("SASL authentication failed: %s", err)
+	}
+	if _, err := io.ReadFull(conn, make([]byte, 4)); err != nil {
+		return fmt.Errorf("SASL authentication failed: %s", err)
+	}
+	return nil
+}
+
+// createTopic asks the controller to create the topic, trying each broker of the metadata until the controller answers
+func createTopic(brokers []*sarama.Broker, brokerConfig *sarama.Config, topic string, partitions int32, replicationFactor int16) error {
+	body := &requestEncoder{}
+	body.putInt32(1)
+	body.putString(topic)
+	body.putInt32(partitions)
+	body.putInt16(replicationFactor)
+	body.putInt32(0) // No replica assignment, the controller assigns the replicas
+	body.putInt32(0) // No config entries, the topic takes the defaults of the brokers
+	body.putInt32(int32(adminTimeout / time.Millisecond))
+
+	err := fmt.Errorf("no broker is known")
+	for _, broker := range brokers {
+		var code sarama.KError
+		code, err = createTopicOn(broker.Addr(), brokerConfig, body.Bytes())
+		switch {
+		case err != nil:
+			logger.Debugf("Kafka broker %s did not create topic %s: %s", broker.Addr(), topic, err)
+		case code == errNotController:
+			err = fmt.Errorf("broker %s is not the controller", broker.Addr())
+		case code == sarama.ErrNoError:
+			logger.Infof("Created Kafka topic %s with %d partitions and a replication factor of %d", topic, partitions, replicationFactor)
+			return nil
+		case code == errTopicAlreadyExists:
+			// Another orderer created it first, the next start checks its layout
+			logger.Infof("Kafka topic %s was created concurrently", topic)
+			return nil
+		default:
+			return fmt.Errorf("The Kafka controller refused to create topic %s: %s", topic, code)
+		}
+	}
+	return fmt.Errorf("Failed to create Kafka topic %s, which needs brokers of version 0.10.1.0 or later: %s", topic, err)
+}
+
+// createTopicOn sends the body of a CreateTopics request for a single topic to the broker at addr, and returns its error
+func createTopicOn(addr string, brokerConfig *sarama.Config, body []byte) (sarama.KError, error) {
+	conn, err := dialBroker(addr, brokerConfig, adminTimeout+handshakeTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	res, err := roundTrip(conn, createTopicsKey, body)
+	if err != nil {
+		return 0, err
+	}
+	if count := res.getInt32(); res.err == nil && count != 1 {
+		return 0, fmt.Errorf("expected the outcome of one topic, got %d", count)
+	}
+	res.getString()
+	code := sarama.KError(res.getInt16())
+	return code, res.err
+}
+
+// roundTrip sends a request of version 0 with the given key and body on conn, and returns a decoder of its response body
+// A broker which does not know the request closes the connection, which is returned as an error
+func roundTrip(conn net.Conn, key int16, body []byte) (*responseDecoder, error) {
+	const correlationID = 1
+	req := &requestEncoder{}
+	req.putInt16(key)
+	req.putInt16(0)
+	req.putInt32(correlationID)
+	req.putString(adminClientID)
+	req.Write(body)
+
+	frame := make([]byte, 4+req.Len())
+	binary.BigEndian.PutUint32(frame, uint32(req.Len()))
+	copy(frame[4:], req.Bytes())
+	if _, err := conn.Write(frame); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if id := binary.BigEndian.Uint32(header[4:]); id != correlationID {
+		return nil, fmt.Errorf("response has correlation ID %d, expected %d", id, correlationID)
+	}
+	size := binary.BigEndian.Uint32(header)
+	if size < 4 {
+		return nil, fmt.Errorf("response of length %d is too short", size)
+	}
+	res := make([]byte, size-4)
+	if _, err := io.ReadFull(conn, res); err != nil {
+		return nil, err
+	}
+	return &responseDecoder{buf: res}, nil
+}
+
+// requestEncoder writes the big endian fields of a request
+type requestEncoder struct {
+	bytes.Buffer
+}
+
+func (e *requestEncoder) putInt16(v int16) {
+	binary.Write(e, binary.BigEndian, v)
+}
+
+func (e *requestEncoder) putInt32(v int32) {
+	binary.Write(e, binary.BigEndian, v)
+}
+
+func (e *requestEncoder) putString(s string) {
+	e.putInt16(int16(len(s)))
+	e.WriteString(s)
+}
+
+// responseDecoder reads the big endian fields of a response, after the first failure err is set and zero values are returned
+type responseDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *responseDecoder) next(n int) []byte {
+	if d.err == nil && len(d.buf) < n {
+		d.err = fmt.Errorf("response is truncated")
+	}
+	if d.err != nil {
+		return make([]byte, n)
+	}
+	field := d.buf[:n]
+	d.buf = d.buf[n:]
+	return field
+}
+
+func (d *responseDecoder) getInt16() int16 {
+	return int16(binary.BigEndian.Uint16(d.next(2)))
+}
+
+func (d *responseDecoder) getInt32() int32 {
+	return int32(binary.BigEndian.Uint32(d.next(4)))
+}
+
+func (d *responseDecoder) getString() string {
+	n := d.getInt16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
@@ -19,10 +19,23 @@ package kafka
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Shopify/sarama"
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/blockcutter"
+	"github.com/hyperledger/fabric/orderer/common/blocksigner"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/consenter"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
 	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
 // Broadcaster allows the caller to submit messages to the orderer
@@ -31,107 +44,510 @@ type Broadcaster interface {
 	Closeable
 }
 
+// broadcasterImpl posts messages to the partition, and cuts the messages it consumes from the partition into blocks
+// Every orderer sharing the partition consumes the same messages and so cuts the same blocks
 type broadcasterImpl struct {
-	producer Producer
-	config   *config.TopLevel
-	once     sync.Once
+	producer    Producer
+	consumer    Consumer
+	config      *config.TopLevel
+	filter      *broadcastfilter.RuleSet
+	ledger      rawledger.ReadWriter
+	tracker     *configtx.LastConfigTracker // Points each block to the last configuration block
+	chainConfig configtx.Manager            // Applies the configuration transactions committed on the chain, if nil they are only recorded
+	audit       *audit.Trail                // Records each configuration transaction applied to chainConfig, once its block is committed
+	stamper     *rawledger.Timestamper      // Records the time each block is committed
+	signer      *blocksigner.Signer         // Signs each block as it is appended, blocks are unsigned if nil
+	metrics     *chainMetrics
+	notify      func(Connectivity) // Told when the chain fails
+	outage      *int32             // Set to 1 while the consumer of the partition is being re-established
+	clock       clock.Clock        // Drives the batch timer of the chain
+	next        int64              // The offset of the first message the consumer delivers, negative if it is not known
+	once        sync.Once
+	haltOnce    sync.Once
+	failOnce    sync.Once
 
-	batchChan  chan *ab.BroadcastMessage
-	messages   []*ab.BroadcastMessage
-	nextNumber uint64
-	prevHash   []byte
+	sendLock   sync.RWMutex // Held for reading by each Enqueue, guards closing, paused, retryAfter, and produced
+	closing    bool
+	paused     bool
+	retryAfter time.Duration // The retry hint sent to clients while paused
+	produced   int64         // The offset of the last message posted by Enqueue
+
+	closeOnce sync.Once
+	closeErr  error
+
+	haltChan  chan struct{}
+	errorChan chan struct{} // Closed once the partition can no longer be written to or consumed from
+	exitChan  chan struct{} // Closed once the consumer loop returns
+	drainChan chan int64    // Asks the consumer loop to commit the messages up to the given offset and return
 }
 
-func newBroadcaster(conf *config.TopLevel) Broadcaster {
+// newBroadcaster returns a chain which consumes the partition from seek once started, exports the metrics of the chain in registry, which may be nil if
+// they are not to be exported, and tells notify whenever the connectivity of the chain to the brokers changes
+func newBroadcaster(conf *config.TopLevel, rl rawledger.ReadWriter, registry gometrics.Registry, seek int64, notify func(Connectivity)) *broadcasterImpl {
+	logger.Infof("Starting the chain at block %d from offset %d of partition %d of topic %s", rl.Height(), seek, conf.Kafka.PartitionID, conf.Kafka.Topic)
+
+	// The metrics of the sarama clients are exported along with those of the chain
+	saramaRegistry := childRegistry(registry, "sarama.")
+	producer := newProducer(conf, saramaRegistry)
+	consumerErrors := newConsumerErrorCounters(registry)
+	connect := func(conf *config.TopLevel, seek int64) (Consumer, error) {
+		return newConsumer(conf, seek, saramaRegistry, consumerErrors)
+	}
+	outage := new(int32)
+	reconnecting := func(reconnecting bool) {
+		if reconnecting {
+			atomic.StoreInt32(outage, 1)
+			notify(Reconnecting)
+		} else {
+			atomic.StoreInt32(outage, 0)
+			notify(Connected)
+		}
+	}
+	consumer, err := newSupervisedConsumer(conf, seek, connect, registry, reconnecting)
+	if err != nil {
+		producer.Close()
+		panic(err)
+	}
+	b := newBroadcasterImpl(producer, consumer, conf, rl, registry)
+	b.notify = notify
+	b.outage = outage
+	b.next = seek
+	return b
+}
+
+func newBroadcasterImpl(producer Producer, consumer Consumer, conf *config.TopLevel, rl rawledger.ReadWriter, registry gometrics.Registry) *broadcasterImpl {
+	next, err := resumeOffset(rl, conf.Kafka.Topic, conf.Kafka.PartitionID)
+	if err != nil {
+		next = -1
+	}
 	return &broadcasterImpl{
-		producer:   newProducer(conf),
-		config:     conf,
-		batchChan:  make(chan *ab.BroadcastMessage, conf.General.BatchSize),
-		messages:   []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("genesis")}},
-		nextNumber: 0,
+		producer:  producer,
+		consumer:  consumer,
+		config:    conf,
+		filter:    newFilter(conf),
+		ledger:    rl,
+		tracker:   configtx.NewLastConfigTracker(rl),
+		stamper:   rawledger.NewTimestamper(rl),
+		signer:    newSigner(conf),
+		metrics:   newChainMetrics(registry),
+		notify:    func(Connectivity) {},
+		outage:    new(int32),
+		clock:     clock.Real{},
+		next:      next,
+		produced:  -1,
+		haltChan:  make(chan struct{}),
+		errorChan: make(chan struct{}),
+		exitChan:  make(chan struct{}),
+		drainChan: make(chan int64),
 	}
 }
 
+// kafkaMessageOverhead is what the Kafka client counts against Kafka.Producer.MaxMessageBytes besides the payload
+const kafkaMessageOverhead = 26
+
+// newFilter rejects the messages which, once in their envelope, would exceed Kafka.Producer.MaxMessageBytes, or which exceed General.MaxRecvMsgSize,
+// and then applies rules to the messages within the limits
+// Kafka.Producer.MaxMessageBytes should not exceed the message.max.bytes of the brokers, which the client cannot query
+func newFilter(conf *config.TopLevel, rules ...broadcastfilter.Rule) *broadcastfilter.RuleSet {
+	maxBytes := conf.Kafka.Producer.MaxMessageBytes - kafkaMessageOverhead - envelopeOverhead
+	if maxRecv := int(conf.General.MaxRecvMsgSize); maxRecv > 0 && maxRecv < maxBytes {
+		maxBytes = maxRecv
+	}
+	rules = append([]broadcastfilter.Rule{broadcastfilter.CorrelationIDRule, broadcastfilter.NewMaxBytesRule(maxBytes)}, rules...)
+	return broadcastfilter.NewRuleSet(append(rules, broadcastfilter.AcceptRule))
+}
+
 // Broadcast receives ordering requests by clients and sends back an
 // acknowledgement for each received message in order, indicating
 // success or type of failure
 func (b *broadcasterImpl) Broadcast(stream ab.AtomicBroadcast_BroadcastServer) error {
+	return broadcast.Handle(stream, b.filter, b, b.config.General.Protocol.Enabled())
+}
+
+// Chain returns the broadcaster itself, whatever the chain ID, as it orders a single chain
+func (b *broadcasterImpl) Chain(chainID []byte) (consenter.Chain, bool) {
+	return b, true
+}
+
+// Start begins consuming the partition and cutting blocks, it may be called more than once
+func (b *broadcasterImpl) Start() {
 	b.once.Do(func() {
-		// Send the genesis block to create the topic
-		// otherwise consumers will throw an exception.
-		b.sendBlock()
-		// Spawn the goroutine that cuts blocks
-		go b.cutBlock(b.config.General.BatchTimeout, b.config.General.BatchSize)
+		go b.loop(b.consumer.Recv())
+	})
+}
+
+// Halt stops cutting blocks, messages which are pending are left on the partition
+func (b *broadcasterImpl) Halt() {
+	b.haltOnce.Do(func() {
+		close(b.haltChan)
 	})
-	return b.recvRequests(stream)
 }
 
-// Close shuts down the broadcast side of the orderer
+// Errored returns a channel which is closed once the partition can no longer be written to or consumed from
+func (b *broadcasterImpl) Errored() <-chan struct{} {
+	return b.errorChan
+}
+
+// Enqueue posts a message to the partition, returning false if it could not be posted
+func (b *broadcasterImpl) Enqueue(msg *ab.BroadcastMessage) bool {
+	return b.EnqueueReply(msg).Status == ab.Status_SUCCESS
+}
+
+// EnqueueReply posts a message to the partition, replying BAD_REQUEST if the brokers refuse it as too large, and
+// SERVICE_UNAVAILABLE without failing the chain if too few replicas are in sync to store it once retries are exhausted
+// While the consumer is being re-established nothing posted could be cut into a block, so SERVICE_UNAVAILABLE is replied
+func (b *broadcasterImpl) EnqueueReply(msg *ab.BroadcastMessage) *ab.BroadcastResponse {
+	select {
+	case <-b.errorChan:
+		return ab.ReasonUnavailable.BroadcastResponse("partition %d has failed", b.config.Kafka.PartitionID)
+	case <-b.haltChan:
+		return ab.ReasonUnavailable.BroadcastResponse("partition %d is halted", b.config.Kafka.PartitionID)
+	default:
+	}
+	if atomic.LoadInt32(b.outage) == 1 {
+		return ab.ReasonUnavailable.BroadcastResponse("consumer of partition %d is reconnecting", b.config.Kafka.PartitionID)
+	}
+
+	data, err := encodeRegular(msg)
+	if err != nil {
+		logger.Errorf("Failed to marshal the message: %s", err)
+		return ab.ReasonMalformed.BroadcastResponse("message cannot be encoded")
+	}
+
+	b.sendLock.RLock()
+	defer b.sendLock.RUnlock()
+	if b.closing {
+		return ab.ReasonUnavailable.BroadcastResponse("orderer is shutting down")
+	}
+	if b.paused {
+		paused := ab.ReasonUnavailable.BroadcastResponse("ordering is paused")
+		paused.RetryAfter = uint64(b.retryAfter / time.Millisecond)
+		return paused
+	}
+	offset, err := b.producer.Send(data)
+	if err == sarama.ErrMessageSizeTooLarge {
+		// The partition is still usable, only this message cannot be stored
+		logger.Warningf("The Kafka brokers refused a message of %d bytes as too large, Kafka.Producer.MaxMessageBytes may exceed their message.max.bytes", len(data))
+		b.metrics.oversized.Inc(1)
+		return ab.ReasonOversized.BroadcastResponse("message %d bytes exceeds the limit of the brokers", len(data))
+	}
+	if err == sarama.ErrNotEnoughReplicas || err == sarama.ErrNotEnoughReplicasAfterAppend {
+		// The in-sync replicas may recover, the client is to retry later rather than be told the message is ordered
+		// After an append the leader may still have stored the message, in which case it is ordered as well
+		logger.Warningf("Too few in-sync replicas of partition %d of topic %s to store a message: %s", b.config.Kafka.PartitionID, b.config.Kafka.Topic, err)
+		b.metrics.underReplicated.Inc(1)
+		return ab.ReasonUnavailable.BroadcastResponse("too few in-sync replicas of partition %d", b.config.Kafka.PartitionID)
+	}
+	if err != nil {
+		b.fail(err)
+		return ab.ReasonUnavailable.BroadcastResponse("posting to partition %d failed: %s", b.config.Kafka.PartitionID, err)
+	}
+	b.advanceProduced(offset)
+	b.metrics.produced.Inc(1)
+	return &ab.BroadcastResponse{Status: ab.Status_SUCCESS}
+}
+
+// pause stops posting messages, replying SERVICE_UNAVAILABLE with a hint to retry after retryAfter, once it returns the messages
+// being posted have been posted, they and the messages posted by the other orderers of the partition are still cut into blocks
+func (b *broadcasterImpl) pause(retryAfter time.Duration) {
+	b.sendLock.Lock()
+	defer b.sendLock.Unlock()
+	b.paused = true
+	b.retryAfter = retryAfter
+}
+
+// resume posts messages again
+func (b *broadcasterImpl) resume() {
+	b.sendLock.Lock()
+	defer b.sendLock.Unlock()
+	b.paused = false
+}
+
+// advanceProduced records the offset of a posted message, the caller must hold the send lock for reading
+func (b *broadcasterImpl) advanceProduced(offset int64) {
+	for {
+		produced := atomic.LoadInt64(&b.produced)
+		if offset <= produced || atomic.CompareAndSwapInt64(&b.produced, produced, offset) {
+			return
+		}
+	}
+}
+
+// Close stops accepting messages, waits until the messages it posted have been consumed and cut into blocks,
+// up to Kafka.ShutdownTimeout, and then closes the consumer and the producer, it may be called more than once
 func (b *broadcasterImpl) Close() error {
-	if b.producer != nil {
-		return b.producer.Close()
+	b.closeOnce.Do(func() {
+		b.closeErr = b.close()
+	})
+	return b.closeErr
+}
+
+func (b *broadcasterImpl) close() error {
+	// Waits for the messages being posted, once the lock is released no more are accepted
+	b.sendLock.Lock()
+	b.closing = true
+	produced := b.produced
+	b.sendLock.Unlock()
+
+	// If the loop was never started there is nothing to drain
+	b.once.Do(func() { close(b.exitChan) })
+
+	select {
+	case b.drainChan <- produced:
+		select {
+		case <-b.exitChan:
+		case <-time.After(b.config.Kafka.ShutdownTimeout):
+			logger.Warningf("Messages up to offset %d were not all cut into blocks within %v, they remain on the partition", produced, b.config.Kafka.ShutdownTimeout)
+		}
+	case <-b.exitChan:
 	}
-	return nil
+
+	b.Halt()
+	<-b.exitChan
+	if err := b.consumer.Close(); err != nil {
+		return err
+	}
+	return b.producer.Close()
 }
 
-func (b *broadcasterImpl) sendBlock() error {
-	block := &ab.Block{
-		Messages: b.messages,
-		Number:   b.nextNumber,
-		PrevHash: b.prevHash,
+func (b *broadcasterImpl) fail(err error) {
+	b.failOnce.Do(func() {
+		logger.Errorf("Cannot communicate with Kafka broker: %s", err)
+		close(b.errorChan)
+		b.notify(Failed)
+	})
+}
+
+// batchTimeout is how long after the first message of a batch is consumed the time-to-cut message is posted, latency
+// is the delay between posting a message and consuming it, which is taken from the batch timeout for the first
+// message of the batch and again for the time-to-cut message
+func batchTimeout(timeout, latency time.Duration) time.Duration {
+	if timeout -= 2 * latency; timeout > 0 {
+		return timeout
 	}
-	logger.Debugf("Prepared block %d with %d messages (%+v)", block.Number, len(block.Messages), block)
+	return 0
+}
 
-	b.messages = []*ab.BroadcastMessage{}
-	b.nextNumber++
-	hash, data := hashBlock(block)
-	b.prevHash = hash
+// batchParameters returns how the blocks of the chain are cut, as set by General unless the configuration of the chain overrides it
+func (b *broadcasterImpl) batchParameters() sharedconfig.BatchParameters {
+	params := sharedconfig.BatchParameters{Size: int(b.config.General.BatchSize), MaxBytes: int(b.config.General.BatchMaxBytes), Timeout: b.config.General.BatchTimeout}
+	if b.chainConfig == nil {
+		return params
+	}
+	return sharedconfig.Batch(b.chainConfig, params)
+}
 
-	return b.producer.Send(data)
+// applyConfig applies a configuration transaction committed in block to the configuration of the chain, if it is tracked, and audits it
+func (b *broadcasterImpl) applyConfig(configTx *ab.ConfigurationEnvelope, block *ab.Block, offset int64) {
+	if b.chainConfig == nil || configTx == nil {
+		return
+	}
+	if err := b.chainConfig.Apply(configTx); err != nil {
+		logger.Errorf("Configuration transaction at offset %d could not be applied: %s", offset, err)
+		return
+	}
+	b.audit.Write(audit.NewRecord(block.Header.Number, configTx, b.clock.Now()))
 }
 
-func (b *broadcasterImpl) cutBlock(period time.Duration, maxSize uint) {
-	timer := time.NewTimer(period)
+// loop cuts the messages consumed from the partition into blocks, by count or General.BatchMaxBytes, or when the first time-to-cut message for the next block is consumed
+// A configuration transaction is committed in a block of its own, and the blocks which follow it are cut under the configuration it sets
+// Once the first message of a batch is consumed the orderer waits BatchTimeout, then posts a time-to-cut message for the next block
+// Each orderer sharing the partition may post one, the first to be consumed cuts the block and the others are stale
+func (b *broadcasterImpl) loop(messages <-chan *sarama.ConsumerMessage) {
+	defer close(b.exitChan)
+
+	params := b.batchParameters()
+	cutter := blockcutter.NewReceiver(params.Size, params.MaxBytes)
+	// The timer is armed when the first message of a batch is consumed, each chain keeps its own
+	var timer clock.Timer
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+		}
+	}
+	pending := false
+
+	// The delay between posting a message and consuming it starts at the flush delay of the producer, and follows the
+	// round trips of the time-to-cut messages this orderer posts, so that blocks are cut close to General.BatchTimeout
+	// after their first message was posted
+	latency := b.config.Kafka.Producer.Flush.Frequency
+	var timeToCutNumber uint64 // The block of the time-to-cut message this orderer posted last, zero once it is consumed
+	var timeToCutPosted time.Time
+	postTimeToCut := func(number uint64) error {
+		if _, err := b.producer.Send(encodeTimeToCut(number)); err != nil {
+			return err
+		}
+		b.metrics.timeToCutPosted.Inc(1)
+		timeToCutNumber, timeToCutPosted = number, b.clock.Now()
+		return nil
+	}
+	// The offset of the last message consumed, unknown until the first message if the chain is cut from the oldest retained one
+	consumed := int64(-1)
+	if b.next >= 0 {
+		consumed = b.next - 1
+	}
+
+	// Once draining, the loop returns when every message up to drainTarget has been consumed and cut
+	draining := false
+	drainTarget := int64(-1)
+	drainPosted := false // Whether a time-to-cut message was posted since the last block was cut
+
+	defer stopTimer()
 
 	for {
+		var timeout <-chan time.Time
+		if timer != nil {
+			timeout = timer.C()
+		}
+
 		select {
-		case msg := <-b.batchChan:
-			b.messages = append(b.messages, msg)
-			if len(b.messages) >= int(maxSize) {
-				if err := b.sendBlock(); err != nil {
-					panic(fmt.Errorf("Cannot communicate with Kafka broker: %s", err))
+		case in, ok := <-messages:
+			if !ok {
+				b.fail(fmt.Errorf("The partition consumer was closed"))
+				return
+			}
+			if consumed >= 0 && in.Offset != consumed+1 {
+				// Messages were lost or delivered twice, the ledger would no longer match that of the other orderers
+				logger.Errorf("Expected the message at offset %d of partition %d of topic %s, but consumed offset %d", consumed+1, b.config.Kafka.PartitionID, b.config.Kafka.Topic, in.Offset)
+				if !b.config.Kafka.Consumer.AllowOffsetGaps {
+					b.fail(fmt.Errorf("Halting the chain at block %d on an offset gap, set Kafka.Consumer.AllowOffsetGaps to resume", b.ledger.Height()))
+					return
 				}
-				if !timer.Stop() {
-					<-timer.C
+				if in.Offset <= consumed {
+					logger.Warningf("Skipping the message at offset %d, it was already consumed", in.Offset)
+					break
 				}
-				timer.Reset(period)
+				logger.Warningf("Continuing past the gap as Kafka.Consumer.AllowOffsetGaps is set")
+			}
+			consumed = in.Offset
+			b.metrics.consumed.Inc(1)
+			kind, msg, number, err := decodeEnvelope(in.Value)
+			if err != nil {
+				logger.Warningf("Skipping the message at offset %d: %s", in.Offset, err)
+				break
 			}
-		case <-timer.C:
-			if len(b.messages) > 0 {
-				if err := b.sendBlock(); err != nil {
-					panic(fmt.Errorf("Cannot communicate with Kafka broker: %s", err))
+
+			switch kind {
+			case envelopeRegular:
+				if configTx, isConfig, _ := configtx.UnmarshalConfigurationTransaction(msg.Data); isConfig {
+					// A malformed configuration transaction is isolated all the same, every orderer of the partition must cut alike
+					if batch := cutter.Cut(); len(batch) > 0 {
+						b.commit(batch, in.Offset)
+					}
+					block := b.commit([]*ab.BroadcastMessage{msg}, in.Offset+1)
+					stopTimer()
+					pending = false
+					drainPosted = false
+					b.applyConfig(configTx, block, in.Offset)
+					params = b.batchParameters()
+					cutter = blockcutter.NewReceiver(params.Size, params.MaxBytes)
+					break
+				}
+				if maxBytes := params.MaxBytes; maxBytes > 0 && len(msg.Data) > maxBytes {
+					// The message was accepted by the brokers, so it can only be ordered, in a block of its own
+					logger.Warningf("The message at offset %d holds %d bytes, beyond BatchMaxBytes of %d, cutting it into a block of its own", in.Offset, len(msg.Data), maxBytes)
+				}
+				var batches [][]*ab.BroadcastMessage
+				batches, pending = cutter.Ordered(msg)
+				for i, batch := range batches {
+					// Only the last batch may hold the message, and only if it did not begin a new batch
+					next := in.Offset
+					if i == len(batches)-1 && !pending {
+						next = in.Offset + 1
+					}
+					b.commit(batch, next)
+				}
+				if len(batches) > 0 {
+					stopTimer()
+					drainPosted = false
+				}
+				if pending && timer == nil {
+					timer = b.clock.NewTimer(batchTimeout(params.Timeout, latency))
 				}
+			case envelopeTimeToCut:
+				b.metrics.timeToCutConsumed.Inc(1)
+				if number == timeToCutNumber {
+					// The first time-to-cut message for the block, possibly posted by another orderer
+					roundTrip := b.clock.Now().Sub(timeToCutPosted)
+					b.metrics.timeToCutRoundTrip.Update(roundTrip)
+					latency += (roundTrip - latency) / 4
+					timeToCutNumber = 0
+				}
+				if next := b.ledger.Height(); number != next {
+					logger.Debugf("Ignoring the time-to-cut message for block %d at offset %d, the next block is %d", number, in.Offset, next)
+					break
+				}
+				stopTimer()
+				pending = false
+				drainPosted = false
+				if batch := cutter.Cut(); len(batch) > 0 {
+					b.commit(batch, in.Offset+1)
+				}
+			case envelopeConnect:
+				logger.Debugf("Ignoring the connect message at offset %d", in.Offset)
 			}
+		case <-timeout:
+			timer = nil
+			number := b.ledger.Height()
+			logger.Debugf("Batch timeout expired, posting a time-to-cut message for block %d", number)
+			if err := postTimeToCut(number); err != nil {
+				b.fail(err)
+				return
+			}
+		case drainTarget = <-b.drainChan:
+			logger.Debugf("Draining the messages up to offset %d before closing", drainTarget)
+			draining = true
+		case <-b.haltChan:
+			return
 		}
-	}
-}
 
-func (b *broadcasterImpl) recvRequests(stream ab.AtomicBroadcast_BroadcastServer) error {
-	reply := new(ab.BroadcastResponse)
-	for {
-		msg, err := stream.Recv()
-		if err != nil {
-			logger.Debug("Can no longer receive requests from client (exited?)")
-			return err
+		if !draining || consumed < drainTarget {
+			continue
+		}
+		if !pending {
+			return
 		}
+		if !drainPosted {
+			// The pending block is cut without waiting for the batch timeout
+			number := b.ledger.Height()
+			logger.Debugf("Posting a time-to-cut message for block %d before closing", number)
+			if err := postTimeToCut(number); err != nil {
+				b.fail(err)
+				return
+			}
+			drainPosted = true
+		}
+	}
+}
 
-		b.batchChan <- msg
-		reply.Status = ab.Status_SUCCESS // TODO This shouldn't always be a success
+// commit appends a block to the ledger and returns it, next is the offset at which consumption resumes once it is committed
+func (b *broadcasterImpl) commit(batch []*ab.BroadcastMessage, next int64) *ab.Block {
+	proof := encodeProof(b.config.Kafka.Topic, b.config.Kafka.PartitionID, next)
+	block := b.ledger.Append(batch, func(block *ab.Block) {
+		block.SetProof(proof)
+		b.tracker.Seal(block)
+		b.stamper.Stamp(block, b.clock.Now())
+		b.signer.Seal(block)
+	})
+	logger.Debugf("Cut block %d with %d messages", block.Header.Number, len(block.Data.Messages))
+	b.snapshotConfig(block, next)
+	return block
+}
 
-		if err := stream.Send(reply); err != nil {
-			logger.Info("Cannot send broadcast reply to client")
-			return err
-		}
-		logger.Debugf("Sent broadcast reply %v to client", reply.Status.String())
+// snapshotConfig commits a block restating the configuration once General.ConfigSnapshotInterval blocks have followed the last
+// configuration block, every orderer of the partition commits it after the same block, as it depends only on the blocks cut
+func (b *broadcasterImpl) snapshotConfig(block *ab.Block, next int64) {
+	interval := uint64(b.config.General.ConfigSnapshotInterval)
+	if b.chainConfig == nil || interval == 0 || block.Header.Number-b.tracker.Last() < interval {
+		return
+	}
+	snapshot := b.chainConfig.Snapshot()
+	data, err := configtx.MarshalConfigurationTransaction(snapshot)
+	if err != nil {
+		logger.Errorf("Could not marshal a snapshot of configuration sequence %d: %s", snapshot.Sequence, err)
+		return
 	}
+	logger.Infof("Committing a snapshot of configuration sequence %d after block %d", snapshot.Sequence, block.Header.Number)
+	b.commit([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: data}}, next)
 }
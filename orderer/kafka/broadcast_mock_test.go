@@ -17,19 +17,138 @@ limitations under the License.
 package kafka
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Shopify/sarama"
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
-func mockNewBroadcaster(t *testing.T, conf *config.TopLevel, seek int64, disk chan []byte) Broadcaster {
-	mb := &broadcasterImpl{
-		producer:   mockNewProducer(t, conf, seek, disk),
-		config:     conf,
-		batchChan:  make(chan *ab.BroadcastMessage, conf.General.BatchSize),
-		messages:   []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("checkpoint")}},
-		nextNumber: uint64(seek),
+var testGenesisBlock = ab.NewBlock(0, nil, []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("genesis")}}, nil)
+
+// mockPartition stands in for a partition shared by several orderers, every subscriber consumes each message posted to it
+type mockPartition struct {
+	lock        sync.Mutex
+	oldest      int64 // The offset of the first payload of log, those before it are no longer retained
+	log         [][]byte
+	subscribers []chan *sarama.ConsumerMessage
+}
+
+func newMockPartition() *mockPartition {
+	return &mockPartition{}
+}
+
+// Send appends the payload to the partition, so that the partition may be used as a Producer
+func (mp *mockPartition) Send(payload []byte) (int64, error) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	msg := &sarama.ConsumerMessage{Value: payload, Offset: mp.oldest + int64(len(mp.log))}
+	mp.log = append(mp.log, payload)
+	for _, subscriber := range mp.subscribers {
+		subscriber <- msg
+	}
+	return msg.Offset, nil
+}
+
+// Close is a no-op, the partition outlives the orderers using it
+func (mp *mockPartition) Close() error {
+	return nil
+}
+
+// posted returns the payloads which have been posted to the partition so far
+func (mp *mockPartition) posted() [][]byte {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return append([][]byte(nil), mp.log...)
+}
+
+// truncate discards the payloads before the given offset, as the brokers do once they pass the retention period
+func (mp *mockPartition) truncate(offset int64) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	if offset > mp.oldest+int64(len(mp.log)) {
+		offset = mp.oldest + int64(len(mp.log))
+	}
+	if offset > mp.oldest {
+		mp.log = mp.log[offset-mp.oldest:]
+		mp.oldest = offset
+	}
+}
+
+// subscribe returns a consumer of the partition from the oldest offset
+func (mp *mockPartition) subscribe() Consumer {
+	return mp.subscribeAt(sarama.OffsetOldest)
+}
+
+// subscribeAt returns a consumer of the partition from the given offset, which must still be retained
+func (mp *mockPartition) subscribeAt(seek int64) Consumer {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	if seek == sarama.OffsetOldest {
+		seek = mp.oldest
 	}
+	if seek < mp.oldest {
+		panic(fmt.Sprintf("Offset %d is no longer retained, the oldest is %d", seek, mp.oldest))
+	}
+	messages := make(chan *sarama.ConsumerMessage, 1000)
+	for i := seek - mp.oldest; i < int64(len(mp.log)); i++ {
+		messages <- &sarama.ConsumerMessage{Value: mp.log[i], Offset: mp.oldest + i}
+	}
+	mp.subscribers = append(mp.subscribers, messages)
+	return &mockPartitionConsumer{messages: messages}
+}
+
+type mockPartitionConsumer struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func (mpc *mockPartitionConsumer) Recv() <-chan *sarama.ConsumerMessage {
+	return mpc.messages
+}
+
+func (mpc *mockPartitionConsumer) Close() error {
+	return nil
+}
+
+// mockNewBroadcaster resumes consuming the partition after the last block of rl, as newBroadcaster does, and like it
+// returns the chain unstarted, so that the server sets its configuration before starting it
+func mockNewBroadcaster(t *testing.T, conf *config.TopLevel, mp *mockPartition, rl rawledger.ReadWriter, registry gometrics.Registry) *broadcasterImpl {
+	seek, err := resumeOffset(rl, conf.Kafka.Topic, conf.Kafka.PartitionID)
+	if err != nil {
+		t.Fatal("Cannot resume the chain:", err)
+	}
+	return newBroadcasterImpl(mp, mp.subscribeAt(seek), conf, rl, registry)
+}
+
+// startMockBroadcaster returns a started mockNewBroadcaster, for the tests which drive a chain without a server
+func startMockBroadcaster(t *testing.T, conf *config.TopLevel, mp *mockPartition, rl rawledger.ReadWriter, registry gometrics.Registry) *broadcasterImpl {
+	mb := mockNewBroadcaster(t, conf, mp, rl, registry)
+	mb.Start()
 	return mb
 }
+
+func mockNewLedger() rawledger.ReadWriter {
+	return ramledger.New(10, testGenesisBlock)
+}
+
+// waitForBlock returns the block with the given number once it has been cut
+func waitForBlock(t *testing.T, rl rawledger.Reader, number uint64) *ab.Block {
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, number)
+	select {
+	case <-it.ReadyChan():
+		block, status := it.Next()
+		if status != ab.Status_SUCCESS {
+			t.Fatalf("Failed to read block %d: %s", number, status)
+		}
+		return block
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("Block %d should have been cut by now", number)
+	}
+	return nil
+}
@@ -17,50 +17,33 @@ limitations under the License.
 package kafka
 
 import (
-	"bytes"
+	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Shopify/sarama"
 	"github.com/golang/protobuf/proto"
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
+	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
-func TestBroadcastInit(t *testing.T) {
-	disk := make(chan []byte)
-
-	mb := mockNewBroadcaster(t, testConf, oldestOffset, disk)
-	defer testClose(t, mb)
-
-	mbs := newMockBroadcastStream(t)
-	go func() {
-		if err := mb.Broadcast(mbs); err != nil {
-			t.Fatal("Broadcast error:", err)
-		}
-	}()
-
-	for {
-		select {
-		case in := <-disk:
-			block := new(ab.Block)
-			err := proto.Unmarshal(in, block)
-			if err != nil {
-				t.Fatal("Expected a block on the broker's disk")
-			}
-			if !(bytes.Equal(block.GetMessages()[0].Data, []byte("checkpoint"))) {
-				t.Fatal("Expected first block to be a checkpoint")
-			}
-			return
-		case <-time.After(500 * time.Millisecond):
-			t.Fatal("Should have received the initialization block by now")
-		}
-	}
+func testConfWithBatch(size uint, timeout time.Duration) *config.TopLevel {
+	conf := *testConf
+	conf.General.BatchSize = size
+	conf.General.BatchTimeout = timeout
+	return &conf
 }
 
 func TestBroadcastResponse(t *testing.T) {
-	disk := make(chan []byte)
-
-	mb := mockNewBroadcaster(t, testConf, oldestOffset, disk)
+	mb := startMockBroadcaster(t, testConf, newMockPartition(), mockNewLedger(), nil)
 	defer testClose(t, mb)
 
 	mbs := newMockBroadcastStream(t)
@@ -70,8 +53,6 @@ func TestBroadcastResponse(t *testing.T) {
 		}
 	}()
 
-	<-disk // We tested the checkpoint block in a previous test, so we can ignore it now
-
 	// Send a message to the orderer
 	go func() {
 		mbs.incoming <- &ab.BroadcastMessage{Data: []byte("single message")}
@@ -91,9 +72,8 @@ func TestBroadcastResponse(t *testing.T) {
 }
 
 func TestBroadcastBatch(t *testing.T) {
-	disk := make(chan []byte)
-
-	mb := mockNewBroadcaster(t, testConf, oldestOffset, disk)
+	rl := mockNewLedger()
+	mb := startMockBroadcaster(t, testConf, newMockPartition(), rl, nil)
 	defer testClose(t, mb)
 
 	mbs := newMockBroadcastStream(t)
@@ -103,8 +83,6 @@ func TestBroadcastBatch(t *testing.T) {
 		}
 	}()
 
-	<-disk // We tested the checkpoint block in a previous test, so we can ignore it now
-
 	// Pump a batch's worth of messages into the system
 	go func() {
 		for i := 0; i < int(testConf.General.BatchSize); i++ {
@@ -117,28 +95,15 @@ func TestBroadcastBatch(t *testing.T) {
 		<-mbs.outgoing
 	}
 
-	for {
-		select {
-		case in := <-disk:
-			block := new(ab.Block)
-			err := proto.Unmarshal(in, block)
-			if err != nil {
-				t.Fatal("Expected a block on the broker's disk")
-			}
-			if len(block.Messages) != int(testConf.General.BatchSize) {
-				t.Fatalf("Expected block to have %d messages instead of %d", testConf.General.BatchSize, len(block.Messages))
-			}
-			return
-		case <-time.After(500 * time.Millisecond):
-			t.Fatal("Should have received the initialization block by now")
-		}
+	block := waitForBlock(t, rl, 1)
+	if len(block.Data.Messages) != int(testConf.General.BatchSize) {
+		t.Fatalf("Expected block to have %d messages instead of %d", testConf.General.BatchSize, len(block.Data.Messages))
 	}
 }
 
 func TestBroadcastBatchAndQuitEarly(t *testing.T) {
-	disk := make(chan []byte)
-
-	mb := mockNewBroadcaster(t, testConf, oldestOffset, disk)
+	rl := mockNewLedger()
+	mb := startMockBroadcaster(t, testConf, newMockPartition(), rl, nil)
 	defer testClose(t, mb)
 
 	mbs := newMockBroadcastStream(t)
@@ -148,8 +113,6 @@ func TestBroadcastBatchAndQuitEarly(t *testing.T) {
 		}
 	}()
 
-	<-disk // We tested the checkpoint block in a previous test, so we can ignore it now
-
 	// Pump a batch's worth of messages into the system
 	go func() {
 		for i := 0; i < int(testConf.General.BatchSize); i++ {
@@ -164,28 +127,16 @@ func TestBroadcastBatchAndQuitEarly(t *testing.T) {
 	for !mbs.CloseOut() {
 	}
 
-	for {
-		select {
-		case in := <-disk:
-			block := new(ab.Block)
-			err := proto.Unmarshal(in, block)
-			if err != nil {
-				t.Fatal("Expected a block on the broker's disk")
-			}
-			if len(block.Messages) != int(testConf.General.BatchSize) {
-				t.Fatalf("Expected block to have %d messages instead of %d", testConf.General.BatchSize, len(block.Messages))
-			}
-			return
-		case <-time.After(500 * time.Millisecond):
-			t.Fatal("Should have received the initialization block by now")
-		}
+	block := waitForBlock(t, rl, 1)
+	if len(block.Data.Messages) != int(testConf.General.BatchSize) {
+		t.Fatalf("Expected block to have %d messages instead of %d", testConf.General.BatchSize, len(block.Data.Messages))
 	}
 }
 
 func TestBroadcastClose(t *testing.T) {
 	errChan := make(chan error)
 
-	mb := mockNewBroadcaster(t, testConf, oldestOffset, make(chan []byte))
+	mb := startMockBroadcaster(t, testConf, newMockPartition(), mockNewLedger(), nil)
 	mbs := newMockBroadcastStream(t)
 	go func() {
 		if err := mb.Broadcast(mbs); err != nil {
@@ -208,5 +159,572 @@ func TestBroadcastClose(t *testing.T) {
 			t.Fatal("Broadcaster should have closed its producer by now")
 		}
 	}
+}
+
+func TestBroadcastTimeToCutPosted(t *testing.T) {
+	mp := newMockPartition()
+	rl := mockNewLedger()
+	mb := startMockBroadcaster(t, testConfWithBatch(10, 50*time.Millisecond), mp, rl, nil)
+	defer testClose(t, mb)
+
+	if !mb.Enqueue(&ab.BroadcastMessage{Data: []byte("single message")}) {
+		t.Fatal("Should have posted the message")
+	}
+
+	block := waitForBlock(t, rl, 1)
+	if len(block.Data.Messages) != 1 || string(block.Data.Messages[0].Data) != "single message" {
+		t.Fatalf("Expected the pending message to be cut into block 1, got %v", block.Data.Messages)
+	}
+
+	posted := mp.posted()
+	if len(posted) != 2 {
+		t.Fatalf("Expected the message and a time-to-cut message on the partition, got %d messages", len(posted))
+	}
+	if kind, _, number, _ := decodeEnvelope(posted[1]); kind != envelopeTimeToCut || number != 1 {
+		t.Fatalf("Expected a time-to-cut message for block 1, got type %d for block %d", kind, number)
+	}
+}
+
+func TestBroadcastBatchTimeoutAccountsForFlush(t *testing.T) {
+	conf := testConfWithBatch(10, time.Second)
+	if timeout := batchTimeout(conf.General.BatchTimeout, conf.Kafka.Producer.Flush.Frequency); timeout != time.Second {
+		t.Fatalf("Expected the time-to-cut message to be posted after BatchTimeout without flush delay, got %v", timeout)
+	}
+	conf.Kafka.Producer.Flush.Frequency = 200 * time.Millisecond
+	if timeout := batchTimeout(conf.General.BatchTimeout, conf.Kafka.Producer.Flush.Frequency); timeout != 600*time.Millisecond {
+		t.Fatalf("Expected the flush delay of the message and the time-to-cut message to be taken from BatchTimeout, got %v", timeout)
+	}
+	conf.Kafka.Producer.Flush.Frequency = time.Second
+	if timeout := batchTimeout(conf.General.BatchTimeout, conf.Kafka.Producer.Flush.Frequency); timeout != 0 {
+		t.Fatalf("Expected the time-to-cut message to be posted at once when flushing takes longer than BatchTimeout, got %v", timeout)
+	}
+}
+
+func TestBroadcastTimeToCutStream(t *testing.T) {
+	rl := mockNewLedger()
+	mb := newBroadcasterImpl(newMockPartition(), nil, testConfWithBatch(3, time.Hour), rl, nil)
+	messages := make(chan *sarama.ConsumerMessage)
+	go mb.loop(messages)
+
+	regular := func(data string) []byte { return testEncodeRegular(t, data) }
+	stream := [][]byte{
+		regular("a"),
+		regular("b"),
+		encodeTimeToCut(1), // Cuts block 1
+		encodeTimeToCut(1), // Duplicate, posted by another orderer
+		regular("c"),
+		encodeTimeToCut(3), // Not the next block
+		encodeTimeToCut(1), // Stale
+		encodeTimeToCut(2), // Cuts block 2
+		regular("d"),
+		regular("e"),
+		regular("f"),       // Cut by size into block 3
+		encodeTimeToCut(3), // Stale, block 3 was cut by size
+		regular("g"),
+		encodeTimeToCut(4), // Cuts block 4
+		encodeTimeToCut(5), // Nothing is pending, so no block is cut
+		[]byte("garbage"),  // Skipped
+	}
+	for offset, payload := range stream {
+		messages <- &sarama.ConsumerMessage{Value: payload, Offset: int64(offset)}
+	}
+	// The unbuffered send returns once the loop has taken the last message, which it processes before halting
+	mb.Halt()
+	<-mb.exitChan
+
+	checkBlocks(t, rl, [][]string{{"a", "b"}, {"c"}, {"d", "e", "f"}, {"g"}})
+}
+
+func TestBroadcastCutByBytes(t *testing.T) {
+	rl := mockNewLedger()
+	conf := testConfWithBatch(10, time.Hour)
+	conf.General.BatchMaxBytes = 10
+	mb := newBroadcasterImpl(newMockPartition(), nil, conf, rl, nil)
+	messages := make(chan *sarama.ConsumerMessage)
+	go mb.loop(messages)
+
+	oversized := strings.Repeat("d", 12)
+	exact := strings.Repeat("g", 10)
+	regular := func(data string) []byte { return testEncodeRegular(t, data) }
+	stream := [][]byte{
+		regular("aaaa"),
+		regular("bbbb"),
+		regular("cccc"),    // Would take the batch to 12 bytes, so block 1 is cut without it
+		regular(oversized), // Beyond the limit alone, block 2 is cut and the message is cut into block 3 by itself
+		regular("ee"),
+		encodeTimeToCut(4), // Cuts block 4
+		regular(exact),     // Exactly at the limit, it remains pending
+		regular("h"),       // Cuts block 5
+		encodeTimeToCut(6), // Cuts block 6
+		regular(oversized), // Nothing is pending, cut into block 7 by itself
+	}
+	for offset, payload := range stream {
+		messages <- &sarama.ConsumerMessage{Value: payload, Offset: int64(offset)}
+	}
+	mb.Halt()
+	<-mb.exitChan
+
+	checkBlocks(t, rl, [][]string{{"aaaa", "bbbb"}, {"cccc"}, {oversized}, {"ee"}, {exact}, {"h"}, {oversized}})
+}
+
+func testEncodeRegular(t *testing.T, data string) []byte {
+	payload, err := encodeRegular(&ab.BroadcastMessage{Data: []byte(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+// checkBlocks fails unless the blocks after the genesis block hold exactly the expected message data
+func checkBlocks(t *testing.T, rl rawledger.Reader, expected [][]string) {
+	if rl.Height() != uint64(len(expected)+1) {
+		t.Fatalf("Expected %d blocks to be cut, got %d", len(expected), rl.Height()-1)
+	}
+	for i, contents := range expected {
+		block := waitForBlock(t, rl, uint64(i+1))
+		if len(block.Data.Messages) != len(contents) {
+			t.Fatalf("Expected block %d to hold %v, got %d messages", i+1, contents, len(block.Data.Messages))
+		}
+		for j, data := range contents {
+			if string(block.Data.Messages[j].Data) != data {
+				t.Fatalf("Expected block %d to hold %v, got %q at %d", i+1, contents, block.Data.Messages[j].Data, j)
+			}
+		}
+	}
+}
+
+func TestBroadcastRedundantOrderers(t *testing.T) {
+	mp := newMockPartition()
+	conf := testConfWithBatch(3, 20*time.Millisecond)
+	first, second := mockNewLedger(), mockNewLedger()
+	mb1 := startMockBroadcaster(t, conf, mp, first, nil)
+	defer testClose(t, mb1)
+	mb2 := startMockBroadcaster(t, conf, mp, second, nil)
+	defer testClose(t, mb2)
+
+	// Both orderers post a time-to-cut message for the trailing message, only the first consumed cuts a block
+	for i := 0; i < 7; i++ {
+		orderer := mb1
+		if i%2 == 1 {
+			orderer = mb2
+		}
+		if !orderer.Enqueue(&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("message %d", i))}) {
+			t.Fatal("Should have posted the message")
+		}
+	}
+
+	for number := uint64(1); number <= 3; number++ {
+		b1, b2 := waitForBlock(t, first, number), waitForBlock(t, second, number)
+		if b1.Header.Number != b2.Header.Number || string(b1.Header.PreviousHash) != string(b2.Header.PreviousHash) || len(b1.Data.Messages) != len(b2.Data.Messages) {
+			t.Fatalf("The orderers cut different blocks at %d", number)
+		}
+		for i := range b1.Data.Messages {
+			if string(b1.Data.Messages[i].Data) != string(b2.Data.Messages[i].Data) {
+				t.Fatalf("The orderers cut different blocks at %d", number)
+			}
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	for _, rl := range []rawledger.Reader{first, second} {
+		if rl.Height() != 4 {
+			t.Fatalf("Expected no empty blocks to be cut from the stale time-to-cut messages, height is %d", rl.Height())
+		}
+	}
+}
+
+func TestBroadcastCloseDrains(t *testing.T) {
+	mp := newMockPartition()
+	rl := mockNewLedger()
+	mb := startMockBroadcaster(t, testConfWithBatch(100, time.Hour), mp, rl, nil)
+
+	for i := 0; i < 5; i++ {
+		if !mb.Enqueue(&ab.BroadcastMessage{Data: []byte(strconv.Itoa(i))}) {
+			t.Fatal("Should have posted the message")
+		}
+	}
+	testClose(t, mb)
+
+	if rl.Height() != 2 {
+		t.Fatalf("Expected the pending messages to be cut into a final block on close, height is %d", rl.Height())
+	}
+	if block := waitForBlock(t, rl, 1); len(block.Data.Messages) != 5 {
+		t.Fatalf("Expected the final block to hold the 5 accepted messages, got %d", len(block.Data.Messages))
+	}
+	posted := mp.posted()
+	if kind, _, number, _ := decodeEnvelope(posted[len(posted)-1]); kind != envelopeTimeToCut || number != 1 {
+		t.Fatalf("Expected a time-to-cut message for block 1 to be posted on close, got type %d for block %d", kind, number)
+	}
+	if mb.Enqueue(&ab.BroadcastMessage{Data: []byte("late")}) {
+		t.Fatal("Should not accept messages once closed")
+	}
+}
 
+func TestBroadcastCloseTwice(t *testing.T) {
+	mb := startMockBroadcaster(t, testConf, newMockPartition(), mockNewLedger(), nil)
+	testClose(t, mb)
+	testClose(t, mb)
+
+	// A broadcaster whose loop was never started must close as well
+	mp := newMockPartition()
+	testClose(t, newBroadcasterImpl(mp, mp.subscribe(), testConf, mockNewLedger(), nil))
+}
+
+func TestBroadcastCloseTimeout(t *testing.T) {
+	conf := testConfWithBatch(100, time.Hour)
+	conf.Kafka.ShutdownTimeout = 100 * time.Millisecond
+
+	// The messages are posted to a partition which the broadcaster never consumes
+	mb := newBroadcasterImpl(newMockPartition(), newMockPartition().subscribe(), conf, mockNewLedger(), nil)
+	mb.Start()
+	if !mb.Enqueue(&ab.BroadcastMessage{Data: []byte("unconsumed")}) {
+		t.Fatal("Should have posted the message")
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- mb.Close()
+	}()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatal("Error when closing the broadcaster:", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Broadcaster should have given up draining by now")
+	}
+}
+
+func TestBroadcastRejectOversized(t *testing.T) {
+	conf := *testConf
+	conf.Kafka.Producer.MaxMessageBytes = 100
+	mp := newMockPartition()
+	mb := startMockBroadcaster(t, &conf, mp, mockNewLedger(), nil)
+	defer testClose(t, mb)
+
+	mbs := newMockBroadcastStream(t)
+	go mb.Broadcast(mbs)
+
+	limit := conf.Kafka.Producer.MaxMessageBytes - kafkaMessageOverhead - envelopeOverhead
+	for _, tc := range []struct {
+		data   []byte
+		status ab.Status
+	}{
+		{make([]byte, limit-2), ab.Status_SUCCESS}, // The tag and length of the data take the other two bytes
+		{make([]byte, limit-1), ab.Status_BAD_REQUEST},
+	} {
+		mbs.incoming <- &ab.BroadcastMessage{Data: tc.data}
+		select {
+		case reply := <-mbs.outgoing:
+			if reply.Status != tc.status {
+				t.Fatalf("Expected %v for a message of %d bytes, got %v", tc.status, len(tc.data), reply.Status)
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Should have received a broadcast reply by the orderer by now")
+		}
+	}
+	if posted := len(mp.posted()); posted != 1 {
+		t.Fatalf("Expected only the message within the limit to be posted, got %d messages", posted)
+	}
+}
+
+func TestBroadcastBrokerRefusesOversized(t *testing.T) {
+	mockBroker := startProduceBroker(t,
+		sarama.NewMockProduceResponse(t).SetError(testConf.Kafka.Topic, testConf.Kafka.PartitionID, sarama.ErrMessageSizeTooLarge),
+		sarama.NewMockProduceResponse(t))
+	defer mockBroker.Close()
+	conf := newFailingProducerConf(mockBroker)
+	producer := newProducer(conf, nil)
+	defer testClose(t, producer)
+
+	registry := gometrics.NewRegistry()
+	b := newBroadcasterImpl(producer, nil, conf, mockNewLedger(), registry)
+	if reply := b.EnqueueReply(&ab.BroadcastMessage{Data: []byte("refused")}); reply.Status != ab.Status_BAD_REQUEST || !strings.HasSuffix(reply.Info, "bytes exceeds the limit of the brokers") {
+		t.Fatalf("Expected a message refused by the brokers as too large to be rejected, got %v", reply)
+	}
+	if oversized := registry.Get("messages.oversized").(gometrics.Counter).Count(); oversized != 1 {
+		t.Fatalf("Expected the refused message to be counted, got %d", oversized)
+	}
+	select {
+	case <-b.Errored():
+		t.Fatal("A message refused as too large should not take the chain down")
+	default:
+	}
+	if reply := b.EnqueueReply(&ab.BroadcastMessage{Data: []byte("stored")}); reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the next message to be posted, got %v", reply)
+	}
+}
+
+func TestBroadcastHaltsOnOffsetGap(t *testing.T) {
+	testCases := []struct {
+		name    string
+		offsets []int64
+		allow   bool
+		blocks  [][]string
+		halted  bool
+	}{
+		{"contiguous", []int64{0, 1, 2}, false, [][]string{{"0"}, {"1"}, {"2"}}, false},
+		{"gap", []int64{0, 1, 3}, false, [][]string{{"0"}, {"1"}}, true},
+		{"regression", []int64{0, 1, 1}, false, [][]string{{"0"}, {"1"}}, true},
+		{"gap allowed", []int64{0, 1, 3}, true, [][]string{{"0"}, {"1"}, {"3"}}, false},
+		{"regression skipped", []int64{0, 1, 1, 2}, true, [][]string{{"0"}, {"1"}, {"2"}}, false},
+	}
+
+	for _, tc := range testCases {
+		conf := testConfWithBatch(1, time.Hour)
+		conf.Kafka.Consumer.AllowOffsetGaps = tc.allow
+		rl := mockNewLedger()
+		mb := newBroadcasterImpl(newMockPartition(), nil, conf, rl, nil)
+		messages := make(chan *sarama.ConsumerMessage, len(tc.offsets))
+		for _, offset := range tc.offsets {
+			messages <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, fmt.Sprintf("%d", offset)), Offset: offset}
+		}
+		go mb.loop(messages)
+
+		if tc.halted {
+			select {
+			case <-mb.Errored():
+			case <-time.After(time.Second):
+				t.Fatalf("%s: expected the chain to halt", tc.name)
+			}
+			<-mb.exitChan
+			if reply := mb.EnqueueReply(&ab.BroadcastMessage{Data: []byte("refused")}); reply.Status != ab.Status_SERVICE_UNAVAILABLE || reply.Info != "partition 0 has failed" {
+				t.Fatalf("%s: expected a halted chain to refuse messages, got %v", tc.name, reply)
+			}
+		} else {
+			for number := range tc.blocks {
+				waitForBlock(t, rl, uint64(number+1))
+			}
+			mb.Halt()
+			<-mb.exitChan
+		}
+		checkBlocks(t, rl, tc.blocks)
+	}
+}
+
+func TestBroadcastResumeChecksOffsets(t *testing.T) {
+	mp := newMockPartition()
+	rl := mockNewLedger()
+	conf := testConfWithBatch(1, time.Hour)
+	mb := startMockBroadcaster(t, conf, mp, rl, nil)
+	mb.Enqueue(&ab.BroadcastMessage{Data: []byte("a")})
+	waitForBlock(t, rl, 1)
+	testClose(t, mb)
+
+	// The resumed chain expects offset 1 first, the message at offset 1 is lost
+	mb = newBroadcasterImpl(mp, nil, conf, rl, nil)
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, "c"), Offset: 2}
+	go mb.loop(messages)
+	select {
+	case <-mb.Errored():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the resumed chain to halt on the gap after its last block")
+	}
+	checkBlocks(t, rl, [][]string{{"a"}})
+}
+
+// waitForTimer returns the deadline of the next timer created on fc
+func waitForTimer(t *testing.T, fc *clock.Fake) time.Time {
+	select {
+	case deadline := <-fc.Created():
+		return deadline
+	case <-time.After(time.Second):
+		t.Fatal("Expected a timer to be created")
+		return time.Time{}
+	}
+}
+
+// waitForPosted waits until count messages were posted to mp and returns them
+func waitForPosted(t *testing.T, mp *mockPartition, count int) [][]byte {
+	deadline := time.Now().Add(time.Second)
+	for {
+		posted := mp.posted()
+		if len(posted) >= count {
+			return posted
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d messages to be posted, got %d", count, len(posted))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBroadcastTimeToCutPerChain(t *testing.T) {
+	fc := clock.NewFake()
+	start := fc.Now()
+	conf := testConfWithBatch(10, time.Second)
+	chains := make([]*mockPartition, 2)
+	streams := make([]chan *sarama.ConsumerMessage, 2)
+	for i := range chains {
+		chains[i] = newMockPartition()
+		streams[i] = make(chan *sarama.ConsumerMessage)
+		mb := newBroadcasterImpl(chains[i], nil, conf, mockNewLedger(), nil)
+		mb.clock = fc
+		go mb.loop(streams[i])
+		defer mb.Halt()
+	}
+
+	// The second chain receives its first message half a batch timeout after the first chain
+	streams[0] <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, "a"), Offset: 0}
+	if deadline := waitForTimer(t, fc); deadline != start.Add(time.Second) {
+		t.Fatalf("Expected the timer of the first chain to expire at %v, got %v", start.Add(time.Second), deadline)
+	}
+	fc.Advance(500 * time.Millisecond)
+	streams[1] <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, "b"), Offset: 0}
+	if deadline := waitForTimer(t, fc); deadline != start.Add(1500*time.Millisecond) {
+		t.Fatalf("Expected the timer of the second chain to expire at %v, got %v", start.Add(1500*time.Millisecond), deadline)
+	}
+
+	fc.Advance(500 * time.Millisecond)
+	if kind, _, number, _ := decodeEnvelope(waitForPosted(t, chains[0], 1)[0]); kind != envelopeTimeToCut || number != 1 {
+		t.Fatalf("Expected the first chain to post a time-to-cut message for block 1, got type %d for block %d", kind, number)
+	}
+	if posted := chains[1].posted(); len(posted) != 0 {
+		t.Fatal("Expected the second chain to wait for its own batch timeout")
+	}
+
+	fc.Advance(500 * time.Millisecond)
+	waitForPosted(t, chains[1], 1)
+	if posted := chains[0].posted(); len(posted) != 1 {
+		t.Fatalf("Expected the first chain to post a single time-to-cut message, got %d", len(posted))
+	}
+}
+
+func TestBroadcastTimeToCutCorrectsLatency(t *testing.T) {
+	fc := clock.NewFake()
+	mp := newMockPartition()
+	rl := mockNewLedger()
+	mb := newBroadcasterImpl(mp, nil, testConfWithBatch(10, time.Second), rl, nil)
+	mb.clock = fc
+	messages := make(chan *sarama.ConsumerMessage)
+	go mb.loop(messages)
+	defer mb.Halt()
+
+	messages <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, "a"), Offset: 0}
+	waitForTimer(t, fc)
+	fc.Advance(time.Second)
+	waitForPosted(t, mp, 1)
+
+	// The time-to-cut message takes 200ms to come back, a quarter of which is taken into the estimated latency
+	fc.Advance(200 * time.Millisecond)
+	messages <- &sarama.ConsumerMessage{Value: encodeTimeToCut(1), Offset: 1}
+	waitForBlock(t, rl, 1)
+
+	posted := fc.Now()
+	messages <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, "b"), Offset: 2}
+	// The first message and the time-to-cut message are each expected to take the estimated 50ms
+	if deadline := waitForTimer(t, fc); deadline != posted.Add(900*time.Millisecond) {
+		t.Fatalf("Expected the timer to allow for the latency of the partition, expiring at %v, got %v", posted.Add(900*time.Millisecond), deadline)
+	}
+}
+
+func TestBroadcastStampsBlocks(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Advance(time.Hour)
+	rl := mockNewLedger()
+	mb := newBroadcasterImpl(newMockPartition(), nil, testConfWithBatch(1, time.Hour), rl, nil)
+	mb.clock = fc
+	messages := make(chan *sarama.ConsumerMessage)
+	go mb.loop(messages)
+	defer mb.Halt()
+
+	messages <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, "a"), Offset: 0}
+	if committed, ok := waitForBlock(t, rl, 1).Timestamp(); !ok || !committed.Equal(fc.Now()) {
+		t.Fatalf("Expected block 1 to be stamped with %v but got %v", fc.Now(), committed)
+	}
+
+	// A backwards step of the clock must not make the timestamps of the chain decrease
+	stamped := fc.Now()
+	fc.Advance(-time.Minute)
+	messages <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, "b"), Offset: 1}
+	if committed, ok := waitForBlock(t, rl, 2).Timestamp(); !ok || !committed.Equal(stamped) {
+		t.Fatalf("Expected block 2 to be stamped with the previous timestamp %v but got %v", stamped, committed)
+	}
+}
+
+// acceptAllPolicies authorizes every configuration change
+type acceptAllPolicies struct{}
+
+func (ap acceptAllPolicies) GetPolicy(id string) (policies.Policy, bool) {
+	return ap, true
+}
+
+func (ap acceptAllPolicies) Evaluate(msg []byte, sigs []*ab.SignedData) error {
+	return nil
+}
+
+func TestBroadcastBatchSizeFromCommittedConfig(t *testing.T) {
+	registry := configtx.NewRegistry()
+	if err := registry.Register(ab.Configuration_Orderer, sharedconfig.NewHandler(sharedconfig.Limits{})); err != nil {
+		t.Fatalf("Error registering handler: %s", err)
+	}
+	cm, err := registry.NewManager(&ab.ConfigurationEnvelope{Sequence: 0, ChainID: []byte("test")}, acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	data, _ := proto.Marshal(&ab.BatchSize{Messages: 2})
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: []byte("test"), ID: sharedconfig.BatchSizeKey, Type: ab.Configuration_Orderer, Data: data, LastModified: 1})
+	configTx, err := configtx.MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  []byte("test"),
+		Entries:  []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}},
+	})
+	if err != nil {
+		t.Fatalf("Error marshaling configuration: %s", err)
+	}
+
+	rl := mockNewLedger()
+	mb := newBroadcasterImpl(newMockPartition(), nil, testConfWithBatch(3, time.Hour), rl, nil)
+	mb.chainConfig = cm
+	messages := make(chan *sarama.ConsumerMessage)
+	go mb.loop(messages)
+	defer mb.Halt()
+
+	for i, data := range []string{"a", "b", string(configTx), "c", "d", "e", "f"} {
+		messages <- &sarama.ConsumerMessage{Value: testEncodeRegular(t, data), Offset: int64(i)}
+	}
+
+	// The configuration transaction cuts the batch ahead of it short, and the blocks which follow are cut at the size it commits
+	waitForBlocks(t, rl, [][]string{{"a", "b"}, {string(configTx)}, {"c", "d"}, {"e", "f"}})
+	if cm.Sequence() != 1 {
+		t.Fatalf("Expected the committed configuration to have been applied, but the sequence is %d", cm.Sequence())
+	}
+	if block := waitForBlock(t, rl, 2); configtx.BlockConfiguration(block) == nil {
+		t.Fatalf("Expected block 2 to carry the configuration transaction")
+	}
+}
+
+func TestBroadcastConfigSnapshot(t *testing.T) {
+	conf := testConfWithBatch(1, time.Hour)
+	conf.General.ConfigSnapshotInterval = 2
+	mp := newMockPartition()
+	ledgers := []rawledger.ReadWriter{mockNewLedger(), mockNewLedger()}
+	for _, rl := range ledgers {
+		cm, err := configtx.NewRegistry().NewManager(&ab.ConfigurationEnvelope{Sequence: 0, ChainID: []byte("test")}, acceptAllPolicies{})
+		if err != nil {
+			t.Fatalf("Error constructing configuration manager: %s", err)
+		}
+		mb := newBroadcasterImpl(mp, mp.subscribe(), conf, rl, nil)
+		mb.chainConfig = cm
+		mb.Start()
+		defer mb.Halt()
+	}
+
+	for _, data := range []string{"a", "b", "c"} {
+		mp.Send(testEncodeRegular(t, data))
+	}
+
+	// Every orderer of the partition commits the snapshot after the same block
+	for _, rl := range ledgers {
+		waitForBlock(t, rl, 1)
+		waitForBlock(t, rl, 2)
+		snapshot := waitForBlock(t, rl, 3)
+		if configTx := configtx.BlockConfiguration(snapshot); configTx == nil || configTx.Sequence != 0 {
+			t.Fatalf("Expected block 3 to be a snapshot of configuration sequence 0, got %v", configTx)
+		}
+		if block := waitForBlock(t, rl, 4); string(block.Data.Messages[0].Data) != "c" {
+			t.Fatalf("Expected the message after the snapshot in block 4, got %q", block.Data.Messages[0].Data)
+		}
+	}
 }
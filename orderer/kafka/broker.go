@@ -35,8 +35,12 @@ type brokerImpl struct {
 }
 
 func newBroker(conf *config.TopLevel) Broker {
-	broker := sarama.NewBroker(conf.Kafka.Brokers[0])
-	if err := broker.Open(nil); err != nil {
+	addrs, err := resolveBrokers(conf)
+	if err != nil {
+		panic(fmt.Errorf("Failed to create Kafka broker: %v", err))
+	}
+	broker := sarama.NewBroker(addrs[0])
+	if err := broker.Open(newBrokerConfig(conf)); err != nil {
 		panic(fmt.Errorf("Failed to create Kafka broker: %v", err))
 	}
 	return &brokerImpl{
@@ -0,0 +1,150 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+// The ways in which chains may be mapped onto the Kafka cluster
+const (
+	chainMappingPartition = "partition" // Every chain is ordered on its own partition of Kafka.Topic
+	chainMappingTopic     = "topic"     // Every chain is ordered on Kafka.PartitionID of its own topic
+)
+
+// chainConfig returns a copy of conf whose Kafka.Topic and Kafka.PartitionID name the partition on which the chain is ordered
+func chainConfig(conf *config.TopLevel, chainID, defaultChainID []byte) (*config.TopLevel, error) {
+	chainConf := *conf
+
+	switch conf.Kafka.ChainMapping {
+	case chainMappingTopic:
+		if !strings.Contains(conf.Kafka.TopicTemplate, "{chain}") {
+			return nil, fmt.Errorf("Kafka.TopicTemplate %s must contain {chain}, so that each chain has its own topic", conf.Kafka.TopicTemplate)
+		}
+		chainConf.Kafka.Topic = strings.NewReplacer("{network}", conf.General.NetworkID, "{chain}", hex.EncodeToString(chainID)).Replace(conf.Kafka.TopicTemplate)
+	case chainMappingPartition:
+		partitions, err := parseChainPartitions(conf.Kafka.ChainPartitions, conf.Kafka.PartitionID)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(chainID, defaultChainID) {
+			break
+		}
+		partition, ok := partitions[hex.EncodeToString(chainID)]
+		if !ok {
+			return nil, fmt.Errorf("Chain %x is not mapped to a partition of Kafka topic %s by Kafka.ChainPartitions", chainID, conf.Kafka.Topic)
+		}
+		chainConf.Kafka.PartitionID = partition
+	default:
+		return nil, fmt.Errorf("Unknown Kafka.ChainMapping %s, expected %s or %s", conf.Kafka.ChainMapping, chainMappingPartition, chainMappingTopic)
+	}
+
+	if err := checkTopicName(chainConf.Kafka.Topic); err != nil {
+		return nil, fmt.Errorf("Cannot order chain %x: %s", chainID, err)
+	}
+	return &chainConf, nil
+}
+
+// maxTopicNameLength is the longest topic name the brokers accept
+const maxTopicNameLength = 249
+
+var legalTopicName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// checkTopicName returns an error unless the brokers would accept name as the name of a topic
+func checkTopicName(name string) error {
+	switch {
+	case name == "." || name == "..":
+		return fmt.Errorf("Kafka topic name %s is reserved", name)
+	case len(name) > maxTopicNameLength:
+		return fmt.Errorf("Kafka topic name %s is longer than %d characters", name, maxTopicNameLength)
+	case !legalTopicName.MatchString(name):
+		return fmt.Errorf("Kafka topic name '%s' may only contain letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// parseChainPartitions parses the entries of Kafka.ChainPartitions, keyed by chain ID in lower case hex
+// A partition may order only one chain, and the default partition orders the default chain
+func parseChainPartitions(entries []string, defaultPartition int32) (map[string]int32, error) {
+	partitions := make(map[string]int32, len(entries))
+	owners := map[int32]string{defaultPartition: "the default chain"}
+
+	for _, entry := range entries {
+		fields := strings.Split(entry, ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Kafka.ChainPartitions entry %s is not of the form <chain ID in hex>:<partition>", entry)
+		}
+		chainID, err := hex.DecodeString(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("Kafka.ChainPartitions entry %s has a malformed chain ID: %s", entry, err)
+		}
+		partition, err := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 32)
+		if err != nil || partition < 0 {
+			return nil, fmt.Errorf("Kafka.ChainPartitions entry %s has a malformed partition", entry)
+		}
+
+		key := hex.EncodeToString(chainID)
+		if _, ok := partitions[key]; ok {
+			return nil, fmt.Errorf("Kafka.ChainPartitions maps chain %s more than once", key)
+		}
+		if owner, ok := owners[int32(partition)]; ok {
+			return nil, fmt.Errorf("Kafka.ChainPartitions maps chain %s to partition %d, which already orders %s", key, partition, owner)
+		}
+		partitions[key] = int32(partition)
+		owners[int32(partition)] = "chain " + key
+	}
+
+	return partitions, nil
+}
+
+// ParseStartOffsets parses entries of the form <chain ID in hex>:<offset>, separated by commas, keyed by chain ID in
+// lower case hex, for NewWithStartOffsets
+func ParseStartOffsets(spec string) (map[string]int64, error) {
+	offsets := make(map[string]int64)
+	if strings.TrimSpace(spec) == "" {
+		return offsets, nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		fields := strings.Split(entry, ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Start offset %s is not of the form <chain ID in hex>:<offset>", entry)
+		}
+		chainID, err := hex.DecodeString(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("Start offset %s has a malformed chain ID: %s", entry, err)
+		}
+		offset, err := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("Start offset %s has a malformed offset", entry)
+		}
+
+		key := hex.EncodeToString(chainID)
+		if _, ok := offsets[key]; ok {
+			return nil, fmt.Errorf("A start offset is given for chain %s more than once", key)
+		}
+		offsets[key] = offset
+	}
+	return offsets, nil
+}
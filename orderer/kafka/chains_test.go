@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChainConfigPartition(t *testing.T) {
+	conf := *testConf
+	conf.Kafka.ChainPartitions = []string{"0a0b:2", " 0c : 1 "}
+
+	for _, tc := range []struct {
+		chainID   []byte
+		partition int32
+	}{
+		{[]byte("default"), conf.Kafka.PartitionID},
+		{[]byte{0x0a, 0x0b}, 2},
+		{[]byte{0x0c}, 1},
+	} {
+		chainConf, err := chainConfig(&conf, tc.chainID, []byte("default"))
+		if err != nil {
+			t.Fatalf("Failed to map chain %x: %s", tc.chainID, err)
+		}
+		if chainConf.Kafka.Topic != conf.Kafka.Topic || chainConf.Kafka.PartitionID != tc.partition {
+			t.Fatalf("Expected chain %x on partition %d of %s, got %d of %s", tc.chainID, tc.partition, conf.Kafka.Topic, chainConf.Kafka.PartitionID, chainConf.Kafka.Topic)
+		}
+	}
+
+	if _, err := chainConfig(&conf, []byte{0x0d}, []byte("default")); err == nil {
+		t.Fatal("Expected an error mapping a chain which is not listed")
+	}
+	if conf.Kafka.PartitionID != testConf.Kafka.PartitionID {
+		t.Fatal("The config of a chain should be a copy")
+	}
+}
+
+func TestChainConfigTopic(t *testing.T) {
+	conf := *testConf
+	conf.Kafka.ChainMapping = chainMappingTopic
+
+	chainConf, err := chainConfig(&conf, []byte{0x0a, 0x0b}, []byte("default"))
+	if err != nil {
+		t.Fatal("Failed to map the chain:", err)
+	}
+	if chainConf.Kafka.Topic != "fabric-test-0a0b" || chainConf.Kafka.PartitionID != conf.Kafka.PartitionID {
+		t.Fatalf("Expected the chain on partition %d of fabric-test-0a0b, got %d of %s", conf.Kafka.PartitionID, chainConf.Kafka.PartitionID, chainConf.Kafka.Topic)
+	}
+
+	for template, expected := range map[string]string{
+		"{chain}":                     "0a0b",
+		"{network}.{chain}.{network}": "test.0a0b.test",
+		"orderer_{chain}":             "orderer_0a0b",
+	} {
+		conf.Kafka.TopicTemplate = template
+		if chainConf, err := chainConfig(&conf, []byte{0x0a, 0x0b}, []byte("default")); err != nil || chainConf.Kafka.Topic != expected {
+			t.Fatalf("Expected the topic template %s to expand to %s, got %v (%v)", template, expected, chainConf, err)
+		}
+	}
+}
+
+func TestChainConfigIllegalTopic(t *testing.T) {
+	conf := *testConf
+	conf.Kafka.ChainMapping = chainMappingTopic
+
+	for _, tc := range []struct {
+		template  string
+		networkID string
+	}{
+		{"fabric-{network}", "test"},                   // Every chain would share the topic
+		{"fabric-{network}-{chain}", "a network"},      // Spaces are illegal
+		{"fabric/{network}/{chain}", "test"},           // So are slashes
+		{"{network}{chain}", strings.Repeat("n", 250)}, // Too long
+		{"fabric-{network}-{chain}", "tést"},           // Only ASCII is legal
+	} {
+		conf.Kafka.TopicTemplate = tc.template
+		conf.General.NetworkID = tc.networkID
+		if chainConf, err := chainConfig(&conf, []byte{0x0a}, []byte("default")); err == nil {
+			t.Fatalf("Expected an error with the topic template %s and network ID %s, got topic %s", tc.template, tc.networkID, chainConf.Kafka.Topic)
+		}
+	}
+
+	partitionConf := *testConf
+	partitionConf.Kafka.Topic = ".."
+	if _, err := chainConfig(&partitionConf, []byte("default"), []byte("default")); err == nil {
+		t.Fatal("Expected an error with a reserved topic name")
+	}
+}
+
+func TestChainPartitionsMalformed(t *testing.T) {
+	for _, entries := range [][]string{
+		{"0a0b"},
+		{"0a0b:1:2"},
+		{"zz:1"},
+		{"0a0b:x"},
+		{"0a0b:-1"},
+		{"0a0b:0"}, // The partition of the default chain
+		{"0a0b:1", "0c:1"},
+		{"0a0b:1", "0A0B:2"},
+	} {
+		if _, err := parseChainPartitions(entries, 0); err == nil {
+			t.Fatalf("Expected an error parsing %v", entries)
+		}
+	}
+}
+
+func TestParseStartOffsets(t *testing.T) {
+	offsets, err := ParseStartOffsets(" 0A0B:5, 0c:0 ")
+	if err != nil {
+		t.Fatal("Error parsing the start offsets:", err)
+	}
+	if len(offsets) != 2 || offsets["0a0b"] != 5 || offsets["0c"] != 0 {
+		t.Fatalf("Expected the start offsets to be keyed by chain ID in lower case hex, got %v", offsets)
+	}
+	if offsets, err := ParseStartOffsets(""); err != nil || len(offsets) != 0 {
+		t.Fatalf("Expected no start offsets, got %v, %v", offsets, err)
+	}
+
+	for _, spec := range []string{"0a0b", "zz:1", "0a0b:x", "0a0b:-2", "0a0b:1,0A0B:2"} {
+		if _, err := ParseStartOffsets(spec); err == nil {
+			t.Fatalf("Expected an error parsing %q", spec)
+		}
+	}
+}
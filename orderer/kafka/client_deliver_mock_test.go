@@ -17,35 +17,42 @@ limitations under the License.
 package kafka
 
 import (
+	"sync"
 	"testing"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
 )
 
-type mockClientDelivererImpl struct {
-	clientDelivererImpl
-	t *testing.T
+var (
+	deliverLedger     rawledger.ReadWriter
+	deliverLedgerOnce sync.Once
+)
+
+// mockNewDeliverLedger returns a ledger which retains the blocks from oldestOffset up to, but not including, newestOffset
+func mockNewDeliverLedger() rawledger.Reader {
+	deliverLedgerOnce.Do(func() {
+		deliverLedger = ramledger.New(int(newestOffset-oldestOffset), testGenesisBlock)
+		for i := int64(1); i < newestOffset; i++ {
+			deliverLedger.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("message")}}, nil)
+		}
+	})
+	return deliverLedger
 }
 
+// mockNewClientDeliverer returns a deliverer which is closed once deadChan is
 func mockNewClientDeliverer(t *testing.T, conf *config.TopLevel, deadChan chan struct{}) Deliverer {
-	mockBrokerFunc := func(conf *config.TopLevel) Broker {
-		return mockNewBroker(t, conf)
-	}
-	mockConsumerFunc := func(conf *config.TopLevel, seek int64) (Consumer, error) {
-		return mockNewConsumer(t, conf, seek)
-	}
-
-	return &mockClientDelivererImpl{
-		clientDelivererImpl: clientDelivererImpl{
-			brokerFunc:   mockBrokerFunc,
-			consumerFunc: mockConsumerFunc,
-
-			config:   conf,
-			deadChan: deadChan,
-			errChan:  make(chan error),
-			updChan:  make(chan *ab.DeliverUpdate),
-		},
-		t: t,
-	}
+	d := newDeliverer(conf, mockDeliverLedgers, nil, nil)
+	go func() {
+		<-deadChan
+		d.Close()
+	}()
+	return d
+}
+
+// mockDeliverLedgers resolves every chain to the ledger of mockNewDeliverLedger
+func mockDeliverLedgers(chainID []byte) (rawledger.Reader, bool) {
+	return mockNewDeliverLedger(), true
 }
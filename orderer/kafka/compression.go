@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// The value of Kafka.Compression which needs brokers of 0.10 or later
+const compressionLZ4 = "lz4"
+
+// supportedCompressions are the codecs known to the Kafka client
+var supportedCompressions = map[string]sarama.CompressionCodec{
+	"none":   sarama.CompressionNone,
+	"gzip":   sarama.CompressionGZIP,
+	"snappy": sarama.CompressionSnappy,
+}
+
+// parseCompression returns the codec named by Kafka.Compression, if the brokers of the given version can decompress it
+func parseCompression(name string, version sarama.KafkaVersion) (sarama.CompressionCodec, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return sarama.CompressionNone, nil
+	}
+	if codec, ok := supportedCompressions[name]; ok {
+		return codec, nil
+	}
+	if name == compressionLZ4 {
+		if !version.IsAtLeast(sarama.V0_10_0_0) {
+			return sarama.CompressionNone, fmt.Errorf("Kafka.Compression %s needs Kafka.Version 0.10.0.0 or later", name)
+		}
+		return sarama.CompressionNone, fmt.Errorf("Kafka.Compression %s is not supported by the Kafka client, expected none, gzip, or snappy", name)
+	}
+	return sarama.CompressionNone, fmt.Errorf("Unknown Kafka.Compression %s, expected none, gzip, snappy, or lz4", name)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+func TestParseCompression(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		version sarama.KafkaVersion
+		codec   sarama.CompressionCodec
+		ok      bool
+	}{
+		{"", sarama.V0_9_0_1, sarama.CompressionNone, true},
+		{"none", sarama.V0_9_0_1, sarama.CompressionNone, true},
+		{"GZIP", sarama.V0_9_0_1, sarama.CompressionGZIP, true},
+		{"snappy", sarama.V0_8_2_0, sarama.CompressionSnappy, true},
+		{"lz4", sarama.V0_9_0_1, sarama.CompressionNone, false},
+		{"lz4", sarama.V0_10_0_0, sarama.CompressionNone, false},
+		{"zstd", sarama.V0_10_0_0, sarama.CompressionNone, false},
+	} {
+		codec, err := parseCompression(tc.name, tc.version)
+		if tc.ok && (err != nil || codec != tc.codec) {
+			t.Errorf("Expected %q to select codec %d, got %d, %v", tc.name, tc.codec, codec, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("Expected %q to be rejected with version %v", tc.name, tc.version)
+		}
+	}
+}
+
+// encodeMessageSet encodes payloads as the uncompressed message set wrapped by a compressed message
+func encodeMessageSet(offset int64, payloads ...[]byte) []byte {
+	var set []byte
+	for i, payload := range payloads {
+		// The magic byte and attributes are zero, and the key is null
+		body := make([]byte, 10+len(payload))
+		binary.BigEndian.PutUint32(body[2:], 0xffffffff)
+		binary.BigEndian.PutUint32(body[6:], uint32(len(payload)))
+		copy(body[10:], payload)
+
+		header := make([]byte, 16)
+		binary.BigEndian.PutUint64(header, uint64(offset+int64(i)))
+		binary.BigEndian.PutUint32(header[8:], uint32(4+len(body)))
+		binary.BigEndian.PutUint32(header[12:], crc32.ChecksumIEEE(body))
+		set = append(set, header...)
+		set = append(set, body...)
+	}
+	return set
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	for _, name := range []string{"none", "gzip", "snappy"} {
+		conf := *testConf
+		conf.Kafka.Compression = name
+		conf.Kafka.Retry = config.Retry{Period: time.Millisecond, Stop: time.Second}
+		codec, _ := parseCompression(name, sarama.V0_9_0_1)
+		if brokerConfig := newProducerConfig(&conf); brokerConfig.Producer.Compression != codec {
+			t.Fatalf("Expected the producer to compress with %s, got codec %d", name, brokerConfig.Producer.Compression)
+		}
+
+		// The mock broker decodes the produce request, decompressing its message set
+		produceBroker := startProduceBroker(t, sarama.NewMockProduceResponse(t))
+		conf.Kafka.Brokers = []string{produceBroker.Addr()}
+		producer := newProducer(&conf, nil)
+		if _, err := producer.Send([]byte("compressed")); err != nil {
+			t.Fatalf("Failed to produce with %s compression: %s", name, err)
+		}
+		producer.Close()
+		produceBroker.Close()
+
+		// The consumer is served a message set compressed with the codec
+		fetchBroker := sarama.NewMockBroker(t, brokerID)
+		metadata := &sarama.MetadataResponse{}
+		metadata.AddBroker(fetchBroker.Addr(), brokerID)
+		metadata.AddTopicPartition(conf.Kafka.Topic, conf.Kafka.PartitionID, brokerID, []int32{brokerID}, []int32{brokerID}, sarama.ErrNoError)
+		fetch := &sarama.FetchResponse{}
+		fetch.AddMessage(conf.Kafka.Topic, conf.Kafka.PartitionID, nil, nil, 0)
+		block := fetch.GetBlock(conf.Kafka.Topic, conf.Kafka.PartitionID)
+		block.HighWaterMarkOffset = 2
+		if codec == sarama.CompressionNone {
+			block.MsgSet.Messages = nil
+			fetch.AddMessage(conf.Kafka.Topic, conf.Kafka.PartitionID, nil, sarama.ByteEncoder("first"), 0)
+			fetch.AddMessage(conf.Kafka.Topic, conf.Kafka.PartitionID, nil, sarama.ByteEncoder("second"), 1)
+		} else {
+			block.MsgSet.Messages[0].Msg = &sarama.Message{Codec: codec, Value: encodeMessageSet(0, []byte("first"), []byte("second"))}
+		}
+		fetchBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockWrapper(metadata),
+			"OffsetRequest": sarama.NewMockOffsetResponse(t).
+				SetOffset(conf.Kafka.Topic, conf.Kafka.PartitionID, sarama.OffsetOldest, 0).
+				SetOffset(conf.Kafka.Topic, conf.Kafka.PartitionID, sarama.OffsetNewest, 2),
+			"FetchRequest": sarama.NewMockWrapper(fetch),
+		})
+		conf.Kafka.Brokers = []string{fetchBroker.Addr()}
+
+		consumer, err := newConsumer(&conf, sarama.OffsetOldest, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create the consumer with %s compression: %s", name, err)
+		}
+		for i, expected := range []string{"first", "second"} {
+			select {
+			case msg := <-consumer.Recv():
+				if string(msg.Value) != expected || msg.Offset != int64(i) {
+					t.Fatalf("Expected %q at offset %d with %s compression, got %q at %d", expected, i, name, msg.Value, msg.Offset)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Should have consumed the %s compressed messages by now", name)
+			}
+		}
+		consumer.Close()
+		fetchBroker.Close()
+	}
+}
@@ -19,7 +19,6 @@ package kafka
 import (
 	"time"
 
-	"github.com/Shopify/sarama"
 	"github.com/hyperledger/fabric/orderer/config"
 )
 
@@ -39,11 +38,38 @@ var testConf = &config.TopLevel{
 		MaxWindowSize: 100,
 		ListenAddress: "127.0.0.1",
 		ListenPort:    5151,
+		NetworkID:     "test",
+		Protocol: config.Protocol{
+			Features: []string{"CHAIN_ROUTING", "DELIVER_ACKS"},
+		},
 	},
 	Kafka: config.Kafka{
-		Brokers:     []string{"127.0.0.1:9092"},
-		Topic:       "test",
-		PartitionID: 0,
-		Version:     sarama.V0_9_0_1,
+		Brokers:                 []string{"127.0.0.1:9092"},
+		Topic:                   "test",
+		PartitionID:             0,
+		ChainMapping:            "partition",
+		TopicTemplate:           "fabric-{network}-{chain}",
+		Partitions:              1,
+		ReplicationFactor:       1,
+		MinInSyncReplicas:       1,
+		StartupTimeout:          time.Second,
+		ShutdownTimeout:         time.Second,
+		MetadataRefreshInterval: time.Minute,
+		Version:                 "0.9.0.1",
+		Compression:             "none",
+		Producer: config.Producer{
+			RequiredAcks:    "WaitForAll",
+			MaxMessageBytes: 1000000,
+			Retry: config.ProducerRetry{
+				Max:     3,
+				Backoff: 100 * time.Millisecond,
+			},
+		},
+		Consumer: config.Consumer{
+			Retry: config.ConsumerRetry{
+				Backoff:    time.Millisecond,
+				MaxBackoff: 10 * time.Millisecond,
+			},
+		},
 	},
 }
@@ -17,8 +17,13 @@ limitations under the License.
 package kafka
 
 import (
+	"io"
+	"net"
+	"sync"
+
 	"github.com/Shopify/sarama"
 	"github.com/hyperledger/fabric/orderer/config"
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
 // Consumer allows the caller to receive a stream of messages from the orderer
@@ -27,25 +32,113 @@ type Consumer interface {
 	Closeable
 }
 
+// The classes of the errors of a partition consumer, each counted by the metric consumer.errors.<class>
+const (
+	consumerErrorTransient        = "transient"           // The brokers can no longer be reached, the consumer is re-established
+	consumerErrorOffsetOutOfRange = "offset_out_of_range" // The partition no longer retains the offset, the consumer is re-established
+	consumerErrorUnknown          = "unknown"             // A fetch failed, the partition consumer retries by itself
+)
+
+var consumerErrorClasses = []string{consumerErrorTransient, consumerErrorOffsetOutOfRange, consumerErrorUnknown}
+
+// consumerErrorCounters counts the errors of the partition consumers of a chain by class
+type consumerErrorCounters map[string]gometrics.Counter
+
+// newConsumerErrorCounters registers the counters in registry, which may be nil if they are not to be exported
+func newConsumerErrorCounters(registry gometrics.Registry) consumerErrorCounters {
+	if registry == nil {
+		registry = gometrics.NewRegistry()
+	}
+	counters := make(consumerErrorCounters)
+	for _, class := range consumerErrorClasses {
+		counters[class] = gometrics.NewRegisteredCounter("consumer.errors."+class, registry)
+	}
+	return counters
+}
+
 type consumerImpl struct {
+	conf      *config.TopLevel
 	parent    sarama.Consumer
 	partition sarama.PartitionConsumer
+	errors    consumerErrorCounters
+	closeOnce sync.Once
+	doneChan  chan struct{} // Closed once the errors of the partition consumer have all been read
 }
 
-func newConsumer(conf *config.TopLevel, seek int64) (Consumer, error) {
-	parent, err := sarama.NewConsumer(conf.Kafka.Brokers, newBrokerConfig(conf))
+// newConsumer exports the metrics of the sarama client in registry, which may be nil if they are not to be exported,
+// and counts the errors of the partition consumer in errors, which may also be nil
+func newConsumer(conf *config.TopLevel, seek int64, registry gometrics.Registry, errors consumerErrorCounters) (Consumer, error) {
+	brokerConfig := newBrokerConfig(conf)
+	if registry != nil {
+		brokerConfig.MetricRegistry = registry
+	}
+	brokerConfig.Consumer.Return.Errors = true
+	addrs, err := resolveBrokers(conf)
 	if err != nil {
 		return nil, err
 	}
+	parent, err := sarama.NewConsumer(addrs, brokerConfig)
+	if err != nil {
+		// Creating the client fetches the cluster metadata, which failed from every broker
+		return nil, metadataError{err}
+	}
 	partition, err := parent.ConsumePartition(conf.Kafka.Topic, conf.Kafka.PartitionID, seek)
 	if err != nil {
+		parent.Close()
 		return nil, err
 	}
-	c := &consumerImpl{parent: parent, partition: partition}
+	if errors == nil {
+		errors = newConsumerErrorCounters(nil)
+	}
+	c := &consumerImpl{conf: conf, parent: parent, partition: partition, errors: errors, doneChan: make(chan struct{})}
+	go c.watch()
 	logger.Debug("Created new consumer for client beginning from block", seek)
 	return c, nil
 }
 
+// watch counts and logs the errors of the partition consumer, and shuts it down once its connection to the brokers
+// is lost or its offset is out of range, closing the channel returned by Recv so that the supervisor reconnects
+func (c *consumerImpl) watch() {
+	defer close(c.doneChan)
+	for err := range c.partition.Errors() {
+		class := classifyConsumerError(err.Err)
+		c.errors[class].Inc(1)
+		switch class {
+		case consumerErrorTransient:
+			logger.Warningf("Lost the connection consuming partition %d of topic %s: %s", err.Partition, err.Topic, err.Err)
+		case consumerErrorOffsetOutOfRange:
+			// Nothing can be cut past the missing messages, the retention of the topic may be shorter than an outage
+			logger.Errorf("Partition %d of topic %s no longer retains the messages the chain resumes from: %s", err.Partition, err.Topic, err.Err)
+		default:
+			logger.Warningf("Error consuming partition %d of topic %s: %s", err.Partition, err.Topic, err.Err)
+			continue
+		}
+		c.closeOnce.Do(c.partition.AsyncClose)
+	}
+}
+
+// classifyConsumerError returns the class of an error of a partition consumer
+func classifyConsumerError(err error) string {
+	switch {
+	case isConnectionError(err):
+		return consumerErrorTransient
+	case err == sarama.ErrOffsetOutOfRange:
+		return consumerErrorOffsetOutOfRange
+	default:
+		return consumerErrorUnknown
+	}
+}
+
+// isConnectionError returns true if err means the brokers can no longer be reached, rather than that a request failed
+func isConnectionError(err error) bool {
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF, sarama.ErrOutOfBrokers, sarama.ErrNotConnected, sarama.ErrClosedClient:
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
 // Recv returns a channel with messages received from the orderer
 func (c *consumerImpl) Recv() <-chan *sarama.ConsumerMessage {
 	return c.partition.Messages()
@@ -53,8 +146,12 @@ func (c *consumerImpl) Recv() <-chan *sarama.ConsumerMessage {
 
 // Close shuts down the partition consumer
 func (c *consumerImpl) Close() error {
-	if err := c.partition.Close(); err != nil {
-		return err
-	}
+	c.closeOnce.Do(c.partition.AsyncClose)
+	go func() {
+		for range c.partition.Messages() {
+			// Drained so that the partition consumer can shut down
+		}
+	}()
+	<-c.doneChan
 	return c.parent.Close()
 }
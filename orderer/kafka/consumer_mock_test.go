@@ -93,14 +93,11 @@ func (mc *mockConsumerImpl) testFillWithBlocks(seek int64) {
 }
 
 func testNewConsumerMessage(offset int64, topic string) *sarama.ConsumerMessage {
-	block := &ab.Block{
-		Messages: []*ab.BroadcastMessage{
-			&ab.BroadcastMessage{
-				Data: []byte(strconv.FormatInt(offset, 10)),
-			},
+	block := ab.NewBlock(uint64(offset), nil, []*ab.BroadcastMessage{
+		&ab.BroadcastMessage{
+			Data: []byte(strconv.FormatInt(offset, 10)),
 		},
-		Number: uint64(offset),
-	}
+	}, nil)
 	_, data := hashBlock(block)
 
 	return &sarama.ConsumerMessage{
@@ -16,7 +16,13 @@ limitations under the License.
 
 package kafka
 
-import "testing"
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
 
 func TestConsumerInitWrong(t *testing.T) {
 	cases := []int64{oldestOffset - 1, newestOffset}
@@ -54,3 +60,21 @@ func testConsumerRecvFunc(given, expected int64) func(t *testing.T) {
 		testClose(t, mc)
 	}
 }
+
+func TestClassifyConsumerError(t *testing.T) {
+	testCases := []struct {
+		err   error
+		class string
+	}{
+		{io.EOF, consumerErrorTransient},
+		{sarama.ErrOutOfBrokers, consumerErrorTransient},
+		{sarama.ErrOffsetOutOfRange, consumerErrorOffsetOutOfRange},
+		{sarama.ErrInvalidMessage, consumerErrorUnknown},
+		{errors.New("unexpected"), consumerErrorUnknown},
+	}
+	for _, tc := range testCases {
+		if class := classifyConsumerError(tc.err); class != tc.class {
+			t.Errorf("Expected %v to be classed as %s, got %s", tc.err, tc.class, class)
+		}
+	}
+}
@@ -17,9 +17,10 @@ limitations under the License.
 package kafka
 
 import (
-	"sync"
-
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/deliver"
+	"github.com/hyperledger/fabric/orderer/common/policies"
 	"github.com/hyperledger/fabric/orderer/config"
 )
 
@@ -30,35 +31,38 @@ type Deliverer interface {
 }
 
 type delivererImpl struct {
-	config   *config.TopLevel
-	deadChan chan struct{}
-	wg       sync.WaitGroup
+	server *deliver.Server
 }
 
-func newDeliverer(conf *config.TopLevel) Deliverer {
-	return &delivererImpl{
-		config:   conf,
-		deadChan: make(chan struct{}),
-	}
+// newDeliverer serves the Deliver streams from the ledgers of the chains through the handler shared with the solo orderer,
+// seeks must satisfy the Readers policy of the chain of chainPolicies unless it is nil, and its decisions are recorded to decisions
+func newDeliverer(conf *config.TopLevel, ledgers deliver.LedgerResolver, chainPolicies policies.ManagerResolver, decisions *audit.DecisionLog) Deliverer {
+	return &delivererImpl{server: deliver.NewServer(ledgers, deliver.Options{
+		MaxWindowSize:       int(conf.General.MaxWindowSize),
+		DefaultWindowSize:   int(conf.General.Deliver.DefaultWindowSize),
+		MaxIdleTime:         conf.General.Deliver.MaxIdleTime,
+		MaxLag:              int(conf.General.Deliver.MaxLag),
+		HeartbeatInterval:   conf.General.Deliver.HeartbeatInterval,
+		CursorKey:           []byte(conf.General.Deliver.CursorKey),
+		MaxSendMsgSize:      int(conf.General.MaxSendMsgSize),
+		Features:            conf.General.Protocol.Enabled(),
+		MaxStreams:          int(conf.General.Deliver.MaxGlobalStreams),
+		MaxStreamsPerClient: int(conf.General.Deliver.MaxStreamsPerClient),
+		RetryAfter:          conf.General.Deliver.RetryAfter,
+		Policies:            chainPolicies,
+		Decisions:           decisions,
+		SeekSkew:            conf.General.Deliver.SeekSkew,
+	})}
 }
 
 // Deliver receives updates from connected clients and adjusts
 // the transmission of ordered messages to them accordingly
 func (d *delivererImpl) Deliver(stream ab.AtomicBroadcast_DeliverServer) error {
-	cd := newClientDeliverer(d.config, d.deadChan)
-
-	d.wg.Add(1)
-	defer d.wg.Done()
-
-	defer cd.Close()
-	return cd.Deliver(stream)
+	return d.server.Handle(stream)
 }
 
 // Close shuts down the delivery side of the orderer
 func (d *delivererImpl) Close() error {
-	close(d.deadChan)
-	// Wait till all the client-deliverer consumers have closed
-	// Note that their recvReplies goroutines keep on going
-	d.wg.Wait()
+	d.server.Shutdown()
 	return nil
 }
@@ -19,32 +19,9 @@ package kafka
 import (
 	"testing"
 
-	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/config"
 )
 
-type mockDelivererImpl struct {
-	delivererImpl
-	t *testing.T
-}
-
 func mockNewDeliverer(t *testing.T, conf *config.TopLevel) Deliverer {
-	md := &mockDelivererImpl{
-		delivererImpl: delivererImpl{
-			config:   conf,
-			deadChan: make(chan struct{}),
-		},
-		t: t,
-	}
-	return md
-}
-
-func (md *mockDelivererImpl) Deliver(stream ab.AtomicBroadcast_DeliverServer) error {
-	mcd := mockNewClientDeliverer(md.t, md.config, md.deadChan)
-
-	md.wg.Add(1)
-	defer md.wg.Done()
-
-	defer mcd.Close()
-	return mcd.Deliver(stream)
+	return newDeliverer(conf, mockDeliverLedgers, nil, nil)
 }
@@ -19,6 +19,8 @@ package kafka
 import (
 	"testing"
 	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 )
 
 func TestDeliverMultipleClients(t *testing.T) {
@@ -79,6 +81,15 @@ func TestDeliverClose(t *testing.T) {
 		errChan <- md.Close()
 	}()
 
+	select {
+	case reply := <-mds.outgoing:
+		if reply.GetError() != ab.Status_SERVICE_UNAVAILABLE {
+			t.Fatalf("Expected the stream to end with SERVICE_UNAVAILABLE, got %v", reply)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Client deliverer should have sent a terminal status by now")
+	}
+
 	for {
 		select {
 		case err := <-errChan:
@@ -92,3 +103,126 @@ func TestDeliverClose(t *testing.T) {
 	}
 
 }
+
+// countDelivered returns the number of blocks sent on mds until it has been idle for a while
+func countDelivered(mds *mockDeliverStream) int {
+	count := 0
+	for {
+		select {
+		case reply := <-mds.outgoing:
+			if reply.GetBlock() != nil {
+				count++
+			}
+		case <-time.After(200 * time.Millisecond):
+			return count
+		}
+	}
+}
+
+func TestDeliverWindowUpdate(t *testing.T) {
+	md := mockNewDeliverer(t, testConf)
+	defer testClose(t, md)
+	mds := newMockDeliverStream(t)
+	go md.Deliver(mds)
+
+	mds.incoming <- testNewSeekMessage("oldest", 0, 2)
+	if count := countDelivered(mds); count != 2 {
+		t.Fatalf("Expected a window of 2 blocks to be delivered, got %d", count)
+	}
+
+	mds.incoming <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_WindowUpdate{WindowUpdate: &ab.WindowUpdate{WindowSize: 5}}}
+	if count := countDelivered(mds); count != 3 {
+		t.Fatalf("Expected growing the window to 5 to deliver 3 more blocks, got %d", count)
+	}
+
+	// Shrunk below the 5 blocks outstanding, only an acknowledgement of 4 of them makes room for another
+	mds.incoming <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_WindowUpdate{WindowUpdate: &ab.WindowUpdate{WindowSize: 2}}}
+	mds.incoming <- testNewAckMessage(uint64(oldestOffset + 2))
+	if count := countDelivered(mds); count != 0 {
+		t.Fatalf("Expected no blocks while the window is full, got %d", count)
+	}
+	mds.incoming <- testNewAckMessage(uint64(oldestOffset + 3))
+	if count := countDelivered(mds); count != 1 {
+		t.Fatalf("Expected 1 block once the window had room, got %d", count)
+	}
+}
+
+func TestDeliverCompatWindow(t *testing.T) {
+	conf := *testConf
+	conf.General.Deliver.DefaultWindowSize = 3
+	md := mockNewDeliverer(t, &conf)
+	defer testClose(t, md)
+	mds := newMockDeliverStream(t)
+	go md.Deliver(mds)
+
+	// A client which sends no window is never waited on for an acknowledgement
+	mds.incoming <- testNewSeekMessage("specific", uint64(newestOffset-10), 0)
+	if count := countDelivered(mds); count != 10 {
+		t.Fatalf("Expected every block from the seek to be delivered without acknowledgement, got %d", count)
+	}
+
+}
+
+func expectHelloReply(t *testing.T, mds *mockDeliverStream, features ...ab.Feature) {
+	select {
+	case reply := <-mds.outgoing:
+		if reply.GetHello() == nil || ab.NewFeatures(reply.GetHello().Features...) != ab.NewFeatures(features...) {
+			t.Fatalf("Expected the features %v to be selected, got %v", features, reply)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the reply to the hello")
+	}
+}
+
+func TestDeliverLegacyClient(t *testing.T) {
+	md := mockNewDeliverer(t, testConf)
+	defer testClose(t, md)
+	mds := newMockDeliverStream(t)
+	mds.hello = nil
+	go md.Deliver(mds)
+
+	// A client which sends no hello is never waited on for an acknowledgement
+	mds.incoming <- testNewSeekMessage("specific", uint64(newestOffset-10), 2)
+	if count := countDelivered(mds); count != 10 {
+		t.Fatalf("Expected every block from the seek to be delivered to a legacy client, got %d", count)
+	}
+}
+
+func TestDeliverHelloNegotiation(t *testing.T) {
+	conf := *testConf
+	conf.General.Protocol.Features = []string{"CHAIN_ROUTING", "DELIVER_ACKS", "FILTERED_DELIVERY"}
+	md := mockNewDeliverer(t, &conf)
+	defer testClose(t, md)
+	mds := newMockDeliverStream(t)
+	mds.hello = nil
+	go md.Deliver(mds)
+
+	mds.incoming <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.AllFeatures.Hello()}}
+	expectHelloReply(t, mds, ab.Feature_CHAIN_ROUTING, ab.Feature_DELIVER_ACKS, ab.Feature_FILTERED_DELIVERY)
+	mds.incoming <- testNewSeekMessage("specific", uint64(newestOffset-10), 2)
+	if count := countDelivered(mds); count != 2 {
+		t.Fatalf("Expected the window to be enforced once negotiated, got %d blocks", count)
+	}
+
+	mds.incoming <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.AllFeatures.Hello()}}
+	if reply := <-mds.outgoing; reply.GetError() != ab.Status_BAD_REQUEST {
+		t.Fatalf("Expected a second hello to be refused, got %v", reply)
+	}
+}
+
+func TestDeliverHelloFeaturesDisabled(t *testing.T) {
+	conf := *testConf
+	conf.General.Protocol.Features = nil
+	md := mockNewDeliverer(t, &conf)
+	defer testClose(t, md)
+	mds := newMockDeliverStream(t)
+	mds.hello = nil
+	go md.Deliver(mds)
+
+	mds.incoming <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.AllFeatures.Hello()}}
+	expectHelloReply(t, mds)
+	mds.incoming <- testNewSeekMessage("specific", uint64(newestOffset-10), 2)
+	if count := countDelivered(mds); count != 10 {
+		t.Fatalf("Expected every block to be delivered when acknowledgements are disabled, got %d", count)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+// Each message on the partition is a version byte followed by a marshaled KafkaMessage
+// A marshaled BroadcastMessage cannot begin with a byte below 8, as no field is numbered 0, so the versions below 8
+// are told apart from the bare BroadcastMessages posted before the messages were versioned
+const (
+	envelopeVersion byte = 2
+	framedVersion   byte = 1 // Followed by the type and the payload, posted by orderers predating KafkaMessage
+	maxVersion      byte = 7
+)
+
+// The types of message on the partition
+const (
+	envelopeRegular   = ab.KafkaMessage_REGULAR
+	envelopeTimeToCut = ab.KafkaMessage_TIME_TO_CUT
+	envelopeConnect   = ab.KafkaMessage_CONNECT
+)
+
+// envelopeOverhead bounds the bytes the envelope adds to a regular message, the version, type, and the tag and length of the payload
+const envelopeOverhead = 1 + 2 + 1 + binary.MaxVarintLen32
+
+func encodeEnvelope(kind ab.KafkaMessage_Type, payload []byte) []byte {
+	data, err := proto.Marshal(&ab.KafkaMessage{Type: kind, Payload: payload})
+	if err != nil {
+		panic(err)
+	}
+	return append([]byte{envelopeVersion}, data...)
+}
+
+func encodeRegular(msg *ab.BroadcastMessage) ([]byte, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return encodeEnvelope(envelopeRegular, data), nil
+}
+
+func encodeTimeToCut(blockNumber uint64) []byte {
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint64(payload, blockNumber)
+	return encodeEnvelope(envelopeTimeToCut, payload)
+}
+
+// decodeEnvelope returns the type of a message from the partition, along with either the message to order or the block number to cut
+// Messages of a version newer than this orderer knows are returned as an error, to be skipped
+func decodeEnvelope(data []byte) (ab.KafkaMessage_Type, *ab.BroadcastMessage, uint64, error) {
+	if len(data) == 0 || data[0] > maxVersion {
+		// A bare BroadcastMessage, an empty one marshals to no bytes at all
+		msg := &ab.BroadcastMessage{}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return 0, nil, 0, err
+		}
+		return envelopeRegular, msg, 0, nil
+	}
+
+	var kind ab.KafkaMessage_Type
+	var payload []byte
+	switch data[0] {
+	case envelopeVersion:
+		envelope := &ab.KafkaMessage{}
+		if err := proto.Unmarshal(data[1:], envelope); err != nil {
+			return 0, nil, 0, err
+		}
+		kind, payload = envelope.Type, envelope.Payload
+	case framedVersion:
+		if len(data) < 2 {
+			return 0, nil, 0, fmt.Errorf("Message of %d bytes is too short", len(data))
+		}
+		kind, payload = ab.KafkaMessage_Type(data[1]), data[2:]
+	default:
+		return 0, nil, 0, fmt.Errorf("Unknown message version %d, it was posted by a newer orderer", data[0])
+	}
+
+	switch kind {
+	case envelopeRegular:
+		msg := &ab.BroadcastMessage{}
+		if err := proto.Unmarshal(payload, msg); err != nil {
+			return 0, nil, 0, err
+		}
+		return envelopeRegular, msg, 0, nil
+	case envelopeTimeToCut:
+		if len(payload) != 8 {
+			return 0, nil, 0, fmt.Errorf("Time-to-cut message with a payload of %d bytes is malformed", len(payload))
+		}
+		return envelopeTimeToCut, nil, binary.BigEndian.Uint64(payload), nil
+	case envelopeConnect:
+		return envelopeConnect, nil, 0, nil
+	default:
+		return 0, nil, 0, fmt.Errorf("Unknown message type %d", kind)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/protobuf/proto"
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	data, err := encodeRegular(&ab.BroadcastMessage{Data: []byte("message")})
+	if err != nil {
+		t.Fatal("Failed to encode the message:", err)
+	}
+	kind, msg, _, err := decodeEnvelope(data)
+	if err != nil || kind != envelopeRegular || string(msg.Data) != "message" {
+		t.Fatalf("Expected the regular message back, got type %d, %v, %v", kind, msg, err)
+	}
+
+	kind, _, number, err := decodeEnvelope(encodeTimeToCut(42))
+	if err != nil || kind != envelopeTimeToCut || number != 42 {
+		t.Fatalf("Expected a time-to-cut message for block 42, got type %d for block %d, %v", kind, number, err)
+	}
+
+	if kind, _, _, err = decodeEnvelope(encodeEnvelope(envelopeConnect, nil)); err != nil || kind != envelopeConnect {
+		t.Fatalf("Expected a connect message, got type %d, %v", kind, err)
+	}
+}
+
+func TestEnvelopeLegacy(t *testing.T) {
+	for _, msg := range []*ab.BroadcastMessage{{Data: []byte("bare")}, {ChainID: []byte("chain")}, {}} {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatal("Failed to marshal the message:", err)
+		}
+		kind, decoded, _, err := decodeEnvelope(data)
+		if err != nil || kind != envelopeRegular || !proto.Equal(decoded, msg) {
+			t.Fatalf("Expected the bare message %v back as a regular message, got type %d, %v, %v", msg, kind, decoded, err)
+		}
+	}
+
+	kind, msg, _, err := decodeEnvelope(append([]byte{framedVersion, byte(envelopeRegular)}, []byte("\x0a\x06framed")...))
+	if err != nil || kind != envelopeRegular || string(msg.Data) != "framed" {
+		t.Fatalf("Expected the framed message back, got type %d, %v, %v", kind, msg, err)
+	}
+	kind, _, number, err := decodeEnvelope([]byte{framedVersion, byte(envelopeTimeToCut), 0, 0, 0, 0, 0, 0, 0, 7})
+	if err != nil || kind != envelopeTimeToCut || number != 7 {
+		t.Fatalf("Expected the framed time-to-cut message for block 7, got type %d for block %d, %v", kind, number, err)
+	}
+}
+
+func TestEnvelopeMalformed(t *testing.T) {
+	for _, data := range [][]byte{
+		{envelopeVersion, 0xff},
+		{envelopeVersion + 1, 0x08, 0x00},
+		{maxVersion},
+		{framedVersion},
+		{framedVersion, 7},
+		{framedVersion, byte(envelopeTimeToCut), 1, 2},
+		{framedVersion, byte(envelopeRegular), 0xff},
+		encodeEnvelope(envelopeTimeToCut, []byte{1, 2}),
+		encodeEnvelope(ab.KafkaMessage_Type(7), nil),
+		{0xff},
+	} {
+		if _, _, _, err := decodeEnvelope(data); err == nil {
+			t.Fatalf("Expected an error decoding %v", data)
+		}
+	}
+}
+
+func TestBroadcastSkipsUnknownVersion(t *testing.T) {
+	rl := mockNewLedger()
+	mb := newBroadcasterImpl(newMockPartition(), nil, testConfWithBatch(2, time.Hour), rl, nil)
+	messages := make(chan *sarama.ConsumerMessage)
+	go mb.loop(messages)
+
+	legacy, _ := proto.Marshal(&ab.BroadcastMessage{Data: []byte("b")})
+	stream := [][]byte{
+		testEncodeRegular(t, "a"),
+		{envelopeVersion + 1, 0x08, 0x00},    // Posted by a newer orderer, skipped
+		encodeEnvelope(envelopeConnect, nil), // Ignored
+		legacy,                               // Cut with the first message by size
+	}
+	for offset, payload := range stream {
+		messages <- &sarama.ConsumerMessage{Value: payload, Offset: int64(offset)}
+	}
+	mb.Halt()
+	<-mb.exitChan
+
+	checkBlocks(t, rl, [][]string{{"a", "b"}})
+}
@@ -0,0 +1,339 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/protobuf/proto"
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/kafka/mocks"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+var testChainID = []byte("default")
+
+// newTestConsenter starts an orderer of the default chain against broker, each tweak adjusting its config first
+func newTestConsenter(t *testing.T, broker *mocks.Broker, tweaks ...func(*config.TopLevel)) (Orderer, rawledger.ReadWriter) {
+	conf := mocks.NewTestConfig(broker)
+	for _, tweak := range tweaks {
+		tweak(conf)
+	}
+	lf := ramledger.NewFactory(10)
+	rl := lf.GetOrCreate(testChainID, testGenesisBlock)
+	return New(conf, lf, testChainID, nil), rl
+}
+
+func withBatchSize(size uint) func(*config.TopLevel) {
+	return func(conf *config.TopLevel) { conf.General.BatchSize = size }
+}
+
+// broadcastOne sends a message on a new Broadcast stream of o and returns the status of the reply
+func broadcastOne(t *testing.T, o Orderer, data string) ab.Status {
+	mbs := newMockBroadcastStream(t)
+	go o.Broadcast(mbs)
+	mbs.incoming <- &ab.BroadcastMessage{Data: []byte(data)}
+	select {
+	case reply := <-mbs.outgoing:
+		return reply.Status
+	case <-time.After(5 * time.Second):
+		t.Fatal("Should have received a broadcast reply by now")
+		return ab.Status_SUCCESS
+	}
+}
+
+func waitForBlocks(t *testing.T, rl rawledger.Reader, expected [][]string) {
+	for number := range expected {
+		waitForBlock(t, rl, uint64(number+1))
+	}
+	checkBlocks(t, rl, expected)
+}
+
+func TestHarnessConsume(t *testing.T) {
+	legacy, _ := proto.Marshal(&ab.BroadcastMessage{Data: []byte("legacy")})
+	regular := func(data string) []byte { return testEncodeRegular(t, data) }
+
+	testCases := []struct {
+		name    string
+		size    uint
+		stream  [][]byte
+		expects [][]string
+	}{
+		{"cut by size", 2, [][]byte{regular("a"), regular("b"), regular("c"), regular("d")}, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"cut by time-to-cut", 10, [][]byte{regular("a"), encodeTimeToCut(1), regular("b"), encodeTimeToCut(1), encodeTimeToCut(2)}, [][]string{{"a"}, {"b"}}},
+		{"legacy and unknown versions", 2, [][]byte{legacy, {envelopeVersion + 1}, encodeEnvelope(envelopeConnect, nil), regular("b")}, [][]string{{"legacy", "b"}}},
+	}
+
+	for _, tc := range testCases {
+		broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+		broker.Seed(0, tc.stream...)
+		o, rl := newTestConsenter(t, broker, withBatchSize(tc.size))
+		waitForBlocks(t, rl, tc.expects)
+		if err := o.Teardown(); err != nil {
+			t.Fatalf("%s: error tearing down the orderer: %s", tc.name, err)
+		}
+		broker.Close()
+	}
+}
+
+func TestHarnessProduce(t *testing.T) {
+	testCases := []struct {
+		name       string
+		produceErr sarama.KError
+		status     ab.Status
+		produced   int
+	}{
+		{"stored", sarama.ErrNoError, ab.Status_SUCCESS, 1},
+		{"refused as too large", sarama.ErrMessageSizeTooLarge, ab.Status_BAD_REQUEST, 0},
+		{"too few in-sync replicas", sarama.ErrNotEnoughReplicas, ab.Status_SERVICE_UNAVAILABLE, 0},
+		{"broker failing", sarama.ErrRequestTimedOut, ab.Status_SERVICE_UNAVAILABLE, 0},
+	}
+
+	for _, tc := range testCases {
+		broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+		o, _ := newTestConsenter(t, broker)
+		broker.SetProduceError(tc.produceErr)
+		if status := broadcastOne(t, o, tc.name); status != tc.status {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.status, status)
+		}
+		produced := broker.Produced(0)
+		if len(produced) != tc.produced {
+			t.Fatalf("%s: expected %d messages to be stored, got %d", tc.name, tc.produced, len(produced))
+		}
+		if tc.produced > 0 {
+			if kind, msg, _, err := decodeEnvelope(produced[0]); err != nil || kind != envelopeRegular || string(msg.Data) != tc.name {
+				t.Fatalf("%s: expected the broadcast message to be stored, got type %d, %v, %v", tc.name, kind, msg, err)
+			}
+		}
+		o.Teardown()
+		broker.Close()
+	}
+}
+
+func TestHarnessTimeToCut(t *testing.T) {
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	o, rl := newTestConsenter(t, broker)
+	defer o.Teardown()
+
+	if status := broadcastOne(t, o, "a"); status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message to be accepted, got %v", status)
+	}
+	broker.Forward(0)
+
+	// Once the batch timeout expires the orderer posts a time-to-cut message, which cuts the block once stored
+	produced := broker.WaitProduced(0, 2, 5*time.Second)
+	if len(produced) != 2 {
+		t.Fatalf("Expected a time-to-cut message to be posted, got %d messages", len(produced))
+	}
+	if kind, _, number, _ := decodeEnvelope(produced[1]); kind != envelopeTimeToCut || number != 1 {
+		t.Fatalf("Expected a time-to-cut message for block 1, got type %d for block %d", kind, number)
+	}
+	broker.Forward(0)
+	waitForBlocks(t, rl, [][]string{{"a"}})
+}
+
+func TestHarnessReconnect(t *testing.T) {
+	regular := func(data string) []byte { return testEncodeRegular(t, data) }
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	broker.Seed(0, regular("a"), regular("b"))
+	o, rl := newTestConsenter(t, broker, withBatchSize(2))
+	defer o.Teardown()
+	waitForBlocks(t, rl, [][]string{{"a", "b"}})
+
+	broker.Stop()
+	time.Sleep(50 * time.Millisecond)
+	broker.Seed(0, regular("c"), regular("d"))
+	broker.Start()
+	waitForBlocks(t, rl, [][]string{{"a", "b"}, {"c", "d"}})
+
+	// Messages broadcast after the restart are stored
+	if status := broadcastOne(t, o, "e"); status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message to be accepted after the restart, got %v", status)
+	}
+	produced := broker.WaitProduced(0, 1, 5*time.Second)
+	if len(produced) != 1 {
+		t.Fatalf("Expected the message to be stored after the restart, got %d messages", len(produced))
+	}
+	if _, msg, _, _ := decodeEnvelope(produced[0]); msg == nil || string(msg.Data) != "e" {
+		t.Fatalf("Expected the message broadcast after the restart to be stored, got %v", msg)
+	}
+}
+
+func TestHarnessNotEnoughReplicas(t *testing.T) {
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	o, _ := newTestConsenter(t, broker)
+	defer o.Teardown()
+
+	broker.SetProduceError(sarama.ErrNotEnoughReplicas)
+	if status := broadcastOne(t, o, "a"); status != ab.Status_SERVICE_UNAVAILABLE {
+		t.Fatalf("Expected the message to be refused as unavailable, got %v", status)
+	}
+	// NewTestConfig allows one retry
+	if attempts := broker.ProduceAttempts(0); attempts != 2 {
+		t.Fatalf("Expected the message to be sent and retried once, got %d attempts", attempts)
+	}
+
+	// The chain has not failed, once the replicas are back in sync messages are stored again
+	broker.SetProduceError(sarama.ErrNoError)
+	if status := broadcastOne(t, o, "b"); status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message to be accepted once the replicas are in sync, got %v", status)
+	}
+	if produced := broker.Produced(0); len(produced) != 1 {
+		t.Fatalf("Expected only the second message to be stored, got %d messages", len(produced))
+	}
+}
+
+func TestHarnessConsumerErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fetchErr sarama.KError
+		class    string
+	}{
+		{"offset out of range", sarama.ErrOffsetOutOfRange, consumerErrorOffsetOutOfRange},
+		{"fetch failing", sarama.ErrInvalidMessage, consumerErrorUnknown},
+	}
+
+	for _, tc := range testCases {
+		broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+		registry := gometrics.NewRegistry()
+		lf := ramledger.NewFactory(10)
+		lf.GetOrCreate(testChainID, testGenesisBlock)
+		o := New(mocks.NewTestConfig(broker), lf, testChainID, registry)
+
+		broker.SetFetchError(tc.fetchErr)
+		name := fmt.Sprintf("%x.consumer.errors.%s", testChainID, tc.class)
+		deadline := time.Now().Add(5 * time.Second)
+		for registry.Get(name).(gometrics.Counter).Count() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: expected the error to be counted as %s", tc.name, tc.class)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		o.Teardown()
+		broker.Close()
+	}
+}
+
+func TestHarnessConsumerOutage(t *testing.T) {
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	registry := gometrics.NewRegistry()
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(testChainID, testGenesisBlock)
+	conf := mocks.NewTestConfig(broker)
+	// The consumer stays down for the duration of the test once the broker is stopped
+	conf.Kafka.Consumer.Retry.Backoff = time.Hour
+	o := New(conf, lf, testChainID, registry)
+	defer o.Teardown()
+
+	reconnecting := make(chan struct{})
+	var once sync.Once
+	o.WatchConnectivity(func(c Connectivity) {
+		if c == Reconnecting {
+			once.Do(func() { close(reconnecting) })
+		}
+	})
+	broker.Stop()
+	select {
+	case <-reconnecting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the consumer to be re-established once the broker stopped")
+	}
+	name := fmt.Sprintf("%x.consumer.errors.%s", testChainID, consumerErrorTransient)
+	if count := registry.Get(name).(gometrics.Counter).Count(); count == 0 {
+		t.Fatal("Expected the lost connection to be counted as transient")
+	}
+	broker.Start()
+
+	// The producer could store the message, but it cannot be cut into a block until the consumer is re-established
+	if status := broadcastOne(t, o, "a"); status != ab.Status_SERVICE_UNAVAILABLE {
+		t.Fatalf("Expected the message to be refused during the outage, got %v", status)
+	}
+	if attempts := broker.ProduceAttempts(0); attempts != 0 {
+		t.Fatalf("Expected nothing to be sent to the brokers during the outage, got %d attempts", attempts)
+	}
+}
+
+func TestHarnessRebuildFromStartOffset(t *testing.T) {
+	regular := func(data string) []byte { return testEncodeRegular(t, data) }
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	broker.Seed(0, regular("a"), regular("b"), regular("c"), regular("d"))
+	o, original := newTestConsenter(t, broker, withBatchSize(2))
+	waitForBlocks(t, original, [][]string{{"a", "b"}, {"c", "d"}})
+	o.Teardown()
+
+	key := fmt.Sprintf("%x", testChainID)
+	// The lost ledger is rebuilt block for block from the retained messages
+	lf := ramledger.NewFactory(10)
+	rl := lf.GetOrCreate(testChainID, testGenesisBlock)
+	conf := mocks.NewTestConfig(broker)
+	conf.General.BatchSize = 2
+	rebuilt := NewWithStartOffsets(conf, lf, testChainID, nil, map[string]int64{key: 0}, nil, nil, nil, nil)
+	waitForBlocks(t, rl, [][]string{{"a", "b"}, {"c", "d"}})
+	rebuilt.Teardown()
+	// The timestamps record when each ledger committed the block, so they are not compared
+	for number := uint64(1); number < original.Height(); number++ {
+		if expected, block := waitForBlock(t, original, number), waitForBlock(t, rl, number); !proto.Equal(expected.Header, block.Header) || !proto.Equal(expected.Data, block.Data) || !bytes.Equal(expected.Proof(), block.Proof()) {
+			t.Fatalf("Expected block %d to be rebuilt as %v, got %v", number, expected, block)
+		}
+	}
+
+	// Blocks rebuilt into a ledger which has blocks are numbered after them
+	lf = ramledger.NewFactory(10)
+	rl = lf.GetOrCreate(testChainID, testGenesisBlock)
+	rl.Append([]*ab.BroadcastMessage{{Data: []byte("kept")}}, nil)
+	conf = mocks.NewTestConfig(broker)
+	conf.General.BatchSize = 2
+	rebuilt = NewWithStartOffsets(conf, lf, testChainID, nil, map[string]int64{key: 2}, nil, nil, nil, nil)
+	waitForBlock(t, rl, 2)
+	rebuilt.Teardown()
+	checkBlocks(t, rl, [][]string{{"kept"}, {"c", "d"}})
+}
+
+func TestHarnessStartOffsetOutOfRange(t *testing.T) {
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	broker.Seed(0, testEncodeRegular(t, "a"))
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(testChainID, testGenesisBlock)
+
+	for _, offsets := range []map[string]int64{
+		{fmt.Sprintf("%x", testChainID): 2},
+		{"0a0b": 0}, // Not a chain of the ledger
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected the start offsets %v to be refused", offsets)
+				}
+			}()
+			NewWithStartOffsets(mocks.NewTestConfig(broker), lf, testChainID, nil, offsets, nil, nil, nil, nil)
+		}()
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/orderer/common/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthService is the name under which the orderer reports its link to the Kafka brokers to the health service
+const HealthService = "orderer.Consenter"
+
+// Connectivity is the state of the link between the orderer, or one of its chains, and the Kafka brokers
+type Connectivity int
+
+const (
+	// Connected means that every chain is consuming its partition
+	Connected Connectivity = iota
+	// Reconnecting means that a chain lost its partition consumer and is re-establishing it
+	Reconnecting
+	// Failed means that a chain can no longer write to or consume from its partition
+	Failed
+)
+
+func (c Connectivity) String() string {
+	switch c {
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	default:
+		return "failed"
+	}
+}
+
+// ReportHealth keeps the status of HealthService in hs in step with the connectivity of o, SERVING only while connected
+// Deliver is served from the ledger and so keeps working while disconnected, but nothing broadcast can be ordered
+func ReportHealth(o Orderer, hs *health.Server) {
+	o.WatchConnectivity(func(c Connectivity) {
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if c == Connected {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+		hs.SetServingStatus(HealthService, status)
+	})
+}
+
+// connectivityTracker aggregates the connectivity of the chains, the worst of them being that of the orderer
+type connectivityTracker struct {
+	lock     sync.Mutex
+	chains   map[string]Connectivity
+	state    Connectivity
+	watchers []func(Connectivity)
+}
+
+func newConnectivityTracker() *connectivityTracker {
+	return &connectivityTracker{chains: make(map[string]Connectivity)}
+}
+
+// set records the connectivity of a chain, a chain which failed does not recover
+func (ct *connectivityTracker) set(chainID string, c Connectivity) {
+	ct.lock.Lock()
+	defer ct.lock.Unlock()
+
+	if ct.chains[chainID] == Failed {
+		return
+	}
+	ct.chains[chainID] = c
+
+	state := Connected
+	for _, c := range ct.chains {
+		if c > state {
+			state = c
+		}
+	}
+	if state == ct.state {
+		return
+	}
+	logger.Infof("The orderer is now %s to the Kafka brokers", state)
+	ct.state = state
+	for _, f := range ct.watchers {
+		f(state)
+	}
+}
+
+// watch calls f with the connectivity of the orderer now and whenever it changes, f is called in order and must not block
+func (ct *connectivityTracker) watch(f func(Connectivity)) {
+	ct.lock.Lock()
+	defer ct.lock.Unlock()
+	ct.watchers = append(ct.watchers, f)
+	f(ct.state)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/health"
+	"github.com/hyperledger/fabric/orderer/kafka/mocks"
+	"golang.org/x/net/context"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestConnectivityTracker(t *testing.T) {
+	ct := newConnectivityTracker()
+	var seen []Connectivity
+	ct.watch(func(c Connectivity) { seen = append(seen, c) })
+
+	ct.set("a", Connected)
+	ct.set("b", Reconnecting)
+	ct.set("a", Failed)
+	ct.set("b", Connected)
+	ct.set("a", Connected)
+
+	expected := []Connectivity{Connected, Reconnecting, Failed}
+	if len(seen) != len(expected) {
+		t.Fatalf("Expected the transitions %v, got %v", expected, seen)
+	}
+	for i := range expected {
+		if seen[i] != expected[i] {
+			t.Fatalf("Expected the transitions %v, got %v", expected, seen)
+		}
+	}
+}
+
+// waitForStatus polls hs until service reports the expected status
+func waitForStatus(t *testing.T, hs *health.Server, service string, expected healthpb.HealthCheckResponse_ServingStatus) {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Error checking the health of %q: %s", service, err)
+		}
+		if resp.Status == expected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %q to be %v, got %v", service, expected, resp.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReportHealth(t *testing.T) {
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	o, _ := newTestConsenter(t, broker)
+	defer o.Teardown()
+
+	hs := health.NewServer()
+	ReportHealth(o, hs)
+	hs.Started()
+	waitForStatus(t, hs, HealthService, healthpb.HealthCheckResponse_SERVING)
+	waitForStatus(t, hs, "", healthpb.HealthCheckResponse_SERVING)
+
+	broker.Stop()
+	waitForStatus(t, hs, HealthService, healthpb.HealthCheckResponse_NOT_SERVING)
+	waitForStatus(t, hs, "", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	broker.Start()
+	waitForStatus(t, hs, HealthService, healthpb.HealthCheckResponse_SERVING)
+	waitForStatus(t, hs, "", healthpb.HealthCheckResponse_SERVING)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// trackedRegistry registers metrics in a parent registry under a prefix, remembering their names, so that the metrics
+// of an orderer, and of the sarama clients it creates, can be unregistered on teardown without touching those of others
+type trackedRegistry struct {
+	parent  gometrics.Registry
+	prefix  string
+	tracker *registryTracker
+}
+
+type registryTracker struct {
+	lock  sync.Mutex
+	names map[string]struct{} // The names registered in the parent, with their prefix
+}
+
+func newTrackedRegistry(parent gometrics.Registry) *trackedRegistry {
+	return &trackedRegistry{parent: parent, tracker: &registryTracker{names: make(map[string]struct{})}}
+}
+
+// child returns a registry whose metrics are named <prefix><name> in r, and are unregistered along with those of r
+func (r *trackedRegistry) child(prefix string) *trackedRegistry {
+	return &trackedRegistry{parent: r.parent, prefix: r.prefix + prefix, tracker: r.tracker}
+}
+
+func (r *trackedRegistry) track(name string) {
+	r.tracker.lock.Lock()
+	defer r.tracker.lock.Unlock()
+	r.tracker.names[r.prefix+name] = struct{}{}
+}
+
+// Each calls f for each metric registered through r or its children under the prefix of r
+func (r *trackedRegistry) Each(f func(string, interface{})) {
+	r.tracker.lock.Lock()
+	var names []string
+	for name := range r.tracker.names {
+		if strings.HasPrefix(name, r.prefix) {
+			names = append(names, name)
+		}
+	}
+	r.tracker.lock.Unlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		if metric := r.parent.Get(name); metric != nil {
+			f(strings.TrimPrefix(name, r.prefix), metric)
+		}
+	}
+}
+
+func (r *trackedRegistry) Get(name string) interface{} {
+	return r.parent.Get(r.prefix + name)
+}
+
+func (r *trackedRegistry) GetOrRegister(name string, metric interface{}) interface{} {
+	r.track(name)
+	return r.parent.GetOrRegister(r.prefix+name, metric)
+}
+
+func (r *trackedRegistry) Register(name string, metric interface{}) error {
+	if err := r.parent.Register(r.prefix+name, metric); err != nil {
+		return err
+	}
+	r.track(name)
+	return nil
+}
+
+func (r *trackedRegistry) RunHealthchecks() {
+	r.parent.RunHealthchecks()
+}
+
+func (r *trackedRegistry) Unregister(name string) {
+	r.tracker.lock.Lock()
+	delete(r.tracker.names, r.prefix+name)
+	r.tracker.lock.Unlock()
+	r.parent.Unregister(r.prefix + name)
+}
+
+// UnregisterAll unregisters the metrics registered through r or its children under the prefix of r, and no others
+func (r *trackedRegistry) UnregisterAll() {
+	r.tracker.lock.Lock()
+	defer r.tracker.lock.Unlock()
+	for name := range r.tracker.names {
+		if strings.HasPrefix(name, r.prefix) {
+			r.parent.Unregister(name)
+			delete(r.tracker.names, name)
+		}
+	}
+}
+
+// chainMetrics are the metrics of the messages a chain posts to and consumes from its partition
+type chainMetrics struct {
+	produced           gometrics.Counter
+	consumed           gometrics.Counter
+	timeToCutPosted    gometrics.Counter
+	timeToCutConsumed  gometrics.Counter // Including those which are stale
+	timeToCutRoundTrip gometrics.Timer   // From posting a time-to-cut message to consuming the first one for its block
+	oversized          gometrics.Counter // Messages within the limit of the orderer which the brokers refused as too large
+	underReplicated    gometrics.Counter // Messages refused because too few replicas of the partition were in sync
+}
+
+// newChainMetrics registers the metrics of a chain in registry, which may be nil if they are not to be exported
+func newChainMetrics(registry gometrics.Registry) *chainMetrics {
+	if registry == nil {
+		registry = gometrics.NewRegistry()
+	}
+	return &chainMetrics{
+		produced:           gometrics.NewRegisteredCounter("messages.produced", registry),
+		consumed:           gometrics.NewRegisteredCounter("messages.consumed", registry),
+		timeToCutPosted:    gometrics.NewRegisteredCounter("time_to_cut.posted", registry),
+		timeToCutConsumed:  gometrics.NewRegisteredCounter("time_to_cut.consumed", registry),
+		timeToCutRoundTrip: gometrics.NewRegisteredTimer("time_to_cut.round_trip", registry),
+		oversized:          gometrics.NewRegisteredCounter("messages.oversized", registry),
+		underReplicated:    gometrics.NewRegisteredCounter("messages.under_replicated", registry),
+	}
+}
+
+// childRegistry returns a registry whose metrics are named <prefix><name> in registry, or nil if registry is nil
+func childRegistry(registry gometrics.Registry, prefix string) gometrics.Registry {
+	switch r := registry.(type) {
+	case nil:
+		return nil
+	case *trackedRegistry:
+		return r.child(prefix)
+	default:
+		return gometrics.NewPrefixedChildRegistry(registry, prefix)
+	}
+}
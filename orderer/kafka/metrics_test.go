@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/metrics"
+	"github.com/hyperledger/fabric/orderer/config"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+func TestTrackedRegistry(t *testing.T) {
+	parent := gometrics.NewRegistry()
+	other := gometrics.NewRegisteredCounter("other", parent)
+	tracked := newTrackedRegistry(parent)
+	gometrics.NewRegisteredCounter("foo", tracked.child("chain."))
+	gometrics.GetOrRegisterMeter("bar", tracked.child("chain.").child("sarama."))
+
+	var names []string
+	tracked.Each(func(name string, metric interface{}) { names = append(names, name) })
+	if len(names) != 2 || names[0] != "chain.foo" || names[1] != "chain.sarama.bar" {
+		t.Fatalf("Expected only the tracked metrics, got %v", names)
+	}
+	if parent.Get("chain.sarama.bar") == nil {
+		t.Fatal("Expected the metrics to be registered in the parent under their prefixes")
+	}
+
+	tracked.UnregisterAll()
+	if parent.Get("chain.foo") != nil || parent.Get("chain.sarama.bar") != nil {
+		t.Fatal("Expected the tracked metrics to be unregistered")
+	}
+	if parent.Get("other") != other {
+		t.Fatal("Metrics registered by others should not be unregistered")
+	}
+}
+
+func TestChainMetrics(t *testing.T) {
+	mockBroker := startProduceBroker(t, sarama.NewMockProduceResponse(t))
+	defer mockBroker.Close()
+	conf := *testConfWithBatch(10, 10*time.Millisecond)
+	conf.Kafka.Brokers = []string{mockBroker.Addr()}
+	conf.Kafka.Retry = config.Retry{Period: time.Millisecond, Stop: time.Second}
+
+	registry := gometrics.NewRegistry()
+	tracked := newTrackedRegistry(metrics.NewSubsystemRegistry(registry, "kafka"))
+	chainRegistry := tracked.child("chain.")
+
+	// The messages are posted to the mock broker, while the messages consumed are posted to the mock partition
+	rl := mockNewLedger()
+	mp := newMockPartition()
+	b := newBroadcasterImpl(newProducer(&conf, chainRegistry.child("sarama.")), mp.subscribe(), &conf, rl, chainRegistry)
+	b.Start()
+	for _, data := range []string{"a", "b", "c"} {
+		if !b.Enqueue(&ab.BroadcastMessage{Data: []byte(data)}) {
+			t.Fatal("Expected the message to be posted")
+		}
+		mp.Send(testEncodeRegular(t, data))
+	}
+	// The batch timeout expires before block 1 is cut, so a time-to-cut message is posted to the mock broker
+	time.Sleep(50 * time.Millisecond)
+	mp.Send(encodeTimeToCut(1))
+	waitForBlock(t, rl, 1)
+	testClose(t, b)
+
+	scrape := func(name string) interface{} {
+		metric := registry.Get(metrics.Namespace + ".kafka.chain." + name)
+		if metric == nil {
+			t.Fatalf("Expected %s to be exported", name)
+		}
+		return metric
+	}
+	if count := scrape("messages.produced").(gometrics.Counter).Count(); count != 3 {
+		t.Fatalf("Expected 3 produced messages, got %d", count)
+	}
+	if count := scrape("messages.consumed").(gometrics.Counter).Count(); count != 4 {
+		t.Fatalf("Expected 4 consumed messages, got %d", count)
+	}
+	if count := scrape("time_to_cut.posted").(gometrics.Counter).Count(); count < 1 {
+		t.Fatalf("Expected a time-to-cut message to be posted, got %d", count)
+	}
+	if count := scrape("time_to_cut.consumed").(gometrics.Counter).Count(); count != 1 {
+		t.Fatalf("Expected 1 consumed time-to-cut message, got %d", count)
+	}
+	if count := scrape("sarama.request-rate").(gometrics.Meter).Count(); count < 4 {
+		t.Fatalf("Expected the requests of the sarama client to be counted, got %d", count)
+	}
+	if size := scrape("sarama.request-size").(gometrics.Histogram); size.Count() < 4 || size.Min() <= 0 {
+		t.Fatalf("Expected the sizes of the requests of the sarama client, got %d requests of at least %d bytes", size.Count(), size.Min())
+	}
+
+	tracked.UnregisterAll()
+	registry.Each(func(name string, metric interface{}) {
+		t.Fatalf("Expected every metric to be unregistered, %s remains", name)
+	})
+}
@@ -0,0 +1,303 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mocks provides a mock Kafka broker, so that the Kafka orderer can be tested without a cluster
+package mocks
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+// BrokerID is the ID the mock broker announces in its metadata
+const BrokerID = int32(0)
+
+// Broker is a mock Kafka broker leading every partition of one topic, it serves the messages seeded on each
+// partition and records the messages produced to it, which reach the partitions only once forwarded
+type Broker struct {
+	t          sarama.TestReporter
+	topic      string
+	partitions int32
+	addr       string
+
+	lock       sync.Mutex // Guards the fields below
+	mock       *sarama.MockBroker
+	logs       map[int32][][]byte // The messages of each partition, by offset
+	produced   map[int32][][]byte // The messages produced to each partition, in the order received
+	forwarded  map[int32]int      // How many of the produced messages of each partition were forwarded
+	attempts   map[int32]int      // How many messages were sent to each partition, including those refused
+	past       int                // The requests of brokers which were stopped, included in produced
+	produceErr sarama.KError
+	fetchErr   sarama.KError
+	version    sarama.KafkaVersion // The protocol version whose requests the broker answers
+}
+
+// NewBroker starts a mock broker for a topic of the given number of partitions, on a free local port
+func NewBroker(t sarama.TestReporter, topic string, partitions int32) *Broker {
+	b := &Broker{
+		t:          t,
+		topic:      topic,
+		partitions: partitions,
+		logs:       make(map[int32][][]byte),
+		produced:   make(map[int32][][]byte),
+		forwarded:  make(map[int32]int),
+		attempts:   make(map[int32]int),
+		version:    sarama.V0_10_0_0,
+	}
+	b.mock = sarama.NewMockBroker(t, BrokerID)
+	b.addr = b.mock.Addr()
+	b.update()
+	return b
+}
+
+// Addr returns the address the broker listens on, which is kept across restarts
+func (b *Broker) Addr() string {
+	return b.addr
+}
+
+// Seed appends messages to a partition, to be served to its consumers
+func (b *Broker) Seed(partition int32, values ...[]byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.logs[partition] = append(b.logs[partition], values...)
+	b.update()
+}
+
+// Forward appends the messages produced to a partition since the last call onto it, as a broker storing them would,
+// and returns how many were appended
+func (b *Broker) Forward(partition int32) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.collect()
+	pending := b.produced[partition][b.forwarded[partition]:]
+	b.logs[partition] = append(b.logs[partition], pending...)
+	b.forwarded[partition] += len(pending)
+	b.update()
+	return len(pending)
+}
+
+// Produced returns the messages produced to a partition so far, across restarts of the broker
+func (b *Broker) Produced(partition int32) [][]byte {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.collect()
+	return append([][]byte(nil), b.produced[partition]...)
+}
+
+// ProduceAttempts returns how many messages were sent to a partition so far, counting each retry and refusal
+func (b *Broker) ProduceAttempts(partition int32) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.collect()
+	return b.attempts[partition]
+}
+
+// WaitProduced waits up to timeout until at least count messages were produced to a partition, and returns them
+func (b *Broker) WaitProduced(partition int32, count int, timeout time.Duration) [][]byte {
+	deadline := time.Now().Add(timeout)
+	for {
+		produced := b.Produced(partition)
+		if len(produced) >= count || time.Now().After(deadline) {
+			return produced
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// SetProduceError has every produce request fail with err until it is set to ErrNoError
+func (b *Broker) SetProduceError(err sarama.KError) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.produceErr = err
+	b.update()
+}
+
+// SetFetchError has every fetch request fail with err until it is set to ErrNoError
+func (b *Broker) SetFetchError(err sarama.KError) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.fetchErr = err
+	b.update()
+}
+
+// Stop closes the broker and its connections, as a crashed broker would, the messages of its partitions are kept
+func (b *Broker) Stop() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.mock == nil {
+		return
+	}
+	b.collect()
+	b.mock.Close()
+	b.mock = nil
+	b.past = 0
+}
+
+// Start restarts a stopped broker on the same address
+func (b *Broker) Start() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.mock != nil {
+		return
+	}
+	b.mock = sarama.NewMockBrokerAddr(b.t, BrokerID, b.addr)
+	b.update()
+}
+
+// Close stops the broker for good
+func (b *Broker) Close() {
+	b.Stop()
+}
+
+// SetVersion has the broker answer only the requests known to the given protocol version, as an older broker would
+func (b *Broker) SetVersion(version sarama.KafkaVersion) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.version = version
+	b.update()
+}
+
+// update installs the responses for the current state of the partitions, the caller must hold the lock
+func (b *Broker) update() {
+	if b.mock == nil {
+		return
+	}
+
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddBroker(b.addr, BrokerID)
+	metadata.AddTopic(b.topic, sarama.ErrNoError)
+	offsets := sarama.NewMockOffsetResponse(b.t)
+	fetch := sarama.NewMockFetchResponse(b.t, 10)
+	produce := sarama.NewMockProduceResponse(b.t)
+	failedFetch := &sarama.FetchResponse{}
+	for partition := int32(0); partition < b.partitions; partition++ {
+		metadata.AddTopicPartition(b.topic, partition, BrokerID, []int32{BrokerID}, []int32{BrokerID}, sarama.ErrNoError)
+		newest := int64(len(b.logs[partition]))
+		offsets.SetOffset(b.topic, partition, sarama.OffsetOldest, 0)
+		offsets.SetOffset(b.topic, partition, sarama.OffsetNewest, newest)
+		fetch.SetHighWaterMark(b.topic, partition, newest)
+		for offset, value := range b.logs[partition] {
+			fetch.SetMessage(b.topic, partition, int64(offset), sarama.ByteEncoder(value))
+		}
+		produce.SetError(b.topic, partition, b.produceErr)
+		failedFetch.AddError(b.topic, partition, b.fetchErr)
+	}
+
+	handlers := map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockWrapper(metadata),
+		"OffsetRequest":   offsets,
+		"FetchRequest":    fetch,
+		"ProduceRequest":  produce,
+	}
+	if b.fetchErr != sarama.ErrNoError {
+		handlers["FetchRequest"] = sarama.NewMockWrapper(failedFetch)
+	}
+	if b.version.IsAtLeast(sarama.V0_9_0_0) {
+		handlers["ListGroupsRequest"] = sarama.NewMockWrapper(&sarama.ListGroupsResponse{})
+	}
+	if b.version.IsAtLeast(sarama.V0_10_0_0) {
+		handlers["ApiVersionsRequest"] = sarama.NewMockWrapper(&sarama.ApiVersionsResponse{})
+	}
+	b.mock.SetHandlerByMap(handlers)
+}
+
+// collect records the messages of the produce requests received since the last call, the caller must hold the lock
+func (b *Broker) collect() {
+	if b.mock == nil {
+		return
+	}
+	history := b.mock.History()
+	for _, rr := range history[b.past:] {
+		req, ok := rr.Request.(*sarama.ProduceRequest)
+		if !ok {
+			continue
+		}
+		res, _ := rr.Response.(*sarama.ProduceResponse)
+		for partition, values := range producedValues(req, b.topic) {
+			b.attempts[partition] += len(values)
+			if res == nil || res.GetBlock(b.topic, partition) == nil || res.GetBlock(b.topic, partition).Err != sarama.ErrNoError {
+				// The messages were refused, and so are not stored
+				continue
+			}
+			b.produced[partition] = append(b.produced[partition], values...)
+		}
+	}
+	b.past = len(history)
+}
+
+// producedValues returns the values of the uncompressed messages of a produce request for topic, by partition
+// The client does not export the message sets of a request, so they are read by reflection
+func producedValues(req *sarama.ProduceRequest, topic string) map[int32][][]byte {
+	values := make(map[int32][][]byte)
+	partitions := reflect.ValueOf(req).Elem().FieldByName("msgSets").MapIndex(reflect.ValueOf(topic))
+	if !partitions.IsValid() {
+		return values
+	}
+	for _, key := range partitions.MapKeys() {
+		partition := int32(key.Int())
+		blocks := partitions.MapIndex(key).Elem().FieldByName("Messages")
+		for i := 0; i < blocks.Len(); i++ {
+			value := blocks.Index(i).Elem().FieldByName("Msg").Elem().FieldByName("Value").Bytes()
+			values[partition] = append(values[partition], append([]byte(nil), value...))
+		}
+	}
+	return values
+}
+
+// NewTestConfig returns the config of an orderer of the default chain on partition 0 of the topic of broker, with
+// timeouts short enough for tests
+func NewTestConfig(broker *Broker) *config.TopLevel {
+	return &config.TopLevel{
+		General: config.General{
+			OrdererType:   "kafka",
+			BatchTimeout:  100 * time.Millisecond,
+			BatchSize:     10,
+			QueueSize:     100,
+			MaxWindowSize: 100,
+			ListenAddress: "127.0.0.1",
+			ListenPort:    5151,
+			NetworkID:     "test",
+		},
+		Kafka: config.Kafka{
+			Brokers:                 []string{broker.Addr()},
+			Topic:                   broker.topic,
+			PartitionID:             0,
+			ChainMapping:            "partition",
+			TopicTemplate:           "fabric-{network}-{chain}",
+			Partitions:              broker.partitions,
+			ReplicationFactor:       1,
+			MinInSyncReplicas:       1,
+			StartupTimeout:          time.Second,
+			ShutdownTimeout:         time.Second,
+			MetadataRefreshInterval: time.Minute,
+			Version:                 "0.9.0.1",
+			Compression:             "none",
+			Retry:                   config.Retry{Period: 10 * time.Millisecond, Stop: 5 * time.Second},
+			Producer: config.Producer{
+				RequiredAcks:    "WaitForAll",
+				MaxMessageBytes: 1000000,
+				Retry:           config.ProducerRetry{Max: 1, Backoff: time.Millisecond},
+			},
+			Consumer: config.Consumer{
+				Retry: config.ConsumerRetry{Backoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+			},
+		},
+	}
+}
@@ -17,15 +17,34 @@ limitations under the License.
 package kafka
 
 import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/consenter"
+	"github.com/hyperledger/fabric/orderer/common/policies"
 	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
 )
 
 // Orderer allows the caller to submit to and receive messages from the orderer
 type Orderer interface {
 	Broadcast(stream ab.AtomicBroadcast_BroadcastServer) error
+	SubmitBroadcast(ctx context.Context, msg *ab.BroadcastMessage) (*ab.BroadcastResponse, error)
+	BroadcastBatch(stream ab.AtomicBroadcast_BroadcastBatchServer) error
 	Deliver(stream ab.AtomicBroadcast_DeliverServer) error
+	ab.AdminServer
 	Teardown() error
+	// WatchConnectivity calls f with the connectivity of the orderer to the Kafka brokers now and whenever it changes
+	WatchConnectivity(f func(Connectivity))
 }
 
 // Closeable allows the shut down of the calling resource
@@ -34,21 +53,305 @@ type Closeable interface {
 }
 
 type serverImpl struct {
-	broadcaster Broadcaster
-	deliverer   Deliverer
+	config         *config.TopLevel
+	lf             rawledger.Factory
+	defaultChainID []byte
+	filter         *broadcastfilter.RuleSet
+	deliverer      Deliverer
+	registry       *trackedRegistry // Receives the metrics of each chain, prefixed by the hex encoded chain ID, may be nil
+
+	chainFunc    func(conf *config.TopLevel, rl rawledger.ReadWriter, registry gometrics.Registry, seek int64, notify func(Connectivity)) *broadcasterImpl
+	connectivity *connectivityTracker
+	startOffsets map[string]int64 // The offsets the chains are rebuilt from instead of resuming, by chain ID in hex
+	chainConfig  configtx.Manager // The configuration of the default chain, nil if it is not tracked
+	audit        *audit.Trail     // Records each configuration transaction applied to chainConfig
+
+	lock       sync.Mutex // Guards chains, paused, retryAfter, and stopped
+	chains     map[string]*broadcasterImpl
+	paused     bool
+	retryAfter time.Duration // The retry hint sent to clients while paused
+	stopped    bool
+
+	teardownOnce sync.Once
+	teardownErr  error
+}
+
+// New creates a new orderer which orders every chain of lf on its own partition, messages and seeks which do not specify a chain are routed to defaultChainID
+// The metrics of the chains are exported in registry, which may be nil if they are not to be exported
+func New(conf *config.TopLevel, lf rawledger.Factory, defaultChainID []byte, registry gometrics.Registry) Orderer {
+	return NewWithStartOffsets(conf, lf, defaultChainID, registry, nil, nil, nil, nil, nil)
+}
+
+// checkBatching returns an error if the batch configuration is one the solo orderer would refuse, so that both cut blocks alike
+func checkBatching(conf *config.TopLevel) error {
+	switch {
+	case conf.General.BatchSize == 0:
+		return fmt.Errorf("General.BatchSize must be positive, got %d", conf.General.BatchSize)
+	case conf.General.BatchTimeout <= 0:
+		return fmt.Errorf("General.BatchTimeout must be positive, got %v", conf.General.BatchTimeout)
+	}
+	return nil
+}
+
+// NewWithStartOffsets creates an orderer as New does, except that the chains of startOffsets, keyed by chain ID in hex,
+// consume their partition from the given offset rather than after the messages of their last block, appending the
+// blocks cut from there to their ledger, so that a lost ledger may be rebuilt from the partition
+// If chainConfig is not nil it is the configuration of the default chain, the configuration transactions committed on the
+// chain are applied to it and recorded in trail, which may be nil, and its Orderer batch items override those of General from the block which follows
+// The configuration transactions broadcast on the default chain are then posted only if chainConfig would apply them, those of other chains
+// and chain creation transactions never are, and if chainPolicies is not nil, the other messages broadcast to a chain must satisfy its Writers
+// policy, and seeks its Readers policy
+// Each decision of these policies is recorded to decisions, which may be nil to log every denial
+func NewWithStartOffsets(conf *config.TopLevel, lf rawledger.Factory, defaultChainID []byte, registry gometrics.Registry, startOffsets map[string]int64, chainConfig configtx.Manager, chainPolicies policies.ManagerResolver, trail *audit.Trail, decisions *audit.DecisionLog) Orderer {
+	name := conf.Kafka.Version
+	if name == "" {
+		logger.Infof("Kafka.Version unset, defaulting to %s", defaultVersion)
+		name = defaultVersion
+	}
+	version, err := parseVersion(name)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := checkBatching(conf); err != nil {
+		panic(err)
+	}
+
+	if err := preflight(conf); err != nil {
+		panic(err)
+	}
+
+	negotiated, err := negotiateVersion(conf, version)
+	if err != nil {
+		panic(err)
+	}
+	if negotiated != version {
+		// The chains configure their clients from Kafka.Version
+		conf.Kafka.Version = versionName(negotiated)
+		name, version = conf.Kafka.Version, negotiated
+	}
+	if registry != nil {
+		registry.GetOrRegister("brokers.version", gometrics.NewGauge).(gometrics.Gauge).Update(versionNumber(version))
+	}
+	logger.Infof("Using Kafka protocol version %s (message timestamps: %t)", name, supportsTimestamps(version))
+	if _, err := parseCompression(conf.Kafka.Compression, version); err != nil {
+		panic(err)
+	}
+	logger.Infof("Compressing the messages posted to Kafka with %s", conf.Kafka.Compression)
+
+	s := newServerImpl(conf, lf, defaultChainID, registry, newBroadcaster)
+	s.startOffsets = startOffsets
+	s.govern(chainConfig, chainPolicies, trail, decisions)
+	if err := s.start(); err != nil {
+		s.Teardown()
+		panic(err)
+	}
+	return s
+}
+
+func newServerImpl(conf *config.TopLevel, lf rawledger.Factory, defaultChainID []byte, registry gometrics.Registry, chainFunc func(*config.TopLevel, rawledger.ReadWriter, gometrics.Registry, int64, func(Connectivity)) *broadcasterImpl) *serverImpl {
+	s := &serverImpl{
+		config:         conf,
+		lf:             lf,
+		defaultChainID: defaultChainID,
+		filter:         newFilter(conf),
+		chainFunc:      chainFunc,
+		connectivity:   newConnectivityTracker(),
+		chains:         make(map[string]*broadcasterImpl),
+		paused:         conf.General.Broadcast.StartPaused,
+		retryAfter:     conf.General.Broadcast.RetryAfter,
+	}
+	if registry != nil {
+		s.registry = newTrackedRegistry(registry)
+	}
+	s.deliverer = newDeliverer(conf, s.ledger, nil, nil)
+	return s
+}
+
+// govern applies the configuration transactions committed on the default chain to chainConfig, recording them in trail,
+// refuses to post those broadcast which chainConfig would not apply, as well as every chain creation transaction, and checks the other messages and the seeks of each
+// chain against the Writers and Readers policies of chainPolicies, recording the decisions of the policies to decisions
+// Either of chainConfig and chainPolicies may be nil, it must be called before the orderer is started
+func (s *serverImpl) govern(chainConfig configtx.Manager, chainPolicies policies.ManagerResolver, trail *audit.Trail, decisions *audit.DecisionLog) {
+	s.chainConfig = chainConfig
+	s.audit = trail
+	// As with solo, a replayed or out of sequence configuration transaction is refused rather than ordered and ignored
+	// Chains are mapped to partitions by the configuration of the orderer, so none may be created by a transaction
+	rules := []broadcastfilter.Rule{broadcastfilter.NewChainConfigRule(s.resolveConfig, decisions), broadcastfilter.ChainCreationRejectRule}
+	if chainPolicies != nil {
+		// Every other message must satisfy the Writers policy of its chain
+		rules = append(rules, broadcastfilter.NewChainPolicyRule(chainPolicies, policies.WritersPolicyID, decisions))
+	}
+	s.filter = newFilter(s.config, rules...)
+	s.deliverer = newDeliverer(s.config, s.ledger, chainPolicies, decisions)
+}
+
+// resolveConfig returns the configuration of a chain, which is only tracked for the default chain
+func (s *serverImpl) resolveConfig(chainID []byte) (configtx.Manager, bool) {
+	if s.chainConfig == nil || (len(chainID) != 0 && !bytes.Equal(chainID, s.defaultChainID)) {
+		return nil, false
+	}
+	return s.chainConfig, true
+}
+
+// start begins ordering every chain which exists in the ledger factory
+func (s *serverImpl) start() error {
+	if _, ok := s.lf.Get(s.defaultChainID); !ok {
+		return fmt.Errorf("The default chain %x does not exist in the ledger factory", s.defaultChainID)
+	}
+	// The mapping of every chain is checked before any chain is started
+	chainIDs := s.lf.ChainIDs()
+	known := make(map[string]bool, len(chainIDs))
+	for _, chainID := range chainIDs {
+		rl, _ := s.lf.Get(chainID)
+		if _, err := s.resolve(chainID, rl); err != nil {
+			return err
+		}
+		known[fmt.Sprintf("%x", chainID)] = true
+	}
+	for key := range s.startOffsets {
+		if !known[key] {
+			return fmt.Errorf("A start offset was given for chain %s, which does not exist in the ledger", key)
+		}
+	}
+	for _, chainID := range chainIDs {
+		if _, err := s.chain(chainID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the config of the partition on which a chain is ordered, and an error if the name of the partition's
+// topic is illegal, or if the blocks of rl were cut from another partition, as after a change of the chain mapping
+// A chain given a start offset is not checked, as its blocks are rebuilt regardless of where the last one was cut
+func (s *serverImpl) resolve(chainID []byte, rl rawledger.Reader) (*config.TopLevel, error) {
+	conf, err := chainConfig(s.config, chainID, s.defaultChainID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.startOffsets[fmt.Sprintf("%x", chainID)]; ok {
+		return conf, nil
+	}
+	if _, err := resumeOffset(rl, conf.Kafka.Topic, conf.Kafka.PartitionID); err != nil {
+		return nil, fmt.Errorf("Cannot order chain %x: %s", chainID, err)
+	}
+	return conf, nil
+}
+
+// chain returns the broadcaster of a chain, starting it the first time the chain is referenced, or nil if the ledger factory has no such chain
+func (s *serverImpl) chain(chainID []byte) (*broadcasterImpl, error) {
+	if len(chainID) == 0 {
+		chainID = s.defaultChainID
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if b, ok := s.chains[string(chainID)]; ok {
+		return b, nil
+	}
+	if s.stopped {
+		return nil, fmt.Errorf("The orderer is shutting down")
+	}
+
+	rl, ok := s.lf.Get(chainID)
+	if !ok {
+		return nil, nil
+	}
+
+	conf, err := s.resolve(chainID, rl)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Ordering chain %x on partition %d of Kafka topic %s", chainID, conf.Kafka.PartitionID, conf.Kafka.Topic)
+	var chainRegistry gometrics.Registry
+	if s.registry != nil {
+		chainRegistry = s.registry.child(fmt.Sprintf("%x.", chainID))
+	}
+	seek, err := s.seek(chainID, conf, rl)
+	if err != nil {
+		return nil, err
+	}
+	key := string(chainID)
+	s.connectivity.set(key, Connected)
+	b := s.chainFunc(conf, rl, chainRegistry, seek, func(c Connectivity) { s.connectivity.set(key, c) })
+	if s.chainConfig != nil && bytes.Equal(chainID, s.defaultChainID) {
+		// Set before the chain is started, so that its first block is cut under the configuration
+		b.chainConfig = s.chainConfig
+		b.audit = s.audit
+	}
+	if s.paused {
+		b.pause(s.retryAfter)
+	}
+	b.Start()
+	s.chains[string(chainID)] = b
+	return b, nil
+}
+
+// seek returns the offset at which a chain consumes its partition, after the messages of its last block unless a
+// start offset was given for the chain, which applies only the first time the chain is started
+func (s *serverImpl) seek(chainID []byte, conf *config.TopLevel, rl rawledger.Reader) (int64, error) {
+	key := fmt.Sprintf("%x", chainID)
+	offset, ok := s.startOffsets[key]
+	if !ok {
+		return resumeOffset(rl, conf.Kafka.Topic, conf.Kafka.PartitionID)
+	}
+	delete(s.startOffsets, key)
+
+	oldest, newest, err := partitionRange(conf)
+	if err != nil {
+		return 0, fmt.Errorf("Cannot check the start offset of chain %s: %s", key, err)
+	}
+	if offset < oldest || offset > newest {
+		return 0, fmt.Errorf("The start offset %d of chain %s is outside of offsets %d to %d retained by partition %d of topic %s", offset, key, oldest, newest, conf.Kafka.PartitionID, conf.Kafka.Topic)
+	}
+	logger.Warningf("RECOVERY MODE: chain %s is rebuilt from offset %d of partition %d of topic %s, rather than resumed after its last block, the blocks cut are appended from block %d", key, offset, conf.Kafka.PartitionID, conf.Kafka.Topic, rl.Height())
+	return offset, nil
 }
 
-// New creates a new orderer
-func New(conf *config.TopLevel) Orderer {
-	return &serverImpl{
-		broadcaster: newBroadcaster(conf),
-		deliverer:   newDeliverer(conf),
+// Chain returns the broadcaster of a chain, so that the chain may be ordered through the shared broadcast handler
+func (s *serverImpl) Chain(chainID []byte) (consenter.Chain, bool) {
+	b, err := s.chain(chainID)
+	if err != nil {
+		logger.Errorf("Cannot order chain %x: %s", chainID, err)
+	}
+	if b == nil {
+		return nil, false
+	}
+	return b, true
+}
+
+func (s *serverImpl) ledger(chainID []byte) (rawledger.Reader, bool) {
+	b, err := s.chain(chainID)
+	if err != nil {
+		logger.Errorf("Cannot order chain %x: %s", chainID, err)
+	}
+	if b == nil {
+		return nil, false
 	}
+	return b.ledger, true
+}
+
+// WatchConnectivity calls f with the connectivity of the orderer now and whenever it changes, f must not block
+func (s *serverImpl) WatchConnectivity(f func(Connectivity)) {
+	s.connectivity.watch(f)
 }
 
 // Broadcast submits messages for ordering
 func (s *serverImpl) Broadcast(stream ab.AtomicBroadcast_BroadcastServer) error {
-	return s.broadcaster.Broadcast(stream)
+	return broadcast.Handle(stream, s.filter, s, s.config.General.Protocol.Enabled())
+}
+
+// SubmitBroadcast submits a single message for ordering, replying as Broadcast would
+func (s *serverImpl) SubmitBroadcast(ctx context.Context, msg *ab.BroadcastMessage) (*ab.BroadcastResponse, error) {
+	return broadcast.Submit(ctx, msg, s.Broadcast)
+}
+
+// BroadcastBatch submits batches of messages for ordering, replying to the messages of each batch together
+func (s *serverImpl) BroadcastBatch(stream ab.AtomicBroadcast_BroadcastBatchServer) error {
+	return broadcast.HandleBatches(stream, s.Broadcast)
 }
 
 // Deliver returns a stream of ordered messages
@@ -56,8 +359,99 @@ func (s *serverImpl) Deliver(stream ab.AtomicBroadcast_DeliverServer) error {
 	return s.deliverer.Deliver(stream)
 }
 
-// Teardown shuts down the orderer
+// Pause stops posting the messages broadcast to every chain, they are replied SERVICE_UNAVAILABLE while Deliver continues to be served
+// Blocks are still cut from the messages already posted, and from those posted by the other orderers of each partition, as every
+// orderer sharing a partition must cut the same blocks
+func (s *serverImpl) Pause(ctx context.Context, req *ab.PauseRequest) (*ab.AdminResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped {
+		return &ab.AdminResponse{Status: ab.ReasonUnavailable.Status(), Paused: s.paused}, nil
+	}
+
+	retryAfter := time.Duration(req.RetryAfter) * time.Millisecond
+	if retryAfter == 0 {
+		retryAfter = s.config.General.Broadcast.RetryAfter
+	}
+
+	logger.Infof("Pausing ordering, clients will be asked to retry after %v", retryAfter)
+	s.paused = true
+	s.retryAfter = retryAfter
+	for _, b := range s.chains {
+		b.pause(retryAfter)
+	}
+	return &ab.AdminResponse{Status: ab.Status_SUCCESS, Paused: true}, nil
+}
+
+// Resume posts the messages broadcast to every chain again
+func (s *serverImpl) Resume(ctx context.Context, req *ab.ResumeRequest) (*ab.AdminResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped {
+		return &ab.AdminResponse{Status: ab.ReasonUnavailable.Status(), Paused: s.paused}, nil
+	}
+
+	logger.Infof("Resuming ordering")
+	s.paused = false
+	for _, b := range s.chains {
+		b.resume()
+	}
+	return &ab.AdminResponse{Status: ab.Status_SUCCESS, Paused: false}, nil
+}
+
+// ValidateConfig checks a configuration envelope against the configuration of the default chain, which is left unchanged
+func (s *serverImpl) ValidateConfig(ctx context.Context, configTx *ab.ConfigurationEnvelope) (*ab.ValidateConfigResponse, error) {
+	if s.chainConfig == nil {
+		return &ab.ValidateConfigResponse{Status: ab.ReasonUnavailable.Status(), Info: "configuration is not managed by this orderer"}, nil
+	}
+	if err := s.chainConfig.Validate(configTx); err != nil {
+		return &ab.ValidateConfigResponse{Status: ab.ReasonMalformed.Status(), Info: ab.Detail("%s", err)}, nil
+	}
+	return &ab.ValidateConfigResponse{Status: ab.Status_SUCCESS}, nil
+}
+
+// Teardown stops accepting messages, waits for the messages already accepted on every chain to be cut into blocks,
+// and then shuts down the orderer, it may be called more than once and on an orderer which failed to start
 func (s *serverImpl) Teardown() error {
+	s.teardownOnce.Do(func() {
+		s.teardownErr = s.teardown()
+	})
+	return s.teardownErr
+}
+
+func (s *serverImpl) teardown() error {
+	s.lock.Lock()
+	s.stopped = true
+	chains := make(map[string]*broadcasterImpl, len(s.chains))
+	for chainID, b := range s.chains {
+		chains[chainID] = b
+	}
+	s.lock.Unlock()
+
+	// The chains drain concurrently, so that each may take up to Kafka.ShutdownTimeout
+	var wg sync.WaitGroup
+	errs := make(chan error, len(chains))
+	for chainID, b := range chains {
+		wg.Add(1)
+		go func(chainID string, b *broadcasterImpl) {
+			defer wg.Done()
+			if err := b.Close(); err != nil {
+				logger.Errorf("Error closing chain %x: %s", chainID, err)
+				errs <- err
+			}
+		}(chainID, b)
+	}
+	wg.Wait()
+	close(errs)
+
+	// Deliver is served until the final blocks are cut, so that clients may receive them
 	s.deliverer.Close()
-	return s.broadcaster.Close()
+
+	// The registry outlives the orderer, so that a new one may register the same metrics
+	if s.registry != nil {
+		s.registry.UnregisterAll()
+	}
+	return <-errs
 }
@@ -17,83 +17,148 @@ limitations under the License.
 package kafka
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
-func mockNew(t *testing.T, conf *config.TopLevel, disk chan []byte) Orderer {
-	return &serverImpl{
-		broadcaster: mockNewBroadcaster(t, conf, oldestOffset, disk),
-		deliverer:   mockNewDeliverer(t, conf),
+// mockCluster holds the partitions of a Kafka cluster, created as they are first used
+type mockCluster struct {
+	lock       sync.Mutex
+	partitions map[string]*mockPartition
+}
+
+func newMockCluster() *mockCluster {
+	return &mockCluster{partitions: make(map[string]*mockPartition)}
+}
+
+func (mc *mockCluster) partition(topic string, partition int32) *mockPartition {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	key := fmt.Sprintf("%s/%d", topic, partition)
+	mp, ok := mc.partitions[key]
+	if !ok {
+		mp = newMockPartition()
+		mc.partitions[key] = mp
 	}
+	return mp
 }
 
+// mockNew creates an orderer whose chains are ordered on the partitions of the mock cluster
+func mockNew(t *testing.T, conf *config.TopLevel, mc *mockCluster, lf rawledger.Factory, defaultChainID []byte) *serverImpl {
+	s := newServerImpl(conf, lf, defaultChainID, nil, func(conf *config.TopLevel, rl rawledger.ReadWriter, registry gometrics.Registry, seek int64, notify func(Connectivity)) *broadcasterImpl {
+		return mockNewBroadcaster(t, conf, mc.partition(conf.Kafka.Topic, conf.Kafka.PartitionID), rl, registry)
+	})
+	if err := s.start(); err != nil {
+		t.Fatal("Failed to start the orderer:", err)
+	}
+	return s
+}
+
+// mockBroadcastStream announces every feature by a hello before the incoming messages, and drops the reply, unless hello is cleared
 type mockBroadcastStream struct {
 	grpc.ServerStream
 	incoming chan *ab.BroadcastMessage
 	outgoing chan *ab.BroadcastResponse
 	t        *testing.T
-	closed   bool // Set to true if the outgoing channel is closed
+	closed   chan struct{} // Closed once replies are no longer received from outgoing
+	hello    *ab.Hello
+	greeted  bool
 }
 
 func newMockBroadcastStream(t *testing.T) *mockBroadcastStream {
 	return &mockBroadcastStream{
 		incoming: make(chan *ab.BroadcastMessage),
 		outgoing: make(chan *ab.BroadcastResponse),
+		closed:   make(chan struct{}),
 		t:        t,
+		hello:    ab.AllFeatures.Hello(),
 	}
 }
 
+// Context returns a context which identifies no client
+func (mbs *mockBroadcastStream) Context() context.Context {
+	return context.Background()
+}
+
 func (mbs *mockBroadcastStream) Recv() (*ab.BroadcastMessage, error) {
+	if hello := mbs.hello; hello != nil && !mbs.greeted {
+		mbs.greeted = true
+		return &ab.BroadcastMessage{Hello: hello}, nil
+	}
 	return <-mbs.incoming, nil
 }
 
 func (mbs *mockBroadcastStream) Send(reply *ab.BroadcastResponse) error {
-	if !mbs.closed {
-		mbs.outgoing <- reply
+	if mbs.hello != nil && reply.Hello != nil {
+		return nil
+	}
+	select {
+	case mbs.outgoing <- reply:
+	case <-mbs.closed:
 	}
 	return nil
 }
 
 func (mbs *mockBroadcastStream) CloseOut() bool {
-	close(mbs.outgoing)
-	mbs.closed = true
-	return mbs.closed
+	close(mbs.closed)
+	return true
 }
 
+// mockDeliverStream announces every feature by a hello before the incoming updates, and drops the reply, unless hello is cleared
 type mockDeliverStream struct {
 	grpc.ServerStream
 	incoming chan *ab.DeliverUpdate
 	outgoing chan *ab.DeliverResponse
 	t        *testing.T
-	closed   bool
+	closed   chan struct{}
+	hello    *ab.Hello
+	greeted  bool
 }
 
 func newMockDeliverStream(t *testing.T) *mockDeliverStream {
 	return &mockDeliverStream{
 		incoming: make(chan *ab.DeliverUpdate),
 		outgoing: make(chan *ab.DeliverResponse),
+		closed:   make(chan struct{}),
 		t:        t,
+		hello:    ab.AllFeatures.Hello(),
 	}
 }
 
+// Context returns a context which identifies no client
+func (mds *mockDeliverStream) Context() context.Context {
+	return context.Background()
+}
+
 func (mds *mockDeliverStream) Recv() (*ab.DeliverUpdate, error) {
+	if hello := mds.hello; hello != nil && !mds.greeted {
+		mds.greeted = true
+		return &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: hello}}, nil
+	}
 	return <-mds.incoming, nil
 
 }
 
 func (mds *mockDeliverStream) Send(reply *ab.DeliverResponse) error {
-	if !mds.closed {
-		mds.outgoing <- reply
+	if mds.hello != nil && reply.GetHello() != nil {
+		return nil
+	}
+	select {
+	case mds.outgoing <- reply:
+	case <-mds.closed:
 	}
 	return nil
 }
 
 func (mds *mockDeliverStream) CloseOut() bool {
-	close(mds.outgoing)
-	mds.closed = true
-	return mds.closed
+	close(mds.closed)
+	return true
 }
@@ -0,0 +1,476 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
+	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/fileledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	"github.com/golang/protobuf/proto"
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
+)
+
+func TestMultipleChains(t *testing.T) {
+	defaultChainID, otherChainID := []byte("default"), []byte("other")
+
+	for _, mapping := range []string{chainMappingPartition, chainMappingTopic} {
+		conf := testConfWithBatch(2, time.Hour)
+		conf.Kafka.ChainMapping = mapping
+		conf.Kafka.ChainPartitions = []string{fmt.Sprintf("%x:1", otherChainID)}
+
+		lf := ramledger.NewFactory(10)
+		lf.GetOrCreate(defaultChainID, testGenesisBlock)
+		lf.GetOrCreate(otherChainID, testGenesisBlock)
+
+		mc := newMockCluster()
+		s := mockNew(t, conf, mc, lf, defaultChainID)
+
+		// Each chain is broadcast to on its own stream, concurrently
+		var wg sync.WaitGroup
+		for _, chainID := range [][]byte{defaultChainID, otherChainID} {
+			wg.Add(1)
+			go func(chainID []byte) {
+				defer wg.Done()
+				mbs := newMockBroadcastStream(t)
+				go s.Broadcast(mbs)
+				for i := 0; i < 4; i++ {
+					mbs.incoming <- &ab.BroadcastMessage{ChainID: chainID, Data: []byte(fmt.Sprintf("%s %d", chainID, i))}
+					if reply := <-mbs.outgoing; reply.Status != ab.Status_SUCCESS {
+						t.Errorf("Expected the message to be accepted on chain %s, got %v", chainID, reply.Status)
+					}
+				}
+			}(chainID)
+		}
+		wg.Wait()
+
+		if len(mc.partitions) != 2 {
+			t.Fatalf("Expected the chains to be ordered on 2 partitions with %s mapping, got %d", mapping, len(mc.partitions))
+		}
+		for key, mp := range mc.partitions {
+			if posted := len(mp.posted()); posted != 4 {
+				t.Fatalf("Expected 4 messages on partition %s with %s mapping, got %d", key, mapping, posted)
+			}
+		}
+
+		for _, chainID := range [][]byte{defaultChainID, otherChainID} {
+			rl, _ := lf.Get(chainID)
+			for number := uint64(1); number <= 2; number++ {
+				block := waitForBlock(t, rl, number)
+				for i, msg := range block.Data.Messages {
+					if expected := fmt.Sprintf("%s %d", chainID, 2*(number-1)+uint64(i)); string(msg.Data) != expected {
+						t.Fatalf("Expected %q in block %d of chain %s with %s mapping, got %q", expected, number, chainID, mapping, msg.Data)
+					}
+				}
+			}
+			if rl.Height() != 3 {
+				t.Fatalf("Expected chain %s to have 3 blocks with %s mapping, got %d", chainID, mapping, rl.Height())
+			}
+		}
+
+		if err := s.Teardown(); err != nil {
+			t.Fatal("Error tearing down the orderer:", err)
+		}
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	chainID := []byte("default")
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(chainID, testGenesisBlock)
+	conf := testConfWithBatch(1, time.Hour)
+	conf.General.Broadcast.StartPaused = true
+	conf.General.Broadcast.RetryAfter = 2 * time.Second
+	mc := newMockCluster()
+	s := mockNew(t, conf, mc, lf, chainID)
+	defer s.Teardown()
+
+	mbs := newMockBroadcastStream(t)
+	go s.Broadcast(mbs)
+	broadcast := func(data string) *ab.BroadcastResponse {
+		mbs.incoming <- &ab.BroadcastMessage{Data: []byte(data)}
+		return <-mbs.outgoing
+	}
+
+	// Started paused, the configured retry hint is sent
+	if reply := broadcast("a"); reply.Status != ab.Status_SERVICE_UNAVAILABLE || reply.RetryAfter != 2000 {
+		t.Fatalf("Expected SERVICE_UNAVAILABLE with a retry after 2000ms while started paused, got %v", reply)
+	}
+	if resp, _ := s.Resume(context.Background(), &ab.ResumeRequest{}); resp.Status != ab.Status_SUCCESS || resp.Paused {
+		t.Fatalf("Expected to resume, got %v", resp)
+	}
+	if reply := broadcast("b"); reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message to be accepted once resumed, got %v", reply)
+	}
+	rl, _ := lf.Get(chainID)
+	waitForBlock(t, rl, 1)
+
+	if resp, _ := s.Pause(context.Background(), &ab.PauseRequest{RetryAfter: 250}); resp.Status != ab.Status_SUCCESS || !resp.Paused {
+		t.Fatalf("Expected to pause, got %v", resp)
+	}
+	if reply := broadcast("c"); reply.Status != ab.Status_SERVICE_UNAVAILABLE || reply.RetryAfter != 250 {
+		t.Fatalf("Expected SERVICE_UNAVAILABLE with the retry hint of the pause request, got %v", reply)
+	}
+	if posted := len(mc.partition(conf.Kafka.Topic, conf.Kafka.PartitionID).posted()); posted != 1 {
+		t.Fatalf("Expected only the message accepted before the pause to be posted, got %d", posted)
+	}
+
+	s.Resume(context.Background(), &ab.ResumeRequest{})
+	if reply := broadcast("d"); reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message to be accepted once resumed, got %v", reply)
+	}
+	if block := waitForBlock(t, rl, 2); string(block.Data.Messages[0].Data) != "d" {
+		t.Fatalf("Expected the message broadcast after resuming in block 2, got %q", block.Data.Messages[0].Data)
+	}
+
+	// The configuration of the default chain is not tracked, so there is nothing to validate against
+	if resp, _ := s.ValidateConfig(context.Background(), &ab.ConfigurationEnvelope{}); resp.Status != ab.Status_SERVICE_UNAVAILABLE {
+		t.Fatalf("Expected SERVICE_UNAVAILABLE validating a configuration which is not managed, got %v", resp)
+	}
+}
+
+func TestMultipleChainsDeliver(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate([]byte("default"), testGenesisBlock)
+	other := lf.GetOrCreate([]byte("other"), testGenesisBlock)
+	other.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("other")}}, func(block *ab.Block) {
+		block.SetProof(encodeProof(testConf.Kafka.Topic, 1, 0))
+	})
+
+	conf := *testConf
+	conf.Kafka.ChainPartitions = []string{fmt.Sprintf("%x:1", "other")}
+	s := mockNew(t, &conf, newMockCluster(), lf, []byte("default"))
+	defer s.Teardown()
+
+	mds := newMockDeliverStream(t)
+	go s.Deliver(mds)
+
+	seek := testNewSeekMessage("specific", 1, 10)
+	seek.GetSeek().ChainID = []byte("other")
+	mds.incoming <- seek
+	select {
+	case reply := <-mds.outgoing:
+		if block := reply.GetBlock(); block == nil || string(block.Data.Messages[0].Data) != "other" {
+			t.Fatalf("Expected block 1 of the other chain, got %v", reply)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Should have received block 1 of the other chain by now")
+	}
+
+	mds = newMockDeliverStream(t)
+	go s.Deliver(mds)
+	seek = testNewSeekMessage("oldest", 0, 10)
+	seek.GetSeek().ChainID = []byte("missing")
+	mds.incoming <- seek
+	select {
+	case reply := <-mds.outgoing:
+		if reply.GetError() != ab.Status_NOT_FOUND {
+			t.Fatalf("Expected NOT_FOUND seeking an unknown chain, got %v", reply)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Should have received an error seeking an unknown chain by now")
+	}
+}
+
+func TestDeliverBeyondRetention(t *testing.T) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+
+	chainID := []byte("default")
+	fileledger.NewFactory(location).GetOrCreate(chainID, testGenesisBlock)
+	conf := testConfWithBatch(2, time.Hour)
+	mc := newMockCluster()
+
+	broadcast := func(s *serverImpl, data ...string) {
+		mbs := newMockBroadcastStream(t)
+		go s.Broadcast(mbs)
+		for _, d := range data {
+			mbs.incoming <- &ab.BroadcastMessage{Data: []byte(d)}
+			if reply := <-mbs.outgoing; reply.Status != ab.Status_SUCCESS {
+				t.Fatalf("Expected the message to be accepted, got %v", reply.Status)
+			}
+		}
+	}
+
+	s := mockNew(t, conf, mc, fileledger.NewFactory(location), chainID)
+	broadcast(s, "a", "b", "c", "d")
+	rl, _ := s.ledger(chainID)
+	// The file ledger only waits for the next block, so each is waited for in turn
+	waitForBlock(t, rl, 1)
+	waitForBlock(t, rl, 2)
+	if err := s.Teardown(); err != nil {
+		t.Fatal("Error tearing down the orderer:", err)
+	}
+
+	// The brokers no longer retain any of the messages which were cut into blocks
+	mp := mc.partition(conf.Kafka.Topic, conf.Kafka.PartitionID)
+	mp.truncate(int64(len(mp.posted())))
+
+	s = mockNew(t, conf, mc, fileledger.NewFactory(location), chainID)
+	defer s.Teardown()
+	broadcast(s, "e", "f")
+	rl, _ = s.ledger(chainID)
+	waitForBlock(t, rl, 3)
+
+	mds := newMockDeliverStream(t)
+	go s.Deliver(mds)
+	mds.incoming <- testNewSeekMessage("oldest", 0, 10)
+	expected := [][]string{{"genesis"}, {"a", "b"}, {"c", "d"}, {"e", "f"}}
+	for number, contents := range expected {
+		select {
+		case reply := <-mds.outgoing:
+			block := reply.GetBlock()
+			if block == nil || block.Header.Number != uint64(number) || len(block.Data.Messages) != len(contents) {
+				t.Fatalf("Expected block %d holding %v, got %v", number, contents, reply)
+			}
+			for i, data := range contents {
+				if string(block.Data.Messages[i].Data) != data {
+					t.Fatalf("Expected block %d to hold %v, got %q at %d", number, contents, block.Data.Messages[i].Data, i)
+				}
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("Should have received block %d by now", number)
+		}
+	}
+}
+
+func TestTeardown(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate([]byte("default"), testGenesisBlock)
+	other := lf.GetOrCreate([]byte("other"), testGenesisBlock)
+
+	conf := testConfWithBatch(100, time.Hour)
+	conf.Kafka.ChainPartitions = []string{fmt.Sprintf("%x:1", "other")}
+	s := mockNew(t, conf, newMockCluster(), lf, []byte("default"))
+
+	mbs := newMockBroadcastStream(t)
+	go s.Broadcast(mbs)
+	mbs.incoming <- &ab.BroadcastMessage{ChainID: []byte("other"), Data: []byte("accepted")}
+	if reply := <-mbs.outgoing; reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message to be accepted, got %v", reply.Status)
+	}
+
+	if err := s.Teardown(); err != nil {
+		t.Fatal("Error tearing down the orderer:", err)
+	}
+	if err := s.Teardown(); err != nil {
+		t.Fatal("Error tearing down the orderer a second time:", err)
+	}
+	if other.Height() != 2 {
+		t.Fatalf("Expected the accepted message to be cut into a block on teardown, height is %d", other.Height())
+	}
+
+	mbs.incoming <- &ab.BroadcastMessage{ChainID: []byte("other"), Data: []byte("refused")}
+	if reply := <-mbs.outgoing; reply.Status == ab.Status_SUCCESS {
+		t.Fatal("Should not accept messages after teardown")
+	}
+
+	lf.GetOrCreate([]byte("late"), testGenesisBlock)
+	if _, ok := s.Chain([]byte("late")); ok {
+		t.Fatal("Should not start chains after teardown")
+	}
+}
+
+func TestTeardownUnregistersMetrics(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate([]byte("default"), testGenesisBlock)
+	registry := gometrics.NewRegistry()
+	mc := newMockCluster()
+	name := fmt.Sprintf("%x.messages.produced", "default")
+
+	for i := 0; i < 2; i++ {
+		s := newServerImpl(testConf, lf, []byte("default"), registry, func(conf *config.TopLevel, rl rawledger.ReadWriter, registry gometrics.Registry, seek int64, notify func(Connectivity)) *broadcasterImpl {
+			return mockNewBroadcaster(t, conf, mc.partition(conf.Kafka.Topic, conf.Kafka.PartitionID), rl, registry)
+		})
+		if err := s.start(); err != nil {
+			t.Fatal("Failed to start the orderer:", err)
+		}
+
+		b, _ := s.chain(nil)
+		b.Enqueue(&ab.BroadcastMessage{Data: []byte("a")})
+		// A restarted orderer registers its own metrics rather than finding those of the previous one
+		if count := registry.Get(name).(gometrics.Counter).Count(); count != 1 {
+			t.Fatalf("Expected 1 produced message on orderer %d, got %d", i, count)
+		}
+
+		if err := s.Teardown(); err != nil {
+			t.Fatal("Error tearing down the orderer:", err)
+		}
+		if registry.Get(name) != nil {
+			t.Fatal("Expected the metrics of the chain to be unregistered on teardown")
+		}
+	}
+}
+
+func TestTopicTemplateChangeRefused(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate([]byte("default"), testGenesisBlock)
+	mc := newMockCluster()
+
+	conf := testConfWithBatch(1, time.Hour)
+	conf.Kafka.ChainMapping = chainMappingTopic
+	s := mockNew(t, conf, mc, lf, []byte("default"))
+	b, _ := s.chain(nil)
+	b.Enqueue(&ab.BroadcastMessage{Data: []byte("a")})
+	rl, _ := lf.Get([]byte("default"))
+	waitForBlock(t, rl, 1)
+	if err := s.Teardown(); err != nil {
+		t.Fatal("Error tearing down the orderer:", err)
+	}
+
+	changed := *conf
+	changed.Kafka.TopicTemplate = "renamed-{chain}"
+	s = newServerImpl(&changed, lf, []byte("default"), nil, func(conf *config.TopLevel, rl rawledger.ReadWriter, registry gometrics.Registry, seek int64, notify func(Connectivity)) *broadcasterImpl {
+		t.Fatalf("Should not start the chain on topic %s", conf.Kafka.Topic)
+		return nil
+	})
+	if err := s.start(); err == nil {
+		t.Fatal("Should refuse to start once the topic of a chain with blocks has changed")
+	}
+	s.Teardown()
+
+	// The chain is still ordered under the template it was cut with
+	s = mockNew(t, conf, mc, lf, []byte("default"))
+	s.Teardown()
+}
+
+func TestCheckBatching(t *testing.T) {
+	conf := *testConf
+	if err := checkBatching(&conf); err != nil {
+		t.Fatal("Expected the test configuration to be accepted:", err)
+	}
+	conf.General.BatchSize = 0
+	if err := checkBatching(&conf); err == nil {
+		t.Fatal("Expected a batch size of zero to be refused")
+	}
+	conf = *testConf
+	conf.General.BatchTimeout = 0
+	if err := checkBatching(&conf); err == nil {
+		t.Fatal("Expected a batch timeout of zero to be refused")
+	}
+}
+
+// rejectAllPolicies authorizes no configuration change
+type rejectAllPolicies struct{}
+
+func (rp rejectAllPolicies) GetPolicy(id string) (policies.Policy, bool) {
+	return rp, true
+}
+
+func (rp rejectAllPolicies) Evaluate(msg []byte, sigs []*ab.SignedData) error {
+	return errors.New("no signature is accepted")
+}
+
+func TestBroadcastUnauthorizedConfig(t *testing.T) {
+	chainID := []byte("default")
+	lf := ramledger.NewFactory(10)
+	rl := lf.GetOrCreate(chainID, testGenesisBlock)
+	registry := configtx.NewRegistry()
+	if err := registry.Register(ab.Configuration_Orderer, sharedconfig.NewHandler(sharedconfig.Limits{})); err != nil {
+		t.Fatalf("Error registering handler: %s", err)
+	}
+	cm, err := registry.NewManager(&ab.ConfigurationEnvelope{Sequence: 0, ChainID: chainID}, rejectAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+
+	mc := newMockCluster()
+	s := newServerImpl(testConfWithBatch(1, time.Hour), lf, chainID, nil, func(conf *config.TopLevel, rl rawledger.ReadWriter, registry gometrics.Registry, seek int64, notify func(Connectivity)) *broadcasterImpl {
+		return mockNewBroadcaster(t, conf, mc.partition(conf.Kafka.Topic, conf.Kafka.PartitionID), rl, registry)
+	})
+	s.govern(cm, nil, nil, nil)
+	if err := s.start(); err != nil {
+		t.Fatal("Failed to start the orderer:", err)
+	}
+	defer s.Teardown()
+
+	data, _ := proto.Marshal(&ab.BatchSize{Messages: 2})
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: chainID, ID: sharedconfig.BatchSizeKey, Type: ab.Configuration_Orderer, Data: data, LastModified: 1})
+	configTx, err := configtx.MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  chainID,
+		Entries:  []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}},
+	})
+	if err != nil {
+		t.Fatalf("Error marshaling configuration: %s", err)
+	}
+
+	mbs := newMockBroadcastStream(t)
+	go s.Broadcast(mbs)
+	mbs.incoming <- &ab.BroadcastMessage{Data: configTx}
+	if reply := <-mbs.outgoing; reply.Status != ab.Status_FORBIDDEN {
+		t.Fatalf("Expected the unauthorized configuration transaction to be refused, got %v", reply)
+	}
+	mbs.incoming <- &ab.BroadcastMessage{Data: []byte("regular")}
+	if reply := <-mbs.outgoing; reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message to be accepted, got %v", reply)
+	}
+
+	// The refused transaction was never posted, so the first block holds the message which followed it
+	if block := waitForBlock(t, rl, 1); len(block.Data.Messages) != 1 || string(block.Data.Messages[0].Data) != "regular" {
+		t.Fatalf("Expected block 1 to hold only the accepted message, got %v", block.Data.Messages)
+	}
+	if cm.Sequence() != 0 {
+		t.Fatalf("Expected the configuration to be unchanged, but the sequence is %d", cm.Sequence())
+	}
+}
+
+func TestChainPolicies(t *testing.T) {
+	chainID := []byte("default")
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(chainID, testGenesisBlock)
+	mc := newMockCluster()
+	s := newServerImpl(testConfWithBatch(1, time.Hour), lf, chainID, nil, func(conf *config.TopLevel, rl rawledger.ReadWriter, registry gometrics.Registry, seek int64, notify func(Connectivity)) *broadcasterImpl {
+		return mockNewBroadcaster(t, conf, mc.partition(conf.Kafka.Topic, conf.Kafka.PartitionID), rl, registry)
+	})
+	s.govern(nil, func(chainID []byte) (policies.Manager, bool) { return rejectAllPolicies{}, true }, nil, nil)
+	if err := s.start(); err != nil {
+		t.Fatal("Failed to start the orderer:", err)
+	}
+	defer s.Teardown()
+
+	mbs := newMockBroadcastStream(t)
+	go s.Broadcast(mbs)
+	mbs.incoming <- &ab.BroadcastMessage{Data: []byte("unauthorized")}
+	if reply := <-mbs.outgoing; reply.Status != ab.Status_FORBIDDEN {
+		t.Fatalf("Expected a message which does not satisfy the Writers policy to be refused, got %v", reply)
+	}
+
+	mds := newMockDeliverStream(t)
+	go s.Deliver(mds)
+	mds.incoming <- testNewSeekMessage("oldest", 0, 10)
+	if reply := <-mds.outgoing; reply.GetError() != ab.Status_FORBIDDEN {
+		t.Fatalf("Expected a seek which does not satisfy the Readers policy to be refused, got %v", reply)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+// preflight waits until Kafka.MinInSyncReplicas brokers answer for the topic metadata, so that the orderer does not serve
+// clients it could never order for, it backs off exponentially from Kafka.Retry.Period until Kafka.StartupTimeout passes
+// Brokers whose names cannot be resolved yet are retried like brokers which do not answer
+func preflight(conf *config.TopLevel) error {
+	if conf.Kafka.MinInSyncReplicas > len(conf.Kafka.Brokers) {
+		return fmt.Errorf("Kafka.MinInSyncReplicas is %d but only %d brokers are listed in Kafka.Brokers", conf.Kafka.MinInSyncReplicas, len(conf.Kafka.Brokers))
+	}
+
+	brokerConfig := newBrokerConfig(conf)
+	deadline := time.Now().Add(conf.Kafka.StartupTimeout)
+	backoff := conf.Kafka.Retry.Period
+
+	for attempt := 1; ; attempt++ {
+		reachable, errs := 0, []string(nil)
+		addrs, err := resolveBrokers(conf)
+		if err == nil {
+			reachable, errs = reachBrokers(addrs, brokerConfig)
+		} else {
+			errs = []string{err.Error()}
+		}
+		if reachable >= conf.Kafka.MinInSyncReplicas {
+			logger.Infof("Reached %d of %d Kafka brokers, resolved %v to %v", reachable, len(conf.Kafka.Brokers), conf.Kafka.Brokers, addrs)
+			return nil
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return fmt.Errorf("Reached %d of the %d Kafka brokers required within %v: %s", reachable, conf.Kafka.MinInSyncReplicas, conf.Kafka.StartupTimeout, strings.Join(errs, "; "))
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		logger.Warningf("Reached %d of the %d Kafka brokers required on attempt %d, retrying in %v: %s", reachable, conf.Kafka.MinInSyncReplicas, attempt, backoff, strings.Join(errs, "; "))
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// reachBrokers returns the number of brokers which answered for the metadata of all topics, and the errors of the others
+func reachBrokers(brokers []string, brokerConfig *sarama.Config) (int, []string) {
+	reachable := 0
+	var errs []string
+	for _, addr := range brokers {
+		broker := sarama.NewBroker(addr)
+		err := broker.Open(brokerConfig)
+		if err == nil {
+			// Opening is asynchronous, the error of the connection is returned by the first request
+			_, err = broker.GetMetadata(&sarama.MetadataRequest{})
+			broker.Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("broker %s: %s", addr, err))
+			continue
+		}
+		reachable++
+	}
+	return reachable, errs
+}
@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+func newPreflightConf(timeout time.Duration, brokers ...string) *config.TopLevel {
+	conf := *testConf
+	conf.Kafka.Brokers = brokers
+	conf.Kafka.StartupTimeout = timeout
+	conf.Kafka.Retry.Period = 50 * time.Millisecond
+	return &conf
+}
+
+func TestPreflightBrokersUp(t *testing.T) {
+	mockBroker := startMetadataBroker(t, testConf.Kafka.Topic, 1)
+	defer mockBroker.Close()
+
+	if err := preflight(newPreflightConf(time.Second, mockBroker.Addr())); err != nil {
+		t.Fatal("Expected the preflight check to pass:", err)
+	}
+}
+
+func TestPreflightBrokersDownThenUp(t *testing.T) {
+	// Reserve an address for the broker which is started later
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	delay := 300 * time.Millisecond
+	started := make(chan *sarama.MockBroker, 1)
+	go func() {
+		time.Sleep(delay)
+		mockBroker := sarama.NewMockBrokerAddr(t, brokerID, addr)
+		metadata := &sarama.MetadataResponse{}
+		metadata.AddBroker(mockBroker.Addr(), brokerID)
+		mockBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockWrapper(metadata),
+		})
+		started <- mockBroker
+	}()
+
+	start := time.Now()
+	err = preflight(newPreflightConf(5*time.Second, addr))
+	(<-started).Close()
+	if err != nil {
+		t.Fatal("Expected the preflight check to pass once the broker is up:", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("Expected the preflight check to wait for the broker, it passed after %v", elapsed)
+	}
+}
+
+func TestPreflightBrokersDownPastTimeout(t *testing.T) {
+	start := time.Now()
+	err := preflight(newPreflightConf(200*time.Millisecond, "127.0.0.1:1"))
+	if err == nil || !strings.Contains(err.Error(), "broker 127.0.0.1:1") {
+		t.Fatalf("Expected the preflight check to fail with the error of the broker, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Expected the preflight check to give up after the startup timeout, it took %v", elapsed)
+	}
+}
+
+func TestPreflightMinInSyncReplicas(t *testing.T) {
+	mockBroker := startMetadataBroker(t, testConf.Kafka.Topic, 1)
+	defer mockBroker.Close()
+
+	conf := newPreflightConf(200*time.Millisecond, mockBroker.Addr(), "127.0.0.1:1")
+	conf.Kafka.MinInSyncReplicas = 2
+	if err := preflight(conf); err == nil || !strings.Contains(err.Error(), "Reached 1 of the 2") {
+		t.Fatalf("Expected the preflight check to fail with one of two brokers reachable, got %v", err)
+	}
+
+	conf.Kafka.MinInSyncReplicas = 3
+	if err := preflight(conf); err == nil {
+		t.Fatal("Expected the preflight check to fail with more replicas required than brokers listed")
+	}
+}
@@ -22,21 +22,72 @@ import (
 
 	"github.com/Shopify/sarama"
 	"github.com/hyperledger/fabric/orderer/config"
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
-// Producer allows the caller to send blocks to the orderer
+// Producer allows the caller to post messages to the partition
 type Producer interface {
-	Send(payload []byte) error
+	// Send returns the offset at which the message was stored
+	Send(payload []byte) (int64, error)
 	Closeable
 }
 
 type producerImpl struct {
-	producer sarama.SyncProducer
-	topic    string
+	producer  sarama.SyncProducer
+	topic     string
+	partition int32
+	version   sarama.KafkaVersion
 }
 
-func newProducer(conf *config.TopLevel) Producer {
+// requiredAcks maps the values of Kafka.Producer.RequiredAcks to the acknowledgements the producer waits for
+var requiredAcks = map[string]sarama.RequiredAcks{
+	"WaitForAll":   sarama.WaitForAll,
+	"WaitForLocal": sarama.WaitForLocal,
+	"NoResponse":   sarama.NoResponse,
+}
+
+// newProducerConfig applies the Kafka.Producer settings to the broker config
+func newProducerConfig(conf *config.TopLevel) *sarama.Config {
 	brokerConfig := newBrokerConfig(conf)
+
+	acks, ok := requiredAcks[conf.Kafka.Producer.RequiredAcks]
+	if !ok {
+		panic(fmt.Errorf("Kafka.Producer.RequiredAcks must be one of WaitForAll, WaitForLocal, or NoResponse, got '%s'", conf.Kafka.Producer.RequiredAcks))
+	}
+	if acks != sarama.WaitForAll {
+		logger.Warningf("Kafka.Producer.RequiredAcks is %s, messages acknowledged to clients may be lost if the partition leader fails, this is only fit for development clusters", conf.Kafka.Producer.RequiredAcks)
+	}
+	brokerConfig.Producer.RequiredAcks = acks
+	codec, err := parseCompression(conf.Kafka.Compression, brokerConfig.Version)
+	if err != nil {
+		panic(err)
+	}
+	brokerConfig.Producer.Compression = codec
+	brokerConfig.Producer.MaxMessageBytes = conf.Kafka.Producer.MaxMessageBytes
+	brokerConfig.Producer.Retry.Max = conf.Kafka.Producer.Retry.Max
+	brokerConfig.Producer.Retry.Backoff = conf.Kafka.Producer.Retry.Backoff
+	flush := conf.Kafka.Producer.Flush
+	if flush.Frequency == 0 && (flush.Bytes > 0 || flush.Messages > 0) {
+		// The producer sends one message at a time per client, a lone message would wait for the others forever
+		panic(fmt.Errorf("Kafka.Producer.Flush.Frequency must be set when Flush.Bytes or Flush.Messages is"))
+	}
+	if 2*flush.Frequency >= conf.General.BatchTimeout {
+		logger.Warningf("Kafka.Producer.Flush.Frequency is %v, blocks may take longer than General.BatchTimeout of %v to be cut", flush.Frequency, conf.General.BatchTimeout)
+	}
+	brokerConfig.Producer.Flush.Frequency = conf.Kafka.Producer.Flush.Frequency
+	brokerConfig.Producer.Flush.Bytes = conf.Kafka.Producer.Flush.Bytes
+	brokerConfig.Producer.Flush.Messages = conf.Kafka.Producer.Flush.Messages
+	// Blocks are consumed from Kafka.PartitionID, they must not be spread over the other partitions of the topic
+	brokerConfig.Producer.Partitioner = sarama.NewManualPartitioner
+	return brokerConfig
+}
+
+// newProducer exports the metrics of the sarama client in registry, which may be nil if they are not to be exported
+func newProducer(conf *config.TopLevel, registry gometrics.Registry) Producer {
+	brokerConfig := newProducerConfig(conf)
+	if registry != nil {
+		brokerConfig.MetricRegistry = registry
+	}
 	var p sarama.SyncProducer
 	var err error
 
@@ -51,8 +102,19 @@ loop:
 		case <-panicTick.C:
 			panic(fmt.Errorf("Failed to create Kafka producer: %v", err))
 		case <-repeatTick.C:
-			logger.Debug("Connecting to Kafka brokers:", conf.Kafka.Brokers)
-			p, err = sarama.NewSyncProducer(conf.Kafka.Brokers, brokerConfig)
+			var addrs []string
+			if addrs, err = resolveBrokers(conf); err != nil {
+				logger.Warning(err)
+				continue
+			}
+			logger.Debug("Connecting to Kafka brokers:", addrs)
+			if brokerConfig.Net.TLS.Enable {
+				// The client only reports that no broker was available, a rejected handshake would be retried until Stop
+				if err = checkHandshake(addrs, brokerConfig.Net.TLS.Config); err != nil {
+					panic(err)
+				}
+			}
+			p, err = sarama.NewSyncProducer(addrs, brokerConfig)
 			if err == nil {
 				break loop
 			}
@@ -60,19 +122,25 @@ loop:
 	}
 
 	logger.Debug("Connected to Kafka brokers")
-	return &producerImpl{producer: p, topic: conf.Kafka.Topic}
+	if err = checkTopic(conf, brokerConfig); err != nil {
+		p.Close()
+		panic(err)
+	}
+	return &producerImpl{producer: p, topic: conf.Kafka.Topic, partition: conf.Kafka.PartitionID, version: brokerConfig.Version}
 }
 
 func (p *producerImpl) Close() error {
 	return p.producer.Close()
 }
 
-func (p *producerImpl) Send(payload []byte) error {
-	_, offset, err := p.producer.SendMessage(newMsg(payload, p.topic))
+func (p *producerImpl) Send(payload []byte) (int64, error) {
+	msg := newTimestampedMsg(payload, p.topic, p.version)
+	msg.Partition = p.partition
+	_, offset, err := p.producer.SendMessage(msg)
 	if err == nil {
-		logger.Debugf("Forwarded block %v to ordering service", offset)
+		logger.Debugf("Forwarded message to offset %v of the partition", offset)
 	} else {
-		logger.Info("Failed to send to Kafka brokers:", err)
+		logger.Error("Failed to send to Kafka brokers:", err)
 	}
-	return err
+	return offset, err
 }
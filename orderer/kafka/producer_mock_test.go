@@ -51,7 +51,7 @@ func mockNewProducer(t *testing.T, conf *config.TopLevel, seek int64, disk chan
 	return mp
 }
 
-func (mp *mockProducerImpl) Send(payload []byte) error {
+func (mp *mockProducerImpl) Send(payload []byte) (int64, error) {
 	mp.producer.ExpectSendMessageWithCheckerFunctionAndSucceed(mp.checker)
 	mp.producedOffset++
 	prt, ofs, err := mp.producer.SendMessage(newMsg(payload, mp.config.Kafka.Topic))
@@ -61,7 +61,7 @@ func (mp *mockProducerImpl) Send(payload []byte) error {
 		mp.t.Fatal("Producer not functioning as expected")
 	}
 	mp.disk <- payload // Reaches the broker's disk
-	return err
+	return ofs, err
 }
 
 func (mp *mockProducerImpl) Close() error {
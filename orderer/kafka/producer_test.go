@@ -16,9 +16,167 @@ limitations under the License.
 
 package kafka
 
-import "testing"
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/config"
+)
 
 func TestProducer(t *testing.T) {
 	mp := mockNewProducer(t, testConf, middleOffset, make(chan []byte))
 	defer testClose(t, mp)
 }
+
+func TestProducerConfig(t *testing.T) {
+	conf := *testConf
+	conf.Kafka.Producer.RequiredAcks = "WaitForLocal"
+	conf.Kafka.Producer.Retry.Max = 7
+	conf.Kafka.Producer.Flush.Messages = 5
+	conf.Kafka.Producer.Flush.Frequency = time.Millisecond
+	brokerConfig := newProducerConfig(&conf)
+	if brokerConfig.Producer.RequiredAcks != sarama.WaitForLocal ||
+		brokerConfig.Producer.Retry.Max != 7 ||
+		brokerConfig.Producer.Flush.Messages != 5 ||
+		brokerConfig.Producer.MaxMessageBytes != conf.Kafka.Producer.MaxMessageBytes {
+		t.Fatalf("The producer settings were not applied: %+v", brokerConfig.Producer)
+	}
+
+	conf.Kafka.Producer.RequiredAcks = "All"
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected an unknown RequiredAcks to be rejected")
+		}
+	}()
+	newProducerConfig(&conf)
+}
+
+func TestProducerConfigFlushNeedsFrequency(t *testing.T) {
+	conf := *testConf
+	conf.Kafka.Producer.Flush.Messages = 5
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected Flush.Messages without Flush.Frequency to be rejected")
+		}
+	}()
+	newProducerConfig(&conf)
+}
+
+// sendLatency returns the 99th percentile of the time the producer takes to have each of count messages stored
+func sendLatency(t *testing.T, flush config.Flush, count int) time.Duration {
+	mockBroker := startProduceBroker(t, sarama.NewMockProduceResponse(t))
+	defer mockBroker.Close()
+	conf := newFailingProducerConf(mockBroker)
+	conf.Kafka.Producer.Flush = flush
+	producer := newProducer(conf, nil)
+	defer testClose(t, producer)
+
+	latencies := make([]time.Duration, count)
+	for i := range latencies {
+		start := time.Now()
+		if _, err := producer.Send([]byte("latency")); err != nil {
+			t.Fatal("Failed to send:", err)
+		}
+		latencies[i] = time.Since(start)
+	}
+	sort.Sort(byDuration(latencies))
+	return latencies[count*99/100]
+}
+
+type byDuration []time.Duration
+
+func (s byDuration) Len() int           { return len(s) }
+func (s byDuration) Less(i, j int) bool { return s[i] < s[j] }
+func (s byDuration) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func TestProducerFlushLatency(t *testing.T) {
+	frequency := 50 * time.Millisecond
+	immediate := sendLatency(t, config.Flush{}, 100)
+	batched := sendLatency(t, config.Flush{Frequency: frequency, Messages: 100}, 20)
+	t.Logf("p99 send latency is %v flushing immediately and %v flushing every %v", immediate, batched, frequency)
+
+	if immediate >= frequency {
+		t.Fatalf("Expected messages to be sent without waiting when flushing immediately, p99 is %v", immediate)
+	}
+	if batched < frequency || batched > 4*frequency {
+		t.Fatalf("Expected a lone message to wait about the flush frequency of %v, p99 is %v", frequency, batched)
+	}
+}
+
+// startProduceBroker returns a mock broker which leads the one partition of the topic and answers produce requests in sequence
+func startProduceBroker(t *testing.T, produce ...interface{}) *sarama.MockBroker {
+	mockBroker := sarama.NewMockBroker(t, brokerID)
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddBroker(mockBroker.Addr(), brokerID)
+	metadata.AddTopicPartition(testConf.Kafka.Topic, testConf.Kafka.PartitionID, brokerID, []int32{brokerID}, []int32{brokerID}, sarama.ErrNoError)
+	mockBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockWrapper(metadata),
+		"ProduceRequest":  sarama.NewMockSequence(produce...),
+	})
+	return mockBroker
+}
+
+func newFailingProducerConf(mockBroker *sarama.MockBroker) *config.TopLevel {
+	conf := *testConf
+	conf.Kafka.Brokers = []string{mockBroker.Addr()}
+	conf.Kafka.Retry = config.Retry{Period: time.Millisecond, Stop: time.Second}
+	conf.Kafka.Producer.Retry = config.ProducerRetry{Max: 2, Backoff: time.Millisecond}
+	conf.General.BatchSize = 1
+	return &conf
+}
+
+func countProduceRequests(mockBroker *sarama.MockBroker) int {
+	count := 0
+	for _, rr := range mockBroker.History() {
+		if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+			count++
+		}
+	}
+	return count
+}
+
+func TestProducerRetriesFailedSend(t *testing.T) {
+	mockBroker := startProduceBroker(t, sarama.NewMockProduceResponse(t).SetError(testConf.Kafka.Topic, testConf.Kafka.PartitionID, sarama.ErrNotEnoughReplicas))
+	defer mockBroker.Close()
+
+	producer := newProducer(newFailingProducerConf(mockBroker), nil)
+	defer producer.Close()
+
+	if _, err := producer.Send([]byte("block")); err != sarama.ErrNotEnoughReplicas {
+		t.Fatalf("Expected the send to fail once the retries were exhausted, got %v", err)
+	}
+	if count := countProduceRequests(mockBroker); count != 3 {
+		t.Fatalf("Expected the block to be sent once and retried twice, but it was sent %d times", count)
+	}
+}
+
+func TestBroadcastProduceFailure(t *testing.T) {
+	failure := sarama.NewMockProduceResponse(t).SetError(testConf.Kafka.Topic, testConf.Kafka.PartitionID, sarama.ErrRequestTimedOut)
+	mockBroker := startProduceBroker(t, failure)
+	defer mockBroker.Close()
+
+	conf := newFailingProducerConf(mockBroker)
+	b := newBroadcasterImpl(newProducer(conf, nil), newMockPartition().subscribe(), conf, mockNewLedger(), nil)
+	defer b.Close()
+
+	mbs := newMockBroadcastStream(t)
+	go b.Broadcast(mbs)
+
+	mbs.incoming <- &ab.BroadcastMessage{Data: []byte("rejected")}
+	if reply := <-mbs.outgoing; reply.Status != ab.Status_SERVICE_UNAVAILABLE {
+		t.Fatalf("Expected the client to be told the service is unavailable, got %v", reply.Status)
+	}
+
+	select {
+	case <-b.Errored():
+	case <-time.After(5 * time.Second):
+		t.Fatal("The broadcaster should fail once the message cannot be posted")
+	}
+
+	if count := countProduceRequests(mockBroker); count != 3 {
+		t.Fatalf("Expected three attempts at the failed message, got %d produce requests", count)
+	}
+}
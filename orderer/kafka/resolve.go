@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+// lookupHost resolves the name of a broker, tests replace it to simulate changing DNS records
+var lookupHost = net.LookupHost
+
+// metadataError is returned when no broker could be resolved or asked for the cluster metadata
+type metadataError struct {
+	err error
+}
+
+func (e metadataError) Error() string {
+	return e.err.Error()
+}
+
+// resolveBrokers looks up the addresses of Kafka.Brokers afresh, so that each connection attempt follows brokers whose
+// DNS records changed, brokers which cannot be resolved are skipped and it is an error only if none of them can be
+// With TLS the names are kept, so that the certificates of the brokers are verified against them
+func resolveBrokers(conf *config.TopLevel) ([]string, error) {
+	var addrs, errs []string
+	for _, broker := range conf.Kafka.Brokers {
+		host, port, err := net.SplitHostPort(broker)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if net.ParseIP(host) != nil {
+			addrs = append(addrs, broker)
+			continue
+		}
+		ips, err := lookupHost(host)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if conf.Kafka.TLS.Enabled {
+			addrs = append(addrs, broker)
+			continue
+		}
+		for _, ip := range ips {
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, metadataError{fmt.Errorf("None of the Kafka brokers %v could be resolved: %s", conf.Kafka.Brokers, strings.Join(errs, "; "))}
+	}
+	return addrs, nil
+}
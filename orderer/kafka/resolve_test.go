@@ -0,0 +1,125 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// fakeResolver answers for the names it holds records of, and fails for the others
+type fakeResolver struct {
+	lock    sync.Mutex
+	records map[string][]string
+}
+
+func (fr *fakeResolver) lookupHost(host string) ([]string, error) {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	if ips, ok := fr.records[host]; ok {
+		return ips, nil
+	}
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+func (fr *fakeResolver) set(host string, ips ...string) {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	if ips == nil {
+		delete(fr.records, host)
+		return
+	}
+	fr.records[host] = ips
+}
+
+func useFakeResolver() (*fakeResolver, func()) {
+	fr := &fakeResolver{records: make(map[string][]string)}
+	lookupHost = fr.lookupHost
+	return fr, func() { lookupHost = net.LookupHost }
+}
+
+func TestResolveBrokers(t *testing.T) {
+	fr, restore := useFakeResolver()
+	defer restore()
+	fr.set("kafka.example.com", "10.0.0.1", "10.0.0.2")
+
+	conf := *testConf
+	conf.Kafka.Brokers = []string{"10.0.0.9:9092", "kafka.example.com:9093", "gone.example.com:9092"}
+	addrs, err := resolveBrokers(&conf)
+	if err != nil {
+		t.Fatal("Expected the brokers to resolve:", err)
+	}
+	if expected := []string{"10.0.0.9:9092", "10.0.0.1:9093", "10.0.0.2:9093"}; !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("Expected the brokers to resolve to %v, got %v", expected, addrs)
+	}
+
+	conf.Kafka.TLS.Enabled = true
+	if addrs, _ = resolveBrokers(&conf); !reflect.DeepEqual(addrs, []string{"10.0.0.9:9092", "kafka.example.com:9093"}) {
+		t.Fatalf("Expected the names of the brokers to be kept with TLS, got %v", addrs)
+	}
+
+	conf.Kafka.Brokers = []string{"gone.example.com:9092"}
+	if _, err = resolveBrokers(&conf); err == nil {
+		t.Fatal("Expected an error when none of the brokers resolve")
+	}
+}
+
+func TestSupervisedConsumerFollowsDNS(t *testing.T) {
+	fr, restore := useFakeResolver()
+	defer restore()
+
+	oldBroker := startFetchBroker(t, "127.0.0.1:0", 0, 3)
+	_, port, _ := net.SplitHostPort(oldBroker.Addr())
+	fr.set("kafka.example.com", "127.0.0.1")
+	conf := *testConf
+	conf.Kafka.Brokers = []string{net.JoinHostPort("kafka.example.com", port)}
+
+	registry := gometrics.NewRegistry()
+	connect := func(conf *config.TopLevel, seek int64) (Consumer, error) {
+		return newConsumer(conf, seek, nil, nil)
+	}
+	sc, err := newSupervisedConsumer(&conf, sarama.OffsetOldest, connect, registry, nil)
+	if err != nil {
+		oldBroker.Close()
+		t.Fatal("Failed to create the consumer:", err)
+	}
+	defer testClose(t, sc)
+	expectOffsets(t, sc, 0, 3)
+
+	// The broker moves, and its name does not resolve for a while
+	fr.set("kafka.example.com")
+	oldBroker.Close()
+	failures := registry.Get("brokers.metadata_failures").(gometrics.Counter)
+	for deadline := time.Now().Add(5 * time.Second); failures.Count() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the failure to resolve the brokers to be counted")
+		}
+	}
+
+	newBroker := startFetchBroker(t, net.JoinHostPort("127.0.0.2", port), 3, 6)
+	defer newBroker.Close()
+	fr.set("kafka.example.com", "127.0.0.2")
+	expectOffsets(t, sc, 3, 6)
+}
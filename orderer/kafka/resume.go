@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+)
+
+// The proof of each block the Kafka orderer cuts records the partition the block was cut from, and the offset of the
+// partition at which consumption resumes once the block is committed, so that a restarted orderer does not depend on
+// the brokers retaining the messages of its ledger, and does not resume a chain on a partition it was not ordered on
+// The offset is followed by the partition ID and then the topic name
+const proofHeaderSize = 8 + 4
+
+func encodeProof(topic string, partition int32, next int64) []byte {
+	proof := make([]byte, proofHeaderSize, proofHeaderSize+len(topic))
+	binary.BigEndian.PutUint64(proof, uint64(next))
+	binary.BigEndian.PutUint32(proof[8:], uint32(partition))
+	return append(proof, topic...)
+}
+
+func decodeProof(proof []byte) (topic string, partition int32, next int64, err error) {
+	if len(proof) <= proofHeaderSize {
+		return "", 0, 0, fmt.Errorf("the proof holds %d bytes", len(proof))
+	}
+	next = int64(binary.BigEndian.Uint64(proof))
+	partition = int32(binary.BigEndian.Uint32(proof[8:]))
+	return string(proof[proofHeaderSize:]), partition, next, nil
+}
+
+// resumeOffset returns the offset of the given partition from which the blocks after the last one in rl are cut,
+// the oldest offset if rl holds only the genesis block, and an error if the last block was cut from another partition
+func resumeOffset(rl rawledger.Reader, topic string, partition int32) (int64, error) {
+	height := rl.Height()
+	if height <= 1 {
+		return sarama.OffsetOldest, nil
+	}
+
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, height-1)
+	block, status := it.Next()
+	if status != ab.Status_SUCCESS {
+		return 0, fmt.Errorf("Cannot read block %d to resume from: %s", height-1, status)
+	}
+	cutTopic, cutPartition, next, err := decodeProof(block.Proof())
+	if err != nil {
+		return 0, fmt.Errorf("Block %d was not cut by the Kafka orderer, %s", block.Header.Number, err)
+	}
+	if cutTopic != topic || cutPartition != partition {
+		return 0, fmt.Errorf("Block %d was cut from partition %d of topic %s, refusing to resume the chain on partition %d of topic %s, was the chain mapping changed?", block.Header.Number, cutPartition, cutTopic, partition, topic)
+	}
+	return next, nil
+}
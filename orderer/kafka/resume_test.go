@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+func TestResumeOffset(t *testing.T) {
+	rl := mockNewLedger()
+	if offset, err := resumeOffset(rl, "topic", 1); err != nil || offset != sarama.OffsetOldest {
+		t.Fatalf("Expected a fresh ledger to resume from the oldest offset, got %d (%v)", offset, err)
+	}
+
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("a")}}, func(block *ab.Block) {
+		block.SetProof(encodeProof("topic", 1, 42))
+	})
+	if offset, err := resumeOffset(rl, "topic", 1); err != nil || offset != 42 {
+		t.Fatalf("Expected to resume from offset 42, got %d (%v)", offset, err)
+	}
+	if _, err := resumeOffset(rl, "topic", 2); err == nil {
+		t.Fatal("Should not resume the chain on another partition")
+	}
+	if _, err := resumeOffset(rl, "other", 1); err == nil {
+		t.Fatal("Should not resume the chain on another topic")
+	}
+
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("b")}}, nil)
+	if _, err := resumeOffset(rl, "topic", 1); err == nil {
+		t.Fatal("Should not resume after a block which was not cut by the Kafka orderer")
+	}
+}
+
+func TestBroadcastResumeOffsets(t *testing.T) {
+	rl := mockNewLedger()
+	conf := testConfWithBatch(2, time.Hour)
+	conf.General.BatchMaxBytes = 4
+	mb := newBroadcasterImpl(newMockPartition(), nil, conf, rl, nil)
+	messages := make(chan *sarama.ConsumerMessage)
+	go mb.loop(messages)
+
+	stream := [][]byte{
+		testEncodeRegular(t, "a"),
+		testEncodeRegular(t, "bbbbb"), // Block 1 is cut without it, so it is consumed again on resume, then it is cut into block 2
+		testEncodeRegular(t, "c"),
+		testEncodeRegular(t, "dd"), // Cut by count into block 3, consumption resumes after it
+		testEncodeRegular(t, "e"),
+		encodeTimeToCut(4), // Cuts block 4, consumption resumes after the time-to-cut message
+	}
+	for offset, payload := range stream {
+		messages <- &sarama.ConsumerMessage{Value: payload, Offset: int64(offset)}
+	}
+	mb.Halt()
+	<-mb.exitChan
+
+	expected := []int64{1, 2, 4, 6}
+	if rl.Height() != uint64(len(expected)+1) {
+		t.Fatalf("Expected %d blocks to be cut, got %d", len(expected), rl.Height()-1)
+	}
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	for i, offset := range expected {
+		block, _ := it.Next()
+		topic, partition, next, err := decodeProof(block.Proof())
+		if err != nil || topic != conf.Kafka.Topic || partition != conf.Kafka.PartitionID || next != offset {
+			t.Fatalf("Expected block %d to resume from offset %d of partition %d of topic %s, got proof %x", i+1, offset, conf.Kafka.PartitionID, conf.Kafka.Topic, block.Proof())
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+func TestSASLDisabled(t *testing.T) {
+	// The mock broker fails the test if the first bytes it reads are not a Kafka request
+	mockBroker := sarama.NewMockBroker(t, brokerID)
+	defer mockBroker.Close()
+	mockBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(testConf.Kafka.Topic, testConf.Kafka.PartitionID, sarama.OffsetNewest, newestOffset),
+	})
+
+	conf := *testConf
+	conf.Kafka.Brokers = []string{mockBroker.Addr()}
+	broker := newBroker(&conf)
+	defer broker.Close()
+
+	offset, err := broker.GetOffset(newOffsetReq(&conf, sarama.OffsetNewest))
+	if err != nil || offset != newestOffset {
+		t.Fatalf("Expected offset %d, got %d, %v", newestOffset, offset, err)
+	}
+}
+
+func TestSASLEnabled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		auth := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := io.ReadFull(conn, auth); err != nil {
+			return
+		}
+		received <- auth
+		// A broker accepting the credentials replies with four zero bytes
+		conn.Write(make([]byte, 4))
+	}()
+
+	conf := *testConf
+	conf.Kafka.Brokers = []string{listener.Addr().String()}
+	conf.Kafka.SASL = config.SASL{Enabled: true, Mechanism: config.SASLPlain, User: "orderer", Password: "secret"}
+	broker := newBroker(&conf)
+	defer broker.Close()
+
+	auth := <-received
+	if expected := "\x00orderer\x00secret"; string(auth) != expected {
+		t.Fatalf("Expected the SASL PLAIN handshake %q, got %q", expected, auth)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/common/tlsutil"
+	"github.com/hyperledger/fabric/orderer/config"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// supervisedConsumer keeps consuming the partition across the loss of the brokers, re-establishing the partition
+// consumer after the last message it delivered, so that the channel returned by Recv outlives any one connection
+type supervisedConsumer struct {
+	conf    *config.TopLevel
+	connect func(conf *config.TopLevel, seek int64) (Consumer, error)
+	next    int64 // The offset at which to resume, owned by run
+
+	messages         chan *sarama.ConsumerMessage
+	reconnects       gometrics.Counter
+	connected        gometrics.Gauge   // 1 while a partition consumer is established, 0 while reconnecting
+	metadataFailures gometrics.Counter // Reconnection attempts which could not resolve the brokers or fetch the cluster metadata
+	notify           func(reconnecting bool)
+
+	closeOnce sync.Once
+	haltChan  chan struct{}
+	exitChan  chan struct{}
+}
+
+// newSupervisedConsumer connects once before returning, so that a partition which cannot be consumed at startup is
+// still reported to the caller, the registry may be nil if the metrics are not to be exported
+// notify, which may be nil, is told whenever the supervisor starts and stops reconnecting
+func newSupervisedConsumer(conf *config.TopLevel, seek int64, connect func(*config.TopLevel, int64) (Consumer, error), registry gometrics.Registry, notify func(reconnecting bool)) (Consumer, error) {
+	consumer, err := connect(conf, seek)
+	if err != nil {
+		return nil, err
+	}
+
+	if registry == nil {
+		registry = gometrics.NewRegistry()
+	}
+	if notify == nil {
+		notify = func(bool) {}
+	}
+	sc := &supervisedConsumer{
+		conf:             conf,
+		connect:          connect,
+		next:             seek,
+		messages:         make(chan *sarama.ConsumerMessage),
+		reconnects:       gometrics.NewRegisteredCounter("consumer.reconnects", registry),
+		connected:        gometrics.NewRegisteredGauge("consumer.connected", registry),
+		metadataFailures: gometrics.NewRegisteredCounter("brokers.metadata_failures", registry),
+		notify:           notify,
+		haltChan:         make(chan struct{}),
+		exitChan:         make(chan struct{}),
+	}
+	sc.connected.Update(1)
+	go sc.run(consumer)
+	return sc, nil
+}
+
+// Recv returns a channel with the messages of the partition, it is not closed when a connection is lost
+func (sc *supervisedConsumer) Recv() <-chan *sarama.ConsumerMessage {
+	return sc.messages
+}
+
+// Close stops reconnecting and shuts down the partition consumer, it may be called more than once
+func (sc *supervisedConsumer) Close() error {
+	sc.closeOnce.Do(func() {
+		close(sc.haltChan)
+	})
+	<-sc.exitChan
+	return nil
+}
+
+func (sc *supervisedConsumer) run(consumer Consumer) {
+	defer close(sc.exitChan)
+
+	for {
+		lost := sc.forward(consumer)
+		if err := consumer.Close(); err != nil {
+			logger.Debugf("Error closing the partition consumer: %s", err)
+		}
+		if !lost {
+			return
+		}
+
+		sc.connected.Update(0)
+		sc.notify(true)
+		logger.Warningf("Lost the consumer of partition %d of topic %s, reconnecting at offset %d", sc.conf.Kafka.PartitionID, sc.conf.Kafka.Topic, sc.next)
+		if consumer = sc.reconnect(); consumer == nil {
+			return
+		}
+		sc.reconnects.Inc(1)
+		sc.connected.Update(1)
+		sc.notify(false)
+		logger.Infof("Re-established the consumer of partition %d of topic %s at offset %d", sc.conf.Kafka.PartitionID, sc.conf.Kafka.Topic, sc.next)
+	}
+}
+
+// forward passes on the messages of a partition consumer until its channel is closed, returning true, or until the
+// supervisor is closed, returning false
+func (sc *supervisedConsumer) forward(consumer Consumer) bool {
+	messages := consumer.Recv()
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return true
+			}
+			select {
+			case sc.messages <- msg:
+				sc.next = msg.Offset + 1
+			case <-sc.haltChan:
+				return false
+			}
+		case <-sc.haltChan:
+			return false
+		}
+	}
+}
+
+// reconnect attempts to re-establish the partition consumer until it succeeds, returning nil if the supervisor is
+// closed first, the delay between attempts doubles from Kafka.Consumer.Retry.Backoff up to Kafka.Consumer.Retry.MaxBackoff
+func (sc *supervisedConsumer) reconnect() Consumer {
+	backoff := sc.conf.Kafka.Consumer.Retry.Backoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(tlsutil.Jitter(backoff)):
+		case <-sc.haltChan:
+			return nil
+		}
+
+		consumer, err := sc.connect(sc.conf, sc.next)
+		if err == nil {
+			return consumer
+		}
+		if _, ok := err.(metadataError); ok {
+			sc.metadataFailures.Inc(1)
+		}
+		logger.Warningf("Failed to re-establish the consumer of partition %d of topic %s on attempt %d: %s", sc.conf.Kafka.PartitionID, sc.conf.Kafka.Topic, attempt, err)
+
+		if backoff *= 2; backoff > sc.conf.Kafka.Consumer.Retry.MaxBackoff {
+			backoff = sc.conf.Kafka.Consumer.Retry.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// startFetchBroker serves the messages from..to-1 of the test partition on addr
+func startFetchBroker(t *testing.T, addr string, from, to int64) *sarama.MockBroker {
+	mockBroker := sarama.NewMockBrokerAddr(t, brokerID, addr)
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddBroker(mockBroker.Addr(), brokerID)
+	metadata.AddTopicPartition(testConf.Kafka.Topic, testConf.Kafka.PartitionID, brokerID, []int32{brokerID}, []int32{brokerID}, sarama.ErrNoError)
+	fetch := sarama.NewMockFetchResponse(t, 1).SetHighWaterMark(testConf.Kafka.Topic, testConf.Kafka.PartitionID, to)
+	for offset := from; offset < to; offset++ {
+		fetch.SetMessage(testConf.Kafka.Topic, testConf.Kafka.PartitionID, offset, sarama.StringEncoder(fmt.Sprintf("%d", offset)))
+	}
+	mockBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockWrapper(metadata),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(testConf.Kafka.Topic, testConf.Kafka.PartitionID, sarama.OffsetOldest, 0).
+			SetOffset(testConf.Kafka.Topic, testConf.Kafka.PartitionID, sarama.OffsetNewest, to),
+		"FetchRequest": fetch,
+	})
+	return mockBroker
+}
+
+func expectOffsets(t *testing.T, c Consumer, from, to int64) {
+	for offset := from; offset < to; offset++ {
+		select {
+		case msg := <-c.Recv():
+			if msg.Offset != offset || string(msg.Value) != fmt.Sprintf("%d", offset) {
+				t.Fatalf("Expected the message at offset %d, got offset %d with value %s", offset, msg.Offset, msg.Value)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the message at offset %d to be consumed by now", offset)
+		}
+	}
+}
+
+func TestSupervisedConsumerReconnects(t *testing.T) {
+	mockBroker := startFetchBroker(t, "localhost:0", 0, 3)
+	addr := mockBroker.Addr()
+	conf := *testConf
+	conf.Kafka.Brokers = []string{addr}
+
+	registry := gometrics.NewRegistry()
+	connect := func(conf *config.TopLevel, seek int64) (Consumer, error) {
+		return newConsumer(conf, seek, nil, nil)
+	}
+	sc, err := newSupervisedConsumer(&conf, sarama.OffsetOldest, connect, registry, nil)
+	if err != nil {
+		mockBroker.Close()
+		t.Fatal("Failed to create the consumer:", err)
+	}
+	defer testClose(t, sc)
+	expectOffsets(t, sc, 0, 3)
+
+	// The restored broker only holds the later messages, so consumption must resume after the last one delivered
+	mockBroker.Close()
+	time.Sleep(50 * time.Millisecond)
+	mockBroker = startFetchBroker(t, addr, 3, 6)
+	defer mockBroker.Close()
+	expectOffsets(t, sc, 3, 6)
+
+	if reconnects := registry.Get("consumer.reconnects").(gometrics.Counter).Count(); reconnects < 1 {
+		t.Fatalf("Expected the reconnect to be counted")
+	}
+	if connected := registry.Get("consumer.connected").(gometrics.Gauge).Value(); connected != 1 {
+		t.Fatalf("Expected the consumer to be reported as connected")
+	}
+}
+
+// flakyConnect hands out the given consumers, failing the given number of attempts before each but the first
+type flakyConnect struct {
+	lock      sync.Mutex
+	consumers []Consumer
+	failures  int
+	failed    int
+	seeks     []int64
+}
+
+func (fc *flakyConnect) connect(conf *config.TopLevel, seek int64) (Consumer, error) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	fc.seeks = append(fc.seeks, seek)
+	if len(fc.seeks) > 1 && fc.failed < fc.failures {
+		fc.failed++
+		return nil, fmt.Errorf("Broker unreachable")
+	}
+	if len(fc.consumers) == 0 {
+		return nil, fmt.Errorf("Broker unreachable")
+	}
+	c := fc.consumers[0]
+	fc.consumers = fc.consumers[1:]
+	fc.failed = 0
+	return c, nil
+}
+
+func (fc *flakyConnect) attempts() []int64 {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	return append([]int64(nil), fc.seeks...)
+}
+
+func newTestMessages(from, to int64) chan *sarama.ConsumerMessage {
+	messages := make(chan *sarama.ConsumerMessage, to-from)
+	for offset := from; offset < to; offset++ {
+		messages <- &sarama.ConsumerMessage{Offset: offset, Value: []byte(fmt.Sprintf("%d", offset))}
+	}
+	return messages
+}
+
+func TestSupervisedConsumerBacksOff(t *testing.T) {
+	first := newTestMessages(0, 2)
+	close(first)
+	fc := &flakyConnect{
+		consumers: []Consumer{&mockPartitionConsumer{messages: first}, &mockPartitionConsumer{messages: newTestMessages(2, 4)}},
+		failures:  3,
+	}
+
+	sc, err := newSupervisedConsumer(testConf, sarama.OffsetOldest, fc.connect, nil, nil)
+	if err != nil {
+		t.Fatal("Failed to create the consumer:", err)
+	}
+	defer testClose(t, sc)
+	expectOffsets(t, sc, 0, 4)
+
+	expected := []int64{sarama.OffsetOldest, 2, 2, 2, 2}
+	if seeks := fc.attempts(); fmt.Sprint(seeks) != fmt.Sprint(expected) {
+		t.Fatalf("Expected the connection attempts to seek %v, got %v", expected, seeks)
+	}
+}
+
+func TestSupervisedConsumerCloseWhileReconnecting(t *testing.T) {
+	lost := newTestMessages(0, 0)
+	close(lost)
+	fc := &flakyConnect{consumers: []Consumer{&mockPartitionConsumer{messages: lost}}}
+
+	conf := *testConf
+	conf.Kafka.Consumer.Retry = config.ConsumerRetry{Backoff: time.Hour, MaxBackoff: time.Hour}
+	sc, err := newSupervisedConsumer(&conf, sarama.OffsetOldest, fc.connect, nil, nil)
+	if err != nil {
+		t.Fatal("Failed to create the consumer:", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sc.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close should not wait for the backoff to expire")
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/tlsutil"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second // Bounds the check of each broker's TLS handshake
+	rejectionWait    = time.Second      // How long to wait, after the handshake, for a broker to reject the client certificate
+)
+
+// newTLSConfig builds the config of the connections to the brokers from conf, returning nil if TLS is not enabled
+func newTLSConfig(conf config.TLS) (*tls.Config, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(conf.Certificate, conf.PrivateKey, conf.RootCAs, "Kafka")
+	if err != nil {
+		return nil, err
+	}
+	if conf.InsecureSkipVerify {
+		logger.Warning("Kafka.TLS.InsecureSkipVerify is set, the certificates and hostnames of the Kafka brokers will not be verified")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return tlsConfig, nil
+}
+
+// checkHandshake returns the error of the first broker to fail the TLS handshake
+// Brokers which cannot be reached are skipped, as the caller retries those, but a rejected handshake will not succeed on retry
+func checkHandshake(brokers []string, tlsConfig *tls.Config) error {
+	for _, addr := range brokers {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: handshakeTimeout}, "tcp", addr, tlsConfig)
+		if err == nil {
+			// Depending on the protocol version, a broker may only reject the client certificate once the client has finished its side
+			conn.SetReadDeadline(time.Now().Add(rejectionWait))
+			_, err = conn.Read(make([]byte, 1))
+			conn.Close()
+			if netErr, ok := err.(net.Error); err == nil || ok && netErr.Timeout() {
+				continue
+			}
+		}
+		if opErr, ok := err.(*net.OpError); ok && opErr.Op == "dial" {
+			continue
+		}
+		return fmt.Errorf("TLS handshake with Kafka broker %s failed: %s", addr, err)
+	}
+	return nil
+}
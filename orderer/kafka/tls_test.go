@@ -0,0 +1,301 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+func (tc *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{tc.cert.Raw}, PrivateKey: tc.key}
+}
+
+// newTestCert issues a certificate for hosts signed by parent, or a self-signed CA certificate if parent is nil, and writes it to dir
+func newTestCert(t *testing.T, dir, name string, parent *testCert, hosts ...string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	if err := ioutil.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+// startTLSListener accepts connections with the given config and completes their handshakes until closed
+func startTLSListener(t *testing.T, serverConfig *tls.Config) net.Listener {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := conn.(*tls.Conn).Handshake(); err != nil {
+					return
+				}
+				ioutil.ReadAll(conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+type tlsFixture struct {
+	dir    string
+	ca     *testCert
+	server *testCert
+	client *testCert
+}
+
+func newTLSFixture(t *testing.T) *tlsFixture {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := newTestCert(t, dir, "ca", nil)
+	return &tlsFixture{
+		dir:    dir,
+		ca:     ca,
+		server: newTestCert(t, dir, "server", ca, "127.0.0.1"),
+		client: newTestCert(t, dir, "client", ca),
+	}
+}
+
+func (f *tlsFixture) clientConf() config.TLS {
+	return config.TLS{
+		Enabled:     true,
+		Certificate: f.client.certFile,
+		PrivateKey:  f.client.keyFile,
+		RootCAs:     []string{f.ca.certFile},
+	}
+}
+
+func (f *tlsFixture) serverConfig(requireClientCert bool) *tls.Config {
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{f.server.tlsCertificate()}}
+	if requireClientCert {
+		serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		serverConfig.ClientCAs = x509.NewCertPool()
+		serverConfig.ClientCAs.AddCert(f.ca.cert)
+	}
+	return serverConfig
+}
+
+func TestTLSConfigDisabled(t *testing.T) {
+	tlsConfig, err := newTLSConfig(config.TLS{Certificate: "missing"})
+	if err != nil || tlsConfig != nil {
+		t.Fatalf("Expected no TLS config when TLS is disabled, got %v, %v", tlsConfig, err)
+	}
+
+	brokerConfig := newBrokerConfig(testConf)
+	if brokerConfig.Net.TLS.Enable {
+		t.Fatal("TLS should not be enabled by default")
+	}
+}
+
+func TestTLSConfigLoad(t *testing.T) {
+	f := newTLSFixture(t)
+	defer os.RemoveAll(f.dir)
+
+	conf := *testConf
+	conf.Kafka.TLS = f.clientConf()
+	brokerConfig := newBrokerConfig(&conf)
+	if !brokerConfig.Net.TLS.Enable {
+		t.Fatal("Expected TLS to be enabled on the broker config")
+	}
+	tlsConfig := brokerConfig.Net.TLS.Config
+	if tlsConfig.InsecureSkipVerify {
+		t.Fatal("Certificates must be verified unless explicitly disabled")
+	}
+	if len(tlsConfig.Certificates) != 1 || tlsConfig.RootCAs == nil {
+		t.Fatal("Expected the client certificate and root CA to be loaded")
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	f := newTLSFixture(t)
+	defer os.RemoveAll(f.dir)
+
+	missingKey := f.clientConf()
+	missingKey.PrivateKey = ""
+	badRoot := f.clientConf()
+	badRoot.RootCAs = []string{f.client.keyFile}
+	missingRoot := f.clientConf()
+	missingRoot.RootCAs = []string{filepath.Join(f.dir, "missing.crt")}
+
+	for name, conf := range map[string]config.TLS{
+		"missing key":  missingKey,
+		"bad root":     badRoot,
+		"missing root": missingRoot,
+	} {
+		if _, err := newTLSConfig(conf); err == nil {
+			t.Errorf("Expected an error for the %s", name)
+		}
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	f := newTLSFixture(t)
+	defer os.RemoveAll(f.dir)
+
+	otherHost := newTestCert(t, f.dir, "other", f.ca, "broker.example.com")
+
+	verified := startTLSListener(t, f.serverConfig(true))
+	defer verified.Close()
+	mismatched := startTLSListener(t, &tls.Config{Certificates: []tls.Certificate{otherHost.tlsCertificate()}})
+	defer mismatched.Close()
+	closed := startTLSListener(t, f.serverConfig(false))
+	closed.Close()
+
+	noClientCert := f.clientConf()
+	noClientCert.Certificate, noClientCert.PrivateKey = "", ""
+	untrusted := f.clientConf()
+	untrusted.RootCAs = nil
+	insecure := noClientCert
+	insecure.InsecureSkipVerify = true
+
+	testCases := []struct {
+		name   string
+		broker net.Listener
+		conf   config.TLS
+		fails  bool
+	}{
+		{"verified", verified, f.clientConf(), false},
+		{"client certificate rejected", verified, noClientCert, true},
+		{"unknown authority", verified, untrusted, true},
+		{"hostname mismatch", mismatched, f.clientConf(), true},
+		{"hostname mismatch skipped", mismatched, insecure, false},
+		{"unreachable", closed, f.clientConf(), false},
+	}
+
+	for _, tc := range testCases {
+		tlsConfig, err := newTLSConfig(tc.conf)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		err = checkHandshake([]string{tc.broker.Addr().String()}, tlsConfig)
+		if tc.fails && err == nil {
+			t.Errorf("%s: expected the handshake to fail", tc.name)
+		}
+		if !tc.fails && err != nil {
+			t.Errorf("%s: expected no error, got %s", tc.name, err)
+		}
+	}
+}
+
+func TestProducerTLSRejectedFailsFast(t *testing.T) {
+	f := newTLSFixture(t)
+	defer os.RemoveAll(f.dir)
+
+	broker := startTLSListener(t, f.serverConfig(true))
+	defer broker.Close()
+
+	conf := *testConf
+	conf.Kafka.Brokers = []string{broker.Addr().String()}
+	conf.Kafka.Retry = config.Retry{Period: time.Millisecond, Stop: time.Hour}
+	conf.Kafka.TLS = f.clientConf()
+	conf.Kafka.TLS.Certificate, conf.Kafka.TLS.PrivateKey = "", ""
+
+	done := make(chan interface{})
+	go func() {
+		defer func() { done <- recover() }()
+		newProducer(&conf, nil)
+	}()
+
+	select {
+	case r := <-done:
+		err, ok := r.(error)
+		if !ok || !strings.Contains(err.Error(), "TLS handshake") {
+			t.Fatalf("Expected the producer to fail with the handshake error, got %v", r)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("The producer should not retry a rejected handshake")
+	}
+}
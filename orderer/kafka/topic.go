@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+// checkTopic verifies that the topic exists with the configured number of partitions and at least the configured replication factor,
+// creating a missing topic with them unless Kafka.CreateTopic is false
+// The metadata of all topics is requested, as asking for the topic by name would have the brokers create it with their own defaults
+func checkTopic(conf *config.TopLevel, brokerConfig *sarama.Config) error {
+	addrs, err := resolveBrokers(conf)
+	if err != nil {
+		return err
+	}
+	metadata, err := fetchMetadata(addrs, brokerConfig)
+	if err != nil {
+		return err
+	}
+
+	create := fmt.Sprintf("create it with %d partitions and a replication factor of %d", conf.Kafka.Partitions, conf.Kafka.ReplicationFactor)
+	for _, topic := range metadata.Topics {
+		if topic.Name != conf.Kafka.Topic {
+			continue
+		}
+		if topic.Err != sarama.ErrNoError {
+			return fmt.Errorf("Failed to retrieve the metadata of Kafka topic %s: %s", topic.Name, topic.Err)
+		}
+		if len(topic.Partitions) != int(conf.Kafka.Partitions) {
+			return fmt.Errorf("Kafka topic %s has %d partitions but Kafka.Partitions is %d", topic.Name, len(topic.Partitions), conf.Kafka.Partitions)
+		}
+		for _, partition := range topic.Partitions {
+			if len(partition.Replicas) < int(conf.Kafka.ReplicationFactor) {
+				return fmt.Errorf("Partition %d of Kafka topic %s has %d replicas but Kafka.ReplicationFactor is %d", partition.ID, topic.Name, len(partition.Replicas), conf.Kafka.ReplicationFactor)
+			}
+		}
+		logger.Debugf("Kafka topic %s has the expected %d partitions", topic.Name, len(topic.Partitions))
+		return nil
+	}
+
+	if !conf.Kafka.CreateTopic {
+		return fmt.Errorf("Kafka topic %s does not exist and Kafka.CreateTopic is false, %s", conf.Kafka.Topic, create)
+	}
+	logger.Infof("Kafka topic %s does not exist, creating it", conf.Kafka.Topic)
+	if err := createTopic(metadata.Brokers, brokerConfig, conf.Kafka.Topic, conf.Kafka.Partitions, conf.Kafka.ReplicationFactor); err != nil {
+		return fmt.Errorf("%s, %s", err, create)
+	}
+	return nil
+}
+
+// fetchMetadata returns the metadata of all topics from the first broker to answer
+func fetchMetadata(brokers []string, brokerConfig *sarama.Config) (*sarama.MetadataResponse, error) {
+	var err error
+	for _, addr := range brokers {
+		broker := sarama.NewBroker(addr)
+		if err = broker.Open(brokerConfig); err != nil {
+			continue
+		}
+		var metadata *sarama.MetadataResponse
+		metadata, err = broker.GetMetadata(&sarama.MetadataRequest{})
+		broker.Close()
+		if err == nil {
+			return metadata, nil
+		}
+	}
+	return nil, fmt.Errorf("Failed to retrieve the Kafka topic metadata: %v", err)
+}
+
+// partitionRange returns the offset of the oldest message retained by the partition of conf, and the offset the next
+// message posted to it will have
+func partitionRange(conf *config.TopLevel) (int64, int64, error) {
+	addrs, err := resolveBrokers(conf)
+	if err != nil {
+		return 0, 0, err
+	}
+	client, err := sarama.NewClient(addrs, newBrokerConfig(conf))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer client.Close()
+
+	oldest, err := client.GetOffset(conf.Kafka.Topic, conf.Kafka.PartitionID, sarama.OffsetOldest)
+	if err != nil {
+		return 0, 0, err
+	}
+	newest, err := client.GetOffset(conf.Kafka.Topic, conf.Kafka.PartitionID, sarama.OffsetNewest)
+	if err != nil {
+		return 0, 0, err
+	}
+	return oldest, newest, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+// startMetadataBroker returns a mock broker which reports the topic with the given number of replicas for each partition
+func startMetadataBroker(t *testing.T, topic string, replicas ...int) *sarama.MockBroker {
+	mockBroker := sarama.NewMockBroker(t, brokerID)
+	metadata := &sarama.MetadataResponse{}
+	metadata.AddBroker(mockBroker.Addr(), brokerID)
+	metadata.AddTopic("other", sarama.ErrNoError)
+	if topic != "" {
+		metadata.AddTopic(topic, sarama.ErrNoError)
+	}
+	for partition, count := range replicas {
+		ids := make([]int32, count)
+		metadata.AddTopicPartition(topic, int32(partition), brokerID, ids, ids, sarama.ErrNoError)
+	}
+	mockBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockWrapper(metadata),
+	})
+	return mockBroker
+}
+
+func TestCheckTopic(t *testing.T) {
+	testCases := []struct {
+		name     string
+		topic    string
+		replicas []int
+		err      string
+	}{
+		{"exists", testConf.Kafka.Topic, []int{3, 3}, ""},
+		{"missing", "", nil, "does not exist"},
+		{"partition mismatch", testConf.Kafka.Topic, []int{3}, "has 1 partitions"},
+		{"under replicated", testConf.Kafka.Topic, []int{3, 1}, "has 1 replicas"},
+	}
+
+	for _, tc := range testCases {
+		mockBroker := startMetadataBroker(t, tc.topic, tc.replicas...)
+		conf := *testConf
+		conf.Kafka.Brokers = []string{"127.0.0.1:1", mockBroker.Addr()}
+		conf.Kafka.Partitions = 2
+		conf.Kafka.ReplicationFactor = 2
+
+		err := checkTopic(&conf, newBrokerConfig(&conf))
+		mockBroker.Close()
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s: expected the topic to be accepted, got %s", tc.name, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
+
+// createdTopic is a topic which a mock controller was asked to create
+type createdTopic struct {
+	name              string
+	partitions        int32
+	replicationFactor int16
+}
+
+// startController starts a mock controller which answers each CreateTopics request with code, passing the topic to created
+// Any other request closes the connection, as a broker older than 0.10.1.0 would on a CreateTopics request
+func startController(t *testing.T, code sarama.KError, created chan<- createdTopic) net.Listener {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go serveController(conn, code, created)
+		}
+	}()
+	return lis
+}
+
+func serveController(conn net.Conn, code sarama.KError, created chan<- createdTopic) {
+	defer conn.Close()
+	size := make([]byte, 4)
+	if _, err := io.ReadFull(conn, size); err != nil {
+		return
+	}
+	req := &responseDecoder{buf: make([]byte, binary.BigEndian.Uint32(size))}
+	if _, err := io.ReadFull(conn, req.buf); err != nil {
+		return
+	}
+	if key := req.getInt16(); key != createTopicsKey {
+		return
+	}
+	req.getInt16()
+	correlationID := req.getInt32()
+	req.getString()
+	req.getInt32()
+	topic := createdTopic{name: req.getString(), partitions: req.getInt32(), replicationFactor: req.getInt16()}
+	if req.err != nil {
+		return
+	}
+	if code == sarama.ErrNoError {
+		created <- topic
+	}
+
+	res := &requestEncoder{}
+	res.putInt32(correlationID)
+	res.putInt32(1)
+	res.putString(topic.name)
+	res.putInt16(int16(code))
+	binary.BigEndian.PutUint32(size, uint32(res.Len()))
+	conn.Write(append(size, res.Bytes()...))
+}
+
+// startClusterBroker returns a mock broker which reports no topic but other, and the given brokers as the cluster
+func startClusterBroker(t *testing.T, brokers ...string) *sarama.MockBroker {
+	mockBroker := sarama.NewMockBroker(t, brokerID)
+	metadata := &sarama.MetadataResponse{}
+	for i, addr := range brokers {
+		metadata.AddBroker(addr, brokerID+1+int32(i))
+	}
+	metadata.AddTopic("other", sarama.ErrNoError)
+	mockBroker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockWrapper(metadata),
+	})
+	return mockBroker
+}
+
+func TestCreateTopic(t *testing.T) {
+	created := make(chan createdTopic, 1)
+	follower := startController(t, errNotController, created)
+	defer follower.Close()
+	controller := startController(t, sarama.ErrNoError, created)
+	defer controller.Close()
+	mockBroker := startClusterBroker(t, follower.Addr().String(), controller.Addr().String())
+	defer mockBroker.Close()
+
+	conf := *testConf
+	conf.Kafka.Brokers = []string{mockBroker.Addr()}
+	conf.Kafka.Partitions = 2
+	conf.Kafka.ReplicationFactor = 3
+	conf.Kafka.CreateTopic = true
+	if err := checkTopic(&conf, newBrokerConfig(&conf)); err != nil {
+		t.Fatal("Expected the missing topic to be created, got", err)
+	}
+
+	select {
+	case topic := <-created:
+		if topic != (createdTopic{conf.Kafka.Topic, 2, 3}) {
+			t.Fatalf("Expected topic %s to be created with 2 partitions and 3 replicas, got %+v", conf.Kafka.Topic, topic)
+		}
+	default:
+		t.Fatal("Expected the controller to have created the topic")
+	}
+}
+
+func TestCreateTopicFails(t *testing.T) {
+	refusing := startController(t, sarama.KError(38), nil)
+	defer refusing.Close()
+	// A broker which closes the connection on CreateTopics predates it
+	unsupported, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	defer unsupported.Close()
+	go func() {
+		for {
+			conn, err := unsupported.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	testCases := []struct {
+		name    string
+		brokers []string
+		create  bool
+		err     string
+	}{
+		{"creation disabled", []string{refusing.Addr().String()}, false, "Kafka.CreateTopic is false"},
+		{"refused", []string{refusing.Addr().String()}, true, "refused to create topic"},
+		{"unsupported", []string{unsupported.Addr().String()}, true, "0.10.1.0 or later"},
+	}
+	for _, tc := range testCases {
+		mockBroker := startClusterBroker(t, tc.brokers...)
+		conf := *testConf
+		conf.Kafka.Brokers = []string{mockBroker.Addr()}
+		conf.Kafka.CreateTopic = tc.create
+		err := checkTopic(&conf, newBrokerConfig(&conf))
+		mockBroker.Close()
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
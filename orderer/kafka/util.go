@@ -18,20 +18,16 @@ package kafka
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/golang/protobuf/proto"
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/blocksigner"
 	"github.com/hyperledger/fabric/orderer/config"
 	"golang.org/x/crypto/sha3"
 )
 
-const (
-	ackOutOfRangeError    = "ACK out of range"
-	seekOutOfRangeError   = "Seek out of range"
-	windowOutOfRangeError = "Window out of range"
-)
-
 func hashBlock(block *ab.Block) (hash, data []byte) {
 	data, err := proto.Marshal(block)
 	if err != nil {
@@ -43,9 +39,38 @@ func hashBlock(block *ab.Block) (hash, data []byte) {
 	return
 }
 
+// newSigner loads the identity with which the blocks of the chains are signed, or returns nil if none is configured
+func newSigner(conf *config.TopLevel) *blocksigner.Signer {
+	signer, err := blocksigner.New(conf.General.Signer.Certificate, conf.General.Signer.PrivateKey)
+	if err != nil {
+		panic(err)
+	}
+	return signer
+}
+
 func newBrokerConfig(conf *config.TopLevel) *sarama.Config {
 	brokerConfig := sarama.NewConfig()
-	brokerConfig.Version = conf.Kafka.Version
+
+	version, err := parseVersion(conf.Kafka.Version)
+	if err != nil {
+		panic(err)
+	}
+	brokerConfig.Version = version
+	brokerConfig.Metadata.RefreshFrequency = conf.Kafka.MetadataRefreshInterval
+
+	tlsConfig, err := newTLSConfig(conf.Kafka.TLS)
+	if err != nil {
+		panic(err)
+	}
+	brokerConfig.Net.TLS.Enable = tlsConfig != nil
+	brokerConfig.Net.TLS.Config = tlsConfig
+
+	// The mechanism was validated when the config was loaded, PLAIN is the only one the client implements
+	if conf.Kafka.SASL.Enabled {
+		brokerConfig.Net.SASL.Enable = true
+		brokerConfig.Net.SASL.User = conf.Kafka.SASL.User
+		brokerConfig.Net.SASL.Password = conf.Kafka.SASL.Password
+	}
 	return brokerConfig
 }
 
@@ -56,6 +81,15 @@ func newMsg(payload []byte, topic string) *sarama.ProducerMessage {
 	}
 }
 
+// newTimestampedMsg stamps the message with the time it was sent, for brokers whose version supports timestamps
+func newTimestampedMsg(payload []byte, topic string, version sarama.KafkaVersion) *sarama.ProducerMessage {
+	msg := newMsg(payload, topic)
+	if supportsTimestamps(version) {
+		msg.Timestamp = time.Now()
+	}
+	return msg
+}
+
 func newOffsetReq(conf *config.TopLevel, seek int64) *sarama.OffsetRequest {
 	req := &sarama.OffsetRequest{}
 	// If seek == -1, ask for the for the offset assigned to next new message
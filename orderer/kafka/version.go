@@ -0,0 +1,199 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+// defaultVersion is the Kafka protocol version used when the config omits Kafka.Version
+const defaultVersion = "0.9.0.1"
+
+// supportedVersions are the protocol versions known to the Kafka client
+var supportedVersions = map[string]sarama.KafkaVersion{
+	"0.8.2.0":  sarama.V0_8_2_0,
+	"0.8.2.1":  sarama.V0_8_2_1,
+	"0.8.2.2":  sarama.V0_8_2_2,
+	"0.9.0.0":  sarama.V0_9_0_0,
+	"0.9.0.1":  sarama.V0_9_0_1,
+	"0.10.0.0": sarama.V0_10_0_0,
+}
+
+// parseVersion returns the protocol version named by the config, or the default if it is empty
+func parseVersion(version string) (sarama.KafkaVersion, error) {
+	if version == "" {
+		version = defaultVersion
+	}
+	if v, ok := supportedVersions[strings.TrimSpace(version)]; ok {
+		return v, nil
+	}
+
+	supported := make([]string, 0, len(supportedVersions))
+	for name := range supportedVersions {
+		supported = append(supported, name)
+	}
+	sort.Strings(supported)
+	return sarama.KafkaVersion{}, fmt.Errorf("Kafka.Version %s is not supported, expected one of %s", version, strings.Join(supported, ", "))
+}
+
+// supportsTimestamps reports whether messages sent to brokers of the given version carry a timestamp
+// Record batches, which arrive with 0.11, are not known to the Kafka client and so cannot be enabled
+func supportsTimestamps(version sarama.KafkaVersion) bool {
+	return version.IsAtLeast(sarama.V0_10_0_0)
+}
+
+// versionName returns the name of a protocol version known to the Kafka client
+func versionName(version sarama.KafkaVersion) string {
+	for name, v := range supportedVersions {
+		if v == version {
+			return name
+		}
+	}
+	return fmt.Sprint(version)
+}
+
+// versionNumber encodes a protocol version a.b.c.d as the number abbccdd, for the brokers.version gauge
+func versionNumber(version sarama.KafkaVersion) int64 {
+	var number int64
+	for _, part := range strings.Split(versionName(version), ".") {
+		n, _ := strconv.ParseInt(part, 10, 64)
+		number = number*100 + n
+	}
+	return number
+}
+
+// versionProbeTimeout bounds each probe of a broker, brokers close the connection on requests they do not know, but
+// an unanswered request is taken the same way
+var versionProbeTimeout = 5 * time.Second
+
+// The keys of the requests which tell the protocol version of a broker apart, neither request has a body
+const (
+	apiVersionsKey int16 = 18 // Answered from 0.10.0.0
+	listGroupsKey  int16 = 16 // Answered from 0.9.0.0
+)
+
+// negotiateVersion probes the brokers, returning the configured version if each broker which answers is at least as
+// recent, and otherwise the newest version known to the client which the oldest broker supports
+// Protocol versions which are told apart by no request are taken as equal, if Kafka.StrictVersion is set older brokers
+// are an error instead
+func negotiateVersion(conf *config.TopLevel, configured sarama.KafkaVersion) (sarama.KafkaVersion, error) {
+	addrs, err := resolveBrokers(conf)
+	if err != nil {
+		return configured, err
+	}
+	tlsConfig, err := newTLSConfig(conf.Kafka.TLS)
+	if err != nil {
+		return configured, err
+	}
+
+	var oldest *sarama.KafkaVersion
+	var oldestAddr string
+	for _, addr := range addrs {
+		version, err := probeVersion(addr, tlsConfig)
+		if err != nil {
+			logger.Warningf("Could not probe the protocol version of Kafka broker %s: %s", addr, err)
+			continue
+		}
+		logger.Debugf("Kafka broker %s supports protocol version %s", addr, versionName(version))
+		if oldest == nil || !version.IsAtLeast(*oldest) {
+			oldest, oldestAddr = &version, addr
+		}
+	}
+	if oldest == nil {
+		logger.Warningf("Could not probe the protocol version of any Kafka broker, using Kafka.Version %s", versionName(configured))
+		return configured, nil
+	}
+	if oldest.IsAtLeast(configured) {
+		logger.Infof("The Kafka brokers support the configured protocol version %s", versionName(configured))
+		return configured, nil
+	}
+
+	if conf.Kafka.StrictVersion {
+		return configured, fmt.Errorf("Kafka.Version is %s, but broker %s only supports %s and Kafka.StrictVersion is set", versionName(configured), oldestAddr, versionName(*oldest))
+	}
+	logger.Warningf("Kafka.Version is %s, but broker %s only supports %s, falling back to %s, set Kafka.Version to match the oldest broker", versionName(configured), oldestAddr, versionName(*oldest), versionName(*oldest))
+	return *oldest, nil
+}
+
+// probeVersion returns the newest protocol version known to the client which the broker at addr supports
+func probeVersion(addr string, tlsConfig *tls.Config) (sarama.KafkaVersion, error) {
+	for _, probe := range []struct {
+		key     int16
+		version sarama.KafkaVersion
+	}{
+		{apiVersionsKey, sarama.V0_10_0_0},
+		{listGroupsKey, sarama.V0_9_0_1},
+	} {
+		answered, err := probeRequest(addr, tlsConfig, probe.key)
+		if err != nil {
+			return sarama.KafkaVersion{}, err
+		}
+		if answered {
+			return probe.version, nil
+		}
+	}
+	return sarama.V0_8_2_2, nil
+}
+
+// probeRequest sends a request without a body on a connection of its own, and returns whether the broker answered it
+// The request is sent ahead of any SASL handshake, which the brokers permit for ApiVersions
+func probeRequest(addr string, tlsConfig *tls.Config, key int16) (bool, error) {
+	dialer := &net.Dialer{Timeout: versionProbeTimeout}
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(versionProbeTimeout))
+
+	const clientID = "orderer-version-probe"
+	const correlationID = 1
+	// The size, api key, api version, correlation id, and client id of the request header
+	request := make([]byte, 4+2+2+4+2+len(clientID))
+	binary.BigEndian.PutUint32(request, uint32(len(request)-4))
+	binary.BigEndian.PutUint16(request[4:], uint16(key))
+	binary.BigEndian.PutUint32(request[8:], correlationID)
+	binary.BigEndian.PutUint16(request[12:], uint16(len(clientID)))
+	copy(request[14:], clientID)
+	if _, err := conn.Write(request); err != nil {
+		return false, nil
+	}
+
+	// The size and correlation id of the response header suffice, the body is not needed
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return false, nil
+	}
+	return binary.BigEndian.Uint32(header[4:]) == correlationID, nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hyperledger/fabric/orderer/kafka/mocks"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected sarama.KafkaVersion
+	}{
+		{"", sarama.V0_9_0_1},
+		{"0.8.2.0", sarama.V0_8_2_0},
+		{"0.9.0.1", sarama.V0_9_0_1},
+		{" 0.10.0.0 ", sarama.V0_10_0_0},
+	}
+	for _, tc := range testCases {
+		version, err := parseVersion(tc.name)
+		if err != nil {
+			t.Fatalf("Failed to parse version %q: %s", tc.name, err)
+		}
+		if version != tc.expected {
+			t.Fatalf("Parsed %q as %v, expected %v", tc.name, version, tc.expected)
+		}
+	}
+}
+
+func TestParseUnsupportedVersion(t *testing.T) {
+	for _, name := range []string{"0.11.0.0", "0.8.1", "latest"} {
+		if _, err := parseVersion(name); err == nil {
+			t.Fatalf("Expected version %q to be rejected", name)
+		}
+	}
+}
+
+func TestBrokerConfigVersion(t *testing.T) {
+	conf := *testConf
+	conf.Kafka.Version = "0.10.0.0"
+	if version := newBrokerConfig(&conf).Version; version != sarama.V0_10_0_0 {
+		t.Fatalf("Expected the broker config to use version 0.10.0.0, got %v", version)
+	}
+
+	conf.Kafka.Version = ""
+	if version := newBrokerConfig(&conf).Version; version != sarama.V0_9_0_1 {
+		t.Fatalf("Expected the broker config to default to version %s, got %v", defaultVersion, version)
+	}
+}
+
+func TestMessageTimestamps(t *testing.T) {
+	if msg := newTimestampedMsg(nil, "test", sarama.V0_9_0_1); !msg.Timestamp.IsZero() {
+		t.Fatal("Messages should not be timestamped before 0.10.0.0")
+	}
+	if msg := newTimestampedMsg(nil, "test", sarama.V0_10_0_0); time.Since(msg.Timestamp) > time.Minute {
+		t.Fatal("Messages should be timestamped from 0.10.0.0")
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	defer func(timeout time.Duration) { versionProbeTimeout = timeout }(versionProbeTimeout)
+	versionProbeTimeout = 100 * time.Millisecond
+
+	testCases := []struct {
+		name       string
+		brokers    sarama.KafkaVersion
+		configured sarama.KafkaVersion
+		strict     bool
+		expected   sarama.KafkaVersion
+		fails      bool
+	}{
+		{"newer brokers", sarama.V0_10_0_0, sarama.V0_9_0_1, false, sarama.V0_9_0_1, false},
+		{"matching brokers", sarama.V0_9_0_0, sarama.V0_9_0_1, true, sarama.V0_9_0_1, false},
+		{"older brokers", sarama.V0_9_0_0, sarama.V0_10_0_0, false, sarama.V0_9_0_1, false},
+		{"oldest brokers", sarama.V0_8_2_0, sarama.V0_10_0_0, false, sarama.V0_8_2_2, false},
+		{"older brokers with strict version", sarama.V0_9_0_0, sarama.V0_10_0_0, true, sarama.V0_10_0_0, true},
+	}
+	for _, tc := range testCases {
+		broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+		broker.SetVersion(tc.brokers)
+		conf := mocks.NewTestConfig(broker)
+		conf.Kafka.StrictVersion = tc.strict
+		version, err := negotiateVersion(conf, tc.configured)
+		broker.Close()
+		if (err != nil) != tc.fails {
+			t.Fatalf("%s: expected failure %t, got %v", tc.name, tc.fails, err)
+		}
+		if version != tc.expected {
+			t.Fatalf("%s: expected version %s, got %s", tc.name, versionName(tc.expected), versionName(version))
+		}
+	}
+}
+
+func TestNewClampsVersion(t *testing.T) {
+	defer func(timeout time.Duration) { versionProbeTimeout = timeout }(versionProbeTimeout)
+	versionProbeTimeout = 100 * time.Millisecond
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	broker.SetVersion(sarama.V0_9_0_0)
+	conf := mocks.NewTestConfig(broker)
+	conf.Kafka.Version = "0.10.0.0"
+	registry := gometrics.NewRegistry()
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(testChainID, testGenesisBlock)
+
+	o := New(conf, lf, testChainID, registry)
+	defer o.Teardown()
+	if conf.Kafka.Version != "0.9.0.1" {
+		t.Fatalf("Expected the chains to use the version of the brokers, got %s", conf.Kafka.Version)
+	}
+	if version := registry.Get("brokers.version").(gometrics.Gauge).Value(); version != 90001 {
+		t.Fatalf("Expected the negotiated version to be exported as 90001, got %d", version)
+	}
+}
+
+func TestNewStrictVersion(t *testing.T) {
+	defer func(timeout time.Duration) { versionProbeTimeout = timeout }(versionProbeTimeout)
+	versionProbeTimeout = 100 * time.Millisecond
+	broker := mocks.NewBroker(t, testConf.Kafka.Topic, 1)
+	defer broker.Close()
+	broker.SetVersion(sarama.V0_9_0_0)
+	conf := mocks.NewTestConfig(broker)
+	conf.Kafka.Version = "0.10.0.0"
+	conf.Kafka.StrictVersion = true
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(testChainID, testGenesisBlock)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected the orderer to refuse brokers older than Kafka.Version")
+		}
+	}()
+	New(conf, lf, testChainID, nil)
+}
@@ -24,13 +24,26 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"time"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/blocksigner"
 	"github.com/hyperledger/fabric/orderer/common/bootstrap"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap/file"
 	"github.com/hyperledger/fabric/orderer/common/bootstrap/static"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/clock"
 	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/health"
+	"github.com/hyperledger/fabric/orderer/common/metrics"
 	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
 	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/gateway"
 	"github.com/hyperledger/fabric/orderer/kafka"
 	"github.com/hyperledger/fabric/orderer/rawledger"
 	"github.com/hyperledger/fabric/orderer/rawledger/fileledger"
@@ -41,99 +54,230 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/op/go-logging"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
+var logger = logging.MustGetLogger("orderer/main")
+
+// drainTimeout bounds the wait for the calls in flight once the orderer has torn down, so that a client cannot hold up the exit
+const drainTimeout = 5 * time.Second
+
+// flags holds the command line options, which apply whichever consenter is configured
+type flags struct {
+	loglevel          string
+	verbose           bool
+	startOffset       int64
+	chainStartOffsets string
+	exportGenesis     string
+}
+
+func parseFlags() *flags {
+	f := &flags{}
+	flag.StringVar(&f.loglevel, "loglevel", "info",
+		"Set the logging level for the orderer. (Suggested values: info, debug)")
+	flag.BoolVar(&f.verbose, "verbose", false,
+		"Turn on logging for the Kafka library. (Default: \"false\")")
+	flag.Int64Var(&f.startOffset, "kafka-start-offset", -1,
+		"Rebuild the default chain from this offset of its partition for this run only, rather than resume after its last block.")
+	flag.StringVar(&f.chainStartOffsets, "kafka-chain-start-offsets", "",
+		"Rebuild chains for this run only, from entries of the form <chain ID in hex>:<offset> separated by commas.")
+	flag.StringVar(&f.exportGenesis, "export-genesis", "",
+		"Write a genesis block restating the configuration of the default chain in the file ledger at FileLedger.Location to this file, then exit.")
+	flag.Parse()
+	return f
+}
+
 func main() {
+	f := parseFlags()
 	conf := config.Load()
 
+	level, err := logging.LogLevel(strings.ToUpper(f.loglevel))
+	if err != nil {
+		panic(fmt.Errorf("Invalid log level %s: %s", f.loglevel, err))
+	}
+	logging.SetLevel(level, "")
+	kafka.SetLogLevel(f.loglevel)
+	if f.verbose {
+		sarama.Logger = log.New(os.Stdout, "[sarama] ", log.Lshortfile)
+	}
+
+	if f.exportGenesis != "" {
+		if err := exportGenesis(conf, f.exportGenesis); err != nil {
+			panic(fmt.Errorf("Error exporting the genesis block: %s", err))
+		}
+		return
+	}
+
 	switch conf.General.OrdererType {
 	case "solo":
 		launchSolo(conf)
 	case "kafka":
-		launchKafka(conf)
+		launchKafka(conf, f)
 	default:
 		panic("Invalid orderer type specified in config")
 	}
 }
 
-// XXX This crypto helper is a stand in until we have a real crypto handler
-// it considers all signatures to be valid
-type xxxCryptoHelper struct{}
+// retrieveConfiguration returns the configuration transaction in force on the chain, reading only the tail block and
+// the block its metadata points to, unless the chain was written before the pointer was recorded and must be scanned
+func retrieveConfiguration(rl rawledger.Reader) (*ab.ConfigurationEnvelope, error) {
+	number, err := configtx.LastConfigurationNumber(rl)
+	if err != nil {
+		return nil, err
+	}
+	block, status := rl.GetBlock(number)
+	if status != ab.Status_SUCCESS {
+		return nil, fmt.Errorf("Error reading the last configuration block %d: %v", number, status)
+	}
+	configTx := configtx.BlockConfiguration(block)
+	if configTx == nil {
+		return nil, fmt.Errorf("Block %d is recorded as the last configuration block, but carries no configuration transaction", number)
+	}
+	return configTx, nil
+}
 
-func (xxx xxxCryptoHelper) VerifySignature(msg []byte, ids []byte, sigs []byte) bool {
-	return true
+// genesisChainID returns the chain ID from the configuration transaction of a genesis block
+func genesisChainID(genesisBlock *ab.Block) []byte {
+	configTx := configtx.BlockConfiguration(genesisBlock)
+	if configTx == nil {
+		panic("Genesis block must contain exactly one configuration transaction")
+	}
+	return configTx.ChainID
 }
 
-func init() {
-	logging.SetLevel(logging.DEBUG, "")
+func bootstrapConfigManager(conf *config.TopLevel, lastConfigTx *ab.ConfigurationEnvelope) (configtx.Manager, policies.Manager) {
+	configManager, policyManager, err := newConfigManager(conf, lastConfigTx)
+	if err != nil {
+		panic(err)
+	}
+	return configManager, policyManager
 }
 
-func retrieveConfiguration(rl rawledger.Reader) *ab.ConfigurationEnvelope {
-	var lastConfigTx *ab.ConfigurationEnvelope
+// newConfigManager creates the configuration manager of a chain whose configuration in force is lastConfigTx, and its policy manager
+func newConfigManager(conf *config.TopLevel, lastConfigTx *ab.ConfigurationEnvelope) (configtx.Manager, policies.Manager, error) {
+	// Signatures are verified, so that only the identities named by a policy may satisfy it
+	policyManager := policies.NewManagerImpl(cauthdsl.ECDSAHelper{})
+	policyManager.SetDefaultDeny(conf.General.Policies.DefaultDeny)
+	cache := policies.NewEvaluationCache(int(conf.General.Policies.CacheSize), conf.General.Policies.CacheTTL, clock.Real{}, metrics.NewSubsystemRegistry(metrics.Registry, "policies"))
+	policyManager.SetCache(cache)
+	registry := configtx.NewRegistry()
+	registry.SetValiditySkew(conf.General.ConfigValiditySkew)
+	if err := registry.Register(ab.Configuration_Policy, policyManager); err != nil {
+		return nil, nil, err
+	}
+	limits := sharedconfig.Limits{
+		BatchSize:     uint32(conf.General.ConfigLimits.BatchSize),
+		BatchMaxBytes: uint32(conf.General.ConfigLimits.BatchMaxBytes),
+		BatchTimeout:  conf.General.ConfigLimits.BatchTimeout,
+	}
+	if err := registry.Register(ab.Configuration_Orderer, sharedconfig.NewHandler(limits)); err != nil {
+		return nil, nil, err
+	}
 
-	it, _ := rl.Iterator(ab.SeekInfo_OLDEST, 0)
-	// Iterate over the blockchain, looking for config transactions, track the most recent one encountered
-	// this will be the transaction which is returned
-	for {
-		select {
-		case <-it.ReadyChan():
-			block, status := it.Next()
-			if status != ab.Status_SUCCESS {
-				panic(fmt.Errorf("Error parsing blockchain at startup: %v", status))
-			}
-			// ConfigTxs should always be by themselves
-			if len(block.Messages) != 1 {
-				continue
-			}
+	configManager, err := registry.NewManager(lastConfigTx, policyManager)
+	if err != nil {
+		return nil, nil, err
+	}
+	return configManager, policyManager, nil
+}
 
-			maybeConfigTx := &ab.ConfigurationEnvelope{}
+// chainConfigs resolves the configuration and policy managers of each chain, every chain other than the default is bootstrapped from
+// the configuration in its ledger when first resolved, the policy manager of a chain is a handler of its configuration manager, so
+// that the configuration transactions applied by the chain's committer keep the policies resolved for the chain current
+type chainConfigs struct {
+	conf           *config.TopLevel
+	lf             rawledger.Factory
+	defaultChainID []byte
+	lock           sync.Mutex // Guards chains
+	chains         map[string]*chainConfig
+}
 
-			err := proto.Unmarshal(block.Messages[0].Data, maybeConfigTx)
+// chainConfig holds the managers of a chain, config is nil if the configuration in the chain's ledger could not be read
+type chainConfig struct {
+	config   configtx.Manager
+	policies policies.Manager
+}
 
-			if err == nil {
-				lastConfigTx = maybeConfigTx
-			}
-		default:
-			return lastConfigTx
-		}
+func newChainConfigs(conf *config.TopLevel, lf rawledger.Factory, defaultChainID []byte, defaultConfig configtx.Manager, defaultPolicies policies.Manager) *chainConfigs {
+	return &chainConfigs{
+		conf:           conf,
+		lf:             lf,
+		defaultChainID: defaultChainID,
+		chains:         map[string]*chainConfig{string(defaultChainID): &chainConfig{config: defaultConfig, policies: defaultPolicies}},
 	}
 }
 
-func bootstrapConfigManager(lastConfigTx *ab.ConfigurationEnvelope) configtx.Manager {
-	policyManager := policies.NewManagerImpl(xxxCryptoHelper{})
-	configHandlerMap := make(map[ab.Configuration_ConfigurationType]configtx.Handler)
-	for ctype := range ab.Configuration_ConfigurationType_name {
-		rtype := ab.Configuration_ConfigurationType(ctype)
-		switch rtype {
-		case ab.Configuration_Policy:
-			configHandlerMap[rtype] = policyManager
-		default:
-			configHandlerMap[rtype] = configtx.NewBytesHandler()
-		}
+// chain returns the managers of a chain, or false if the chain does not exist
+func (cc *chainConfigs) chain(chainID []byte) (*chainConfig, bool) {
+	if len(chainID) == 0 {
+		chainID = cc.defaultChainID
 	}
 
-	configManager, err := configtx.NewConfigurationManager(lastConfigTx, policyManager, configHandlerMap)
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	if chain, ok := cc.chains[string(chainID)]; ok {
+		return chain, true
+	}
+	rl, ok := cc.lf.Get(chainID)
+	if !ok {
+		return nil, false
+	}
+	chain := &chainConfig{}
+	lastConfigTx, err := retrieveConfiguration(rl)
+	if err == nil {
+		chain.config, chain.policies, err = newConfigManager(cc.conf, lastConfigTx)
+	}
 	if err != nil {
-		panic(err)
+		// Every policy of the chain is missing, and so decided by the default
+		logger.Errorf("Could not read the configuration of chain %x: %s", chainID, err)
+		empty := policies.NewManagerImpl(cauthdsl.ECDSAHelper{})
+		empty.SetDefaultDeny(cc.conf.General.Policies.DefaultDeny)
+		chain.policies = empty
 	}
-	return configManager
+	cc.chains[string(chainID)] = chain
+	return chain, true
 }
 
-func launchSolo(conf *config.TopLevel) {
-	grpcServer := grpc.NewServer()
+// resolveConfig returns the configuration manager of a chain, or false if the chain does not exist or its configuration could not be read
+func (cc *chainConfigs) resolveConfig(chainID []byte) (configtx.Manager, bool) {
+	chain, ok := cc.chain(chainID)
+	if !ok || chain.config == nil {
+		return nil, false
+	}
+	return chain.config, true
+}
 
-	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", conf.General.ListenAddress, conf.General.ListenPort))
-	if err != nil {
-		fmt.Println("Failed to listen:", err)
-		return
+// resolvePolicies returns the policy manager of a chain, or false if the chain does not exist
+func (cc *chainConfigs) resolvePolicies(chainID []byte) (policies.Manager, bool) {
+	chain, ok := cc.chain(chainID)
+	if !ok {
+		return nil, false
+	}
+	return chain.policies, true
+}
+
+// refreshMaxBytes limits the size of broadcast messages to that configured by the chain's batch size, which may only lower the
+// limit of the local configuration, as gRPC refuses larger messages regardless
+func refreshMaxBytes(conf *config.TopLevel, configManager configtx.Manager, maxBytesRule *broadcastfilter.MaxBytesRule) {
+	maxBytes := int(conf.General.MaxRecvMsgSize)
+	if batchSize, ok := solo.BatchSizeConfig(configManager); ok && batchSize.MaxMessageBytes > 0 && int(batchSize.MaxMessageBytes) < maxBytes {
+		maxBytes = int(batchSize.MaxMessageBytes)
 	}
+	maxBytesRule.SetMaxBytes(maxBytes)
+}
 
+// bootstrapGenesisBlock retrieves the genesis block from the configured bootstrapping mechanism
+func bootstrapGenesisBlock(conf *config.TopLevel) *ab.Block {
 	var bootstrapper bootstrap.Helper
 
 	// Select the bootstrapping mechanism
 	switch conf.General.GenesisMethod {
 	case "static":
 		bootstrapper = static.New()
+	case "file":
+		bootstrapper = file.New(conf.General.GenesisFile)
 	default:
 		panic(fmt.Errorf("Unknown genesis method %s", conf.General.GenesisMethod))
 	}
@@ -143,11 +287,41 @@ func launchSolo(conf *config.TopLevel) {
 	if err != nil {
 		panic(fmt.Errorf("Error retrieving the genesis block %s", err))
 	}
+	return genesisBlock
+}
+
+// exportGenesis writes a genesis block restating the configuration in force on the default chain of the file ledger at
+// FileLedger.Location to path, reading only the ledger, so that another orderer may join the network with GenesisMethod file
+func exportGenesis(conf *config.TopLevel, path string) error {
+	if conf.FileLedger.Location == "" {
+		return fmt.Errorf("FileLedger.Location must name the ledger to export the genesis block from")
+	}
+	chainID := genesisChainID(bootstrapGenesisBlock(conf))
+	rl, ok := fileledger.NewFactory(conf.FileLedger.Location).Get(chainID)
+	if !ok {
+		return fmt.Errorf("The ledger at %s holds no chain %x", conf.FileLedger.Location, chainID)
+	}
+	lastConfigTx, err := retrieveConfiguration(rl)
+	if err != nil {
+		return err
+	}
+	configManager, _ := bootstrapConfigManager(conf, lastConfigTx)
+	block, err := configManager.ExportGenesis()
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(block)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exporting configuration sequence %d of chain %x to %s\n", configManager.Sequence(), chainID, path)
+	return ioutil.WriteFile(path, data, 0644)
+}
 
+// newLedgerFactory creates the ledger factory of the type named by ORDERER_LEDGER_TYPE, the RAM ledger by default
+func newLedgerFactory(conf *config.TopLevel) rawledger.Factory {
 	// Stand in until real config
-	ledgerType := os.Getenv("ORDERER_LEDGER_TYPE")
-	var rawledger rawledger.ReadWriter
-	switch ledgerType {
+	switch os.Getenv("ORDERER_LEDGER_TYPE") {
 	case "file":
 		location := conf.FileLedger.Location
 		if location == "" {
@@ -158,55 +332,249 @@ func launchSolo(conf *config.TopLevel) {
 			}
 		}
 
-		rawledger = fileledger.New(location, genesisBlock)
+		return fileledger.NewFactoryWithSyncWrites(location, conf.FileLedger.SyncWrites)
 	case "ram":
 		fallthrough
 	default:
-		rawledger = ramledger.New(int(conf.RAMLedger.HistorySize), genesisBlock)
+		return ramledger.NewFactory(int(conf.RAMLedger.HistorySize))
 	}
+}
 
-	lastConfigTx := retrieveConfiguration(rawledger)
-	if lastConfigTx == nil {
-		panic("No chain configuration found")
+// soloOptions maps the configuration onto the options of the solo orderer, less its filter and metrics registry
+func soloOptions(conf *config.TopLevel) solo.Options {
+	return solo.Options{
+		QueueSize:         int(conf.General.QueueSize),
+		BatchSize:         int(conf.General.BatchSize),
+		BatchMaxBytes:     int(conf.General.BatchMaxBytes),
+		BatchTimeout:      conf.General.BatchTimeout,
+		MaxWindowSize:     int(conf.General.MaxWindowSize),
+		DefaultWindowSize: int(conf.General.Deliver.DefaultWindowSize),
+		Features:          conf.General.Protocol.Enabled(),
+		MaxSendMsgSize:    int(conf.General.MaxSendMsgSize),
+		AckAfterCommit:    conf.General.Broadcast.AckAfterCommit,
+		DedupWindow:       int(conf.General.Broadcast.DedupWindow),
+		MaxIdleTime:       conf.General.Deliver.MaxIdleTime,
+		MaxLag:            int(conf.General.Deliver.MaxLag),
+		HeartbeatInterval: conf.General.Deliver.HeartbeatInterval,
+		SeekSkew:          conf.General.Deliver.SeekSkew,
+		CursorKey:         []byte(conf.General.Deliver.CursorKey),
+		PendingLogDir:     conf.General.Broadcast.PendingLogDir,
+		Paused:            conf.General.Broadcast.StartPaused,
+		RetryAfter:        conf.General.Broadcast.RetryAfter,
+		IdleTimeout:       conf.General.Broadcast.IdleTimeout,
+		SnapshotInterval:  int(conf.General.ConfigSnapshotInterval),
+		MaxChains:         int(conf.General.Broadcast.MaxChains),
+
+		MaxDeliverStreams:          int(conf.General.Deliver.MaxGlobalStreams),
+		MaxDeliverStreamsPerClient: int(conf.General.Deliver.MaxStreamsPerClient),
+		DeliverRetryAfter:          conf.General.Deliver.RetryAfter,
 	}
+}
 
-	configManager := bootstrapConfigManager(lastConfigTx)
+// registerReflection registers the reflection service if enabled, it describes every service registered on the server
+func registerReflection(conf *config.TopLevel, server *grpc.Server) {
+	if !conf.General.EnableReflection {
+		return
+	}
+	reflection.Register(server)
+}
 
-	// XXX actually use the config manager in the future
-	_ = configManager
+// keepaliveListener enables TCP keepalive on the connections it accepts, probing each after it has been idle for period
+type keepaliveListener struct {
+	*net.TCPListener
+	period time.Duration
+}
 
-	solo.New(int(conf.General.QueueSize), int(conf.General.BatchSize), int(conf.General.MaxWindowSize), conf.General.BatchTimeout, rawledger, grpcServer)
-	grpcServer.Serve(lis)
+func (kl keepaliveListener) Accept() (net.Conn, error) {
+	conn, err := kl.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetKeepAlive(true)
+	conn.SetKeepAlivePeriod(kl.period)
+	return conn, nil
 }
 
-func launchKafka(conf *config.TopLevel) {
-	var kafkaVersion = sarama.V0_9_0_1 // TODO Ideally we'd set this in the YAML file but its type makes this impossible
-	conf.Kafka.Version = kafkaVersion
+// listen listens on the configured address for gRPC clients, probing their connections with TCP keepalive beneath the HTTP/2 pings of gRPC
+func listen(conf *config.TopLevel) (net.Listener, error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", conf.General.ListenAddress, conf.General.ListenPort))
+	if err != nil {
+		return nil, err
+	}
+	return keepaliveListener{TCPListener: lis.(*net.TCPListener), period: conf.General.Keepalive.Time}, nil
+}
 
-	var loglevel string
-	var verbose bool
+func launchSolo(conf *config.TopLevel) {
+	grpcRegistry := metrics.NewSubsystemRegistry(metrics.Registry, "grpc")
+	serverOpts, drain, err := serverOptions(conf, grpcRegistry)
+	if err != nil {
+		panic(err)
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
 
-	flag.StringVar(&loglevel, "loglevel", "info",
-		"Set the logging level for the orderer. (Suggested values: info, debug)")
-	flag.BoolVar(&verbose, "verbose", false,
-		"Turn on logging for the Kafka library. (Default: \"false\")")
-	flag.Parse()
+	lis, err := listen(conf)
+	if err != nil {
+		fmt.Println("Failed to listen:", err)
+		return
+	}
+	lis = newCountingListener(lis, grpcRegistry)
 
-	kafka.SetLogLevel(loglevel)
-	if verbose {
-		sarama.Logger = log.New(os.Stdout, "[sarama] ", log.Lshortfile)
+	genesisBlock := bootstrapGenesisBlock(conf)
+
+	// The chain created from the genesis block is the default for clients which do not specify a chain
+	chainID := genesisChainID(genesisBlock)
+
+	ledgerFactory := newLedgerFactory(conf)
+	lastConfigTx, err := retrieveConfiguration(ledgerFactory.GetOrCreate(chainID, genesisBlock))
+	if err != nil {
+		panic(fmt.Errorf("Error retrieving the chain configuration: %s", err))
+	}
+
+	configManager, policyManager := bootstrapConfigManager(conf, lastConfigTx)
+
+	opts := soloOptions(conf)
+	configureSolo(conf, &opts, ledgerFactory, chainID, configManager, policyManager)
+	opts.Audit = openAuditTrail(conf)
+	opts.Registry = metrics.NewSubsystemRegistry(metrics.Registry, "solo")
+	opts.Signer, err = blocksigner.New(conf.General.Signer.Certificate, conf.General.Signer.PrivateKey)
+	if err != nil {
+		panic(err)
+	}
+	ordererSrv, err := solo.New(opts, ledgerFactory, chainID)
+	if err != nil {
+		panic(fmt.Errorf("Error creating the solo orderer: %s", err))
 	}
+	healthSrv := health.NewServer()
+	ab.RegisterAtomicBroadcastServer(grpcServer, ordererSrv)
+	ab.RegisterAdminServer(grpcServer, ordererSrv)
+	healthpb.RegisterHealthServer(grpcServer, healthSrv)
+	registerReflection(conf, grpcServer)
+	go grpcServer.Serve(lis)
+	gatewayLis, err := gateway.Start(conf.General.Gateway, ordererSrv)
+	if err != nil {
+		panic(err)
+	}
+	healthSrv.Started()
 
-	ordererSrv := kafka.New(conf)
+	// Trap SIGINT to trigger a shutdown
+	// We must use a buffered channel or risk missing the signal
+	// if we're not ready to receive when the signal is sent.
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt)
+
+	for range signalChan {
+		fmt.Println("Server shutting down")
+		// Commit the pending batch and the queued messages before the server stops, once its calls complete
+		ordererSrv.Teardown()
+		drain.Stop(grpcServer, lis, drainTimeout)
+		if gatewayLis != nil {
+			gatewayLis.Close()
+		}
+		opts.Audit.Close()
+		return
+	}
+}
+
+// configureSolo sets the filter of the solo options, which checks broadcast messages against the configuration and policies
+// of the chains, and the configuration and Readers policy of each chain which the orderer applies,
+// and enables the creation of chains through the default chain
+func configureSolo(conf *config.TopLevel, opts *solo.Options, ledgerFactory rawledger.Factory, chainID []byte, configManager configtx.Manager, policyManager policies.Manager) {
+	// Empty, oversized, and overlong correlation IDs are rejected first, so that the policy is only evaluated over well formed messages
+	maxBytesRule := broadcastfilter.NewMaxBytesRule(int(conf.General.MaxRecvMsgSize))
+	refreshMaxBytes(conf, configManager, maxBytesRule)
+	configManager.RegisterObserver(func(sequence uint64, changed []ab.Configuration_ConfigurationType) {
+		refreshMaxBytes(conf, configManager, maxBytesRule)
+	})
+	rules := []broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.CorrelationIDRule, maxBytesRule}
+	decisions := newDecisionLog(conf)
+	if conf.General.Broadcast.WritePolicy != "" {
+		rules = append(rules, broadcastfilter.NewPolicyRule(policyManager, conf.General.Broadcast.WritePolicy, decisions))
+	}
+	// Configuration transactions which could not be applied to their chain, such as replays of earlier ones, are not ordered
+	chains := newChainConfigs(conf, ledgerFactory, chainID, configManager, policyManager)
+	rules = append(rules, broadcastfilter.NewChainConfigRule(chains.resolveConfig, decisions))
+	// The chain created from the genesis block is the system chain, through which the others are created as its ChainCreators policy allows
+	rules = append(rules, broadcastfilter.NewChainCreationRule(policyManager, policies.ChainCreatorsPolicyID, chainID, ledgerFactory, int(conf.General.Broadcast.MaxChains), decisions))
+	// Every other message must satisfy the Writers policy of its chain
+	rules = append(rules, broadcastfilter.NewChainPolicyRule(chains.resolvePolicies, policies.WritersPolicyID, decisions))
+	rules = append(rules, broadcastfilter.AcceptRule)
+
+	opts.Filter = broadcastfilter.NewRuleSet(rules)
+	opts.CreateChains = true
+	opts.Config = configManager
+	opts.ChainConfig = chains.resolveConfig
+	opts.Policies = chains.resolvePolicies
+	opts.Decisions = decisions
+}
+
+// newDecisionLog returns the log of policy decisions, every enforcement point shares its limit on the denials logged for each client
+func newDecisionLog(conf *config.TopLevel) *audit.DecisionLog {
+	return audit.NewDecisionLog(int(conf.General.Audit.DenialBurst), conf.General.Audit.DenialInterval, clock.Real{})
+}
+
+// openAuditTrail opens the audit trail of the configuration changes applied to the default chain, appending to General.Audit.File if set
+func openAuditTrail(conf *config.TopLevel) *audit.Trail {
+	trail, err := audit.Open(conf.General.Audit.File)
+	if err != nil {
+		panic(fmt.Errorf("Error opening the audit trail: %s", err))
+	}
+	return trail
+}
+
+func launchKafka(conf *config.TopLevel, f *flags) {
+	genesisBlock := bootstrapGenesisBlock(conf)
+	chainID := genesisChainID(genesisBlock)
+
+	// Deliver is served from the ledger alone, each chain resumes consuming its partition after its last block,
+	// so with a file ledger the brokers need only retain the messages which have not yet been cut into blocks
+	ledgerFactory := newLedgerFactory(conf)
+	lastConfigTx, err := retrieveConfiguration(ledgerFactory.GetOrCreate(chainID, genesisBlock))
+	if err != nil {
+		panic(fmt.Errorf("Error retrieving the chain configuration: %s", err))
+	}
+	// The Orderer batch items of the default chain are applied to it as the blocks carrying them are cut, and its
+	// configuration transactions are only posted if they would be applied
+	configManager, policyManager := bootstrapConfigManager(conf, lastConfigTx)
+	// As with solo, broadcasts and seeks must satisfy the Writers and Readers policies of their chain
+	chains := newChainConfigs(conf, ledgerFactory, chainID, configManager, policyManager)
+	// A lost ledger is rebuilt by starting the orderer once with an empty ledger and the offset to rebuild from
+	startOffsets, err := kafka.ParseStartOffsets(f.chainStartOffsets)
+	if err != nil {
+		panic(err)
+	}
+	if f.startOffset >= 0 {
+		startOffsets[fmt.Sprintf("%x", chainID)] = f.startOffset
+	}
+	trail := openAuditTrail(conf)
+	defer trail.Close()
+	ordererSrv := kafka.NewWithStartOffsets(conf, ledgerFactory, chainID, metrics.NewSubsystemRegistry(metrics.Registry, "kafka"), startOffsets, configManager, chains.resolvePolicies, trail, newDecisionLog(conf))
+	// Teardown may be called again, this covers a failure to start serving
 	defer ordererSrv.Teardown()
 
-	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", conf.General.ListenAddress, conf.General.ListenPort))
+	lis, err := listen(conf)
 	if err != nil {
 		panic(err)
 	}
-	rpcSrv := grpc.NewServer() // TODO Add TLS support
+	grpcRegistry := metrics.NewSubsystemRegistry(metrics.Registry, "grpc")
+	lis = newCountingListener(lis, grpcRegistry)
+	serverOpts, drain, err := serverOptions(conf, grpcRegistry)
+	if err != nil {
+		panic(err)
+	}
+	rpcSrv := grpc.NewServer(serverOpts...) // TODO Add TLS support
+	healthSrv := health.NewServer()
+	// The orderer is reported NOT_SERVING while any chain has lost its brokers, Deliver continues from the ledger
+	kafka.ReportHealth(ordererSrv, healthSrv)
 	ab.RegisterAtomicBroadcastServer(rpcSrv, ordererSrv)
+	ab.RegisterAdminServer(rpcSrv, ordererSrv)
+	healthpb.RegisterHealthServer(rpcSrv, healthSrv)
+	registerReflection(conf, rpcSrv)
 	go rpcSrv.Serve(lis)
+	gatewayLis, err := gateway.Start(conf.General.Gateway, ordererSrv)
+	if err != nil {
+		panic(err)
+	}
+	healthSrv.Started()
 
 	// Trap SIGINT to trigger a shutdown
 	// We must use a buffered channel or risk missing the signal
@@ -216,6 +584,14 @@ func launchKafka(conf *config.TopLevel) {
 
 	for range signalChan {
 		fmt.Println("Server shutting down")
+		// Broadcasts are refused and the accepted messages are cut into blocks before the connections are torn down
+		if err := ordererSrv.Teardown(); err != nil {
+			fmt.Println("Error tearing down the Kafka orderer:", err)
+		}
+		drain.Stop(rpcSrv, lis, drainTimeout)
+		if gatewayLis != nil {
+			gatewayLis.Close()
+		}
 		return
 	}
 }
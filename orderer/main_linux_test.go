@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/config"
+)
+
+func TestListenEnablesKeepalive(t *testing.T) {
+	conf := &config.TopLevel{General: config.General{ListenAddress: "127.0.0.1", Keepalive: config.Keepalive{Time: 7 * time.Second}}}
+	lis, err := listen(conf)
+	if err != nil {
+		t.Fatal("Error listening:", err)
+	}
+	defer lis.Close()
+
+	client, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal("Error dialing:", err)
+	}
+	defer client.Close()
+	conn, err := lis.Accept()
+	if err != nil {
+		t.Fatal("Error accepting:", err)
+	}
+	defer conn.Close()
+
+	file, err := conn.(*net.TCPConn).File()
+	if err != nil {
+		t.Fatal("Error getting the socket:", err)
+	}
+	defer file.Close()
+
+	if enabled, err := syscall.GetsockoptInt(int(file.Fd()), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE); err != nil || enabled != 1 {
+		t.Fatalf("Expected keepalive to be enabled on the accepted connection, got %d (%v)", enabled, err)
+	}
+	if idle, err := syscall.GetsockoptInt(int(file.Fd()), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE); err != nil || idle != 7 {
+		t.Fatalf("Expected probes to begin after 7 idle seconds, got %d (%v)", idle, err)
+	}
+}
@@ -0,0 +1,1192 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/cauthdsl"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/health"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
+	"github.com/hyperledger/fabric/orderer/config"
+	"github.com/hyperledger/fabric/orderer/kafka"
+	"github.com/hyperledger/fabric/orderer/kafka/mocks"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/fileledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+	"github.com/hyperledger/fabric/orderer/solo"
+
+	"github.com/golang/protobuf/proto"
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+var testChainID = []byte("default")
+
+var testGenesisBlock = ab.NewBlock(0, nil, []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("genesis")}}, nil)
+
+type mockBroadcastStream struct {
+	grpc.ServerStream
+	incoming chan *ab.BroadcastMessage
+	outgoing chan *ab.BroadcastResponse
+}
+
+// Context returns a context which identifies no client
+func (mbs *mockBroadcastStream) Context() context.Context {
+	return context.Background()
+}
+
+func (mbs *mockBroadcastStream) Recv() (*ab.BroadcastMessage, error) {
+	return <-mbs.incoming, nil
+}
+
+func (mbs *mockBroadcastStream) Send(reply *ab.BroadcastResponse) error {
+	mbs.outgoing <- reply
+	return nil
+}
+
+// broadcastAll sends each message on a Broadcast stream of srv, waiting for it to be accepted before sending the next
+func broadcastAll(t *testing.T, srv ab.AtomicBroadcastServer, messages []string) {
+	mbs := &mockBroadcastStream{
+		incoming: make(chan *ab.BroadcastMessage),
+		outgoing: make(chan *ab.BroadcastResponse),
+	}
+	go srv.Broadcast(mbs)
+	for _, data := range messages {
+		mbs.incoming <- &ab.BroadcastMessage{Data: []byte(data)}
+		select {
+		case reply := <-mbs.outgoing:
+			if reply.Status != ab.Status_SUCCESS {
+				t.Fatalf("Expected message %s to be accepted, got %v", data, reply.Status)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Should have received a broadcast reply to message %s by now", data)
+		}
+	}
+}
+
+// blocksOf waits for the ledger to hold the given number of blocks after the genesis block, and returns their messages
+func blocksOf(t *testing.T, rl rawledger.Reader, count int) [][]string {
+	deadline := time.Now().Add(5 * time.Second)
+	for rl.Height() < uint64(count+1) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d blocks, the ledger has %d", count, rl.Height()-1)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var blocks [][]string
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	for number := uint64(1); number < rl.Height(); number++ {
+		block, _ := it.Next()
+		var messages []string
+		for _, msg := range block.Data.Messages {
+			messages = append(messages, string(msg.Data))
+		}
+		blocks = append(blocks, messages)
+	}
+	return blocks
+}
+
+func orderSolo(t *testing.T, conf *config.TopLevel, messages []string, count int) [][]string {
+	lf := ramledger.NewFactory(10)
+	rl := lf.GetOrCreate(testChainID, testGenesisBlock)
+	opts := soloOptions(conf)
+	opts.Filter = broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.AcceptRule})
+	srv, err := solo.New(opts, lf, testChainID)
+	if err != nil {
+		t.Fatal("Error creating the solo orderer:", err)
+	}
+	defer srv.Teardown()
+	broadcastAll(t, srv, messages)
+	return blocksOf(t, rl, count)
+}
+
+func orderKafka(t *testing.T, conf *config.TopLevel, messages []string, count int) [][]string {
+	broker := mocks.NewBroker(t, "fabric", 1)
+	defer broker.Close()
+	kconf := mocks.NewTestConfig(broker)
+	kconf.General = conf.General
+	lf := ramledger.NewFactory(10)
+	rl := lf.GetOrCreate(testChainID, testGenesisBlock)
+	srv := kafka.New(kconf, lf, testChainID, nil)
+	defer srv.Teardown()
+
+	// The broker stores what it is sent as it arrives, including the time-to-cut messages of the orderer
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				broker.Forward(0)
+			}
+		}
+	}()
+
+	broadcastAll(t, srv, messages)
+	return blocksOf(t, rl, count)
+}
+
+func TestConsentersCutSameBlocks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		size     uint
+		maxBytes uint
+		messages []string
+		expects  [][]string
+	}{
+		{"BatchSize", 2, 0, []string{"a", "b", "c", "d", "e"}, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{"BatchMaxBytes", 10, 4, []string{"aa", "bb", "ccc", "d"}, [][]string{{"aa", "bb"}, {"ccc", "d"}}},
+		{"Oversized", 10, 2, []string{"a", "bbbbb", "c"}, [][]string{{"a"}, {"bbbbb"}, {"c"}}},
+	}
+
+	for _, tc := range testCases {
+		conf := &config.TopLevel{General: config.General{
+			QueueSize:     100,
+			BatchSize:     tc.size,
+			BatchMaxBytes: tc.maxBytes,
+			// Long enough that only the last block of each case is cut by the timeout
+			BatchTimeout:  500 * time.Millisecond,
+			MaxWindowSize: 100,
+		}}
+		for consenter, order := range map[string]func(*testing.T, *config.TopLevel, []string, int) [][]string{
+			"solo":  orderSolo,
+			"kafka": orderKafka,
+		} {
+			blocks := order(t, conf, tc.messages, len(tc.expects))
+			if fmt.Sprint(blocks) != fmt.Sprint(tc.expects) {
+				t.Fatalf("%s: expected %s to cut blocks %v, got %v", tc.name, consenter, tc.expects, blocks)
+			}
+		}
+	}
+}
+
+// countingReader counts the blocks read through its iterators and by number
+type countingReader struct {
+	rawledger.Reader
+	reads int
+}
+
+func (cr *countingReader) GetBlock(number uint64) (*ab.Block, ab.Status) {
+	cr.reads++
+	return cr.Reader.GetBlock(number)
+}
+
+type countingIterator struct {
+	rawledger.Iterator
+	cr *countingReader
+}
+
+func (cr *countingReader) Iterator(startType ab.SeekInfo_StartType, specified uint64) (rawledger.Iterator, uint64) {
+	it, number := cr.Reader.Iterator(startType, specified)
+	return &countingIterator{Iterator: it, cr: cr}, number
+}
+
+func (ci *countingIterator) Next() (*ab.Block, ab.Status) {
+	ci.cr.reads++
+	return ci.Iterator.Next()
+}
+
+func TestRetrieveConfigurationReadsTwoBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configAt := map[int]bool{3: true, 7: true}
+	genesis := ab.NewBlock(0, nil, []*ab.BroadcastMessage{configMessage(t, 0)}, nil)
+	genesis.SetLastConfiguration(0)
+	rl := fileledger.New(dir, genesis)
+	tracker := configtx.NewLastConfigTracker(rl)
+	for number := 1; number <= 10; number++ {
+		msg := &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("normal %d", number))}
+		if configAt[number] {
+			msg = configMessage(t, uint64(number))
+		}
+		rl.Append([]*ab.BroadcastMessage{msg}, tracker.Seal)
+	}
+
+	cr := &countingReader{Reader: fileledger.New(dir, genesis)}
+	configTx, err := retrieveConfiguration(cr)
+	if err != nil || configTx.Sequence != 7 {
+		t.Fatalf("Expected the configuration committed in block 7, got %v (%v)", configTx, err)
+	}
+	if cr.reads != 2 {
+		t.Fatalf("Expected only the tail block and the configuration block to be read, but %d blocks were read", cr.reads)
+	}
+}
+
+func TestRetrieveConfigurationLegacyChain(t *testing.T) {
+	rl := ramledger.New(20, ab.NewBlock(0, nil, []*ab.BroadcastMessage{configMessage(t, 0)}, nil))
+	for number := 1; number <= 5; number++ {
+		msg := &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("normal %d", number))}
+		if number == 3 {
+			msg = configMessage(t, 1)
+		}
+		rl.Append([]*ab.BroadcastMessage{msg}, nil)
+	}
+
+	cr := &countingReader{Reader: rl}
+	configTx, err := retrieveConfiguration(cr)
+	if err != nil || configTx.Sequence != 1 {
+		t.Fatalf("Expected the configuration committed in block 3, got %v (%v)", configTx, err)
+	}
+	if cr.reads != 8 {
+		t.Fatalf("Expected the tail, the scan of all 6 blocks, and the configuration block to be read, but %d blocks were read", cr.reads)
+	}
+}
+
+// unreadableReader fails to return the block with the given number
+type unreadableReader struct {
+	rawledger.Reader
+	number uint64
+}
+
+func (ur *unreadableReader) GetBlock(number uint64) (*ab.Block, ab.Status) {
+	if number == ur.number {
+		return nil, ab.Status_SERVICE_UNAVAILABLE
+	}
+	return ur.Reader.GetBlock(number)
+}
+
+func TestRetrieveConfigurationErrors(t *testing.T) {
+	genesis := ab.NewBlock(0, nil, []*ab.BroadcastMessage{configMessage(t, 0)}, nil)
+	genesis.SetLastConfiguration(0)
+	rl := ramledger.New(10, genesis)
+	rl.Append([]*ab.BroadcastMessage{configMessage(t, 1)}, configtx.NewLastConfigTracker(rl).Seal)
+
+	if _, err := retrieveConfiguration(&unreadableReader{Reader: rl, number: 1}); err == nil || !strings.Contains(err.Error(), "tail block") {
+		t.Errorf("Expected an error reading the tail block, got %v", err)
+	}
+	if _, err := retrieveConfiguration(&unreadableReader{Reader: rl, number: 0}); err != nil {
+		t.Errorf("Expected block 0 not to be read, as the tail block is the last configuration block, got %v", err)
+	}
+
+	// The tail points to a block which carries no configuration
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("normal")}}, func(block *ab.Block) { block.SetLastConfiguration(block.Header.Number) })
+	if _, err := retrieveConfiguration(rl); err == nil || !strings.Contains(err.Error(), "carries no configuration") {
+		t.Errorf("Expected an error for a pointer to a block without configuration, got %v", err)
+	}
+}
+
+func configMessage(t *testing.T, sequence uint64) *ab.BroadcastMessage {
+	data, err := configtx.MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{Sequence: sequence, ChainID: testChainID})
+	if err != nil {
+		t.Fatalf("Error marshaling configuration: %s", err)
+	}
+	return &ab.BroadcastMessage{Data: data}
+}
+
+// listServices asks the reflection service of the server at addr for the names of the services it serves
+func listServices(addr string) ([]string, error) {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if err = stream.Send(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_ListServices{}}); err != nil {
+		return nil, err
+	}
+	reply, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, service := range reply.GetListServicesResponse().GetService() {
+		names = append(names, service.Name)
+	}
+	return names, nil
+}
+
+func TestReflection(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		conf := &config.TopLevel{General: config.General{QueueSize: 10, BatchSize: 10, BatchTimeout: time.Second, MaxWindowSize: 10, EnableReflection: enabled}}
+		lf := ramledger.NewFactory(10)
+		lf.GetOrCreate(testChainID, testGenesisBlock)
+		srv, err := solo.New(soloOptions(conf), lf, testChainID)
+		if err != nil {
+			t.Fatal("Error creating the solo orderer:", err)
+		}
+
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal("Error listening:", err)
+		}
+		grpcServer := grpc.NewServer()
+		ab.RegisterAtomicBroadcastServer(grpcServer, srv)
+		ab.RegisterAdminServer(grpcServer, srv)
+		healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+		registerReflection(conf, grpcServer)
+		go grpcServer.Serve(lis)
+
+		names, err := listServices(lis.Addr().String())
+		grpcServer.Stop()
+		srv.Teardown()
+
+		if !enabled {
+			if grpc.Code(err) != codes.Unimplemented {
+				t.Fatalf("Expected listing the services to be unimplemented with reflection disabled, got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("Error listing the services:", err)
+		}
+		for _, expected := range []string{"atomicbroadcast.AtomicBroadcast", "atomicbroadcast.Admin", "grpc.health.v1.Health"} {
+			found := false
+			for _, name := range names {
+				found = found || name == expected
+			}
+			if !found {
+				t.Fatalf("Expected %s among the services listed, got %v", expected, names)
+			}
+		}
+	}
+}
+
+// deliverOverGRPC replays the blocks of the test chain up to last from a server compressing its responses as configured,
+// to a client compressing its requests if compress is set, returning them and the bytes written on the wire
+func deliverOverGRPC(t *testing.T, compression string, compress bool, lf rawledger.Factory, last uint64) ([]*ab.Block, int64) {
+	conf := &config.TopLevel{General: config.General{QueueSize: 10, BatchSize: 10, BatchTimeout: time.Second, MaxWindowSize: 100, Deliver: config.Deliver{Compression: compression}}}
+	srv, err := solo.New(soloOptions(conf), lf, testChainID)
+	if err != nil {
+		t.Fatal("Error creating the solo orderer:", err)
+	}
+	defer srv.Teardown()
+
+	registry := gometrics.NewRegistry()
+	opts, _, err := serverOptions(conf, registry)
+	if err != nil {
+		t.Fatal("Error building the server options:", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error listening:", err)
+	}
+	grpcServer := grpc.NewServer(opts...)
+	ab.RegisterAtomicBroadcastServer(grpcServer, srv)
+	go grpcServer.Serve(newCountingListener(lis, registry))
+	defer grpcServer.Stop()
+
+	dialOpts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithTimeout(time.Second)}
+	if compress {
+		dialOpts = append(dialOpts, grpc.WithCompressor(grpc.NewGZIPCompressor()))
+	}
+	conn, err := grpc.Dial(lis.Addr().String(), dialOpts...)
+	if err != nil {
+		t.Fatal("Error dialing:", err)
+	}
+	defer conn.Close()
+
+	stream, err := ab.NewAtomicBroadcastClient(conn).Deliver(context.Background())
+	if err != nil {
+		t.Fatal("Error opening the Deliver stream:", err)
+	}
+	if err = stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_OLDEST, Stop: ab.SeekInfo_STOP_SPECIFIED, StopNumber: last, WindowSize: 100}}}); err != nil {
+		t.Fatal("Error sending the seek:", err)
+	}
+
+	var blocks []*ab.Block
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			t.Fatal("Error receiving:", err)
+		}
+		if reply.GetBlock() == nil {
+			if reply.GetError() != ab.Status_SUCCESS {
+				t.Fatalf("Expected the replay to end with SUCCESS, got %v", reply)
+			}
+			break
+		}
+		blocks = append(blocks, reply.GetBlock())
+	}
+
+	if sent := gometrics.GetOrRegisterCounter("message_bytes_sent", registry).Count(); sent == 0 {
+		t.Fatalf("Expected the bytes of the messages sent to be counted")
+	}
+	return blocks, gometrics.GetOrRegisterCounter("wire_bytes_sent", registry).Count()
+}
+
+func TestDeliverCompression(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	rl := lf.GetOrCreate(testChainID, testGenesisBlock)
+	for i := 1; i < 5; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: bytes.Repeat([]byte(fmt.Sprintf("block %d ", i)), 1000)}}, nil)
+	}
+
+	// A client which does not compress its requests may not accept gzip, and is answered uncompressed
+	plain, plainBytes := deliverOverGRPC(t, "gzip", false, lf, 4)
+	compressed, compressedBytes := deliverOverGRPC(t, "gzip", true, lf, 4)
+
+	if len(plain) != 5 || len(compressed) != 5 {
+		t.Fatalf("Expected 5 blocks with and without compression, got %d and %d", len(plain), len(compressed))
+	}
+	for i := range plain {
+		if !proto.Equal(plain[i], compressed[i]) {
+			t.Fatalf("Block %d differs when compressed", i)
+		}
+	}
+
+	if compressedBytes*3 > plainBytes {
+		t.Fatalf("Expected compression to send a third of the %d bytes or fewer, sent %d", plainBytes, compressedBytes)
+	}
+}
+
+func TestServerOptionsRejectsUnknownCompression(t *testing.T) {
+	conf := &config.TopLevel{General: config.General{Deliver: config.Deliver{Compression: "snappy"}}}
+	if _, _, err := serverOptions(conf, gometrics.NewRegistry()); err == nil {
+		t.Fatalf("Expected an unsupported compression to be refused")
+	}
+}
+
+// dialRawHTTP2 serves the solo orderer with the server options of conf, and opens a raw HTTP/2 connection to it, which shows the pings and GOAWAY frames gRPC hides from its clients
+func dialRawHTTP2(t *testing.T, conf *config.TopLevel) (*http2.Framer, net.Conn, func()) {
+	conf.General.QueueSize, conf.General.BatchSize, conf.General.BatchTimeout, conf.General.MaxWindowSize = 10, 10, time.Second, 100
+	conf.General.Deliver.Compression = "none"
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(testChainID, testGenesisBlock)
+	srv, err := solo.New(soloOptions(conf), lf, testChainID)
+	if err != nil {
+		t.Fatal("Error creating the solo orderer:", err)
+	}
+
+	opts, _, err := serverOptions(conf, gometrics.NewRegistry())
+	if err != nil {
+		t.Fatal("Error building the server options:", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error listening:", err)
+	}
+	grpcServer := grpc.NewServer(opts...)
+	ab.RegisterAtomicBroadcastServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal("Error dialing:", err)
+	}
+	framer := http2.NewFramer(conn, conn)
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		t.Fatal("Error writing the client preface:", err)
+	}
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal("Error writing the client settings:", err)
+	}
+	return framer, conn, func() {
+		conn.Close()
+		grpcServer.Stop()
+		srv.Teardown()
+	}
+}
+
+// nextFrame reads frames until one satisfying match arrives, failing the test if none does within timeout
+func nextFrame(t *testing.T, framer *http2.Framer, conn net.Conn, timeout time.Duration, match func(http2.Frame) bool) http2.Frame {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatal("Error reading a frame:", err)
+		}
+		if match(frame) {
+			return frame
+		}
+	}
+}
+
+func isPing(frame http2.Frame) bool {
+	ping, ok := frame.(*http2.PingFrame)
+	return ok && !ping.IsAck()
+}
+
+func TestKeepalivePingsIdleClients(t *testing.T) {
+	conf := &config.TopLevel{General: config.General{Keepalive: config.Keepalive{Time: 100 * time.Millisecond, Timeout: 200 * time.Millisecond, MinTime: time.Minute}}}
+	framer, conn, stop := dialRawHTTP2(t, conf)
+	defer stop()
+
+	ping := nextFrame(t, framer, conn, 2*time.Second, isPing).(*http2.PingFrame)
+	if err := framer.WritePing(true, ping.Data); err != nil {
+		t.Fatal("Error answering the ping:", err)
+	}
+	nextFrame(t, framer, conn, 2*time.Second, isPing)
+
+	// Leaving the second ping unanswered gets the connection closed
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, err := framer.ReadFrame()
+		if err == nil {
+			continue
+		}
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			t.Fatalf("Expected the connection to be closed once the ping went unanswered")
+		}
+		break
+	}
+}
+
+func TestKeepaliveEnforcementPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		keepalive config.Keepalive
+		goAway    bool
+	}{
+		{"PingsTooOften", config.Keepalive{Time: time.Minute, Timeout: time.Minute, MinTime: time.Minute, PermitWithoutStream: true}, true},
+		{"PingsWithoutStream", config.Keepalive{Time: time.Minute, Timeout: time.Minute, MinTime: time.Nanosecond}, true},
+		{"PingsPermitted", config.Keepalive{Time: time.Minute, Timeout: time.Minute, MinTime: time.Nanosecond, PermitWithoutStream: true}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			framer, conn, stop := dialRawHTTP2(t, &config.TopLevel{General: config.General{Keepalive: tc.keepalive}})
+			defer stop()
+
+			for i := 0; i < 4; i++ {
+				if err := framer.WritePing(false, [8]byte{byte(i)}); err != nil {
+					t.Fatal("Error pinging:", err)
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			var acks int
+			frame := nextFrame(t, framer, conn, 2*time.Second, func(frame http2.Frame) bool {
+				if ping, ok := frame.(*http2.PingFrame); ok && ping.IsAck() {
+					acks++
+				}
+				_, isGoAway := frame.(*http2.GoAwayFrame)
+				return isGoAway || acks == 4 && !tc.goAway
+			})
+			goAway, isGoAway := frame.(*http2.GoAwayFrame)
+			if isGoAway != tc.goAway {
+				t.Fatalf("Expected a GOAWAY to be sent to be %v, got %v", tc.goAway, frame)
+			}
+			if isGoAway && (goAway.ErrCode != http2.ErrCodeEnhanceYourCalm || string(goAway.DebugData()) != "too_many_pings") {
+				t.Fatalf("Expected a GOAWAY with ENHANCE_YOUR_CALM for too many pings, got %v %q", goAway.ErrCode, goAway.DebugData())
+			}
+		})
+	}
+}
+
+func TestKeepaliveQuietWhileHeartbeating(t *testing.T) {
+	conf := &config.TopLevel{General: config.General{
+		Keepalive: config.Keepalive{Time: 200 * time.Millisecond, Timeout: time.Second, MinTime: time.Minute},
+		Deliver:   config.Deliver{HeartbeatInterval: 50 * time.Millisecond},
+	}}
+	framer, conn, stop := dialRawHTTP2(t, conf)
+	defer stop()
+
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	for _, field := range []hpack.HeaderField{
+		{Name: ":method", Value: "POST"},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":path", Value: "/atomicbroadcast.AtomicBroadcast/Deliver"},
+		{Name: ":authority", Value: conn.RemoteAddr().String()},
+		{Name: "content-type", Value: "application/grpc"},
+		{Name: "te", Value: "trailers"},
+	} {
+		encoder.WriteField(field)
+	}
+	if err := framer.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: block.Bytes(), EndHeaders: true}); err != nil {
+		t.Fatal("Error opening the Deliver stream:", err)
+	}
+
+	// The client returns the flow control window of each heartbeat as a gRPC client does, which is activity well within Time
+	var heartbeats int
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		frame, err := framer.ReadFrame()
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			break
+		}
+		if err != nil {
+			t.Fatal("Error reading a frame:", err)
+		}
+		if isPing(frame) {
+			t.Fatalf("Expected no ping while heartbeats are sent, got one after %d heartbeats", heartbeats)
+		}
+		if data, ok := frame.(*http2.DataFrame); ok && len(data.Data()) > 0 {
+			heartbeats++
+			if err := framer.WriteWindowUpdate(0, uint32(len(data.Data()))); err != nil {
+				t.Fatal("Error returning the flow control window:", err)
+			}
+		}
+	}
+	if heartbeats < 5 {
+		t.Fatalf("Expected heartbeats every 50ms for a second, got %d", heartbeats)
+	}
+}
+
+func TestExportGenesis(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := &config.TopLevel{General: config.General{
+		GenesisMethod: "static",
+		QueueSize:     100,
+		BatchSize:     10,
+		BatchTimeout:  time.Hour,
+		MaxWindowSize: 100,
+	}}
+	conf.FileLedger.Location = dir + "/ledger"
+
+	// The chain of the first orderer has since been configured to cut blocks of two messages
+	genesis := bootstrapGenesisBlock(conf)
+	chainID := genesisChainID(genesis)
+	configTx := configtx.BlockConfiguration(genesis)
+	configTx.Sequence = 1
+	data, _ := proto.Marshal(&ab.BatchSize{Messages: 2})
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: chainID, ID: sharedconfig.BatchSizeKey, Type: ab.Configuration_Orderer, Data: data, LastModified: 1})
+	configTx.Entries = append(configTx.Entries, &ab.ConfigurationEntry{Configuration: item})
+	configData, err := configtx.MarshalConfigurationTransaction(configTx)
+	if err != nil {
+		t.Fatalf("Error marshaling configuration: %s", err)
+	}
+	rl := fileledger.NewFactory(conf.FileLedger.Location).GetOrCreate(chainID, genesis)
+	tracker := configtx.NewLastConfigTracker(rl)
+	for _, msg := range []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("a")}, &ab.BroadcastMessage{Data: configData}, &ab.BroadcastMessage{Data: []byte("b")}} {
+		rl.Append([]*ab.BroadcastMessage{msg}, tracker.Seal)
+	}
+	lastConfigTx, err := retrieveConfiguration(rl)
+	if err != nil {
+		t.Fatalf("Error retrieving the configuration: %s", err)
+	}
+	first, _ := bootstrapConfigManager(conf, lastConfigTx)
+
+	path := dir + "/genesis.block"
+	if err := exportGenesis(conf, path); err != nil {
+		t.Fatalf("Error exporting the genesis block: %s", err)
+	}
+
+	// A second orderer is bootstrapped from the exported block alone
+	joinConf := *conf
+	joinConf.General.GenesisMethod = "file"
+	joinConf.General.GenesisFile = path
+	exported := bootstrapGenesisBlock(&joinConf)
+	if exported.Header.Number != 0 || !bytes.Equal(exported.Header.DataHash, exported.Data.Hash()) {
+		t.Fatalf("Expected a genesis block with a matching data hash, got %v", exported.Header)
+	}
+	if !bytes.Equal(genesisChainID(exported), chainID) {
+		t.Fatalf("Expected the exported block to be for chain %x", chainID)
+	}
+	lf := ramledger.NewFactory(10)
+	joinRL := lf.GetOrCreate(chainID, exported)
+	lastConfigTx, err = retrieveConfiguration(joinRL)
+	if err != nil {
+		t.Fatalf("Error retrieving the exported configuration: %s", err)
+	}
+	second, _ := bootstrapConfigManager(&joinConf, lastConfigTx)
+	if second.Sequence() != first.Sequence() || !proto.Equal(second.Snapshot(), first.Snapshot()) {
+		t.Fatalf("Expected the configuration bootstrapped from the export to match, got %v rather than %v", second.Snapshot(), first.Snapshot())
+	}
+
+	opts := soloOptions(&joinConf)
+	opts.Filter = broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.AcceptRule})
+	opts.Config = second
+	srv, err := solo.New(opts, lf, chainID)
+	if err != nil {
+		t.Fatal("Error creating the solo orderer:", err)
+	}
+	defer srv.Teardown()
+	broadcastAll(t, srv, []string{"c", "d"})
+	if blocks := blocksOf(t, joinRL, 1); fmt.Sprint(blocks) != "[[c d]]" {
+		t.Fatalf("Expected the second orderer to cut blocks of two messages, got %v", blocks)
+	}
+}
+
+// testIdentity is a generated key and a self signed certificate for it
+type testIdentity struct {
+	cert []byte
+	key  *ecdsa.PrivateKey
+}
+
+func newTestIdentity(t *testing.T) *testIdentity {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	return &testIdentity{cert: cert, key: key}
+}
+
+// sign returns the signatures of data by the identity, or none if it is nil
+func (id *testIdentity) sign(t *testing.T, data []byte) []*ab.SignedData {
+	if id == nil {
+		return nil
+	}
+	signature, err := cauthdsl.SignECDSA(id.key, data)
+	if err != nil {
+		t.Fatalf("Error signing: %s", err)
+	}
+	envelope, _ := proto.Marshal(&ab.PayloadEnvelope{Payload: data, Signer: id.cert})
+	return []*ab.SignedData{&ab.SignedData{PayloadEnvelope: envelope, Signature: signature}}
+}
+
+// policiesGenesis returns the genesis block of a chain whose Writers and Readers policies each require a signature by one identity
+func policiesGenesis(t *testing.T, writer, reader *testIdentity) *ab.Block {
+	entry := func(id string, member *testIdentity) *ab.ConfigurationEntry {
+		policy := cauthdsl.RejectAllPolicy
+		if member != nil {
+			policy = cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{member.cert})
+		}
+		data, _ := policies.Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: policy}})
+		item, _ := proto.Marshal(&ab.Configuration{ChainID: testChainID, ID: id, Type: ab.Configuration_Policy, Data: data, ModificationPolicy: configtx.DefaultModificationPolicyID})
+		return &ab.ConfigurationEntry{Configuration: item}
+	}
+	genesis, err := configtx.GenesisBlock(&ab.ConfigurationEnvelope{ChainID: testChainID, Entries: []*ab.ConfigurationEntry{
+		entry(configtx.DefaultModificationPolicyID, nil),
+		entry(policies.WritersPolicyID, writer),
+		entry(policies.ReadersPolicyID, reader),
+	}})
+	if err != nil {
+		t.Fatalf("Error creating the genesis block: %s", err)
+	}
+	return genesis
+}
+
+func TestPoliciesAuthorizeClients(t *testing.T) {
+	writer, reader, other := newTestIdentity(t), newTestIdentity(t), newTestIdentity(t)
+	conf := &config.TopLevel{General: config.General{QueueSize: 10, BatchSize: 1, BatchTimeout: time.Second, MaxWindowSize: 100, MaxRecvMsgSize: 1024 * 1024, Protocol: config.Protocol{Features: []string{"CHAIN_ROUTING"}}}}
+	lf := ramledger.NewFactory(10)
+	rl := lf.GetOrCreate(testChainID, policiesGenesis(t, writer, reader))
+	lastConfigTx, err := retrieveConfiguration(rl)
+	if err != nil {
+		t.Fatalf("Error retrieving the configuration: %s", err)
+	}
+	configManager, policyManager := bootstrapConfigManager(conf, lastConfigTx)
+	opts := soloOptions(conf)
+	configureSolo(conf, &opts, lf, testChainID, configManager, policyManager)
+	srv, err := solo.New(opts, lf, testChainID)
+	if err != nil {
+		t.Fatal("Error creating the solo orderer:", err)
+	}
+	defer srv.Teardown()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Error listening:", err)
+	}
+	grpcServer := grpc.NewServer()
+	ab.RegisterAtomicBroadcastServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal("Error dialing:", err)
+	}
+	defer conn.Close()
+	client := ab.NewAtomicBroadcastClient(conn)
+
+	broadcast, err := client.Broadcast(context.Background())
+	if err != nil {
+		t.Fatal("Error opening the Broadcast stream:", err)
+	}
+	for _, tc := range []struct {
+		name   string
+		signer *testIdentity
+		status ab.Status
+	}{
+		{"writer", writer, ab.Status_SUCCESS},
+		{"reader", reader, ab.Status_FORBIDDEN},
+		{"unsigned client", nil, ab.Status_FORBIDDEN},
+	} {
+		payload := []byte("from " + tc.name)
+		data, _ := proto.Marshal(&ab.Transaction{Type: &ab.Transaction_Opaque{Opaque: payload}, Signatures: tc.signer.sign(t, payload)})
+		if err := broadcast.Send(&ab.BroadcastMessage{Data: data}); err != nil {
+			t.Fatal("Error broadcasting:", err)
+		}
+		if reply, err := broadcast.Recv(); err != nil || reply.Status != tc.status {
+			t.Fatalf("Expected the message of the %s to be %v, got %v %v", tc.name, tc.status, reply, err)
+		}
+	}
+	blocksOf(t, rl, 1)
+
+	for _, tc := range []struct {
+		name   string
+		signer *testIdentity
+		status ab.Status
+	}{
+		{"reader", reader, ab.Status_SUCCESS},
+		{"writer", writer, ab.Status_FORBIDDEN},
+		{"impostor", other, ab.Status_FORBIDDEN},
+		{"unsigned client", nil, ab.Status_FORBIDDEN},
+	} {
+		deliver, err := client.Deliver(context.Background())
+		if err != nil {
+			t.Fatal("Error opening the Deliver stream:", err)
+		}
+		// The seek names the chain it is signed for, which needs chain routing
+		if err = deliver.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.NewFeatures(ab.Feature_CHAIN_ROUTING).Hello()}}); err != nil {
+			t.Fatal("Error sending the hello:", err)
+		}
+		if reply, err := deliver.Recv(); err != nil || reply.GetHello() == nil {
+			t.Fatalf("Expected chain routing to be negotiated, got %v %v", reply, err)
+		}
+		seek := &ab.SeekInfo{Start: ab.SeekInfo_OLDEST, Stop: ab.SeekInfo_STOP_NEWEST, WindowSize: 10, ChainID: testChainID, Timestamp: time.Now().UnixNano()}
+		seek.Signatures = tc.signer.sign(t, seek.SignedBytes())
+		if err = deliver.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: seek}}); err != nil {
+			t.Fatal("Error sending the seek:", err)
+		}
+		var delivered []*ab.Block
+		for {
+			reply, err := deliver.Recv()
+			if err != nil {
+				t.Fatal("Error receiving:", err)
+			}
+			if reply.GetBlock() == nil {
+				if reply.GetError() != tc.status {
+					t.Fatalf("Expected the seek of the %s to end with %v, got %v", tc.name, tc.status, reply)
+				}
+				break
+			}
+			delivered = append(delivered, reply.GetBlock())
+		}
+		if tc.status == ab.Status_SUCCESS && len(delivered) != 2 {
+			t.Fatalf("Expected the %s to be delivered the genesis block and the block of the writer, got %d blocks", tc.name, len(delivered))
+		}
+		if tc.status != ab.Status_SUCCESS && len(delivered) != 0 {
+			t.Fatalf("Expected the %s to be delivered no blocks, got %d", tc.name, len(delivered))
+		}
+	}
+}
+
+func TestChainCreatorsPolicy(t *testing.T) {
+	creator, other := newTestIdentity(t), newTestIdentity(t)
+	data, _ := policies.Marshal(&ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{creator.cert})}})
+	// The policy may only be changed by those it authorizes to create chains
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: testChainID, ID: policies.ChainCreatorsPolicyID, Type: ab.Configuration_Policy, Data: data, ModificationPolicy: policies.ChainCreatorsPolicyID})
+	genesis, err := configtx.GenesisBlock(&ab.ConfigurationEnvelope{ChainID: testChainID, Entries: []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}}})
+	if err != nil {
+		t.Fatalf("Error creating the genesis block: %s", err)
+	}
+
+	conf := &config.TopLevel{General: config.General{QueueSize: 10, BatchSize: 1, BatchTimeout: time.Second, MaxWindowSize: 100, MaxRecvMsgSize: 1024 * 1024,
+		Broadcast: config.Broadcast{AckAfterCommit: true, MaxChains: 2}, Protocol: config.Protocol{Features: []string{"CHAIN_ROUTING"}}}}
+	lf := ramledger.NewFactory(10)
+	lastConfigTx, err := retrieveConfiguration(lf.GetOrCreate(testChainID, genesis))
+	if err != nil {
+		t.Fatalf("Error retrieving the configuration: %s", err)
+	}
+	configManager, policyManager := bootstrapConfigManager(conf, lastConfigTx)
+	opts := soloOptions(conf)
+	configureSolo(conf, &opts, lf, testChainID, configManager, policyManager)
+	srv, err := solo.New(opts, lf, testChainID)
+	if err != nil {
+		t.Fatal("Error creating the solo orderer:", err)
+	}
+	defer srv.Teardown()
+
+	mbs := &mockBroadcastStream{
+		incoming: make(chan *ab.BroadcastMessage),
+		outgoing: make(chan *ab.BroadcastResponse),
+	}
+	go srv.Broadcast(mbs)
+	for _, tc := range []struct {
+		name    string
+		chainID string
+		signer  *testIdentity
+		status  ab.Status
+		info    string
+	}{
+		{"impostor", "firstchain", other, ab.Status_FORBIDDEN, "not authorized by policy " + policies.ChainCreatorsPolicyID},
+		{"unsigned client", "firstchain", nil, ab.Status_FORBIDDEN, "not authorized by policy " + policies.ChainCreatorsPolicyID},
+		{"creator", "firstchain", creator, ab.Status_SUCCESS, ""},
+		{"creator beyond the maximum", "secondchain", creator, ab.Status_FORBIDDEN, "maximum of 2 chains"},
+	} {
+		item, _ := proto.Marshal(&ab.Configuration{ChainID: []byte(tc.chainID), ID: "foo", Type: ab.Configuration_Fabric})
+		configTx := &ab.ConfigurationEnvelope{ChainID: []byte(tc.chainID), Entries: []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}}}
+		envelope, _ := proto.Marshal(configTx)
+		data, err := configtx.MarshalChainCreationTransaction(configTx, tc.signer.sign(t, envelope))
+		if err != nil {
+			t.Fatalf("Error marshaling the chain creation transaction: %s", err)
+		}
+		mbs.incoming <- &ab.BroadcastMessage{Data: data}
+		if reply := <-mbs.outgoing; reply.Status != tc.status || !strings.Contains(reply.Info, tc.info) {
+			t.Fatalf("Expected the chain creation by the %s to be %v mentioning %q, got %v", tc.name, tc.status, tc.info, reply)
+		}
+	}
+
+	if _, ok := lf.Get([]byte("firstchain")); !ok {
+		t.Fatalf("Expected the chain authorized by the ChainCreators policy to be created")
+	}
+	if _, ok := lf.Get([]byte("secondchain")); ok {
+		t.Fatalf("Expected no chain to be created beyond the maximum")
+	}
+}
+
+func TestPolicyCacheInvalidatedByConfiguration(t *testing.T) {
+	alice, bob := newTestIdentity(t), newTestIdentity(t)
+	envelope := func(sequence uint64, writer *testIdentity) *ab.ConfigurationEnvelope {
+		entry := func(id string, lastModified uint64, policy *ab.Policy) *ab.ConfigurationEntry {
+			data, _ := policies.Marshal(policy)
+			item, _ := proto.Marshal(&ab.Configuration{ChainID: testChainID, ID: id, Type: ab.Configuration_Policy, Data: data, LastModified: lastModified, ModificationPolicy: configtx.DefaultModificationPolicyID})
+			return &ab.ConfigurationEntry{Configuration: item}
+		}
+		// The Writers policy itself is unchanged, only the sub-policy it names
+		return &ab.ConfigurationEnvelope{Sequence: sequence, ChainID: testChainID, Entries: []*ab.ConfigurationEntry{
+			entry(configtx.DefaultModificationPolicyID, 0, &ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.AcceptAllPolicy}}),
+			entry(policies.WritersPolicyID, 0, &ab.Policy{Type: &ab.Policy_Meta{Meta: &ab.MetaPolicy{Rule: ab.MetaPolicy_ANY, SubPolicies: []string{"writer"}}}}),
+			entry("writer", sequence, &ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{writer.cert})}}),
+		}}
+	}
+
+	conf := &config.TopLevel{General: config.General{Policies: config.Policies{CacheSize: 10}}}
+	configManager, policyManager := bootstrapConfigManager(conf, envelope(0, alice))
+	// The same signatures are presented again, as a repeat submitter would
+	msg := []byte("message")
+	sigs := alice.sign(t, msg)
+	policy, _ := policyManager.GetPolicy(policies.WritersPolicyID)
+	if err := policy.Evaluate(msg, sigs); err != nil {
+		t.Fatalf("Expected alice to satisfy the Writers policy: %s", err)
+	}
+
+	if err := configManager.Apply(envelope(1, bob)); err != nil {
+		t.Fatalf("Error applying the configuration: %s", err)
+	}
+	policy, _ = policyManager.GetPolicy(policies.WritersPolicyID)
+	if err := policy.Evaluate(msg, sigs); err == nil {
+		t.Fatalf("Expected alice no longer to satisfy the Writers policy once its sub-policy was changed")
+	}
+}
+
+func TestChainPoliciesFollowItsConfiguration(t *testing.T) {
+	alice, bob := newTestIdentity(t), newTestIdentity(t)
+	otherChain := []byte("otherchain")
+	envelope := func(sequence uint64, writer *testIdentity) *ab.ConfigurationEnvelope {
+		entry := func(id string, lastModified uint64, policy *ab.Policy) *ab.ConfigurationEntry {
+			data, _ := policies.Marshal(policy)
+			item, _ := proto.Marshal(&ab.Configuration{ChainID: otherChain, ID: id, Type: ab.Configuration_Policy, Data: data, LastModified: lastModified, ModificationPolicy: configtx.DefaultModificationPolicyID})
+			return &ab.ConfigurationEntry{Configuration: item}
+		}
+		return &ab.ConfigurationEnvelope{Sequence: sequence, ChainID: otherChain, Entries: []*ab.ConfigurationEntry{
+			entry(configtx.DefaultModificationPolicyID, 0, &ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.AcceptAllPolicy}}),
+			entry(policies.WritersPolicyID, sequence, &ab.Policy{Type: &ab.Policy_SignaturePolicy{SignaturePolicy: cauthdsl.Envelope(cauthdsl.SignedBy(0), [][]byte{writer.cert})}}),
+		}}
+	}
+
+	conf := &config.TopLevel{General: config.General{QueueSize: 10, BatchSize: 1, BatchTimeout: time.Second, MaxWindowSize: 100, MaxRecvMsgSize: 1024 * 1024,
+		Broadcast: config.Broadcast{AckAfterCommit: true}, Protocol: config.Protocol{Features: []string{"CHAIN_ROUTING"}}, Policies: config.Policies{CacheSize: 10}}}
+	lf := ramledger.NewFactory(10)
+	lastConfigTx, err := retrieveConfiguration(lf.GetOrCreate(testChainID, policiesGenesis(t, nil, nil)))
+	if err != nil {
+		t.Fatalf("Error retrieving the configuration: %s", err)
+	}
+	genesis, err := configtx.GenesisBlock(envelope(0, alice))
+	if err != nil {
+		t.Fatalf("Error creating the genesis block: %s", err)
+	}
+	rl := lf.GetOrCreate(otherChain, genesis)
+	configManager, policyManager := bootstrapConfigManager(conf, lastConfigTx)
+	opts := soloOptions(conf)
+	configureSolo(conf, &opts, lf, testChainID, configManager, policyManager)
+	srv, err := solo.New(opts, lf, testChainID)
+	if err != nil {
+		t.Fatal("Error creating the solo orderer:", err)
+	}
+	defer srv.Teardown()
+
+	mbs := &mockBroadcastStream{
+		incoming: make(chan *ab.BroadcastMessage),
+		outgoing: make(chan *ab.BroadcastResponse),
+	}
+	go srv.Broadcast(mbs)
+	mbs.incoming <- &ab.BroadcastMessage{Hello: conf.General.Protocol.Enabled().Hello()}
+	if reply := <-mbs.outgoing; reply.Hello == nil {
+		t.Fatalf("Expected a hello in reply, got %v", reply)
+	}
+	broadcast := func(name string, signer *testIdentity, status ab.Status) {
+		payload := []byte("from " + name)
+		data, _ := proto.Marshal(&ab.Transaction{Type: &ab.Transaction_Opaque{Opaque: payload}, Signatures: signer.sign(t, payload)})
+		mbs.incoming <- &ab.BroadcastMessage{Data: data, ChainID: otherChain}
+		if reply := <-mbs.outgoing; reply.Status != status {
+			t.Fatalf("Expected the message of %s to be %v, got %v", name, status, reply)
+		}
+	}
+	broadcast("alice", alice, ab.Status_SUCCESS)
+	broadcast("bob", bob, ab.Status_FORBIDDEN)
+
+	// The configuration is checked and applied on the chain it is for, and the policies of that chain follow it
+	data, err := configtx.MarshalConfigurationTransaction(envelope(1, bob))
+	if err != nil {
+		t.Fatalf("Error marshaling the configuration: %s", err)
+	}
+	mbs.incoming <- &ab.BroadcastMessage{Data: data, ChainID: otherChain}
+	if reply := <-mbs.outgoing; reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 2 {
+		t.Fatalf("Expected the configuration to be committed in block 2 of the chain, got %v", reply)
+	}
+	broadcast("alice", alice, ab.Status_FORBIDDEN)
+	broadcast("bob", bob, ab.Status_SUCCESS)
+	if rl.Height() != 4 {
+		t.Fatalf("Expected 4 blocks on the chain, got %d", rl.Height())
+	}
+}
+
+func TestWritersPolicyNotBypassed(t *testing.T) {
+	writer := newTestIdentity(t)
+	otherChain := []byte("otherchain")
+	general := config.General{QueueSize: 10, BatchSize: 1, BatchTimeout: time.Second, MaxWindowSize: 100, MaxRecvMsgSize: 1024 * 1024,
+		Protocol: config.Protocol{Features: []string{"CHAIN_ROUTING"}}, Policies: config.Policies{DefaultDeny: true}}
+
+	// The configuration of the other chain cannot be read, so nothing governs its configuration transactions
+	newLedger := func() (rawledger.Factory, rawledger.ReadWriter, rawledger.ReadWriter) {
+		lf := ramledger.NewFactory(10)
+		return lf, lf.GetOrCreate(testChainID, policiesGenesis(t, writer, nil)), lf.GetOrCreate(otherChain, testGenesisBlock)
+	}
+	startSolo := func() (ab.AtomicBroadcastServer, rawledger.Reader, rawledger.Reader, func()) {
+		conf := &config.TopLevel{General: general}
+		lf, rl, other := newLedger()
+		lastConfigTx, err := retrieveConfiguration(rl)
+		if err != nil {
+			t.Fatalf("Error retrieving the configuration: %s", err)
+		}
+		configManager, policyManager := bootstrapConfigManager(conf, lastConfigTx)
+		opts := soloOptions(conf)
+		configureSolo(conf, &opts, lf, testChainID, configManager, policyManager)
+		srv, err := solo.New(opts, lf, testChainID)
+		if err != nil {
+			t.Fatal("Error creating the solo orderer:", err)
+		}
+		return srv, rl, other, func() { srv.Teardown() }
+	}
+	startKafka := func() (ab.AtomicBroadcastServer, rawledger.Reader, rawledger.Reader, func()) {
+		broker := mocks.NewBroker(t, "fabric", 2)
+		conf := mocks.NewTestConfig(broker)
+		conf.General = general
+		conf.Kafka.ChainPartitions = []string{fmt.Sprintf("%x:1", otherChain)}
+		lf, rl, other := newLedger()
+		lastConfigTx, err := retrieveConfiguration(rl)
+		if err != nil {
+			t.Fatalf("Error retrieving the configuration: %s", err)
+		}
+		configManager, policyManager := bootstrapConfigManager(conf, lastConfigTx)
+		chains := newChainConfigs(conf, lf, testChainID, configManager, policyManager)
+		srv := kafka.NewWithStartOffsets(conf, lf, testChainID, nil, nil, configManager, chains.resolvePolicies, nil, nil)
+
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(10 * time.Millisecond):
+					broker.Forward(0)
+					broker.Forward(1)
+				}
+			}
+		}()
+		return srv, rl, other, func() {
+			srv.Teardown()
+			close(done)
+			broker.Close()
+		}
+	}
+
+	configData, err := configtx.MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{Sequence: 1, ChainID: otherChain})
+	if err != nil {
+		t.Fatalf("Error marshaling the configuration: %s", err)
+	}
+	genesisConfig := &ab.ConfigurationEnvelope{ChainID: []byte("newchain")}
+	envelope, _ := proto.Marshal(genesisConfig)
+	creationData, err := configtx.MarshalChainCreationTransaction(genesisConfig, writer.sign(t, envelope))
+	if err != nil {
+		t.Fatalf("Error marshaling the chain creation transaction: %s", err)
+	}
+
+	for consenter, start := range map[string]func() (ab.AtomicBroadcastServer, rawledger.Reader, rawledger.Reader, func()){"solo": startSolo, "kafka": startKafka} {
+		srv, rl, other, stop := start()
+		mbs := &mockBroadcastStream{
+			incoming: make(chan *ab.BroadcastMessage),
+			outgoing: make(chan *ab.BroadcastResponse),
+		}
+		go srv.Broadcast(mbs)
+		mbs.incoming <- &ab.BroadcastMessage{Hello: general.Protocol.Enabled().Hello()}
+		if reply := <-mbs.outgoing; reply.Hello == nil {
+			t.Fatalf("%s: expected a hello in reply, got %v", consenter, reply)
+		}
+
+		// Signed by a writer of the default chain, which authorizes neither the configuration nor the chain creation
+		for _, tc := range []struct {
+			name   string
+			msg    *ab.BroadcastMessage
+			status ab.Status
+		}{
+			{"configuration of a chain which is not managed", &ab.BroadcastMessage{Data: configData, ChainID: otherChain}, ab.Status_BAD_REQUEST},
+			{"chain creation through another chain", &ab.BroadcastMessage{Data: creationData, ChainID: otherChain}, ab.Status_BAD_REQUEST},
+			{"chain creation through the default chain", &ab.BroadcastMessage{Data: creationData}, map[string]ab.Status{"solo": ab.Status_FORBIDDEN, "kafka": ab.Status_BAD_REQUEST}[consenter]},
+		} {
+			mbs.incoming <- tc.msg
+			if reply := <-mbs.outgoing; reply.Status != tc.status {
+				t.Fatalf("%s: expected the %s to be %v, got %v", consenter, tc.name, tc.status, reply)
+			}
+		}
+
+		payload := []byte("from the writer")
+		data, _ := proto.Marshal(&ab.Transaction{Type: &ab.Transaction_Opaque{Opaque: payload}, Signatures: writer.sign(t, payload)})
+		mbs.incoming <- &ab.BroadcastMessage{Data: data}
+		if reply := <-mbs.outgoing; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("%s: expected the message of the writer to be accepted, got %v", consenter, reply)
+		}
+		if blocks := blocksOf(t, rl, 1); len(blocks[0]) != 1 || blocks[0][0] != string(data) {
+			t.Fatalf("%s: expected only the message of the writer to be ordered, got %q", consenter, blocks)
+		}
+		if other.Height() != 1 {
+			t.Fatalf("%s: expected nothing to be ordered on the other chain, it has %d blocks", consenter, other.Height())
+		}
+		stop()
+	}
+}
@@ -19,6 +19,7 @@ package rawledger_test
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	. "github.com/hyperledger/fabric/orderer/rawledger"
@@ -131,7 +132,7 @@ func testAddition(lf ledgerFactory, t *testing.T) {
 	if block == nil {
 		t.Fatalf("Error retrieving genesis block")
 	}
-	if !bytes.Equal(block.PrevHash, prevHash) {
+	if !bytes.Equal(block.Header.PreviousHash, prevHash) {
 		t.Fatalf("Block hashes did no match")
 	}
 }
@@ -157,7 +158,7 @@ func testRetrieval(lf ledgerFactory, t *testing.T) {
 	if status != ab.Status_SUCCESS {
 		t.Fatalf("Expected to successfully read the genesis block")
 	}
-	if block.Number != 0 {
+	if block.Header.Number != 0 {
 		t.Fatalf("Expected to successfully retrieve the genesis block")
 	}
 	signal = it.ReadyChan()
@@ -170,8 +171,8 @@ func testRetrieval(lf ledgerFactory, t *testing.T) {
 	if status != ab.Status_SUCCESS {
 		t.Fatalf("Expected to successfully read the second block")
 	}
-	if block.Number != 1 {
-		t.Fatalf("Expected to successfully retrieve the second block but got block number %d", block.Number)
+	if block.Header.Number != 1 {
+		t.Fatalf("Expected to successfully retrieve the second block but got block number %d", block.Header.Number)
 	}
 }
 
@@ -201,7 +202,118 @@ func testBlockedRetrieval(lf ledgerFactory, t *testing.T) {
 	if status != ab.Status_SUCCESS {
 		t.Fatalf("Expected to successfully read the second block")
 	}
-	if block.Number != 1 {
+	if block.Header.Number != 1 {
 		t.Fatalf("Expected to successfully retrieve the second block")
 	}
 }
+
+func TestOldestRetained(t *testing.T) {
+	allTest(t, testOldestRetained)
+}
+
+func testOldestRetained(lf ledgerFactory, t *testing.T) {
+	li := lf.New()
+	if li.OldestRetained() != 0 {
+		t.Fatalf("Expected the genesis block to be the oldest retained")
+	}
+	li.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("My Data")}}, nil)
+	oldest := li.OldestRetained()
+	if getBlock(oldest, li) == nil {
+		t.Fatalf("Expected to retrieve the oldest retained block %d", oldest)
+	}
+}
+
+func TestGetBlock(t *testing.T) {
+	allTest(t, testGetBlock)
+}
+
+func testGetBlock(lf ledgerFactory, t *testing.T) {
+	li := lf.New()
+	appended := li.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("My Data")}}, nil)
+
+	block, status := li.GetBlock(1)
+	if status != ab.Status_SUCCESS {
+		t.Fatalf("Expected to retrieve block 1 but got %v", status)
+	}
+	if !bytes.Equal(block.Hash(), appended.Hash()) {
+		t.Fatalf("Retrieved a different block than was appended")
+	}
+
+	if _, status = li.GetBlock(2); status != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected NOT_FOUND for a block beyond the tail but got %v", status)
+	}
+}
+
+func TestSeekTimestamp(t *testing.T) {
+	allTest(t, testSeekTimestamp)
+}
+
+func testSeekTimestamp(lf ledgerFactory, t *testing.T) {
+	li := lf.New()
+	base := time.Unix(1000, 0)
+	// The genesis block predates timestamps, blocks 1 through 4 are committed at 10, 20, 20, and 30 seconds after base
+	for _, offset := range []int{10, 20, 20, 30} {
+		committed := base.Add(time.Duration(offset) * time.Second)
+		li.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("My Data")}}, func(block *ab.Block) { block.SetTimestamp(committed) })
+	}
+
+	for _, c := range []struct {
+		offset   time.Duration
+		expected uint64
+	}{
+		{0, 1},
+		{10 * time.Second, 1},
+		{15 * time.Second, 2},
+		{20 * time.Second, 2},
+		{25 * time.Second, 4},
+		{30 * time.Second, 4},
+	} {
+		number, status := SeekTimestamp(li, base.Add(c.offset))
+		if status != ab.Status_SUCCESS || number != c.expected {
+			t.Fatalf("Seeking %v after base expected block %d but got %d with status %v", c.offset, c.expected, number, status)
+		}
+	}
+
+	if _, status := SeekTimestamp(li, base.Add(30*time.Second+time.Nanosecond)); status != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected NOT_FOUND seeking after the newest block but got %v", status)
+	}
+}
+
+func TestTimestamper(t *testing.T) {
+	allTest(t, testTimestamper)
+}
+
+func testTimestamper(lf ledgerFactory, t *testing.T) {
+	li := lf.New()
+	ts := NewTimestamper(li)
+	base := time.Unix(1000, 0)
+	stampAt := func(now time.Time) time.Time {
+		block := li.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("My Data")}}, func(block *ab.Block) { ts.Stamp(block, now) })
+		committed, ok := getBlock(block.Header.Number, li).Timestamp()
+		if !ok {
+			t.Fatalf("Expected block %d to record its timestamp", block.Header.Number)
+		}
+		return committed
+	}
+
+	if committed := stampAt(base); !committed.Equal(base) {
+		t.Fatalf("Expected the block to be stamped with the current time %v but got %v", base, committed)
+	}
+	if committed := stampAt(base.Add(time.Second)); !committed.Equal(base.Add(time.Second)) {
+		t.Fatalf("Expected the block to be stamped with the current time %v but got %v", base.Add(time.Second), committed)
+	}
+
+	// The clock steps back behind the previous block, which becomes the floor
+	if committed := stampAt(base.Add(-time.Hour)); !committed.Equal(base.Add(time.Second)) {
+		t.Fatalf("Expected the block to be stamped with the previous timestamp %v but got %v", base.Add(time.Second), committed)
+	}
+
+	if !lf.Persistent() {
+		return
+	}
+	li = lf.New()
+	ts = NewTimestamper(li)
+	if committed := stampAt(base); !committed.Equal(base.Add(time.Second)) {
+		t.Fatalf("Expected the floor to be recovered from the tail of the ledger, but the block was stamped with %v", committed)
+	}
+}
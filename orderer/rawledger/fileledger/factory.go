@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileledger
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+)
+
+const chainDirectoryFormatString string = "chain_%s"
+
+type fileLedgerFactory struct {
+	directory  string
+	syncWrites bool
+	lock       sync.Mutex
+	ledgers    map[string]rawledger.ReadWriter
+}
+
+// NewFactory creates a factory which stores the ledger of each chain in its own subdirectory of directory
+func NewFactory(directory string) rawledger.Factory {
+	return NewFactoryWithSyncWrites(directory, false)
+}
+
+// NewFactoryWithSyncWrites creates a factory as NewFactory does, whose ledgers sync each block to disk before it is appended if syncWrites is set
+func NewFactoryWithSyncWrites(directory string, syncWrites bool) rawledger.Factory {
+	logger.Debugf("Initializing fileLedger factory at '%s'", directory)
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		panic(err)
+	}
+	return &fileLedgerFactory{
+		directory:  directory,
+		syncWrites: syncWrites,
+		ledgers:    make(map[string]rawledger.ReadWriter),
+	}
+}
+
+// chainDirectory returns the fully qualified path to the directory where the blocks of a chain are stored
+func (flf *fileLedgerFactory) chainDirectory(chainID []byte) string {
+	return flf.directory + "/" + fmt.Sprintf(chainDirectoryFormatString, hex.EncodeToString(chainID))
+}
+
+// GetOrCreate returns the ledger for a chain, creating it from the genesis block if it does not already exist
+func (flf *fileLedgerFactory) GetOrCreate(chainID []byte, genesisBlock *ab.Block) rawledger.ReadWriter {
+	flf.lock.Lock()
+	defer flf.lock.Unlock()
+
+	key := string(chainID)
+	fl, ok := flf.ledgers[key]
+	if !ok {
+		fl = NewWithSyncWrites(flf.chainDirectory(chainID), genesisBlock, flf.syncWrites)
+		flf.ledgers[key] = fl
+	}
+	return fl
+}
+
+// Get returns the ledger for a chain, or false if the chain does not exist, chains created by an earlier process are opened on first use
+func (flf *fileLedgerFactory) Get(chainID []byte) (rawledger.ReadWriter, bool) {
+	flf.lock.Lock()
+	defer flf.lock.Unlock()
+
+	key := string(chainID)
+	if fl, ok := flf.ledgers[key]; ok {
+		return fl, true
+	}
+
+	fl := newFileLedger(flf.chainDirectory(chainID), flf.syncWrites)
+	if _, err := os.Stat(fl.blockFilename(0)); err != nil {
+		return nil, false
+	}
+	fl.initializeBlockHeight()
+	flf.ledgers[key] = fl
+	return fl, true
+}
+
+// ChainIDs returns the IDs of all chains which exist, including those created by an earlier process
+func (flf *fileLedgerFactory) ChainIDs() [][]byte {
+	infos, err := ioutil.ReadDir(flf.directory)
+	if err != nil {
+		panic(err)
+	}
+
+	var ids [][]byte
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		var encoded string
+		if _, err := fmt.Sscanf(info.Name(), chainDirectoryFormatString, &encoded); err != nil {
+			continue
+		}
+		chainID, err := hex.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, chainID)
+	}
+	return ids
+}
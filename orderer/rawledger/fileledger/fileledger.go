@@ -17,9 +17,11 @@ limitations under the License.
 package fileledger
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sync"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/rawledger"
@@ -47,25 +49,28 @@ type cursor struct {
 type fileLedger struct {
 	directory      string
 	fqFormatString string
+	lock           sync.Mutex // Guards height, signal, and lastHash
 	height         uint64
 	signal         chan struct{}
 	lastHash       []byte
 	marshaler      *jsonpb.Marshaler
+	syncWrites     bool // Whether each block is synced to disk before it is appended
 }
 
 // New creates a new instance of the file ledger
 func New(directory string, genesisBlock *ab.Block) rawledger.ReadWriter {
+	return NewWithSyncWrites(directory, genesisBlock, false)
+}
+
+// NewWithSyncWrites creates a new instance of the file ledger which, if syncWrites is set, syncs each block to disk before
+// it is appended, so that a block which has been appended survives a crash of the host and not only of the process
+func NewWithSyncWrites(directory string, genesisBlock *ab.Block, syncWrites bool) rawledger.ReadWriter {
 	logger.Debugf("Initializing fileLedger at '%s'", directory)
 	if err := os.MkdirAll(directory, 0700); err != nil {
 		panic(err)
 	}
-	fl := &fileLedger{
-		directory:      directory,
-		fqFormatString: directory + "/" + blockFileFormatString,
-		signal:         make(chan struct{}),
-		marshaler:      &jsonpb.Marshaler{Indent: "  "},
-	}
-	if _, err := os.Stat(fl.blockFilename(genesisBlock.Number)); os.IsNotExist(err) {
+	fl := newFileLedger(directory, syncWrites)
+	if _, err := os.Stat(fl.blockFilename(genesisBlock.Header.Number)); os.IsNotExist(err) {
 		fl.writeBlock(genesisBlock)
 	}
 	fl.initializeBlockHeight()
@@ -73,6 +78,16 @@ func New(directory string, genesisBlock *ab.Block) rawledger.ReadWriter {
 	return fl
 }
 
+func newFileLedger(directory string, syncWrites bool) *fileLedger {
+	return &fileLedger{
+		directory:      directory,
+		fqFormatString: directory + "/" + blockFileFormatString,
+		signal:         make(chan struct{}),
+		marshaler:      &jsonpb.Marshaler{Indent: "  "},
+		syncWrites:     syncWrites,
+	}
+}
+
 // initializeBlockHeight verifies all blocks exist between 0 and the block height, and populates the lastHash
 func (fl *fileLedger) initializeBlockHeight() {
 	infos, err := ioutil.ReadDir(fl.directory)
@@ -110,32 +125,61 @@ func (fl *fileLedger) blockFilename(number uint64) string {
 	return fmt.Sprintf(fl.fqFormatString, number)
 }
 
-// writeBlock commits a block to disk
+// writeBlock commits a block to disk, the block is written to a temporary file first so that readers never observe a partial block
 func (fl *fileLedger) writeBlock(block *ab.Block) {
-	file, err := os.Create(fl.blockFilename(block.Number))
+	name := fl.blockFilename(block.Header.Number)
+	file, err := ioutil.TempFile(fl.directory, "tmp_block_")
 	if err != nil {
 		panic(err)
 	}
-	defer file.Close()
 	err = fl.marshaler.Marshal(file, block)
-	logger.Debugf("Wrote block %d", block.Number)
 	if err != nil {
 		panic(err)
 	}
+	if fl.syncWrites {
+		if err = file.Sync(); err != nil {
+			panic(err)
+		}
+	}
+	if err = file.Close(); err != nil {
+		panic(err)
+	}
+	if err = os.Rename(file.Name(), name); err != nil {
+		panic(err)
+	}
+	if fl.syncWrites {
+		fl.syncDirectory()
+	}
+	logger.Debugf("Wrote block %d", block.Header.Number)
+}
 
+// syncDirectory syncs the directory to disk, so that the rename of a block file is as durable as its contents
+func (fl *fileLedger) syncDirectory() {
+	dir, err := os.Open(fl.directory)
+	if err != nil {
+		panic(err)
+	}
+	defer dir.Close()
+	if err = dir.Sync(); err != nil {
+		panic(err)
+	}
 }
 
 // readBlock returns the block or nil, and whether the block was found or not, (nil,true) generally indicates an irrecoverable problem
 func (fl *fileLedger) readBlock(number uint64) (*ab.Block, bool) {
-	file, err := os.Open(fl.blockFilename(number))
+	data, err := ioutil.ReadFile(fl.blockFilename(number))
 	if err == nil {
-		defer file.Close()
 		block := &ab.Block{}
-		err = jsonpb.Unmarshal(file, block)
+		err = jsonpb.Unmarshal(bytes.NewReader(data), block)
 		if err != nil {
-			return nil, true
+			// The fields of the two shapes are disjoint, so a block only unmarshals as a legacy block if it was written as one
+			legacy := &ab.LegacyBlock{}
+			if jsonpb.Unmarshal(bytes.NewReader(data), legacy) != nil {
+				return nil, true
+			}
+			block = upgradeLegacyBlock(legacy)
 		}
-		logger.Debugf("Read block %d", block.Number)
+		logger.Debugf("Read block %d", block.Header.Number)
 		return block, true
 	}
 	return nil, false
@@ -143,34 +187,59 @@ func (fl *fileLedger) readBlock(number uint64) (*ab.Block, bool) {
 
 // Height returns the highest block number in the chain, plus one
 func (fl *fileLedger) Height() uint64 {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
 	return fl.height
 }
 
+// OldestRetained returns the number of the oldest block in the chain, which is always the genesis block
+func (fl *fileLedger) OldestRetained() uint64 {
+	return 0
+}
+
 // Append creates a new block and appends it to the ledger
-func (fl *fileLedger) Append(messages []*ab.BroadcastMessage, proof []byte) *ab.Block {
-	block := &ab.Block{
-		Number:   fl.height,
-		PrevHash: fl.lastHash,
-		Messages: messages,
-		Proof:    proof,
+func (fl *fileLedger) Append(messages []*ab.BroadcastMessage, seal rawledger.Sealer) *ab.Block {
+	return fl.AppendEncoded(messages, nil, seal)
+}
+
+// AppendEncoded creates a new block, hashing the marshaled messages supplied rather than marshaling them again
+func (fl *fileLedger) AppendEncoded(messages []*ab.BroadcastMessage, encoded [][]byte, seal rawledger.Sealer) *ab.Block {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	block := ab.NewBlock(fl.height, fl.lastHash, messages, encoded)
+	if seal != nil {
+		seal(block)
 	}
 	fl.writeBlock(block)
 	fl.height++
+	fl.lastHash = block.Hash()
 	close(fl.signal)
 	fl.signal = make(chan struct{})
 	return block
 }
 
+// GetBlock returns the block with the given number, or NOT_FOUND if it has not yet been created
+func (fl *fileLedger) GetBlock(number uint64) (*ab.Block, ab.Status) {
+	block, found := fl.readBlock(number)
+	if !found {
+		return nil, ab.Status_NOT_FOUND
+	}
+	if block == nil {
+		return nil, ab.Status_SERVICE_UNAVAILABLE
+	}
+	return block, ab.Status_SUCCESS
+}
+
 // Iterator implements the rawledger.Reader definition
 func (fl *fileLedger) Iterator(startType ab.SeekInfo_StartType, specified uint64) (rawledger.Iterator, uint64) {
 	switch startType {
 	case ab.SeekInfo_OLDEST:
 		return &cursor{fl: fl, blockNumber: 0}, 0
 	case ab.SeekInfo_NEWEST:
-		high := fl.height - 1
+		high := fl.Height() - 1
 		return &cursor{fl: fl, blockNumber: high}, high
 	case ab.SeekInfo_SPECIFIED:
-		if specified > fl.height {
+		if specified > fl.Height() {
 			return &rawledger.NotFoundErrorIterator{}, 0
 		}
 		return &cursor{fl: fl, blockNumber: specified}, specified
@@ -184,6 +253,8 @@ func (fl *fileLedger) Iterator(startType ab.SeekInfo_StartType, specified uint64
 func (cu *cursor) Next() (*ab.Block, ab.Status) {
 	// This only loops once, as signal reading indicates the new block has been written
 	for {
+		// The signal must be retrieved before reading, otherwise a block written in between would be missed
+		signal := cu.fl.currentSignal()
 		block, found := cu.fl.readBlock(cu.blockNumber)
 		if found {
 			if block == nil {
@@ -192,13 +263,20 @@ func (cu *cursor) Next() (*ab.Block, ab.Status) {
 			cu.blockNumber++
 			return block, ab.Status_SUCCESS
 		}
-		<-cu.fl.signal
+		<-signal
 	}
 }
 
+// currentSignal returns the channel which will be closed when the next block is appended
+func (fl *fileLedger) currentSignal() chan struct{} {
+	fl.lock.Lock()
+	defer fl.lock.Unlock()
+	return fl.signal
+}
+
 // ReadyChan returns a channel that will close when Next is ready to be called without blocking
 func (cu *cursor) ReadyChan() <-chan struct{} {
-	signal := cu.fl.signal
+	signal := cu.fl.currentSignal()
 	if _, err := os.Stat(cu.fl.blockFilename(cu.blockNumber)); os.IsNotExist(err) {
 		return signal
 	}
@@ -101,7 +101,7 @@ func TestAddition(t *testing.T) {
 	if block == nil || !found {
 		t.Fatalf("Error retrieving genesis block")
 	}
-	if !bytes.Equal(block.PrevHash, prevHash) {
+	if !bytes.Equal(block.Header.PreviousHash, prevHash) {
 		t.Fatalf("Block hashes did no match")
 	}
 }
@@ -124,7 +124,7 @@ func TestRetrieval(t *testing.T) {
 	if status != ab.Status_SUCCESS {
 		t.Fatalf("Expected to successfully read the genesis block")
 	}
-	if block.Number != 0 {
+	if block.Header.Number != 0 {
 		t.Fatalf("Expected to successfully retrieve the genesis block")
 	}
 	signal = it.ReadyChan()
@@ -137,8 +137,8 @@ func TestRetrieval(t *testing.T) {
 	if status != ab.Status_SUCCESS {
 		t.Fatalf("Expected to successfully read the second block")
 	}
-	if block.Number != 1 {
-		t.Fatalf("Expected to successfully retrieve the second block but got block number %d", block.Number)
+	if block.Header.Number != 1 {
+		t.Fatalf("Expected to successfully retrieve the second block but got block number %d", block.Header.Number)
 	}
 }
 
@@ -165,7 +165,79 @@ func TestBlockedRetrieval(t *testing.T) {
 	if status != ab.Status_SUCCESS {
 		t.Fatalf("Expected to successfully read the second block")
 	}
-	if block.Number != 1 {
+	if block.Header.Number != 1 {
 		t.Fatalf("Expected to successfully retrieve the second block")
 	}
 }
+
+func TestFactoryReopen(t *testing.T) {
+	tev, _ := initialize(t)
+	defer tev.tearDown()
+
+	flf := NewFactory(tev.location)
+	if _, ok := flf.Get([]byte("foo")); ok {
+		t.Fatalf("Should not have found a chain which was never created")
+	}
+	fl := flf.GetOrCreate([]byte("foo"), genesisBlock)
+	fl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("My Data")}}, nil)
+	flf.GetOrCreate([]byte("bar"), genesisBlock)
+
+	flf = NewFactory(tev.location)
+	if len(flf.ChainIDs()) != 2 {
+		t.Fatalf("Expected 2 chains after reopening but got %d", len(flf.ChainIDs()))
+	}
+	fl, ok := flf.Get([]byte("foo"))
+	if !ok {
+		t.Fatalf("Should have found the chain created before reopening")
+	}
+	if fl.Height() != 2 {
+		t.Fatalf("Expected height 2 after reopening but got %d", fl.Height())
+	}
+	if fl, _ := flf.Get([]byte("bar")); fl.Height() != 1 {
+		t.Fatalf("Chains should not share blocks")
+	}
+}
+
+// legacyBlock is block 1 as it was written before the header was split out
+const legacyBlock = `{
+  "number": "1",
+  "prevHash": "cHJldmlvdXM=",
+  "proof": "cHJvb2Y=",
+  "messages": [
+    {
+      "data": "bGVnYWN5"
+    }
+  ]
+}`
+
+func TestLegacyBlockUpgrade(t *testing.T) {
+	tev, ofl := initialize(t)
+	defer tev.tearDown()
+	if err := ioutil.WriteFile(ofl.blockFilename(1), []byte(legacyBlock), 0600); err != nil {
+		t.Fatalf("Error writing legacy block: %s", err)
+	}
+
+	fl := New(tev.location, genesisBlock).(*fileLedger)
+	if fl.height != 2 {
+		t.Fatalf("Block height should be 2")
+	}
+	block, found := fl.readBlock(1)
+	if block == nil || !found {
+		t.Fatalf("Error retrieving legacy block")
+	}
+	if block.Header.Number != 1 || !bytes.Equal(block.Header.PreviousHash, []byte("previous")) {
+		t.Fatalf("Expected the legacy header to be kept, got %v", block.Header)
+	}
+	if len(block.Data.Messages) != 1 || string(block.Data.Messages[0].Data) != "legacy" || !bytes.Equal(block.Header.DataHash, block.Data.Hash()) {
+		t.Fatalf("Expected the legacy messages to be committed to by the data hash, got %v", block.Data)
+	}
+	if !bytes.Equal(block.Proof(), []byte("proof")) {
+		t.Fatalf("Expected the legacy proof to be moved to the metadata, got %x", block.Proof())
+	}
+
+	fl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("My Data")}}, nil)
+	next, _ := fl.readBlock(2)
+	if next == nil || !bytes.Equal(next.Header.PreviousHash, block.Hash()) {
+		t.Fatalf("Expected the first block after the upgrade to link to the converted legacy block")
+	}
+}
@@ -0,0 +1,30 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileledger
+
+import (
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+// upgradeLegacyBlock converts a block written before the header was split out into the current shape
+// The previous hash is kept as it was recorded, so the blocks of a legacy ledger remain linked by the hashes they were written with,
+// while the blocks appended after the upgrade link to the header hash of the last converted block
+func upgradeLegacyBlock(legacy *ab.LegacyBlock) *ab.Block {
+	block := ab.NewBlock(legacy.Number, legacy.PrevHash, legacy.Messages, nil)
+	block.SetProof(legacy.Proof)
+	return block
+}
@@ -36,14 +36,16 @@ func init() {
 		panic("Error intializing static bootstrap genesis block")
 	}
 
-	testables = append(testables, &fileLedgerTestEnv{})
+	testables = append(testables, &fileLedgerTestEnv{}, &fileLedgerTestEnv{syncWrites: true})
 }
 
 type fileLedgerFactory struct {
-	location string
+	location   string
+	syncWrites bool
 }
 
 type fileLedgerTestEnv struct {
+	syncWrites bool
 }
 
 func (env *fileLedgerTestEnv) Initialize() (ledgerFactory, error) {
@@ -52,10 +54,13 @@ func (env *fileLedgerTestEnv) Initialize() (ledgerFactory, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &fileLedgerFactory{location: location}, nil
+	return &fileLedgerFactory{location: location, syncWrites: env.syncWrites}, nil
 }
 
 func (env *fileLedgerTestEnv) Name() string {
+	if env.syncWrites {
+		return "fileledger with synced writes"
+	}
 	return "fileledger"
 }
 
@@ -69,5 +74,5 @@ func (env *fileLedgerFactory) Persistent() bool {
 }
 
 func (env *fileLedgerFactory) New() ReadWriter {
-	return fileledger.New(env.location, genesisBlock)
+	return fileledger.NewWithSyncWrites(env.location, genesisBlock, env.syncWrites)
 }
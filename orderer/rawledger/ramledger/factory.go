@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ramledger
+
+import (
+	"sync"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+)
+
+type ramLedgerFactory struct {
+	maxSize int
+	lock    sync.Mutex
+	ledgers map[string]rawledger.ReadWriter
+}
+
+// NewFactory creates a factory whose ledgers each retain at most maxSize blocks
+func NewFactory(maxSize int) rawledger.Factory {
+	return &ramLedgerFactory{
+		maxSize: maxSize,
+		ledgers: make(map[string]rawledger.ReadWriter),
+	}
+}
+
+// GetOrCreate returns the ledger for a chain, creating it from the genesis block if it does not already exist
+func (rlf *ramLedgerFactory) GetOrCreate(chainID []byte, genesisBlock *ab.Block) rawledger.ReadWriter {
+	rlf.lock.Lock()
+	defer rlf.lock.Unlock()
+
+	key := string(chainID)
+	rl, ok := rlf.ledgers[key]
+	if !ok {
+		rl = New(rlf.maxSize, genesisBlock)
+		rlf.ledgers[key] = rl
+	}
+	return rl
+}
+
+// Get returns the ledger for a chain, or false if the chain does not exist
+func (rlf *ramLedgerFactory) Get(chainID []byte) (rawledger.ReadWriter, bool) {
+	rlf.lock.Lock()
+	defer rlf.lock.Unlock()
+
+	rl, ok := rlf.ledgers[string(chainID)]
+	return rl, ok
+}
+
+// ChainIDs returns the IDs of all chains which exist
+func (rlf *ramLedgerFactory) ChainIDs() [][]byte {
+	rlf.lock.Lock()
+	defer rlf.lock.Unlock()
+
+	ids := make([][]byte, 0, len(rlf.ledgers))
+	for key := range rlf.ledgers {
+		ids = append(ids, []byte(key))
+	}
+	return ids
+}
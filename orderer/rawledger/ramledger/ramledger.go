@@ -17,6 +17,8 @@ limitations under the License.
 package ramledger
 
 import (
+	"sync"
+
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 	"github.com/hyperledger/fabric/orderer/rawledger"
 
@@ -37,11 +39,13 @@ type simpleList struct {
 	next   *simpleList
 	signal chan struct{}
 	block  *ab.Block
+	hash   []byte // The hash of block, computed once when it is appended
 }
 
 type ramLedger struct {
 	maxSize int
-	size    int
+	lock    sync.Mutex    // Guards index, oldest, and newest
+	index   []*simpleList // The retained blocks by number, from oldest to newest
 	oldest  *simpleList
 	newest  *simpleList
 }
@@ -50,29 +54,54 @@ type ramLedger struct {
 func New(maxSize int, genesis *ab.Block) rawledger.ReadWriter {
 	rl := &ramLedger{
 		maxSize: maxSize,
-		size:    1,
 		oldest: &simpleList{
 			signal: make(chan struct{}),
 			block:  genesis,
+			hash:   genesis.Hash(),
 		},
 	}
 	rl.newest = rl.oldest
+	rl.index = []*simpleList{rl.oldest}
 	return rl
 }
 
 // Height returns the highest block number in the chain, plus one
 func (rl *ramLedger) Height() uint64 {
-	return rl.newest.block.Number + 1
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	return rl.newest.block.Header.Number + 1
+}
+
+// OldestRetained returns the number of the oldest block which has not been discarded from the history
+func (rl *ramLedger) OldestRetained() uint64 {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	return rl.oldest.block.Header.Number
+}
+
+// GetBlock returns the block with the given number, or NOT_FOUND if it has been discarded from the history or not yet created
+func (rl *ramLedger) GetBlock(number uint64) (*ab.Block, ab.Status) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	if number < rl.oldest.block.Header.Number || number > rl.newest.block.Header.Number {
+		return nil, ab.Status_NOT_FOUND
+	}
+
+	return rl.index[number-rl.oldest.block.Header.Number].block, ab.Status_SUCCESS
 }
 
 // Iterator implements the rawledger.Reader definition
 func (rl *ramLedger) Iterator(startType ab.SeekInfo_StartType, specified uint64) (rawledger.Iterator, uint64) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
 	var list *simpleList
 	switch startType {
 	case ab.SeekInfo_OLDEST:
 		oldest := rl.oldest
 		list = &simpleList{
-			block:  &ab.Block{Number: oldest.block.Number - 1},
+			block:  &ab.Block{Header: &ab.BlockHeader{Number: oldest.block.Header.Number - 1}},
 			next:   oldest,
 			signal: make(chan struct{}),
 		}
@@ -80,20 +109,20 @@ func (rl *ramLedger) Iterator(startType ab.SeekInfo_StartType, specified uint64)
 	case ab.SeekInfo_NEWEST:
 		newest := rl.newest
 		list = &simpleList{
-			block:  &ab.Block{Number: newest.block.Number - 1},
+			block:  &ab.Block{Header: &ab.BlockHeader{Number: newest.block.Header.Number - 1}},
 			next:   newest,
 			signal: make(chan struct{}),
 		}
 		close(list.signal)
 	case ab.SeekInfo_SPECIFIED:
 		oldest := rl.oldest
-		if specified < oldest.block.Number || specified > rl.newest.block.Number+1 {
+		if specified < oldest.block.Header.Number || specified > rl.newest.block.Header.Number+1 {
 			return &rawledger.NotFoundErrorIterator{}, 0
 		}
 
-		if specified == oldest.block.Number {
+		if specified == oldest.block.Header.Number {
 			list = &simpleList{
-				block:  &ab.Block{Number: oldest.block.Number - 1},
+				block:  &ab.Block{Header: &ab.BlockHeader{Number: oldest.block.Header.Number - 1}},
 				next:   oldest,
 				signal: make(chan struct{}),
 			}
@@ -101,28 +130,17 @@ func (rl *ramLedger) Iterator(startType ab.SeekInfo_StartType, specified uint64)
 			break
 		}
 
-		list = oldest
-		for {
-			if list.block.Number == specified-1 {
-				break
-			}
-			list = list.next // No need for nil check, because of range check above
-		}
+		list = rl.index[specified-1-oldest.block.Header.Number]
 	}
-	return &cursor{list: list}, list.block.Number + 1
+	return &cursor{list: list}, list.block.Header.Number + 1
 }
 
 // Next blocks until there is a new block available, or returns an error if the next block is no longer retrievable
 func (cu *cursor) Next() (*ab.Block, ab.Status) {
-	// This only loops once, as signal reading indicates non-nil next
-	for {
-		if cu.list.next != nil {
-			cu.list = cu.list.next
-			return cu.list.block, ab.Status_SUCCESS
-		}
-
-		<-cu.list.signal
-	}
+	// The signal is only closed once next has been set
+	<-cu.list.signal
+	cu.list = cu.list.next
+	return cu.list.block, ab.Status_SUCCESS
 }
 
 // ReadyChan returns a channel that will close when Next is ready to be called without blocking
@@ -131,32 +149,41 @@ func (cu *cursor) ReadyChan() <-chan struct{} {
 }
 
 // Append creates a new block and appends it to the ledger
-func (rl *ramLedger) Append(messages []*ab.BroadcastMessage, proof []byte) *ab.Block {
-	block := &ab.Block{
-		Number:   rl.newest.block.Number + 1,
-		PrevHash: rl.newest.block.Hash(),
-		Messages: messages,
-		Proof:    proof,
+func (rl *ramLedger) Append(messages []*ab.BroadcastMessage, seal rawledger.Sealer) *ab.Block {
+	return rl.AppendEncoded(messages, nil, seal)
+}
+
+// AppendEncoded creates a new block, hashing the marshaled messages supplied rather than marshaling them again
+func (rl *ramLedger) AppendEncoded(messages []*ab.BroadcastMessage, encoded [][]byte, seal rawledger.Sealer) *ab.Block {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	block := ab.NewBlock(rl.newest.block.Header.Number+1, rl.newest.hash, messages, encoded)
+	if seal != nil {
+		seal(block)
 	}
-	rl.appendBlock(block)
+	rl.appendBlock(block, block.Hash())
 	return block
 }
 
-func (rl *ramLedger) appendBlock(block *ab.Block) {
+func (rl *ramLedger) appendBlock(block *ab.Block, hash []byte) {
 	rl.newest.next = &simpleList{
 		signal: make(chan struct{}),
 		block:  block,
+		hash:   hash,
 	}
 
 	lastSignal := rl.newest.signal
-	logger.Debugf("Sending signal that block %d has a successor", rl.newest.block.Number)
+	logger.Debugf("Sending signal that block %d has a successor", rl.newest.block.Header.Number)
 	rl.newest = rl.newest.next
 	close(lastSignal)
 
-	rl.size++
+	rl.index = append(rl.index, rl.newest)
 
-	if rl.size > rl.maxSize {
-		rl.oldest = rl.oldest.next
-		rl.size--
+	if len(rl.index) > rl.maxSize {
+		// The discarded block is released by the index, though a reader holding it may still follow it to its successors
+		rl.index[0] = nil
+		rl.index = rl.index[1:]
+		rl.oldest = rl.index[0]
 	}
 }
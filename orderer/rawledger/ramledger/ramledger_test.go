@@ -17,6 +17,7 @@ limitations under the License.
 package ramledger
 
 import (
+	"fmt"
 	"testing"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
@@ -41,16 +42,16 @@ func TestAppend(t *testing.T) {
 	rl := New(maxSize, genesisBlock).(*ramLedger)
 	var blocks []*ab.Block
 	for i := 0; i < 3; i++ {
-		blocks = append(blocks, &ab.Block{Number: uint64(i + 1)})
-		rl.appendBlock(blocks[i])
+		blocks = append(blocks, &ab.Block{Header: &ab.BlockHeader{Number: uint64(i + 1)}})
+		rl.appendBlock(blocks[i], nil)
 	}
 	item := rl.oldest
 	for i := 0; i < 3; i++ {
 		if item.block == nil {
 			t.Fatalf("Block for item %d should not be nil", i)
 		}
-		if item.block.Number != blocks[i].Number {
-			t.Errorf("Expected block %d to be %d but got %d", i, blocks[i].Number, item.block.Number)
+		if item.block.Header.Number != blocks[i].Header.Number {
+			t.Errorf("Expected block %d to be %d but got %d", i, blocks[i].Header.Number, item.block.Header.Number)
 		}
 		if i != 2 && item.next == nil {
 			t.Fatalf("Next item should not be nil")
@@ -70,7 +71,7 @@ func TestSignal(t *testing.T) {
 		t.Fatalf("There is no successor, there should be no signal to continue")
 	default:
 	}
-	rl.appendBlock(&ab.Block{Number: 1})
+	rl.appendBlock(&ab.Block{Header: &ab.BlockHeader{Number: 1}}, nil)
 	select {
 	case <-item.signal:
 	default:
@@ -87,7 +88,7 @@ func TestTruncationSafety(t *testing.T) {
 	rl := New(maxSize, genesisBlock).(*ramLedger)
 	item := rl.oldest
 	for i := 0; i < newBlocks; i++ {
-		rl.appendBlock(&ab.Block{Number: uint64(i + 1)})
+		rl.appendBlock(&ab.Block{Header: &ab.BlockHeader{Number: uint64(i + 1)}}, nil)
 	}
 	count := 0
 	for item.next != nil {
@@ -99,3 +100,63 @@ func TestTruncationSafety(t *testing.T) {
 		t.Fatalf("The iterator should have found %d new blocks but found %d", newBlocks, count)
 	}
 }
+
+func TestOldestRetained(t *testing.T) {
+	maxSize := 3
+	rl := New(maxSize, genesisBlock)
+	for i := 0; i < 2*maxSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("My Data")}}, nil)
+	}
+	expected := uint64(2*maxSize + 1 - maxSize)
+	if rl.OldestRetained() != expected {
+		t.Fatalf("Expected oldest retained block to be %d but got %d", expected, rl.OldestRetained())
+	}
+}
+
+func TestGetBlock(t *testing.T) {
+	maxSize := 3
+	rl := New(maxSize, genesisBlock)
+	for i := 0; i < 2*maxSize; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}}, nil)
+	}
+
+	for number := uint64(0); number <= uint64(2*maxSize)+1; number++ {
+		block, status := rl.GetBlock(number)
+		retained := number >= rl.OldestRetained() && number < rl.Height()
+		switch {
+		case retained && (status != ab.Status_SUCCESS || block.Header.Number != number):
+			t.Fatalf("Expected block %d to be retrieved, got %v", number, status)
+		case !retained && status != ab.Status_NOT_FOUND:
+			t.Fatalf("Expected block %d not to be found, got %v", number, status)
+		}
+	}
+
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, rl.OldestRetained()+1)
+	if block, _ := it.Next(); block.Header.Number != rl.OldestRetained()+1 {
+		t.Fatalf("Expected the iterator to start at block %d, got %d", rl.OldestRetained()+1, block.Header.Number)
+	}
+}
+
+func TestFactory(t *testing.T) {
+	rlf := NewFactory(3)
+	if _, ok := rlf.Get([]byte("foo")); ok {
+		t.Fatalf("Should not have found a chain which was never created")
+	}
+
+	rl := rlf.GetOrCreate([]byte("foo"), genesisBlock)
+	rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("My Data")}}, nil)
+	rlf.GetOrCreate([]byte("bar"), genesisBlock)
+
+	if other, ok := rlf.Get([]byte("foo")); !ok || other != rl {
+		t.Fatalf("Should have retrieved the ledger which was created")
+	}
+	if rlf.GetOrCreate([]byte("foo"), genesisBlock).Height() != 2 {
+		t.Fatalf("Should not have recreated an existing ledger")
+	}
+	if rl, _ := rlf.Get([]byte("bar")); rl.Height() != 1 {
+		t.Fatalf("Chains should not share blocks")
+	}
+	if len(rlf.ChainIDs()) != 2 {
+		t.Fatalf("Expected 2 chains but got %d", len(rlf.ChainIDs()))
+	}
+}
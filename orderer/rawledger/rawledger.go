@@ -34,12 +34,22 @@ type Reader interface {
 	Iterator(startType ab.SeekInfo_StartType, specified uint64) (Iterator, uint64)
 	// Height returns the highest block number in the chain, plus one
 	Height() uint64
+	// OldestRetained returns the number of the oldest block which may still be retrieved
+	OldestRetained() uint64
+	// GetBlock returns the block with the given number without waiting, or NOT_FOUND if it is not retained or not yet created
+	GetBlock(number uint64) (*ab.Block, ab.Status)
 }
 
+// Sealer completes the metadata of a block, such as the consenter's proof or signature, once the ledger has assigned
+// the block its number and previous hash but before the block is stored
+type Sealer func(block *ab.Block)
+
 // Writer allows the caller to modify the raw ledger
 type Writer interface {
-	// Append a new block to the ledger
-	Append(blockContents []*ab.BroadcastMessage, proof []byte) *ab.Block
+	// Append a new block to the ledger, calling seal on it first if it is not nil
+	Append(blockContents []*ab.BroadcastMessage, seal Sealer) *ab.Block
+	// AppendEncoded appends a new block as Append does, given also the marshaled form of each message, so that they are not marshaled again to hash the block
+	AppendEncoded(blockContents []*ab.BroadcastMessage, encoded [][]byte, seal Sealer) *ab.Block
 }
 
 // ReadWriter encapsulated both the reading and writing functions of the rawledger
@@ -47,3 +57,13 @@ type ReadWriter interface {
 	Reader
 	Writer
 }
+
+// Factory retrieves the ledgers of each chain, creating them as required
+type Factory interface {
+	// GetOrCreate returns the ledger for a chain, creating it from the genesis block if it does not already exist
+	GetOrCreate(chainID []byte, genesisBlock *ab.Block) ReadWriter
+	// Get returns the ledger for a chain, or false if the chain does not exist
+	Get(chainID []byte) (ReadWriter, bool)
+	// ChainIDs returns the IDs of all chains which exist
+	ChainIDs() [][]byte
+}
@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rawledger
+
+import (
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+
+	"github.com/op/go-logging"
+)
+
+var logger = logging.MustGetLogger("rawledger")
+
+// Timestamper records in the metadata of each block it stamps the time it was committed, never earlier than the block before it
+// so that the timestamps of a chain do not decrease even if the clock of the orderer steps backwards
+// It is not safe for concurrent use, the blocks of a chain must be stamped in order by a single goroutine
+type Timestamper struct {
+	floor time.Time
+}
+
+// NewTimestamper returns a timestamper for the blocks appended to rl, following from the timestamp of its tail block
+func NewTimestamper(rl Reader) *Timestamper {
+	ts := &Timestamper{}
+	if tail, status := rl.GetBlock(rl.Height() - 1); status == ab.Status_SUCCESS {
+		if committed, ok := tail.Timestamp(); ok {
+			ts.floor = committed
+		}
+	}
+	return ts
+}
+
+// Stamp records now as the block's timestamp, or the timestamp of the previous block if now is earlier, a nil timestamper leaves the metadata unset
+func (ts *Timestamper) Stamp(block *ab.Block, now time.Time) {
+	if ts == nil {
+		return
+	}
+
+	// Compared by wall time, as that is what is recorded, a monotonic reading would hide a step of the wall clock
+	if now.UnixNano() < ts.floor.UnixNano() {
+		logger.Warningf("Clock reads %v which is before the previous block was committed at %v, block %d is stamped with the previous time", now, ts.floor, block.Header.Number)
+		now = ts.floor
+	}
+	ts.floor = now
+	block.SetTimestamp(now)
+}
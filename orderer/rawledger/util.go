@@ -17,6 +17,9 @@ limitations under the License.
 package rawledger
 
 import (
+	"sort"
+	"time"
+
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
 )
 
@@ -39,3 +42,32 @@ func (nfei *NotFoundErrorIterator) Next() (*ab.Block, ab.Status) {
 func (nfei *NotFoundErrorIterator) ReadyChan() <-chan struct{} {
 	return closedChan
 }
+
+// SeekTimestamp returns the number of the first retained block committed at or after timestamp, or NOT_FOUND if every block was committed before it
+// The search relies on block timestamps not decreasing along the chain, blocks which predate timestamps are treated as committed before any time
+func SeekTimestamp(rl Reader, timestamp time.Time) (uint64, ab.Status) {
+	oldest := rl.OldestRetained()
+	count := int(rl.Height() - oldest)
+	status := ab.Status_SUCCESS
+
+	index := sort.Search(count, func(i int) bool {
+		if status != ab.Status_SUCCESS {
+			return true
+		}
+		var block *ab.Block
+		block, status = rl.GetBlock(oldest + uint64(i))
+		if status != ab.Status_SUCCESS {
+			return true
+		}
+		committed, ok := block.Timestamp()
+		return ok && !committed.Before(timestamp)
+	})
+
+	if status != ab.Status_SUCCESS {
+		return 0, status
+	}
+	if index == count {
+		return 0, ab.Status_NOT_FOUND
+	}
+	return oldest + uint64(index), ab.Status_SUCCESS
+}
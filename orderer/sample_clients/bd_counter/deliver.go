@@ -51,6 +51,11 @@ func (c *clientImpl) deliver() {
 
 	go c.recvDeliverReplies(stream)
 
+	// The window is enforced only for a client which negotiates acknowledgements
+	err = stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.NewFeatures(ab.Feature_DELIVER_ACKS).Hello()}})
+	if err != nil {
+		log.Println("Failed to send hello to orderer: ", err)
+	}
 	err = stream.Send(updateSeek)
 	if err != nil {
 		log.Println("Failed to send seek update to orderer: ", err)
@@ -86,17 +91,19 @@ func (c *clientImpl) recvDeliverReplies(stream ab.AtomicBroadcast_DeliverClient)
 		}
 
 		switch t := reply.GetType().(type) {
+		case *ab.DeliverResponse_Hello:
+			logger.Debugf("Negotiated features %v", t.Hello.Features)
 		case *ab.DeliverResponse_Block:
 			logger.Infof("Deliver reply from orderer: block %v, payload %v, prevHash %v",
-				t.Block.Number, t.Block.Messages, t.Block.PrevHash)
+				t.Block.Header.Number, t.Block.Data.Messages, t.Block.Header.PreviousHash)
 			count++
 			if (count > 0) && (count%c.config.ack == 0) {
-				updateAck.GetAcknowledgement().Number = t.Block.Number
+				updateAck.GetAcknowledgement().Number = t.Block.Header.Number
 				err = stream.Send(updateAck)
 				if err != nil {
 					logger.Info("Failed to send ACK update to orderer: ", err)
 				}
-				logger.Debugf("Sent ACK for block %d", t.Block.Number)
+				logger.Debugf("Sent ACK for block %d", t.Block.Header.Number)
 			}
 		case *ab.DeliverResponse_Error:
 			logger.Info("Deliver reply from orderer:", t.Error.String())
@@ -35,6 +35,11 @@ func newDeliverClient(client ab.AtomicBroadcast_DeliverClient, windowSize uint64
 	return &deliverClient{client: client, windowSize: windowSize}
 }
 
+// hello negotiates acknowledgements, without which the window is not enforced
+func (r *deliverClient) hello() error {
+	return r.client.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.NewFeatures(ab.Feature_DELIVER_ACKS).Hello()}})
+}
+
 func (r *deliverClient) seekOldest() error {
 	return r.client.Send(&ab.DeliverUpdate{
 		Type: &ab.DeliverUpdate_Seek{
@@ -77,6 +82,8 @@ func (r *deliverClient) readUntilClose() {
 		}
 
 		switch t := msg.Type.(type) {
+		case *ab.DeliverResponse_Hello:
+			fmt.Println("Negotiated features: ", t.Hello.Features)
 		case *ab.DeliverResponse_Error:
 			if t.Error == ab.Status_SUCCESS {
 				fmt.Println("ERROR! Received success in error field")
@@ -88,7 +95,7 @@ func (r *deliverClient) readUntilClose() {
 			r.unAcknowledged++
 			if r.unAcknowledged >= r.windowSize/2 {
 				fmt.Println("Sending acknowledgement")
-				err = r.client.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: t.Block.Number}}})
+				err = r.client.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Acknowledgement{Acknowledgement: &ab.Acknowledgement{Number: t.Block.Header.Number}}})
 				if err != nil {
 					return
 				}
@@ -116,6 +123,7 @@ func main() {
 	}
 
 	s := newDeliverClient(client, 10)
+	s.hello()
 	s.seekOldest()
 	s.readUntilClose()
 
@@ -17,154 +17,693 @@ limitations under the License.
 package solo
 
 import (
+	"sync"
 	"time"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/blockcutter"
+	"github.com/hyperledger/fabric/orderer/common/blocksigner"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
 	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/consenter"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
 	"github.com/hyperledger/fabric/orderer/rawledger"
+
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
 type broadcastServer struct {
-	queueSize    int
-	batchSize    int
-	batchTimeout time.Duration
-	rl           rawledger.Writer
-	filter       *broadcastfilter.RuleSet
-	sendChan     chan *ab.BroadcastMessage
-	exitChan     chan struct{}
+	queueSize      int
+	batchSize      int
+	batchTimeout   time.Duration
+	cutter         blockcutter.Receiver // Only accessed by the batching loop
+	ackAfterCommit bool
+	dedup          *dedupCache
+	plog           *pendingLog
+	metrics        *batchMetrics
+	clock          clock.Clock
+	rl             rawledger.Writer
+	lastConfig     *configtx.LastConfigTracker // Points each block to the last configuration block, if nil no pointer is recorded
+	config         configtx.Manager            // Applies the configuration transactions committed on the chain, if nil they are only recorded
+	snapshotEvery  uint64                      // How many blocks may follow the last configuration block before a snapshot of config is committed, zero for none
+	audit          *audit.Trail                // Records each configuration transaction applied to config, once its block is committed
+	signer         *blocksigner.Signer         // Signs each block as it is appended, blocks are unsigned if nil
+	stamper        *rawledger.Timestamper      // Records the time each block is appended, blocks are not stamped if nil
+	filter         *broadcastfilter.RuleSet
+	chainCreator   func(configTx *ab.ConfigurationEnvelope) // Creates the chain whose genesis configuration a committed block carries, if nil such blocks are only recorded
+	queues         *queueScheduler
+	chainQueue     *broadcastQueue // The queue of the messages enqueued through the Chain interface
+	chainQueueOnce sync.Once
+	pauseLock      sync.RWMutex // Held for writing while the paused state changes, and for reading while a message is enqueued
+	paused         bool
+	retryAfter     time.Duration // The retry hint sent to clients while paused
+	pauseChan      chan chan struct{}
+	resumeChan     chan chan struct{}
+	batchSizeLock  sync.Mutex // Guards nextBatch
+	nextBatch      sharedconfig.BatchParameters
+	batchSizeChan  chan struct{} // Holds a token while nextBatch has not been taken by the batching loop
+	commitChan     chan *readyBatch
+	committedChan  chan struct{} // Closed once the committer has committed every batch cut
+	unpipelined    bool          // Set to wait for each batch to be committed before cutting the next, as benchmarks compare
+	stopChan       chan struct{}
+	stopOnce       sync.Once
+	doneChan       chan struct{}
+	exitChan       chan struct{}
+	haltOnce       sync.Once
 }
 
-func newBroadcastServer(queueSize, batchSize int, batchTimeout time.Duration, rl rawledger.Writer) *broadcastServer {
-	bs := newPlainBroadcastServer(queueSize, batchSize, batchTimeout, rl)
-	go bs.main()
+// commitQueueSize is the number of cut batches which may await the committer, beyond which cutting waits for the ledger
+const commitQueueSize = 2
+
+// readyBatch is a cut batch awaiting the committer, or if done is set, a marker which is closed once the batches before it are committed
+type readyBatch struct {
+	batch  []*pendingMessage
+	reason cutReason
+	done   chan struct{}
+}
+
+// pendingMessage carries a message through the batching loop along with the reply slot to fill once it is committed
+type pendingMessage struct {
+	msg      *ab.BroadcastMessage
+	seq      uint64                     // The sequence number in the pending log, if enabled
+	encoded  []byte                     // The marshaled message as recorded in the pending log, nil if it is disabled
+	received time.Time                  // When the message was received, zero if it was recovered from the pending log
+	reply    chan *ab.BroadcastResponse // nil unless acknowledging after commit
+	isolated bool                       // Set for a configuration or chain creation transaction, which is committed in a block by itself
+}
+
+func (pm *pendingMessage) respond(resp *ab.BroadcastResponse) {
+	if pm.reply != nil {
+		pm.reply <- resp
+	}
+}
+
+// newBroadcastServer starts a batching loop over rl, a batchMaxBytes of zero places no limit on the size of a batch, plog may be nil to disable the recording of pending messages,
+// filter may be nil to accept any non-empty message, registry may be nil if metrics are not to be exported, and clk may be nil to use the real clock
+func newBroadcastServer(queueSize, batchSize, batchMaxBytes int, batchTimeout time.Duration, ackAfterCommit bool, dedupWindow int, filter *broadcastfilter.RuleSet, rl rawledger.Writer, plog *pendingLog, registry gometrics.Registry, clk clock.Clock) *broadcastServer {
+	bs := newPlainBroadcastServer(queueSize, batchSize, batchMaxBytes, batchTimeout, ackAfterCommit, dedupWindow, filter, rl, plog, registry, clk)
+	bs.Start()
 	return bs
 }
 
-func newPlainBroadcastServer(queueSize, batchSize int, batchTimeout time.Duration, rl rawledger.Writer) *broadcastServer {
+func newPlainBroadcastServer(queueSize, batchSize, batchMaxBytes int, batchTimeout time.Duration, ackAfterCommit bool, dedupWindow int, filter *broadcastfilter.RuleSet, rl rawledger.Writer, plog *pendingLog, registry gometrics.Registry, clk clock.Clock) *broadcastServer {
+	if filter == nil {
+		filter = broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.CorrelationIDRule, broadcastfilter.AcceptRule})
+	}
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	queues := newQueueScheduler()
 	bs := &broadcastServer{
-		queueSize:    queueSize,
-		batchSize:    batchSize,
-		batchTimeout: batchTimeout,
-		rl:           rl,
-		filter:       broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.AcceptRule}),
-		sendChan:     make(chan *ab.BroadcastMessage),
-		exitChan:     make(chan struct{}),
+		queueSize:      queueSize,
+		batchSize:      batchSize,
+		batchTimeout:   batchTimeout,
+		cutter:         blockcutter.NewReceiver(batchSize, batchMaxBytes),
+		ackAfterCommit: ackAfterCommit,
+		dedup:          newDedupCache(dedupWindow),
+		plog:           plog,
+		rl:             rl,
+		filter:         filter,
+		queues:         queues,
+		metrics:        newBatchMetrics(registry, queues),
+		clock:          clk,
+		pauseChan:      make(chan chan struct{}),
+		resumeChan:     make(chan chan struct{}),
+		batchSizeChan:  make(chan struct{}, 1),
+		commitChan:     make(chan *readyBatch, commitQueueSize),
+		committedChan:  make(chan struct{}),
+		stopChan:       make(chan struct{}),
+		doneChan:       make(chan struct{}),
+		exitChan:       make(chan struct{}),
 	}
 	return bs
 }
 
-func (bs *broadcastServer) halt() {
-	close(bs.exitChan)
+// Start begins the batching loop
+func (bs *broadcastServer) Start() {
+	go bs.main()
+}
+
+// Halt stops the batching loop without committing the pending batch
+func (bs *broadcastServer) Halt() {
+	bs.haltOnce.Do(func() { close(bs.exitChan) })
+}
+
+// Errored returns a channel which is closed once the batching loop has exited
+func (bs *broadcastServer) Errored() <-chan struct{} {
+	return bs.doneChan
+}
+
+// Enqueue queues a message which has already been filtered, as a stream shared by the callers of the Chain interface would,
+// it is acknowledged once queued regardless of ackAfterCommit
+func (bs *broadcastServer) Enqueue(msg *ab.BroadcastMessage) bool {
+	bs.chainQueueOnce.Do(func() {
+		bs.chainQueue = bs.queues.newQueue(bs.queueSize)
+	})
+	reply := make(chan *ab.BroadcastResponse, 1)
+	bs.enqueue(bs.chainQueue, msg, reply, false)
+	select {
+	case resp := <-reply:
+		return resp.Status == ab.Status_SUCCESS
+	default:
+		// A duplicate of a pending message is replied to once that message is committed
+		return true
+	}
+}
+
+// shutdown stops accepting new messages, commits the pending batch and every queued message, then halts
+// It may be called more than once, later calls return once the first has completed
+func (bs *broadcastServer) shutdown() {
+	bs.stopOnce.Do(func() {
+		// Closed under the lock held while messages are enqueued, so that none is queued behind the final blocks
+		bs.pauseLock.Lock()
+		close(bs.stopChan)
+		bs.pauseLock.Unlock()
+		<-bs.doneChan
+		bs.Halt()
+		bs.plog.close()
+	})
+}
+
+// stopping returns whether shutdown has begun, the caller must hold pauseLock to be sure no message follows the final blocks
+func (bs *broadcastServer) stopping() bool {
+	select {
+	case <-bs.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// pause stops accepting new messages, commits everything already accepted, and then stops cutting blocks until resumed
+// Clients are asked to retry after retryAfter while paused
+func (bs *broadcastServer) pause(retryAfter time.Duration) {
+	bs.pauseLock.Lock()
+	defer bs.pauseLock.Unlock()
+	bs.retryAfter = retryAfter
+	if bs.paused {
+		return
+	}
+	bs.paused = true
+	bs.signal(bs.pauseChan)
+}
+
+// resume begins accepting messages and cutting blocks again
+func (bs *broadcastServer) resume() {
+	bs.pauseLock.Lock()
+	defer bs.pauseLock.Unlock()
+	if !bs.paused {
+		return
+	}
+	bs.paused = false
+	bs.signal(bs.resumeChan)
+}
+
+// setBatchParameters changes how blocks are cut, the pending batch is cut again under the new parameters before any message which follows
+// It does not wait for the batching loop, so that it may be called by the committer as a configuration transaction is applied
+func (bs *broadcastServer) setBatchParameters(params sharedconfig.BatchParameters) {
+	bs.batchSizeLock.Lock()
+	bs.nextBatch = params
+	bs.batchSizeLock.Unlock()
+	select {
+	case bs.batchSizeChan <- struct{}{}:
+	default:
+		// The batching loop has yet to take the last change, it will take this one in its place
+	}
+}
+
+// takeBatchParameters returns the batch parameters last set, or false if they have not changed since last taken
+func (bs *broadcastServer) takeBatchParameters() (sharedconfig.BatchParameters, bool) {
+	select {
+	case <-bs.batchSizeChan:
+		return bs.nextBatchParameters(), true
+	default:
+		return sharedconfig.BatchParameters{}, false
+	}
 }
 
+func (bs *broadcastServer) nextBatchParameters() sharedconfig.BatchParameters {
+	bs.batchSizeLock.Lock()
+	defer bs.batchSizeLock.Unlock()
+	return bs.nextBatch
+}
+
+// signal waits for the batching loop to act on a change of state, or to exit
+func (bs *broadcastServer) signal(stateChan chan chan struct{}) {
+	done := make(chan struct{})
+	select {
+	case stateChan <- done:
+		<-done
+	case <-bs.doneChan:
+	}
+}
+
+// main cuts a block once the batch is full, or once the batch timeout has elapsed since the first message of the batch
+// The blocks are appended by the committer, so that the next batch may be cut while the ledger writes the last
 func (bs *broadcastServer) main() {
-	var curBatch []*ab.BroadcastMessage
-outer:
+	defer close(bs.doneChan)
+	go bs.committer()
+	defer func() {
+		// Every batch which was cut is committed before the loop is done
+		close(bs.commitChan)
+		<-bs.committedChan
+	}()
+	curBatch := bs.recover()
+
+	// The timer is armed only while a batch is pending, so that an idle chain does not wake
+	var timer clock.Timer
+	if len(curBatch) > 0 {
+		timer = bs.clock.NewTimer(bs.batchTimeout)
+	}
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+		}
+	}
+	paused := false
+
 	for {
-		timer := time.After(bs.batchTimeout)
-		for {
-			select {
-			case msg := <-bs.sendChan:
-				// The messages must be filtered a second time in case configuration has changed since the message was received
-				action, _ := bs.filter.Apply(msg)
-				switch action {
-				case broadcastfilter.Accept:
-					curBatch = append(curBatch, msg)
-					if len(curBatch) < bs.batchSize {
-						continue
-					}
-					logger.Debugf("Batch size met, creating block")
-				case broadcastfilter.Forward:
-					logger.Debugf("Ignoring message because it was not accepted by a filter")
-				default:
-					// TODO add support for other cases, unreachable for now
-					logger.Fatalf("NOT IMPLEMENTED YET")
-				}
-			case <-timer:
-				if len(curBatch) == 0 {
-					continue outer
-				}
-				logger.Debugf("Batch timer expired, creating block")
-			case <-bs.exitChan:
-				logger.Debugf("Exiting")
-				return
+		var timeout <-chan time.Time
+		if timer != nil {
+			timeout = timer.C()
+		}
+		readyChan := bs.queues.readyChan
+		if paused {
+			// Queued messages are left for the resume
+			readyChan = nil
+		}
+
+		var reason cutReason
+		select {
+		case <-readyChan:
+			var ready [][]*pendingMessage
+			ready, curBatch = bs.fill(curBatch)
+			if len(ready) == 0 && len(curBatch) == 0 {
+				continue
 			}
-			break
+			if timer == nil {
+				timer = bs.clock.NewTimer(bs.batchTimeout)
+			}
+			if len(ready) == 0 {
+				continue
+			}
+			// Messages may remain queued beyond those which fit in these batches
+			bs.queues.notify()
+			logger.Debugf("Batch size met, creating block")
+			stopTimer()
+			for _, batch := range ready {
+				bs.submit(batch, cutSize)
+			}
+			if len(curBatch) > 0 {
+				timer = bs.clock.NewTimer(bs.batchTimeout)
+			}
+			continue
+		case <-timeout:
+			logger.Debugf("Batch timer expired, creating block")
+			reason = cutTimeout
+		case done := <-bs.pauseChan:
+			stopTimer()
+			logger.Debugf("Pausing, creating blocks of the pending messages")
+			bs.flush(curBatch, cutPause)
+			curBatch = nil
+			paused = true
+			close(done)
+			continue
+		case done := <-bs.resumeChan:
+			logger.Debugf("Resuming")
+			paused = false
+			close(done)
+			continue
+		case <-bs.batchSizeChan:
+			var ready [][]*pendingMessage
+			ready, curBatch = bs.rebatch(bs.nextBatchParameters(), curBatch)
+			for _, batch := range ready {
+				bs.submit(batch, cutSize)
+			}
+			if len(curBatch) == 0 {
+				stopTimer()
+			}
+			continue
+		case <-bs.stopChan:
+			stopTimer()
+			// The queued messages may already have been acknowledged, so they are committed along with the pending batch
+			logger.Debugf("Shutting down, creating the final blocks")
+			bs.flush(curBatch, cutShutdown)
+			logger.Debugf("Exiting")
+			return
+		case <-bs.exitChan:
+			stopTimer()
+			logger.Debugf("Exiting")
+			return
 		}
 
-		bs.rl.Append(curBatch, nil)
+		stopTimer()
+		bs.cutter.Cut()
+		bs.submit(curBatch, reason)
 		curBatch = nil
 	}
 }
 
-func (bs *broadcastServer) handleBroadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
-	b := newBroadcaster(bs)
-	defer close(b.queue)
-	go b.drainQueue()
-	return b.queueBroadcastMessages(srv)
+// fill passes queued messages to the block cutter until it cuts a batch or the queues are empty,
+// returning the batches which are ready to be committed and the remainder of the pending batch
+func (bs *broadcastServer) fill(batch []*pendingMessage) ([][]*pendingMessage, []*pendingMessage) {
+	for {
+		if params, ok := bs.takeBatchParameters(); ok {
+			// A change made before these messages were queued applies to them, even if the loop has yet to see it
+			ready, rest := bs.rebatch(params, batch)
+			if len(ready) > 0 {
+				return ready, rest
+			}
+			batch = rest
+		}
+		pending := bs.queues.next()
+		if pending == nil {
+			return nil, batch
+		}
+		if !bs.refilter(pending) {
+			continue
+		}
+		ready, rest := bs.order(batch, pending)
+		if len(ready) > 0 {
+			return ready, rest
+		}
+		batch = rest
+	}
 }
 
-type broadcaster struct {
-	bs    *broadcastServer
-	queue chan *ab.BroadcastMessage
+// order adds a message to the pending batch, returning the batches the block cutter has cut and the remainder of the pending batch
+func (bs *broadcastServer) order(batch []*pendingMessage, pending *pendingMessage) ([][]*pendingMessage, []*pendingMessage) {
+	if pending.isolated {
+		// The pending batch is cut ahead of the configuration transaction, so that blocks which follow it are ordered under it
+		bs.cutter.Cut()
+		var ready [][]*pendingMessage
+		if len(batch) > 0 {
+			ready = append(ready, batch)
+		}
+		return append(ready, []*pendingMessage{pending}), nil
+	}
+
+	batch = append(batch, pending)
+	msgBatches, _ := bs.cutter.Ordered(pending.msg)
+
+	// The block cutter preserves the order of the messages, so its batches are successive runs of the pending batch
+	ready := make([][]*pendingMessage, len(msgBatches))
+	for i, msgs := range msgBatches {
+		ready[i] = batch[:len(msgs):len(msgs)]
+		batch = batch[len(msgs):]
+	}
+	return ready, batch
+}
+
+// rebatch replaces the block cutter and batch timeout, returning the batches cut from the pending batch under the new parameters and its remainder
+func (bs *broadcastServer) rebatch(params sharedconfig.BatchParameters, batch []*pendingMessage) ([][]*pendingMessage, []*pendingMessage) {
+	logger.Debugf("Cutting blocks of %d messages and %d bytes, with a timeout of %v", params.Size, params.MaxBytes, params.Timeout)
+	bs.cutter.Cut()
+	bs.cutter = blockcutter.NewReceiver(params.Size, params.MaxBytes)
+	bs.batchTimeout = params.Timeout
+
+	var ready [][]*pendingMessage
+	var rest []*pendingMessage
+	for _, pending := range batch {
+		var cut [][]*pendingMessage
+		cut, rest = bs.order(rest, pending)
+		ready = append(ready, cut...)
+	}
+	return ready, rest
 }
 
-func (b *broadcaster) drainQueue() {
+// flush commits the batch along with every queued message, in as many blocks as needed, returning once they are committed
+func (bs *broadcastServer) flush(batch []*pendingMessage, reason cutReason) {
 	for {
-		select {
-		case msg, ok := <-b.queue:
-			if ok {
-				select {
-				case b.bs.sendChan <- msg:
-				case <-b.bs.exitChan:
-					return
-				}
-			} else {
-				return
-			}
-		case <-b.bs.exitChan:
-			return
+		var ready [][]*pendingMessage
+		ready, batch = bs.fill(batch)
+		for _, cut := range ready {
+			bs.submit(cut, reason)
+		}
+		if len(ready) == 0 {
+			break
 		}
 	}
+	if len(batch) > 0 {
+		bs.cutter.Cut()
+		bs.submit(batch, reason)
+	}
+	bs.waitForCommits()
 }
 
-func (b *broadcaster) queueBroadcastMessages(srv ab.AtomicBroadcast_BroadcastServer) error {
+// submit passes a cut batch to the committer, waiting while the committer already has commitQueueSize batches to append
+// A configuration transaction which is to be applied is committed before submit returns, so that the messages which follow are ordered under it
+func (bs *broadcastServer) submit(batch []*pendingMessage, reason cutReason) {
+	bs.commitChan <- &readyBatch{batch: batch, reason: reason}
+	if bs.unpipelined || (bs.config != nil && len(batch) == 1 && batch[0].isolated) {
+		bs.waitForCommits()
+	}
+}
 
-	for {
-		msg, err := srv.Recv()
-		if err != nil {
-			return err
+// waitForCommits returns once every batch submitted has been committed
+func (bs *broadcastServer) waitForCommits() {
+	done := make(chan struct{})
+	bs.commitChan <- &readyBatch{done: done}
+	<-done
+}
+
+// committer appends the cut batches to the ledger one at a time, in the order they were cut, so that each block follows the last
+func (bs *broadcastServer) committer() {
+	defer close(bs.committedChan)
+	for rb := range bs.commitChan {
+		if rb.done != nil {
+			close(rb.done)
+			continue
 		}
+		bs.commit(rb.batch, rb.reason)
+	}
+}
 
-		action, _ := b.bs.filter.Apply(msg)
+// recover orders the messages recovered from the pending log ahead of any new traffic, returning those which do not fill a batch
+func (bs *broadcastServer) recover() []*pendingMessage {
+	if bs.plog == nil {
+		return nil
+	}
 
-		switch action {
-		case broadcastfilter.Accept:
-			select {
-			case b.queue <- msg:
-				err = srv.Send(&ab.BroadcastResponse{ab.Status_SUCCESS})
-			default:
-				err = srv.Send(&ab.BroadcastResponse{ab.Status_SERVICE_UNAVAILABLE})
+	var batch []*pendingMessage
+	for _, pending := range bs.plog.recovered {
+		var ready [][]*pendingMessage
+		if params, ok := bs.takeBatchParameters(); ok {
+			// A recovered configuration transaction applies to the messages recovered after it
+			ready, batch = bs.rebatch(params, batch)
+			for _, cut := range ready {
+				bs.submit(cut, cutSize)
 			}
-		case broadcastfilter.Forward:
-			fallthrough
-		case broadcastfilter.Reject:
-			err = srv.Send(&ab.BroadcastResponse{ab.Status_BAD_REQUEST})
-		default:
-			// TODO add support for other cases, unreachable for now
-			logger.Fatalf("NOT IMPLEMENTED YET")
 		}
+		if !bs.refilter(pending) {
+			continue
+		}
+		// Recorded as pending so that a client retrying after the restart is not ordered twice
+		bs.dedup.check(pending.msg, nil)
+		ready, batch = bs.order(batch, pending)
+		for _, cut := range ready {
+			bs.submit(cut, cutSize)
+		}
+	}
+	bs.plog.recovered = nil
+	return batch
+}
+
+// refilter returns whether a queued message may still be ordered, replying to it otherwise
+func (bs *broadcastServer) refilter(pending *pendingMessage) bool {
+	// The messages must be filtered a second time in case configuration has changed since the message was received
+	action, rule := bs.filter.Apply(pending.msg)
+	switch action {
+	case broadcastfilter.Accept, broadcastfilter.Reconfigure:
+		pending.isolated = action == broadcastfilter.Reconfigure
+		return true
+	case broadcastfilter.Forward, broadcastfilter.Reject:
+		logger.Debugf("Ignoring message because it was not accepted by a filter")
+		reply := broadcastfilter.RejectReply(rule, pending.msg)
+		bs.dedup.forget(pending.msg, reply)
+		bs.plog.drop(pending.seq)
+		pending.respond(reply)
+		return false
+	default:
+		// TODO add support for other cases, unreachable for now
+		logger.Fatalf("NOT IMPLEMENTED YET")
+		return false
+	}
+}
+
+// newPendingMessage records a message in the pending log, if enabled, keeping the marshaled form written so that the block hash may reuse it
+func (bs *broadcastServer) newPendingMessage(msg *ab.BroadcastMessage) *pendingMessage {
+	pending := &pendingMessage{msg: msg, received: bs.clock.Now()}
+	pending.seq, pending.encoded = bs.plog.record(msg)
+	return pending
+}
+
+// seal completes the metadata of each block once the ledger has assigned its number and previous hash
+func (bs *broadcastServer) seal(block *ab.Block) {
+	bs.lastConfig.Seal(block)
+	bs.stamper.Stamp(block, bs.clock.Now())
+	bs.signer.Seal(block)
+}
+
+// commit appends the batch to the ledger as a new block and replies to any clients awaiting the commit
+func (bs *broadcastServer) commit(batch []*pendingMessage, reason cutReason) {
+	msgs := make([]*ab.BroadcastMessage, len(batch))
+	seqs := make([]uint64, len(batch))
+	encoded := make([][]byte, len(batch))
+	for i, pending := range batch {
+		msgs[i] = pending.msg
+		seqs[i] = pending.seq
+		if pending.encoded == nil {
+			// The ledger marshals the messages itself unless every one was already marshaled
+			encoded = nil
+		} else if encoded != nil {
+			encoded[i] = pending.encoded
+		}
+	}
+
+	block := bs.rl.AppendEncoded(msgs, encoded, bs.seal)
+	bs.plog.commit(block.Header.Number, seqs)
+	bs.dedup.commit(block)
+	bs.metrics.blockCommitted(batch, reason, bs.clock.Now())
+	bs.applyConfig(block)
+	bs.createChain(block)
+	bs.snapshotConfig(block)
 
-		if err != nil {
-			return err
+	for i, pending := range batch {
+		if pending.reply == nil {
+			// Acknowledged when queued, no response need be built
+			continue
 		}
+		pending.respond(&ab.BroadcastResponse{Status: ab.Status_SUCCESS, BlockNumber: block.Header.Number, Index: uint64(i)})
+	}
+}
+
+// applyConfig applies the configuration transaction a block carries, if any, a transaction which cannot be applied leaves the configuration unchanged
+func (bs *broadcastServer) applyConfig(block *ab.Block) {
+	if bs.config == nil {
+		return
+	}
+	configTx := configtx.BlockConfiguration(block)
+	if configTx == nil {
+		return
+	}
+	if err := bs.config.Apply(configTx); err != nil {
+		logger.Errorf("Configuration transaction committed in block %d could not be applied: %s", block.Header.Number, err)
+		return
+	}
+	bs.audit.Write(audit.NewRecord(block.Header.Number, configTx, bs.clock.Now()))
+}
+
+// createChain creates the chain whose genesis configuration a block carries, if any
+func (bs *broadcastServer) createChain(block *ab.Block) {
+	if bs.chainCreator == nil {
+		return
+	}
+	if configTx := configtx.BlockChainCreation(block); configTx != nil {
+		bs.chainCreator(configTx)
+	}
+}
+
+// snapshotConfig commits a block restating the configuration once snapshotEvery blocks have followed the last configuration block,
+// the snapshot is at the sequence already applied, so it is not applied again and configuration transactions in flight remain valid
+func (bs *broadcastServer) snapshotConfig(block *ab.Block) {
+	if bs.config == nil || bs.lastConfig == nil || bs.snapshotEvery == 0 || block.Header.Number-bs.lastConfig.Last() < bs.snapshotEvery {
+		return
+	}
+	snapshot := bs.config.Snapshot()
+	data, err := configtx.MarshalConfigurationTransaction(snapshot)
+	if err != nil {
+		logger.Errorf("Could not marshal a snapshot of configuration sequence %d: %s", snapshot.Sequence, err)
+		return
 	}
+	snapshotBlock := bs.rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: data}}, bs.seal)
+	bs.plog.commit(snapshotBlock.Header.Number, nil)
+	bs.dedup.commit(snapshotBlock)
+	logger.Infof("Committed a snapshot of configuration sequence %d in block %d", snapshot.Sequence, snapshotBlock.Header.Number)
+}
+
+// handleBroadcast orders every message of the stream on the chain of bs, regardless of its chain ID, through the shared broadcast handler
+func (bs *broadcastServer) handleBroadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
+	// Replies may be outstanding for every queued message as well as those awaiting commit in the current batch
+	return broadcast.HandleStream(srv, singleChain{bs}, broadcast.Options{Filter: bs.filter, Enabled: ab.AllFeatures, MaxOutstanding: bs.queueSize + bs.batchSize, Clock: bs.clock})
+}
+
+// singleChain is a Consenter of the chain of bs alone, which it returns for every chain ID
+type singleChain struct {
+	bs *broadcastServer
+}
+
+func (sc singleChain) Chain(chainID []byte) (consenter.Chain, bool) {
+	return sc.bs, true
 }
 
-func newBroadcaster(bs *broadcastServer) *broadcaster {
-	b := &broadcaster{
-		bs:    bs,
-		queue: make(chan *ab.BroadcastMessage, bs.queueSize),
+// broadcastStream queues the messages of one Broadcast stream for the batching loop
+type broadcastStream struct {
+	bs    *broadcastServer
+	queue *broadcastQueue
+}
+
+// NewStream opens the queue of one Broadcast stream, the batching loop takes from the queues of the streams in turn
+func (bs *broadcastServer) NewStream() consenter.Stream {
+	return &broadcastStream{bs: bs, queue: bs.queues.newQueue(bs.queueSize)}
+}
+
+// Enqueue queues a message accepted by the filters, its reply is filled once it is queued, or with ackAfterCommit once it is committed
+func (st *broadcastStream) Enqueue(msg *ab.BroadcastMessage, reply chan *ab.BroadcastResponse) {
+	st.bs.enqueue(st.queue, msg, reply, st.bs.ackAfterCommit)
+}
+
+// Close leaves the queued messages of the stream to be ordered
+func (st *broadcastStream) Close() {
+	st.queue.close()
+}
+
+// enqueue queues a message accepted by the filters on bq, filling reply once it is queued, or if awaitCommit once it is committed
+func (bs *broadcastServer) enqueue(bq *broadcastQueue, msg *ab.BroadcastMessage, reply chan *ab.BroadcastResponse, awaitCommit bool) {
+	// Neither a pause nor a shutdown may take effect while a message is being enqueued, or the message could be left behind the flush
+	bs.pauseLock.RLock()
+	defer bs.pauseLock.RUnlock()
+	if bs.stopping() {
+		reply <- ab.ReasonUnavailable.BroadcastResponse("orderer is shutting down")
+		return
+	}
+	if bs.paused {
+		paused := ab.ReasonUnavailable.BroadcastResponse("ordering is paused")
+		paused.RetryAfter = uint64(bs.retryAfter / time.Millisecond)
+		reply <- paused
+		return
+	}
+
+	// Duplicates are checked only once the message would otherwise be accepted
+	if entry := bs.dedup.check(msg, reply); entry != nil {
+		if !entry.committed {
+			// Replied to once the message it duplicates is committed or dropped, so that it is never acknowledged ahead of it
+			logger.Debugf("Not enqueueing duplicate of a pending message, replying once it is committed")
+			return
+		}
+		logger.Debugf("Not enqueueing duplicate message, previously ordered in block %d", entry.blockNumber)
+		reply <- &ab.BroadcastResponse{Status: ab.Status_SUCCESS, BlockNumber: entry.blockNumber, Index: entry.index}
+		return
+	}
+
+	// The message is recorded before it is queued, so that it is ordered after a crash even though it was acknowledged
+	pending := bs.newPendingMessage(msg)
+	if awaitCommit {
+		pending.reply = reply
+	}
+	if bq.enqueue(pending) {
+		if !awaitCommit {
+			reply <- &ab.BroadcastResponse{Status: ab.Status_SUCCESS}
+		}
+	} else {
+		// The queue is full, the client is to back off until the batching loop has caught up
+		full := ab.ReasonBackpressure.BroadcastResponse("queue of %d messages is full", bs.queueSize)
+		bs.dedup.forget(msg, full)
+		bs.plog.drop(pending.seq)
+		reply <- full
 	}
-	return b
 }
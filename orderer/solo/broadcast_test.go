@@ -17,16 +17,28 @@ limitations under the License.
 package solo
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
 	"testing"
 	"time"
 
-	"google.golang.org/grpc"
-
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
 	"github.com/hyperledger/fabric/orderer/common/bootstrap/static"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/consenter"
+	"github.com/hyperledger/fabric/orderer/common/deliver"
 	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/fileledger"
 	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
 var genesisBlock *ab.Block
@@ -40,72 +52,87 @@ func init() {
 	}
 }
 
-type mockB struct {
-	grpc.ServerStream
-	recvChan chan *ab.BroadcastMessage
-	sendChan chan *ab.BroadcastResponse
+// defaultChain is a Consenter of the chain of bs alone, which it returns for the empty chain ID only
+type defaultChain struct {
+	bs *broadcastServer
 }
 
-func newMockB() *mockB {
-	return &mockB{
-		recvChan: make(chan *ab.BroadcastMessage),
-		sendChan: make(chan *ab.BroadcastResponse),
-	}
+func (dc defaultChain) Chain(chainID []byte) (consenter.Chain, bool) {
+	return dc.bs, len(chainID) == 0
 }
 
-func (m *mockB) Send(br *ab.BroadcastResponse) error {
-	m.sendChan <- br
-	return nil
-}
+func TestBroadcastHello(t *testing.T) {
+	bs := newPlainBroadcastServer(2, 1, 0, time.Second, false, 0, nil, nil, nil, nil, nil)
+	start := func(m *mocks.BroadcastStream) {
+		go broadcast.HandleStream(m, defaultChain{bs}, broadcast.Options{Filter: bs.filter, Enabled: ab.AllFeatures, MaxOutstanding: 2})
+	}
 
-func (m *mockB) Recv() (*ab.BroadcastMessage, error) {
-	msg, ok := <-m.recvChan
-	if !ok {
-		return msg, fmt.Errorf("Channel closed")
+	// A legacy client may not name a chain, and is ordered on the default chain
+	legacy := mocks.NewLegacyBroadcastStream()
+	start(legacy)
+	legacy.RecvChan <- &ab.BroadcastMessage{Data: []byte("legacy"), ChainID: []byte("other")}
+	if reply := <-legacy.SendChan; reply.Status != ab.Status_BAD_REQUEST {
+		t.Fatalf("Expected the message of a legacy client naming a chain to be refused, got %v", reply)
+	}
+	legacy.RecvChan <- &ab.BroadcastMessage{Data: []byte("legacy")}
+	if reply := <-legacy.SendChan; reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message of a legacy client to be ordered on the default chain, got %v", reply)
+	}
+
+	m := mocks.NewLegacyBroadcastStream()
+	start(m)
+	m.RecvChan <- &ab.BroadcastMessage{Hello: ab.AllFeatures.Hello(), CorrelationID: []byte("hello")}
+	if reply := <-m.SendChan; reply.Hello == nil || !ab.NewFeatures(reply.Hello.Features...).Has(ab.Feature_CHAIN_ROUTING) || string(reply.CorrelationID) != "hello" {
+		t.Fatalf("Expected chain routing to be negotiated, got %v", reply)
+	}
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("routed"), ChainID: []byte("other")}
+	if reply := <-m.SendChan; reply.Status != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected the chain ID to be honored once negotiated, got %v", reply)
+	}
+	m.RecvChan <- &ab.BroadcastMessage{Hello: ab.AllFeatures.Hello()}
+	if reply := <-m.SendChan; reply.Status != ab.Status_BAD_REQUEST {
+		t.Fatalf("Expected a second hello to be refused, got %v", reply)
 	}
-	return msg, nil
 }
 
 func TestQueueOverflow(t *testing.T) {
-	bs := newPlainBroadcastServer(2, 1, time.Second, nil) // queueSize, batchSize (unused), batchTimeout (unused), ramLedger (unused)
-	m := newMockB()
-	b := newBroadcaster(bs)
-	go b.queueBroadcastMessages(m)
-	defer close(m.recvChan)
+	bs := newPlainBroadcastServer(2, 1, 0, time.Second, false, 0, nil, nil, nil, nil, nil) // queueSize, batchSize (unused), batchMaxBytes (unused), batchTimeout (unused), ackAfterCommit, dedupWindow, filter, ramLedger (unused), pendingLog, registry, clock
+	m := mocks.NewBroadcastStream()
+	go bs.handleBroadcast(m)
+	defer close(m.RecvChan)
 
-	bs.halt()
+	bs.Halt()
 
 	for i := 0; i < 2; i++ {
-		m.recvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
-		reply := <-m.sendChan
+		m.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+		reply := <-m.SendChan
 		if reply.Status != ab.Status_SUCCESS {
 			t.Fatalf("Should have successfully queued the message")
 		}
 	}
 
-	m.recvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
-	reply := <-m.sendChan
-	if reply.Status != ab.Status_SERVICE_UNAVAILABLE {
-		t.Fatalf("Should not have successfully queued the message")
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+	reply := <-m.SendChan
+	if reply.Status != ab.Status_TOO_MANY_REQUESTS || reply.Info != "queue of 2 messages is full" {
+		t.Fatalf("Expected TOO_MANY_REQUESTS once the queue is full, got %v", reply)
 	}
 
 }
 
 func TestMultiQueueOverflow(t *testing.T) {
-	bs := newPlainBroadcastServer(2, 1, time.Second, nil) // queueSize, batchSize (unused), batchTimeout (unused), ramLedger (unused)
-	// m := newMockB()
-	ms := []*mockB{newMockB(), newMockB(), newMockB()}
+	bs := newPlainBroadcastServer(2, 1, 0, time.Second, false, 0, nil, nil, nil, nil, nil) // queueSize, batchSize (unused), batchMaxBytes (unused), batchTimeout (unused), ackAfterCommit, dedupWindow, filter, ramLedger (unused), pendingLog, registry, clock
+	// m := mocks.NewBroadcastStream()
+	ms := []*mocks.BroadcastStream{mocks.NewBroadcastStream(), mocks.NewBroadcastStream(), mocks.NewBroadcastStream()}
 
 	for _, m := range ms {
-		b := newBroadcaster(bs)
-		go b.queueBroadcastMessages(m)
-		defer close(m.recvChan)
+		go bs.handleBroadcast(m)
+		defer close(m.RecvChan)
 	}
 
 	for _, m := range ms {
 		for i := 0; i < 2; i++ {
-			m.recvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
-			reply := <-m.sendChan
+			m.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+			reply := <-m.SendChan
 			if reply.Status != ab.Status_SUCCESS {
 				t.Fatalf("Should have successfully queued the message")
 			}
@@ -113,30 +140,61 @@ func TestMultiQueueOverflow(t *testing.T) {
 	}
 
 	for _, m := range ms {
-		m.recvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
-		reply := <-m.sendChan
-		if reply.Status != ab.Status_SERVICE_UNAVAILABLE {
-			t.Fatalf("Should not have successfully queued the message")
+		m.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+		reply := <-m.SendChan
+		if reply.Status != ab.Status_TOO_MANY_REQUESTS {
+			t.Fatalf("Expected TOO_MANY_REQUESTS once the queue is full, got %v", reply.Status)
 		}
 	}
 }
 
 func TestEmptyBroadcastMessage(t *testing.T) {
-	bs := newPlainBroadcastServer(2, 1, time.Second, nil) // queueSize, batchSize (unused), batchTimeout (unused), ramLedger (unused)
-	m := newMockB()
-	defer close(m.recvChan)
+	bs := newPlainBroadcastServer(2, 1, 0, time.Second, false, 0, nil, nil, nil, nil, nil) // queueSize, batchSize (unused), batchMaxBytes (unused), batchTimeout (unused), ackAfterCommit, dedupWindow, filter, ramLedger (unused), pendingLog, registry, clock
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
 	go bs.handleBroadcast(m)
 
-	m.recvChan <- &ab.BroadcastMessage{}
-	reply := <-m.sendChan
+	m.RecvChan <- &ab.BroadcastMessage{}
+	reply := <-m.SendChan
 	if reply.Status != ab.Status_BAD_REQUEST {
 		t.Fatalf("Should have rejected the null message")
 	}
 
 }
 
+type forbidRule struct{}
+
+func (fr forbidRule) Apply(message *ab.BroadcastMessage) broadcastfilter.Action {
+	if string(message.Data) == "forbidden" {
+		return broadcastfilter.Reject
+	}
+	return broadcastfilter.Forward
+}
+
+func (fr forbidRule) RejectReply(message *ab.BroadcastMessage) *ab.BroadcastResponse {
+	return ab.ReasonForbidden.BroadcastResponse("message is forbidden")
+}
+
+func TestForbiddenMessage(t *testing.T) {
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, forbidRule{}, broadcastfilter.AcceptRule})
+	bs := newPlainBroadcastServer(2, 1, 0, time.Second, false, 0, filter, nil, nil, nil, nil)
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("forbidden")}
+	if reply := <-m.SendChan; reply.Status != ab.Status_FORBIDDEN || reply.Info != "message is forbidden" {
+		t.Fatalf("Expected FORBIDDEN but got %v", reply)
+	}
+
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("allowed")}
+	if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected SUCCESS but got %v", reply)
+	}
+}
+
 func TestEmptyBatch(t *testing.T) {
-	bs := newPlainBroadcastServer(2, 1, time.Millisecond, ramledger.New(10, genesisBlock))
+	bs := newPlainBroadcastServer(2, 1, 0, time.Millisecond, false, 0, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
 	time.Sleep(100 * time.Millisecond) // Note, this is not a race, as worst case, the timer does not expire, and the test still passes
 	if bs.rl.(rawledger.Reader).Height() != 1 {
 		t.Fatalf("Expected no new blocks created")
@@ -145,14 +203,603 @@ func TestEmptyBatch(t *testing.T) {
 
 func TestFilledBatch(t *testing.T) {
 	batchSize := 2
-	bs := newBroadcastServer(0, batchSize, time.Hour, ramledger.New(10, genesisBlock))
-	defer bs.halt()
-	messages := 11 // Sending 11 messages, with a batch size of 2, ensures the 10th message is processed before we proceed for 5 blocks
+	messages := 11 // Sending 11 messages, with a batch size of 2, fills 5 blocks and leaves 1 message pending
+	bs := newBroadcastServer(messages, batchSize, 0, time.Hour, false, 0, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
+	defer bs.Halt()
+	bq := bs.queues.newQueue(messages)
 	for i := 0; i < messages; i++ {
-		bs.sendChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+		bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("Some bytes")}})
 	}
 	expected := uint64(1 + messages/batchSize)
+	waitForHeight(t, bs.rl.(rawledger.Reader), expected)
+	time.Sleep(100 * time.Millisecond)
 	if bs.rl.(rawledger.Reader).Height() != expected {
 		t.Fatalf("Expected %d blocks but got %d", expected, bs.rl.(rawledger.Reader).Height())
 	}
 }
+
+func TestBatchMaxBytes(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	bs := newBroadcastServer(10, 10, 10, time.Hour, false, 0, nil, rl, nil, nil, nil)
+	defer bs.Halt()
+	bq := bs.queues.newQueue(10)
+	for _, size := range []int{4, 4, 4, 20} {
+		bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: make([]byte, size)}})
+	}
+
+	// The third message would take the batch beyond the limit, and the last exceeds it alone
+	waitForHeight(t, rl, 4)
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	for i, expected := range []int{2, 1, 1} {
+		if block, _ := it.Next(); len(block.Data.Messages) != expected {
+			t.Fatalf("Expected block %d to contain %d messages but got %d", i+1, expected, len(block.Data.Messages))
+		}
+	}
+}
+
+// waitForHeight fails the test if the ledger does not reach the given height within a second
+func waitForHeight(t *testing.T, rl rawledger.Reader, height uint64) {
+	deadline := time.After(time.Second)
+	for rl.Height() < height {
+		select {
+		case <-deadline:
+			t.Fatalf("Expected height %d but got %d", height, rl.Height())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestFairScheduling(t *testing.T) {
+	batchSize := 4
+	rl := ramledger.New(10000, genesisBlock)
+	bs := newBroadcastServer(100, batchSize, 0, time.Hour, false, 0, nil, rl, nil, nil, nil)
+	defer bs.Halt()
+
+	flood := mocks.NewBroadcastStream()
+	go bs.handleBroadcast(flood)
+	go func() {
+		for range flood.SendChan {
+		}
+	}()
+	stopFlood := make(chan struct{})
+	go func() {
+		defer close(flood.RecvChan)
+		for i := 0; ; i++ {
+			select {
+			case flood.RecvChan <- &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("flood %d", i))}:
+			case <-stopFlood:
+				return
+			}
+		}
+	}()
+
+	// Let the flooding client fill its queue
+	waitForHeight(t, rl, 10)
+
+	slow := mocks.NewBroadcastStream()
+	defer close(slow.RecvChan)
+	go bs.handleBroadcast(slow)
+	messages := 10
+	queuedAt := make(map[string]uint64)
+	for i := 0; i < messages; i++ {
+		data := fmt.Sprintf("slow %d", i)
+		slow.RecvChan <- &ab.BroadcastMessage{Data: []byte(data)}
+		if reply := <-slow.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Should have successfully queued the message")
+		}
+		// The reply is sent once the message is queued, so no block after the next may be cut without it
+		queuedAt[data] = rl.Height()
+		// The slow client sends no faster than its fair share would be ordered
+		waitForHeight(t, rl, queuedAt[data]+2)
+	}
+	close(stopFlood)
+
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	for len(queuedAt) > 0 {
+		block, _ := it.Next()
+		for _, msg := range block.Data.Messages {
+			height, ok := queuedAt[string(msg.Data)]
+			if !ok {
+				continue
+			}
+			if block.Header.Number > height+1 {
+				t.Fatalf("Message %s was queued at height %d but not ordered until block %d", msg.Data, height, block.Header.Number)
+			}
+			delete(queuedAt, string(msg.Data))
+		}
+	}
+}
+
+func TestAckAfterCommit(t *testing.T) {
+	batchSize := 2
+	bs := newBroadcastServer(2, batchSize, 0, time.Hour, true, 0, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
+	defer bs.Halt()
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	for i := 0; i < batchSize; i++ {
+		m.RecvChan <- &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}
+	}
+
+	replies := make([]*ab.BroadcastResponse, batchSize)
+	for i := range replies {
+		select {
+		case replies[i] = <-m.SendChan:
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for commit reply")
+		}
+		if replies[i].Status != ab.Status_SUCCESS {
+			t.Fatalf("Should have successfully committed the message")
+		}
+		if replies[i].Index != uint64(i) {
+			t.Fatalf("Expected index %d but got %d", i, replies[i].Index)
+		}
+	}
+
+	md := mocks.NewDeliverStream()
+	defer close(md.RecvChan)
+	ds := deliver.NewServer(func(chainID []byte) (rawledger.Reader, bool) { return bs.rl.(rawledger.Reader), true }, deliver.Options{MaxWindowSize: MagicLargestWindow, Features: ab.AllFeatures})
+	go ds.Handle(md)
+
+	md.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: replies[0].BlockNumber}}}
+
+	select {
+	case blockReply := <-md.SendChan:
+		block := blockReply.GetBlock()
+		if block == nil {
+			t.Fatalf("Expected a block but got %v", blockReply)
+		}
+		if block.Header.Number != replies[0].BlockNumber {
+			t.Fatalf("Expected block %d but got %d", replies[0].BlockNumber, block.Header.Number)
+		}
+		for i, reply := range replies {
+			if reply.BlockNumber != block.Header.Number {
+				t.Fatalf("Expected all messages to be committed in block %d", block.Header.Number)
+			}
+			if string(block.Data.Messages[reply.Index].Data) != fmt.Sprintf("%d", i) {
+				t.Fatalf("Message %d was not at its acknowledged index", i)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the block")
+	}
+}
+
+func TestPipelinedCorrelationIDs(t *testing.T) {
+	messages, batchSize := 100, 10
+	bs := newBroadcastServer(messages, batchSize, 0, time.Hour, true, 0, nil, ramledger.New(20, genesisBlock), nil, nil, nil)
+	defer bs.Halt()
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	go func() {
+		for i := 0; i < messages; i++ {
+			m.RecvChan <- &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i)), CorrelationID: []byte(fmt.Sprintf("id-%d", i))}
+		}
+	}()
+
+	rl := bs.rl.(rawledger.Reader)
+	for i := 0; i < messages; i++ {
+		var reply *ab.BroadcastResponse
+		select {
+		case reply = <-m.SendChan:
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for the reply to message %d", i)
+		}
+		if reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Should have successfully committed message %d, got %v", i, reply)
+		}
+		if string(reply.CorrelationID) != fmt.Sprintf("id-%d", i) {
+			t.Fatalf("Expected the reply to message %d to echo its correlation ID, got %q", i, reply.CorrelationID)
+		}
+
+		it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, reply.BlockNumber)
+		block, _ := it.Next()
+		msg := block.Data.Messages[reply.Index]
+		if string(msg.Data) != fmt.Sprintf("%d", i) || !bytes.Equal(msg.CorrelationID, reply.CorrelationID) {
+			t.Fatalf("Message %d was not at block %d index %d", i, reply.BlockNumber, reply.Index)
+		}
+	}
+}
+
+func TestOverlongCorrelationID(t *testing.T) {
+	bs := newPlainBroadcastServer(2, 1, 0, time.Second, false, 0, nil, nil, nil, nil, nil)
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("data"), CorrelationID: make([]byte, broadcastfilter.MaxCorrelationIDBytes+1)}
+	if reply := <-m.SendChan; reply.Status != ab.Status_BAD_REQUEST {
+		t.Fatalf("Should have rejected an overlong correlation ID, got %v", reply)
+	}
+}
+
+func TestNoAckBeforeCommit(t *testing.T) {
+	bs := newBroadcastServer(2, 2, 0, time.Hour, true, 0, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+
+	// Stop the orderer before the batch can be cut, losing the message
+	time.Sleep(100 * time.Millisecond)
+	bs.Halt()
+
+	// The lost message is never acknowledged, the client is told that the chain failed instead
+	select {
+	case reply := <-m.SendChan:
+		if reply.Status != ab.Status_SERVICE_UNAVAILABLE {
+			t.Fatalf("Should not have acknowledged an uncommitted message, but got %v", reply)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Should have told the client that the chain failed")
+	}
+
+	if bs.rl.(rawledger.Reader).Height() != 1 {
+		t.Fatalf("Expected no new blocks created")
+	}
+}
+
+func TestShutdownFlushesBatch(t *testing.T) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+
+	messages := 3
+	lf := fileledger.NewFactory(location)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: messages + 1, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, AckAfterCommit: true, Features: ab.AllFeatures}, lf, static.TestChainID)
+	m := mocks.NewBroadcastStream()
+	go s.Broadcast(m)
+
+	for i := 0; i < messages; i++ {
+		m.RecvChan <- &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}
+	}
+
+	// Wait for the messages to reach the pending batch
+	time.Sleep(100 * time.Millisecond)
+
+	go s.Teardown()
+
+	for i := 0; i < messages; i++ {
+		select {
+		case reply := <-m.SendChan:
+			if reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 1 {
+				t.Fatalf("Expected message to be committed to block 1 but got %v", reply)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for the pending batch to be committed")
+		}
+	}
+
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+	if reply := <-m.SendChan; reply.Status != ab.Status_SERVICE_UNAVAILABLE {
+		t.Fatalf("Should not have accepted a message after shutdown but got %v", reply)
+	}
+	close(m.RecvChan)
+
+	rl, ok := fileledger.NewFactory(location).Get(static.TestChainID)
+	if !ok {
+		t.Fatalf("Expected the chain to be found after reopening the ledger")
+	}
+	if rl.Height() != 2 {
+		t.Fatalf("Expected the pending batch to be the final block, but height is %d", rl.Height())
+	}
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	block, status := it.Next()
+	if status != ab.Status_SUCCESS {
+		t.Fatalf("Error reading final block: %v", status)
+	}
+	if len(block.Data.Messages) != messages {
+		t.Fatalf("Expected %d messages in the final block but got %d", messages, len(block.Data.Messages))
+	}
+
+	// A second teardown, as a repeated interrupt causes, returns without effect
+	s.Teardown()
+}
+
+func startIdleTimeoutServer(t *testing.T, ackAfterCommit bool, batchSize int, batchTimeout time.Duration) (Orderer, *fakeClock, gometrics.Registry) {
+	clock := newFakeClock()
+	registry := gometrics.NewRegistry()
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: batchSize, MaxWindowSize: MagicLargestWindow, BatchTimeout: batchTimeout, AckAfterCommit: ackAfterCommit, IdleTimeout: time.Second, Registry: registry, Clock: clock}, lf, static.TestChainID)
+	return s, clock, registry
+}
+
+func expectIdleClosed(t *testing.T, m *mocks.BroadcastStream, done chan error) {
+	if reply := <-m.SendChan; reply.Status != ab.Status_REQUEST_TIMEOUT {
+		t.Fatalf("Expected REQUEST_TIMEOUT but got %v", reply)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected the idle stream to be closed cleanly but got %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the idle stream to be closed")
+	}
+}
+
+func TestIdleStreamClosed(t *testing.T) {
+	s, clock, registry := startIdleTimeoutServer(t, false, 10, time.Hour)
+	defer s.Teardown()
+
+	m := mocks.NewLegacyBroadcastStream()
+	defer close(m.RecvChan)
+	done := make(chan error)
+	go func() { done <- s.Broadcast(m) }()
+
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+	if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Should have successfully queued the message")
+	}
+
+	clock.advance(999 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatalf("Expected the stream to remain open before the idle timeout")
+	default:
+	}
+
+	clock.advance(time.Millisecond)
+	expectIdleClosed(t, m, done)
+	expectCount(t, registry, "broadcast.idle_closed", 1)
+}
+
+func TestActiveStreamNotClosed(t *testing.T) {
+	s, clock, registry := startIdleTimeoutServer(t, false, 10, time.Hour)
+	defer s.Teardown()
+
+	m := mocks.NewLegacyBroadcastStream()
+	defer close(m.RecvChan)
+	done := make(chan error)
+	go func() { done <- s.Broadcast(m) }()
+
+	// Well past the idle timeout in total, but never more than half of it between messages
+	for i := 0; i < 10; i++ {
+		m.RecvChan <- &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected the message to be queued on an active stream but got %v", reply)
+		}
+		clock.advance(500 * time.Millisecond)
+	}
+
+	select {
+	case <-done:
+		t.Fatalf("Expected the active stream to remain open")
+	default:
+	}
+	expectCount(t, registry, "broadcast.idle_closed", 0)
+}
+
+func TestUnackedStreamNotIdle(t *testing.T) {
+	s, clock, registry := startIdleTimeoutServer(t, true, 2, 2*time.Second)
+	defer s.Teardown()
+
+	m := mocks.NewLegacyBroadcastStream()
+	defer close(m.RecvChan)
+	done := make(chan error)
+	go func() { done <- s.Broadcast(m) }()
+
+	// The idle timer, armed when the stream opened, is replaced once the message arrives, then the batch timer is armed
+	clock.waitForTimer(t)
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+	clock.waitForTimer(t)
+	clock.waitForTimer(t)
+
+	// The idle timeout passes while the reply awaits the commit
+	clock.advance(time.Second)
+	clock.advance(time.Second)
+	if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 1 {
+		t.Fatalf("Expected the message to be committed by the batch timeout but got %v", reply)
+	}
+
+	// The stream becomes idle only once the reply is sent
+	if deadline := clock.waitForTimer(t); !deadline.Equal(clock.Now().Add(time.Second)) {
+		t.Fatalf("Expected the idle timer to be armed a full timeout after the reply, but it expires at %v", deadline)
+	}
+	clock.advance(999 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatalf("Expected the stream to remain open before the idle timeout")
+	default:
+	}
+
+	clock.advance(time.Millisecond)
+	expectIdleClosed(t, m, done)
+	expectCount(t, registry, "broadcast.idle_closed", 1)
+}
+
+// blockingWriter holds each append until it is released, signaling appending if the test awaits it
+type blockingWriter struct {
+	rawledger.ReadWriter
+	appending chan struct{}
+	release   chan struct{}
+}
+
+func (bw *blockingWriter) AppendEncoded(messages []*ab.BroadcastMessage, encoded [][]byte, seal rawledger.Sealer) *ab.Block {
+	select {
+	case bw.appending <- struct{}{}:
+	default:
+	}
+	<-bw.release
+	return bw.ReadWriter.AppendEncoded(messages, encoded, seal)
+}
+
+func TestCutWhileCommitting(t *testing.T) {
+	batchSize := 2
+	bw := &blockingWriter{ReadWriter: ramledger.New(10, genesisBlock), appending: make(chan struct{}, 1), release: make(chan struct{})}
+	bs := newBroadcastServer(10, batchSize, 0, time.Hour, false, 0, nil, bw, nil, nil, nil)
+	bq := bs.queues.newQueue(10)
+	for i := 0; i < 3*batchSize; i++ {
+		bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}})
+	}
+
+	// While the first block is being appended, the batches behind it are still taken from the queue
+	<-bw.appending
+	deadline := time.After(time.Second)
+	for bs.queues.depth() != 0 {
+		select {
+		case <-deadline:
+			t.Fatalf("Expected the queued messages to be cut while the ledger was appending, but %d remain", bs.queues.depth())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	close(bw.release)
+	waitForHeight(t, bw, 4)
+	bs.shutdown()
+}
+
+// slowWriter delays each append, so that cut batches back up behind the committer
+type slowWriter struct {
+	rawledger.ReadWriter
+	delay time.Duration
+}
+
+func (sw *slowWriter) AppendEncoded(messages []*ab.BroadcastMessage, encoded [][]byte, seal rawledger.Sealer) *ab.Block {
+	time.Sleep(sw.delay)
+	return sw.ReadWriter.AppendEncoded(messages, encoded, seal)
+}
+
+func TestShutdownDrainsCommitter(t *testing.T) {
+	batchSize := 2
+	sw := &slowWriter{ReadWriter: ramledger.New(10, genesisBlock), delay: 20 * time.Millisecond}
+	bs := newBroadcastServer(10, batchSize, 0, time.Hour, false, 0, nil, sw, nil, nil, nil)
+	bq := bs.queues.newQueue(10)
+	for i := 0; i < 3*batchSize+1; i++ {
+		bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}})
+	}
+	for bs.queues.depth() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	bs.shutdown()
+	if sw.Height() != 5 {
+		t.Fatalf("Expected every cut batch and the pending one to be committed by the shutdown, but the height is %d", sw.Height())
+	}
+	it, _ := sw.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	for i := 0; i < 3*batchSize+1; {
+		block, _ := it.Next()
+		for _, msg := range block.Data.Messages {
+			if string(msg.Data) != fmt.Sprintf("%d", i) {
+				t.Fatalf("Expected the messages to be committed in the order they were cut")
+			}
+			i++
+		}
+	}
+}
+
+// benchmarkCommit commits blocks of 500 messages, which carry their marshaled form if encoded, as those recorded in the pending log do
+func benchmarkCommit(b *testing.B, encoded bool) {
+	batchSize := 500
+	bs := newPlainBroadcastServer(batchSize, batchSize, 0, time.Hour, false, 0, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
+	batch := make([]*pendingMessage, batchSize)
+	for i := range batch {
+		msg := &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("Message %d of some length", i))}
+		batch[i] = &pendingMessage{msg: msg}
+		if encoded {
+			batch[i].encoded, _ = proto.Marshal(msg)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bs.commit(batch, cutSize)
+	}
+}
+
+func BenchmarkCommit(b *testing.B) {
+	benchmarkCommit(b, false)
+}
+
+func BenchmarkCommitEncoded(b *testing.B) {
+	benchmarkCommit(b, true)
+}
+
+// benchmarkThroughput orders blocks of 500 messages onto rl, with the messages queued as fast as they are taken
+func benchmarkThroughput(b *testing.B, rl rawledger.ReadWriter, pipelined bool) {
+	logging.SetLevel(logging.ERROR, "")
+	defer logging.SetLevel(logging.DEBUG, "")
+
+	batchSize := 500
+	bs := newPlainBroadcastServer(batchSize, batchSize, 0, time.Hour, false, 0, nil, rl, nil, nil, nil)
+	bs.unpipelined = !pipelined
+	bs.Start()
+	defer bs.Halt()
+	bq := bs.queues.newQueue(batchSize)
+	msg := &ab.BroadcastMessage{Data: make([]byte, 100)}
+
+	b.ResetTimer()
+	for i := 0; i < b.N*batchSize; i++ {
+		for !bq.enqueue(&pendingMessage{msg: msg}) {
+			runtime.Gosched()
+		}
+	}
+	for rl.Height() < uint64(1+b.N) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// benchmarkFileLedgerThroughput orders onto a file ledger in a temporary directory, syncing each block to disk if syncWrites is set
+func benchmarkFileLedgerThroughput(b *testing.B, syncWrites, pipelined bool) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		b.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+	benchmarkThroughput(b, fileledger.NewWithSyncWrites(location, genesisBlock, syncWrites), pipelined)
+}
+
+func BenchmarkFileLedgerThroughput(b *testing.B) {
+	benchmarkFileLedgerThroughput(b, false, true)
+}
+
+func BenchmarkSyncedFileLedgerThroughput(b *testing.B) {
+	benchmarkFileLedgerThroughput(b, true, true)
+}
+
+func BenchmarkSyncedFileLedgerThroughputUnpipelined(b *testing.B) {
+	benchmarkFileLedgerThroughput(b, true, false)
+}
+
+func TestShutdownCommitsQueuedMessages(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	bs := newPlainBroadcastServer(10, 10, 0, time.Hour, false, 0, nil, rl, nil, nil, nil)
+	for i := 0; i < 3; i++ {
+		if !bs.Enqueue(&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}) {
+			t.Fatalf("Should have queued the message")
+		}
+	}
+
+	// The batching loop starts only once the shutdown has begun, so that the messages are still queued
+	shutdown := make(chan struct{})
+	go func() {
+		bs.shutdown()
+		close(shutdown)
+	}()
+	<-bs.stopChan
+	bs.Start()
+	<-shutdown
+
+	if rl.Height() != 2 {
+		t.Fatalf("Expected the queued messages to be committed in a final block, but the height is %d", rl.Height())
+	}
+	block, _ := rl.GetBlock(1)
+	if len(block.Data.Messages) != 3 {
+		t.Fatalf("Expected the final block to hold the 3 queued messages, got %d", len(block.Data.Messages))
+	}
+	if bs.Enqueue(&ab.BroadcastMessage{Data: []byte("late")}) {
+		t.Fatalf("Should not have queued a message after the shutdown")
+	}
+
+	// Shutting down again, as a second Teardown does, returns at once
+	bs.shutdown()
+}
@@ -0,0 +1,205 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// fakeClock adds the expectations of the tests to the fake clock
+type fakeClock struct {
+	*clock.Fake
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{clock.NewFake()}
+}
+
+// advance moves the clock forward by d, firing any timers which fall due
+func (fc *fakeClock) advance(d time.Duration) {
+	fc.Advance(d)
+}
+
+// activeTimers returns the number of timers which have neither fired nor been stopped
+func (fc *fakeClock) activeTimers() int {
+	return fc.ActiveTimers()
+}
+
+// waitForTimer waits for a timer to be created, returning its deadline
+func (fc *fakeClock) waitForTimer(t *testing.T) time.Time {
+	select {
+	case deadline := <-fc.Created():
+		return deadline
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a timer to be created")
+		return time.Time{}
+	}
+}
+
+// forgetTimers discards the record of the timers created so far, so that waitForTimer waits for a new one
+func (fc *fakeClock) forgetTimers() {
+	for {
+		select {
+		case <-fc.Created():
+		default:
+			return
+		}
+	}
+}
+
+// expectNoTimer fails if a timer was created which was not yet waited for
+func (fc *fakeClock) expectNoTimer(t *testing.T) {
+	select {
+	case <-fc.Created():
+		t.Fatalf("Expected no further timer to be created")
+	default:
+	}
+}
+
+func TestFirstMessageArmsTimer(t *testing.T) {
+	clock := newFakeClock()
+	registry := gometrics.NewRegistry()
+	rl := ramledger.New(10, genesisBlock)
+	bs := newBroadcastServer(10, 10, 0, time.Second, false, 0, nil, rl, nil, registry, clock)
+	defer bs.Halt()
+	bq := bs.queues.newQueue(10)
+
+	// An idle chain has nothing to cut, so no timer may be running
+	clock.advance(time.Hour)
+	if clock.activeTimers() != 0 {
+		t.Fatalf("Expected no timer to be armed before the first message")
+	}
+
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("first")}, received: clock.Now()})
+	if deadline := clock.waitForTimer(t); !deadline.Equal(clock.Now().Add(time.Second)) {
+		t.Fatalf("Expected the timer to expire a batch timeout after the first message, but it expires at %v", deadline)
+	}
+
+	// Later messages of the same batch must not push the cut back
+	clock.advance(500 * time.Millisecond)
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("second")}, received: clock.Now()})
+	clock.advance(499 * time.Millisecond)
+	if rl.Height() != 1 {
+		t.Fatalf("Expected no block to be cut before the batch timeout")
+	}
+
+	clock.advance(time.Millisecond)
+	waitForHeight(t, rl, 2)
+	clock.expectNoTimer(t)
+
+	expectCount(t, registry, "cuts.timeout", 1)
+	latency := registry.Get("commit_latency").(gometrics.Timer)
+	if latency.Max() != int64(time.Second) {
+		t.Fatalf("Expected the first message to wait exactly the batch timeout, but the longest wait was %v", time.Duration(latency.Max()))
+	}
+}
+
+func TestTimerResetOnCut(t *testing.T) {
+	clock := newFakeClock()
+	registry := gometrics.NewRegistry()
+	rl := ramledger.New(10, genesisBlock)
+	bs := newBroadcastServer(10, 2, 0, time.Second, false, 0, nil, rl, nil, registry, clock)
+	defer bs.Halt()
+	bq := bs.queues.newQueue(10)
+
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("first")}})
+	clock.waitForTimer(t)
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("second")}})
+	waitForHeight(t, rl, 2)
+	if clock.activeTimers() != 0 {
+		t.Fatalf("Expected the timer to be stopped once the batch was cut by size")
+	}
+
+	// The stale timer must not cut the next batch early
+	clock.advance(900 * time.Millisecond)
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("third")}})
+	if deadline := clock.waitForTimer(t); !deadline.Equal(clock.Now().Add(time.Second)) {
+		t.Fatalf("Expected the timer to be rearmed by the first message of the new batch, but it expires at %v", deadline)
+	}
+	clock.advance(999 * time.Millisecond)
+	if rl.Height() != 2 {
+		t.Fatalf("Expected no block to be cut before the rearmed timer expired")
+	}
+
+	clock.advance(time.Millisecond)
+	waitForHeight(t, rl, 3)
+	expectCount(t, registry, "cuts.size", 1)
+	expectCount(t, registry, "cuts.timeout", 1)
+}
+
+func TestShutdownCancelsTimer(t *testing.T) {
+	clock := newFakeClock()
+	registry := gometrics.NewRegistry()
+	rl := ramledger.New(10, genesisBlock)
+	bs := newBroadcastServer(10, 10, 0, time.Second, false, 0, nil, rl, nil, registry, clock)
+	bq := bs.queues.newQueue(10)
+
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("first")}})
+	clock.waitForTimer(t)
+	bs.shutdown()
+
+	if rl.Height() != 2 {
+		t.Fatalf("Expected the pending batch to be cut by the shutdown")
+	}
+	if clock.activeTimers() != 0 {
+		t.Fatalf("Expected the timer to be stopped by the shutdown")
+	}
+
+	clock.advance(time.Hour)
+	expectCount(t, registry, "cuts.shutdown", 1)
+	expectCount(t, registry, "cuts.timeout", 0)
+}
+
+func TestBlocksStampedWithClock(t *testing.T) {
+	clock := newFakeClock()
+	rl := ramledger.New(10, genesisBlock)
+	bs := newPlainBroadcastServer(10, 1, 0, time.Hour, false, 0, nil, rl, nil, nil, clock)
+	bs.stamper = rawledger.NewTimestamper(rl)
+	bs.Start()
+	defer bs.Halt()
+	bq := bs.queues.newQueue(10)
+
+	expectStamp := func(number uint64, expected time.Time) {
+		waitForHeight(t, rl, number+1)
+		block, _ := rl.GetBlock(number)
+		if committed, ok := block.Timestamp(); !ok || !committed.Equal(expected) {
+			t.Fatalf("Expected block %d to be stamped with %v but got %v", number, expected, committed)
+		}
+	}
+
+	clock.advance(time.Hour)
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("first")}, received: clock.Now()})
+	expectStamp(1, clock.Now())
+
+	// A backwards step of the clock must not make the timestamps of the chain decrease
+	stamped := clock.Now()
+	clock.advance(-time.Minute)
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("second")}, received: clock.Now()})
+	expectStamp(2, stamped)
+
+	clock.advance(2 * time.Minute)
+	bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("third")}, received: clock.Now()})
+	expectStamp(3, clock.Now())
+}
@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// BatchSizeKey is the ID of the Solo configuration item whose Data is a marshaled BatchSize
+const BatchSizeKey = "BatchSize"
+
+// BatchSizeConfig returns the BatchSize configured by cm, or false if none is configured or it cannot be read
+func BatchSizeConfig(cm configtx.Manager) (*ab.BatchSize, bool) {
+	data, ok := cm.Get(ab.Configuration_Solo, BatchSizeKey)
+	if !ok {
+		return nil, false
+	}
+	batchSize := &ab.BatchSize{}
+	if err := proto.Unmarshal(data, batchSize); err != nil {
+		logger.Warningf("Ignoring configured batch size which cannot be read: %s", err)
+		return nil, false
+	}
+	return batchSize, true
+}
+
+// configure is the observer of the default chain's configuration, a change of how its blocks are cut applies from the next block
+func (s *server) configure(sequence uint64, changed []ab.Configuration_ConfigurationType) {
+	if batchChanged(changed) {
+		s.refreshBatchSize()
+	}
+}
+
+// batchChanged returns whether a change of configuration may have changed how blocks are cut
+func batchChanged(changed []ab.Configuration_ConfigurationType) bool {
+	for _, ctype := range changed {
+		if ctype == ab.Configuration_Solo || ctype == ab.Configuration_Orderer {
+			return true
+		}
+	}
+	return false
+}
+
+// configuredBatch returns the batch parameters configured by cm, in place of those given by the options
+func (s *server) configuredBatch(cm configtx.Manager) sharedconfig.BatchParameters {
+	batch := sharedconfig.BatchParameters{Size: s.opts.BatchSize, MaxBytes: s.opts.BatchMaxBytes, Timeout: s.opts.BatchTimeout}
+	if batchSize, ok := BatchSizeConfig(cm); ok {
+		if batchSize.Messages == 0 {
+			logger.Warningf("Ignoring configured batch size of zero messages")
+		} else {
+			batch.Size = int(batchSize.Messages)
+			batch.MaxBytes = int(batchSize.MaxBytes)
+		}
+	}
+	return sharedconfig.Batch(cm, batch)
+}
+
+// configureChain is the observer of the configuration of a chain other than the default, applying a change of how its blocks are cut from the next block
+func (s *server) configureChain(bs *broadcastServer) configtx.Observer {
+	return func(sequence uint64, changed []ab.Configuration_ConfigurationType) {
+		if !batchChanged(changed) {
+			return
+		}
+		batch := s.configuredBatch(bs.config)
+		logger.Infof("Batch size of chain %x configured to %d messages and %d bytes, with a timeout of %v", bs.config.ChainID(), batch.Size, batch.MaxBytes, batch.Timeout)
+		bs.setBatchParameters(batch)
+	}
+}
+
+// refreshBatchSize applies the configured batch parameters to the default chain, in place of those given by the options
+func (s *server) refreshBatchSize() {
+	batch := s.configuredBatch(s.opts.Config)
+
+	s.batchLock.Lock()
+	defer s.batchLock.Unlock()
+	if batch == s.batch {
+		return
+	}
+	s.batch = batch
+	logger.Infof("Batch size of the default chain configured to %d messages and %d bytes, with a timeout of %v", batch.Size, batch.MaxBytes, batch.Timeout)
+	if s.defaultBS != nil {
+		s.defaultBS.setBatchParameters(batch)
+	}
+}
+
+// ValidateConfig checks a configuration envelope against the configuration of the default chain, which is left unchanged
+func (s *server) ValidateConfig(ctx context.Context, configTx *ab.ConfigurationEnvelope) (*ab.ValidateConfigResponse, error) {
+	if s.opts.Config == nil {
+		return &ab.ValidateConfigResponse{Status: ab.ReasonUnavailable.Status(), Info: "configuration is not managed by this orderer"}, nil
+	}
+	if err := s.opts.Config.Validate(configTx); err != nil {
+		return &ab.ValidateConfigResponse{Status: ab.ReasonMalformed.Status(), Info: ab.Detail("%s", err)}, nil
+	}
+	return &ab.ValidateConfigResponse{Status: ab.Status_SUCCESS}, nil
+}
@@ -0,0 +1,577 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap/static"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// acceptAllPolicies authorizes every configuration change
+type acceptAllPolicies struct{}
+
+func (ap acceptAllPolicies) GetPolicy(id string) (policies.Policy, bool) {
+	return ap, true
+}
+
+func (ap acceptAllPolicies) Evaluate(msg []byte, sigs []*ab.SignedData) error {
+	return nil
+}
+
+// batchSizeEnvelope configures the batch size of the test chain to the given number of messages
+func batchSizeEnvelope(sequence uint64, messages uint32) *ab.ConfigurationEnvelope {
+	data, _ := proto.Marshal(&ab.BatchSize{Messages: messages})
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: static.TestChainID, ID: BatchSizeKey, Type: ab.Configuration_Solo, Data: data, LastModified: sequence})
+	return &ab.ConfigurationEnvelope{
+		Sequence: sequence,
+		ChainID:  static.TestChainID,
+		Entries:  []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}},
+	}
+}
+
+func expectBlockSize(t *testing.T, rl rawledger.Reader, number uint64, messages int) {
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, number)
+	block, _ := it.Next()
+	if len(block.Data.Messages) != messages {
+		t.Fatalf("Expected block %d to contain %d messages, got %d", number, messages, len(block.Data.Messages))
+	}
+}
+
+func TestBatchSizeFromConfig(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	cm, err := configtx.NewRegistry().NewManager(batchSizeEnvelope(0, 3), acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 10, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, Config: cm, Features: ab.AllFeatures}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go s.Broadcast(m)
+	broadcast := func(count int) {
+		for i := 0; i < count; i++ {
+			m.RecvChan <- &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", rl.Height()*10+uint64(i)))}
+			if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+				t.Fatalf("Expected the message to be queued but got %v", reply)
+			}
+		}
+	}
+
+	// The configured batch size replaces that of the options
+	broadcast(3)
+	waitForHeight(t, rl, 2)
+	expectBlockSize(t, rl, 1, 3)
+
+	if err := cm.Apply(batchSizeEnvelope(1, 2)); err != nil {
+		t.Fatalf("Error applying configuration: %s", err)
+	}
+	broadcast(2)
+	waitForHeight(t, rl, 3)
+	expectBlockSize(t, rl, 2, 2)
+
+	// Lowering the batch size to below the pending batch cuts it at once
+	broadcast(1)
+	if err := cm.Apply(batchSizeEnvelope(2, 1)); err != nil {
+		t.Fatalf("Error applying configuration: %s", err)
+	}
+	waitForHeight(t, rl, 4)
+	expectBlockSize(t, rl, 3, 1)
+}
+
+func TestConfigTransactionIsolated(t *testing.T) {
+	rl := ramledger.New(10, genesisBlock)
+	cm, err := configtx.NewRegistry().NewManager(batchSizeEnvelope(0, 3), acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.NewConfigRule(cm, nil), broadcastfilter.AcceptRule})
+	bs := newBroadcastServer(10, 3, 0, time.Hour, false, 0, filter, rl, nil, nil, nil)
+	defer bs.Halt()
+
+	configTx, err := configtx.MarshalConfigurationTransaction(batchSizeEnvelope(1, 3))
+	if err != nil {
+		t.Fatalf("Error marshaling configuration: %s", err)
+	}
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+	for _, data := range [][]byte{[]byte("a"), []byte("b"), configTx, []byte("c"), []byte("d"), []byte("e")} {
+		m.RecvChan <- &ab.BroadcastMessage{Data: data}
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected the message to be queued but got %v", reply)
+		}
+	}
+
+	// The configuration transaction cuts the batch ahead of it short, and is followed by a full batch
+	waitForHeight(t, rl, 4)
+	expectBlockSize(t, rl, 1, 2)
+	expectBlockSize(t, rl, 2, 1)
+	expectBlockSize(t, rl, 3, 3)
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 2)
+	if block, _ := it.Next(); configtx.BlockConfiguration(block) == nil {
+		t.Fatalf("Expected block 2 to carry the configuration transaction")
+	}
+}
+
+func TestBatchSizeFromCommittedConfig(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	registry := configtx.NewRegistry()
+	if err := registry.Register(ab.Configuration_Orderer, sharedconfig.NewHandler(sharedconfig.Limits{})); err != nil {
+		t.Fatalf("Error registering handler: %s", err)
+	}
+	cm, err := registry.NewManager(&ab.ConfigurationEnvelope{Sequence: 0, ChainID: static.TestChainID}, acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.NewConfigRule(cm, nil), broadcastfilter.AcceptRule})
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 3, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, Config: cm, Filter: filter}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	data, _ := proto.Marshal(&ab.BatchSize{Messages: 2})
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: static.TestChainID, ID: sharedconfig.BatchSizeKey, Type: ab.Configuration_Orderer, Data: data, LastModified: 1})
+	configTx, err := configtx.MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  static.TestChainID,
+		Entries:  []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}},
+	})
+	if err != nil {
+		t.Fatalf("Error marshaling configuration: %s", err)
+	}
+
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go s.Broadcast(m)
+	for _, data := range [][]byte{[]byte("a"), []byte("b"), configTx, []byte("c"), []byte("d"), []byte("e"), []byte("f")} {
+		m.RecvChan <- &ab.BroadcastMessage{Data: data}
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected the message to be queued but got %v", reply)
+		}
+	}
+
+	// The blocks after the configuration block are cut at the batch size it commits, rather than at that of the options
+	waitForHeight(t, rl, 5)
+	expectBlockSize(t, rl, 1, 2)
+	expectBlockSize(t, rl, 2, 1)
+	expectBlockSize(t, rl, 3, 2)
+	expectBlockSize(t, rl, 4, 2)
+	if cm.Sequence() != 1 {
+		t.Fatalf("Expected the committed configuration to have been applied, but the sequence is %d", cm.Sequence())
+	}
+}
+
+func TestBatchSizeFromCommittedChainConfig(t *testing.T) {
+	otherChain := []byte("otherchain")
+	lf := ramledger.NewFactory(100)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	genesis, err := configtx.GenesisBlock(&ab.ConfigurationEnvelope{ChainID: otherChain})
+	if err != nil {
+		t.Fatalf("Error creating the genesis block: %s", err)
+	}
+	rl := lf.GetOrCreate(otherChain, genesis)
+	registry := configtx.NewRegistry()
+	if err := registry.Register(ab.Configuration_Orderer, sharedconfig.NewHandler(sharedconfig.Limits{})); err != nil {
+		t.Fatalf("Error registering handler: %s", err)
+	}
+	cm, err := registry.NewManager(&ab.ConfigurationEnvelope{Sequence: 0, ChainID: otherChain}, acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	resolve := func(chainID []byte) (configtx.Manager, bool) {
+		return cm, bytes.Equal(chainID, otherChain)
+	}
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.NewChainConfigRule(resolve, nil), broadcastfilter.AcceptRule})
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 3, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, Features: ab.AllFeatures, ChainConfig: resolve, Filter: filter}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	data, _ := proto.Marshal(&ab.BatchSize{Messages: 2})
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: otherChain, ID: sharedconfig.BatchSizeKey, Type: ab.Configuration_Orderer, Data: data, LastModified: 1})
+	configTx, err := configtx.MarshalConfigurationTransaction(&ab.ConfigurationEnvelope{
+		Sequence: 1,
+		ChainID:  otherChain,
+		Entries:  []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}},
+	})
+	if err != nil {
+		t.Fatalf("Error marshaling configuration: %s", err)
+	}
+
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go s.Broadcast(m)
+	for _, data := range [][]byte{[]byte("a"), []byte("b"), configTx, []byte("c"), []byte("d"), []byte("e"), []byte("f")} {
+		m.RecvChan <- &ab.BroadcastMessage{Data: data, ChainID: otherChain}
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected the message to be queued but got %v", reply)
+		}
+	}
+
+	// The chain applies its own configuration, as the default chain does
+	waitForHeight(t, rl, 5)
+	expectBlockSize(t, rl, 1, 2)
+	expectBlockSize(t, rl, 2, 1)
+	expectBlockSize(t, rl, 3, 2)
+	expectBlockSize(t, rl, 4, 2)
+	if cm.Sequence() != 1 {
+		t.Fatalf("Expected the committed configuration to have been applied, but the sequence is %d", cm.Sequence())
+	}
+	if defaultChain, _ := lf.Get(static.TestChainID); defaultChain.Height() != 1 {
+		t.Fatalf("Expected nothing to be committed on the default chain, but its height is %d", defaultChain.Height())
+	}
+
+	// A replay of the configuration is checked against the configuration of the chain
+	m.RecvChan <- &ab.BroadcastMessage{Data: configTx, ChainID: otherChain}
+	if reply := <-m.SendChan; reply.Status != ab.Status_BAD_REQUEST {
+		t.Fatalf("Expected the replayed configuration to be a BAD_REQUEST but got %v", reply)
+	}
+}
+
+func TestConfigSnapshot(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	cm, err := configtx.NewRegistry().NewManager(batchSizeEnvelope(0, 1), acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.NewConfigRule(cm, nil), broadcastfilter.AcceptRule})
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 10, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, Config: cm, Filter: filter, SnapshotInterval: 3}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	configTx, err := configtx.MarshalConfigurationTransaction(batchSizeEnvelope(1, 1))
+	if err != nil {
+		t.Fatalf("Error marshaling configuration: %s", err)
+	}
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go s.Broadcast(m)
+	for _, data := range [][]byte{configTx, []byte("a"), []byte("b"), []byte("c"), []byte("d")} {
+		m.RecvChan <- &ab.BroadcastMessage{Data: data}
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected the message to be queued but got %v", reply)
+		}
+	}
+
+	// Three blocks follow the configuration block 1 before the snapshot is committed
+	waitForHeight(t, rl, 7)
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 5)
+	block, _ := it.Next()
+	snapshot := configtx.BlockConfiguration(block)
+	if snapshot == nil {
+		t.Fatalf("Expected block 5 to be a snapshot of the configuration")
+	}
+	if !proto.Equal(snapshot, cm.Snapshot()) || snapshot.Sequence != 1 {
+		t.Fatalf("Expected the snapshot to restate configuration sequence 1, got %v", snapshot)
+	}
+	if block, _ = it.Next(); block.Header.Number != 6 || string(block.Data.Messages[0].Data) != "d" {
+		t.Fatalf("Expected the message after the snapshot in block 6")
+	}
+
+	// Recovery starts from the snapshot rather than the configuration block before it
+	if last, err := configtx.LastConfigurationNumber(rl); err != nil || last != 5 {
+		t.Fatalf("Expected the last configuration block to be the snapshot, got %d (%v)", last, err)
+	}
+	restored, err := configtx.NewRegistry().NewManager(snapshot, acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager from the snapshot: %s", err)
+	}
+	if restored.Sequence() != 1 || !proto.Equal(restored.Snapshot(), snapshot) {
+		t.Fatalf("Expected the configuration restored from the snapshot to match that applied")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	cm, err := configtx.NewRegistry().NewManager(batchSizeEnvelope(0, 3), acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 10, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, Config: cm}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	if resp, _ := s.ValidateConfig(context.Background(), batchSizeEnvelope(1, 2)); resp.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the next configuration to be valid, got %v", resp)
+	}
+
+	wrongChain := batchSizeEnvelope(1, 2)
+	wrongChain.ChainID = []byte("other")
+	unknownType := batchSizeEnvelope(1, 2)
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: static.TestChainID, ID: "custom", Type: ab.Configuration_ConfigurationType(100), LastModified: 1})
+	unknownType.Entries = append(unknownType.Entries, &ab.ConfigurationEntry{Configuration: item})
+
+	for _, invalid := range []struct {
+		name     string
+		configTx *ab.ConfigurationEnvelope
+		info     string
+	}{
+		{"replay", batchSizeEnvelope(0, 2), "was already applied"},
+		{"skipped sequence", batchSizeEnvelope(2, 2), "jumped from 0 to 2"},
+		{"wrong chain", wrongChain, "wrong chain"},
+		{"unknown type", unknownType, "unknown type"},
+	} {
+		resp, _ := s.ValidateConfig(context.Background(), invalid.configTx)
+		if resp.Status != ab.Status_BAD_REQUEST || !strings.Contains(resp.Info, invalid.info) {
+			t.Errorf("Expected the %s to be a BAD_REQUEST mentioning %q, got %v", invalid.name, invalid.info, resp)
+		}
+	}
+
+	if cm.Sequence() != 0 {
+		t.Fatalf("Validating should not have applied any configuration, but the sequence is %d", cm.Sequence())
+	}
+}
+
+func TestValidateConfigConcurrentWithApply(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	cm, err := configtx.NewRegistry().NewManager(batchSizeEnvelope(0, 3), acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 10, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, Config: cm}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.ValidateConfig(context.Background(), batchSizeEnvelope(uint64(i%10), uint32(i+1)))
+		}(i)
+	}
+	for i := uint64(1); i < 10; i++ {
+		if err := cm.Apply(batchSizeEnvelope(i, uint32(i))); err != nil {
+			t.Fatalf("Error applying configuration %d: %s", i, err)
+		}
+	}
+	wg.Wait()
+
+	if cm.Sequence() != 9 {
+		t.Fatalf("Expected only the applied configurations to take effect, but the sequence is %d", cm.Sequence())
+	}
+	if batchSize, ok := BatchSizeConfig(cm); !ok || batchSize.Messages != 9 {
+		t.Fatalf("Expected the batch size of the last applied configuration, got %v", batchSize)
+	}
+}
+
+func TestCreateChain(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.NewChainCreationRule(acceptAllPolicies{}, policies.ChainCreatorsPolicyID, static.TestChainID, lf, 0, nil), broadcastfilter.AcceptRule})
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 2, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, AckAfterCommit: true, Features: ab.AllFeatures, Filter: filter, CreateChains: true}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	newChain := []byte("newchain")
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: newChain, ID: "foo", Type: ab.Configuration_Fabric, Data: []byte("bar")})
+	genesisConfig := &ab.ConfigurationEnvelope{ChainID: newChain, Entries: []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}}}
+	createTx, err := configtx.MarshalChainCreationTransaction(genesisConfig, nil)
+	if err != nil {
+		t.Fatalf("Error marshaling chain creation transaction: %s", err)
+	}
+
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go s.Broadcast(m)
+	m.RecvChan <- &ab.BroadcastMessage{Data: createTx}
+	if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 1 {
+		t.Fatalf("Expected the chain creation transaction to be committed in block 1 but got %v", reply)
+	}
+
+	// The chain is created once its creation transaction is committed, so it may be ordered on straight away
+	for _, data := range []string{"a", "b"} {
+		m.RecvChan <- &ab.BroadcastMessage{Data: []byte(data), ChainID: newChain}
+	}
+	for range []string{"a", "b"} {
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 1 {
+			t.Fatalf("Expected the message to be committed in block 1 of the new chain but got %v", reply)
+		}
+	}
+
+	d := mocks.NewDeliverStream()
+	defer close(d.RecvChan)
+	go s.Deliver(d)
+	d.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_OLDEST, WindowSize: 2, ChainID: newChain}}}
+	genesis := (<-d.SendChan).GetBlock()
+	if genesis == nil || genesis.Header.Number != 0 || !proto.Equal(configtx.BlockConfiguration(genesis), genesisConfig) {
+		t.Fatalf("Expected the genesis block of the new chain to carry its genesis configuration, got %v", genesis)
+	}
+	if block := (<-d.SendChan).GetBlock(); block == nil || len(block.Data.Messages) != 2 || string(block.Data.Messages[0].Data) != "a" {
+		t.Fatalf("Expected block 1 of the new chain to hold the messages ordered on it, got %v", block)
+	}
+
+	// The default chain is left as it was, less the creation transaction
+	if rl, _ := lf.Get(static.TestChainID); rl.Height() != 2 {
+		t.Fatalf("Expected only the creation transaction to be committed on the system chain, but its height is %d", rl.Height())
+	}
+
+	m.RecvChan <- &ab.BroadcastMessage{Data: createTx}
+	if reply := <-m.SendChan; reply.Status != ab.Status_BAD_REQUEST || !strings.Contains(reply.Info, "already exists") {
+		t.Fatalf("Expected a second creation of the chain to be a BAD_REQUEST but got %v", reply)
+	}
+}
+
+func TestCreateChainMaxChains(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.NewChainCreationRule(acceptAllPolicies{}, policies.ChainCreatorsPolicyID, static.TestChainID, lf, 0, nil), broadcastfilter.AcceptRule})
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 2, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, AckAfterCommit: true, Features: ab.AllFeatures, Filter: filter, CreateChains: true, MaxChains: 2}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	// The filter may accept more creation transactions than the maximum allows, as it does while they are being ordered
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go s.Broadcast(m)
+	for _, chainID := range []string{"firstchain", "secondchain"} {
+		item, _ := proto.Marshal(&ab.Configuration{ChainID: []byte(chainID), ID: "foo", Type: ab.Configuration_Fabric, Data: []byte("bar")})
+		createTx, err := configtx.MarshalChainCreationTransaction(&ab.ConfigurationEnvelope{ChainID: []byte(chainID), Entries: []*ab.ConfigurationEntry{&ab.ConfigurationEntry{Configuration: item}}}, nil)
+		if err != nil {
+			t.Fatalf("Error marshaling chain creation transaction: %s", err)
+		}
+		m.RecvChan <- &ab.BroadcastMessage{Data: createTx}
+	}
+	for i := 0; i < 2; i++ {
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected the chain creation transactions to be committed but got %v", reply)
+		}
+	}
+
+	if _, ok := lf.Get([]byte("firstchain")); !ok {
+		t.Fatalf("Expected the first chain to be created")
+	}
+	if _, ok := lf.Get([]byte("secondchain")); ok {
+		t.Fatalf("Expected the second chain not to be created beyond the maximum number of chains")
+	}
+}
+
+// signedEnvelope configures the batch size of the test chain as batchSizeEnvelope does, and an extra Fabric item last modified
+// at extraModified, each item signed by signer
+func signedEnvelope(sequence uint64, messages uint32, extraModified uint64, signer string) *ab.ConfigurationEnvelope {
+	env := batchSizeEnvelope(sequence, messages)
+	item, _ := proto.Marshal(&ab.Configuration{ChainID: static.TestChainID, ID: "extra", Type: ab.Configuration_Fabric, LastModified: extraModified})
+	env.Entries = append(env.Entries, &ab.ConfigurationEntry{Configuration: item})
+	envelope, _ := proto.Marshal(&ab.PayloadEnvelope{Signer: []byte(signer)})
+	for _, entry := range env.Entries {
+		entry.Signatures = []*ab.SignedData{&ab.SignedData{PayloadEnvelope: envelope}}
+	}
+	return env
+}
+
+func TestConfigAudit(t *testing.T) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+	trail, err := audit.Open(location + "/audit/config.log")
+	if err != nil {
+		t.Fatalf("Error opening the audit trail: %s", err)
+	}
+
+	lf := ramledger.NewFactory(100)
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	cm, err := configtx.NewRegistry().NewManager(batchSizeEnvelope(0, 10), acceptAllPolicies{})
+	if err != nil {
+		t.Fatalf("Error constructing configuration manager: %s", err)
+	}
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.NewConfigRule(cm, nil), broadcastfilter.AcceptRule})
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 10, MaxWindowSize: MagicLargestWindow, BatchTimeout: 10 * time.Millisecond, Config: cm, Filter: filter, Audit: trail}, lf, static.TestChainID)
+
+	envelopes := []*ab.ConfigurationEnvelope{
+		signedEnvelope(1, 5, 1, "alice"),
+		signedEnvelope(2, 3, 1, "bob"),
+		signedEnvelope(3, 2, 3, "carol"),
+	}
+	m := mocks.NewBroadcastStream()
+	go s.Broadcast(m)
+	for i, env := range envelopes {
+		configTx, err := configtx.MarshalConfigurationTransaction(env)
+		if err != nil {
+			t.Fatalf("Error marshaling configuration: %s", err)
+		}
+		for _, data := range [][]byte{[]byte(fmt.Sprintf("%d", i)), configTx} {
+			m.RecvChan <- &ab.BroadcastMessage{Data: data}
+			if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+				t.Fatalf("Expected the message to be queued but got %v", reply)
+			}
+		}
+		// Each configuration transaction is applied before the next is validated
+		waitForHeight(t, rl, uint64(3+2*i))
+	}
+	close(m.RecvChan)
+	s.Teardown()
+	trail.Close()
+
+	data, err := ioutil.ReadFile(location + "/audit/config.log")
+	if err != nil {
+		t.Fatalf("Error reading the audit trail: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(envelopes) {
+		t.Fatalf("Expected %d audit records, got %d", len(envelopes), len(lines))
+	}
+	// The extra item is restated unchanged at sequence 2, so only the batch size is listed
+	alice, bob, carol := audit.Fingerprints([][]byte{[]byte("alice")}), audit.Fingerprints([][]byte{[]byte("bob")}), audit.Fingerprints([][]byte{[]byte("carol")})
+	expected := [][]audit.Item{
+		{{Type: "Solo", ID: BatchSizeKey, Signers: alice}, {Type: "Fabric", ID: "extra", Signers: alice}},
+		{{Type: "Solo", ID: BatchSizeKey, Signers: bob}},
+		{{Type: "Solo", ID: BatchSizeKey, Signers: carol}, {Type: "Fabric", ID: "extra", Signers: carol}},
+	}
+	for i, line := range lines {
+		record := &audit.Record{}
+		if err := json.Unmarshal([]byte(line), record); err != nil {
+			t.Fatalf("Error parsing audit record %d: %s", i, err)
+		}
+
+		// The record names the block in which the configuration transaction was committed
+		if record.BlockNumber >= rl.Height() {
+			t.Fatalf("Audit record %d names block %d, which is not on the chain", i, record.BlockNumber)
+		}
+		it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, record.BlockNumber)
+		block, _ := it.Next()
+		committed := configtx.BlockConfiguration(block)
+		if committed == nil || committed.Sequence != record.Sequence || record.Sequence != uint64(i+1) {
+			t.Fatalf("Expected block %d to carry configuration sequence %d", record.BlockNumber, record.Sequence)
+		}
+		if record.ChainID != fmt.Sprintf("%x", static.TestChainID) || record.Time.IsZero() {
+			t.Fatalf("Expected audit record %d to name the chain and time, got %+v", i, record)
+		}
+		if fmt.Sprint(record.Changed) != fmt.Sprint(expected[i]) {
+			t.Fatalf("Expected audit record %d to list changes %v, got %v", i, expected[i], record.Changed)
+		}
+	}
+}
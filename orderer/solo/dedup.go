@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+)
+
+type dedupEntry struct {
+	committed   bool
+	blockNumber uint64
+	index       uint64
+	waiters     []chan *ab.BroadcastResponse // The replies of the duplicates received while the message was pending
+}
+
+// dedupCache remembers the hashes of messages which are pending or were committed within the last window blocks
+type dedupCache struct {
+	window int
+	lock   sync.Mutex
+	seen   map[[sha256.Size]byte]*dedupEntry
+	blocks [][][sha256.Size]byte // The hashes committed in each block of the window, oldest first
+}
+
+// newDedupCache returns nil if window is zero, disabling duplicate suppression
+func newDedupCache(window int) *dedupCache {
+	if window == 0 {
+		return nil
+	}
+	return &dedupCache{
+		window: window,
+		seen:   make(map[[sha256.Size]byte]*dedupEntry),
+	}
+}
+
+// check returns the entry of a message which has already been seen, or records the message as pending and returns nil
+// If the message seen is still pending, reply is filled once it is committed or dropped, unless reply is nil
+func (dc *dedupCache) check(msg *ab.BroadcastMessage, reply chan *ab.BroadcastResponse) *dedupEntry {
+	if dc == nil {
+		return nil
+	}
+
+	hash := sha256.Sum256(msg.Data)
+
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+
+	if entry, ok := dc.seen[hash]; ok {
+		if !entry.committed && reply != nil {
+			entry.waiters = append(entry.waiters, reply)
+		}
+		return &dedupEntry{committed: entry.committed, blockNumber: entry.blockNumber, index: entry.index}
+	}
+
+	dc.seen[hash] = &dedupEntry{}
+	return nil
+}
+
+// forget removes a pending message which will not be ordered, so that a retry may be accepted, its duplicates are replied to with resp
+func (dc *dedupCache) forget(msg *ab.BroadcastMessage, resp *ab.BroadcastResponse) {
+	if dc == nil {
+		return
+	}
+
+	hash := sha256.Sum256(msg.Data)
+
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+
+	if entry, ok := dc.seen[hash]; ok && !entry.committed {
+		delete(dc.seen, hash)
+		for _, waiter := range entry.waiters {
+			// Each reply is given its own copy, as the correlation ID of its duplicate is set on it
+			reply := *resp
+			waiter <- &reply
+		}
+	}
+}
+
+// commit marks the messages of a block as committed and expires the hashes of blocks which fall outside the window
+func (dc *dedupCache) commit(block *ab.Block) {
+	if dc == nil {
+		return
+	}
+
+	hashes := make([][sha256.Size]byte, len(block.Data.Messages))
+	for i, msg := range block.Data.Messages {
+		hashes[i] = sha256.Sum256(msg.Data)
+	}
+
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+
+	for i, hash := range hashes {
+		if entry, ok := dc.seen[hash]; ok && !entry.committed {
+			for _, waiter := range entry.waiters {
+				waiter <- &ab.BroadcastResponse{Status: ab.Status_SUCCESS, BlockNumber: block.Header.Number, Index: uint64(i)}
+			}
+		}
+		dc.seen[hash] = &dedupEntry{committed: true, blockNumber: block.Header.Number, index: uint64(i)}
+	}
+	dc.blocks = append(dc.blocks, hashes)
+
+	for len(dc.blocks) > dc.window {
+		for _, hash := range dc.blocks[0] {
+			// The message may have been ordered again in a later block after an earlier expiry
+			if entry, ok := dc.seen[hash]; ok && entry.committed && entry.blockNumber <= block.Header.Number-uint64(dc.window) {
+				delete(dc.seen, hash)
+			}
+		}
+		dc.blocks = dc.blocks[1:]
+	}
+}
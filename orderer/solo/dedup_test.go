@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+)
+
+func broadcastAndWait(t *testing.T, m *mocks.BroadcastStream, data string) *ab.BroadcastResponse {
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte(data)}
+	select {
+	case reply := <-m.SendChan:
+		if reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected message %s to be accepted but got %v", data, reply.Status)
+		}
+		return reply
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for reply to message %s", data)
+	}
+	return nil
+}
+
+func TestDuplicateWithinWindow(t *testing.T) {
+	bs := newBroadcastServer(2, 1, 0, time.Hour, true, 2, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
+	defer bs.Halt()
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	original := broadcastAndWait(t, m, "Some bytes")
+	duplicate := broadcastAndWait(t, m, "Some bytes")
+
+	if duplicate.BlockNumber != original.BlockNumber {
+		t.Fatalf("Expected duplicate to report original block %d but got %d", original.BlockNumber, duplicate.BlockNumber)
+	}
+
+	if bs.rl.(rawledger.Reader).Height() != 2 {
+		t.Fatalf("Expected the duplicate not to be ordered again")
+	}
+}
+
+func TestDuplicateAfterWindow(t *testing.T) {
+	bs := newBroadcastServer(2, 1, 0, time.Hour, true, 1, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
+	defer bs.Halt()
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	original := broadcastAndWait(t, m, "Some bytes")
+	broadcastAndWait(t, m, "Other bytes") // Pushes the original out of the window
+	reordered := broadcastAndWait(t, m, "Some bytes")
+
+	if reordered.BlockNumber == original.BlockNumber {
+		t.Fatalf("Expected message to be ordered again after the window expired")
+	}
+
+	if bs.rl.(rawledger.Reader).Height() != 4 {
+		t.Fatalf("Expected 4 blocks but got %d", bs.rl.(rawledger.Reader).Height())
+	}
+}
+
+func TestDistinctMessagesSharedPrefix(t *testing.T) {
+	bs := newBroadcastServer(2, 1, 0, time.Hour, true, 10, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
+	defer bs.Halt()
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	first := broadcastAndWait(t, m, "Some bytes")
+	second := broadcastAndWait(t, m, "Some bytes and more")
+
+	if first.BlockNumber == second.BlockNumber {
+		t.Fatalf("Expected distinct messages to both be ordered")
+	}
+}
+
+func TestDedupCacheBounded(t *testing.T) {
+	window := 3
+	dc := newDedupCache(window)
+	for i := uint64(1); i < 100; i++ {
+		msg := &ab.BroadcastMessage{Data: []byte{byte(i)}}
+		if dc.check(msg, nil) != nil {
+			t.Fatalf("Message %d should not have been seen before", i)
+		}
+		dc.commit(ab.NewBlock(i, nil, []*ab.BroadcastMessage{msg}, nil))
+	}
+
+	if len(dc.seen) != window || len(dc.blocks) != window {
+		t.Fatalf("Expected only %d blocks of hashes to be retained, but have %d hashes and %d blocks", window, len(dc.seen), len(dc.blocks))
+	}
+}
+
+func TestDuplicateOfPendingMessage(t *testing.T) {
+	for _, ackAfterCommit := range []bool{true, false} {
+		bs := newBroadcastServer(2, 2, 0, time.Hour, ackAfterCommit, 2, nil, ramledger.New(10, genesisBlock), nil, nil, nil)
+		first := mocks.NewBroadcastStream()
+		second := mocks.NewBroadcastStream()
+		go bs.handleBroadcast(first)
+		go bs.handleBroadcast(second)
+
+		first.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+		if !ackAfterCommit {
+			if reply := <-first.SendChan; reply.Status != ab.Status_SUCCESS {
+				t.Fatalf("Expected the original to be accepted but got %v", reply.Status)
+			}
+		}
+
+		second.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes")}
+		select {
+		case reply := <-second.SendChan:
+			t.Fatalf("Expected no reply to the duplicate before the original was committed, got %v for block %d", reply.Status, reply.BlockNumber)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		first.RecvChan <- &ab.BroadcastMessage{Data: []byte("Other bytes")} // Completes the batch
+		select {
+		case reply := <-second.SendChan:
+			if reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 1 || reply.Index != 0 {
+				t.Fatalf("Expected the duplicate to report the original at block 1 index 0 but got %v at block %d index %d", reply.Status, reply.BlockNumber, reply.Index)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for the reply to the duplicate")
+		}
+
+		if bs.rl.(rawledger.Reader).Height() != 2 {
+			t.Fatalf("Expected the duplicate not to be ordered again")
+		}
+		close(first.RecvChan)
+		close(second.RecvChan)
+		bs.Halt()
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+)
+
+// goldenHashes are the hashes of the blocks cut by runGoldenScenario, recorded so that changes to the batching loop
+// which alter the blocks produced for the same inputs are caught
+var goldenHashes = []string{
+	"2fbc5a8fe0b3cbaa467b18398a339c05c8008ba56a73dba0bb380df73caff8e979ff00288cf98e8affd4407d8bbd0e4ed871745884ab2c2f20a2473584857e99",
+	"bed45a16596ef62646d9b9541f538410252df5db5929e0fd85cd7f01361ca7fb8870f24b1e80d9d5cacd2b942901f24f4af82fa8ceb0b4b033ceb4f0b4a3cc32",
+	"eda05bc089c9f887da7f6ccfc54d43949b3a5ec228708bfb8e12f5bab77468f6d7104f23b81f746057c63e180d34dddc7601e1a0f26c4f49b6f1bb9fbe616704",
+	"dee4760d9f6b617fc758175ab43e24a88565e621794074ae1add8da2f187ec427499f8009c1cfb6d0a4935f1a15793c39c580c544dbad6b264dce6ba95175fc4",
+	"c543cadd84d228a136044bb150d9b5ccb5452e5aaae7f49aebfc26d7229464022201f3696e0e11742863896a1e871e35262ff84d2d469dfeaedd7f21509a6649",
+}
+
+// waitForBatch waits until the queues are drained into a pending batch whose timer is armed
+func waitForBatch(t *testing.T, bs *broadcastServer, clock *fakeClock) {
+	deadline := time.After(time.Second)
+	for bs.queues.depth() != 0 || clock.activeTimers() != 1 {
+		select {
+		case <-deadline:
+			t.Fatalf("Expected the queued messages to be batched")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// runGoldenScenario cuts blocks by size, timeout, pause, and shutdown, from messages interleaved across streams
+// The messages are recorded in a pending log if logDir is set, so that the blocks are hashed from their marshaled form
+func runGoldenScenario(t *testing.T, logDir string) []string {
+	clock := newFakeClock()
+	rl := ramledger.New(10, genesisBlock)
+	var plog *pendingLog
+	if logDir != "" {
+		plog = openPendingLog(logDir+"/pending.log", rl)
+	}
+	bs := newPlainBroadcastServer(10, 4, 0, time.Second, false, 0, nil, rl, plog, nil, clock)
+	bq1 := bs.queues.newQueue(10)
+	bq2 := bs.queues.newQueue(10)
+
+	for i := 0; i < 6; i++ {
+		bq1.enqueue(bs.newPendingMessage(&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("a%d", i))}))
+	}
+	for i := 0; i < 4; i++ {
+		bq2.enqueue(bs.newPendingMessage(&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("b%d", i))}))
+	}
+	// Rejected by the filter when batched, so it must not occupy a slot in any block
+	bq2.enqueue(bs.newPendingMessage(&ab.BroadcastMessage{}))
+
+	go bs.main()
+	waitForHeight(t, rl, 3)
+	waitForBatch(t, bs, clock)
+	clock.advance(time.Second)
+	waitForHeight(t, rl, 4)
+
+	for i := 0; i < 3; i++ {
+		bq1.enqueue(bs.newPendingMessage(&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("c%d", i))}))
+	}
+	waitForBatch(t, bs, clock)
+	bs.pause(time.Second)
+	bs.resume()
+	waitForHeight(t, rl, 5)
+
+	for i := 0; i < 2; i++ {
+		bq2.enqueue(bs.newPendingMessage(&ab.BroadcastMessage{Data: []byte(fmt.Sprintf("d%d", i))}))
+	}
+	waitForBatch(t, bs, clock)
+	bs.shutdown()
+
+	if rl.Height() != 6 {
+		t.Fatalf("Expected 5 blocks to be cut but the height is %d", rl.Height())
+	}
+	var hashes []string
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	for number := uint64(1); number < rl.Height(); number++ {
+		block, _ := it.Next()
+		hashes = append(hashes, hex.EncodeToString(block.Hash()))
+	}
+	return hashes
+}
+
+func TestGoldenBlocks(t *testing.T) {
+	expectGoldenHashes(t, runGoldenScenario(t, ""))
+}
+
+func TestGoldenBlocksEncoded(t *testing.T) {
+	logDir, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(logDir)
+	expectGoldenHashes(t, runGoldenScenario(t, logDir))
+}
+
+func expectGoldenHashes(t *testing.T, hashes []string) {
+	if len(hashes) != len(goldenHashes) {
+		t.Fatalf("Expected %d blocks but got %d", len(goldenHashes), len(hashes))
+	}
+	for i, hash := range hashes {
+		if hash != goldenHashes[i] {
+			t.Errorf("Block %d differs from the recorded block, expected hash %s but got %s", i+1, goldenHashes[i], hash)
+		}
+	}
+}
+
+// orderThroughStream broadcasts the messages on a single stream and returns the hashes of the blocks cut
+func orderThroughStream(t *testing.T, msgs []*ab.BroadcastMessage, handle func(bs *broadcastServer, m *mocks.BroadcastStream)) []string {
+	rl := ramledger.New(10, genesisBlock)
+	bs := newBroadcastServer(10, 4, 0, time.Hour, false, 0, nil, rl, nil, nil, nil)
+	m := mocks.NewBroadcastStream()
+	go handle(bs, m)
+
+	for _, msg := range msgs {
+		m.RecvChan <- msg
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected the message to be accepted but got %v", reply.Status)
+		}
+	}
+	close(m.RecvChan)
+	for bs.queues.depth() != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	bs.shutdown()
+
+	var hashes []string
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	for number := uint64(1); number < rl.Height(); number++ {
+		block, _ := it.Next()
+		hashes = append(hashes, hex.EncodeToString(block.Hash()))
+	}
+	return hashes
+}
+
+func TestSharedHandlerBlocks(t *testing.T) {
+	var msgs []*ab.BroadcastMessage
+	for i := 0; i < 10; i++ {
+		msgs = append(msgs, &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))})
+	}
+
+	expected := orderThroughStream(t, msgs, func(bs *broadcastServer, m *mocks.BroadcastStream) { bs.handleBroadcast(m) })
+	filter := broadcastfilter.NewRuleSet([]broadcastfilter.Rule{broadcastfilter.EmptyRejectRule, broadcastfilter.AcceptRule})
+	hashes := orderThroughStream(t, msgs, func(bs *broadcastServer, m *mocks.BroadcastStream) {
+		broadcast.Handle(m, filter, singleChain{bs}, ab.AllFeatures)
+	})
+
+	if len(expected) != 3 || len(hashes) != len(expected) {
+		t.Fatalf("Expected 3 blocks from each handler but got %d and %d", len(expected), len(hashes))
+	}
+	for i := range expected {
+		if hashes[i] != expected[i] {
+			t.Fatalf("Expected block %d to be the same through the shared handler", i+1)
+		}
+	}
+}
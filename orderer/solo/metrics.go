@@ -0,0 +1,79 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// cutReason records why a batch was cut into a block
+type cutReason int
+
+const (
+	cutSize     cutReason = iota // The batch reached the batch size
+	cutTimeout                   // The batch timer expired
+	cutShutdown                  // The orderer is shutting down
+	cutPause                     // Ordering is being paused
+)
+
+const histogramReservoirSize = 1028
+
+// batchMetrics are the metrics of the batching loop of a single chain
+type batchMetrics struct {
+	blockMessages gometrics.Histogram
+	blockBytes    gometrics.Histogram
+	cuts          map[cutReason]gometrics.Counter
+	commitLatency gometrics.Timer
+}
+
+// newBatchMetrics registers the metrics of a batching loop in registry, which may be nil if they are not to be exported
+// The queue depth is sampled from qs when the gauge is read
+func newBatchMetrics(registry gometrics.Registry, qs *queueScheduler) *batchMetrics {
+	if registry == nil {
+		registry = gometrics.NewRegistry()
+	}
+
+	gometrics.NewRegisteredFunctionalGauge("queue_depth", registry, qs.depth)
+	return &batchMetrics{
+		blockMessages: gometrics.NewRegisteredHistogram("block_messages", registry, gometrics.NewUniformSample(histogramReservoirSize)),
+		blockBytes:    gometrics.NewRegisteredHistogram("block_bytes", registry, gometrics.NewUniformSample(histogramReservoirSize)),
+		cuts: map[cutReason]gometrics.Counter{
+			cutSize:     gometrics.NewRegisteredCounter("cuts.size", registry),
+			cutTimeout:  gometrics.NewRegisteredCounter("cuts.timeout", registry),
+			cutShutdown: gometrics.NewRegisteredCounter("cuts.shutdown", registry),
+			cutPause:    gometrics.NewRegisteredCounter("cuts.pause", registry),
+		},
+		commitLatency: gometrics.NewRegisteredTimer("commit_latency", registry),
+	}
+}
+
+// blockCommitted records the cut of a batch at now, and the time each of its messages waited since being received
+func (bm *batchMetrics) blockCommitted(batch []*pendingMessage, reason cutReason, now time.Time) {
+	bm.cuts[reason].Inc(1)
+	bm.blockMessages.Update(int64(len(batch)))
+
+	size := 0
+	for _, pending := range batch {
+		size += len(pending.msg.Data)
+		if !pending.received.IsZero() {
+			bm.commitLatency.Update(now.Sub(pending.received))
+		}
+	}
+	bm.blockBytes.Update(int64(size))
+}
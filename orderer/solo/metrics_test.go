@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+func expectCount(t *testing.T, registry gometrics.Registry, name string, expected int64) {
+	var count int64
+	switch metric := registry.Get(name).(type) {
+	case gometrics.Counter:
+		count = metric.Count()
+	case gometrics.Histogram:
+		count = metric.Count()
+	case gometrics.Timer:
+		count = metric.Count()
+	default:
+		t.Fatalf("Metric %s was not registered", name)
+	}
+	if count != expected {
+		t.Fatalf("Expected %s to be %d but got %d", name, expected, count)
+	}
+}
+
+func TestCutReasonMetrics(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	batchSize := 2
+	rl := ramledger.New(10, genesisBlock)
+	bs := newBroadcastServer(10, batchSize, 0, time.Hour, false, 0, nil, rl, nil, registry, nil)
+	bq := bs.queues.newQueue(10)
+
+	// Two full batches, and a partial batch which is cut by the shutdown
+	for i := 0; i < 2*batchSize+1; i++ {
+		bq.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("1234")}, received: time.Now()})
+	}
+	waitForHeight(t, rl, 3)
+	time.Sleep(100 * time.Millisecond)
+	bs.shutdown()
+
+	expectCount(t, registry, "cuts.size", 2)
+	expectCount(t, registry, "cuts.timeout", 0)
+	expectCount(t, registry, "cuts.shutdown", 1)
+	expectCount(t, registry, "block_messages", 3)
+	expectCount(t, registry, "commit_latency", int64(2*batchSize+1))
+
+	messages := registry.Get("block_messages").(gometrics.Histogram)
+	if messages.Sum() != int64(2*batchSize+1) || messages.Max() != int64(batchSize) || messages.Min() != 1 {
+		t.Fatalf("Expected blocks of %d, %d, and 1 messages, but got sum %d, max %d, min %d", batchSize, batchSize, messages.Sum(), messages.Max(), messages.Min())
+	}
+	if bytes := registry.Get("block_bytes").(gometrics.Histogram); bytes.Sum() != int64(4*(2*batchSize+1)) {
+		t.Fatalf("Expected %d bytes to be committed but got %d", 4*(2*batchSize+1), bytes.Sum())
+	}
+}
+
+func TestTimeoutCutMetrics(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	rl := ramledger.New(10, genesisBlock)
+	clock := newFakeClock()
+	bs := newBroadcastServer(10, 10, 0, time.Second, false, 0, nil, rl, nil, registry, clock)
+	defer bs.Halt()
+
+	bs.queues.newQueue(10).enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("1234")}})
+	clock.waitForTimer(t)
+	clock.advance(time.Second)
+	waitForHeight(t, rl, 2)
+
+	expectCount(t, registry, "cuts.timeout", 1)
+	expectCount(t, registry, "cuts.size", 0)
+	expectCount(t, registry, "block_messages", 1)
+}
+
+func TestQueueDepthMetric(t *testing.T) {
+	registry := gometrics.NewRegistry()
+	bs := newPlainBroadcastServer(10, 1, 0, time.Hour, false, 0, nil, nil, nil, registry, nil)
+	first, second := bs.queues.newQueue(10), bs.queues.newQueue(10)
+	first.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("1234")}})
+	first.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("1234")}})
+	second.enqueue(&pendingMessage{msg: &ab.BroadcastMessage{Data: []byte("1234")}})
+
+	if depth := registry.Get("queue_depth").(gometrics.Gauge).Value(); depth != 3 {
+		t.Fatalf("Expected a queue depth of 3 but got %d", depth)
+	}
+}
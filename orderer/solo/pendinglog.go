@@ -0,0 +1,303 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The types of record in the pending log, each record is a 4 byte length, followed by the type and its payload
+const (
+	recordEnqueued  byte = iota // The sequence number followed by the marshaled message
+	recordDropped               // The sequence number of a message which will not be ordered
+	recordCommitted             // The block number followed by the sequence numbers of the messages it contains
+)
+
+// pendingLog is an append-only file recording the messages which have been accepted but not yet committed, so that they may be replayed after a crash
+type pendingLog struct {
+	path        string
+	lock        sync.Mutex // Guards file, nextSeq, and outstanding
+	file        *os.File
+	nextSeq     uint64
+	outstanding map[uint64]struct{}
+	recovered   []*pendingMessage // Messages from a previous process which must be ordered ahead of new traffic
+}
+
+type logEntry struct {
+	seq     uint64
+	msg     *ab.BroadcastMessage
+	encoded []byte // The message as marshaled in the log
+}
+
+// openPendingLog recovers the messages which were recorded but not committed to rl by a previous process, and opens the log for appending
+func openPendingLog(path string, rl rawledger.Reader) *pendingLog {
+	logger.Debugf("Initializing pending log at '%s'", path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		panic(err)
+	}
+
+	entries, nextSeq := recoverPendingLog(path, rl)
+
+	pl := &pendingLog{
+		path:        path,
+		nextSeq:     nextSeq,
+		outstanding: make(map[uint64]struct{}),
+	}
+
+	// The log is rewritten to contain only the recovered entries, so that it does not grow across restarts
+	var buf bytes.Buffer
+	writeRecord(&buf, recordCommitted, committedPayload(rl.Height()-1, nil))
+	for _, entry := range entries {
+		writeRecord(&buf, recordEnqueued, enqueuedPayload(entry.seq, entry.encoded))
+		pl.outstanding[entry.seq] = struct{}{}
+		pl.recovered = append(pl.recovered, &pendingMessage{msg: entry.msg, seq: entry.seq, encoded: entry.encoded})
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(path), "tmp_pending_")
+	if err != nil {
+		panic(err)
+	}
+	if _, err = file.Write(buf.Bytes()); err != nil {
+		panic(err)
+	}
+	if err = file.Sync(); err != nil {
+		panic(err)
+	}
+	if err = os.Rename(file.Name(), path); err != nil {
+		panic(err)
+	}
+	pl.file = file
+
+	if len(entries) > 0 {
+		logger.Infof("Recovered %d messages which were accepted but not yet ordered", len(entries))
+	}
+	return pl
+}
+
+// recoverPendingLog returns the entries of the log which were not committed to rl, in the order they were recorded, and the next sequence number
+func recoverPendingLog(path string, rl rawledger.Reader) ([]*logEntry, uint64) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, 0
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	pending := make(map[uint64]*logEntry)
+	nextSeq := uint64(0)
+	var lastCommitted uint64
+	haveCommitted := false
+
+	for {
+		recordType, payload, rest, ok := readRecord(data)
+		if !ok {
+			// A partial record at the tail was being written when the process stopped, the message was never acknowledged
+			break
+		}
+		data = rest
+
+		switch recordType {
+		case recordEnqueued:
+			seq := binary.BigEndian.Uint64(payload)
+			msg := &ab.BroadcastMessage{}
+			if err := proto.Unmarshal(payload[8:], msg); err != nil {
+				panic(err)
+			}
+			pending[seq] = &logEntry{seq: seq, msg: msg, encoded: payload[8:]}
+			if seq >= nextSeq {
+				nextSeq = seq + 1
+			}
+		case recordDropped:
+			delete(pending, binary.BigEndian.Uint64(payload))
+		case recordCommitted:
+			lastCommitted = binary.BigEndian.Uint64(payload)
+			haveCommitted = true
+			for i := 8; i+8 <= len(payload); i += 8 {
+				delete(pending, binary.BigEndian.Uint64(payload[i:]))
+			}
+		}
+	}
+
+	entries := make([]*logEntry, 0, len(pending))
+	for _, entry := range pending {
+		entries = append(entries, entry)
+	}
+	sort.Sort(bySeq(entries))
+
+	if !haveCommitted || len(entries) == 0 {
+		return entries, nextSeq
+	}
+
+	// Blocks may have been appended after the last commit was recorded, their messages must not be ordered a second time
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, lastCommitted+1)
+	for number := lastCommitted + 1; number < rl.Height(); number++ {
+		block, status := it.Next()
+		if status != ab.Status_SUCCESS {
+			break
+		}
+		for _, msg := range block.Data.Messages {
+			for i, entry := range entries {
+				if proto.Equal(entry.msg, msg) {
+					entries = append(entries[:i], entries[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	return entries, nextSeq
+}
+
+type bySeq []*logEntry
+
+func (s bySeq) Len() int           { return len(s) }
+func (s bySeq) Less(i, j int) bool { return s[i].seq < s[j].seq }
+func (s bySeq) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func readRecord(data []byte) (byte, []byte, []byte, bool) {
+	if len(data) < 4 {
+		return 0, nil, nil, false
+	}
+	length := binary.BigEndian.Uint32(data)
+	if length == 0 || uint64(len(data)-4) < uint64(length) {
+		return 0, nil, nil, false
+	}
+	record := data[4 : 4+length]
+	return record[0], record[1:], data[4+length:], true
+}
+
+func writeRecord(w io.Writer, recordType byte, payload []byte) {
+	record := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(record, uint32(1+len(payload)))
+	record[4] = recordType
+	copy(record[5:], payload)
+	if _, err := w.Write(record); err != nil {
+		panic(err)
+	}
+}
+
+func enqueuedPayload(seq uint64, encoded []byte) []byte {
+	payload := make([]byte, 8, 8+len(encoded))
+	binary.BigEndian.PutUint64(payload, seq)
+	return append(payload, encoded...)
+}
+
+func committedPayload(blockNumber uint64, seqs []uint64) []byte {
+	payload := make([]byte, 8*(1+len(seqs)))
+	binary.BigEndian.PutUint64(payload, blockNumber)
+	for i, seq := range seqs {
+		binary.BigEndian.PutUint64(payload[8*(i+1):], seq)
+	}
+	return payload
+}
+
+// append writes a record and syncs it to disk, the caller must hold the lock
+func (pl *pendingLog) append(recordType byte, payload []byte) {
+	writeRecord(pl.file, recordType, payload)
+	if err := pl.file.Sync(); err != nil {
+		panic(err)
+	}
+}
+
+// record durably logs a message before it is queued, returning its sequence number and the message as marshaled in the log
+func (pl *pendingLog) record(msg *ab.BroadcastMessage) (uint64, []byte) {
+	if pl == nil {
+		return 0, nil
+	}
+
+	// Marshaled outside the lock, the bytes are kept so that the block hash need not marshal the message again
+	encoded, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	seq := pl.nextSeq
+	pl.nextSeq++
+	pl.append(recordEnqueued, enqueuedPayload(seq, encoded))
+	pl.outstanding[seq] = struct{}{}
+	return seq, encoded
+}
+
+// drop logs that a recorded message will not be ordered
+func (pl *pendingLog) drop(seq uint64) {
+	if pl == nil {
+		return
+	}
+
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, seq)
+	pl.append(recordDropped, buf)
+	delete(pl.outstanding, seq)
+}
+
+// commit logs that the messages with the given sequence numbers were appended to the ledger in a block
+// Once nothing is outstanding the log is truncated, leaving only the record of the block
+func (pl *pendingLog) commit(blockNumber uint64, seqs []uint64) {
+	if pl == nil {
+		return
+	}
+
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	for _, seq := range seqs {
+		delete(pl.outstanding, seq)
+	}
+
+	if len(pl.outstanding) > 0 {
+		pl.append(recordCommitted, committedPayload(blockNumber, seqs))
+		return
+	}
+
+	if err := pl.file.Truncate(0); err != nil {
+		panic(err)
+	}
+	if _, err := pl.file.Seek(0, io.SeekStart); err != nil {
+		panic(err)
+	}
+	pl.append(recordCommitted, committedPayload(blockNumber, nil))
+}
+
+// close releases the file, no further records may be written
+func (pl *pendingLog) close() {
+	if pl == nil {
+		return
+	}
+
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	pl.file.Close()
+}
@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
+	"github.com/hyperledger/fabric/orderer/rawledger/fileledger"
+)
+
+func TestPendingLogReplayAfterCrash(t *testing.T) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+	ledgerDir := location + "/ledger"
+	logPath := location + "/pending.log"
+
+	messages := 3
+	rl := fileledger.New(ledgerDir, genesisBlock)
+	plog := openPendingLog(logPath, rl)
+	bs := newBroadcastServer(10, messages+1, 0, time.Hour, false, 0, nil, rl, plog, nil, nil)
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go bs.handleBroadcast(m)
+
+	for i := 0; i < messages; i++ {
+		m.RecvChan <- &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%d", i))}
+		if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Should have successfully queued the message")
+		}
+	}
+
+	// Stop without committing the pending batch, as a crash would
+	time.Sleep(100 * time.Millisecond)
+	bs.Halt()
+	plog.close()
+	if rl.Height() != 1 {
+		t.Fatalf("Expected no new blocks before the restart")
+	}
+
+	rl = fileledger.New(ledgerDir, genesisBlock)
+	plog = openPendingLog(logPath, rl)
+	bs = newBroadcastServer(10, messages+1, 0, 10*time.Millisecond, false, 0, nil, rl, plog, nil, nil)
+	waitForHeight(t, rl, 2)
+	bs.shutdown()
+
+	it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+	block, _ := it.Next()
+	if len(block.Data.Messages) != messages {
+		t.Fatalf("Expected %d replayed messages but got %d", messages, len(block.Data.Messages))
+	}
+	for i, msg := range block.Data.Messages {
+		if string(msg.Data) != fmt.Sprintf("%d", i) {
+			t.Fatalf("Expected replayed messages in the order they were accepted")
+		}
+	}
+
+	plog = openPendingLog(logPath, fileledger.New(ledgerDir, genesisBlock))
+	defer plog.close()
+	if len(plog.recovered) != 0 {
+		t.Fatalf("Messages should be replayed only once, but %d were recovered again", len(plog.recovered))
+	}
+}
+
+func TestPendingLogCrashBeforeCommitRecorded(t *testing.T) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+	ledgerDir := location + "/ledger"
+	logPath := location + "/pending.log"
+
+	rl := fileledger.New(ledgerDir, genesisBlock)
+	plog := openPendingLog(logPath, rl)
+	committed := &ab.BroadcastMessage{Data: []byte("committed")}
+	uncommitted := &ab.BroadcastMessage{Data: []byte("uncommitted")}
+	plog.record(committed)
+	plog.record(uncommitted)
+
+	// The block is appended, but the process stops before the commit is recorded, and while writing another record
+	rl.Append([]*ab.BroadcastMessage{committed}, nil)
+	plog.file.Write([]byte{0, 0, 0, 9, recordEnqueued})
+	plog.close()
+
+	plog = openPendingLog(logPath, fileledger.New(ledgerDir, genesisBlock))
+	defer plog.close()
+	if len(plog.recovered) != 1 || string(plog.recovered[0].msg.Data) != "uncommitted" {
+		t.Fatalf("Expected only the uncommitted message to be recovered, but got %v", plog.recovered)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"sync"
+)
+
+// broadcastQueue holds the messages of one stream which are awaiting ordering on one chain
+type broadcastQueue struct {
+	qs    *queueScheduler
+	queue chan *pendingMessage
+}
+
+// enqueue adds a message to the queue without blocking, returning false if the queue is full
+func (bq *broadcastQueue) enqueue(pending *pendingMessage) bool {
+	select {
+	case bq.queue <- pending:
+		bq.qs.notify()
+		return true
+	default:
+		return false
+	}
+}
+
+// close indicates no more messages will be enqueued, those already queued are still ordered
+func (bq *broadcastQueue) close() {
+	close(bq.queue)
+	bq.qs.notify()
+}
+
+// queueScheduler takes messages from the queues of all streams in turn, so that no stream may starve another
+type queueScheduler struct {
+	lock      sync.Mutex // Guards queues and position
+	queues    []*broadcastQueue
+	position  int
+	readyChan chan struct{} // Signaled when a queue may have become non-empty or closed
+}
+
+func newQueueScheduler() *queueScheduler {
+	return &queueScheduler{
+		readyChan: make(chan struct{}, 1),
+	}
+}
+
+// newQueue registers a queue of the given capacity for a new stream
+func (qs *queueScheduler) newQueue(size int) *broadcastQueue {
+	bq := &broadcastQueue{
+		qs:    qs,
+		queue: make(chan *pendingMessage, size),
+	}
+
+	qs.lock.Lock()
+	defer qs.lock.Unlock()
+	qs.queues = append(qs.queues, bq)
+	return bq
+}
+
+// depth returns the number of messages in all queues
+func (qs *queueScheduler) depth() int64 {
+	qs.lock.Lock()
+	defer qs.lock.Unlock()
+
+	depth := 0
+	for _, bq := range qs.queues {
+		depth += len(bq.queue)
+	}
+	return int64(depth)
+}
+
+func (qs *queueScheduler) notify() {
+	select {
+	case qs.readyChan <- struct{}{}:
+	default:
+	}
+}
+
+// next returns a message from the queue following the one which last supplied a message, or nil if all queues are empty
+// Closed queues are unregistered once they have been drained
+func (qs *queueScheduler) next() *pendingMessage {
+	qs.lock.Lock()
+	defer qs.lock.Unlock()
+
+	for checked := 0; checked < len(qs.queues); {
+		if qs.position >= len(qs.queues) {
+			qs.position = 0
+		}
+
+		select {
+		case pending, ok := <-qs.queues[qs.position].queue:
+			if !ok {
+				qs.queues = append(qs.queues[:qs.position], qs.queues[qs.position+1:]...)
+				continue
+			}
+			qs.position++
+			return pending
+		default:
+			qs.position++
+			checked++
+		}
+	}
+
+	return nil
+}
@@ -17,13 +17,28 @@ limitations under the License.
 package solo
 
 import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"time"
 
 	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/blocksigner"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/broadcastfilter"
+	"github.com/hyperledger/fabric/orderer/common/clock"
+	"github.com/hyperledger/fabric/orderer/common/configtx"
+	"github.com/hyperledger/fabric/orderer/common/consenter"
+	"github.com/hyperledger/fabric/orderer/common/deliver"
+	"github.com/hyperledger/fabric/orderer/common/policies"
+	"github.com/hyperledger/fabric/orderer/common/sharedconfig"
 	"github.com/hyperledger/fabric/orderer/rawledger"
 
 	"github.com/op/go-logging"
-	"google.golang.org/grpc"
+	gometrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/net/context"
 )
 
 var logger = logging.MustGetLogger("orderer/solo")
@@ -32,29 +47,375 @@ func init() {
 	logging.SetLevel(logging.DEBUG, "")
 }
 
-type server struct {
+// Orderer allows the caller to submit to and receive messages from the solo orderer
+type Orderer interface {
+	ab.AtomicBroadcastServer
+	ab.AdminServer
+	consenter.Consenter
+	Teardown() error
+}
+
+// chain holds the batching pipeline and ledger of a single chain
+type chain struct {
 	bs *broadcastServer
-	ds *deliverServer
+	rl rawledger.ReadWriter
+}
+
+// Options configures the solo orderer, zero values of the optional fields disable the feature they control
+type Options struct {
+	QueueSize         int           // The number of messages each Broadcast stream may have queued
+	BatchSize         int           // The number of messages which cut a block
+	BatchMaxBytes     int           // The total size of message data beyond which a block is cut, zero for no limit
+	BatchTimeout      time.Duration // How long after the first message of a batch the block is cut regardless of size
+	MaxWindowSize     int           // The largest window a Deliver client may request
+	DefaultWindowSize int           // The window of Deliver seeks which give none, not enforced, zero to refuse such seeks
+	AckAfterCommit    bool          // Whether Broadcast replies wait for the block containing the message to be committed
+	DedupWindow       int           // The number of recent blocks whose messages are remembered to suppress duplicates
+	MaxIdleTime       time.Duration // How long a Deliver client may leave its window exhausted before it is evicted
+	MaxLag            int           // How many blocks further behind the tail a Deliver client may fall before it is evicted
+	HeartbeatInterval time.Duration // How long a Deliver stream may be idle before a heartbeat is sent
+	CursorKey         []byte        // The key with which the cursors sent to Deliver clients are authenticated, if empty they are not
+	MaxSendMsgSize    int           // The largest Deliver response sent, zero for no limit
+	Features          ab.Features   // The protocol features streams may negotiate, a stream without a hello uses none
+	SeekSkew          time.Duration // How far the Timestamp of a signed Deliver seek may be from the clock of the orderer, the default of deliver if zero
+
+	MaxDeliverStreams          int           // The number of Deliver streams which may be open at once, zero for no limit
+	MaxDeliverStreamsPerClient int           // The number of Deliver streams each client may have open at once, zero for no limit
+	DeliverRetryAfter          time.Duration // How long clients whose Deliver stream is not admitted are asked to wait before retrying
+	PendingLogDir              string        // Where accepted messages are recorded until committed, so that they survive a crash
+	Paused                     bool          // Whether ordering is paused until resumed through the Admin service
+	RetryAfter                 time.Duration // How long Broadcast clients are asked to wait before retrying while paused, unless the pause request specifies otherwise
+	IdleTimeout                time.Duration // How long a Broadcast stream may go without sending a message, once its replies are sent, before it is closed
+	SnapshotInterval           int           // How many blocks of the default chain may follow its last configuration block before a snapshot of Config is committed, zero for none
+	CreateChains               bool          // Whether the chain creation transactions committed on the default chain create chains, they must be authorized by Filter
+	MaxChains                  int           // The most chains, the default chain among them, which may exist before creation transactions are ignored, zero for no limit
+
+	// Filter checks incoming messages before the duplicate check and queueing, if nil only empty messages are rejected
+	Filter *broadcastfilter.RuleSet
+	// Registry receives the metrics of each chain, prefixed by the hex encoded chain ID, if nil metrics are not exported
+	Registry gometrics.Registry
+	// Clock drives the batch timer, if nil the real clock is used
+	Clock clock.Clock
+	// Signer signs the blocks committed on every chain, if nil they are not signed
+	Signer *blocksigner.Signer
+	// Config is the configuration of the default chain, if set a Solo BatchSize item overrides BatchSize and BatchMaxBytes for that chain,
+	// and the Orderer batch items override both it and BatchTimeout, the configuration transactions committed on the chain are applied
+	// to it, and changes take effect from the next block
+	Config configtx.Manager
+	// ChainConfig resolves the configuration of each chain other than the default, which is applied as that of the default chain is,
+	// if nil or a chain has none its blocks are cut as the options give and its configuration transactions are not applied
+	ChainConfig configtx.ManagerResolver
+	// Audit records each configuration transaction applied to the chains, if nil the records are only logged
+	Audit *audit.Trail
+	// Policies resolves the Readers policy which the signatures of Deliver seeks must satisfy, if nil seeks are not checked,
+	// the Writers policy of broadcast messages is checked by Filter
+	Policies policies.ManagerResolver
+	// Decisions records each decision of the Readers policies, if nil every denial is logged
+	Decisions *audit.DecisionLog
+}
+
+type server struct {
+	opts           Options
+	lf             rawledger.Factory
+	defaultChainID []byte
+	ds             *deliver.Server
+	idleClosed     gometrics.Counter
+	lock           sync.Mutex // Guards chains, paused, retryAfter, and stopped, and is held while waiting on the batching loops
+	chains         map[string]*chain
+	batchLock      sync.Mutex                   // Guards batch and defaultBS, taken by the committer as configuration is applied, so never held while waiting
+	batch          sharedconfig.BatchParameters // How the default chain cuts blocks
+	defaultBS      *broadcastServer             // The batching loop of the default chain, nil until it is first referenced
+	paused         bool
+	retryAfter     time.Duration
+	stopped        bool
 }
 
-// New creates a ab.AtomicBroadcastServer based on the solo orderer implementation
-func New(queueSize, batchSize, maxWindowSize int, batchTimeout time.Duration, rl rawledger.ReadWriter, grpcServer *grpc.Server) ab.AtomicBroadcastServer {
-	logger.Infof("Starting solo with queueSize=%d, batchSize=%d batchTimeout=%v and ledger=%T", queueSize, batchSize, batchTimeout, rl)
+// Validate returns an error describing the first option which is missing or out of range
+func (opts *Options) Validate() error {
+	switch {
+	case opts.QueueSize <= 0:
+		return fmt.Errorf("QueueSize must be positive, got %d", opts.QueueSize)
+	case opts.BatchSize <= 0:
+		return fmt.Errorf("BatchSize must be positive, got %d", opts.BatchSize)
+	case opts.BatchMaxBytes < 0:
+		return fmt.Errorf("BatchMaxBytes must not be negative, got %d", opts.BatchMaxBytes)
+	case opts.BatchTimeout <= 0:
+		return fmt.Errorf("BatchTimeout must be positive, got %v", opts.BatchTimeout)
+	case opts.MaxWindowSize <= 0:
+		return fmt.Errorf("MaxWindowSize must be positive, got %d", opts.MaxWindowSize)
+	case opts.DedupWindow < 0:
+		return fmt.Errorf("DedupWindow must not be negative, got %d", opts.DedupWindow)
+	case opts.MaxIdleTime < 0:
+		return fmt.Errorf("MaxIdleTime must not be negative, got %v", opts.MaxIdleTime)
+	case opts.MaxLag < 0:
+		return fmt.Errorf("MaxLag must not be negative, got %d", opts.MaxLag)
+	case opts.HeartbeatInterval < 0:
+		return fmt.Errorf("HeartbeatInterval must not be negative, got %v", opts.HeartbeatInterval)
+	case opts.MaxDeliverStreams < 0:
+		return fmt.Errorf("MaxDeliverStreams must not be negative, got %d", opts.MaxDeliverStreams)
+	case opts.MaxDeliverStreamsPerClient < 0:
+		return fmt.Errorf("MaxDeliverStreamsPerClient must not be negative, got %d", opts.MaxDeliverStreamsPerClient)
+	case opts.DeliverRetryAfter < 0:
+		return fmt.Errorf("DeliverRetryAfter must not be negative, got %v", opts.DeliverRetryAfter)
+	case opts.RetryAfter < 0:
+		return fmt.Errorf("RetryAfter must not be negative, got %v", opts.RetryAfter)
+	case opts.IdleTimeout < 0:
+		return fmt.Errorf("IdleTimeout must not be negative, got %v", opts.IdleTimeout)
+	case opts.SnapshotInterval < 0:
+		return fmt.Errorf("SnapshotInterval must not be negative, got %d", opts.SnapshotInterval)
+	case opts.MaxChains < 0:
+		return fmt.Errorf("MaxChains must not be negative, got %d", opts.MaxChains)
+	case opts.CreateChains && opts.Filter == nil:
+		return fmt.Errorf("CreateChains must not be set without a Filter to authorize chain creation transactions")
+	}
+	return nil
+}
+
+// New creates an Orderer based on the solo orderer implementation, messages and seeks which do not specify a chain are routed to defaultChainID
+// The caller registers the Orderer with its gRPC server, as both an AtomicBroadcast and an Admin server
+func New(opts Options, lf rawledger.Factory, defaultChainID []byte) (Orderer, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid solo options: %s", err)
+	}
+	if lf == nil {
+		return nil, fmt.Errorf("A ledger factory is required")
+	}
+	if _, ok := lf.Get(defaultChainID); !ok {
+		return nil, fmt.Errorf("The default chain %x does not exist in the ledger factory", defaultChainID)
+	}
+
+	logger.Infof("Starting solo with queueSize=%d, batchSize=%d batchTimeout=%v ackAfterCommit=%v dedupWindow=%d and ledger factory=%T", opts.QueueSize, opts.BatchSize, opts.BatchTimeout, opts.AckAfterCommit, opts.DedupWindow, lf)
 	s := &server{
-		bs: newBroadcastServer(queueSize, batchSize, batchTimeout, rl),
-		ds: newDeliverServer(rl, maxWindowSize),
+		opts:           opts,
+		lf:             lf,
+		defaultChainID: defaultChainID,
+		chains:         make(map[string]*chain),
+		batch:          sharedconfig.BatchParameters{Size: opts.BatchSize, MaxBytes: opts.BatchMaxBytes, Timeout: opts.BatchTimeout},
+		paused:         opts.Paused,
+		retryAfter:     opts.RetryAfter,
+	}
+	if opts.Registry != nil {
+		s.idleClosed = gometrics.NewRegisteredCounter("broadcast.idle_closed", opts.Registry)
+	}
+	s.ds = deliver.NewServer(s.ledger, deliver.Options{
+		MaxWindowSize:       opts.MaxWindowSize,
+		DefaultWindowSize:   opts.DefaultWindowSize,
+		MaxIdleTime:         opts.MaxIdleTime,
+		MaxLag:              opts.MaxLag,
+		HeartbeatInterval:   opts.HeartbeatInterval,
+		CursorKey:           opts.CursorKey,
+		MaxSendMsgSize:      opts.MaxSendMsgSize,
+		Features:            opts.Features,
+		MaxStreams:          opts.MaxDeliverStreams,
+		MaxStreamsPerClient: opts.MaxDeliverStreamsPerClient,
+		RetryAfter:          opts.DeliverRetryAfter,
+		Policies:            opts.Policies,
+		Decisions:           opts.Decisions,
+		SeekSkew:            opts.SeekSkew,
+	})
+	if opts.Config != nil {
+		s.refreshBatchSize()
+		opts.Config.RegisterObserver(s.configure)
+	}
+	return s, nil
+}
+
+// chain returns the state of a chain, starting its batching pipeline the first time the chain is referenced, or false if the ledger has no such chain
+func (s *server) chain(chainID []byte) (*chain, bool) {
+	if len(chainID) == 0 {
+		chainID = s.defaultChainID
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if c, ok := s.chains[string(chainID)]; ok {
+		return c, true
+	}
+
+	rl, ok := s.lf.Get(chainID)
+	if !ok {
+		return nil, false
+	}
+
+	var plog *pendingLog
+	if s.opts.PendingLogDir != "" {
+		plog = openPendingLog(filepath.Join(s.opts.PendingLogDir, fmt.Sprintf("pending_%x.log", chainID)), rl)
+	}
+
+	var chainRegistry gometrics.Registry
+	if s.opts.Registry != nil {
+		chainRegistry = gometrics.NewPrefixedChildRegistry(s.opts.Registry, fmt.Sprintf("%x.", chainID))
+	}
+
+	logger.Debugf("Starting batching for chain %x", chainID)
+	batch := sharedconfig.BatchParameters{Size: s.opts.BatchSize, MaxBytes: s.opts.BatchMaxBytes, Timeout: s.opts.BatchTimeout}
+	var config configtx.Manager
+	isDefault := bytes.Equal(chainID, s.defaultChainID)
+	if isDefault {
+		s.batchLock.Lock()
+		batch = s.batch
+	} else if s.opts.ChainConfig != nil {
+		if cm, ok := s.opts.ChainConfig(chainID); ok {
+			config = cm
+			batch = s.configuredBatch(config)
+		}
+	}
+	bs := newPlainBroadcastServer(s.opts.QueueSize, batch.Size, batch.MaxBytes, batch.Timeout, s.opts.AckAfterCommit, s.opts.DedupWindow, s.opts.Filter, rl, plog, chainRegistry, s.opts.Clock)
+	bs.audit = s.opts.Audit
+	if isDefault {
+		bs.config = s.opts.Config
+		bs.snapshotEvery = uint64(s.opts.SnapshotInterval)
+		if s.opts.CreateChains {
+			bs.chainCreator = s.createChain
+		}
+		s.defaultBS = bs
+		s.batchLock.Unlock()
+	} else if config != nil {
+		// As on the default chain, the committer applies the configuration and a change of batch parameters takes effect from the next block
+		bs.config = config
+		config.RegisterObserver(s.configureChain(bs))
+	}
+	bs.lastConfig = configtx.NewLastConfigTracker(rl)
+	bs.stamper = rawledger.NewTimestamper(rl)
+	bs.signer = s.opts.Signer
+	bs.Start()
+	c := &chain{
+		bs: bs,
+		rl: rl,
 	}
-	ab.RegisterAtomicBroadcastServer(grpcServer, s)
-	return s
+	if s.stopped {
+		// Chains referenced after teardown must not accept messages either
+		c.bs.shutdown()
+	} else if s.paused {
+		c.bs.pause(s.retryAfter)
+	}
+	s.chains[string(chainID)] = c
+	return c, true
+}
+
+// createChain creates the ledger of a chain from the genesis configuration committed on the default chain, and starts its batching pipeline
+// It is called by the committer of the default chain, which must not wait on s.lock, so the pipeline is started asynchronously
+func (s *server) createChain(configTx *ab.ConfigurationEnvelope) {
+	if _, ok := s.lf.Get(configTx.ChainID); ok {
+		// Both of two creation transactions for the same chain may be ordered before either is committed
+		logger.Warningf("Not creating chain %x, which already exists", configTx.ChainID)
+		return
+	}
+	if s.opts.MaxChains > 0 && len(s.lf.ChainIDs()) >= s.opts.MaxChains {
+		// As with duplicates, creation transactions ordered together may each have been accepted below the limit
+		logger.Warningf("Not creating chain %x, the maximum of %d chains already exist", configTx.ChainID, s.opts.MaxChains)
+		return
+	}
+	genesis, err := configtx.GenesisBlock(configTx)
+	if err != nil {
+		logger.Errorf("Could not create the genesis block of chain %x: %s", configTx.ChainID, err)
+		return
+	}
+	s.lf.GetOrCreate(configTx.ChainID, genesis)
+	logger.Infof("Created chain %x", configTx.ChainID)
+	go s.chain(configTx.ChainID)
+}
+
+// Chain returns the batching loop of a chain, so that the chain may be ordered through the shared broadcast handler
+func (s *server) Chain(chainID []byte) (consenter.Chain, bool) {
+	c, ok := s.chain(chainID)
+	if !ok {
+		return nil, false
+	}
+	return c.bs, true
+}
+
+func (s *server) ledger(chainID []byte) (rawledger.Reader, bool) {
+	c, ok := s.chain(chainID)
+	if !ok {
+		return nil, false
+	}
+	return c.rl, true
 }
 
 // Broadcast receives a stream of messages from a client for ordering
 func (s *server) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
-	return s.bs.handleBroadcast(srv)
+	return broadcast.HandleStream(srv, s, broadcast.Options{
+		Filter:  s.opts.Filter,
+		Enabled: s.opts.Features,
+		// Replies may be outstanding for every queued message as well as those awaiting commit in the current batch
+		MaxOutstanding: s.opts.QueueSize + s.opts.BatchSize,
+		IdleTimeout:    s.opts.IdleTimeout,
+		Clock:          s.opts.Clock,
+		IdleClosed:     s.idleClosed,
+	})
+}
+
+// SubmitBroadcast orders a single message as a Broadcast stream would, with AckAfterCommit it returns once the message is committed
+func (s *server) SubmitBroadcast(ctx context.Context, msg *ab.BroadcastMessage) (*ab.BroadcastResponse, error) {
+	return broadcast.Submit(ctx, msg, s.Broadcast)
+}
+
+// BroadcastBatch receives a stream of batches of messages from a client, ordering each message as Broadcast would
+func (s *server) BroadcastBatch(srv ab.AtomicBroadcast_BroadcastBatchServer) error {
+	return broadcast.HandleBatches(srv, s.Broadcast)
 }
 
 // Deliver sends a stream of blocks to a client after ordering
 func (s *server) Deliver(srv ab.AtomicBroadcast_DeliverServer) error {
 	logger.Debugf("Starting new Deliver loop")
-	return s.ds.handleDeliver(srv)
+	return s.ds.Handle(srv)
+}
+
+// Pause commits the messages already accepted on every chain and then stops cutting blocks, Deliver continues to be served
+func (s *server) Pause(ctx context.Context, req *ab.PauseRequest) (*ab.AdminResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped {
+		return &ab.AdminResponse{Status: ab.ReasonUnavailable.Status(), Paused: s.paused}, nil
+	}
+
+	retryAfter := time.Duration(req.RetryAfter) * time.Millisecond
+	if retryAfter == 0 {
+		retryAfter = s.opts.RetryAfter
+	}
+
+	logger.Infof("Pausing ordering, clients will be asked to retry after %v", retryAfter)
+	s.paused = true
+	s.retryAfter = retryAfter
+	for _, c := range s.chains {
+		c.bs.pause(retryAfter)
+	}
+	return &ab.AdminResponse{Status: ab.Status_SUCCESS, Paused: true}, nil
+}
+
+// Resume restarts the cutting of blocks on every chain
+func (s *server) Resume(ctx context.Context, req *ab.ResumeRequest) (*ab.AdminResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped {
+		return &ab.AdminResponse{Status: ab.ReasonUnavailable.Status(), Paused: s.paused}, nil
+	}
+
+	logger.Infof("Resuming ordering")
+	s.paused = false
+	for _, c := range s.chains {
+		c.bs.resume()
+	}
+	return &ab.AdminResponse{Status: ab.Status_SUCCESS, Paused: false}, nil
+}
+
+// Teardown stops accepting new messages and commits the pending batches and queued messages to the ledger before returning,
+// the Deliver streams are then ended, it may be called more than once
+func (s *server) Teardown() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stopped {
+		return nil
+	}
+	s.stopped = true
+	for _, c := range s.chains {
+		c.bs.shutdown()
+	}
+	s.ds.Shutdown()
+	return nil
 }
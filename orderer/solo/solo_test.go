@@ -0,0 +1,529 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	ab "github.com/hyperledger/fabric/orderer/atomicbroadcast"
+	"github.com/hyperledger/fabric/orderer/atomicbroadcast/mocks"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap/static"
+	"github.com/hyperledger/fabric/orderer/rawledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/fileledger"
+	"github.com/hyperledger/fabric/orderer/rawledger/ramledger"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// MagicLargestWindow is used as the default max window size for initializing the deliver service
+const MagicLargestWindow int = 1000
+
+// newOrderer creates a solo orderer, failing the test if it cannot be created
+func newOrderer(t *testing.T, opts Options, lf rawledger.Factory, defaultChainID []byte) Orderer {
+	s, err := New(opts, lf, defaultChainID)
+	if err != nil {
+		t.Fatalf("Error creating the orderer: %s", err)
+	}
+	return s
+}
+
+// startServer starts a solo orderer over the given ledgers on a real gRPC server, returning a connected client
+func startServer(t *testing.T, lf rawledger.Factory) (ab.AtomicBroadcastClient, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	grpcServer := grpc.NewServer()
+	ab.RegisterAtomicBroadcastServer(grpcServer, newOrderer(t, Options{QueueSize: 10, BatchSize: 1, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, AckAfterCommit: true, Features: ab.AllFeatures}, lf, static.TestChainID))
+	go grpcServer.Serve(lis)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+
+	return ab.NewAtomicBroadcastClient(conn), func() {
+		conn.Close()
+		grpcServer.Stop()
+	}
+}
+
+func seekSpecified(t *testing.T, client ab.AtomicBroadcastClient, number uint64) ab.AtomicBroadcast_DeliverClient {
+	stream, err := client.Deliver(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open deliver stream: %s", err)
+	}
+	err = stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{WindowSize: uint64(MagicLargestWindow), Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: number}}})
+	if err != nil {
+		t.Fatalf("Failed to send seek: %s", err)
+	}
+	return stream
+}
+
+func expectNotFound(t *testing.T, stream ab.AtomicBroadcast_DeliverClient, info string) {
+	reply, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected a status reply but got error: %s", err)
+	}
+	if reply.GetError() != ab.Status_NOT_FOUND || reply.Info != info {
+		t.Fatalf("Expected NOT_FOUND (%q) but got %v", info, reply)
+	}
+	if _, err = stream.Recv(); err != io.EOF {
+		t.Fatalf("Expected the stream to be closed after NOT_FOUND, but got %v", err)
+	}
+}
+
+func testSeekValidation(t *testing.T, lf rawledger.Factory) {
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	for i := 0; i < 5; i++ {
+		rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("Some bytes")}}, nil)
+	}
+
+	client, stop := startServer(t, lf)
+	defer stop()
+
+	// Beyond the next block to be created
+	expectNotFound(t, seekSpecified(t, client, rl.Height()+1), fmt.Sprintf("seek target %d exceeds height %d", rl.Height()+1, rl.Height()))
+
+	// Below the retained window, if the ledger discards history
+	if oldest := rl.OldestRetained(); oldest > 0 {
+		expectNotFound(t, seekSpecified(t, client, oldest-1), fmt.Sprintf("seek target %d precedes oldest retained block %d", oldest-1, oldest))
+	}
+
+	// The next block to be created is valid, and is delivered once appended
+	stream := seekSpecified(t, client, rl.Height())
+	time.Sleep(100 * time.Millisecond)
+	expected := rl.Append([]*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("Some bytes")}}, nil)
+	reply, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected a block but got error: %s", err)
+	}
+	if reply.GetBlock() == nil || reply.GetBlock().Header.Number != expected.Header.Number {
+		t.Fatalf("Expected block %d but got %v", expected.Header.Number, reply)
+	}
+}
+
+func TestSeekValidationRAMLedger(t *testing.T) {
+	testSeekValidation(t, ramledger.NewFactory(3))
+}
+
+func TestSeekValidationFileLedger(t *testing.T) {
+	location, err := ioutil.TempDir("", "hyperledger")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(location)
+	testSeekValidation(t, fileledger.NewFactory(location))
+}
+
+func TestMultipleChains(t *testing.T) {
+	chainIDs := [][]byte{[]byte("chainA"), []byte("chainB")}
+	lf := ramledger.NewFactory(100)
+	for _, chainID := range chainIDs {
+		lf.GetOrCreate(chainID, genesisBlock)
+	}
+
+	batchSize := 2
+	messages := 10 // Per chain, per stream
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: batchSize, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, AckAfterCommit: true, Features: ab.AllFeatures}, lf, chainIDs[0])
+	defer s.Teardown()
+
+	streams := []*mocks.BroadcastStream{mocks.NewBroadcastStream(), mocks.NewBroadcastStream()}
+	done := make(chan struct{})
+	for i, m := range streams {
+		go s.Broadcast(m)
+		go func(i int, m *mocks.BroadcastStream) {
+			for j := 0; j < messages; j++ {
+				for _, chainID := range chainIDs {
+					m.RecvChan <- &ab.BroadcastMessage{Data: []byte(fmt.Sprintf("%s-%d-%d", chainID, i, j)), ChainID: chainID}
+				}
+			}
+			done <- struct{}{}
+		}(i, m)
+		go func(m *mocks.BroadcastStream) {
+			for range m.SendChan {
+			}
+		}(m)
+	}
+	for range streams {
+		<-done
+	}
+
+	unknown := mocks.NewBroadcastStream()
+	go s.Broadcast(unknown)
+	unknown.RecvChan <- &ab.BroadcastMessage{Data: []byte("Some bytes"), ChainID: []byte("unknown")}
+	if reply := <-unknown.SendChan; reply.Status != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected NOT_FOUND for an unknown chain but got %v", reply)
+	}
+
+	expectedHeight := uint64(1 + len(streams)*messages/batchSize)
+	for _, chainID := range chainIDs {
+		rl, _ := lf.Get(chainID)
+		waitForHeight(t, rl, expectedHeight)
+
+		it, _ := rl.Iterator(ab.SeekInfo_SPECIFIED, 1)
+		for number := uint64(1); number < expectedHeight; number++ {
+			block, _ := it.Next()
+			if block.Header.Number != number {
+				t.Fatalf("Expected block %d on chain %s but got %d", number, chainID, block.Header.Number)
+			}
+			for _, msg := range block.Data.Messages {
+				if !bytes.HasPrefix(msg.Data, chainID) {
+					t.Fatalf("Block %d of chain %s contains message %s from another chain", number, chainID, msg.Data)
+				}
+			}
+		}
+	}
+
+	// Deliver serves the requested chain, and rejects unknown ones
+	m := mocks.NewDeliverStream()
+	defer close(m.RecvChan)
+	go s.Deliver(m)
+	m.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: 1, WindowSize: 1, ChainID: chainIDs[1]}}}
+	reply := <-m.SendChan
+	if block := reply.GetBlock(); block == nil || !bytes.HasPrefix(block.Data.Messages[0].Data, chainIDs[1]) {
+		t.Fatalf("Expected the first block of %s but got %v", chainIDs[1], reply)
+	}
+
+	md := mocks.NewDeliverStream()
+	defer close(md.RecvChan)
+	go s.Deliver(md)
+	md.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_OLDEST, WindowSize: 1, ChainID: []byte("unknown")}}}
+	if reply := <-md.SendChan; reply.GetError() != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected NOT_FOUND for an unknown chain but got %v", reply)
+	}
+}
+
+// helloBroadcast negotiates every feature on a Broadcast stream
+func helloBroadcast(t *testing.T, stream ab.AtomicBroadcast_BroadcastClient) {
+	if err := stream.Send(&ab.BroadcastMessage{Hello: ab.AllFeatures.Hello()}); err != nil {
+		t.Fatalf("Failed to send hello: %s", err)
+	}
+	if reply, err := stream.Recv(); err != nil || reply.Hello == nil {
+		t.Fatalf("Expected a reply to the hello but got %v (%v)", reply, err)
+	}
+}
+
+// helloDeliver negotiates every feature on a Deliver stream
+func helloDeliver(t *testing.T, stream ab.AtomicBroadcast_DeliverClient) {
+	if err := stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Hello{Hello: ab.AllFeatures.Hello()}}); err != nil {
+		t.Fatalf("Failed to send hello: %s", err)
+	}
+	if reply, err := stream.Recv(); err != nil || reply.GetHello() == nil {
+		t.Fatalf("Expected a reply to the hello but got %v (%v)", reply, err)
+	}
+}
+
+func TestChainIDOverGRPC(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	other := []byte("other")
+	lf.GetOrCreate(other, genesisBlock)
+	client, stop := startServer(t, lf)
+	defer stop()
+
+	broadcast, err := client.Broadcast(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open broadcast stream: %s", err)
+	}
+	helloBroadcast(t, broadcast)
+	for _, tc := range []struct {
+		chainID []byte
+		data    string
+		status  ab.Status
+	}{
+		{nil, "default", ab.Status_SUCCESS}, // Clients which predate chains order on the default chain
+		{other, "other", ab.Status_SUCCESS},
+		{[]byte("unknown"), "unknown", ab.Status_NOT_FOUND},
+	} {
+		if err := broadcast.Send(&ab.BroadcastMessage{Data: []byte(tc.data), ChainID: tc.chainID}); err != nil {
+			t.Fatalf("Failed to send to chain %s: %s", tc.chainID, err)
+		}
+		reply, err := broadcast.Recv()
+		if err != nil {
+			t.Fatalf("Expected a reply for chain %s but got error: %s", tc.chainID, err)
+		}
+		if reply.Status != tc.status {
+			t.Fatalf("Expected %v for chain %s but got %v", tc.status, tc.chainID, reply.Status)
+		}
+	}
+
+	for _, tc := range []struct {
+		chainID []byte
+		data    string
+	}{
+		{nil, "default"},
+		{static.TestChainID, "default"},
+		{other, "other"},
+	} {
+		stream, err := client.Deliver(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to open deliver stream: %s", err)
+		}
+		helloDeliver(t, stream)
+		if err := stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_SPECIFIED, SpecifiedNumber: 1, WindowSize: 1, ChainID: tc.chainID}}}); err != nil {
+			t.Fatalf("Failed to send seek: %s", err)
+		}
+		reply, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Expected a block of chain %s but got error: %s", tc.chainID, err)
+		}
+		if block := reply.GetBlock(); block == nil || string(block.Data.Messages[0].Data) != tc.data {
+			t.Fatalf("Expected the first block of chain %s to hold %q but got %v", tc.chainID, tc.data, reply)
+		}
+	}
+
+	stream, err := client.Deliver(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open deliver stream: %s", err)
+	}
+	helloDeliver(t, stream)
+	if err := stream.Send(&ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_OLDEST, WindowSize: 1, ChainID: []byte("unknown")}}}); err != nil {
+		t.Fatalf("Failed to send seek: %s", err)
+	}
+	expectNotFound(t, stream, "chain 756e6b6e6f776e does not exist")
+}
+
+func TestSubmitBroadcastOverGRPC(t *testing.T) {
+	msg := &ab.BroadcastMessage{Data: []byte("submitted")}
+
+	streamed := ramledger.NewFactory(10)
+	streamedRL := streamed.GetOrCreate(static.TestChainID, genesisBlock)
+	client, stop := startServer(t, streamed)
+	defer stop()
+	stream, err := client.Broadcast(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open broadcast stream: %s", err)
+	}
+	if err = stream.Send(msg); err != nil {
+		t.Fatalf("Failed to send: %s", err)
+	}
+	if reply, err := stream.Recv(); err != nil || reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the streamed message to be committed, got %v (%v)", reply, err)
+	}
+
+	submitted := ramledger.NewFactory(10)
+	submittedRL := submitted.GetOrCreate(static.TestChainID, genesisBlock)
+	client, stop = startServer(t, submitted)
+	defer stop()
+	// Replies are sent after commit, so the block is in the ledger once the call returns
+	reply, err := client.SubmitBroadcast(context.Background(), msg)
+	if err != nil || reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 1 {
+		t.Fatalf("Expected the submitted message to be committed in block 1, got %v (%v)", reply, err)
+	}
+
+	expected, _ := streamedRL.GetBlock(1)
+	block, status := submittedRL.GetBlock(1)
+	if status != ab.Status_SUCCESS || !proto.Equal(expected.Header, block.Header) || !proto.Equal(expected.Data, block.Data) {
+		t.Fatalf("Expected the submitted message to be cut into the same block as the streamed one, got %v", block)
+	}
+
+	reply, err = client.SubmitBroadcast(context.Background(), &ab.BroadcastMessage{Data: []byte("unknown"), ChainID: []byte("unknown")})
+	if err != nil || reply.Status != ab.Status_NOT_FOUND {
+		t.Fatalf("Expected NOT_FOUND for an unknown chain, got %v (%v)", reply, err)
+	}
+}
+
+func TestSubmitBroadcastDeadline(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 2, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, AckAfterCommit: true, Features: ab.AllFeatures}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	// The batch is not cut before the deadline, so the commit cannot be awaited
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := s.SubmitBroadcast(ctx, &ab.BroadcastMessage{Data: []byte("pending")}); grpc.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("Expected the call to end with its deadline, got %v", err)
+	}
+}
+
+func TestBroadcastBatchOverGRPC(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	client, stop := startServer(t, lf)
+	defer stop()
+
+	stream, err := client.BroadcastBatch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open broadcast batch stream: %s", err)
+	}
+	// The second batch is sent before the first is replied to, each message is cut into its own block
+	batches := []*ab.BroadcastBatch{
+		&ab.BroadcastBatch{Messages: []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("a")}, &ab.BroadcastMessage{}, &ab.BroadcastMessage{Data: []byte("b")}}},
+		&ab.BroadcastBatch{Messages: []*ab.BroadcastMessage{&ab.BroadcastMessage{Data: []byte("c")}}},
+	}
+	for _, batch := range batches {
+		if err = stream.Send(batch); err != nil {
+			t.Fatalf("Failed to send batch: %s", err)
+		}
+	}
+
+	for i, expected := range [][]*ab.BroadcastResponse{
+		{{Status: ab.Status_SUCCESS, BlockNumber: 1}, {Status: ab.Status_BAD_REQUEST, Info: "message is empty"}, {Status: ab.Status_SUCCESS, BlockNumber: 2}},
+		{{Status: ab.Status_SUCCESS, BlockNumber: 3}},
+	} {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Expected the response to batch %d but got error: %s", i, err)
+		}
+		if !proto.Equal(resp, &ab.BroadcastBatchResponse{Responses: expected}) {
+			t.Fatalf("Expected the response to batch %d to be %v but got %v", i, expected, resp)
+		}
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	clock := newFakeClock()
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 2, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Second, Clock: clock, Features: ab.AllFeatures}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go s.Broadcast(m)
+	broadcast := func(data string) *ab.BroadcastResponse {
+		m.RecvChan <- &ab.BroadcastMessage{Data: []byte(data)}
+		return <-m.SendChan
+	}
+
+	for i := 0; i < 5; i++ {
+		if reply := broadcast(fmt.Sprintf("%d", i)); reply.Status != ab.Status_SUCCESS {
+			t.Fatalf("Expected the message to be queued but got %v", reply)
+		}
+	}
+
+	// Everything accepted before the pause is committed before it returns
+	if resp, _ := s.Pause(context.Background(), &ab.PauseRequest{RetryAfter: 250}); resp.Status != ab.Status_SUCCESS || !resp.Paused {
+		t.Fatalf("Expected the orderer to be paused but got %v", resp)
+	}
+	if rl.Height() != 4 {
+		t.Fatalf("Expected the 5 accepted messages to be flushed into 3 blocks, but the height is %d", rl.Height())
+	}
+	if clock.activeTimers() != 0 {
+		t.Fatalf("Expected the batch timer to be suspended while paused")
+	}
+	clock.forgetTimers()
+
+	if reply := broadcast("paused"); reply.Status != ab.Status_SERVICE_UNAVAILABLE || reply.RetryAfter != 250 {
+		t.Fatalf("Expected SERVICE_UNAVAILABLE with a retry hint of 250ms but got %v", reply)
+	}
+	clock.advance(time.Hour)
+	clock.expectNoTimer(t)
+	if rl.Height() != 4 {
+		t.Fatalf("Expected no block to be cut while paused, but the height is %d", rl.Height())
+	}
+
+	// Deliver is served normally while paused
+	md := mocks.NewDeliverStream()
+	defer close(md.RecvChan)
+	go s.Deliver(md)
+	md.RecvChan <- &ab.DeliverUpdate{Type: &ab.DeliverUpdate_Seek{Seek: &ab.SeekInfo{Start: ab.SeekInfo_NEWEST, WindowSize: 1}}}
+	if reply := <-md.SendChan; reply.GetBlock() == nil || reply.GetBlock().Header.Number != 3 {
+		t.Fatalf("Expected the newest block to be delivered while paused but got %v", reply)
+	}
+
+	if resp, _ := s.Resume(context.Background(), &ab.ResumeRequest{}); resp.Status != ab.Status_SUCCESS || resp.Paused {
+		t.Fatalf("Expected the orderer to be resumed but got %v", resp)
+	}
+	if reply := broadcast("resumed"); reply.Status != ab.Status_SUCCESS {
+		t.Fatalf("Expected the message to be queued after resuming but got %v", reply)
+	}
+	clock.waitForTimer(t)
+	clock.advance(time.Second)
+	waitForHeight(t, rl, 5)
+}
+
+func TestStartPaused(t *testing.T) {
+	lf := ramledger.NewFactory(100)
+	rl := lf.GetOrCreate(static.TestChainID, genesisBlock)
+	s := newOrderer(t, Options{QueueSize: 10, BatchSize: 1, MaxWindowSize: MagicLargestWindow, BatchTimeout: time.Hour, AckAfterCommit: true, Paused: true, RetryAfter: 2 * time.Second, Features: ab.AllFeatures}, lf, static.TestChainID)
+	defer s.Teardown()
+
+	m := mocks.NewBroadcastStream()
+	defer close(m.RecvChan)
+	go s.Broadcast(m)
+
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("paused")}
+	if reply := <-m.SendChan; reply.Status != ab.Status_SERVICE_UNAVAILABLE || reply.RetryAfter != 2000 {
+		t.Fatalf("Expected SERVICE_UNAVAILABLE with the configured retry hint but got %v", reply)
+	}
+
+	s.Resume(context.Background(), &ab.ResumeRequest{})
+	m.RecvChan <- &ab.BroadcastMessage{Data: []byte("resumed")}
+	if reply := <-m.SendChan; reply.Status != ab.Status_SUCCESS || reply.BlockNumber != 1 {
+		t.Fatalf("Expected the message to be ordered in block 1 after resuming but got %v", reply)
+	}
+	if rl.Height() != 2 {
+		t.Fatalf("Expected a single block to be cut after resuming, but the height is %d", rl.Height())
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	lf := ramledger.NewFactory(10)
+	lf.GetOrCreate(static.TestChainID, genesisBlock)
+	valid := Options{QueueSize: 10, BatchSize: 1, BatchTimeout: time.Second, MaxWindowSize: 10}
+	if _, err := New(valid, lf, static.TestChainID); err != nil {
+		t.Fatalf("Expected the valid options to be accepted but got %s", err)
+	}
+
+	invalid := map[string]func(opts *Options){
+		"QueueSize":                  func(opts *Options) { opts.QueueSize = 0 },
+		"BatchSize":                  func(opts *Options) { opts.BatchSize = 0 },
+		"BatchMaxBytes":              func(opts *Options) { opts.BatchMaxBytes = -1 },
+		"BatchTimeout":               func(opts *Options) { opts.BatchTimeout = 0 },
+		"MaxWindowSize":              func(opts *Options) { opts.MaxWindowSize = 0 },
+		"DedupWindow":                func(opts *Options) { opts.DedupWindow = -1 },
+		"MaxIdleTime":                func(opts *Options) { opts.MaxIdleTime = -time.Second },
+		"MaxLag":                     func(opts *Options) { opts.MaxLag = -1 },
+		"HeartbeatInterval":          func(opts *Options) { opts.HeartbeatInterval = -time.Second },
+		"MaxDeliverStreams":          func(opts *Options) { opts.MaxDeliverStreams = -1 },
+		"MaxDeliverStreamsPerClient": func(opts *Options) { opts.MaxDeliverStreamsPerClient = -1 },
+		"DeliverRetryAfter":          func(opts *Options) { opts.DeliverRetryAfter = -time.Second },
+		"RetryAfter":                 func(opts *Options) { opts.RetryAfter = -time.Second },
+		"IdleTimeout":                func(opts *Options) { opts.IdleTimeout = -time.Second },
+		"MaxChains":                  func(opts *Options) { opts.MaxChains = -1 },
+		"CreateChains":               func(opts *Options) { opts.CreateChains = true },
+	}
+	for field, mutate := range invalid {
+		opts := valid
+		mutate(&opts)
+		_, err := New(opts, lf, static.TestChainID)
+		if err == nil || !strings.Contains(err.Error(), field+" must") {
+			t.Errorf("Expected an error naming %s but got %v", field, err)
+		}
+	}
+
+	if _, err := New(valid, nil, static.TestChainID); err == nil {
+		t.Errorf("Expected an error for a nil ledger factory")
+	}
+	if _, err := New(valid, lf, []byte("missing")); err == nil {
+		t.Errorf("Expected an error for a default chain which does not exist")
+	}
+}
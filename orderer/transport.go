@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+                 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/hyperledger/fabric/orderer/common/server"
+	"github.com/hyperledger/fabric/orderer/config"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/keepalive"
+)
+
+// registerGzip registers gzipCompressor once, gRPC allows compressors to be registered only before serving and for the whole process
+var registerGzip sync.Once
+
+// gzipCompressor compresses the responses of the calls whose requests were compressed with gzip
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string {
+	return "gzip"
+}
+
+func (gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// serverOptions returns the options of the gRPC server, registering the counts of its calls and the bytes it sends in registry
+func serverOptions(conf *config.TopLevel, registry gometrics.Registry) ([]grpc.ServerOption, *server.Drain, error) {
+	// Clients may compress their requests whether or not the responses are compressed
+	builder := server.NewBuilder(conf.General.Interceptors, registry).AddOption(grpc.RPCDecompressor(grpc.NewGZIPDecompressor()))
+
+	builder.AddOption(grpc.KeepaliveParams(keepalive.ServerParameters{
+		Time:    conf.General.Keepalive.Time,
+		Timeout: conf.General.Keepalive.Timeout,
+	}))
+	builder.AddOption(grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             conf.General.Keepalive.MinTime,
+		PermitWithoutStream: conf.General.Keepalive.PermitWithoutStream,
+	}))
+
+	switch conf.General.Deliver.Compression {
+	case "none":
+	case "gzip":
+		// No call is compressed by force, gRPC answers a call compressed only when its request was, as the Deliver client compresses its seeks and the Broadcast client does not
+		registerGzip.Do(func() { encoding.RegisterCompressor(gzipCompressor{}) })
+	default:
+		return nil, nil, fmt.Errorf("General.Deliver.Compression must be one of none or gzip, got '%s'", conf.General.Deliver.Compression)
+	}
+
+	drain := builder.AddDrain()
+	return builder.Options(), drain, nil
+}
+
+// countingListener counts the bytes written to the connections it accepts, as they go on the wire
+type countingListener struct {
+	net.Listener
+	sent gometrics.Counter
+}
+
+// newCountingListener registers the count of the bytes written to the connections accepted from lis in registry
+func newCountingListener(lis net.Listener, registry gometrics.Registry) net.Listener {
+	return &countingListener{Listener: lis, sent: gometrics.GetOrRegisterCounter("wire_bytes_sent", registry)}
+}
+
+func (cl *countingListener) Accept() (net.Conn, error) {
+	conn, err := cl.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn, sent: cl.sent}, nil
+}
+
+type countingConn struct {
+	net.Conn
+	sent gometrics.Counter
+}
+
+func (cc *countingConn) Write(b []byte) (int, error) {
+	n, err := cc.Conn.Write(b)
+	cc.sent.Inc(int64(n))
+	return n, err
+}